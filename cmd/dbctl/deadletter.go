@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+var deadletterCmd = &cobra.Command{
+	Use:   "deadletter",
+	Short: "Inspect and requeue PRs whose processing failed MAX_PROCESSING_ATTEMPTS times",
+}
+
+var deadletterListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List dead-lettered PRs with the failure of each attempt",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWithDatabase(func(database *db.Database) error {
+			letters, err := db.NewSearchRepository(database).DeadLetters(cmd.Context())
+			if err != nil {
+				return err
+			}
+			printDeadLetters(cmd.OutOrStdout(), letters)
+			return nil
+		})
+	},
+}
+
+var deadletterRequeueCmd = &cobra.Command{
+	Use:   "requeue [pr-number...]",
+	Short: "Return dead-lettered PRs to the processing queue",
+	Long: `Take the given PRs, or every dead-lettered PR with --all, out of the
+dead letter queue and mark them unprocessed so the next PROCESS run or worker
+picks them up with a fresh MAX_PROCESSING_ATTEMPTS budget.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		if all == (len(args) > 0) {
+			return errors.New("pass PR numbers or --all")
+		}
+		var numbers []int
+		for _, arg := range args {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid PR number %q", arg)
+			}
+			numbers = append(numbers, n)
+		}
+		return runWithDatabase(func(database *db.Database) error {
+			requeued, err := db.NewSearchRepository(database).RequeueDeadLetters(cmd.Context(), numbers)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "requeued %d PRs: %v\n", len(requeued), requeued)
+			return nil
+		})
+	},
+}
+
+func init() {
+	_ = deadletterRequeueCmd.Flags().Bool("all", false, "Requeue every dead-lettered PR")
+	deadletterCmd.AddCommand(deadletterListCmd, deadletterRequeueCmd)
+}
+
+func printDeadLetters(out io.Writer, letters []db.PRDeadLetter) {
+	if len(letters) == 0 {
+		fmt.Fprintln(out, "no dead-lettered PRs")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	for _, l := range letters {
+		category := db.UnknownFailureCategory
+		if l.FailureCategory != nil {
+			category = *l.FailureCategory
+		}
+		fmt.Fprintf(w, "#%d\t%s\t%d attempts\t%s\n", l.PRNumber, l.DeadletteredAt.UTC().Format(time.RFC3339), l.Attempts, category)
+		for _, failure := range l.FailureChain {
+			fmt.Fprintf(w, "  %s\t\t\t\n", failure)
+		}
+	}
+}
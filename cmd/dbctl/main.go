@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -14,8 +15,12 @@ import (
 	"github.com/roivaz/aro-hcp-intelhub/internal/config"
 	"github.com/roivaz/aro-hcp-intelhub/internal/db"
 	dbmigrate "github.com/roivaz/aro-hcp-intelhub/internal/db/migrate"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
 )
 
+// sourceMigrationsDir is where migrate create writes when --migrations is unset.
+const sourceMigrationsDir = "internal/db/migrations"
+
 var rootCmd = &cobra.Command{
 	Use:   "dbctl",
 	Short: "Database schema management CLI",
@@ -26,7 +31,7 @@ var initCmd = &cobra.Command{
 	Short: "Initialize migration tables and extensions",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runWithDatabase(func(database *db.Database) error {
-			manager, err := dbmigrate.NewManager(database.Bun(), viper.GetString("db_migrations_dir"))
+			manager, err := newManager(database)
 			if err != nil {
 				return err
 			}
@@ -74,6 +79,35 @@ var migrateDownCmd = &cobra.Command{
 	},
 }
 
+var migrateCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Scaffold a new timestamped migration",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		goFormat, _ := cmd.Flags().GetBool("go")
+		sqlFormat, _ := cmd.Flags().GetBool("sql")
+		if goFormat && sqlFormat {
+			return errors.New("--sql and --go are mutually exclusive")
+		}
+		format := dbmigrate.FormatSQL
+		if goFormat {
+			format = dbmigrate.FormatGo
+		}
+		dir := migrationsDir()
+		if dir == "" {
+			dir = sourceMigrationsDir
+		}
+		paths, err := dbmigrate.Create(dir, args[0], format, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			fmt.Fprintf(cmd.OutOrStdout(), "created %s\n", p)
+		}
+		return nil
+	},
+}
+
 var statusCmd = &cobra.Command{
 	Use:           "status",
 	Short:         "Show applied and pending migrations",
@@ -113,6 +147,33 @@ var verifyCmd = &cobra.Command{
 	},
 }
 
+var diffCmd = &cobra.Command{
+	Use:           "diff",
+	Short:         "Report schema drift between the database and the models/migrations",
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fsys, err := dbmigrate.MigrationsFS(migrationsDir())
+		if err != nil {
+			return err
+		}
+		return runWithDatabase(func(database *db.Database) error {
+			drift, err := dbmigrate.Diff(cmd.Context(), database.Bun(), fsys, db.Models())
+			if err != nil {
+				return err
+			}
+			for _, d := range drift {
+				fmt.Fprintln(cmd.OutOrStdout(), d)
+			}
+			if len(drift) > 0 {
+				return fmt.Errorf("schema drift detected: %d difference(s)", len(drift))
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "no schema drift detected")
+			return nil
+		})
+	},
+}
+
 var recreateCmd = &cobra.Command{
 	Use:   "recreate <scope>",
 	Short: "Drop and recreate tables for a scope (destructive)",
@@ -142,14 +203,16 @@ func main() {
 	config.Init(rootCmd)
 
 	rootCmd.PersistentFlags().String("dsn", "", "PostgreSQL DSN (overrides POSTGRES_URL)")
-	rootCmd.PersistentFlags().String("migrations", "internal/db/migrations", "Migrations directory")
+	rootCmd.PersistentFlags().String("migrations", "", "Migrations directory (defaults to the migrations embedded in the binary)")
 	_ = viper.BindPFlag("postgres_url", rootCmd.PersistentFlags().Lookup("dsn"))
 	_ = viper.BindPFlag("db_migrations_dir", rootCmd.PersistentFlags().Lookup("migrations"))
 
-	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd)
-	rootCmd.AddCommand(initCmd, migrateCmd, statusCmd, verifyCmd, recreateCmd)
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateCreateCmd)
+	rootCmd.AddCommand(initCmd, migrateCmd, statusCmd, verifyCmd, diffCmd, recreateCmd, pruneCmd, deadletterCmd)
 	_ = migrateDownCmd.Flags().Int("steps", 1, "Number of migrations to roll back (0 = all)")
 	_ = migrateDownCmd.Flags().String("to", "", "Roll back to the specified migration (inclusive)")
+	_ = migrateCreateCmd.Flags().Bool("sql", false, "Create .up.sql/.down.sql files (default)")
+	_ = migrateCreateCmd.Flags().Bool("go", false, "Create a Go migration registered in the migrations package")
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "dbctl: %v\n", err)
@@ -165,7 +228,7 @@ func runWithDatabase(fn func(*db.Database) error) error {
 	if dsn == "" {
 		return errors.New("postgres DSN must be provided via flag or environment")
 	}
-	database, err := db.NewDatabase(db.Config{DSN: dsn})
+	database, err := db.NewDatabase(settings.Database(dsn))
 	if err != nil {
 		return err
 	}
@@ -176,11 +239,11 @@ func runWithDatabase(fn func(*db.Database) error) error {
 func recreateScope(ctx context.Context, bunDB *bun.DB, scope string) error {
 	switch scope {
 	case "all":
-		if _, err := bunDB.ExecContext(ctx, `DROP TABLE IF EXISTS documents, pr_embeddings, processing_state CASCADE`); err != nil {
+		if _, err := bunDB.ExecContext(ctx, `DROP TABLE IF EXISTS documents, pr_embeddings, pr_diff_chunks, pr_processing_deadletter, processing_state CASCADE`); err != nil {
 			return err
 		}
 	case "prs":
-		if _, err := bunDB.ExecContext(ctx, `DROP TABLE IF EXISTS pr_embeddings, processing_state CASCADE`); err != nil {
+		if _, err := bunDB.ExecContext(ctx, `DROP TABLE IF EXISTS pr_embeddings, pr_diff_chunks, pr_processing_deadletter, processing_state CASCADE`); err != nil {
 			return err
 		}
 	case "docs":
@@ -190,17 +253,15 @@ func recreateScope(ctx context.Context, bunDB *bun.DB, scope string) error {
 	default:
 		return fmt.Errorf("unknown scope: %s", scope)
 	}
-	return dbmigrate.EnsureCurrent(ctx, bunDB, viper.GetString("db_migrations_dir"), true)
+	return dbmigrate.EnsureCurrent(ctx, bunDB, migrationsDir(), true)
 }
 
 func newManager(database *db.Database) (*dbmigrate.Manager, error) {
 	return dbmigrate.NewManager(database.Bun(), migrationsDir())
 }
 
+// migrationsDir returns the --migrations override; empty selects the embedded
+// migrations.
 func migrationsDir() string {
-	dir := viper.GetString("db_migrations_dir")
-	if dir == "" {
-		dir = "internal/db/migrations"
-	}
-	return dir
+	return viper.GetString("db_migrations_dir")
 }
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/uptrace/bun"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+// pruneTarget is a table pruned by age, keyed by the column holding the
+// row's timestamp.
+type pruneTarget struct {
+	table  string
+	ageCol string
+	// key is referenced by the cascades tables, whose rows go with the
+	// pruned row through ON DELETE CASCADE.
+	key      string
+	cascades []string
+}
+
+var pruneScopes = map[string][]pruneTarget{
+	// Unmerged PRs have no merged_at and age from creation.
+	"prs": {{
+		table: "pr_embeddings", ageCol: "COALESCE(merged_at, created_at)",
+		key: "pr_number", cascades: []string{"pr_feedback", "pr_references", "pr_diff_chunks", "pr_processing_deadletter"},
+	}},
+	"traces": {{table: "trace_image_cache", ageCol: "inserted_at"}},
+	"evals":  {{table: "eval_runs", ageCol: "started_at"}},
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete rows older than a retention window (destructive)",
+	Long: `Delete rows older than --older-than from the tables in --scope.
+
+Scopes: prs (PR embeddings with their feedback, tickets, diff chunks and
+dead letters), traces (trace_images cache), evals (eval runs), all.
+Retention accepts a number followed by d (days), w (weeks), m (months) or y (years).
+Without --dry-run, DB_ALLOW_DESTRUCTIVE=yes must be set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		olderThan, _ := cmd.Flags().GetString("older-than")
+		scope, _ := cmd.Flags().GetString("scope")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		cutoff, err := retentionCutoff(olderThan, time.Now())
+		if err != nil {
+			return err
+		}
+		targets, err := pruneTargets(scope)
+		if err != nil {
+			return err
+		}
+		if !dryRun && strings.ToLower(os.Getenv("DB_ALLOW_DESTRUCTIVE")) != "yes" {
+			return errors.New("DB_ALLOW_DESTRUCTIVE=yes must be set for prune (or use --dry-run)")
+		}
+		return runWithDatabase(func(database *db.Database) error {
+			return pruneTables(cmd.Context(), database.Bun(), cmd.OutOrStdout(), targets, cutoff, dryRun)
+		})
+	},
+}
+
+func init() {
+	_ = pruneCmd.Flags().String("older-than", "", "Retention window, e.g. 90d, 18m, 2y (required)")
+	_ = pruneCmd.Flags().String("scope", "prs", "Data to prune: prs, traces, evals or all")
+	_ = pruneCmd.Flags().Bool("dry-run", false, "Report how many rows would be deleted without deleting them")
+	_ = pruneCmd.MarkFlagRequired("older-than")
+}
+
+func pruneTargets(scope string) ([]pruneTarget, error) {
+	if scope == "all" {
+		var targets []pruneTarget
+		for _, name := range []string{"prs", "traces", "evals"} {
+			targets = append(targets, pruneScopes[name]...)
+		}
+		return targets, nil
+	}
+	targets, ok := pruneScopes[scope]
+	if !ok {
+		return nil, fmt.Errorf("scope must be one of: prs, traces, evals, all")
+	}
+	return targets, nil
+}
+
+// retentionCutoff parses a retention window such as 90d or 18m and returns
+// the instant before which rows are pruned. Months and years use calendar
+// arithmetic; "m" is months, not minutes.
+func retentionCutoff(window string, now time.Time) (time.Time, error) {
+	window = strings.TrimSpace(strings.ToLower(window))
+	if len(window) < 2 {
+		return time.Time{}, fmt.Errorf("invalid retention %q: expected <n>d, <n>w, <n>m or <n>y", window)
+	}
+	n, err := strconv.Atoi(window[:len(window)-1])
+	if err != nil || n <= 0 {
+		return time.Time{}, fmt.Errorf("invalid retention %q: expected a positive number before the unit", window)
+	}
+	switch window[len(window)-1] {
+	case 'd':
+		return now.AddDate(0, 0, -n), nil
+	case 'w':
+		return now.AddDate(0, 0, -7*n), nil
+	case 'm':
+		return now.AddDate(0, -n, 0), nil
+	case 'y':
+		return now.AddDate(-n, 0, 0), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid retention %q: unit must be d, w, m or y", window)
+	}
+}
+
+// pruneTables deletes expired rows, and the rows cascading from them, from
+// every target in a single transaction and vacuums the affected tables
+// afterwards so HNSW indexes shrink.
+func pruneTables(ctx context.Context, bunDB *bun.DB, out io.Writer, targets []pruneTarget, cutoff time.Time, dryRun bool) error {
+	verb := "deleted"
+	if dryRun {
+		verb = "would delete"
+	}
+
+	var vacuum []string
+	err := bunDB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, t := range targets {
+			// Count the cascaded rows first; they are gone after the DELETE.
+			for _, child := range t.cascades {
+				var count int
+				if err := tx.NewRaw(fmt.Sprintf(`SELECT count(*) FROM %s WHERE %s IN (SELECT %s FROM %s WHERE %s < ?)`,
+					child, t.key, t.key, t.table, t.ageCol), cutoff).Scan(ctx, &count); err != nil {
+					return fmt.Errorf("count %s: %w", child, err)
+				}
+				if count > 0 && !dryRun {
+					vacuum = append(vacuum, child)
+				}
+				fmt.Fprintf(out, "%s: %s %d rows of %s older than %s\n", child, verb, count, t.table, cutoff.UTC().Format(time.RFC3339))
+			}
+			var count int
+			if dryRun {
+				if err := tx.NewRaw(fmt.Sprintf(`SELECT count(*) FROM %s WHERE %s < ?`, t.table, t.ageCol), cutoff).
+					Scan(ctx, &count); err != nil {
+					return fmt.Errorf("count %s: %w", t.table, err)
+				}
+			} else {
+				res, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s < ?`, t.table, t.ageCol), cutoff)
+				if err != nil {
+					return fmt.Errorf("prune %s: %w", t.table, err)
+				}
+				affected, _ := res.RowsAffected()
+				count = int(affected)
+				if count > 0 {
+					vacuum = append(vacuum, t.table)
+				}
+			}
+			fmt.Fprintf(out, "%s: %s %d rows older than %s\n", t.table, verb, count, cutoff.UTC().Format(time.RFC3339))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, table := range vacuum {
+		if _, err := bunDB.ExecContext(ctx, fmt.Sprintf(`VACUUM (ANALYZE) %s`, table)); err != nil {
+			return fmt.Errorf("vacuum %s: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/uptrace/bun"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	dbmigrate "github.com/roivaz/aro-hcp-intelhub/internal/db/migrate"
+	"github.com/roivaz/aro-hcp-intelhub/internal/ollama"
+)
+
+// Report is the dependency health snapshot printed by dbstatus.
+type Report struct {
+	Healthy    bool           `json:"healthy"`
+	Database   CheckResult    `json:"database"`
+	PGVector   string         `json:"pgvector_version,omitempty"`
+	Tables     []TableStatus  `json:"tables,omitempty"`
+	Indexes    []IndexStatus  `json:"indexes,omitempty"`
+	Migrations MigrationState `json:"migrations"`
+	Ollama     []OllamaStatus `json:"ollama"`
+}
+
+type CheckResult struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type TableStatus struct {
+	Name    string `json:"name"`
+	Present bool   `json:"present"`
+	Rows    int64  `json:"rows"`
+}
+
+type IndexStatus struct {
+	Name    string `json:"name"`
+	Table   string `json:"table"`
+	Present bool   `json:"present"`
+}
+
+type MigrationState struct {
+	CheckResult
+	Pending []string `json:"pending,omitempty"`
+}
+
+type OllamaStatus struct {
+	URL    string      `json:"url"`
+	Usage  string      `json:"usage"`
+	Model  string      `json:"model"`
+	Server CheckResult `json:"server"`
+	Found  bool        `json:"model_available"`
+}
+
+func main() {
+	var jsonOutput bool
+	var timeout time.Duration
+
+	root := &cobra.Command{
+		Use:           "dbstatus",
+		Short:         "Report database, schema and Ollama dependency health",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+
+			report := collect(ctx)
+			if jsonOutput {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(report); err != nil {
+					return err
+				}
+			} else {
+				printReport(cmd.OutOrStdout(), report)
+			}
+			if !report.Healthy {
+				return errors.New("one or more dependency checks failed")
+			}
+			return nil
+		},
+	}
+	config.Init(root)
+
+	root.Flags().BoolVar(&jsonOutput, "json", false, "Print the report as JSON")
+	root.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Overall timeout for all checks")
+	root.Flags().String("dsn", "", "PostgreSQL DSN (overrides POSTGRES_URL)")
+	root.Flags().String("migrations", "", "Migrations directory (defaults to the migrations embedded in the binary)")
+	_ = viper.BindPFlag("postgres_url", root.Flags().Lookup("dsn"))
+	_ = viper.BindPFlag("db_migrations_dir", root.Flags().Lookup("migrations"))
+
+	if err := root.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "dbstatus: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func collect(ctx context.Context) Report {
+	report := Report{Healthy: true}
+	checkDatabase(ctx, &report)
+	report.Ollama = checkOllama(ctx)
+
+	if !report.Database.OK || !report.Migrations.OK {
+		report.Healthy = false
+	}
+	for _, t := range report.Tables {
+		report.Healthy = report.Healthy && t.Present
+	}
+	for _, idx := range report.Indexes {
+		report.Healthy = report.Healthy && idx.Present
+	}
+	for _, o := range report.Ollama {
+		report.Healthy = report.Healthy && o.Server.OK && o.Found
+	}
+	return report
+}
+
+func checkDatabase(ctx context.Context, report *Report) {
+	fail := func(err error) CheckResult { return CheckResult{Error: err.Error()} }
+
+	dsn := viper.GetString("postgres_url")
+	if dsn == "" {
+		report.Database = fail(errors.New("postgres DSN must be provided via flag or environment"))
+		report.Migrations.CheckResult = fail(errors.New("database unavailable"))
+		return
+	}
+	database, err := db.NewDatabase(db.Config{DSN: dsn})
+	if err == nil {
+		err = database.Bun().PingContext(ctx)
+		defer database.Close()
+	}
+	if err != nil {
+		report.Database = fail(err)
+		report.Migrations.CheckResult = fail(errors.New("database unavailable"))
+		return
+	}
+	report.Database.OK = true
+	bunDB := database.Bun()
+
+	var version string
+	if err := bunDB.NewRaw(`SELECT extversion FROM pg_extension WHERE extname = 'vector'`).Scan(ctx, &version); err == nil {
+		report.PGVector = version
+	}
+
+	fsys, err := dbmigrate.MigrationsFS(viper.GetString("db_migrations_dir"))
+	if err != nil {
+		report.Migrations.CheckResult = fail(err)
+		return
+	}
+	tables, indexes, err := dbmigrate.SchemaObjects(fsys)
+	if err != nil {
+		report.Migrations.CheckResult = fail(err)
+		return
+	}
+	report.Tables = tableStatus(ctx, bunDB, tables)
+	report.Indexes = indexStatus(ctx, bunDB, indexes)
+	report.Migrations = migrationState(ctx, bunDB, fsys)
+}
+
+func tableStatus(ctx context.Context, bunDB *bun.DB, tables []string) []TableStatus {
+	out := make([]TableStatus, 0, len(tables))
+	for _, name := range tables {
+		status := TableStatus{Name: name}
+		var regclass *string
+		if err := bunDB.NewRaw(`SELECT to_regclass(?)::text`, name).Scan(ctx, &regclass); err == nil && regclass != nil {
+			status.Present = true
+			_ = bunDB.NewRaw(`SELECT count(*) FROM ?`, bun.Ident(name)).Scan(ctx, &status.Rows)
+		}
+		out = append(out, status)
+	}
+	return out
+}
+
+func indexStatus(ctx context.Context, bunDB *bun.DB, indexes map[string]string) []IndexStatus {
+	var live []string
+	_ = bunDB.NewRaw(`SELECT indexname FROM pg_indexes WHERE schemaname = current_schema()`).Scan(ctx, &live)
+	present := make(map[string]bool, len(live))
+	for _, name := range live {
+		present[name] = true
+	}
+
+	out := make([]IndexStatus, 0, len(indexes))
+	for name, table := range indexes {
+		out = append(out, IndexStatus{Name: name, Table: table, Present: present[name]})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func migrationState(ctx context.Context, bunDB *bun.DB, fsys fs.FS) MigrationState {
+	var state MigrationState
+	manager, err := dbmigrate.NewManagerWithFS(bunDB, fsys)
+	if err != nil {
+		state.Error = err.Error()
+		return state
+	}
+	status, err := manager.Status(ctx)
+	if err != nil {
+		state.Error = fmt.Sprintf("fetch migration status: %v", err)
+		return state
+	}
+	for _, mig := range status {
+		if !mig.IsApplied() {
+			state.Pending = append(state.Pending, fmt.Sprintf("%s_%s", mig.Name, mig.Comment))
+		}
+	}
+	state.OK = len(state.Pending) == 0
+	if !state.OK {
+		state.Error = "pending migrations; run 'dbctl migrate up'"
+	}
+	return state
+}
+
+// checkOllama verifies every Ollama server/model pair the services depend on.
+func checkOllama(ctx context.Context) []OllamaStatus {
+	targets := []OllamaStatus{{URL: config.OllamaURL(), Usage: "embeddings", Model: config.EmbeddingModel()}}
+	if config.DiffAnalysisEnabled() {
+		targets = append(targets, OllamaStatus{URL: config.DiffAnalysisOllamaURL(), Usage: "diff analysis", Model: config.DiffAnalysisModel()})
+	}
+
+	cache := make(map[string][]string)
+	errs := make(map[string]error)
+	for i := range targets {
+		t := &targets[i]
+		if _, seen := cache[t.URL]; !seen && errs[t.URL] == nil {
+			cache[t.URL], errs[t.URL] = ollama.AvailableModels(ctx, t.URL)
+		}
+		if err := errs[t.URL]; err != nil {
+			t.Server.Error = err.Error()
+			continue
+		}
+		t.Server.OK = true
+		t.Found = ollama.HasModel(cache[t.URL], t.Model)
+	}
+	return targets
+}
+
+func printReport(out io.Writer, r Report) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "postgres\t%s\n", okOr(r.Database))
+	if r.Database.OK {
+		pgvector := r.PGVector
+		if pgvector == "" {
+			pgvector = "NOT INSTALLED"
+		}
+		fmt.Fprintf(w, "pgvector\t%s\n", pgvector)
+	}
+	fmt.Fprintf(w, "migrations\t%s\n", okOr(r.Migrations.CheckResult))
+	for _, p := range r.Migrations.Pending {
+		fmt.Fprintf(w, "  pending\t%s\n", p)
+	}
+	for _, t := range r.Tables {
+		state := fmt.Sprintf("%d rows", t.Rows)
+		if !t.Present {
+			state = "MISSING"
+		}
+		fmt.Fprintf(w, "table %s\t%s\n", t.Name, state)
+	}
+	for _, idx := range r.Indexes {
+		state := "ok"
+		if !idx.Present {
+			state = "MISSING"
+		}
+		fmt.Fprintf(w, "index %s\t%s\n", idx.Name, state)
+	}
+	for _, o := range r.Ollama {
+		state := okOr(o.Server)
+		if o.Server.OK && !o.Found {
+			state = fmt.Sprintf("model %s not pulled", o.Model)
+		}
+		fmt.Fprintf(w, "ollama %s (%s, %s)\t%s\n", o.Usage, o.URL, o.Model, state)
+	}
+	verdict := "healthy"
+	if !r.Healthy {
+		verdict = "UNHEALTHY"
+	}
+	fmt.Fprintf(w, "overall\t%s\n", verdict)
+}
+
+func okOr(c CheckResult) string {
+	if c.OK {
+		return "ok"
+	}
+	return "FAIL: " + c.Error
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+)
+
+func newCacheGCCmd() *cobra.Command {
+	var maxIdle time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "cache-gc",
+		Short: "Delete cached clones not used recently and run git gc on the rest",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gc := &gitrepo.CacheGC{Dir: config.CacheDir(), MaxIdle: maxIdle, Backend: config.GitBackend()}
+			res, err := gc.RunOnce(cmd.Context())
+			for _, dir := range res.Removed {
+				fmt.Fprintf(cmd.OutOrStdout(), "removed %s\n", dir)
+			}
+			for _, dir := range res.Compacted {
+				fmt.Fprintf(cmd.OutOrStdout(), "compacted %s\n", dir)
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().DurationVar(&maxIdle, "max-idle", config.GitCacheMaxIdle(), "Delete clones unused for this long (0 keeps every clone)")
+	return cmd
+}
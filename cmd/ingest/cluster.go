@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/jobs"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
+	"github.com/roivaz/aro-hcp-intelhub/internal/topics"
+)
+
+func newClusterCmd() *cobra.Command {
+	var (
+		params topics.JobParams
+		async  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cluster",
+		Short: "Group embedded PRs into topics and name them",
+		Long: `Cluster the embeddings of every PR processed with EMBEDDING_MODEL_NAME
+using k-means (cosine similarity), name each cluster with DIFF_ANALYSIS_MODEL
+from the titles of its most central PRs, and replace the topics browsed with
+the list_pr_topics MCP tool. --k defaults to sqrt(PRs/2), between 2 and 50.
+With --async the run is queued as a job for a worker ('ingest worker' or the
+MCP server) and followed with 'ingest jobs'.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.NewDatabase(settings.Database(config.PostgresURL()))
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			model := config.EmbeddingModel()
+			repo := db.NewSearchRepository(database, db.WithEmbeddingModel(model, config.EmbeddingDimension()))
+
+			if async {
+				job, err := repo.EnqueueJob(cmd.Context(), jobs.KindCluster, params)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "queued cluster job %s\n", job.ID)
+				return nil
+			}
+
+			opts := topics.Options{K: params.K, Iterations: params.Iterations, Seed: params.Seed, Samples: params.Samples}
+			if !params.NoLabels {
+				if opts.Labeler, err = newLabeler(config.DiffAnalysisModel()); err != nil {
+					return err
+				}
+			}
+			found, prs, err := topics.Cluster(cmd.Context(), repo, model, opts)
+			if err != nil {
+				return err
+			}
+			printTopics(cmd.OutOrStdout(), found)
+			fmt.Fprintf(cmd.ErrOrStderr(), "stored %d topics over %d PRs\n", len(found), prs)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&params.K, "k", 0, "Number of topics (0 = sqrt(PRs/2), between 2 and 50)")
+	cmd.Flags().IntVar(&params.Iterations, "iterations", topics.DefaultJobParams.Iterations, "Maximum k-means iterations")
+	cmd.Flags().Uint64Var(&params.Seed, "seed", topics.DefaultJobParams.Seed, "Random seed; the same seed and PRs give the same topics")
+	cmd.Flags().IntVar(&params.Samples, "samples", topics.DefaultJobParams.Samples, "Titles shown to the LLM when naming a topic")
+	cmd.Flags().BoolVar(&params.NoLabels, "no-labels", false, "Name topics after their most central PR instead of calling the LLM")
+	cmd.Flags().BoolVar(&async, "async", false, "Queue the run as a job instead of running it")
+	return cmd
+}
+
+// newLabeler names topics with model, DIFF_ANALYSIS_MODEL unless overridden.
+func newLabeler(model string) (*topics.Labeler, error) {
+	timeout, err := time.ParseDuration(config.LLMCallTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("invalid llm_call_timeout: %w", err)
+	}
+	return topics.NewLabeler(config.DiffAnalysisOllamaURL(), model, timeout)
+}
+
+func printTopics(out io.Writer, found []db.PRTopic) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPRS\tLABEL")
+	for _, t := range found {
+		fmt.Fprintf(w, "%d\t%d\t%s\n", t.ID, t.Size, t.Label)
+	}
+	w.Flush()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+
+	vcsurl "github.com/gitsight/go-vcsurl"
+	"github.com/spf13/cobra"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/docs"
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion"
+	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion/embeddings"
+	"github.com/roivaz/aro-hcp-intelhub/internal/progress"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
+)
+
+func newCodeCmd() *cobra.Command {
+	var (
+		repoURLs     []string
+		ref          string
+		includePaths []string
+		exclude      []string
+		maxFiles     int
+		maxChunks    int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "code",
+		Short: "Ingest Go and TypeScript declarations into code_chunks for search_code",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			include := []string{"**/*.go", "**/*.ts", "**/*.tsx"}
+			if len(includePaths) > 0 {
+				include = nil
+				for _, p := range includePaths {
+					p = filepath.ToSlash(filepath.Clean(p))
+					include = append(include, p+"/**/*.go", p+"/**/*.ts", p+"/**/*.tsx")
+				}
+			}
+			return runCodeIngester(cmd, repoURLs, ref, docs.CodeIngester{
+				Include:   include,
+				Exclude:   append([]string{"**/vendor/**", "**/node_modules/**", "**/zz_generated*", "**/*.pb.go"}, exclude...),
+				MaxFiles:  maxFiles,
+				MaxChunks: maxChunks,
+				Languages: docs.CodeLanguages,
+			})
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&repoURLs, "repo-url", nil, "Repo URL to ingest (repeat; default: the cached ARO-HCP clone)")
+	cmd.Flags().StringVar(&ref, "ref", "HEAD", "Revision to ingest")
+	cmd.Flags().StringArrayVar(&includePaths, "include-path", nil, "Only ingest files under this path (repeat)")
+	cmd.Flags().StringArrayVar(&exclude, "exclude", nil, "Additional glob of files to skip (repeat), e.g. '**/*_test.go'")
+	cmd.Flags().IntVar(&maxFiles, "max-files", 2000, "Maximum files per repo (0 = unlimited)")
+	cmd.Flags().IntVar(&maxChunks, "max-chunks", 10000, "Maximum declarations per repo (0 = unlimited)")
+	return cmd
+}
+
+func newConfigCmd() *cobra.Command {
+	var (
+		repoURLs     []string
+		ref          string
+		includePaths []string
+		exclude      []string
+		maxFiles     int
+		maxChunks    int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Ingest YAML, Helm and Bicep configuration into code_chunks for search_config",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(includePaths) == 0 {
+				includePaths = []string{"config", "dev-infrastructure"}
+			}
+			// Pipeline definitions live next to each component.
+			include := []string{"**/pipeline.yaml", "**/pipeline.yml"}
+			for _, p := range includePaths {
+				p = filepath.ToSlash(filepath.Clean(p))
+				include = append(include, p+"/**/*.yaml", p+"/**/*.yml", p+"/**/*.bicep", p+"/**/*.bicepparam")
+			}
+			return runCodeIngester(cmd, repoURLs, ref, docs.CodeIngester{
+				Include:   include,
+				Exclude:   append([]string{"**/vendor/**", "**/node_modules/**", "**/testdata/**"}, exclude...),
+				MaxFiles:  maxFiles,
+				MaxChunks: maxChunks,
+				Languages: docs.ConfigLanguages,
+			})
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&repoURLs, "repo-url", nil, "Repo URL to ingest (repeat; default: the cached ARO-HCP clone)")
+	cmd.Flags().StringVar(&ref, "ref", "HEAD", "Revision to ingest")
+	cmd.Flags().StringArrayVar(&includePaths, "include-path", nil, "Ingest YAML and Bicep files under this path (repeat; default: config and dev-infrastructure)")
+	cmd.Flags().StringArrayVar(&exclude, "exclude", nil, "Additional glob of files to skip (repeat), e.g. 'config/rendered/**'")
+	cmd.Flags().IntVar(&maxFiles, "max-files", 2000, "Maximum files per repo (0 = unlimited)")
+	cmd.Flags().IntVar(&maxChunks, "max-chunks", 20000, "Maximum blocks per repo (0 = unlimited)")
+	cmd.AddCommand(newConfigValidateCmd())
+	return cmd
+}
+
+// runCodeIngester completes ing with the repository, embedding client and
+// model from the environment and ingests repoURLs at ref, or the cached
+// ARO-HCP clone when none are given.
+func runCodeIngester(cmd *cobra.Command, repoURLs []string, ref string, ing docs.CodeIngester) error {
+	cfg, err := ingestion.LoadConfig()
+	if err != nil {
+		return err
+	}
+	database, err := db.NewDatabase(settings.Database(cfg.PostgresURL))
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	repo := db.NewSearchRepository(database,
+		db.WithEmbeddingModel(cfg.EmbeddingModel, cfg.EmbeddingDim),
+		db.WithQuantization(cfg.Quantization, cfg.RerankCandidates),
+		db.WithDistanceMetric(cfg.DistanceMetric))
+	embedClient, err := ingestion.NewEmbedder(cfg, embeddings.WithAutoPull(cfg.OllamaAutoPull))
+	if err != nil {
+		return err
+	}
+	if err := repo.RegisterEmbeddingModel(cmd.Context()); err != nil {
+		return err
+	}
+	ing.Repo = repo
+	ing.Git = settings.Git()
+	ing.Client = embedClient
+	ing.ModelName = cfg.EmbeddingModel
+	ing.Progress = progress.New("code")
+
+	var repos []docs.RepoSpec
+	for _, url := range repoURLs {
+		surl, err := vcsurl.Parse(url)
+		if err != nil {
+			return fmt.Errorf("doesn't look like a VCS URL: %w", err)
+		}
+		localPath := filepath.Join(config.CacheDir(), surl.Name)
+		gr := gitrepo.New(gitrepo.RepoConfig{Settings: ing.Git, URL: url, Path: localPath})
+		if _, err := gr.Ensure(cmd.Context()); err != nil {
+			log.Printf("ensure clone for %s: %s", url, err)
+			continue
+		}
+		repos = append(repos, docs.RepoSpec{Name: surl.FullName, Path: localPath, Ref: ref})
+	}
+	if len(repoURLs) == 0 {
+		repos = []docs.RepoSpec{{Name: cfg.RepoName(), Path: cfg.LocalRepoPath, Ref: ref}}
+	}
+	return ing.Run(cmd.Context(), repos)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/controller"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
+)
+
+func newControllerCmd() *cobra.Command {
+	var (
+		specPath string
+		resync   time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "controller",
+		Short: "Keep ingestion in line with a spec file, such as a mounted ConfigMap",
+		Long: `Read the ingestion spec at --spec every --resync (Kubernetes updates a
+mounted ConfigMap in place) and queue the jobs it calls for: PR ingestion,
+docs ingestion of the listed repos and topic clustering, each on its
+schedule, and again as soon as the spec's models (or, for docs, repos)
+change. The jobs run in a worker started with the spec's models, restarted
+when they change. See examples/ingestion-spec.yaml.
+
+Leave JOBS_WORKER unset on the MCP server, so that only this worker runs the
+queued ingestion and cluster jobs, with the spec's models.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := ingestion.LoadConfig()
+			if err != nil {
+				return err
+			}
+			database, err := db.NewDatabase(settings.Database(cfg.PostgresURL))
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			var (
+				stopWorker context.CancelFunc
+				workerDone chan struct{}
+			)
+			stop := func() {
+				if stopWorker != nil {
+					stopWorker()
+					<-workerDone
+				}
+			}
+			defer stop()
+			ctrl := &controller.Controller{
+				Store:    db.NewSearchRepository(database),
+				SpecPath: specPath,
+				Resync:   resync,
+				// A job interrupted by a restart is requeued once stale.
+				Apply: func(ctx context.Context, spec *controller.Spec) error {
+					worker, err := newJobsWorker(spec.Models.Config(cfg), database)
+					if err != nil {
+						return err
+					}
+					stop()
+					workerCtx, cancelWorker := context.WithCancel(ctx)
+					done := make(chan struct{})
+					go func() {
+						defer close(done)
+						worker.Run(workerCtx)
+					}()
+					stopWorker, workerDone = cancelWorker, done
+					return nil
+				},
+			}
+			return ctrl.Run(ctx)
+		},
+	}
+	cmd.Flags().StringVar(&specPath, "spec", "/etc/intelhub/ingestion.yaml", "Ingestion spec: models, schedules and docs repos")
+	cmd.Flags().DurationVar(&resync, "resync", 30*time.Second, "How often the spec is re-read and the schedules checked")
+	return cmd
+}
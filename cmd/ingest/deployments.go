@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
+	"github.com/roivaz/aro-hcp-intelhub/internal/traceimages"
+)
+
+const (
+	deploymentSourcePoll    = "poll"
+	deploymentSourceWebhook = "webhook"
+)
+
+var fullCommitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// deploymentEvent is the webhook payload: an environment started running an
+// ARO-HCP commit. DeployedAt defaults to the time the event is received.
+type deploymentEvent struct {
+	Environment string     `json:"environment"`
+	CommitSHA   string     `json:"commit_sha"`
+	DeployedAt  *time.Time `json:"deployed_at,omitempty"`
+}
+
+func newDeploymentsCmd() *cobra.Command {
+	var (
+		interval     time.Duration
+		listen       string
+		environments []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "deployments",
+		Short: "Record which ARO-HCP commit each environment runs",
+		Long: `Record deployments into the deployments table.
+
+By default the command polls once: it traces the images configured for each
+environment on origin/main and records the ARO-HCP commit they were built
+from whenever it changed. --interval keeps polling. --listen additionally
+accepts POST /deployments webhooks with {"environment", "commit_sha",
+"deployed_at"}, authenticated with the DEPLOYMENTS_WEBHOOK_TOKEN bearer token.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, env := range environments {
+				if !validEnvironment(env) {
+					return fmt.Errorf("unknown environment %q (valid: %s)", env, strings.Join(traceimages.Environments(), ", "))
+				}
+			}
+			if listen != "" && config.DeploymentsWebhookToken() == "" {
+				return fmt.Errorf("--listen requires DEPLOYMENTS_WEBHOOK_TOKEN")
+			}
+
+			database, err := db.NewDatabase(settings.Database(config.PostgresURL()))
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			repo := db.NewSearchRepository(database,
+				db.WithTraceCacheMax(config.TraceCacheMaxEntries()),
+				db.WithTraceCacheTTL(config.TraceCacheTTL()))
+
+			ctx, cancel := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			if listen != "" {
+				srv := &http.Server{Addr: listen, Handler: deploymentsWebhook(repo, config.DeploymentsWebhookToken())}
+				go func() {
+					<-ctx.Done()
+					_ = srv.Shutdown(context.Background())
+				}()
+				go func() {
+					log.Printf("deployments: webhook listening on %s", listen)
+					if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						log.Printf("deployments: webhook server: %v", err)
+						cancel()
+					}
+				}()
+			}
+
+			poller, err := newDeploymentPoller(repo)
+			if err != nil {
+				return err
+			}
+			for {
+				poller.poll(ctx, environments)
+				if interval <= 0 && listen == "" {
+					return nil
+				}
+				wait := interval
+				if wait <= 0 {
+					wait = 24 * time.Hour // webhook only after the initial poll
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(wait):
+				}
+			}
+		},
+	}
+
+	cmd.Flags().DurationVar(&interval, "interval", 0, "Keep polling at this interval (0 = poll once)")
+	cmd.Flags().StringVar(&listen, "listen", "", "Address to serve the deployment webhook on (e.g. :8090)")
+	cmd.Flags().StringSliceVar(&environments, "environments", traceimages.Environments(), "Environments to poll")
+	return cmd
+}
+
+type deploymentPoller struct {
+	repo    *db.SearchRepository
+	git     *gitrepo.Repo
+	service *traceimages.Service
+}
+
+func newDeploymentPoller(repo *db.SearchRepository) (*deploymentPoller, error) {
+	baseLogger := logging.DefaultLogger()
+	repoPath := filepath.Join(config.CacheDir(), "aro-hcp-repo")
+	tracer, err := traceimages.NewTracer(traceimages.Config{
+		RepoPath:           repoPath,
+		RepoURL:            config.RepoURL(),
+		SkopeoPath:         config.TraceSkopeoPath(),
+		PullSecret:         config.TracePullSecret(),
+		MaxTagCandidates:   config.TraceMaxTagCandidates(),
+		SkopeoOnly:         config.TraceSkopeoOnly(),
+		InspectConcurrency: config.TraceInspectConcurrency(),
+		InspectTimeout:     config.TraceInspectTimeout(),
+		RegistryTimeout:    config.TraceRegistryTimeout(),
+		RegistryCooldown:   config.TraceRegistryCooldown(),
+		WorktreePoolSize:   config.TraceWorktreePoolSize(),
+		RenderCommand:      config.TraceRenderCommand(),
+		RenderTimeout:      config.TraceRenderTimeout(),
+		SyftPath:           config.TraceSyftPath(),
+		Git:                settings.Git(),
+		Logger:             logging.New(baseLogger.WithName("trace")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("init tracer: %w", err)
+	}
+	return &deploymentPoller{
+		repo:    repo,
+		git:     gitrepo.New(gitrepo.RepoConfig{Settings: settings.Git(), URL: config.RepoURL(), Path: repoPath}),
+		service: traceimages.New(tracer, repo, logging.New(baseLogger.WithName("traceimages"))),
+	}, nil
+}
+
+// poll records the commit each environment runs according to origin/main.
+// Failures are logged per environment so one bad trace does not block the rest.
+func (p *deploymentPoller) poll(ctx context.Context, environments []string) {
+	if _, err := p.git.Ensure(ctx); err != nil {
+		log.Printf("deployments: update repo: %v", err)
+		return
+	}
+	head, err := p.git.ResolveRef(ctx, "main")
+	if err != nil {
+		log.Printf("deployments: resolve main: %v", err)
+		return
+	}
+	now := time.Now().UTC()
+	for _, env := range environments {
+		resp, err := p.service.TraceImages(ctx, head, env, false, false)
+		if err != nil {
+			log.Printf("deployments: trace %s at %s: %v", env, head, err)
+			continue
+		}
+		sha, ok := traceimages.DeployedCommit(resp, config.RepoURL())
+		if !ok {
+			log.Printf("deployments: no ARO-HCP source commit found for %s at %s", env, head)
+			continue
+		}
+		inserted, err := p.repo.RecordDeployment(ctx, &db.Deployment{
+			Environment: env,
+			CommitSHA:   sha,
+			DeployedAt:  now,
+			Source:      deploymentSourcePoll,
+		})
+		if err != nil {
+			log.Printf("deployments: record %s: %v", env, err)
+			continue
+		}
+		if inserted {
+			log.Printf("deployments: %s now runs %s", env, sha)
+		}
+	}
+}
+
+// deploymentsWebhook serves POST /deployments. Every request must carry
+// token, which must not be empty.
+func deploymentsWebhook(repo *db.SearchRepository, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /deployments", func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var event deploymentEvent
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&event); err != nil {
+			http.Error(w, fmt.Sprintf("invalid payload: %v", err), http.StatusBadRequest)
+			return
+		}
+		sha := strings.ToLower(strings.TrimSpace(event.CommitSHA))
+		if !validEnvironment(event.Environment) || !fullCommitSHA.MatchString(sha) {
+			http.Error(w, "environment must be one of "+strings.Join(traceimages.Environments(), ", ")+" and commit_sha a full 40-character hex SHA", http.StatusBadRequest)
+			return
+		}
+		deployedAt := time.Now().UTC()
+		if event.DeployedAt != nil {
+			deployedAt = event.DeployedAt.UTC()
+		}
+		inserted, err := repo.RecordDeployment(r.Context(), &db.Deployment{
+			Environment: event.Environment,
+			CommitSHA:   sha,
+			DeployedAt:  deployedAt,
+			Source:      deploymentSourceWebhook,
+		})
+		if err != nil {
+			log.Printf("deployments: record webhook event: %v", err)
+			http.Error(w, "failed to record deployment", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]bool{"recorded": inserted})
+	})
+	return mux
+}
+
+func validEnvironment(env string) bool {
+	for _, known := range traceimages.Environments() {
+		if env == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/report"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
+	"github.com/roivaz/aro-hcp-intelhub/internal/traceimages"
+)
+
+func newExportAnalysisCmd() *cobra.Command {
+	var (
+		prNumber int
+		format   string
+		output   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export-analysis",
+		Short: "Render a stored PR analysis as Slack, Markdown or HTML",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if prNumber <= 0 {
+				return fmt.Errorf("--pr is required")
+			}
+
+			database, err := db.NewDatabase(settings.Database(config.PostgresURL()))
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			repo := db.NewSearchRepository(database, db.WithRepoURL(config.RepoURL()))
+
+			r, err := buildPRReport(cmd.Context(), repo, prNumber)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+			return report.Render(out, format, r)
+		},
+	}
+
+	cmd.Flags().IntVar(&prNumber, "pr", 0, "PR number to export")
+	cmd.Flags().StringVar(&format, "format", report.FormatMarkdown, "Output format: slack, markdown or html")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write to this file instead of stdout")
+	return cmd
+}
+
+// buildPRReport gathers the stored PR, its changed files from the local
+// ARO-HCP clone and any cached image traces of its merge commit. Git and
+// trace lookups are best-effort and only logged on failure.
+func buildPRReport(ctx context.Context, repo *db.SearchRepository, number int) (report.PRReport, error) {
+	pr, err := repo.GetPRByNumber(ctx, number)
+	if err != nil {
+		return report.PRReport{}, err
+	}
+	if pr == nil {
+		return report.PRReport{}, fmt.Errorf("PR #%d is not ingested; run 'ingest prs' first", number)
+	}
+
+	result := repo.ToPRResult(*pr, nil)
+	analysis := db.ToPRAnalysis(*pr)
+	r := report.PRReport{
+		Number:         pr.PRNumber,
+		Title:          pr.PRTitle,
+		Author:         pr.Author,
+		URL:            result.GithubURL,
+		MergedAt:       pr.MergedAt,
+		AnalysisStatus: analysis.Status,
+	}
+	if pr.RichDescription != nil {
+		r.RichDescription = *pr.RichDescription
+	}
+	if pr.MergeCommitSHA == nil {
+		return r, nil
+	}
+	r.MergeCommitSHA = *pr.MergeCommitSHA
+	r.CommitURL = repo.CommitURL(r.MergeCommitSHA)
+
+	git := gitrepo.New(gitrepo.RepoConfig{Settings: settings.Git(), URL: config.RepoURL(), Path: filepath.Join(config.CacheDir(), "aro-hcp-repo")})
+	files, err := git.ChangedFiles(ctx, r.MergeCommitSHA)
+	if err != nil {
+		if _, ensureErr := git.Ensure(ctx); ensureErr == nil {
+			files, err = git.ChangedFiles(ctx, r.MergeCommitSHA)
+		}
+	}
+	if err != nil {
+		log.Printf("export: changed files for %s: %v", r.MergeCommitSHA, err)
+	} else {
+		r.FilesByComponent = make(map[string]int)
+		for _, f := range files {
+			component, _, found := strings.Cut(f, "/")
+			if !found {
+				component = "(root)"
+			}
+			r.FilesByComponent[component]++
+		}
+	}
+
+	for _, env := range traceimages.Environments() {
+		entry, err := repo.TraceImageCacheGet(ctx, r.MergeCommitSHA, env)
+		if err != nil {
+			log.Printf("export: trace cache %s: %v", env, err)
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+		link := report.TraceLink{Environment: env}
+		for _, comp := range entry.Response.Components {
+			if comp.Digest == "" {
+				continue
+			}
+			link.Images = append(link.Images, fmt.Sprintf("%s@%s", comp.Name, shortDigest(comp.Digest)))
+		}
+		r.Traces = append(r.Traces, link)
+	}
+	return r, nil
+}
+
+func shortDigest(digest string) string {
+	algo, hex, found := strings.Cut(digest, ":")
+	if !found || len(hex) <= 12 {
+		return digest
+	}
+	return algo + ":" + hex[:12]
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
+)
+
+func newFailuresCmd() *cobra.Command {
+	var (
+		category string
+		limit    int
+		requeue  []int
+		asJSON   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "failures",
+		Short: "List PRs whose analysis failed, grouped by failure category",
+		Long: `List PRs whose diff analysis failed, grouped by failure category with the
+largest group first. --requeue marks the given failed PRs unprocessed so the
+next 'ingest prs' run in PROCESS or FULL mode retries them.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.NewDatabase(settings.Database(config.PostgresURL()))
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			repo := db.NewSearchRepository(database, db.WithRepoURL(config.RepoURL()))
+
+			if len(requeue) > 0 {
+				requeued, err := repo.RequeuePRs(cmd.Context(), requeue)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "requeued %d of %d PRs: %v\n", len(requeued), len(requeue), requeued)
+			}
+
+			groups, err := repo.FailedAnalyses(cmd.Context(), category, limit)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				out := make([]any, 0, len(groups))
+				for _, g := range groups {
+					out = append(out, repo.ToFailureGroup(g))
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(out)
+			}
+			printFailures(cmd.OutOrStdout(), groups)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&category, "category", "", "Only report this failure category (e.g. timeout, large_diff, error)")
+	cmd.Flags().IntVar(&limit, "limit", 20, "Maximum PRs listed per category")
+	cmd.Flags().IntSliceVar(&requeue, "requeue", nil, "Failed PR numbers to mark for reprocessing (repeat or comma-separate)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the groups as JSON")
+	return cmd
+}
+
+func printFailures(out io.Writer, groups []db.FailureGroup) {
+	if len(groups) == 0 {
+		fmt.Fprintln(out, "no failed analyses")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	for _, g := range groups {
+		fmt.Fprintf(w, "%s\t%d failed\n", g.Category, g.Count)
+		for _, pr := range g.PRs {
+			processed := ""
+			if pr.ProcessedAt != nil {
+				processed = pr.ProcessedAt.UTC().Format(time.RFC3339)
+			}
+			reason := ""
+			if pr.FailureReason != nil {
+				reason = truncateText(*pr.FailureReason, 80)
+			}
+			fmt.Fprintf(w, "  #%d\t%s\t%s\t%s\n", pr.PRNumber, processed, truncateText(pr.PRTitle, 60), reason)
+		}
+		if more := g.Count - len(g.PRs); more > 0 {
+			fmt.Fprintf(w, "  ... %d more\t\t\t\n", more)
+		}
+	}
+}
+
+func truncateText(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
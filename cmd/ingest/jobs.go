@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/controller"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion"
+	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion/embeddings"
+	"github.com/roivaz/aro-hcp-intelhub/internal/jobs"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
+	"github.com/roivaz/aro-hcp-intelhub/internal/topics"
+)
+
+func newJobsCmd() *cobra.Command {
+	var (
+		kind  string
+		limit int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "jobs [job-id]",
+		Short: "List background jobs and their progress",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.NewDatabase(settings.Database(config.PostgresURL()))
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			repo := db.NewSearchRepository(database)
+
+			var found []db.Job
+			if len(args) == 1 {
+				job, err := repo.GetJob(cmd.Context(), args[0])
+				if err != nil {
+					return err
+				}
+				if job == nil {
+					return fmt.Errorf("job %s not found", args[0])
+				}
+				found = []db.Job{*job}
+			} else if found, err = repo.ListJobs(cmd.Context(), kind, limit); err != nil {
+				return err
+			}
+			printJobs(cmd.OutOrStdout(), found)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&kind, "kind", "", "Only list jobs of this kind (ingestion, cluster)")
+	cmd.Flags().IntVar(&limit, "limit", 20, "Jobs to list, newest first")
+	return cmd
+}
+
+func printJobs(out io.Writer, found []db.Job) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "ID\tKIND\tSTATUS\tPROGRESS\tATTEMPTS\tCREATED\tERROR")
+	for _, j := range found {
+		progress := "-"
+		if j.Phase != nil {
+			progress = fmt.Sprintf("%s %d/%d", *j.Phase, j.Done, j.Total)
+		}
+		errMsg := ""
+		if j.Error != nil {
+			errMsg = *j.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", j.ID, j.Kind, j.Status, progress, j.Attempts, j.CreatedAt.UTC().Format(time.RFC3339), errMsg)
+	}
+}
+
+func newWorkerCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "worker",
+		Short: "Run queued background jobs until interrupted",
+		Long: `Claim and run queued jobs (trigger_ingestion runs, 'ingest cluster
+--async', and the jobs of 'ingest controller'), one at a time, until SIGINT
+or SIGTERM. Without it or a controller, queued jobs only run in an MCP
+server with JOBS_WORKER=true; several workers may share the queue.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := ingestion.LoadConfig()
+			if err != nil {
+				return err
+			}
+			database, err := db.NewDatabase(settings.Database(cfg.PostgresURL))
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			worker, err := newJobsWorker(cfg, database)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+			worker.Run(ctx)
+			return nil
+		},
+	}
+}
+
+// newJobsWorker returns a worker running every job kind with the models of
+// cfg: ingestion runs, topic clustering and, for the controller, docs.
+func newJobsWorker(cfg ingestion.Config, database *db.Database) (*jobs.Worker, error) {
+	repo := db.NewSearchRepository(database,
+		db.WithEmbeddingModel(cfg.EmbeddingModel, cfg.EmbeddingDim),
+		db.WithQuantization(cfg.Quantization, cfg.RerankCandidates),
+		db.WithDistanceMetric(cfg.DistanceMetric),
+		db.WithMaxProcessingAttempts(cfg.MaxAttempts))
+	embedClient, err := ingestion.NewEmbedder(cfg, embeddings.WithAutoPull(cfg.OllamaAutoPull))
+	if err != nil {
+		return nil, err
+	}
+	fetcher := cfg.GitHubFetcher()
+	runManager := ingestion.NewRunManager(cfg, repo, func(cfg ingestion.Config) *ingestion.Generator {
+		return ingestion.NewGenerator(cfg, database, repo, embedClient, fetcher)
+	})
+	labeler, err := newLabeler(cfg.DiffAnalyzer.ModelName)
+	if err != nil {
+		return nil, err
+	}
+	return &jobs.Worker{
+		Store: repo,
+		Handlers: map[string]jobs.Handler{
+			jobs.KindIngestion: runManager.Handle,
+			jobs.KindCluster:   topics.JobHandler(repo, cfg.EmbeddingModel, labeler),
+			jobs.KindDocs: func(ctx context.Context, job *db.Job, progress jobs.Progress) error {
+				var params controller.DocsParams
+				if err := json.Unmarshal(job.Params, &params); err != nil {
+					return fmt.Errorf("decode docs job params: %w", err)
+				}
+				return docsRun{}.ingest(ctx, cfg, database, params.Repos)
+			},
+		},
+		ID:          cfg.WorkerID,
+		Poll:        config.JobsPollInterval(),
+		StaleAfter:  config.JobsStaleAfter(),
+		MaxAttempts: config.JobsMaxAttempts(),
+	}, nil
+}
@@ -2,13 +2,14 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
-	"github.com/google/go-github/v66/github"
 	"github.com/spf13/cobra"
 
 	"github.com/roivaz/aro-hcp-intelhub/internal/config"
@@ -17,6 +18,9 @@ import (
 	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
 	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion"
 	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion/embeddings"
+	"github.com/roivaz/aro-hcp-intelhub/internal/progress"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
+	"github.com/roivaz/aro-hcp-intelhub/internal/telemetry"
 
 	vcsurl "github.com/gitsight/go-vcsurl"
 )
@@ -44,16 +48,28 @@ var prsCmd = &cobra.Command{
 			cfg.RetryFailed = true
 		}
 
-		database, err := db.NewDatabase(db.Config{DSN: cfg.PostgresURL})
+		database, err := db.NewDatabase(settings.Database(cfg.PostgresURL))
 		if err != nil {
 			return err
 		}
 		defer database.Close()
 
-		repo := db.NewSearchRepository(database, db.WithTraceCacheMax(config.TraceCacheMaxEntries()))
-		embedClient := embeddings.NewClient(cfg.OllamaURL, cfg.EmbeddingModel, cfg.LLMCallTimeout)
-		ghClient := github.NewClient(nil)
-		fetcher := ingestion.NewGitHubFetcher(ghClient, "Azure", "ARO-HCP")
+		repo := db.NewSearchRepository(database,
+			db.WithTraceCacheMax(config.TraceCacheMaxEntries()),
+			db.WithEmbeddingModel(cfg.EmbeddingModel, cfg.EmbeddingDim),
+			db.WithQuantization(cfg.Quantization, cfg.RerankCandidates),
+			db.WithDistanceMetric(cfg.DistanceMetric),
+			db.WithMaxProcessingAttempts(cfg.MaxAttempts))
+		embedOpts := []func(*embeddings.Client){embeddings.WithAutoPull(cfg.OllamaAutoPull)}
+		if cfg.ExecutionMode == "CACHE" {
+			// CACHE only fetches PRs from GitHub and never embeds.
+			embedOpts = append(embedOpts, embeddings.WithoutPreflight())
+		}
+		embedClient, err := ingestion.NewEmbedder(cfg, embedOpts...)
+		if err != nil {
+			return err
+		}
+		fetcher := cfg.GitHubFetcher()
 
 		generator := ingestion.NewGenerator(cfg, database, repo, embedClient, fetcher)
 
@@ -74,84 +90,199 @@ func newDocsCmd() *cobra.Command {
 	var component string
 	var ref string
 	var includePath string
+	var cloneDepth int
+	var singleBranch bool
+	var sparsePaths []string
+	var force bool
+	var tarball bool
+	var reposFile string
 
 	cmd := &cobra.Command{
 		Use:   "docs",
 		Short: "Ingest documentation (Markdown) into vector store",
 	}
 
-	// Build repo list from flags (repeatable --docs-repo full URL, with optional @ref and #component)
-	cmd.Flags().StringArrayVar(&repoURLs, "repo-url", nil, "Repo URL to ingest (repeat)")
-	cmd.Flags().StringVar(&component, "component", "", "Component name")
+	// Build repo list from flags (repeatable --repo-url full URL, with optional @ref and #component)
+	cmd.Flags().StringArrayVar(&repoURLs, "repo-url", nil, "Repo URL to ingest, as URL[@ref][#component] (repeat)")
+	cmd.Flags().StringVar(&component, "component", "", "Component name of a single --repo-url (default: the repository name)")
 	cmd.Flags().StringVar(&ref, "ref", "HEAD", "Reference name")
 	cmd.Flags().StringVar(&includePath, "include-path", "", "Only ingest files within this path (prefix match)")
+	cmd.Flags().IntVar(&cloneDepth, "clone-depth", 0, "Shallow clone depth for repos not yet cached (0 = full history)")
+	cmd.Flags().BoolVar(&singleBranch, "single-branch", false, "Clone only the default branch of repos not yet cached")
+	cmd.Flags().StringArrayVar(&sparsePaths, "sparse-path", nil, "Sparse checkout path for repos not yet cached (repeat)")
+	cmd.Flags().BoolVar(&tarball, "tarball", false, "Download GitHub repos as a tarball of --ref instead of cloning them, falling back to a clone")
+	cmd.Flags().StringVar(&reposFile, "config", "", "docs-repos.yaml listing the repos to ingest with their refs, components, globs and chunking (replaces --repo-url)")
+	cmd.Flags().BoolVar(&force, "force", false, "Re-chunk and re-embed every file, including those unchanged since the last ingest")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		cfg, err := ingestion.LoadConfig()
 		if err != nil {
 			return err
 		}
-		database, err := db.NewDatabase(db.Config{DSN: cfg.PostgresURL})
+		database, err := db.NewDatabase(settings.Database(cfg.PostgresURL))
 		if err != nil {
 			return err
 		}
 		defer database.Close()
 
-		repo := db.NewSearchRepository(database, db.WithTraceCacheMax(config.TraceCacheMaxEntries()))
-
-		// Markdown-aware chunker via langchaingo
-		chunker := docs.NewMDChunker(1000, 100)
+		ctx, cancel := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
 
-		// Build include patterns, optionally prefixed by includePath
-		includePatterns := []string{"**/*.md", "**/*.mdx"}
-		if includePath != "" {
-			// Ensure trailing slash
-			if includePath[len(includePath)-1] != '/' {
-				includePath = includePath + "/"
+		// The repos to ingest, from the config file or the flags
+		var entries []docs.RepoConfig
+		if reposFile != "" {
+			if len(repoURLs) > 0 {
+				return fmt.Errorf("--config and --repo-url are mutually exclusive")
+			}
+			if entries, err = docs.LoadReposFile(reposFile); err != nil {
+				return err
+			}
+		}
+		if component != "" && len(repoURLs) > 1 {
+			return fmt.Errorf("--component names the component of a single repo; use --repo-url URL#component for several")
+		}
+		for _, url := range repoURLs {
+			entry := docs.ParseRepoURL(url)
+			if entry.Ref == "" {
+				entry.Ref = ref
 			}
-			// Prepend path to each pattern
-			for i := range includePatterns {
-				includePatterns[i] = includePath + includePatterns[i]
+			if entry.Component == "" {
+				entry.Component = component
 			}
+			entry.Tarball = tarball
+			entries = append(entries, entry)
 		}
 
-		ing := docs.Ingester{
-			Repo:      repo,
-			Client:    embeddings.NewClient(cfg.OllamaURL, cfg.EmbeddingModel, cfg.LLMCallTimeout),
-			Chunker:   chunker,
-			Include:   includePatterns,
-			Exclude:   []string{"**/.git/**"},
-			MaxFiles:  200,
-			MaxChunks: 1500,
-			ModelName: cfg.EmbeddingModel,
+		run := docsRun{
+			IncludePath:  includePath,
+			CloneDepth:   cloneDepth,
+			SingleBranch: singleBranch,
+			SparsePaths:  sparsePaths,
+			Force:        force,
 		}
+		return run.ingest(ctx, cfg, database, entries)
+	}
 
-		var repos []docs.RepoSpec
-		for _, url := range repoURLs {
-			surl, err := vcsurl.Parse(url)
+	return cmd
+}
+
+// docsRun holds the options of an 'ingest docs' run that apply to every repo.
+type docsRun struct {
+	IncludePath  string // only ingest files within this path
+	CloneDepth   int
+	SingleBranch bool
+	SparsePaths  []string
+	Force        bool
+}
+
+// ingest indexes the documentation of entries, or of the local ARO-HCP clone
+// when none can be read.
+func (o docsRun) ingest(ctx context.Context, cfg ingestion.Config, database *db.Database, entries []docs.RepoConfig) error {
+	repo := db.NewSearchRepository(database,
+		db.WithTraceCacheMax(config.TraceCacheMaxEntries()),
+		db.WithEmbeddingModel(cfg.EmbeddingModel, cfg.EmbeddingDim),
+		db.WithQuantization(cfg.Quantization, cfg.RerankCandidates),
+		db.WithDistanceMetric(cfg.DistanceMetric),
+		db.WithDocumentFlushSize(config.DocsInsertBatchSize()))
+
+	// Chunk size, overlap and splitter per doc_type
+	chunkSpecs, err := docs.ParseChunkSpecs(config.DocsChunking())
+	if err != nil {
+		return fmt.Errorf("invalid docs_chunking: %w", err)
+	}
+
+	// Build include patterns, optionally prefixed by includePath
+	includePatterns := []string{"**/*.md", "**/*.mdx"}
+	if includePath := o.IncludePath; includePath != "" {
+		// Ensure trailing slash
+		if includePath[len(includePath)-1] != '/' {
+			includePath = includePath + "/"
+		}
+		// Prepend path to each pattern
+		for i := range includePatterns {
+			includePatterns[i] = includePath + includePatterns[i]
+		}
+	}
+
+	embedClient, err := ingestion.NewEmbedder(cfg, embeddings.WithAutoPull(cfg.OllamaAutoPull))
+	if err != nil {
+		return err
+	}
+	if err := repo.RegisterEmbeddingModel(ctx); err != nil {
+		return err
+	}
+
+	ing := docs.Ingester{
+		Repo:      repo,
+		Client:    embedClient,
+		Chunking:  docs.NewChunking(chunkSpecs),
+		Include:   includePatterns,
+		Exclude:   []string{"**/.git/**"},
+		MaxFiles:  200,
+		MaxChunks: 1500,
+		ModelName: cfg.EmbeddingModel,
+		Force:     o.Force,
+		Progress:  progress.New("docs"),
+		Git:       settings.Git(),
+	}
+
+	gh := ingestion.NewGitHubClient(cfg.GitHubToken)
+	var repos []docs.RepoSpec
+	for _, entry := range entries {
+		surl, err := vcsurl.Parse(entry.URL)
+		if err != nil {
+			return fmt.Errorf("doesn't look like a VCS URL: %w", err)
+		}
+		spec := docs.RepoSpec{Name: entry.URL, Ref: entry.Ref, Component: entry.Component, Include: entry.Include, Exclude: entry.Exclude}
+		if spec.Component == "" {
+			spec.Component = surl.Name
+		}
+		if len(entry.Chunking) > 0 {
+			specs, err := entry.ChunkSpecs(chunkSpecs)
 			if err != nil {
-				log.Fatalf("doesn't look like a VCS URL: %s", err)
+				return err
 			}
+			spec.Chunking = docs.NewChunking(specs)
+		}
 
-			localPath := filepath.Join(config.CacheDir(), surl.Name)
-			if _, err := gitrepo.New(gitrepo.RepoConfig{URL: url, Path: localPath}).Ensure(cmd.Context()); err != nil {
-				log.Printf("ensure clone for %s: %s", url, err)
+		if entry.Tarball && surl.Host == vcsurl.GitHub {
+			tb, err := docs.FetchTarball(ctx, gh, surl.Username, surl.Name, entry.Ref, ing.Selects(spec))
+			if err == nil {
+				log.Printf("docs: read %s at %s from its tarball", entry.URL, tb.SHA)
+				spec.Tarball = tb
+				repos = append(repos, spec)
 				continue
 			}
-			if component == "" {
-				component = surl.Name
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
-			repos = append(repos, docs.RepoSpec{Name: url, Path: localPath, Ref: ref, Component: component})
+			log.Printf("docs: tarball of %s unavailable, cloning instead: %v", entry.URL, err)
 		}
-		if len(repos) == 0 {
-			// Fallback to local ARO-HCP repo path
-			repos = []docs.RepoSpec{{Name: "Azure/ARO-HCP", Path: cfg.LocalRepoPath}}
+
+		spec.Path = filepath.Join(config.CacheDir(), surl.Name)
+		gr := gitrepo.New(gitrepo.RepoConfig{
+			Settings:     settings.Git(),
+			URL:          entry.URL,
+			Path:         spec.Path,
+			Depth:        o.CloneDepth,
+			SingleBranch: o.SingleBranch,
+			SparsePaths:  o.SparsePaths,
+			Progress:     func(line string) { log.Printf("clone %s: %s", surl.Name, line) },
+		})
+		if _, err := gr.Ensure(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("ensure clone for %s: %s", entry.URL, err)
+			continue
 		}
-		ctx := context.Background()
-		return ing.Run(ctx, repos)
+		repos = append(repos, spec)
 	}
-
-	return cmd
+	if len(repos) == 0 {
+		// Fallback to local ARO-HCP repo path
+		repos = []docs.RepoSpec{{Name: cfg.RepoName(), Path: cfg.LocalRepoPath, Component: cfg.GitHubRepo}}
+	}
+	return ing.Run(ctx, repos)
 }
 
 func main() {
@@ -163,8 +294,34 @@ func main() {
 
 	rootCmd.AddCommand(prsCmd)
 	rootCmd.AddCommand(newDocsCmd())
+	rootCmd.AddCommand(newCodeCmd())
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newDeploymentsCmd())
+	rootCmd.AddCommand(newExportAnalysisCmd())
+	rootCmd.AddCommand(newFailuresCmd())
+	rootCmd.AddCommand(newStatusCmd())
+	rootCmd.AddCommand(newReferencesCmd())
+	rootCmd.AddCommand(newClusterCmd())
+	rootCmd.AddCommand(newCacheGCCmd())
+	rootCmd.AddCommand(newJobsCmd())
+	rootCmd.AddCommand(newWorkerCmd())
+	rootCmd.AddCommand(newControllerCmd())
 
-	if err := rootCmd.Execute(); err != nil {
+	shutdown, err := telemetry.Init(context.Background(), "aro-hcp-ingest")
+	if err != nil {
+		log.Fatalf("ingest: init telemetry: %v", err)
+	}
+	err = rootCmd.Execute()
+	flushTelemetry(shutdown)
+	if err != nil {
 		log.Fatalf("ingest: %v", err)
 	}
 }
+
+func flushTelemetry(shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		log.Printf("ingest: flush telemetry: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
+)
+
+func newReferencesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "references",
+		Short: "Re-extract issue-tracker tickets from every stored PR",
+		Long: `Parse the tickets of the TICKET_PROJECTS Jira projects (ARO-1234,
+OCPBUGS-567, ...) from the title and body of every stored PR and replace the
+pr_references rows used by find_prs_for_ticket. 'ingest prs' records them for
+new and edited PRs; run this after changing TICKET_PROJECTS or to backfill PRs
+cached before tickets were recorded.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parser := ingestion.NewTicketParser(strings.Split(config.TicketProjects(), ","))
+			if parser == nil {
+				return fmt.Errorf("TICKET_PROJECTS is empty")
+			}
+			database, err := db.NewDatabase(settings.Database(config.PostgresURL()))
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			repo := db.NewSearchRepository(database)
+
+			prs, err := repo.ListPRTexts(cmd.Context())
+			if err != nil {
+				return err
+			}
+			tickets := 0
+			for _, pr := range prs {
+				refs := parser.Parse(pr.PRTitle, pr.PRBody)
+				if err := repo.ReplacePRReferences(cmd.Context(), pr.PRNumber, refs); err != nil {
+					return fmt.Errorf("store tickets of PR #%d: %w", pr.PRNumber, err)
+				}
+				tickets += len(refs)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "recorded %d ticket references from %d PRs\n", tickets, len(prs))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
+)
+
+func newStatusCmd() *cobra.Command {
+	var (
+		since time.Duration
+		worst int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Report ingestion progress and the feedback given on PR analyses",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.NewDatabase(settings.Database(config.PostgresURL()))
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			repo := db.NewSearchRepository(database)
+
+			stats, err := repo.HubStats(cmd.Context())
+			if err != nil {
+				return err
+			}
+			feedback, err := repo.FeedbackSummary(cmd.Context(), time.Now().Add(-since), worst)
+			if err != nil {
+				return err
+			}
+			printStatus(cmd.OutOrStdout(), stats, feedback)
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&since, "since", 30*24*time.Hour, "Aggregate feedback given within this window")
+	cmd.Flags().IntVar(&worst, "worst", 10, "Rich descriptions with the most unhelpful marks to list")
+	return cmd
+}
+
+func printStatus(out io.Writer, stats db.HubStats, feedback db.FeedbackSummary) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "PRs\t%d total, %d processed, %d failed, %d pending\n",
+		stats.TotalPRs, stats.ProcessedPRs, stats.FailedPRs, stats.PendingPRs)
+	if stats.LatestMergedAt != nil {
+		fmt.Fprintf(w, "latest merge\t%s\n", stats.LatestMergedAt.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(w, "document chunks\t%d\n", stats.Documents)
+
+	fmt.Fprintf(w, "feedback since %s\t\n", feedback.Since.UTC().Format(time.DateOnly))
+	if len(feedback.Targets) == 0 {
+		fmt.Fprintf(w, "  none\t\n")
+	}
+	for _, t := range feedback.Targets {
+		fmt.Fprintf(w, "  %s\t%d helpful, %d unhelpful (%s helpful)\n", t.Target, t.Helpful, t.Unhelpful, percent(t.Helpful, t.Helpful+t.Unhelpful))
+	}
+	if len(feedback.WorstDescriptions) > 0 {
+		fmt.Fprintf(w, "least helpful rich descriptions\t\n")
+	}
+	for _, pr := range feedback.WorstDescriptions {
+		fmt.Fprintf(w, "  #%d\t%d unhelpful, %d helpful\t%s\n", pr.PRNumber, pr.Unhelpful, pr.Helpful, truncateText(pr.Title, 60))
+	}
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", 100*float64(n)/float64(total))
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/docs"
+	"github.com/roivaz/aro-hcp-intelhub/internal/ollama"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
+)
+
+func newConfigValidateCmd() *cobra.Command {
+	var connect bool
+
+	cmd := &cobra.Command{
+		Use:          "validate",
+		Short:        "Check the configuration and print the effective values with their sources",
+		SilenceUsage: true,
+		Long: `Check that required keys are set and every value parses (numbers,
+durations, URLs, the Postgres DSN) and that the cache directory is writable,
+then print each key's effective value and where it came from: flag, env,
+config.env or default. Secrets are masked. --connect also pings Postgres and
+the Ollama servers. mcp-server runs the same checks at startup.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problems := config.Validate(config.Check{Key: config.KeyDocsChunking, Parse: func(value string) error {
+				_, err := docs.ParseChunkSpecs(value)
+				return err
+			}})
+			if connect && len(problems) == 0 {
+				problems = append(problems, checkConnectivity(cmd.Context())...)
+			}
+			printSettings(cmd.OutOrStdout(), config.Settings())
+			if len(problems) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "\nconfiguration is valid")
+				return nil
+			}
+			fmt.Fprintln(cmd.OutOrStdout())
+			for _, p := range problems {
+				fmt.Fprintf(cmd.OutOrStdout(), "ERROR %v\n", p)
+			}
+			return fmt.Errorf("%d configuration problem(s)", len(problems))
+		},
+	}
+
+	cmd.Flags().BoolVar(&connect, "connect", false, "Also connect to Postgres and the Ollama servers")
+	return cmd
+}
+
+// checkConnectivity pings the database and each Ollama server once, without
+// the startup retries.
+func checkConnectivity(ctx context.Context) []config.Problem {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var problems []config.Problem
+	dbCfg := settings.Database(config.PostgresURL())
+	dbCfg.ConnectRetries = 0
+	database, err := db.NewDatabase(dbCfg)
+	if err == nil {
+		err = database.Ping(ctx)
+		database.Close()
+	}
+	if err != nil {
+		problems = append(problems, config.Problem{Key: config.KeyPostgresURL, Err: err})
+	}
+
+	urls := map[string]string{config.KeyOllamaURL: config.OllamaURL()}
+	if config.DiffAnalysisEnabled() {
+		urls[config.KeyDiffOllamaURL] = config.DiffAnalysisOllamaURL()
+	}
+	for key, url := range urls {
+		if _, err := ollama.AvailableModels(ctx, url); err != nil {
+			problems = append(problems, config.Problem{Key: key, Err: err})
+		}
+	}
+	return problems
+}
+
+func printSettings(out io.Writer, settings []config.Setting) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "ENV\tVALUE\tSOURCE")
+	for _, s := range settings {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Env, s.Value, s.Source)
+	}
+}
@@ -2,22 +2,140 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
 	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
 	"github.com/roivaz/aro-hcp-intelhub/internal/mcp"
+	"github.com/roivaz/aro-hcp-intelhub/internal/telemetry"
 )
 
 func main() {
+	root := &cobra.Command{
+		Use:          "mcp-server",
+		Short:        "Serve the ARO-HCP intelligence hub tools over MCP",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run()
+		},
+	}
+	flags := root.Flags()
+	flags.String("transport", "http", "MCP transport: http (streamable HTTP on MCP_SERVER_HOST:MCP_SERVER_PORT), sse (for clients without streamable HTTP support) or stdio (for local clients that spawn the binary)")
+	flags.Bool("stateful", false, "Issue and track streamable HTTP sessions instead of serving each request statelessly")
+	flags.Duration("session-idle-timeout", 30*time.Minute, "Forget stateful sessions idle this long (0 = never)")
+	flags.Int("max-sessions", 1000, "Maximum stateful sessions; the least recently used is evicted beyond it (0 = unlimited)")
+	flags.Duration("heartbeat-interval", 0, "Ping interval on stateful streamable HTTP streams (0 = disabled)")
+	flags.Duration("sse-keepalive", 30*time.Second, "Keep-alive interval on SSE streams (0 = disabled)")
+	flags.String("base-url", "", "Public base URL advertised to SSE clients, e.g. https://intelhub.example.com")
+	flags.Duration("shutdown-grace", 30*time.Second, "How long shutdown waits for in-flight tool calls before cancelling them")
 	config.Init(nil)
+	for key, flag := range map[string]string{
+		config.KeyMCPTransport:     "transport",
+		config.KeyMCPStateful:      "stateful",
+		config.KeyMCPSessionIdle:   "session-idle-timeout",
+		config.KeyMCPMaxSessions:   "max-sessions",
+		config.KeyMCPHeartbeat:     "heartbeat-interval",
+		config.KeyMCPSSEKeepAlive:  "sse-keepalive",
+		config.KeyMCPBaseURL:       "base-url",
+		config.KeyMCPShutdownGrace: "shutdown-grace",
+	} {
+		_ = config.BindFlag(key, flags.Lookup(flag))
+	}
+
+	if err := root.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if problems := config.Validate(); len(problems) > 0 {
+		for _, p := range problems {
+			log.Printf("config: %v", p)
+		}
+		return fmt.Errorf("invalid configuration: %d problem(s); run 'ingest config validate' for the effective values", len(problems))
+	}
+	transport := config.MCPTransport()
+
+	shutdownTelemetry, err := telemetry.Init(context.Background(), "aro-hcp-mcp-server")
+	if err != nil {
+		log.Fatalf("init telemetry: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTelemetry(ctx); err != nil {
+			log.Printf("flush telemetry: %v", err)
+		}
+	}()
 
 	srv := mcp.New(mcp.DefaultConfig())
+	defer srv.Close()
+
+	// Background tasks stop before Close releases what they use.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	if transport == "stdio" {
+		return serveStdio(srv)
+	}
+	return serveHTTP(srv, transport)
+}
+
+// serveStdio speaks MCP over stdin and stdout until stdin closes or the
+// process is signalled. stdout carries protocol messages only; all logging
+// goes to stderr.
+func serveStdio(srv *mcp.Server) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
+	stdio := server.NewStdioServer(srv.MCP)
+	stdio.SetErrorLogger(log.New(os.Stderr, "mcp-stdio: ", log.LstdFlags))
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("MCP server serving on stdio")
+		errCh <- stdio.Listen(ctx, os.Stdin, os.Stdout)
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	var err error
+	select {
+	case <-stop:
+		drain(srv)
+		cancel()
+		err = <-errCh
+	case err = <-errCh:
+		drain(srv)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("stdio server: %w", err)
+	}
+	return nil
+}
+
+func serveHTTP(srv *mcp.Server, transport string) error {
 	host := os.Getenv("MCP_SERVER_HOST")
 	if host == "" {
 		host = "0.0.0.0"
@@ -34,10 +152,17 @@ func main() {
 		Addr:    addr,
 		Handler: newLoggingMiddleware(srv.Handler),
 	}
+	if transport == "sse" || config.MCPStateful() {
+		// SSE and stateful GET streams only end when their request context
+		// does; cancel them so Shutdown does not wait out its timeout.
+		streams, cancelStreams := context.WithCancel(context.Background())
+		httpServer.BaseContext = func(net.Listener) context.Context { return streams }
+		httpServer.RegisterOnShutdown(cancelStreams)
+	}
 
 	errCh := make(chan error, 1)
 	go func() {
-		log.Printf("MCP server listening on %s", addr)
+		log.Printf("MCP server listening on %s (%s)", addr, transport)
 		errCh <- httpServer.ListenAndServe()
 	}()
 
@@ -46,16 +171,32 @@ func main() {
 
 	select {
 	case <-stop:
+		// Tool calls arriving on open connections are refused while the
+		// in-flight ones finish; only then are connections closed.
+		drain(srv)
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		if err := httpServer.Shutdown(ctx); err != nil {
-			log.Fatalf("shutdown error: %v", err)
+			return fmt.Errorf("shutdown error: %w", err)
 		}
 	case err := <-errCh:
 		if err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server error: %v", err)
+			return fmt.Errorf("server error: %w", err)
 		}
 	}
+	return nil
+}
+
+// drain stops accepting tool calls and waits up to the shutdown grace period
+// for those in flight, cancelling any still running after it.
+func drain(srv *mcp.Server) {
+	grace := config.MCPShutdownGrace()
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	log.Printf("draining in-flight tool calls (grace period %s)", grace)
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("grace period expired, cancelled remaining tool calls: %v", err)
+	}
 }
 
 type loggingResponseWriter struct {
@@ -68,6 +209,14 @@ func newLoggingResponseWriter(w http.ResponseWriter) *loggingResponseWriter {
 	return &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 }
 
+// Flush lets streamed responses (SSE, streamable HTTP GET) through the
+// middleware.
+func (lrw *loggingResponseWriter) Flush() {
+	if f, ok := lrw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	if !lrw.headerWritten {
 		lrw.statusCode = code
@@ -76,12 +225,25 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	}
 }
 
+// requestIDHeader carries the correlation ID; a client-supplied value is
+// reused so its own logs line up with ours.
+const requestIDHeader = "X-Request-ID"
+
 func newLoggingMiddleware(next http.Handler) http.Handler {
+	accessLog := logging.New(logging.DefaultLogger()).WithName("http")
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = logging.NewRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
 		lrw := newLoggingResponseWriter(w)
-		next.ServeHTTP(lrw, r)
-		elapsed := time.Since(start)
-		log.Printf("%s %s %d %s", r.Method, r.URL.Path, lrw.statusCode, elapsed)
+		// Continue traces started by MCP clients that send traceparent headers.
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx = logging.WithRequestID(ctx, requestID)
+		next.ServeHTTP(lrw, r.WithContext(ctx))
+		accessLog.ForContext(ctx).Info("request served",
+			"method", r.Method, "path", r.URL.Path, "status", lrw.statusCode, "elapsed", time.Since(start).String())
 	})
 }
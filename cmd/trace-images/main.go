@@ -14,6 +14,7 @@ import (
 	"github.com/roivaz/aro-hcp-intelhub/internal/db"
 	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
 	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
 	"github.com/roivaz/aro-hcp-intelhub/internal/traceimages"
 )
 
@@ -22,6 +23,8 @@ func main() {
 
 	var commit string
 	var environment string
+	var forceRefresh bool
+	var includeSBOM bool
 
 	cmd := &cobra.Command{
 		Use:   "run",
@@ -36,14 +39,16 @@ func main() {
 
 			cfg := tracingConfig()
 
-			dbConfig := db.Config{DSN: config.PostgresURL()}
+			dbConfig := settings.Database(config.PostgresURL())
 			database, err := db.NewDatabase(dbConfig)
 			if err != nil {
 				return fmt.Errorf("open database: %w", err)
 			}
 			defer database.Close()
 
-			repo := db.NewSearchRepository(database, db.WithTraceCacheMax(config.TraceCacheMaxEntries()))
+			repo := db.NewSearchRepository(database,
+				db.WithTraceCacheMax(config.TraceCacheMaxEntries()),
+				db.WithTraceCacheTTL(config.TraceCacheTTL()))
 			tclog := logging.New(logging.DefaultLogger())
 
 			tracer, err := traceimages.NewTracer(cfg)
@@ -54,7 +59,7 @@ func main() {
 			service := traceimages.New(tracer, repo, tclog)
 
 			ctx := context.Background()
-			resp, err := service.TraceImages(ctx, commit, environment)
+			resp, err := service.TraceImages(ctx, commit, environment, forceRefresh, includeSBOM)
 			if err != nil {
 				return err
 			}
@@ -65,6 +70,8 @@ func main() {
 
 	cmd.Flags().StringVar(&commit, "commit-sha", "", "Git commit SHA to trace")
 	cmd.Flags().StringVar(&environment, "environment", "", "Deployment environment")
+	cmd.Flags().BoolVar(&forceRefresh, "force-refresh", false, "Bypass the trace cache and replace the cached entry")
+	cmd.Flags().BoolVar(&includeSBOM, "sbom", false, "Summarize each component's SBOM (attached, or generated with TRACE_SYFT_PATH)")
 
 	root.AddCommand(cmd)
 
@@ -77,10 +84,22 @@ func main() {
 
 func tracingConfig() traceimages.Config {
 	return traceimages.Config{
-		RepoPath:   filepath.Join(config.CacheDir(), "aro-hcp-repo"),
-		SkopeoPath: config.TraceSkopeoPath(),
-		PullSecret: config.TracePullSecret(),
-		Logger:     logging.New(logging.DefaultLogger().WithName("trace-images")),
+		RepoPath:           filepath.Join(config.CacheDir(), "aro-hcp-repo"),
+		RepoURL:            config.RepoURL(),
+		SkopeoPath:         config.TraceSkopeoPath(),
+		PullSecret:         config.TracePullSecret(),
+		MaxTagCandidates:   config.TraceMaxTagCandidates(),
+		SkopeoOnly:         config.TraceSkopeoOnly(),
+		InspectConcurrency: config.TraceInspectConcurrency(),
+		InspectTimeout:     config.TraceInspectTimeout(),
+		RegistryTimeout:    config.TraceRegistryTimeout(),
+		RegistryCooldown:   config.TraceRegistryCooldown(),
+		WorktreePoolSize:   config.TraceWorktreePoolSize(),
+		RenderCommand:      config.TraceRenderCommand(),
+		RenderTimeout:      config.TraceRenderTimeout(),
+		SyftPath:           config.TraceSyftPath(),
+		Git:                settings.Git(),
+		Logger:             logging.New(logging.DefaultLogger().WithName("trace-images")),
 	}
 }
 
@@ -0,0 +1,117 @@
+// Package ask answers questions about ARO-HCP from retrieved PRs and
+// documentation with an Ollama chat model, citing the sources it used.
+package ask
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/ollama"
+)
+
+const answerPrompt = `You answer questions about ARO-HCP (Azure Red Hat OpenShift hosted
+control planes) using only the sources below: merged pull requests and
+documentation excerpts.
+
+%s
+Question: %s
+
+Answer concisely. Cite every statement with the label of its source in square
+brackets, such as [PR #1234] or [D2]. If the sources do not answer the
+question, say so instead of guessing.`
+
+// Source is a retrieved PR or documentation chunk offered to the model.
+type Source struct {
+	Label string // citation label without brackets: "PR #1234" or "D2"
+	Title string
+	URL   string
+	Text  string
+}
+
+// Answerer composes answers with an Ollama chat model.
+type Answerer struct {
+	llm     *ollama.LLM
+	model   string
+	timeout time.Duration
+}
+
+func NewAnswerer(ollamaURL, model string, timeout time.Duration) (*Answerer, error) {
+	llm, err := ollama.New(ollama.WithModel(model), ollama.WithServerURL(ollamaURL), ollama.WithKeepAlive("5m"))
+	if err != nil {
+		return nil, fmt.Errorf("create ollama client: %w", err)
+	}
+	return &Answerer{llm: llm, model: model, timeout: timeout}, nil
+}
+
+// Model is the name of the chat model.
+func (a *Answerer) Model() string { return a.model }
+
+// Answer asks the model question over sources and returns its answer, with
+// citation labels linked to the sources' URLs, and the sources it cited.
+func (a *Answerer) Answer(ctx context.Context, question string, sources []Source) (string, []Source, error) {
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+	resp, err := a.llm.GenerateContent(ctx, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, Prompt(question, sources)),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return "", nil, fmt.Errorf("empty response")
+	}
+	answer, cited := LinkCitations(strings.TrimSpace(resp.Choices[0].Content), sources)
+	return answer, cited, nil
+}
+
+// Prompt lists sources under their labels, followed by question.
+func Prompt(question string, sources []Source) string {
+	var list strings.Builder
+	for _, s := range sources {
+		fmt.Fprintf(&list, "[%s] %s\n%s\n\n", s.Label, s.Title, strings.TrimSpace(s.Text))
+	}
+	return fmt.Sprintf(answerPrompt, list.String(), question)
+}
+
+// citation matches a bracketed label the model may have written, tolerating
+// "PR#12" and "pr #12" for "PR #12".
+var citation = regexp.MustCompile(`\[(?i:(PR) ?#(\d+)|(D)(\d+))\]`)
+
+// LinkCitations turns the citations in answer that name a source into
+// Markdown links to it, and returns the cited sources in order of first
+// citation. Labels matching no source are left as written.
+func LinkCitations(answer string, sources []Source) (string, []Source) {
+	byLabel := make(map[string]Source, len(sources))
+	for _, s := range sources {
+		byLabel[s.Label] = s
+	}
+	seen := map[string]bool{}
+	var cited []Source
+	linked := citation.ReplaceAllStringFunc(answer, func(m string) string {
+		sub := citation.FindStringSubmatch(m)
+		label := "D" + sub[4]
+		if sub[1] != "" {
+			label = "PR #" + sub[2]
+		}
+		s, ok := byLabel[label]
+		if !ok {
+			return m
+		}
+		if !seen[label] {
+			seen[label] = true
+			cited = append(cited, s)
+		}
+		if s.URL == "" {
+			return "[" + label + "]"
+		}
+		return fmt.Sprintf("[%s](%s)", label, s.URL)
+	})
+	return linked, cited
+}
@@ -0,0 +1,36 @@
+package ask
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLinkCitations(t *testing.T) {
+	sources := []Source{
+		{Label: "PR #12", URL: "https://github.com/Azure/ARO-HCP/pull/12"},
+		{Label: "D1", URL: "https://github.com/Azure/ARO-HCP/blob/abc/docs/upgrade.md#L3-L9"},
+		{Label: "D2"},
+	}
+	answer, cited := LinkCitations("Upgrades are staged [D1], see [pr#12] and [PR #12]; [PR #99] is unknown.", sources)
+
+	want := "Upgrades are staged [D1](https://github.com/Azure/ARO-HCP/blob/abc/docs/upgrade.md#L3-L9), " +
+		"see [PR #12](https://github.com/Azure/ARO-HCP/pull/12) and [PR #12](https://github.com/Azure/ARO-HCP/pull/12); [PR #99] is unknown."
+	if answer != want {
+		t.Errorf("answer =\n%s\nwant\n%s", answer, want)
+	}
+	if len(cited) != 2 || cited[0].Label != "D1" || cited[1].Label != "PR #12" {
+		t.Errorf("cited = %+v, want D1 then PR #12", cited)
+	}
+}
+
+func TestPromptListsSources(t *testing.T) {
+	prompt := Prompt("How are upgrades rolled out?", []Source{
+		{Label: "PR #12", Title: "Stage upgrades by region", Text: "Body\n"},
+		{Label: "D1", Title: "docs/upgrade.md", Text: "Upgrades roll out per stamp."},
+	})
+	for _, want := range []string{"[PR #12] Stage upgrades by region\nBody\n", "[D1] docs/upgrade.md\nUpgrades roll out per stamp.", "Question: How are upgrades rolled out?"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt lacks %q:\n%s", want, prompt)
+		}
+	}
+}
@@ -1,18 +1,31 @@
 package config
 
 import (
+	"os"
+	"time"
+
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 func Init(root *cobra.Command) {
 	viper.AutomaticEnv()
+	if vars, err := godotenv.Read("config.env"); err == nil {
+		for name := range vars {
+			if _, set := os.LookupEnv(name); !set {
+				fileEnv[name] = true
+			}
+		}
+	}
 	_ = godotenv.Load("config.env")
 	if root != nil {
+		root.PersistentFlags().VisitAll(func(f *pflag.Flag) { boundFlags[f.Name] = f })
 		_ = viper.BindPFlags(root.PersistentFlags())
 	}
 	setDefaults()
+	loadSecrets()
 }
 
 func setDefaults() {
@@ -20,33 +33,201 @@ func setDefaults() {
 	viper.SetDefault(KeyLogLevel, "info")
 	viper.SetDefault(KeyCacheDir, "ignore")
 	viper.SetDefault(KeyEmbeddingModel, "nomic-embed-text")
+	viper.SetDefault(KeyEmbeddingDimension, 768)
+	viper.SetDefault(KeyEmbeddingQuantize, "none")
+	viper.SetDefault(KeyEmbeddingRerank, 100)
+	viper.SetDefault(KeyEmbeddingDistance, "cosine")
+	viper.SetDefault(KeyEmbeddingNormalize, false)
+	viper.SetDefault(KeyEmbeddingConcurrency, 2)
+	viper.SetDefault(KeyFakeEmbeddings, false)
+	viper.SetDefault(KeyDocsInsertBatch, 500)
+	viper.SetDefault(KeyDocsChunking, "default=markdown:1000:100")
+	viper.SetDefault(KeyPRSearchTextWeight, 0.7)
+	viper.SetDefault(KeyPRSearchDescWeight, 0.3)
 	viper.SetDefault(KeyGitHubFetchMax, 100)
+	viper.SetDefault(KeyGitHubOwner, "Azure")
+	viper.SetDefault(KeyGitHubRepo, "ARO-HCP")
+	viper.SetDefault(KeyIngestFixtureMode, "replay")
 	viper.SetDefault(KeyExecutionMode, "FULL")
+	viper.SetDefault(KeyIngestionMode, "UPDATED")
+	viper.SetDefault(KeyIngestionLookback, "2160h")
 	viper.SetDefault(KeyMaxProcessBatch, 100)
+	viper.SetDefault(KeyMaxProcessAttempts, 3)
 	viper.SetDefault(KeyDiffEnabled, false)
 	viper.SetDefault(KeyDiffModel, "phi3")
 	viper.SetDefault(KeyDiffOllamaURL, "http://localhost:11434")
 	viper.SetDefault(KeyDiffContext, 4096)
+	viper.SetDefault(KeyDiffMaxTokens, 0)
 	viper.SetDefault(KeyTraceSkopeo, "skopeo")
 	viper.SetDefault(KeyAutoMigrate, false)
 	viper.SetDefault(KeyLLMCallTimeout, "2m")
 	viper.SetDefault(KeyTraceCacheMaxEntries, 500)
+	viper.SetDefault(KeyTraceMaxTags, 50)
+	viper.SetDefault(KeyTraceSkopeoOnly, false)
+	viper.SetDefault(KeyTraceInspectWorkers, 4)
+	viper.SetDefault(KeyTraceInspectTimeout, "2m")
+	viper.SetDefault(KeyTraceCacheTTL, "0")
+	viper.SetDefault(KeyTraceSyft, "")
+	viper.SetDefault(KeyTraceComponentRepos, "")
+	viper.SetDefault(KeyTraceRegistryTimeout, "15s")
+	viper.SetDefault(KeyTraceRegistryCool, "5m")
+	viper.SetDefault(KeyTraceRenderCommand, "")
+	viper.SetDefault(KeyTraceRenderTimeout, "5m")
+	viper.SetDefault(KeyTraceWorktreePool, 4)
+	viper.SetDefault(KeyGitBackend, "exec")
+	viper.SetDefault(KeyGitCacheMaxIdle, "720h")
+	viper.SetDefault(KeyGitCacheGCInterval, "24h")
+	viper.SetDefault(KeyWorkerBatchSize, 5)
+	viper.SetDefault(KeyWorkerPollInterval, "30s")
+	viper.SetDefault(KeyWorkerVisibility, "30m")
+	viper.SetDefault(KeyWorkerCacheInterval, "0")
+	viper.SetDefault(KeyJobsWorker, false)
+	viper.SetDefault(KeyJobsPollInterval, "5s")
+	viper.SetDefault(KeyJobsStaleAfter, "2m")
+	viper.SetDefault(KeyJobsMaxAttempts, 3)
+	viper.SetDefault(KeyEvalEnabled, false)
+	viper.SetDefault(KeyEvalCasesFile, "eval/cases.yaml")
+	viper.SetDefault(KeyEvalHour, 3)
+	viper.SetDefault(KeyEvalK, 5)
+	viper.SetDefault(KeyAskEnabled, false)
+	viper.SetDefault(KeyDBMaxOpenConns, 20)
+	viper.SetDefault(KeyDBMaxIdleConns, 5)
+	viper.SetDefault(KeyDBConnMaxLifetime, "30m")
+	viper.SetDefault(KeyDBConnMaxIdleTime, "5m")
+	viper.SetDefault(KeyDBStatementTimeout, "0")
+	viper.SetDefault(KeyDBConnectRetries, 5)
+	viper.SetDefault(KeyDBConnectBackoff, "1s")
+	viper.SetDefault(KeyOTelEnabled, false)
+	viper.SetDefault(KeyOTelSampleRatio, 1.0)
+	viper.SetDefault(KeyOllamaAutoPull, false)
+	viper.SetDefault(KeyMCPTransport, "http")
+	viper.SetDefault(KeyMCPStateful, false)
+	viper.SetDefault(KeyMCPSessionIdle, "30m")
+	viper.SetDefault(KeyMCPMaxSessions, 1000)
+	viper.SetDefault(KeyMCPHeartbeat, "0")
+	viper.SetDefault(KeyMCPSSEKeepAlive, "30s")
+	viper.SetDefault(KeyMCPShutdownGrace, "30s")
+	viper.SetDefault(KeyMCPToolTimeout, "30s")
+	viper.SetDefault(KeyMCPToolTimeouts, "trace_images=120s,trace_component_commits=300s,release_notes=60s,commit_context=60s,ask_intelhub=120s")
+	viper.SetDefault(KeyMCPIndexWarmup, false)
+	viper.SetDefault(KeyMCPWarmupThreshold, "500ms")
+	viper.SetDefault(KeySearchCacheTTL, "60s")
+	viper.SetDefault(KeySearchCacheMax, 1000)
+	viper.SetDefault(KeySearchMinSimilarity, 0.6)
+	viper.SetDefault(KeyTicketProjects, "ARO,OCPBUGS,OCPSTRAT,HOSTEDCP")
+}
+
+func PostgresURL() string                  { return withPassword(viper.GetString(KeyPostgresURL)) }
+func OllamaURL() string                    { return viper.GetString(KeyOllamaURL) }
+func AuthFile() string                     { return viper.GetString(KeyAuthFile) }
+func CacheDir() string                     { return viper.GetString(KeyCacheDir) }
+func EmbeddingModel() string               { return viper.GetString(KeyEmbeddingModel) }
+func EmbeddingDimension() int              { return viper.GetInt(KeyEmbeddingDimension) }
+func EmbeddingQuantization() string        { return viper.GetString(KeyEmbeddingQuantize) }
+func EmbeddingRerankCandidates() int       { return viper.GetInt(KeyEmbeddingRerank) }
+func EmbeddingDistance() string            { return viper.GetString(KeyEmbeddingDistance) }
+func EmbeddingNormalize() bool             { return viper.GetBool(KeyEmbeddingNormalize) }
+func EmbeddingConcurrency() int            { return viper.GetInt(KeyEmbeddingConcurrency) }
+func FakeEmbeddings() bool                 { return viper.GetBool(KeyFakeEmbeddings) }
+func DocsInsertBatchSize() int             { return viper.GetInt(KeyDocsInsertBatch) }
+func DocsChunking() string                 { return viper.GetString(KeyDocsChunking) }
+func PRSearchTextWeight() float64          { return viper.GetFloat64(KeyPRSearchTextWeight) }
+func PRSearchDescriptionWeight() float64   { return viper.GetFloat64(KeyPRSearchDescWeight) }
+func GitHubFetchMax() int                  { return viper.GetInt(KeyGitHubFetchMax) }
+func GitHubOwner() string                  { return viper.GetString(KeyGitHubOwner) }
+func GitHubRepo() string                   { return viper.GetString(KeyGitHubRepo) }
+func IngestFixtureDir() string             { return viper.GetString(KeyIngestFixtureDir) }
+func IngestFixtureMode() string            { return viper.GetString(KeyIngestFixtureMode) }
+func ExecutionMode() string                { return viper.GetString(KeyExecutionMode) }
+func IngestionMode() string                { return viper.GetString(KeyIngestionMode) }
+func IngestionLookback() string            { return viper.GetString(KeyIngestionLookback) }
+func MaxProcessBatch() int                 { return viper.GetInt(KeyMaxProcessBatch) }
+func MaxProcessingAttempts() int           { return viper.GetInt(KeyMaxProcessAttempts) }
+func DiffAnalysisEnabled() bool            { return viper.GetBool(KeyDiffEnabled) }
+func DiffAnalysisModel() string            { return viper.GetString(KeyDiffModel) }
+func DiffAnalysisOllamaURL() string        { return viper.GetString(KeyDiffOllamaURL) }
+func DiffAnalysisContextTokens() int       { return viper.GetInt(KeyDiffContext) }
+func DiffAnalysisMaxDiffTokens() int       { return viper.GetInt(KeyDiffMaxTokens) }
+func TraceSkopeoPath() string              { return viper.GetString(KeyTraceSkopeo) }
+func TracePullSecret() string              { return viper.GetString(KeyTraceSecret) }
+func AutoMigrate() bool                    { return viper.GetBool(KeyAutoMigrate) }
+func LLMCallTimeout() string               { return viper.GetString(KeyLLMCallTimeout) }
+func TraceCacheMaxEntries() int            { return viper.GetInt(KeyTraceCacheMaxEntries) }
+func TraceMaxTagCandidates() int           { return viper.GetInt(KeyTraceMaxTags) }
+func TraceSkopeoOnly() bool                { return viper.GetBool(KeyTraceSkopeoOnly) }
+func TraceInspectConcurrency() int         { return viper.GetInt(KeyTraceInspectWorkers) }
+func TraceInspectTimeout() time.Duration   { return viper.GetDuration(KeyTraceInspectTimeout) }
+func TraceCacheTTL() time.Duration         { return viper.GetDuration(KeyTraceCacheTTL) }
+func TraceSyftPath() string                { return viper.GetString(KeyTraceSyft) }
+func TraceComponentRepos() string          { return viper.GetString(KeyTraceComponentRepos) }
+func TraceRegistryTimeout() time.Duration  { return viper.GetDuration(KeyTraceRegistryTimeout) }
+func TraceRegistryCooldown() time.Duration { return viper.GetDuration(KeyTraceRegistryCool) }
+func TraceRenderCommand() string           { return viper.GetString(KeyTraceRenderCommand) }
+func TraceRenderTimeout() time.Duration    { return viper.GetDuration(KeyTraceRenderTimeout) }
+func TraceWorktreePoolSize() int           { return viper.GetInt(KeyTraceWorktreePool) }
+func GitBackend() string                   { return viper.GetString(KeyGitBackend) }
+func GitTokens() string                    { return viper.GetString(KeyGitTokens) }
+func GitSSHKey() string                    { return viper.GetString(KeyGitSSHKey) }
+func GitMirrorURL() string                 { return viper.GetString(KeyGitMirrorURL) }
+func GitCacheMaxIdle() time.Duration       { return viper.GetDuration(KeyGitCacheMaxIdle) }
+func GitCacheGCInterval() time.Duration    { return viper.GetDuration(KeyGitCacheGCInterval) }
+func MCPAdminToken() string                { return viper.GetString(KeyMCPAdminToken) }
+func MCPTransport() string                 { return viper.GetString(KeyMCPTransport) }
+func MCPStateful() bool                    { return viper.GetBool(KeyMCPStateful) }
+func MCPSessionIdleTimeout() time.Duration { return viper.GetDuration(KeyMCPSessionIdle) }
+func MCPMaxSessions() int                  { return viper.GetInt(KeyMCPMaxSessions) }
+func MCPHeartbeatInterval() time.Duration  { return viper.GetDuration(KeyMCPHeartbeat) }
+func MCPSSEKeepAlive() time.Duration       { return viper.GetDuration(KeyMCPSSEKeepAlive) }
+func MCPBaseURL() string                   { return viper.GetString(KeyMCPBaseURL) }
+func MCPShutdownGrace() time.Duration      { return viper.GetDuration(KeyMCPShutdownGrace) }
+func MCPToolTimeout() time.Duration        { return viper.GetDuration(KeyMCPToolTimeout) }
+func MCPToolTimeouts() string              { return viper.GetString(KeyMCPToolTimeouts) }
+func MCPIndexWarmup() bool                 { return viper.GetBool(KeyMCPIndexWarmup) }
+func MCPWarmupThreshold() time.Duration    { return viper.GetDuration(KeyMCPWarmupThreshold) }
+func SearchCacheTTL() time.Duration        { return viper.GetDuration(KeySearchCacheTTL) }
+func SearchCacheMaxEntries() int           { return viper.GetInt(KeySearchCacheMax) }
+func SearchMinSimilarity() float64         { return viper.GetFloat64(KeySearchMinSimilarity) }
+func WorkerID() string                     { return viper.GetString(KeyWorkerID) }
+func WorkerBatchSize() int                 { return viper.GetInt(KeyWorkerBatchSize) }
+func WorkerPollInterval() string           { return viper.GetString(KeyWorkerPollInterval) }
+func WorkerVisibilityTimeout() string      { return viper.GetString(KeyWorkerVisibility) }
+func WorkerCacheInterval() string          { return viper.GetString(KeyWorkerCacheInterval) }
+func JobsWorker() bool                     { return viper.GetBool(KeyJobsWorker) }
+func JobsPollInterval() time.Duration      { return viper.GetDuration(KeyJobsPollInterval) }
+func JobsStaleAfter() time.Duration        { return viper.GetDuration(KeyJobsStaleAfter) }
+func JobsMaxAttempts() int                 { return viper.GetInt(KeyJobsMaxAttempts) }
+func EvalEnabled() bool                    { return viper.GetBool(KeyEvalEnabled) }
+func EvalCasesFile() string                { return viper.GetString(KeyEvalCasesFile) }
+func EvalHour() int                        { return viper.GetInt(KeyEvalHour) }
+func EvalK() int                           { return viper.GetInt(KeyEvalK) }
+func AskEnabled() bool                     { return viper.GetBool(KeyAskEnabled) }
+func DBMaxOpenConns() int                  { return viper.GetInt(KeyDBMaxOpenConns) }
+func DBMaxIdleConns() int                  { return viper.GetInt(KeyDBMaxIdleConns) }
+func DBConnMaxLifetime() time.Duration     { return viper.GetDuration(KeyDBConnMaxLifetime) }
+func DBConnMaxIdleTime() time.Duration     { return viper.GetDuration(KeyDBConnMaxIdleTime) }
+func DBStatementTimeout() time.Duration    { return viper.GetDuration(KeyDBStatementTimeout) }
+func DBConnectRetries() int                { return viper.GetInt(KeyDBConnectRetries) }
+func DBConnectBackoff() time.Duration      { return viper.GetDuration(KeyDBConnectBackoff) }
+func OTelEnabled() bool                    { return viper.GetBool(KeyOTelEnabled) }
+func OTelSampleRatio() float64             { return viper.GetFloat64(KeyOTelSampleRatio) }
+func OllamaAutoPull() bool                 { return viper.GetBool(KeyOllamaAutoPull) }
+func DeploymentsWebhookToken() string      { return viper.GetString(KeyDeploymentsToken) }
+func TicketProjects() string               { return viper.GetString(KeyTicketProjects) }
+
+// AskModel is the chat model answering ask_intelhub, the diff analysis model
+// unless set.
+func AskModel() string {
+	if model := viper.GetString(KeyAskModel); model != "" {
+		return model
+	}
+	return DiffAnalysisModel()
 }
 
-func PostgresURL() string            { return viper.GetString(KeyPostgresURL) }
-func OllamaURL() string              { return viper.GetString(KeyOllamaURL) }
-func AuthFile() string               { return viper.GetString(KeyAuthFile) }
-func CacheDir() string               { return viper.GetString(KeyCacheDir) }
-func EmbeddingModel() string         { return viper.GetString(KeyEmbeddingModel) }
-func GitHubFetchMax() int            { return viper.GetInt(KeyGitHubFetchMax) }
-func ExecutionMode() string          { return viper.GetString(KeyExecutionMode) }
-func MaxProcessBatch() int           { return viper.GetInt(KeyMaxProcessBatch) }
-func DiffAnalysisEnabled() bool      { return viper.GetBool(KeyDiffEnabled) }
-func DiffAnalysisModel() string      { return viper.GetString(KeyDiffModel) }
-func DiffAnalysisOllamaURL() string  { return viper.GetString(KeyDiffOllamaURL) }
-func DiffAnalysisContextTokens() int { return viper.GetInt(KeyDiffContext) }
-func TraceSkopeoPath() string        { return viper.GetString(KeyTraceSkopeo) }
-func TracePullSecret() string        { return viper.GetString(KeyTraceSecret) }
-func AutoMigrate() bool              { return viper.GetBool(KeyAutoMigrate) }
-func LLMCallTimeout() string         { return viper.GetString(KeyLLMCallTimeout) }
-func TraceCacheMaxEntries() int      { return viper.GetInt(KeyTraceCacheMaxEntries) }
+// RepoURL is the clone URL of the ARO-HCP repository, the GitHub repository
+// GITHUB_OWNER/GITHUB_REPO unless set.
+func RepoURL() string {
+	if url := viper.GetString(KeyRepoURL); url != "" {
+		return url
+	}
+	return "https://github.com/" + GitHubOwner() + "/" + GitHubRepo()
+}
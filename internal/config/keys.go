@@ -2,22 +2,105 @@ package config
 
 const (
 	KeyPostgresURL          = "postgres_url"
+	KeyPostgresPassword     = "postgres_password"
 	KeyOllamaURL            = "ollama_url"
 	KeyLogLevel             = "log_level"
 	KeyAuthFile             = "auth_file"
 	KeyCacheDir             = "cache_dir"
 	KeyEmbeddingModel       = "embedding_model_name"
+	KeyEmbeddingDimension   = "embedding_dimension"
+	KeyEmbeddingQuantize    = "embedding_quantization"
+	KeyEmbeddingRerank      = "embedding_rerank_candidates"
+	KeyEmbeddingDistance    = "embedding_distance"
+	KeyEmbeddingNormalize   = "embedding_normalize"
+	KeyEmbeddingConcurrency = "embedding_concurrency"
+	KeyFakeEmbeddings       = "fake_embeddings"
+	KeyDocsInsertBatch      = "docs_insert_batch_size"
+	KeyDocsChunking         = "docs_chunking"
+	KeyPRSearchTextWeight   = "pr_search_text_weight"
+	KeyPRSearchDescWeight   = "pr_search_description_weight"
 	KeyGitHubFetchMax       = "github_fetch_max"
+	KeyGitHubOwner          = "github_owner"
+	KeyGitHubRepo           = "github_repo"
+	KeyIngestFixtureDir     = "ingest_fixture_dir"
+	KeyIngestFixtureMode    = "ingest_fixture_mode"
 	KeyExecutionMode        = "execution_mode"
+	KeyIngestionMode        = "ingestion_mode"
+	KeyIngestionLookback    = "ingestion_lookback"
 	KeyMaxProcessBatch      = "max_process_batch"
+	KeyMaxProcessAttempts   = "max_processing_attempts"
 	KeyDiffEnabled          = "diff_analysis_enabled"
 	KeyDiffModel            = "diff_analysis_model"
 	KeyDiffOllamaURL        = "diff_analysis_ollama_url"
 	KeyDiffContext          = "diff_analysis_context_tokens"
+	KeyDiffMaxTokens        = "diff_analysis_max_diff_tokens"
 	KeyRepoPath             = "aro_hcp_repo_path"
+	KeyRepoURL              = "aro_hcp_repo_url"
 	KeyTraceSkopeo          = "trace_skopeo_path"
 	KeyTraceSecret          = "pull_secret"
 	KeyAutoMigrate          = "auto_migrate"
 	KeyLLMCallTimeout       = "llm_call_timeout"
 	KeyTraceCacheMaxEntries = "trace_cache_max_entries"
+	KeyTraceMaxTags         = "trace_max_tag_candidates"
+	KeyTraceSkopeoOnly      = "trace_skopeo_only"
+	KeyTraceInspectWorkers  = "trace_inspect_concurrency"
+	KeyTraceInspectTimeout  = "trace_inspect_timeout"
+	KeyTraceCacheTTL        = "trace_cache_ttl"
+	KeyTraceSyft            = "trace_syft_path"
+	KeyTraceComponentRepos  = "trace_component_repos"
+	KeyTraceRegistryTimeout = "trace_registry_timeout"
+	KeyTraceRegistryCool    = "trace_registry_cooldown"
+	KeyTraceRenderCommand   = "trace_render_command"
+	KeyTraceRenderTimeout   = "trace_render_timeout"
+	KeyTraceWorktreePool    = "trace_worktree_pool_size"
+	KeyGitBackend           = "git_backend"
+	KeyGitTokens            = "git_tokens"
+	KeyGitSSHKey            = "git_ssh_key"
+	KeyGitMirrorURL         = "git_mirror_url"
+	KeyGitCacheMaxIdle      = "git_cache_max_idle"
+	KeyGitCacheGCInterval   = "git_cache_gc_interval"
+	KeyMCPAdminToken        = "mcp_admin_token"
+	KeyMCPTransport         = "mcp_transport"
+	KeyMCPStateful          = "mcp_stateful"
+	KeyMCPSessionIdle       = "mcp_session_idle_timeout"
+	KeyMCPMaxSessions       = "mcp_max_sessions"
+	KeyMCPHeartbeat         = "mcp_heartbeat_interval"
+	KeyMCPSSEKeepAlive      = "mcp_sse_keepalive"
+	KeyMCPBaseURL           = "mcp_base_url"
+	KeyMCPShutdownGrace     = "mcp_shutdown_grace"
+	KeyMCPToolTimeout       = "mcp_tool_timeout"
+	KeyMCPToolTimeouts      = "mcp_tool_timeouts"
+	KeyMCPIndexWarmup       = "mcp_index_warmup"
+	KeyMCPWarmupThreshold   = "mcp_index_warmup_threshold"
+	KeySearchCacheTTL       = "search_cache_ttl"
+	KeySearchCacheMax       = "search_cache_max_entries"
+	KeySearchMinSimilarity  = "search_min_similarity"
+	KeyWorkerID             = "worker_id"
+	KeyWorkerBatchSize      = "worker_batch_size"
+	KeyWorkerPollInterval   = "worker_poll_interval"
+	KeyWorkerVisibility     = "worker_visibility_timeout"
+	KeyWorkerCacheInterval  = "worker_cache_interval"
+	KeyJobsWorker           = "jobs_worker"
+	KeyJobsPollInterval     = "jobs_poll_interval"
+	KeyJobsStaleAfter       = "jobs_stale_after"
+	KeyJobsMaxAttempts      = "jobs_max_attempts"
+	KeyEvalEnabled          = "eval_enabled"
+	KeyEvalCasesFile        = "eval_cases_file"
+	KeyEvalHour             = "eval_hour"
+	KeyEvalK                = "eval_k"
+	KeyAskEnabled           = "ask_enabled"
+	KeyAskModel             = "ask_model"
+	KeyDBMaxOpenConns       = "db_max_open_conns"
+	KeyDBMaxIdleConns       = "db_max_idle_conns"
+	KeyDBConnMaxLifetime    = "db_conn_max_lifetime"
+	KeyDBConnMaxIdleTime    = "db_conn_max_idle_time"
+	KeyDBStatementTimeout   = "db_statement_timeout"
+	KeyDBConnectRetries     = "db_connect_retries"
+	KeyDBConnectBackoff     = "db_connect_backoff"
+	KeyOTelEnabled          = "otel_enabled"
+	KeyOTelSampleRatio      = "otel_sample_ratio"
+	KeyOllamaAutoPull       = "ollama_auto_pull"
+	KeyDeploymentsToken     = "deployments_webhook_token"
+	KeyKeyVaultURL          = "key_vault_url"
+	KeyTicketProjects       = "ticket_projects"
 )
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	keyVaultResource   = "https://vault.azure.net"
+	keyVaultAPIVersion = "7.4"
+	imdsTokenURL       = "http://169.254.169.254/metadata/identity/oauth2/token"
+	defaultAuthority   = "https://login.microsoftonline.com/"
+)
+
+var errSecretNotFound = errors.New("secret not found")
+
+// keyVaultClient reads secrets over the Key Vault REST API. It authenticates
+// with AKS workload identity when AZURE_FEDERATED_TOKEN_FILE is set and with
+// the VM or pod managed identity (IMDS) otherwise; AZURE_CLIENT_ID selects a
+// user-assigned identity.
+type keyVaultClient struct {
+	vaultURL string
+	http     *http.Client
+
+	once  sync.Once
+	token string
+	err   error
+}
+
+func newKeyVaultClient(vaultURL string) *keyVaultClient {
+	return &keyVaultClient{vaultURL: strings.TrimRight(vaultURL, "/"), http: http.DefaultClient}
+}
+
+func (c *keyVaultClient) secret(ctx context.Context, name string) (string, error) {
+	c.once.Do(func() { c.token, c.err = c.accessToken(ctx) })
+	if c.err != nil {
+		return "", c.err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/secrets/%s?api-version=%s", c.vaultURL, url.PathEscape(name), keyVaultAPIVersion), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	var body struct {
+		Value string `json:"value"`
+	}
+	if err := c.do(req, &body); err != nil {
+		var status *statusError
+		if errors.As(err, &status) && status.code == http.StatusNotFound {
+			return "", errSecretNotFound
+		}
+		return "", err
+	}
+	return body.Value, nil
+}
+
+func (c *keyVaultClient) accessToken(ctx context.Context) (string, error) {
+	clientID := os.Getenv("AZURE_CLIENT_ID")
+	var req *http.Request
+	if tokenFile := os.Getenv("AZURE_FEDERATED_TOKEN_FILE"); tokenFile != "" {
+		assertion, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("read federated token: %w", err)
+		}
+		authority := os.Getenv("AZURE_AUTHORITY_HOST")
+		if authority == "" {
+			authority = defaultAuthority
+		}
+		form := url.Values{
+			"grant_type":            {"client_credentials"},
+			"client_id":             {clientID},
+			"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+			"client_assertion":      {strings.TrimSpace(string(assertion))},
+			"scope":                 {keyVaultResource + "/.default"},
+		}
+		tokenURL := strings.TrimRight(authority, "/") + "/" + os.Getenv("AZURE_TENANT_ID") + "/oauth2/v2.0/token"
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		q := url.Values{"api-version": {"2018-02-01"}, "resource": {keyVaultResource}}
+		if clientID != "" {
+			q.Set("client_id", clientID)
+		}
+		var err error
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, imdsTokenURL+"?"+q.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata", "true")
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.do(req, &body); err != nil {
+		return "", fmt.Errorf("azure token: %w", err)
+	}
+	return body.AccessToken, nil
+}
+
+func (c *keyVaultClient) do(req *http.Request, out any) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &statusError{code: resp.StatusCode, msg: resp.Status + ": " + strings.TrimSpace(string(msg))}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string { return e.msg }
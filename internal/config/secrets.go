@@ -0,0 +1,98 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	// secretSources records keys loaded by loadSecrets: "file" or "keyvault".
+	secretSources = map[string]string{}
+	// secretProblems holds the secrets that could not be loaded; Validate
+	// reports them.
+	secretProblems []Problem
+)
+
+// keyVaultTimeout bounds fetching every secret from Key Vault at startup.
+const keyVaultTimeout = 30 * time.Second
+
+// loadSecrets fills keys from files and Azure Key Vault so credentials need
+// not be passed as plain environment variables:
+//
+//   - FOO_FILE names a file whose contents become FOO, e.g. a mounted
+//     Kubernetes secret in POSTGRES_PASSWORD_FILE. Setting both is an error.
+//   - With KEY_VAULT_URL set, every secret key still unset is read from the
+//     vault secret of the same name in kebab case (postgres-password,
+//     mcp-admin-token, ...). Missing vault secrets are skipped.
+//
+// Loaded values are exported as environment variables, so flags still take
+// precedence.
+func loadSecrets() {
+	for _, spec := range keySpecs {
+		env := EnvName(spec.key)
+		path, ok := os.LookupEnv(env + "_FILE")
+		if !ok || path == "" {
+			continue
+		}
+		if _, set := os.LookupEnv(env); set {
+			secretProblems = append(secretProblems, Problem{Key: spec.key, Err: fmt.Errorf("both %s and %s_FILE are set", env, env)})
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			secretProblems = append(secretProblems, Problem{Key: spec.key, Err: fmt.Errorf("read %s_FILE: %w", env, err)})
+			continue
+		}
+		os.Setenv(env, strings.TrimRight(string(data), "\r\n"))
+		secretSources[spec.key] = "file"
+	}
+
+	vaultURL := viper.GetString(KeyKeyVaultURL)
+	if vaultURL == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), keyVaultTimeout)
+	defer cancel()
+	vault := newKeyVaultClient(vaultURL)
+	for _, spec := range keySpecs {
+		if !spec.secret || viper.GetString(spec.key) != "" {
+			continue
+		}
+		name := strings.ReplaceAll(spec.key, "_", "-")
+		value, err := vault.secret(ctx, name)
+		if errors.Is(err, errSecretNotFound) {
+			continue
+		}
+		if err != nil {
+			secretProblems = append(secretProblems, Problem{Key: spec.key, Err: fmt.Errorf("key vault secret %s: %w", name, err)})
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		os.Setenv(EnvName(spec.key), value)
+		secretSources[spec.key] = "keyvault"
+	}
+}
+
+// withPassword sets POSTGRES_PASSWORD as the password of dsn, so the DSN
+// can live in a ConfigMap and only the password in a secret.
+func withPassword(dsn string) string {
+	password := viper.GetString(KeyPostgresPassword)
+	if password == "" || dsn == "" {
+		return dsn
+	}
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil {
+		return dsn
+	}
+	u.User = url.UserPassword(u.User.Username(), password)
+	return u.String()
+}
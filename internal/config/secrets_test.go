@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLoadFileSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("s3cr/et\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("POSTGRES_PASSWORD_FILE", path)
+	t.Setenv("POSTGRES_URL", "postgres://app@db:5432/intelhub?sslmode=disable")
+	os.Unsetenv("POSTGRES_PASSWORD")
+	t.Cleanup(func() {
+		os.Unsetenv("POSTGRES_PASSWORD")
+		delete(secretSources, KeyPostgresPassword)
+	})
+	viper.AutomaticEnv()
+
+	loadSecrets()
+	if len(secretProblems) > 0 {
+		t.Fatalf("problems: %v", secretProblems)
+	}
+	if got := source(KeyPostgresPassword); got != "file" {
+		t.Errorf("source = %q, want file", got)
+	}
+	if got, want := PostgresURL(), "postgres://app:s3cr%2Fet@db:5432/intelhub?sslmode=disable"; got != want {
+		t.Errorf("PostgresURL() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckValue(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		spec  keySpec
+		value string
+		ok    bool
+	}{
+		{keySpec{kind: kindDSN}, "postgres://u:p@localhost:5432/db?sslmode=disable", true},
+		{keySpec{kind: kindDSN}, "postgres://localhost:5432", false},
+		{keySpec{kind: kindDSN}, "localhost:5432/db", false},
+		{keySpec{kind: kindURL}, "http://ollama:11434", true},
+		{keySpec{kind: kindURL}, "ollama:11434", false},
+		{keySpec{kind: kindDuration}, "90s", true},
+		{keySpec{kind: kindDuration}, "90", false},
+		{keySpec{kind: kindDurationPairs}, "trace_images=2m,search_docs=10s", true},
+		{keySpec{kind: kindDurationPairs}, "trace_images", false},
+		{keySpec{kind: kindHostTokens}, "gitlab.example.com=oauth2:glpat-x, github.com=ghp_x", true},
+		{keySpec{kind: kindHostTokens}, "ghp_x", false},
+		{keySpec{kind: kindInt}, "12", true},
+		{keySpec{kind: kindInt}, "1.5", false},
+		{keySpec{kind: kindString, enum: []string{"FULL", "CACHE"}, fold: true}, "cache", true},
+		{keySpec{kind: kindString, enum: []string{"http", "sse"}}, "HTTP", false},
+		{keySpec{kind: kindDir}, filepath.Join(dir, "new", "cache"), true},
+	}
+	for _, c := range cases {
+		err := checkValue(c.spec, c.value)
+		if (err == nil) != c.ok {
+			t.Errorf("checkValue(%v, %q) = %v, want ok=%v", c.spec.kind, c.value, err, c.ok)
+		}
+	}
+}
+
+func TestMaskSecret(t *testing.T) {
+	got := maskSecret(kindDSN, "postgres://app:s3cret@db:5432/intelhub")
+	if got != "postgres://app:xxxxx@db:5432/intelhub" {
+		t.Errorf("masked DSN = %q", got)
+	}
+	if got := maskSecret(kindString, "token"); got == "token" {
+		t.Error("secret not masked")
+	}
+}
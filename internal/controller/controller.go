@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/docs"
+	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion"
+	"github.com/roivaz/aro-hcp-intelhub/internal/jobs"
+	"github.com/roivaz/aro-hcp-intelhub/internal/topics"
+)
+
+const defaultResync = 30 * time.Second
+
+// recentJobs bounds the jobs of a kind searched for the last one queued by
+// the controller, skipping those queued by hand.
+const recentJobs = 20
+
+// taskKinds maps tasks to the kind of job that runs them.
+var taskKinds = map[string]string{
+	TaskPRs:     jobs.KindIngestion,
+	TaskDocs:    jobs.KindDocs,
+	TaskCluster: jobs.KindCluster,
+}
+
+// DocsParams are the parameters of a docs job.
+type DocsParams struct {
+	Repos []docs.RepoConfig `json:"repos"`
+}
+
+// generation tags the jobs queued by the controller with the spec
+// generation they were queued for.
+type generation struct {
+	Generation string `json:"generation"`
+}
+
+// Store queues and lists jobs; *db.SearchRepository implements it.
+type Store interface {
+	EnqueueJob(ctx context.Context, kind string, params any) (*db.Job, error)
+	ListJobs(ctx context.Context, kind string, limit int) ([]db.Job, error)
+}
+
+// Controller keeps the jobs queue in line with the spec file at SpecPath,
+// re-reading it every Resync: Kubernetes updates a mounted ConfigMap in
+// place.
+type Controller struct {
+	Store    Store
+	SpecPath string
+	Resync   time.Duration // 30s when 0
+	// Apply is called with the first spec and with every later spec whose
+	// models changed, before its jobs are reconciled; an error keeps the
+	// previous spec.
+	Apply func(ctx context.Context, spec *Spec) error
+}
+
+// Run reconciles until ctx is done. It fails when the first spec cannot be
+// loaded; later invalid specs are logged and the last valid one is kept.
+func (c *Controller) Run(ctx context.Context) error {
+	var (
+		current *Spec
+		applied []byte
+	)
+	ticker := time.NewTicker(cmp.Or(c.Resync, defaultResync))
+	defer ticker.Stop()
+	for {
+		data, err := os.ReadFile(c.SpecPath)
+		switch {
+		case err != nil && current == nil:
+			return err
+		case err != nil:
+			log.Printf("controller: %v; keeping the last spec", err)
+		case !bytes.Equal(data, applied):
+			spec, err := c.load(ctx, data, current)
+			if err != nil && current == nil {
+				return err
+			}
+			if err != nil {
+				log.Printf("controller: %v; keeping the last spec", err)
+				break
+			}
+			current, applied = spec, data
+		}
+		if err := c.Reconcile(ctx, current, time.Now()); err != nil {
+			log.Printf("controller: reconcile: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// load parses data and applies it when its models differ from current's.
+func (c *Controller) load(ctx context.Context, data []byte, current *Spec) (*Spec, error) {
+	spec, err := ParseSpec(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", c.SpecPath, err)
+	}
+	if c.Apply != nil && (current == nil || current.Models != spec.Models) {
+		if err := c.Apply(ctx, spec); err != nil {
+			return nil, fmt.Errorf("apply %s: %w", c.SpecPath, err)
+		}
+	}
+	log.Printf("controller: loaded %s (schedules %v, %d repos)", c.SpecPath, spec.Schedules, len(spec.Repos))
+	return spec, nil
+}
+
+// Reconcile queues a job for every scheduled task that is due at now: never
+// run by the controller, last queued an interval ago or more, or queued for
+// another generation of the spec. A task whose job is still queued or
+// running is left for the next pass.
+func (c *Controller) Reconcile(ctx context.Context, spec *Spec, now time.Time) error {
+	var errs []error
+	for _, task := range tasks {
+		interval := spec.Interval(task)
+		if interval == 0 {
+			continue
+		}
+		kind, gen := taskKinds[task], spec.Generation(task)
+		recent, err := c.Store.ListJobs(ctx, kind, recentJobs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", task, err))
+			continue
+		}
+		if last := lastQueued(recent); last != nil && last.gen == gen && now.Sub(last.job.CreatedAt) < interval {
+			continue
+		}
+		job, err := c.Store.EnqueueJob(ctx, kind, params(spec, task, gen))
+		if errors.Is(err, db.ErrJobActive) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", task, err))
+			continue
+		}
+		log.Printf("controller: queued %s job %s for %s (generation %s)", kind, job.ID, task, gen)
+	}
+	return errors.Join(errs...)
+}
+
+type queuedJob struct {
+	job *db.Job
+	gen string
+}
+
+// lastQueued returns the newest of recent queued by the controller.
+func lastQueued(recent []db.Job) *queuedJob {
+	for i := range recent {
+		var g generation
+		if json.Unmarshal(recent[i].Params, &g) == nil && g.Generation != "" {
+			return &queuedJob{job: &recent[i], gen: g.Generation}
+		}
+	}
+	return nil
+}
+
+// params are the job parameters of task, tagged with gen.
+func params(spec *Spec, task, gen string) any {
+	switch task {
+	case TaskPRs:
+		return struct {
+			ingestion.RunParams
+			generation
+		}{ingestion.RunParams{Mode: "FULL"}, generation{gen}}
+	case TaskDocs:
+		return struct {
+			DocsParams
+			generation
+		}{DocsParams{Repos: spec.Repos}, generation{gen}}
+	default:
+		return struct {
+			topics.JobParams
+			generation
+		}{topics.DefaultJobParams, generation{gen}}
+	}
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/jobs"
+)
+
+func TestLoadSpec(t *testing.T) {
+	spec, err := LoadSpec(filepath.Join("..", "..", "examples", "ingestion-spec.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Interval(TaskPRs) != time.Hour || spec.Interval(TaskCluster) != 168*time.Hour || len(spec.Repos) != 2 || spec.Models.Dimension != 768 {
+		t.Fatalf("spec = %+v", spec)
+	}
+
+	for name, data := range map[string]string{
+		"unknown task":       "schedules:\n  code: 1h\n",
+		"bad interval":       "schedules:\n  prs: hourly\n",
+		"docs without repos": "schedules:\n  docs: 24h\n",
+		"model without dim":  "models:\n  embedding: nomic-embed-text\n",
+		"unknown field":      "schedule:\n  prs: 1h\n",
+	} {
+		if _, err := ParseSpec([]byte(data)); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+type fakeStore struct {
+	jobs []db.Job // newest first
+}
+
+func (s *fakeStore) EnqueueJob(ctx context.Context, kind string, params any) (*db.Job, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range s.jobs {
+		if j.Kind == kind && j.Status == db.JobQueued {
+			return &j, db.ErrJobActive
+		}
+	}
+	job := db.Job{ID: kind, Kind: kind, Params: raw, Status: db.JobQueued, CreatedAt: time.Now()}
+	s.jobs = append([]db.Job{job}, s.jobs...)
+	return &job, nil
+}
+
+func (s *fakeStore) ListJobs(ctx context.Context, kind string, limit int) ([]db.Job, error) {
+	var out []db.Job
+	for _, j := range s.jobs {
+		if j.Kind == kind {
+			out = append(out, j)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) finishAll(age time.Duration) {
+	for i := range s.jobs {
+		s.jobs[i].Status = db.JobSucceeded
+		s.jobs[i].CreatedAt = s.jobs[i].CreatedAt.Add(-age)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	spec, err := ParseSpec([]byte("schedules:\n  prs: 1h\n  docs: 24h\nrepos:\n  - url: https://github.com/Azure/ARO-HCP\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &fakeStore{}
+	// A job queued by hand does not count as a run of the schedule.
+	store.jobs = []db.Job{{ID: "manual", Kind: jobs.KindIngestion, Params: json.RawMessage(`{"mode":"CACHE"}`), Status: db.JobSucceeded, CreatedAt: time.Now()}}
+
+	if err := newController(store).Reconcile(ctx, spec, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.jobs) != 3 || store.jobs[0].Kind != jobs.KindDocs || store.jobs[1].Kind != jobs.KindIngestion {
+		t.Fatalf("first pass queued %+v", store.jobs)
+	}
+	var params struct {
+		Mode       string `json:"mode"`
+		Generation string `json:"generation"`
+	}
+	if err := json.Unmarshal(store.jobs[1].Params, &params); err != nil || params.Mode != "FULL" || params.Generation != spec.Generation(TaskPRs) {
+		t.Fatalf("prs params = %s", store.jobs[1].Params)
+	}
+
+	// Still queued: nothing new.
+	if err := newController(store).Reconcile(ctx, spec, time.Now()); err != nil || len(store.jobs) != 3 {
+		t.Fatalf("second pass: %d jobs, %v", len(store.jobs), err)
+	}
+
+	// Two hours later only prs is due.
+	store.finishAll(2 * time.Hour)
+	if err := newController(store).Reconcile(ctx, spec, time.Now()); err != nil || len(store.jobs) != 4 || store.jobs[0].Kind != jobs.KindIngestion {
+		t.Fatalf("after 2h: %+v, %v", store.jobs, err)
+	}
+
+	// New repos change the docs generation: docs runs again at once.
+	store.finishAll(0)
+	changed, err := ParseSpec([]byte("schedules:\n  prs: 1h\n  docs: 24h\nrepos:\n  - url: https://github.com/openshift/hypershift\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := newController(store).Reconcile(ctx, changed, time.Now()); err != nil || len(store.jobs) != 5 || store.jobs[0].Kind != jobs.KindDocs {
+		t.Fatalf("after repos changed: %+v, %v", store.jobs, err)
+	}
+}
+
+func newController(store Store) *Controller { return &Controller{Store: store} }
@@ -0,0 +1,140 @@
+// Package controller reconciles ingestion with a declarative spec, usually a
+// ConfigMap mounted into the 'ingest controller' pod: which documentation
+// repos to index, how often each task runs, and with which models. It queues
+// the work as jobs (see internal/jobs) and never runs it itself.
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/docs"
+	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion"
+)
+
+// Tasks a spec schedules.
+const (
+	TaskPRs     = "prs"     // FULL PR ingestion: cache new PRs, then process them
+	TaskDocs    = "docs"    // documentation of the spec's repos
+	TaskCluster = "cluster" // PR topics
+)
+
+var tasks = []string{TaskPRs, TaskDocs, TaskCluster}
+
+// Spec is the desired ingestion configuration:
+//
+//	models:
+//	  embedding: nomic-embed-text
+//	  dimension: 768
+//	  diff_analysis: qwen2.5-coder:7b
+//	schedules:
+//	  prs: 1h
+//	  docs: 24h
+//	  cluster: 168h
+//	repos:
+//	  - url: https://github.com/Azure/ARO-HCP
+//	    include: ["docs/**/*.md"]
+//
+// Tasks without a schedule are not run. repos takes the entries of a
+// docs-repos.yaml file.
+type Spec struct {
+	Models    Models            `yaml:"models"`
+	Schedules map[string]string `yaml:"schedules"`
+	Repos     []docs.RepoConfig `yaml:"repos"`
+
+	intervals map[string]time.Duration
+}
+
+// Models override the models of the environment; empty fields keep them.
+type Models struct {
+	Embedding    string `yaml:"embedding" json:"embedding,omitempty"`
+	Dimension    int    `yaml:"dimension" json:"dimension,omitempty"`
+	DiffAnalysis string `yaml:"diff_analysis" json:"diff_analysis,omitempty"`
+}
+
+// Config returns cfg with the models set.
+func (m Models) Config(cfg ingestion.Config) ingestion.Config {
+	if m.Embedding != "" {
+		cfg.EmbeddingModel, cfg.EmbeddingDim = m.Embedding, m.Dimension
+	}
+	if m.DiffAnalysis != "" {
+		cfg.DiffAnalyzer.ModelName = m.DiffAnalysis
+	}
+	return cfg
+}
+
+// ParseSpec reads and validates a spec. Unknown fields are rejected so a
+// typo does not silently disable a task.
+func ParseSpec(data []byte) (*Spec, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var spec Spec
+	if err := dec.Decode(&spec); err != nil {
+		return nil, err
+	}
+	if (spec.Models.Embedding == "") != (spec.Models.Dimension == 0) {
+		return nil, fmt.Errorf("models: embedding and dimension are set together")
+	}
+	spec.intervals = map[string]time.Duration{}
+	for task, value := range spec.Schedules {
+		if !slices.Contains(tasks, task) {
+			return nil, fmt.Errorf("schedules: unknown task %q (known: %v)", task, tasks)
+		}
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("schedules: %s: invalid interval %q", task, value)
+		}
+		spec.intervals[task] = interval
+	}
+	if spec.intervals[TaskDocs] > 0 && len(spec.Repos) == 0 {
+		return nil, fmt.Errorf("schedules: docs is scheduled but no repos are listed")
+	}
+	for n, repo := range spec.Repos {
+		if repo.URL == "" {
+			return nil, fmt.Errorf("repo %d has no url", n+1)
+		}
+		if _, err := repo.ChunkSpecs(nil); err != nil {
+			return nil, fmt.Errorf("%s: %w", repo.URL, err)
+		}
+	}
+	return &spec, nil
+}
+
+// LoadSpec reads the spec at path.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := ParseSpec(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// Interval is how often task runs, 0 when it is not scheduled.
+func (s *Spec) Interval(task string) time.Duration {
+	return s.intervals[task]
+}
+
+// Generation identifies the part of the spec a task's jobs depend on: the
+// models, plus the repos for docs. A task whose generation changed is run
+// again without waiting for its schedule.
+func (s *Spec) Generation(task string) string {
+	parts := []any{s.Models}
+	if task == TaskDocs {
+		parts = append(parts, s.Repos)
+	}
+	data, _ := json.Marshal(parts)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	pgvector "github.com/pgvector/pgvector-go"
+	"github.com/uptrace/bun"
+)
+
+// codeInsertBatch bounds the rows per INSERT statement.
+const codeInsertBatch = 200
+
+type CodeSearchRow struct {
+	CodeChunk `bun:",extend"`
+	Distance  float64 `bun:"distance"`
+}
+
+// CodeSearchFilter restricts SearchCode. Empty fields match every chunk.
+type CodeSearchFilter struct {
+	Repo       string
+	Language   string   // go|typescript|yaml|bicep
+	Languages  []string // any of these, e.g. a tool's corpus
+	PathPrefix string   // repo-relative, e.g. "frontend/pkg/"
+	Quality    SearchQuality
+}
+
+// ReplaceCodeChunks atomically replaces the repository model's code chunks
+// in languages for repo with chunks. Chunks embedded by other models or in
+// other languages are kept.
+func (r *SearchRepository) ReplaceCodeChunks(ctx context.Context, repo string, languages []string, chunks []CodeChunk) error {
+	if r.embeddingModel == "" {
+		return errNoEmbeddingModel
+	}
+	for i := range chunks {
+		if err := r.checkVector(chunks[i].Embedding.Slice()); err != nil {
+			return fmt.Errorf("%s %s: %w", chunks[i].Path, chunks[i].Symbol, err)
+		}
+		chunks[i].EmbeddingModel = r.embeddingModel
+	}
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewDelete().Model((*CodeChunk)(nil)).
+			Where("repo = ?", repo).
+			Where("embedding_model = ?", r.embeddingModel).
+			Where("language IN (?)", bun.In(languages)).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		for start := 0; start < len(chunks); start += codeInsertBatch {
+			batch := chunks[start:min(start+codeInsertBatch, len(chunks))]
+			// Identical declarations in one commit hash to the same ID.
+			if _, err := tx.NewInsert().Model(&batch).On("CONFLICT (id) DO NOTHING").Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return notifyCorpusChanged(ctx, tx, CorpusCode)
+	})
+}
+
+// filtered reports whether f drops chunks the index would return.
+func (f CodeSearchFilter) filtered() bool {
+	return f.Repo != "" || f.Language != "" || len(f.Languages) > 0 || f.PathPrefix != ""
+}
+
+func (r *SearchRepository) SearchCode(ctx context.Context, embedding []float32, limit int, filter CodeSearchFilter, after *SearchCursor) ([]CodeSearchRow, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if err := r.checkVector(embedding); err != nil {
+		return nil, err
+	}
+	var results []CodeSearchRow
+	q := r.db.NewSelect().Model(&results).
+		Column("id", "repo", "path", "commit_sha", "language", "symbol", "kind",
+			"start_line", "end_line", "chunk_text", "source_url")
+	q = r.rank(q, (*CodeChunk)(nil), embedding, limit, after.skip(), func(q *bun.SelectQuery) *bun.SelectQuery {
+		if filter.Repo != "" {
+			q = q.Where("repo = ?", filter.Repo)
+		}
+		if filter.Language != "" {
+			q = q.Where("language = ?", filter.Language)
+		}
+		if len(filter.Languages) > 0 {
+			q = q.Where("language IN (?)", bun.In(filter.Languages))
+		}
+		if filter.PathPrefix != "" {
+			q = q.Where("starts_with(path, ?)", filter.PathPrefix)
+		}
+		return q
+	})
+	if after != nil {
+		q = q.Where("("+r.distanceExpr()+", id) > (?, ?)", pgvector.NewVector(embedding), after.Distance, after.ID)
+	}
+	if err := r.scanSearch(ctx, q, filter.Quality, after.skip()+limit, filter.filtered() || after != nil); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -3,6 +3,9 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"log"
+	"time"
 
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
@@ -10,9 +13,30 @@ import (
 	"github.com/uptrace/bun/extra/bundebug"
 )
 
+// maxConnectBackoff caps the exponential delay between connection attempts.
+const maxConnectBackoff = 30 * time.Second
+
 type Config struct {
 	DSN   string
 	Debug bool
+
+	// Pool limits; zero leaves the database/sql default in place.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// StatementTimeout is applied with SET on every new connection. Under
+	// pgbouncer transaction pooling session settings do not stick to a
+	// server connection; set statement_timeout on the database role instead
+	// and leave this at zero.
+	StatementTimeout time.Duration
+
+	// ConnectRetries is how many times a failed initial ping is retried,
+	// waiting ConnectBackoff and doubling it after each attempt. Zero skips
+	// the ping entirely and connects lazily.
+	ConnectRetries int
+	ConnectBackoff time.Duration
 }
 
 type Database struct {
@@ -20,15 +44,74 @@ type Database struct {
 }
 
 func NewDatabase(cfg Config) (*Database, error) {
-	connector := pgdriver.NewConnector(pgdriver.WithDSN(cfg.DSN))
+	opts := []pgdriver.Option{pgdriver.WithDSN(cfg.DSN)}
+	if cfg.StatementTimeout > 0 {
+		// Merge rather than WithConnParams, which would drop params parsed from the DSN.
+		opts = append(opts, func(c *pgdriver.Config) {
+			if c.ConnParams == nil {
+				c.ConnParams = make(map[string]interface{})
+			}
+			c.ConnParams["statement_timeout"] = cfg.StatementTimeout.Milliseconds()
+		})
+	}
+	connector := pgdriver.NewConnector(opts...)
 	sqldb := sql.OpenDB(connector)
+	if cfg.MaxOpenConns > 0 {
+		sqldb.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqldb.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqldb.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		sqldb.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
 	db := bun.NewDB(sqldb, pgdialect.New())
+	db.AddQueryHook(tracingHook{})
 
 	if cfg.Debug {
 		db.AddQueryHook(bundebug.NewQueryHook(bundebug.WithVerbose(true)))
 	}
 
-	return &Database{bun: db}, nil
+	database := &Database{bun: db}
+	if cfg.ConnectRetries > 0 {
+		if err := database.pingWithRetry(context.Background(), cfg.ConnectRetries, cfg.ConnectBackoff); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return database, nil
+}
+
+// pingWithRetry pings until the database answers, retrying up to retries
+// times with exponential backoff so services survive starting before
+// Postgres or a pgbouncer restart.
+func (d *Database) pingWithRetry(ctx context.Context, retries int, backoff time.Duration) error {
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	var err error
+	for attempt := 0; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err = d.bun.PingContext(pingCtx)
+		cancel()
+		if err == nil || attempt >= retries {
+			break
+		}
+		log.Printf("db: connect attempt %d/%d failed: %v; retrying in %s", attempt+1, retries+1, err, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	return nil
 }
 
 func (d *Database) Bun() *bun.DB {
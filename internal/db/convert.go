@@ -2,12 +2,13 @@ package db
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
 )
 
-func ToPRResult(entity PREmbedding, similarity *float64) types.PRResult {
+func (r *SearchRepository) ToPRResult(entity PREmbedding, similarity *float64) types.PRResult {
 	var mergedAt *string
 	if entity.MergedAt != nil {
 		v := entity.MergedAt.Format(time.RFC3339)
@@ -21,12 +22,84 @@ func ToPRResult(entity PREmbedding, similarity *float64) types.PRResult {
 		State:           entity.State,
 		CreatedAt:       entity.CreatedAt.Format(time.RFC3339),
 		MergedAt:        mergedAt,
-		GithubURL:       githubURL(entity.PRNumber),
+		GithubURL:       r.PRURL(entity.PRNumber),
+		Labels:          entity.Labels,
+		Milestone:       entity.Milestone,
+		LinkedIssues:    entity.LinkedIssues,
 		SimilarityScore: similarity,
 	}
+	if entity.ChangedFiles != nil {
+		result.Changes = &types.PRChanges{
+			Additions:    derefInt(entity.Additions),
+			Deletions:    derefInt(entity.Deletions),
+			ChangedFiles: *entity.ChangedFiles,
+			TopLevelDirs: entity.TopLevelDirs,
+		}
+	}
 	return result
 }
 
-func githubURL(prNumber int) string {
-	return fmt.Sprintf("https://github.com/Azure/ARO-HCP/pull/%d", prNumber)
+func derefInt(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// ToPRAnalysis reports the analysis state and rich description of entity.
+func ToPRAnalysis(entity PREmbedding) *types.PRAnalysis {
+	analysis := &types.PRAnalysis{Status: "pending"}
+	if entity.ProcessedAt == nil {
+		return analysis
+	}
+	processedAt := entity.ProcessedAt.Format(time.RFC3339)
+	analysis.ProcessedAt = &processedAt
+	analysis.RichDescription = entity.RichDescription
+	if entity.AnalysisSuccessful {
+		analysis.Status = "succeeded"
+	} else {
+		analysis.Status = "failed"
+		analysis.FailureCategory = entity.FailureCategory
+	}
+	return analysis
+}
+
+// ToFailureGroup converts a failure group for list_failed_analyses.
+func (r *SearchRepository) ToFailureGroup(g FailureGroup) types.FailureGroup {
+	group := types.FailureGroup{Category: g.Category, Count: g.Count, PRs: make([]types.FailedAnalysis, 0, len(g.PRs))}
+	for _, pr := range g.PRs {
+		result := r.ToPRResult(pr, nil)
+		failed := types.FailedAnalysis{
+			PRNumber:      pr.PRNumber,
+			Title:         pr.PRTitle,
+			Author:        pr.Author,
+			MergedAt:      result.MergedAt,
+			FailureReason: pr.FailureReason,
+			GithubURL:     result.GithubURL,
+		}
+		if pr.ProcessedAt != nil {
+			failed.ProcessedAt = pr.ProcessedAt.Format(time.RFC3339)
+		}
+		group.PRs = append(group.PRs, failed)
+	}
+	return group
+}
+
+// PRURL links to PR prNumber in the ingested repository.
+func (r *SearchRepository) PRURL(prNumber int) string {
+	return fmt.Sprintf("%s/pull/%d", r.repoWebURL(), prNumber)
+}
+
+// CommitURL links to commit sha in the ingested repository.
+func (r *SearchRepository) CommitURL(sha string) string {
+	return r.repoWebURL() + "/commit/" + sha
+}
+
+// repoWebURL is the repository URL without a trailing ".git" or slash.
+func (r *SearchRepository) repoWebURL() string {
+	url := r.repoURL
+	if url == "" {
+		url = defaultRepoURL
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
 }
@@ -0,0 +1,165 @@
+//go:build integration
+
+// Package dbtest provides migrated pgvector databases to integration tests,
+// which run with
+//
+//	go test -tags integration ./...
+//
+// A pgvector/pgvector Postgres container is started with the docker CLI on
+// first use and shared by the tests of the package; each test gets its own
+// database. Set INTELHUB_TEST_POSTGRES_URL to use an existing server instead,
+// e.g. in CI without Docker. Its role must be allowed to create databases.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	dbmigrate "github.com/roivaz/aro-hcp-intelhub/internal/db/migrate"
+)
+
+const (
+	image     = "docker.io/pgvector/pgvector:pg16" // as in docker-compose.yml
+	serverEnv = "INTELHUB_TEST_POSTGRES_URL"
+)
+
+var server struct {
+	once      sync.Once
+	container string // ID of the container started by serverDSN
+	dsn       string
+	err       error
+}
+
+// serverDSN returns the DSN of the shared server, starting the container on
+// first use. TestMain stops it through Stop, which also removes it (--rm);
+// those left behind by killed test runs carry the intelhub-dbtest label.
+func serverDSN() (string, error) {
+	server.once.Do(func() {
+		if dsn := os.Getenv(serverEnv); dsn != "" {
+			server.dsn = dsn
+			return
+		}
+		out, err := exec.Command("docker", "run", "-d", "--rm",
+			"--label", "intelhub-dbtest=true",
+			"-e", "POSTGRES_PASSWORD=postgres",
+			"-e", "POSTGRES_DB=intelhub",
+			"-p", "127.0.0.1::5432",
+			image).Output()
+		if err != nil {
+			server.err = fmt.Errorf("start %s: %w%s", image, err, stderr(err))
+			return
+		}
+		server.container = strings.TrimSpace(string(out))
+		port, err := exec.Command("docker", "port", server.container, "5432/tcp").Output()
+		if err != nil {
+			server.err = fmt.Errorf("read port of %s: %w%s", image, err, stderr(err))
+			return
+		}
+		// "127.0.0.1:49153", possibly followed by an IPv6 mapping.
+		hostPort, _, _ := strings.Cut(strings.TrimSpace(string(port)), "\n")
+		server.dsn = "postgres://postgres:postgres@" + hostPort + "/intelhub?sslmode=disable"
+		server.err = waitReady(server.dsn)
+	})
+	return server.dsn, server.err
+}
+
+// waitReady waits until the server accepts connections. The entrypoint
+// restarts Postgres once after initdb, so a single successful ping right
+// after start is not enough; a query must succeed.
+func waitReady(dsn string) error {
+	database, err := db.NewDatabase(db.Config{DSN: dsn})
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	deadline := time.Now().Add(time.Minute)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err := database.Bun().ExecContext(ctx, "SELECT 1")
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("postgres not ready: %w", err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// Stop removes the container started for the package's tests, if any. Call
+// it from TestMain after m.Run.
+func Stop() {
+	if server.container != "" {
+		_ = exec.Command("docker", "stop", server.container).Run()
+	}
+}
+
+func stderr(err error) string {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return ": " + strings.TrimSpace(string(exitErr.Stderr))
+	}
+	return ""
+}
+
+// NewDatabase creates an empty database for t on the shared server and drops
+// it when t ends. No migrations are applied.
+func NewDatabase(t testing.TB) *db.Database {
+	t.Helper()
+	base, err := serverDSN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := db.NewDatabase(db.Config{DSN: base, ConnectRetries: 5, ConnectBackoff: time.Second})
+	if err != nil {
+		t.Fatalf("connect to %s: %v", serverEnv, err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	suffix := make([]byte, 6)
+	_, _ = rand.Read(suffix)
+	name := "test_" + hex.EncodeToString(suffix)
+	ctx := context.Background()
+	if _, err := admin.Bun().ExecContext(ctx, "CREATE DATABASE "+name); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Bun().ExecContext(context.Background(), "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)"); err != nil {
+			t.Logf("drop database %s: %v", name, err)
+		}
+	})
+
+	u, err := url.Parse(base)
+	if err != nil {
+		t.Fatalf("parse %s: %v", serverEnv, err)
+	}
+	u.Path = "/" + name
+	database, err := db.NewDatabase(db.Config{DSN: u.String()})
+	if err != nil {
+		t.Fatalf("connect to %s: %v", name, err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+// NewMigrated is NewDatabase with every migration applied.
+func NewMigrated(t testing.TB) *db.Database {
+	t.Helper()
+	database := NewDatabase(t)
+	if err := dbmigrate.EnsureCurrent(context.Background(), database.Bun(), "", true); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return database
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// disabledFailureCategory marks PRs processed with diff analysis turned off,
+// which do not count as failed attempts.
+const disabledFailureCategory = "disabled"
+
+// notDeadLettered keeps dead-lettered PRs out of a pr_embeddings query.
+const notDeadLettered = "pr_number NOT IN (SELECT pr_number FROM pr_processing_deadletter)"
+
+// failureEntry is one element of a PR's failure chain.
+func failureEntry(at time.Time, category *string, reason string) string {
+	c := UnknownFailureCategory
+	if category != nil && *category != "" {
+		c = *category
+	}
+	return fmt.Sprintf("%s [%s] %s", at.UTC().Format(time.RFC3339), c, reason)
+}
+
+// deadLetter moves prNumber to pr_processing_deadletter when it has failed
+// at least maxAttempts times.
+func deadLetter(ctx context.Context, db bun.IDB, prNumber, maxAttempts int) error {
+	var attempts []int
+	err := db.NewRaw(`INSERT INTO pr_processing_deadletter (pr_number, attempts, failure_category, failure_chain)
+SELECT pr_number, failed_attempts, failure_category, coalesce(failure_chain, '{}')
+FROM pr_embeddings WHERE pr_number = ? AND failed_attempts >= ?
+ON CONFLICT (pr_number) DO UPDATE SET
+  attempts = EXCLUDED.attempts,
+  failure_category = EXCLUDED.failure_category,
+  failure_chain = EXCLUDED.failure_chain,
+  deadlettered_at = now()
+RETURNING attempts`, prNumber, maxAttempts).Scan(ctx, &attempts)
+	if err != nil {
+		return fmt.Errorf("dead-letter PR %d: %w", prNumber, err)
+	}
+	if len(attempts) > 0 {
+		log.Printf("PR #%d dead-lettered after %d failed processing attempts", prNumber, attempts[0])
+	}
+	return nil
+}
+
+// DeadLetters returns the dead-lettered PRs, most recent first.
+func (r *SearchRepository) DeadLetters(ctx context.Context) ([]PRDeadLetter, error) {
+	var letters []PRDeadLetter
+	err := r.db.NewSelect().Model(&letters).
+		OrderExpr("deadlettered_at DESC, pr_number DESC").
+		Scan(ctx)
+	return letters, err
+}
+
+// RequeueDeadLetters takes the dead-lettered PRs among prNumbers, or every
+// one when prNumbers is nil, out of pr_processing_deadletter and marks them
+// unprocessed with a fresh attempt budget. Their failure chain is kept. It
+// returns the PR numbers requeued.
+func (r *SearchRepository) RequeueDeadLetters(ctx context.Context, prNumbers []int) ([]int, error) {
+	requeued := []int{}
+	if prNumbers != nil && len(prNumbers) == 0 {
+		return requeued, nil
+	}
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		q := tx.NewDelete().Model((*PRDeadLetter)(nil)).Returning("pr_number")
+		if prNumbers != nil {
+			q = q.Where("pr_number IN (?)", bun.In(prNumbers))
+		} else {
+			q = q.Where("TRUE")
+		}
+		if _, err := q.Exec(ctx, &requeued); err != nil {
+			return err
+		}
+		if len(requeued) == 0 {
+			return nil
+		}
+		_, err := tx.NewUpdate().Model((*PREmbedding)(nil)).
+			Set("processed_at = NULL").
+			Set("failed_attempts = 0").
+			Set("claimed_by = NULL").
+			Set("claimed_until = NULL").
+			Where("pr_number IN (?)", bun.In(requeued)).
+			Exec(ctx)
+		return err
+	})
+	return requeued, err
+}
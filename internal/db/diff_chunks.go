@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	pgvector "github.com/pgvector/pgvector-go"
+	"github.com/uptrace/bun"
+)
+
+type PRDiffSearchRow struct {
+	PRDiffChunk `bun:",extend"`
+	Distance    float64 `bun:"distance"`
+}
+
+// PRDiffSearchFilter restricts SearchPRDiffs. Empty fields match every chunk.
+type PRDiffSearchFilter struct {
+	PRNumber   int
+	PathPrefix string // repo-relative, e.g. "frontend/pkg/"
+	Quality    SearchQuality
+}
+
+// ReplacePRDiffChunks replaces the repository model's diff chunks of
+// prNumber with chunks.
+func (r *SearchRepository) ReplacePRDiffChunks(ctx context.Context, prNumber int, chunks []PRDiffChunk) error {
+	if r.embeddingModel == "" {
+		return errNoEmbeddingModel
+	}
+	for i := range chunks {
+		if err := r.checkVector(chunks[i].Embedding.Slice()); err != nil {
+			return fmt.Errorf("%s: %w", chunks[i].Path, err)
+		}
+		chunks[i].PRNumber = prNumber
+		chunks[i].EmbeddingModel = r.embeddingModel
+	}
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewDelete().Model((*PRDiffChunk)(nil)).
+			Where("pr_number = ?", prNumber).
+			Where("embedding_model = ?", r.embeddingModel).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		if len(chunks) > 0 {
+			if _, err := tx.NewInsert().Model(&chunks).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return notifyCorpusChanged(ctx, tx, CorpusPRs)
+	})
+}
+
+// filtered reports whether f drops chunks the index would return.
+func (f PRDiffSearchFilter) filtered() bool {
+	return f.PRNumber > 0 || f.PathPrefix != ""
+}
+
+// SearchPRDiffs ranks the stored PR diff chunks by similarity to the given
+// embedding.
+func (r *SearchRepository) SearchPRDiffs(ctx context.Context, embedding []float32, limit int, filter PRDiffSearchFilter, after *SearchCursor) ([]PRDiffSearchRow, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if err := r.checkVector(embedding); err != nil {
+		return nil, err
+	}
+	var afterID int64
+	if after != nil {
+		id, err := strconv.ParseInt(after.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor id %q", after.ID)
+		}
+		afterID = id
+	}
+	var results []PRDiffSearchRow
+	q := r.db.NewSelect().Model(&results).
+		Column("id", "pr_number", "path", "chunk_index", "chunk_text")
+	q = r.rank(q, (*PRDiffChunk)(nil), embedding, limit, after.skip(), func(q *bun.SelectQuery) *bun.SelectQuery {
+		if filter.PRNumber > 0 {
+			q = q.Where("pr_number = ?", filter.PRNumber)
+		}
+		if filter.PathPrefix != "" {
+			q = q.Where("starts_with(path, ?)", filter.PathPrefix)
+		}
+		return q
+	})
+	if after != nil {
+		q = q.Where("("+r.distanceExpr()+", id) > (?, ?)", pgvector.NewVector(embedding), after.Distance, afterID)
+	}
+	if err := r.scanSearch(ctx, q, filter.Quality, after.skip()+limit, filter.filtered() || after != nil); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// PRsByNumbers returns the stored PRs among numbers, without their
+// embeddings.
+func (r *SearchRepository) PRsByNumbers(ctx context.Context, numbers []int) ([]PREmbedding, error) {
+	if len(numbers) == 0 {
+		return nil, nil
+	}
+	var prs []PREmbedding
+	err := r.db.NewSelect().Model(&prs).
+		ExcludeColumn("embedding", "description_embedding").
+		Where("pr_number IN (?)", bun.In(numbers)).
+		Scan(ctx)
+	return prs, err
+}
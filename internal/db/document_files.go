@@ -0,0 +1,65 @@
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ContentHash is the hex SHA-256 of a file's content, as recorded in
+// document_files.
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// DocumentFile returns the full content of path in repo at commitSHA as
+// stored by the docs ingester. ok is false for files ingested before file
+// content was stored.
+func (r *SearchRepository) DocumentFile(ctx context.Context, repo, commitSHA, path string) (content []byte, ok bool, err error) {
+	var file DocumentFile
+	err = r.db.NewSelect().
+		Model(&file).
+		Where("f.repo = ?", repo).
+		Where("f.commit_sha = ?", commitSHA).
+		Where("f.path = ?", path).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	content, err = gunzipBytes(file.Content)
+	if err != nil {
+		return nil, false, fmt.Errorf("decompress %s@%s:%s: %w", repo, commitSHA, path, err)
+	}
+	return content, true, nil
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
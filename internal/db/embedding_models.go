@@ -0,0 +1,313 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	pgvector "github.com/pgvector/pgvector-go"
+	"github.com/uptrace/bun"
+)
+
+// Quantization modes for the per-model HNSW indexes. Vectors are always
+// stored at full precision; quantization shrinks the index, and the exact
+// distance can re-rank the candidates the index returns.
+const (
+	QuantizationNone    = "none"
+	QuantizationHalfvec = "halfvec" // 16-bit floats, half the index size
+	QuantizationBit     = "bit"     // 1 bit per dimension, ranked by Hamming distance
+)
+
+// Distance metrics a model's vectors are compared with. Cosine suits most
+// models; some are trained for inner product, which pgvector negates so
+// smaller is closer.
+const (
+	DistanceCosine       = "cosine"
+	DistanceInnerProduct = "inner_product"
+	DistanceL2           = "l2"
+)
+
+// distanceMetrics maps each metric to its pgvector operator and the suffix
+// of its vector and halfvec operator classes.
+var distanceMetrics = map[string]struct{ operator, ops string }{
+	DistanceCosine:       {"<=>", "cosine_ops"},
+	DistanceInnerProduct: {"<#>", "ip_ops"},
+	DistanceL2:           {"<->", "l2_ops"},
+}
+
+// embeddingTables hold vectors tagged with the model that produced them.
+var embeddingTables = []string{"pr_embeddings", "documents", "code_chunks", "pr_diff_chunks"}
+
+// descriptionIndexTable names the HNSW indexes over
+// pr_embeddings.description_embedding.
+const descriptionIndexTable = "pr_embeddings_description"
+
+var errNoEmbeddingModel = errors.New("no embedding model configured on the repository")
+
+// WithEmbeddingModel sets the model whose vectors are written and searched.
+// Rows embedded by other models are ignored by searches and, for PRs, queued
+// for re-embedding.
+func WithEmbeddingModel(name string, dimension int) func(*SearchRepository) {
+	return func(r *SearchRepository) {
+		r.embeddingModel = name
+		r.embeddingDim = dimension
+	}
+}
+
+// WithQuantization indexes vectors with mode (QuantizationHalfvec or
+// QuantizationBit) and re-ranks the top rerank index candidates by exact
+// distance. rerank 0 returns halfvec distances as-is; bit always re-ranks.
+func WithQuantization(mode string, rerank int) func(*SearchRepository) {
+	return func(r *SearchRepository) {
+		r.quantization = mode
+		r.rerankCandidates = rerank
+	}
+}
+
+// WithDistanceMetric compares vectors with metric (DistanceCosine,
+// DistanceInnerProduct or DistanceL2); empty means cosine.
+func WithDistanceMetric(metric string) func(*SearchRepository) {
+	return func(r *SearchRepository) {
+		r.distanceMetric = metric
+	}
+}
+
+// EmbeddingIndexName returns the name of the HNSW index covering model's
+// vectors in table with the given quantization and metric. Names hash the
+// model so any model name yields a valid, bounded identifier; dbctl diff
+// recognises them by this shape.
+func EmbeddingIndexName(table, model, quantization, metric string) string {
+	key := model
+	if quantization != "" && quantization != QuantizationNone {
+		key += "|" + quantization
+	}
+	if metric != "" && metric != DistanceCosine {
+		key += "|" + metric
+	}
+	sum := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%s_hnsw_%s", table, hex.EncodeToString(sum[:4]))
+}
+
+// RegisterEmbeddingModel records the repository's embedding model, its
+// dimension and distance metric, and creates the partial HNSW indexes its
+// searches use, logging the ones it cannot build. It fails when the model was registered before with a
+// different dimension or metric.
+func (r *SearchRepository) RegisterEmbeddingModel(ctx context.Context) error {
+	if r.embeddingModel == "" || r.embeddingDim <= 0 {
+		return errNoEmbeddingModel
+	}
+	switch r.quantization {
+	case "", QuantizationNone, QuantizationHalfvec:
+	case QuantizationBit:
+		if r.rerankCandidates <= 0 {
+			return fmt.Errorf("bit quantization requires a positive re-rank candidate count")
+		}
+	default:
+		return fmt.Errorf("unknown embedding quantization %q (valid: none, halfvec, bit)", r.quantization)
+	}
+	if _, ok := distanceMetrics[r.metric()]; !ok {
+		return fmt.Errorf("unknown embedding distance metric %q (valid: cosine, inner_product, l2)", r.distanceMetric)
+	}
+	model := &EmbeddingModel{Name: r.embeddingModel, Dimension: r.embeddingDim, DistanceMetric: r.metric()}
+	if _, err := r.db.NewInsert().Model(model).On("CONFLICT (name) DO NOTHING").Exec(ctx); err != nil {
+		return fmt.Errorf("register embedding model %s: %w", r.embeddingModel, err)
+	}
+	stored := new(EmbeddingModel)
+	if err := r.db.NewSelect().Model(stored).Where("name = ?", r.embeddingModel).Scan(ctx); err != nil {
+		return fmt.Errorf("load embedding model %s: %w", r.embeddingModel, err)
+	}
+	if stored.Dimension != r.embeddingDim {
+		return fmt.Errorf("embedding model %s is registered with dimension %d, configured %d", r.embeddingModel, stored.Dimension, r.embeddingDim)
+	}
+	if stored.DistanceMetric != r.metric() {
+		return fmt.Errorf("embedding model %s is registered with distance metric %s, configured %s", r.embeddingModel, stored.DistanceMetric, r.metric())
+	}
+
+	for _, table := range embeddingTables {
+		r.createEmbeddingIndex(ctx, table, table, "embedding")
+	}
+	r.createEmbeddingIndex(ctx, descriptionIndexTable, "pr_embeddings", "description_embedding")
+	return nil
+}
+
+// createEmbeddingIndex builds a partial HNSW index without blocking writes to
+// table. A failed build is logged rather than returned: searches still work,
+// only slower, and the next start retries it. The invalid index a failed
+// concurrent build leaves behind is dropped first, as IF NOT EXISTS would
+// otherwise keep it.
+func (r *SearchRepository) createEmbeddingIndex(ctx context.Context, name, table, column string) {
+	index := EmbeddingIndexName(name, r.embeddingModel, r.quantization, r.distanceMetric)
+	var invalid bool
+	err := r.db.NewRaw(
+		"SELECT EXISTS (SELECT 1 FROM pg_index WHERE indexrelid = to_regclass(?) AND NOT indisvalid)", index,
+	).Scan(ctx, &invalid)
+	if err == nil && invalid {
+		_, err = r.db.NewRaw("DROP INDEX CONCURRENTLY IF EXISTS ?", bun.Ident(index)).Exec(ctx)
+	}
+	if err == nil {
+		_, err = r.db.NewRaw(
+			"CREATE INDEX CONCURRENTLY IF NOT EXISTS ? ON ? USING hnsw ("+r.indexExpr(column)+") WHERE embedding_model = ?",
+			bun.Ident(index), bun.Ident(table), r.embeddingModel,
+		).Exec(ctx)
+	}
+	if err != nil {
+		log.Printf("create %s index for %s: %v", name, r.embeddingModel, err)
+	}
+}
+
+// EmbeddingModels returns the registered models, oldest first.
+func (r *SearchRepository) EmbeddingModels(ctx context.Context) ([]EmbeddingModel, error) {
+	var models []EmbeddingModel
+	if err := r.db.NewSelect().Model(&models).OrderExpr("registered_at, name").Scan(ctx); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// metric is the repository's distance metric, cosine unless set.
+func (r *SearchRepository) metric() string {
+	if r.distanceMetric == "" {
+		return DistanceCosine
+	}
+	return r.distanceMetric
+}
+
+// operator is the pgvector distance operator of the metric.
+func (r *SearchRepository) operator() string {
+	return distanceMetrics[r.metric()].operator
+}
+
+// Similarity maps a distance of the repository's metric onto a score in
+// [0, 1], 1 being identical: 1 - d/2 for cosine, (1 - d)/2 for the negated
+// inner product of unit vectors and 1 - d²/4 for L2 between unit vectors.
+func (r *SearchRepository) Similarity(distance float64) float64 {
+	var s float64
+	switch r.metric() {
+	case DistanceInnerProduct:
+		s = (1 - distance) / 2
+	case DistanceL2:
+		s = 1 - distance*distance/4
+	default:
+		s = 1 - distance/2
+	}
+	return min(max(s, 0), 1)
+}
+
+// maxDistance is the largest distance scoring at least minSimilarity, the
+// inverse of Similarity.
+func (r *SearchRepository) maxDistance(minSimilarity float64) float64 {
+	switch r.metric() {
+	case DistanceInnerProduct:
+		return 1 - 2*minSimilarity
+	case DistanceL2:
+		return 2 * math.Sqrt(max(1-minSimilarity, 0))
+	default:
+		return 2 * (1 - minSimilarity)
+	}
+}
+
+// indexExpr is the indexed expression on column and operator class for the
+// configured quantization and metric, cast to the model's dimension. Bit
+// indexes always use Hamming distance and rely on re-ranking for the metric.
+func (r *SearchRepository) indexExpr(column string) string {
+	ops := distanceMetrics[r.metric()].ops
+	switch r.quantization {
+	case QuantizationHalfvec:
+		return fmt.Sprintf("(%s::halfvec(%d)) halfvec_%s", column, r.embeddingDim, ops)
+	case QuantizationBit:
+		return fmt.Sprintf("(binary_quantize(%s::vector(%d))::bit(%[2]d)) bit_hamming_ops", column, r.embeddingDim)
+	default:
+		return fmt.Sprintf("(%s::vector(%d)) vector_%s", column, r.embeddingDim, ops)
+	}
+}
+
+// approxExpr orders rows by distance to the query vector using the model's
+// index.
+func (r *SearchRepository) approxExpr() string {
+	return r.approxExprOn("embedding")
+}
+
+func (r *SearchRepository) approxExprOn(column string) string {
+	switch r.quantization {
+	case QuantizationHalfvec:
+		return fmt.Sprintf("%s::halfvec(%d) %s ?::halfvec(%[2]d)", column, r.embeddingDim, r.operator())
+	case QuantizationBit:
+		return fmt.Sprintf("binary_quantize(%s::vector(%d))::bit(%[2]d) <~> binary_quantize(?::vector(%[2]d))", column, r.embeddingDim)
+	default:
+		return r.exactExprOn(column)
+	}
+}
+
+// exactExpr is the full-precision distance to the query vector.
+func (r *SearchRepository) exactExpr() string {
+	return r.exactExprOn("embedding")
+}
+
+func (r *SearchRepository) exactExprOn(column string) string {
+	return fmt.Sprintf("%s::vector(%d) %s ?", column, r.embeddingDim, r.operator())
+}
+
+// reranks reports whether searches pick candidates from a quantized index and
+// order them by exact distance.
+func (r *SearchRepository) reranks() bool {
+	switch r.quantization {
+	case QuantizationHalfvec:
+		return r.rerankCandidates > 0
+	case QuantizationBit:
+		return true
+	default:
+		return false
+	}
+}
+
+// candidateWindow is the number of index candidates a re-ranked search
+// needs to return limit rows after skipping skip.
+func (r *SearchRepository) candidateWindow(limit, skip int) int {
+	return max(r.rerankCandidates, skip+limit)
+}
+
+// distanceExpr is the distance returned to callers and used by cursors.
+func (r *SearchRepository) distanceExpr() string {
+	if r.quantization == QuantizationHalfvec && !r.reranks() {
+		return r.approxExpr()
+	}
+	return r.exactExpr()
+}
+
+// rank adds the distance column to q and orders it, restricted to the
+// repository's model and the rows accepted by filter. With re-ranking the
+// quantized index first selects the candidates, a window grown by the skip
+// rows earlier pages returned so later pages are not cut short.
+func (r *SearchRepository) rank(q *bun.SelectQuery, model any, embedding []float32, limit, skip int, filter func(*bun.SelectQuery) *bun.SelectQuery) *bun.SelectQuery {
+	vec := pgvector.NewVector(embedding)
+	q = q.ColumnExpr(r.distanceExpr()+" AS distance", vec)
+	if r.reranks() {
+		candidates := filter(r.embeddingFilter(r.db.NewSelect().Model(model).Column("id"))).
+			OrderExpr(r.approxExpr(), vec).
+			Limit(r.candidateWindow(limit, skip))
+		q = q.Where("id IN (?)", candidates)
+	} else {
+		q = filter(r.embeddingFilter(q))
+	}
+	return q.OrderExpr("distance, id").Limit(limit)
+}
+
+func (r *SearchRepository) embeddingFilter(q *bun.SelectQuery) *bun.SelectQuery {
+	return q.Where("embedding_model = ?", r.embeddingModel)
+}
+
+// checkVector verifies the repository has a model and that vec matches
+// its dimension.
+func (r *SearchRepository) checkVector(vec []float32) error {
+	if r.embeddingModel == "" || r.embeddingDim <= 0 {
+		return errNoEmbeddingModel
+	}
+	if len(vec) != r.embeddingDim {
+		return fmt.Errorf("embedding has %d dimensions, model %s expects %d", len(vec), r.embeddingModel, r.embeddingDim)
+	}
+	return nil
+}
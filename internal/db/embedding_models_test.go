@@ -0,0 +1,42 @@
+package db
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	for _, tc := range []struct {
+		metric   string
+		distance float64
+		want     float64
+	}{
+		{"", 0, 1},
+		{DistanceCosine, 0.5, 0.75},
+		{DistanceCosine, 2, 0},
+		{DistanceCosine, 2.5, 0},
+		{DistanceInnerProduct, -1, 1},
+		{DistanceInnerProduct, 0, 0.5},
+		{DistanceInnerProduct, 1, 0},
+		{DistanceL2, 0, 1},
+		{DistanceL2, 1, 0.75},
+		{DistanceL2, 2, 0},
+	} {
+		r := &SearchRepository{distanceMetric: tc.metric}
+		if got := r.Similarity(tc.distance); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%q: Similarity(%v) = %v, want %v", tc.metric, tc.distance, got, tc.want)
+		}
+	}
+}
+
+func TestMaxDistance(t *testing.T) {
+	for _, metric := range []string{DistanceCosine, DistanceInnerProduct, DistanceL2} {
+		r := &SearchRepository{distanceMetric: metric}
+		for _, score := range []float64{0, 0.25, 0.5, 0.9, 1} {
+			d := r.maxDistance(score)
+			if got := r.Similarity(d); math.Abs(got-score) > 1e-9 {
+				t.Errorf("%s: Similarity(maxDistance(%v)) = %v", metric, score, got)
+			}
+		}
+	}
+}
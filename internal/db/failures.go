@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// UnknownFailureCategory groups failed analyses stored without a category.
+const UnknownFailureCategory = "unknown"
+
+// FailureGroup is one failure category with its total count and the most
+// recently processed PRs in it.
+type FailureGroup struct {
+	Category string
+	Count    int
+	PRs      []PREmbedding
+}
+
+func failedFilter(q *bun.SelectQuery) *bun.SelectQuery {
+	return q.Where("processed_at IS NOT NULL").Where("NOT analysis_successful")
+}
+
+// FailedAnalyses groups PRs whose analysis failed by failure category, largest
+// group first, with up to perCategory PRs each. An empty category returns
+// every group.
+func (r *SearchRepository) FailedAnalyses(ctx context.Context, category string, perCategory int) ([]FailureGroup, error) {
+	if perCategory <= 0 {
+		perCategory = 20
+	}
+	var groups []FailureGroup
+	q := r.db.NewSelect().Model((*PREmbedding)(nil)).
+		ColumnExpr("coalesce(failure_category, ?) AS category", UnknownFailureCategory).
+		ColumnExpr("count(*) AS count").
+		Apply(failedFilter).
+		GroupExpr("1").
+		OrderExpr("count DESC, category")
+	if category != "" {
+		q = q.Where("coalesce(failure_category, ?) = ?", UnknownFailureCategory, category)
+	}
+	var counts []struct {
+		Category string `bun:"category"`
+		Count    int    `bun:"count"`
+	}
+	if err := q.Scan(ctx, &counts); err != nil {
+		return nil, err
+	}
+	for _, c := range counts {
+		var prs []PREmbedding
+		err := r.db.NewSelect().Model(&prs).
+			ExcludeColumn("embedding", "description_embedding").
+			Apply(failedFilter).
+			Where("coalesce(failure_category, ?) = ?", UnknownFailureCategory, c.Category).
+			OrderExpr("processed_at DESC").
+			Limit(perCategory).
+			Scan(ctx)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, FailureGroup{Category: c.Category, Count: c.Count, PRs: prs})
+	}
+	return groups, nil
+}
+
+// RequeuePRs marks the failed analyses among prNumbers unprocessed so the
+// next PROCESS run retries them, and returns the PR numbers requeued. PRs
+// that are pending, were analysed successfully or are dead-lettered (see
+// RequeueDeadLetters) are left alone.
+func (r *SearchRepository) RequeuePRs(ctx context.Context, prNumbers []int) ([]int, error) {
+	requeued := []int{}
+	if len(prNumbers) == 0 {
+		return requeued, nil
+	}
+	_, err := r.db.NewUpdate().Model((*PREmbedding)(nil)).
+		Set("processed_at = NULL").
+		Set("claimed_by = NULL").
+		Set("claimed_until = NULL").
+		Where("pr_number IN (?)", bun.In(prNumbers)).
+		Where("processed_at IS NOT NULL").
+		Where("NOT analysis_successful").
+		Where(notDeadLettered).
+		Returning("pr_number").
+		Exec(ctx, &requeued)
+	return requeued, err
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	FeedbackSearchResult    = "search_result"
+	FeedbackRichDescription = "rich_description"
+)
+
+// FeedbackTotals counts the marks on one feedback target.
+type FeedbackTotals struct {
+	Target    string `bun:"target"`
+	Helpful   int    `bun:"helpful"`
+	Unhelpful int    `bun:"unhelpful"`
+}
+
+// FeedbackPR counts the marks on one PR.
+type FeedbackPR struct {
+	PRNumber  int    `bun:"pr_number"`
+	Title     string `bun:"pr_title"`
+	Helpful   int    `bun:"helpful"`
+	Unhelpful int    `bun:"unhelpful"`
+}
+
+// FeedbackSummary aggregates the feedback given since a point in time.
+type FeedbackSummary struct {
+	Since   time.Time
+	Targets []FeedbackTotals
+	// WorstDescriptions are the PRs whose rich description was marked
+	// unhelpful most often.
+	WorstDescriptions []FeedbackPR
+}
+
+// RecordFeedback stores fb, tagging it with the repository's embedding model.
+func (r *SearchRepository) RecordFeedback(ctx context.Context, fb *PRFeedback) error {
+	if fb.EmbeddingModel == nil && r.embeddingModel != "" {
+		fb.EmbeddingModel = &r.embeddingModel
+	}
+	_, err := r.db.NewInsert().Model(fb).Exec(ctx)
+	return err
+}
+
+// FeedbackSummary aggregates feedback given since since, listing up to worst
+// PRs with unhelpful rich descriptions.
+func (r *SearchRepository) FeedbackSummary(ctx context.Context, since time.Time, worst int) (FeedbackSummary, error) {
+	summary := FeedbackSummary{Since: since}
+	err := r.db.NewSelect().Model((*PRFeedback)(nil)).
+		Column("target").
+		ColumnExpr("count(*) FILTER (WHERE helpful) AS helpful").
+		ColumnExpr("count(*) FILTER (WHERE NOT helpful) AS unhelpful").
+		Where("created_at >= ?", since).
+		Group("target").
+		Order("target").
+		Scan(ctx, &summary.Targets)
+	if err != nil {
+		return FeedbackSummary{}, err
+	}
+	if worst <= 0 {
+		return summary, nil
+	}
+	err = r.db.NewSelect().TableExpr("pr_feedback AS f").
+		Join("LEFT JOIN pr_embeddings AS p ON p.pr_number = f.pr_number").
+		ColumnExpr("f.pr_number").
+		ColumnExpr("coalesce(max(p.pr_title), '') AS pr_title").
+		ColumnExpr("count(*) FILTER (WHERE f.helpful) AS helpful").
+		ColumnExpr("count(*) FILTER (WHERE NOT f.helpful) AS unhelpful").
+		Where("f.target = ?", FeedbackRichDescription).
+		Where("f.created_at >= ?", since).
+		GroupExpr("f.pr_number").
+		Having("count(*) FILTER (WHERE NOT f.helpful) > 0").
+		OrderExpr("unhelpful DESC, f.pr_number DESC").
+		Limit(worst).
+		Scan(ctx, &summary.WorstDescriptions)
+	if err != nil {
+		return FeedbackSummary{}, err
+	}
+	return summary, nil
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// IndexedSource summarizes the documentation chunks of one repository and
+// component embedded with the repository's model.
+type IndexedSource struct {
+	Repo       string    `bun:"repo"`
+	Component  *string   `bun:"component"`
+	DocTypes   []string  `bun:"doc_types,array"`
+	Files      int       `bun:"files"`
+	Chunks     int       `bun:"chunks"`
+	CommitSHA  string    `bun:"commit_sha"` // commit of the most recently written chunk
+	LastUpdate time.Time `bun:"last_update"`
+}
+
+// IndexedSources lists the repositories and components search_docs can
+// filter on, ordered by repo and component.
+func (r *SearchRepository) IndexedSources(ctx context.Context) ([]IndexedSource, error) {
+	var sources []IndexedSource
+	err := r.db.NewSelect().Model((*DocumentChunk)(nil)).
+		Column("repo", "component").
+		ColumnExpr("array_agg(DISTINCT doc_type ORDER BY doc_type) AS doc_types").
+		ColumnExpr("count(DISTINCT path) AS files").
+		ColumnExpr("count(*) AS chunks").
+		ColumnExpr("(array_agg(commit_sha ORDER BY updated_at DESC))[1] AS commit_sha").
+		ColumnExpr("max(updated_at) AS last_update").
+		Where("embedding_model = ?", r.embeddingModel).
+		Group("repo", "component").
+		OrderExpr("repo, component NULLS FIRST").
+		Scan(ctx, &sources)
+	return sources, err
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// ErrJobActive is returned by EnqueueJob when a job of the same kind is
+// already queued or running.
+var ErrJobActive = errors.New("job already active")
+
+// EnqueueJob queues a job of kind with params encoded as JSON. Only one job
+// of a kind is queued or running at a time; otherwise the active job is
+// returned with ErrJobActive.
+func (r *SearchRepository) EnqueueJob(ctx context.Context, kind string, params any) (*Job, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s job params: %w", kind, err)
+	}
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{ID: id, Kind: kind, Params: raw, Status: JobQueued}
+	res, err := r.db.NewInsert().Model(job).
+		On("CONFLICT (kind) WHERE status IN ('queued', 'running') DO NOTHING").
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("enqueue %s job: %w", kind, err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return job, nil
+	}
+	active := new(Job)
+	err = r.db.NewSelect().Model(active).
+		Where("kind = ? AND status IN (?, ?)", kind, JobQueued, JobRunning).
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("enqueue %s job: %w", kind, err)
+	}
+	return active, fmt.Errorf("%s job %s is %s: %w", kind, active.ID, active.Status, ErrJobActive)
+}
+
+// ClaimJob marks the oldest queued job of kinds as running on worker and
+// returns it, or nil when none is queued. Concurrent workers claim
+// different jobs.
+func (r *SearchRepository) ClaimJob(ctx context.Context, worker string, kinds []string) (*Job, error) {
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+	next := r.db.NewSelect().Model((*Job)(nil)).Column("id").
+		Where("status = ?", JobQueued).
+		Where("kind IN (?)", bun.In(kinds)).
+		OrderExpr("created_at").
+		Limit(1).
+		For("UPDATE SKIP LOCKED")
+
+	var jobs []Job
+	err := r.db.NewUpdate().Model((*Job)(nil)).
+		Set("status = ?", JobRunning).
+		Set("worker = ?", worker).
+		Set("attempts = attempts + 1").
+		Set("started_at = now()").
+		Set("heartbeat_at = now()").
+		Set("error = NULL").
+		Where("id IN (?)", next).
+		Returning("*").
+		Scan(ctx, &jobs)
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// UpdateJobProgress records the progress of a running job and counts as a
+// heartbeat. It is a no-op once another worker has taken the job over.
+func (r *SearchRepository) UpdateJobProgress(ctx context.Context, id, worker, phase string, done, total int) error {
+	_, err := r.db.NewUpdate().Model((*Job)(nil)).
+		Set("phase = ?", phase).
+		Set("done = ?", done).
+		Set("total = ?", total).
+		Set("heartbeat_at = now()").
+		Where("id = ? AND worker = ? AND status = ?", id, worker, JobRunning).
+		Exec(ctx)
+	return err
+}
+
+// HeartbeatJob tells other workers that worker is still running the job.
+func (r *SearchRepository) HeartbeatJob(ctx context.Context, id, worker string) error {
+	_, err := r.db.NewUpdate().Model((*Job)(nil)).
+		Set("heartbeat_at = now()").
+		Where("id = ? AND worker = ? AND status = ?", id, worker, JobRunning).
+		Exec(ctx)
+	return err
+}
+
+// FinishJob records the outcome of a job run by worker: succeeded when
+// runErr is nil, failed with its message otherwise.
+func (r *SearchRepository) FinishJob(ctx context.Context, id, worker string, runErr error) error {
+	q := r.db.NewUpdate().Model((*Job)(nil)).
+		Set("finished_at = now()").
+		Where("id = ? AND worker = ? AND status = ?", id, worker, JobRunning)
+	if runErr != nil {
+		q = q.Set("status = ?", JobFailed).Set("error = ?", runErr.Error())
+	} else {
+		q = q.Set("status = ?", JobSucceeded)
+	}
+	_, err := q.Exec(ctx)
+	return err
+}
+
+// RequeueStaleJobs takes back the running jobs not heartbeated for
+// staleAfter, whose worker stopped or died: they are queued again, or failed
+// once they have been attempted maxAttempts times. It returns the number of
+// jobs requeued and failed.
+func (r *SearchRepository) RequeueStaleJobs(ctx context.Context, staleAfter time.Duration, maxAttempts int) (requeued, failed int, err error) {
+	stale := func(q *bun.UpdateQuery) *bun.UpdateQuery {
+		return q.Where("status = ?", JobRunning).
+			Where("heartbeat_at < now() - make_interval(secs => ?)", staleAfter.Seconds())
+	}
+	res, err := r.db.NewUpdate().Model((*Job)(nil)).
+		Set("status = ?", JobFailed).
+		Set("error = ?", "worker stopped heartbeating").
+		Set("finished_at = now()").
+		Apply(stale).
+		Where("attempts >= ?", maxAttempts).
+		Exec(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("fail stale jobs: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	failed = int(n)
+	res, err = r.db.NewUpdate().Model((*Job)(nil)).
+		Set("status = ?", JobQueued).
+		Apply(stale).
+		Exec(ctx)
+	if err != nil {
+		return 0, failed, fmt.Errorf("requeue stale jobs: %w", err)
+	}
+	n, _ = res.RowsAffected()
+	return int(n), failed, nil
+}
+
+// GetJob returns the job with id, or nil when there is none.
+func (r *SearchRepository) GetJob(ctx context.Context, id string) (*Job, error) {
+	job := new(Job)
+	err := r.db.NewSelect().Model(job).Where("id = ?", id).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// ListJobs returns up to limit jobs, newest first, of kind unless empty.
+func (r *SearchRepository) ListJobs(ctx context.Context, kind string, limit int) ([]Job, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	var jobs []Job
+	q := r.db.NewSelect().Model(&jobs).OrderExpr("created_at DESC, id").Limit(limit)
+	if kind != "" {
+		q = q.Where("kind = ?", kind)
+	}
+	err := q.Scan(ctx)
+	return jobs, err
+}
+
+func newJobID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate job id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Leadership is a session-level Postgres advisory lock held on a dedicated
+// connection. Postgres drops the lock when that session ends, so a replica
+// that crashes or loses its connection gives up leadership without waiting
+// for a lease to expire.
+type Leadership struct {
+	conn bun.Conn
+	name string
+}
+
+// TryLeadership takes the advisory lock called name without waiting. It
+// returns nil when another session holds it.
+func (d *Database) TryLeadership(ctx context.Context, name string) (*Leadership, error) {
+	conn, err := d.bun.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var acquired bool
+	if err := conn.NewRaw("SELECT pg_try_advisory_lock(hashtextextended(?, 0))", name).Scan(ctx, &acquired); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !acquired {
+		conn.Close()
+		return nil, nil
+	}
+	return &Leadership{conn: conn, name: name}, nil
+}
+
+// Check fails once the session holding the lock is gone, after which another
+// replica may have taken it.
+func (l *Leadership) Check(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, "SELECT 1")
+	return err
+}
+
+// Release gives up the lock. When it cannot be unlocked the connection is
+// discarded instead of returned to the pool, which ends the session and the
+// lock with it.
+func (l *Leadership) Release() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock(hashtextextended(?, 0))", l.name); err != nil {
+		l.conn.Raw(func(any) error { return driver.ErrBadConn })
+		l.conn.Close()
+		return err
+	}
+	return l.conn.Close()
+}
@@ -8,13 +8,7 @@ import (
 	"github.com/uptrace/bun"
 )
 
-const defaultMigrationsDir = "internal/db/migrations"
-
 func EnsureCurrent(ctx context.Context, bunDB *bun.DB, dir string, autoMigrate bool) error {
-	if dir == "" {
-		dir = defaultMigrationsDir
-	}
-
 	manager, err := NewManager(bunDB, dir)
 	if err != nil {
 		return err
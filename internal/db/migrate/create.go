@@ -0,0 +1,115 @@
+package dbmigrate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	FormatSQL = "sql"
+	FormatGo  = "go"
+)
+
+// versionLayout matches bun's own generator; 14 digits is the longest version
+// bun's file name discovery accepts and sorts after the legacy 4-digit files.
+const versionLayout = "20060102150405"
+
+var migrationNameRE = regexp.MustCompile(`^[0-9a-z_\-]+$`)
+
+const sqlUpTemplate = `-- Write the forward migration here. Statements separated by --bun:split
+-- run individually.
+`
+
+const sqlDownTemplate = `-- Revert the changes made by the matching .up.sql file.
+`
+
+const goTemplate = `package migrations
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		return nil
+	})
+}
+`
+
+// Create scaffolds a new migration in dir and returns the paths written. SQL
+// migrations get a .up.sql/.down.sql pair; Go migrations get a single file
+// registering both directions with the migrations package.
+func Create(dir, name, format string, now time.Time) ([]string, error) {
+	if dir == "" {
+		return nil, errors.New("migrations directory is required")
+	}
+	normalized, err := normalizeMigrationName(name)
+	if err != nil {
+		return nil, err
+	}
+	base := fmt.Sprintf("%s_%s", now.UTC().Format(versionLayout), normalized)
+
+	var files [][2]string
+	switch format {
+	case FormatSQL, "":
+		files = [][2]string{
+			{base + ".up.sql", sqlUpTemplate},
+			{base + ".down.sql", sqlDownTemplate},
+		}
+	case FormatGo:
+		if strings.HasSuffix(normalized, "_test") {
+			return nil, fmt.Errorf("go migration name %q would produce a _test.go file", name)
+		}
+		files = [][2]string{{base + ".go", goTemplate}}
+	default:
+		return nil, fmt.Errorf("unknown migration format %q", format)
+	}
+
+	var written []string
+	for _, file := range files {
+		path := filepath.Join(dir, file[0])
+		if err := writeNewFile(path, file[1]); err != nil {
+			for _, p := range written {
+				_ = os.Remove(p)
+			}
+			return nil, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// normalizeMigrationName lowercases name and replaces separators so the result
+// matches bun's file name pattern.
+func normalizeMigrationName(name string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	normalized = strings.NewReplacer(" ", "_", ".", "_", "/", "_").Replace(normalized)
+	if normalized == "" {
+		return "", errors.New("migration name is required")
+	}
+	if !migrationNameRE.MatchString(normalized) {
+		return "", fmt.Errorf("invalid migration name %q: use letters, digits, '_' or '-'", name)
+	}
+	return normalized, nil
+}
+
+func writeNewFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return f.Close()
+}
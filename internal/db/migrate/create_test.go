@@ -0,0 +1,59 @@
+package dbmigrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNormalizeMigrationName(t *testing.T) {
+	cases := map[string]string{
+		"add_index":       "add_index",
+		"Add PR Labels":   "add_pr_labels",
+		"docs.anchors-v2": "docs_anchors-v2",
+	}
+	for in, want := range cases {
+		got, err := normalizeMigrationName(in)
+		if err != nil {
+			t.Fatalf("normalizeMigrationName(%q): %v", in, err)
+		}
+		if got != want {
+			t.Errorf("normalizeMigrationName(%q) = %q, want %q", in, got, want)
+		}
+	}
+	for _, bad := range []string{"", "   ", "drop;table"} {
+		if _, err := normalizeMigrationName(bad); err == nil {
+			t.Errorf("normalizeMigrationName(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestCreateSQL(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	paths, err := Create(dir, "add labels", FormatSQL, now)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "20250304050607_add_labels.up.sql"),
+		filepath.Join(dir, "20250304050607_add_labels.down.sql"),
+	}
+	if len(paths) != len(want) {
+		t.Fatalf("Create returned %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("path[%d] = %q, want %q", i, paths[i], want[i])
+		}
+		if _, err := os.Stat(want[i]); err != nil {
+			t.Errorf("stat %s: %v", want[i], err)
+		}
+	}
+
+	if _, err := Create(dir, "add labels", FormatSQL, now); err == nil {
+		t.Fatal("second Create with the same version succeeded, want error")
+	}
+}
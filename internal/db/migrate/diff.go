@@ -0,0 +1,232 @@
+package dbmigrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uptrace/bun"
+)
+
+const (
+	DriftMissingTable    = "missing table"
+	DriftMissingColumn   = "missing column"
+	DriftMissingIndex    = "missing index"
+	DriftUnexpectedIndex = "unexpected index"
+	DriftExtraColumn     = "extra column"
+)
+
+// Drift is a single difference between the live schema and the expected one.
+type Drift struct {
+	Kind  string
+	Table string
+	Name  string
+}
+
+func (d Drift) String() string {
+	if d.Name == "" {
+		return fmt.Sprintf("%s: %s", d.Kind, d.Table)
+	}
+	return fmt.Sprintf("%s: %s.%s", d.Kind, d.Table, d.Name)
+}
+
+// expectedSchema is the schema implied by the bun models and SQL migrations.
+type expectedSchema struct {
+	columns map[string]map[string]bool // table -> column
+	indexes map[string]string          // index -> table
+}
+
+var (
+	createTableRE = regexp.MustCompile(`(?i)create\s+table\s+(?:if\s+not\s+exists\s+)?"?(\w+)"?`)
+	createIndexRE = regexp.MustCompile(`(?i)create\s+(?:unique\s+)?index\s+(?:concurrently\s+)?(?:if\s+not\s+exists\s+)?"?(\w+)"?\s+on\s+(?:only\s+)?"?(\w+)"?`)
+	dropIndexRE   = regexp.MustCompile(`(?i)drop\s+index\s+(?:concurrently\s+)?(?:if\s+exists\s+)?"?(\w+)"?`)
+	dropTableRE   = regexp.MustCompile(`(?i)drop\s+table\s+(?:if\s+exists\s+)?"?(\w+)"?`)
+	// embeddingIndexRE matches the per-model HNSW indexes created when an
+	// embedding model is registered (db.EmbeddingIndexName), not by migrations.
+	embeddingIndexRE = regexp.MustCompile(`^\w+_hnsw_[0-9a-f]{8}$`)
+)
+
+// Diff compares the live schema in the current search_path schema against the
+// columns declared by models and the tables and indexes created by the .up.sql
+// migrations in fsys. Go migrations are not inspected.
+func Diff(ctx context.Context, bunDB *bun.DB, fsys fs.FS, models []any) ([]Drift, error) {
+	expected, err := loadExpectedSchema(bunDB, fsys, models)
+	if err != nil {
+		return nil, err
+	}
+
+	liveColumns := make(map[string]map[string]bool)
+	var columns []struct {
+		TableName  string `bun:"table_name"`
+		ColumnName string `bun:"column_name"`
+	}
+	if err := bunDB.NewRaw(`SELECT table_name, column_name FROM information_schema.columns WHERE table_schema = current_schema()`).
+		Scan(ctx, &columns); err != nil {
+		return nil, fmt.Errorf("list columns: %w", err)
+	}
+	for _, c := range columns {
+		if liveColumns[c.TableName] == nil {
+			liveColumns[c.TableName] = make(map[string]bool)
+		}
+		liveColumns[c.TableName][c.ColumnName] = true
+	}
+
+	liveIndexes := make(map[string]string)
+	var indexes []struct {
+		TableName string `bun:"tablename"`
+		IndexName string `bun:"indexname"`
+	}
+	if err := bunDB.NewRaw(`SELECT tablename, indexname FROM pg_indexes WHERE schemaname = current_schema()`).
+		Scan(ctx, &indexes); err != nil {
+		return nil, fmt.Errorf("list indexes: %w", err)
+	}
+	for _, idx := range indexes {
+		liveIndexes[idx.IndexName] = idx.TableName
+	}
+
+	return compareSchema(expected, liveColumns, liveIndexes), nil
+}
+
+// SchemaObjects returns the tables and indexes (index -> table) created by the
+// .up.sql migrations in fsys.
+func SchemaObjects(fsys fs.FS) ([]string, map[string]string, error) {
+	expected, err := loadExpectedSchema(nil, fsys, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	tables := make([]string, 0, len(expected.columns))
+	for table := range expected.columns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables, expected.indexes, nil
+}
+
+func loadExpectedSchema(bunDB *bun.DB, fsys fs.FS, models []any) (expectedSchema, error) {
+	expected := expectedSchema{
+		columns: make(map[string]map[string]bool),
+		indexes: make(map[string]string),
+	}
+
+	files, err := fs.Glob(fsys, "*.up.sql")
+	if err != nil {
+		return expected, fmt.Errorf("list migrations: %w", err)
+	}
+	sort.Strings(files)
+	for _, name := range files {
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return expected, fmt.Errorf("read %s: %w", name, err)
+		}
+		applyMigrationSQL(expected, string(content))
+	}
+
+	for _, model := range models {
+		table := bunDB.Table(reflect.TypeOf(model))
+		cols := expected.columns[table.Name]
+		if cols == nil {
+			cols = make(map[string]bool)
+			expected.columns[table.Name] = cols
+		}
+		for _, field := range table.Fields {
+			cols[field.Name] = true
+		}
+	}
+	return expected, nil
+}
+
+// applyMigrationSQL replays the table and index DDL of one migration. Statements
+// are applied in file order so a later DROP cancels an earlier CREATE.
+func applyMigrationSQL(expected expectedSchema, sql string) {
+	for _, stmt := range strings.Split(stripSQLComments(sql), ";") {
+		if m := createTableRE.FindStringSubmatch(stmt); m != nil {
+			if expected.columns[m[1]] == nil {
+				expected.columns[m[1]] = make(map[string]bool)
+			}
+		}
+		if m := dropTableRE.FindStringSubmatch(stmt); m != nil {
+			delete(expected.columns, m[1])
+			for idx, table := range expected.indexes {
+				if table == m[1] {
+					delete(expected.indexes, idx)
+				}
+			}
+		}
+		if m := createIndexRE.FindStringSubmatch(stmt); m != nil {
+			expected.indexes[m[1]] = m[2]
+		}
+		if m := dropIndexRE.FindStringSubmatch(stmt); m != nil {
+			delete(expected.indexes, m[1])
+		}
+	}
+}
+
+func stripSQLComments(sql string) string {
+	lines := strings.Split(sql, "\n")
+	for i, line := range lines {
+		if idx := strings.Index(line, "--"); idx >= 0 {
+			lines[i] = line[:idx]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// compareSchema reports expected tables, columns and indexes absent from the
+// live schema, plus columns and indexes on known tables that nothing declares.
+// Primary key and unique constraint indexes, and per-model embedding indexes,
+// are implicit and never reported as unexpected.
+func compareSchema(expected expectedSchema, liveColumns map[string]map[string]bool, liveIndexes map[string]string) []Drift {
+	var drift []Drift
+	for table, cols := range expected.columns {
+		live, ok := liveColumns[table]
+		if !ok {
+			drift = append(drift, Drift{Kind: DriftMissingTable, Table: table})
+			continue
+		}
+		for col := range cols {
+			if !live[col] {
+				drift = append(drift, Drift{Kind: DriftMissingColumn, Table: table, Name: col})
+			}
+		}
+		if len(cols) == 0 {
+			continue // no model declares this table's columns
+		}
+		for col := range live {
+			if !cols[col] {
+				drift = append(drift, Drift{Kind: DriftExtraColumn, Table: table, Name: col})
+			}
+		}
+	}
+	for idx, table := range expected.indexes {
+		if _, ok := liveColumns[table]; !ok {
+			continue // already reported as a missing table
+		}
+		if _, ok := liveIndexes[idx]; !ok {
+			drift = append(drift, Drift{Kind: DriftMissingIndex, Table: table, Name: idx})
+		}
+	}
+	for idx, table := range liveIndexes {
+		if _, known := expected.columns[table]; !known {
+			continue
+		}
+		if _, ok := expected.indexes[idx]; ok || strings.HasSuffix(idx, "_pkey") || strings.HasSuffix(idx, "_key") || embeddingIndexRE.MatchString(idx) {
+			continue
+		}
+		drift = append(drift, Drift{Kind: DriftUnexpectedIndex, Table: table, Name: idx})
+	}
+
+	sort.Slice(drift, func(i, j int) bool {
+		if drift[i].Table != drift[j].Table {
+			return drift[i].Table < drift[j].Table
+		}
+		if drift[i].Kind != drift[j].Kind {
+			return drift[i].Kind < drift[j].Kind
+		}
+		return drift[i].Name < drift[j].Name
+	})
+	return drift
+}
@@ -0,0 +1,70 @@
+package dbmigrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyMigrationSQL(t *testing.T) {
+	expected := expectedSchema{columns: map[string]map[string]bool{}, indexes: map[string]string{}}
+	applyMigrationSQL(expected, `
+CREATE TABLE IF NOT EXISTS documents (id TEXT PRIMARY KEY); -- create index ignored_idx on nope
+CREATE INDEX IF NOT EXISTS documents_component_idx ON documents(component);
+CREATE INDEX documents_hnsw ON documents USING hnsw (embedding vector_cosine_ops);
+CREATE TABLE old_table (id INT);
+CREATE UNIQUE INDEX old_idx ON old_table(id);
+`)
+	applyMigrationSQL(expected, `
+DROP INDEX IF EXISTS documents_component_idx;
+DROP TABLE IF EXISTS old_table;
+`)
+
+	if _, ok := expected.columns["documents"]; !ok {
+		t.Error("documents table not recorded")
+	}
+	if _, ok := expected.columns["old_table"]; ok {
+		t.Error("dropped table still expected")
+	}
+	want := map[string]string{"documents_hnsw": "documents"}
+	if !reflect.DeepEqual(expected.indexes, want) {
+		t.Errorf("indexes = %v, want %v", expected.indexes, want)
+	}
+}
+
+func TestCompareSchema(t *testing.T) {
+	expected := expectedSchema{
+		columns: map[string]map[string]bool{
+			"documents":        {"id": true, "anchor": true},
+			"processing_state": {},
+			"eval_runs":        {"id": true},
+		},
+		indexes: map[string]string{
+			"documents_hnsw":   "documents",
+			"eval_runs_idx":    "eval_runs",
+			"processing_index": "processing_state",
+		},
+	}
+	liveColumns := map[string]map[string]bool{
+		"documents":        {"id": true, "hotfix": true},
+		"processing_state": {"key": true},
+	}
+	liveIndexes := map[string]string{
+		"documents_pkey":          "documents",
+		"documents_manual":        "documents",
+		"documents_hnsw_1a2b3c4d": "documents",
+		"bun_migrations_pkey":     "bun_migrations",
+	}
+
+	got := compareSchema(expected, liveColumns, liveIndexes)
+	want := []Drift{
+		{Kind: DriftExtraColumn, Table: "documents", Name: "hotfix"},
+		{Kind: DriftMissingColumn, Table: "documents", Name: "anchor"},
+		{Kind: DriftMissingIndex, Table: "documents", Name: "documents_hnsw"},
+		{Kind: DriftUnexpectedIndex, Table: "documents", Name: "documents_manual"},
+		{Kind: DriftMissingTable, Table: "eval_runs"},
+		{Kind: DriftMissingIndex, Table: "processing_state", Name: "processing_index"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareSchema =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -10,6 +10,8 @@ import (
 
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/migrate"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db/migrations"
 )
 
 type Manager struct {
@@ -24,23 +26,38 @@ func NewManagerWithFS(db *bun.DB, fsys fs.FS) (*Manager, error) {
 		return nil, errors.New("migrations filesystem is required")
 	}
 
-	migrations := migrate.NewMigrations()
-	if err := migrations.Discover(fsys); err != nil {
+	collection := migrate.NewMigrations()
+	if err := collection.Discover(fsys); err != nil {
 		return nil, fmt.Errorf("discover migrations: %w", err)
 	}
+	for _, mig := range migrations.Migrations.Sorted() {
+		collection.Add(mig)
+	}
 
-	return &Manager{migrator: migrate.NewMigrator(db, migrations)}, nil
+	return &Manager{migrator: migrate.NewMigrator(db, collection)}, nil
 }
 
+// NewManager loads SQL migrations from dir, or from the migrations embedded
+// in the binary when dir is empty.
 func NewManager(db *bun.DB, dir string) (*Manager, error) {
+	fsys, err := MigrationsFS(dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewManagerWithFS(db, fsys)
+}
+
+// MigrationsFS returns the SQL migrations in dir, or the embedded ones when dir
+// is empty.
+func MigrationsFS(dir string) (fs.FS, error) {
 	if dir == "" {
-		return nil, errors.New("migrations directory is required")
+		return migrations.FS, nil
 	}
 	abs, err := filepath.Abs(dir)
 	if err != nil {
 		return nil, fmt.Errorf("resolve migrations dir: %w", err)
 	}
-	return NewManagerWithFS(db, os.DirFS(abs))
+	return os.DirFS(abs), nil
 }
 
 func (m *Manager) Migrator() *migrate.Migrator {
@@ -58,6 +75,10 @@ func (m *Manager) MigrateUp(ctx context.Context) error {
 	return nil
 }
 
+// MigrateDownSteps rolls back the last steps applied migrations, newest
+// first; 0 rolls back all of them. Migrator.Rollback undoes a whole group,
+// i.e. everything one "migrate up" applied, so migrations are rolled back
+// one at a time here instead.
 func (m *Manager) MigrateDownSteps(ctx context.Context, steps int) error {
 	if steps < 0 {
 		return errors.New("steps must be >= 0")
@@ -68,18 +89,20 @@ func (m *Manager) MigrateDownSteps(ctx context.Context, steps int) error {
 		return err
 	}
 
-	applied := status.Applied()
-	if len(applied) == 0 {
-		return nil
-	}
-
+	applied := status.Applied() // newest first
 	count := steps
 	if steps <= 0 || steps > len(applied) {
 		count = len(applied)
 	}
 
 	for i := 0; i < count; i++ {
-		if _, err := m.migrator.Rollback(ctx); err != nil {
+		mig := &applied[i]
+		if mig.Down != nil {
+			if err := mig.Down(ctx, m.migrator.DB(), nil); err != nil {
+				return fmt.Errorf("roll back %s_%s: %w", mig.Name, mig.Comment, err)
+			}
+		}
+		if err := m.migrator.MarkUnapplied(ctx, mig); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,86 @@
+//go:build integration
+
+package dbmigrate_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db/dbtest"
+	dbmigrate "github.com/roivaz/aro-hcp-intelhub/internal/db/migrate"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	dbtest.Stop()
+	os.Exit(code)
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.NewDatabase(t)
+	m, err := dbmigrate.NewManager(database.Bun(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.MigrateUp(ctx); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := len(status)
+	if len(status.Unapplied()) != 0 {
+		t.Fatalf("pending after migrate up: %s", status.Unapplied())
+	}
+	if err := dbmigrate.EnsureCurrent(ctx, database.Bun(), "", false); err != nil {
+		t.Fatalf("EnsureCurrent after migrate up: %v", err)
+	}
+
+	applied := func() int {
+		t.Helper()
+		status, err := m.Status(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(status.Applied())
+	}
+
+	// Every migration went up in one group, yet steps count migrations.
+	if err := m.MigrateDownSteps(ctx, 2); err != nil {
+		t.Fatalf("down 2 steps: %v", err)
+	}
+	if got := applied(); got != total-2 {
+		t.Fatalf("applied after down 2 = %d, want %d", got, total-2)
+	}
+	if err := dbmigrate.EnsureCurrent(ctx, database.Bun(), "", false); err == nil {
+		t.Error("EnsureCurrent reported no pending migrations")
+	}
+
+	target := status.Applied()[total-3].Name // the third migration
+	if err := m.MigrateDownTo(ctx, target); err != nil {
+		t.Fatalf("down to %s: %v", target, err)
+	}
+	if got := applied(); got != 3 {
+		t.Fatalf("applied after down to %s = %d, want 3", target, got)
+	}
+
+	// Down migrations must leave a schema the up migrations apply to again.
+	if err := m.MigrateDownSteps(ctx, 0); err != nil {
+		t.Fatalf("down all: %v", err)
+	}
+	if got := applied(); got != 0 {
+		t.Fatalf("applied after down all = %d", got)
+	}
+	if err := dbmigrate.EnsureCurrent(ctx, database.Bun(), "", true); err != nil {
+		t.Fatalf("migrate up again: %v", err)
+	}
+	if got := applied(); got != total {
+		t.Fatalf("applied after second migrate up = %d, want %d", got, total)
+	}
+}
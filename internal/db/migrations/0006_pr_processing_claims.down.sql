@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS pr_embeddings_unprocessed_idx;
+ALTER TABLE pr_embeddings
+  DROP COLUMN IF EXISTS claimed_until,
+  DROP COLUMN IF EXISTS claimed_by;
@@ -0,0 +1,7 @@
+ALTER TABLE pr_embeddings
+  ADD COLUMN IF NOT EXISTS claimed_by TEXT,
+  ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS pr_embeddings_unprocessed_idx
+  ON pr_embeddings (merged_at DESC)
+  WHERE processed_at IS NULL;
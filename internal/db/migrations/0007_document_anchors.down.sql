@@ -0,0 +1,4 @@
+ALTER TABLE documents
+  DROP COLUMN IF EXISTS end_line,
+  DROP COLUMN IF EXISTS start_line,
+  DROP COLUMN IF EXISTS anchor;
@@ -0,0 +1,4 @@
+ALTER TABLE documents
+  ADD COLUMN IF NOT EXISTS anchor TEXT,
+  ADD COLUMN IF NOT EXISTS start_line INT,
+  ADD COLUMN IF NOT EXISTS end_line INT;
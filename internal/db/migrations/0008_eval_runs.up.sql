@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS eval_runs (
+  id BIGSERIAL PRIMARY KEY,
+  started_at TIMESTAMPTZ NOT NULL,
+  finished_at TIMESTAMPTZ NOT NULL,
+  k INT NOT NULL,
+  cases INT NOT NULL,
+  hits INT NOT NULL,
+  recall_at_k DOUBLE PRECISION NOT NULL,
+  mrr DOUBLE PRECISION NOT NULL,
+  details JSONB,
+  error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS eval_runs_started_idx
+  ON eval_runs (started_at DESC);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS deployments (
+  id BIGSERIAL PRIMARY KEY,
+  environment TEXT NOT NULL,
+  commit_sha TEXT NOT NULL,
+  deployed_at TIMESTAMPTZ NOT NULL,
+  source TEXT NOT NULL,
+  recorded_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (environment, commit_sha, deployed_at)
+);
+
+CREATE INDEX IF NOT EXISTS deployments_env_deployed_idx
+  ON deployments (environment, deployed_at DESC);
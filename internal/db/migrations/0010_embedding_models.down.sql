@@ -0,0 +1,31 @@
+DO $$
+DECLARE
+  idx TEXT;
+BEGIN
+  FOR idx IN
+    SELECT indexname FROM pg_indexes
+    WHERE schemaname = current_schema()
+      AND indexname ~ '^(pr_embeddings|documents)_hnsw_[0-9a-f]{8}$'
+  LOOP
+    EXECUTE format('DROP INDEX IF EXISTS %I', idx);
+  END LOOP;
+END $$;
+
+DROP INDEX IF EXISTS documents_model_idx;
+DROP INDEX IF EXISTS pr_embeddings_model_idx;
+
+-- Vectors of other dimensions cannot be kept in vector(768) columns.
+DELETE FROM documents WHERE vector_dims(embedding) <> 768;
+UPDATE pr_embeddings
+SET embedding = NULL, processed_at = NULL
+WHERE embedding IS NOT NULL AND vector_dims(embedding) <> 768;
+
+ALTER TABLE documents ALTER COLUMN embedding TYPE VECTOR(768);
+ALTER TABLE pr_embeddings ALTER COLUMN embedding TYPE VECTOR(768);
+
+CREATE INDEX IF NOT EXISTS documents_hnsw ON documents USING hnsw (embedding vector_cosine_ops);
+CREATE INDEX IF NOT EXISTS pr_embeddings_hnsw
+  ON pr_embeddings USING hnsw (embedding vector_cosine_ops);
+
+ALTER TABLE pr_embeddings DROP COLUMN IF EXISTS embedding_model;
+DROP TABLE IF EXISTS embedding_models;
@@ -0,0 +1,34 @@
+-- Embedding models and their vector dimension. Rows of pr_embeddings and
+-- documents are tagged with the model that produced their vector, so several
+-- models can share the tables while a corpus is re-embedded.
+CREATE TABLE IF NOT EXISTS embedding_models (
+  name TEXT PRIMARY KEY,
+  dimension INT NOT NULL CHECK (dimension > 0),
+  registered_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE pr_embeddings ADD COLUMN IF NOT EXISTS embedding_model TEXT;
+
+-- PR vectors were not tagged before; assume they came from the same model as
+-- the documents, falling back to the previous default.
+UPDATE pr_embeddings
+SET embedding_model = COALESCE((SELECT embedding_model FROM documents LIMIT 1), 'nomic-embed-text')
+WHERE embedding IS NOT NULL AND embedding_model IS NULL;
+
+INSERT INTO embedding_models (name, dimension)
+SELECT DISTINCT embedding_model, 768 FROM documents
+UNION
+SELECT DISTINCT embedding_model, 768 FROM pr_embeddings WHERE embedding_model IS NOT NULL
+ON CONFLICT (name) DO NOTHING;
+
+-- An HNSW index only covers vectors of one dimension. The column becomes
+-- dimension-less and each model gets partial expression indexes, created when
+-- the model is registered (SearchRepository.RegisterEmbeddingModel).
+DROP INDEX IF EXISTS pr_embeddings_hnsw;
+DROP INDEX IF EXISTS documents_hnsw;
+
+ALTER TABLE pr_embeddings ALTER COLUMN embedding TYPE vector;
+ALTER TABLE documents ALTER COLUMN embedding TYPE vector;
+
+CREATE INDEX IF NOT EXISTS pr_embeddings_model_idx ON pr_embeddings (embedding_model);
+CREATE INDEX IF NOT EXISTS documents_model_idx ON documents (embedding_model);
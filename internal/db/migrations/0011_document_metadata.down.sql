@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS documents_alert_names_idx;
+ALTER TABLE documents
+  DROP COLUMN IF EXISTS alert_names,
+  DROP COLUMN IF EXISTS severity,
+  DROP COLUMN IF EXISTS owner;
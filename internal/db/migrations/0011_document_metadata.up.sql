@@ -0,0 +1,7 @@
+-- Front-matter metadata of runbooks and ADRs.
+ALTER TABLE documents
+  ADD COLUMN IF NOT EXISTS owner TEXT,
+  ADD COLUMN IF NOT EXISTS severity TEXT,
+  ADD COLUMN IF NOT EXISTS alert_names TEXT[];
+
+CREATE INDEX IF NOT EXISTS documents_alert_names_idx ON documents USING gin (alert_names);
@@ -0,0 +1,20 @@
+-- Source code split into top-level declarations (functions, methods, types).
+CREATE TABLE IF NOT EXISTS code_chunks (
+  id TEXT PRIMARY KEY,
+  repo TEXT NOT NULL,
+  path TEXT NOT NULL,
+  commit_sha TEXT NOT NULL,
+  language TEXT NOT NULL,
+  symbol TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  start_line INT NOT NULL,
+  end_line INT NOT NULL,
+  chunk_text TEXT NOT NULL,
+  embedding vector NOT NULL,
+  embedding_model TEXT NOT NULL,
+  source_url TEXT,
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS code_chunks_repo_idx ON code_chunks (repo, embedding_model);
+CREATE INDEX IF NOT EXISTS code_chunks_model_idx ON code_chunks (embedding_model);
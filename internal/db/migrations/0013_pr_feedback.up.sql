@@ -0,0 +1,15 @@
+-- Helpful/unhelpful marks from MCP clients on search results and rich
+-- descriptions, used to tune the analysis prompt and models.
+CREATE TABLE IF NOT EXISTS pr_feedback (
+  id BIGSERIAL PRIMARY KEY,
+  pr_number INT NOT NULL,
+  target TEXT NOT NULL,
+  helpful BOOLEAN NOT NULL,
+  query TEXT,
+  comment TEXT,
+  embedding_model TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS pr_feedback_pr_idx ON pr_feedback (pr_number);
+CREATE INDEX IF NOT EXISTS pr_feedback_created_idx ON pr_feedback (created_at);
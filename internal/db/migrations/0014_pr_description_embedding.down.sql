@@ -0,0 +1 @@
+ALTER TABLE pr_embeddings DROP COLUMN IF EXISTS description_embedding;
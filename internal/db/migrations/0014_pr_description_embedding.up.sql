@@ -0,0 +1,3 @@
+-- Vector of the rich description alone, blended with the title/body vector
+-- by SearchPRs. Produced by the same model as embedding.
+ALTER TABLE pr_embeddings ADD COLUMN IF NOT EXISTS description_embedding vector;
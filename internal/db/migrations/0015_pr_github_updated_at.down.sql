@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS pr_embeddings_needs_reembed_idx;
+ALTER TABLE pr_embeddings DROP COLUMN IF EXISTS needs_reembed;
+ALTER TABLE pr_embeddings DROP COLUMN IF EXISTS github_updated_at;
@@ -0,0 +1,7 @@
+-- GitHub's updated_at when the PR was last fetched; ingestion refreshes rows
+-- GitHub reports as updated since.
+ALTER TABLE pr_embeddings ADD COLUMN IF NOT EXISTS github_updated_at TIMESTAMPTZ;
+-- Set when the title or body of a processed PR changes; the next PROCESS run
+-- re-embeds it and keeps its analysis.
+ALTER TABLE pr_embeddings ADD COLUMN IF NOT EXISTS needs_reembed BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS pr_embeddings_needs_reembed_idx ON pr_embeddings (pr_number) WHERE needs_reembed;
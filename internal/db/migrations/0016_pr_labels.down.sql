@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS pr_embeddings_linked_issues_idx;
+DROP INDEX IF EXISTS pr_embeddings_labels_idx;
+ALTER TABLE pr_embeddings
+  DROP COLUMN IF EXISTS labels,
+  DROP COLUMN IF EXISTS milestone,
+  DROP COLUMN IF EXISTS linked_issues;
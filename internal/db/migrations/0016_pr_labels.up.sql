@@ -0,0 +1,8 @@
+-- GitHub labels, milestone title and linked issues ("#12", "other/repo#12"
+-- or Jira keys), embedded with the PR text and filterable in search_prs.
+ALTER TABLE pr_embeddings
+  ADD COLUMN IF NOT EXISTS labels TEXT[],
+  ADD COLUMN IF NOT EXISTS milestone TEXT,
+  ADD COLUMN IF NOT EXISTS linked_issues TEXT[];
+CREATE INDEX IF NOT EXISTS pr_embeddings_labels_idx ON pr_embeddings USING gin (labels);
+CREATE INDEX IF NOT EXISTS pr_embeddings_linked_issues_idx ON pr_embeddings USING gin (linked_issues);
@@ -0,0 +1,5 @@
+ALTER TABLE pr_embeddings
+  DROP COLUMN IF EXISTS additions,
+  DROP COLUMN IF EXISTS deletions,
+  DROP COLUMN IF EXISTS changed_files,
+  DROP COLUMN IF EXISTS top_level_dirs;
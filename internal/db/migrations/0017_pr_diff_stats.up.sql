@@ -0,0 +1,7 @@
+-- Size of the merge diff, recorded by the diff analyzer. NULL until a PR is
+-- analysed with diff analysis enabled.
+ALTER TABLE pr_embeddings
+  ADD COLUMN IF NOT EXISTS additions INTEGER,
+  ADD COLUMN IF NOT EXISTS deletions INTEGER,
+  ADD COLUMN IF NOT EXISTS changed_files INTEGER,
+  ADD COLUMN IF NOT EXISTS top_level_dirs TEXT[];
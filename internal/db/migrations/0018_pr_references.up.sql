@@ -0,0 +1,11 @@
+-- Issue-tracker tickets (ARO-1234, OCPBUGS-567, ...) mentioned in PR titles
+-- and bodies, so find_prs_for_ticket can jump from a bug ID to code changes.
+CREATE TABLE IF NOT EXISTS pr_references (
+  pr_number INT NOT NULL,
+  ticket TEXT NOT NULL,
+  project TEXT NOT NULL,
+  source TEXT NOT NULL,
+  PRIMARY KEY (pr_number, ticket)
+);
+
+CREATE INDEX IF NOT EXISTS pr_references_ticket_idx ON pr_references (ticket);
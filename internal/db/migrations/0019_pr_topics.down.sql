@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS pr_embeddings_topic_idx;
+ALTER TABLE pr_embeddings DROP COLUMN IF EXISTS topic_id;
+DROP TABLE IF EXISTS pr_topics;
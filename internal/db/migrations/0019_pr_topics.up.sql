@@ -0,0 +1,13 @@
+-- Topics found by 'ingest cluster': k-means clusters of PR embeddings named
+-- by an LLM. Each run replaces every topic and assignment.
+CREATE TABLE IF NOT EXISTS pr_topics (
+  id INT PRIMARY KEY,
+  label TEXT NOT NULL,
+  summary TEXT,
+  size INT NOT NULL,
+  embedding_model TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE pr_embeddings ADD COLUMN IF NOT EXISTS topic_id INT;
+CREATE INDEX IF NOT EXISTS pr_embeddings_topic_idx ON pr_embeddings (topic_id) WHERE topic_id IS NOT NULL;
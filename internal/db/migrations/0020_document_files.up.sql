@@ -0,0 +1,12 @@
+-- Full content of ingested documentation files, gzip-compressed, so
+-- search_docs include_full_file is served without cloning the repository.
+-- Rows are keyed by commit, so their content never changes; an ingest drops
+-- the rows no document of its repository references any more.
+CREATE TABLE IF NOT EXISTS document_files (
+  repo TEXT NOT NULL,
+  commit_sha TEXT NOT NULL,
+  path TEXT NOT NULL,
+  content BYTEA NOT NULL,
+  size INT NOT NULL,
+  PRIMARY KEY (repo, commit_sha, path)
+);
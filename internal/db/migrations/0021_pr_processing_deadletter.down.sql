@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS pr_processing_deadletter;
+ALTER TABLE pr_embeddings
+  DROP COLUMN IF EXISTS failed_attempts,
+  DROP COLUMN IF EXISTS failure_chain;
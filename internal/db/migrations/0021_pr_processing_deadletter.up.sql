@@ -0,0 +1,15 @@
+-- Failed processing attempts of each PR since its last success, with the
+-- failure of every attempt ("<time> [<category>] <reason>", oldest first).
+ALTER TABLE pr_embeddings
+  ADD COLUMN IF NOT EXISTS failed_attempts INT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS failure_chain TEXT[];
+
+-- PRs that failed MAX_PROCESSING_ATTEMPTS times. They are left out of the
+-- processing queue, retries included, until 'dbctl deadletter requeue'.
+CREATE TABLE IF NOT EXISTS pr_processing_deadletter (
+  pr_number INT PRIMARY KEY,
+  attempts INT NOT NULL,
+  failure_category TEXT,
+  failure_chain TEXT[] NOT NULL,
+  deadlettered_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
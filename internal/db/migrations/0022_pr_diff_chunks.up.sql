@@ -0,0 +1,14 @@
+-- Per-file chunks of PR merge diffs, as prepared by the diff analyzer after
+-- filtering generated files, embedded for search_pr_diffs.
+CREATE TABLE IF NOT EXISTS pr_diff_chunks (
+  id BIGSERIAL PRIMARY KEY,
+  pr_number INT NOT NULL,
+  path TEXT NOT NULL,
+  chunk_index INT NOT NULL,
+  chunk_text TEXT NOT NULL,
+  embedding vector NOT NULL,
+  embedding_model TEXT NOT NULL,
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS pr_diff_chunks_pr_idx ON pr_diff_chunks (pr_number, embedding_model);
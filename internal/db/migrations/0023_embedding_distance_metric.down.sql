@@ -0,0 +1 @@
+ALTER TABLE embedding_models DROP COLUMN IF EXISTS distance_metric;
@@ -0,0 +1,5 @@
+-- The distance metric a model's vectors are compared with. Models registered
+-- before were always searched by cosine distance.
+ALTER TABLE embedding_models
+  ADD COLUMN IF NOT EXISTS distance_metric TEXT NOT NULL DEFAULT 'cosine'
+  CHECK (distance_metric IN ('cosine', 'inner_product', 'l2'));
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS document_files_hash_idx;
+ALTER TABLE document_files DROP COLUMN IF EXISTS content_sha256;
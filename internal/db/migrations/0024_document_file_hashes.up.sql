@@ -0,0 +1,6 @@
+-- SHA-256 of each ingested file's content, so the docs ingester reuses the
+-- chunks and embeddings of files unchanged since the previous ingest.
+-- Rows stored before are hashed the next time their commit is ingested.
+ALTER TABLE document_files ADD COLUMN IF NOT EXISTS content_sha256 TEXT;
+
+CREATE INDEX IF NOT EXISTS document_files_hash_idx ON document_files (repo, path, content_sha256);
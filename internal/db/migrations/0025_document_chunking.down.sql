@@ -0,0 +1 @@
+ALTER TABLE documents DROP COLUMN IF EXISTS chunking;
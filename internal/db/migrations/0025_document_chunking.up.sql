@@ -0,0 +1,3 @@
+-- How each documentation chunk was split, as splitter:size:overlap, so
+-- chunking can differ per doc_type and changing it re-embeds the files.
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS chunking TEXT;
@@ -0,0 +1,24 @@
+-- Long-running work queued by MCP tools and CLIs (ingestion runs, topic
+-- clustering). A worker claims a queued job and heartbeats while it runs, so
+-- a job whose worker died is requeued instead of staying "running" forever.
+CREATE TABLE IF NOT EXISTS jobs (
+  id TEXT PRIMARY KEY,
+  kind TEXT NOT NULL,
+  params JSONB NOT NULL DEFAULT '{}',
+  status TEXT NOT NULL DEFAULT 'queued',
+  phase TEXT,
+  done INT NOT NULL DEFAULT 0,
+  total INT NOT NULL DEFAULT 0,
+  attempts INT NOT NULL DEFAULT 0,
+  error TEXT,
+  worker TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  started_at TIMESTAMPTZ,
+  heartbeat_at TIMESTAMPTZ,
+  finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS jobs_queue_idx ON jobs (created_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS jobs_kind_idx ON jobs (kind, created_at DESC);
+-- At most one queued or running job of each kind.
+CREATE UNIQUE INDEX IF NOT EXISTS jobs_active_kind_idx ON jobs (kind) WHERE status IN ('queued', 'running');
@@ -0,0 +1,4 @@
+ALTER TABLE pr_feedback DROP CONSTRAINT IF EXISTS pr_feedback_pr_fkey;
+ALTER TABLE pr_references DROP CONSTRAINT IF EXISTS pr_references_pr_fkey;
+ALTER TABLE pr_diff_chunks DROP CONSTRAINT IF EXISTS pr_diff_chunks_pr_fkey;
+ALTER TABLE pr_processing_deadletter DROP CONSTRAINT IF EXISTS pr_processing_deadletter_pr_fkey;
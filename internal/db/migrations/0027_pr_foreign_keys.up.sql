@@ -0,0 +1,15 @@
+-- Rows keyed by a PR go with it: dbctl prune and any other delete from
+-- pr_embeddings cascade to them. Rows already orphaned are dropped first.
+DELETE FROM pr_feedback WHERE pr_number NOT IN (SELECT pr_number FROM pr_embeddings);
+DELETE FROM pr_references WHERE pr_number NOT IN (SELECT pr_number FROM pr_embeddings);
+DELETE FROM pr_diff_chunks WHERE pr_number NOT IN (SELECT pr_number FROM pr_embeddings);
+DELETE FROM pr_processing_deadletter WHERE pr_number NOT IN (SELECT pr_number FROM pr_embeddings);
+
+ALTER TABLE pr_feedback
+  ADD CONSTRAINT pr_feedback_pr_fkey FOREIGN KEY (pr_number) REFERENCES pr_embeddings (pr_number) ON DELETE CASCADE;
+ALTER TABLE pr_references
+  ADD CONSTRAINT pr_references_pr_fkey FOREIGN KEY (pr_number) REFERENCES pr_embeddings (pr_number) ON DELETE CASCADE;
+ALTER TABLE pr_diff_chunks
+  ADD CONSTRAINT pr_diff_chunks_pr_fkey FOREIGN KEY (pr_number) REFERENCES pr_embeddings (pr_number) ON DELETE CASCADE;
+ALTER TABLE pr_processing_deadletter
+  ADD CONSTRAINT pr_processing_deadletter_pr_fkey FOREIGN KEY (pr_number) REFERENCES pr_embeddings (pr_number) ON DELETE CASCADE;
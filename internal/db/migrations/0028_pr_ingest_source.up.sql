@@ -0,0 +1,5 @@
+-- Where each PR row came from: 'ingest' for the ingestion pipeline,
+-- 'github_live' for a get_pr_details live lookup. Ingestion skips live rows
+-- when it looks for the PRs it already stored, and takes them over the next
+-- time it stores them.
+ALTER TABLE pr_embeddings ADD COLUMN IF NOT EXISTS ingest_source TEXT NOT NULL DEFAULT 'ingest';
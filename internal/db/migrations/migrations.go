@@ -0,0 +1,19 @@
+// Package migrations holds the schema migrations applied by dbctl and the
+// services. SQL migrations are discovered from the .up.sql/.down.sql files in
+// this directory (embedded into binaries via FS); Go migrations register
+// themselves with Migrations from init.
+package migrations
+
+import (
+	"embed"
+
+	"github.com/uptrace/bun/migrate"
+)
+
+// FS embeds the SQL migrations so binaries can migrate without the source tree.
+//
+//go:embed *.sql
+var FS embed.FS
+
+// Migrations collects the Go migrations compiled into this package.
+var Migrations = migrate.NewMigrations()
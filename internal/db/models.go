@@ -1,6 +1,7 @@
 package db
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/pgvector/pgvector-go"
@@ -8,35 +9,64 @@ import (
 	"github.com/uptrace/bun"
 )
 
+// Models lists the bun models backed by migrated tables; dbctl diff compares
+// their columns against the live schema.
+func Models() []any {
+	return []any{(*PREmbedding)(nil), (*DocumentChunk)(nil), (*TraceImageCache)(nil), (*EvalRun)(nil), (*Deployment)(nil), (*EmbeddingModel)(nil), (*CodeChunk)(nil), (*PRFeedback)(nil), (*PRReference)(nil), (*PRTopic)(nil), (*PRDeadLetter)(nil), (*PRDiffChunk)(nil), (*Job)(nil)}
+}
+
 type PREmbedding struct {
 	bun.BaseModel `bun:"table:pr_embeddings"`
 
-	ID                 int64            `bun:"id,pk,autoincrement"`
-	PRNumber           int              `bun:"pr_number,unique"`
-	PRTitle            string           `bun:"pr_title"`
-	PRBody             string           `bun:"pr_body"`
-	Author             string           `bun:"author"`
-	CreatedAt          time.Time        `bun:"created_at"`
-	MergedAt           *time.Time       `bun:"merged_at"`
-	State              string           `bun:"state"`
-	BaseRef            string           `bun:"base_ref"`
-	GithubBaseSHA      *string          `bun:"github_base_sha"`
-	BaseMergeBaseSHA   *string          `bun:"base_merge_base_sha"`
-	HeadCommitSHA      *string          `bun:"head_commit_sha"`
-	MergeCommitSHA     *string          `bun:"merge_commit_sha"`
-	Embedding          *pgvector.Vector `bun:"embedding"` // Nullable: NULL = not processed yet
-	RichDescription    *string          `bun:"rich_description"`
-	AnalysisSuccessful bool             `bun:"analysis_successful"`
-	FailureReason      *string          `bun:"failure_reason"`
-	FailureCategory    *string          `bun:"failure_category"`
-	ProcessedAt        *time.Time       `bun:"processed_at"` // NULL = needs processing
+	ID                   int64            `bun:"id,pk,autoincrement"`
+	PRNumber             int              `bun:"pr_number,unique"`
+	PRTitle              string           `bun:"pr_title"`
+	PRBody               string           `bun:"pr_body"`
+	Author               string           `bun:"author"`
+	CreatedAt            time.Time        `bun:"created_at"`
+	MergedAt             *time.Time       `bun:"merged_at"`
+	State                string           `bun:"state"`
+	BaseRef              string           `bun:"base_ref"`
+	GithubBaseSHA        *string          `bun:"github_base_sha"`
+	BaseMergeBaseSHA     *string          `bun:"base_merge_base_sha"`
+	HeadCommitSHA        *string          `bun:"head_commit_sha"`
+	MergeCommitSHA       *string          `bun:"merge_commit_sha"`
+	Embedding            *pgvector.Vector `bun:"embedding"`             // Nullable: NULL = not processed yet
+	EmbeddingModel       *string          `bun:"embedding_model"`       // model that produced Embedding
+	DescriptionEmbedding *pgvector.Vector `bun:"description_embedding"` // rich description alone; NULL without one
+	RichDescription      *string          `bun:"rich_description"`
+	AnalysisSuccessful   bool             `bun:"analysis_successful"`
+	FailureReason        *string          `bun:"failure_reason"`
+	FailureCategory      *string          `bun:"failure_category"`
+	ProcessedAt          *time.Time       `bun:"processed_at"`        // NULL = needs processing
+	ClaimedBy            *string          `bun:"claimed_by"`          // worker holding the processing lease
+	ClaimedUntil         *time.Time       `bun:"claimed_until"`       // lease expiry; expired claims are reclaimable
+	GithubUpdatedAt      *time.Time       `bun:"github_updated_at"`   // GitHub updated_at when last fetched
+	NeedsReembed         bool             `bun:"needs_reembed"`       // title or body edited after processing
+	FailedAttempts       int              `bun:"failed_attempts"`     // failed processing attempts since the last success
+	FailureChain         []string         `bun:"failure_chain,array"` // failure of each of those attempts, oldest first
+	Labels               []string         `bun:"labels,array"`
+	Milestone            *string          `bun:"milestone"`
+	LinkedIssues         []string         `bun:"linked_issues,array"` // "#12", "other/repo#12" or Jira keys
+	Additions            *int             `bun:"additions"`           // merge diff size; NULL until analysed
+	Deletions            *int             `bun:"deletions"`
+	ChangedFiles         *int             `bun:"changed_files"`
+	TopLevelDirs         []string         `bun:"top_level_dirs,array"`   // first path segments touched, "." for the root
+	TopicID              *int             `bun:"topic_id"`               // pr_topics cluster from the last 'ingest cluster' run
+	IngestSource         string           `bun:"ingest_source,nullzero"` // SourceIngest or SourceGitHubLive; empty stores SourceIngest
 }
 
+// Values of PREmbedding.IngestSource.
+const (
+	SourceIngest     = "ingest"      // stored by the ingestion pipeline
+	SourceGitHubLive = "github_live" // stored by a get_pr_details live lookup
+)
+
 // DocumentChunk represents an embedded chunk of a documentation file.
 type DocumentChunk struct {
 	bun.BaseModel `bun:"table:documents"`
 
-	ID             string          `bun:"id,pk"` // sha256(repo|path|commit|idx|text)
+	ID             string          `bun:"id,pk"` // sha256(repo|path|commit|idx|model|text)
 	Repo           string          `bun:"repo"`
 	Component      *string         `bun:"component,nullzero"`
 	Path           string          `bun:"path"` // repo-relative path
@@ -44,14 +74,76 @@ type DocumentChunk struct {
 	DocType        string          `bun:"doc_type"` // readme|docs|adr|runbook|other
 	ChunkIndex     int             `bun:"chunk_index"`
 	ChunkText      string          `bun:"chunk_text"`
-	Embedding      pgvector.Vector `bun:"embedding"` // vector(768)
+	Embedding      pgvector.Vector `bun:"embedding"` // dimension recorded in embedding_models
 	EmbeddingModel string          `bun:"embedding_model"`
 	UpdatedAt      time.Time       `bun:"updated_at,nullzero,default:now()"`
 	SourceURL      *string         `bun:"source_url,nullzero"`
+	Anchor         *string         `bun:"anchor,nullzero"`     // heading slug the chunk falls under
+	StartLine      *int            `bun:"start_line,nullzero"` // 1-based, inclusive
+	EndLine        *int            `bun:"end_line,nullzero"`
+	Owner          *string         `bun:"owner,nullzero"` // front matter of runbooks and ADRs
+	Severity       *string         `bun:"severity,nullzero"`
+	AlertNames     []string        `bun:"alert_names,array"`
+	Chunking       *string         `bun:"chunking,nullzero"` // splitter:size:overlap the file was split with
 }
 
 func (DocumentChunk) TableName() string { return "documents" }
 
+// DocumentFile is the full content of an ingested documentation file at a
+// commit, for search_docs include_full_file.
+type DocumentFile struct {
+	bun.BaseModel `bun:"table:document_files,alias:f"`
+
+	Repo      string `bun:"repo,pk"`
+	CommitSHA string `bun:"commit_sha,pk"`
+	Path      string `bun:"path,pk"`
+	Content   []byte `bun:"content"` // gzip-compressed
+	Size      int    `bun:"size"`    // uncompressed bytes
+	// ContentSHA256 is ContentHash of the uncompressed content; empty for
+	// rows stored before hashes were recorded.
+	ContentSHA256 string `bun:"content_sha256,nullzero"`
+}
+
+func (DocumentFile) TableName() string { return "document_files" }
+
+// CodeChunk is one top-level declaration of a source file.
+type CodeChunk struct {
+	bun.BaseModel `bun:"table:code_chunks"`
+
+	ID             string          `bun:"id,pk"` // sha256(repo|path|commit|symbol|model|text)
+	Repo           string          `bun:"repo"`
+	Path           string          `bun:"path"` // repo-relative path
+	CommitSHA      string          `bun:"commit_sha"`
+	Language       string          `bun:"language"` // go|typescript
+	Symbol         string          `bun:"symbol"`   // e.g. Server.Start, NewClient
+	Kind           string          `bun:"kind"`     // func|method|type|const|var|class|interface|...
+	StartLine      int             `bun:"start_line"`
+	EndLine        int             `bun:"end_line"`
+	ChunkText      string          `bun:"chunk_text"`
+	Embedding      pgvector.Vector `bun:"embedding"`
+	EmbeddingModel string          `bun:"embedding_model"`
+	SourceURL      *string         `bun:"source_url,nullzero"`
+	UpdatedAt      time.Time       `bun:"updated_at,nullzero,default:now()"`
+}
+
+func (CodeChunk) TableName() string { return "code_chunks" }
+
+// PRDiffChunk is a piece of a PR's merge diff, one file or part of one.
+type PRDiffChunk struct {
+	bun.BaseModel `bun:"table:pr_diff_chunks"`
+
+	ID             int64           `bun:"id,pk,autoincrement"`
+	PRNumber       int             `bun:"pr_number"`
+	Path           string          `bun:"path"`        // repo-relative path of the file
+	ChunkIndex     int             `bun:"chunk_index"` // position among the PR's chunks, most relevant file first
+	ChunkText      string          `bun:"chunk_text"`
+	Embedding      pgvector.Vector `bun:"embedding"`
+	EmbeddingModel string          `bun:"embedding_model"`
+	UpdatedAt      time.Time       `bun:"updated_at,nullzero,default:now()"`
+}
+
+func (PRDiffChunk) TableName() string { return "pr_diff_chunks" }
+
 type TraceImageCache struct {
 	bun.BaseModel `bun:"table:trace_image_cache"`
 	CommitSHA     string                        `bun:"commit_sha,pk"`
@@ -61,3 +153,128 @@ type TraceImageCache struct {
 }
 
 func (TraceImageCache) TableName() string { return "trace_image_cache" }
+
+// EvalRun stores the aggregate scores of one retrieval-quality eval run.
+type EvalRun struct {
+	bun.BaseModel `bun:"table:eval_runs"`
+
+	ID         int64                      `bun:"id,pk,autoincrement"`
+	StartedAt  time.Time                  `bun:"started_at"`
+	FinishedAt time.Time                  `bun:"finished_at"`
+	K          int                        `bun:"k"`
+	Cases      int                        `bun:"cases"`
+	Hits       int                        `bun:"hits"`
+	RecallAtK  float64                    `bun:"recall_at_k"`
+	MRR        float64                    `bun:"mrr"`
+	Details    []tooltypes.EvalCaseResult `bun:"details,type:jsonb"`
+	Error      *string                    `bun:"error"`
+}
+
+func (EvalRun) TableName() string { return "eval_runs" }
+
+// Deployment records that an environment started running an ARO-HCP commit.
+type Deployment struct {
+	bun.BaseModel `bun:"table:deployments"`
+
+	ID          int64     `bun:"id,pk,autoincrement"`
+	Environment string    `bun:"environment"`
+	CommitSHA   string    `bun:"commit_sha"`
+	DeployedAt  time.Time `bun:"deployed_at"`
+	Source      string    `bun:"source"` // poll|webhook
+	RecordedAt  time.Time `bun:"recorded_at,nullzero,default:now()"`
+}
+
+func (Deployment) TableName() string { return "deployments" }
+
+// EmbeddingModel records the vector dimension of an embedding model whose
+// vectors are stored in pr_embeddings and documents.
+type EmbeddingModel struct {
+	bun.BaseModel `bun:"table:embedding_models"`
+
+	Name           string    `bun:"name,pk"`
+	Dimension      int       `bun:"dimension"`
+	DistanceMetric string    `bun:"distance_metric"`
+	RegisteredAt   time.Time `bun:"registered_at,nullzero,default:now()"`
+}
+
+func (EmbeddingModel) TableName() string { return "embedding_models" }
+
+// PRFeedback is a client's helpful/unhelpful mark on a PR returned by a
+// search or on its rich description.
+type PRFeedback struct {
+	bun.BaseModel `bun:"table:pr_feedback"`
+
+	ID             int64     `bun:"id,pk,autoincrement"`
+	PRNumber       int       `bun:"pr_number"`
+	Target         string    `bun:"target"` // search_result|rich_description
+	Helpful        bool      `bun:"helpful"`
+	Query          *string   `bun:"query"` // search query the result came from
+	Comment        *string   `bun:"comment"`
+	EmbeddingModel *string   `bun:"embedding_model"` // model ranking the search when feedback was given
+	CreatedAt      time.Time `bun:"created_at,nullzero,default:now()"`
+}
+
+func (PRFeedback) TableName() string { return "pr_feedback" }
+
+// PRReference is an issue-tracker ticket mentioned by a PR.
+type PRReference struct {
+	bun.BaseModel `bun:"table:pr_references"`
+
+	PRNumber int    `bun:"pr_number,pk"`
+	Ticket   string `bun:"ticket,pk"` // normalized key, e.g. OCPBUGS-1234
+	Project  string `bun:"project"`   // e.g. OCPBUGS
+	Source   string `bun:"source"`    // title|body
+}
+
+func (PRReference) TableName() string { return "pr_references" }
+
+// PRTopic is a cluster of similar PRs found by 'ingest cluster'.
+type PRTopic struct {
+	bun.BaseModel `bun:"table:pr_topics"`
+
+	ID             int       `bun:"id,pk"` // 1 is the largest topic
+	Label          string    `bun:"label"`
+	Summary        *string   `bun:"summary"`
+	Size           int       `bun:"size"`
+	EmbeddingModel string    `bun:"embedding_model"`
+	CreatedAt      time.Time `bun:"created_at,nullzero,default:now()"`
+}
+
+func (PRTopic) TableName() string { return "pr_topics" }
+
+// PRDeadLetter is a PR whose processing failed MAX_PROCESSING_ATTEMPTS times
+// in a row. It stays out of the processing queue until requeued.
+type PRDeadLetter struct {
+	bun.BaseModel `bun:"table:pr_processing_deadletter"`
+
+	PRNumber        int       `bun:"pr_number,pk"`
+	Attempts        int       `bun:"attempts"`
+	FailureCategory *string   `bun:"failure_category"` // category of the last failure
+	FailureChain    []string  `bun:"failure_chain,array"`
+	DeadletteredAt  time.Time `bun:"deadlettered_at,nullzero,default:now()"`
+}
+
+func (PRDeadLetter) TableName() string { return "pr_processing_deadletter" }
+
+// Job is a unit of long-running work in the jobs table, run by a jobs.Worker
+// in whichever process claims it.
+type Job struct {
+	bun.BaseModel `bun:"table:jobs"`
+
+	ID          string          `bun:"id,pk"`
+	Kind        string          `bun:"kind"`
+	Params      json.RawMessage `bun:"params,type:jsonb"`
+	Status      string          `bun:"status"` // queued|running|succeeded|failed
+	Phase       *string         `bun:"phase"`
+	Done        int             `bun:"done"`
+	Total       int             `bun:"total"`
+	Attempts    int             `bun:"attempts"`
+	Error       *string         `bun:"error"`
+	Worker      *string         `bun:"worker"` // last worker to claim the job
+	CreatedAt   time.Time       `bun:"created_at,nullzero,default:now()"`
+	StartedAt   *time.Time      `bun:"started_at"`
+	HeartbeatAt *time.Time      `bun:"heartbeat_at"`
+	FinishedAt  *time.Time      `bun:"finished_at"`
+}
+
+func (Job) TableName() string { return "jobs" }
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// corpusChannel is the Postgres NOTIFY channel announcing that a searchable
+// corpus changed; the payload is one of the Corpus* names.
+const corpusChannel = "intelhub_corpus_changed"
+
+const (
+	CorpusPRs  = "prs"
+	CorpusDocs = "docs"
+	CorpusCode = "code"
+)
+
+// notifyCorpusChanged announces a corpus change. Inside a transaction the
+// notification is delivered on commit and dropped on rollback.
+func notifyCorpusChanged(ctx context.Context, db bun.IDB, corpus string) error {
+	_, err := db.ExecContext(ctx, "SELECT pg_notify(?, ?)", corpusChannel, corpus)
+	return err
+}
+
+// ListenCorpusChanges calls fn with the name of each corpus that changes
+// until ctx is done. Notifications sent while the connection was down are
+// lost, so after a receive error fn is called with "" to mean any corpus
+// may have changed.
+func (d *Database) ListenCorpusChanges(ctx context.Context, fn func(corpus string)) error {
+	ln := pgdriver.NewListener(d.bun)
+	defer ln.Close()
+	if err := ln.Listen(ctx, corpusChannel); err != nil {
+		return err
+	}
+	backoff := time.Second
+	for {
+		_, payload, err := ln.Receive(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Printf("db: corpus change listener: %v; retrying in %s", err, backoff)
+			fn("")
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxConnectBackoff)
+			continue
+		}
+		backoff = time.Second
+		fn(payload)
+	}
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/uptrace/bun"
+)
+
+// TicketPR is a PR mentioning a ticket and where: title or body.
+type TicketPR struct {
+	PR     PREmbedding
+	Source string
+}
+
+// ReplacePRReferences replaces the tickets recorded for prNumber with refs.
+func (r *SearchRepository) ReplacePRReferences(ctx context.Context, prNumber int, refs []PRReference) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().Model((*PRReference)(nil)).Where("pr_number = ?", prNumber).Exec(ctx); err != nil {
+			return err
+		}
+		if len(refs) == 0 {
+			return nil
+		}
+		for i := range refs {
+			refs[i].PRNumber = prNumber
+		}
+		_, err := tx.NewInsert().Model(&refs).On("CONFLICT (pr_number, ticket) DO NOTHING").Exec(ctx)
+		return err
+	})
+}
+
+// NormalizeTicket returns the stored form of a ticket key: upper case with
+// the leading zeros of its number dropped, so ARO-0123 and aro-123 match.
+func NormalizeTicket(ticket string) string {
+	project, number, found := strings.Cut(strings.ToUpper(strings.TrimSpace(ticket)), "-")
+	if !found {
+		return project
+	}
+	return project + "-" + strings.TrimLeft(number, "0")
+}
+
+// FindPRsForTicket returns up to limit PRs mentioning ticket, most recently
+// merged first, without their embeddings.
+func (r *SearchRepository) FindPRsForTicket(ctx context.Context, ticket string, limit int) ([]TicketPR, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var refs []PRReference
+	err := r.db.NewSelect().Model(&refs).
+		Where("ticket = ?", NormalizeTicket(ticket)).
+		Scan(ctx)
+	if err != nil || len(refs) == 0 {
+		return nil, err
+	}
+	sources := make(map[int]string, len(refs))
+	numbers := make([]int, 0, len(refs))
+	for _, ref := range refs {
+		sources[ref.PRNumber] = ref.Source
+		numbers = append(numbers, ref.PRNumber)
+	}
+	var prs []PREmbedding
+	err = r.db.NewSelect().Model(&prs).
+		ExcludeColumn("embedding", "description_embedding").
+		Where("pr_number IN (?)", bun.In(numbers)).
+		OrderExpr("merged_at DESC NULLS LAST, pr_number DESC").
+		Limit(limit).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]TicketPR, 0, len(prs))
+	for _, pr := range prs {
+		results = append(results, TicketPR{PR: pr, Source: sources[pr.PRNumber]})
+	}
+	return results, nil
+}
+
+// ListPRTexts returns the number, title and body of every stored PR.
+func (r *SearchRepository) ListPRTexts(ctx context.Context) ([]PREmbedding, error) {
+	var prs []PREmbedding
+	err := r.db.NewSelect().Model(&prs).
+		Column("pr_number", "pr_title", "pr_body").
+		Order("pr_number").
+		Scan(ctx)
+	return prs, err
+}
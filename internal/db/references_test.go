@@ -0,0 +1,16 @@
+package db
+
+import "testing"
+
+func TestNormalizeTicket(t *testing.T) {
+	for in, want := range map[string]string{
+		"ARO-123":     "ARO-123",
+		"aro-0123":    "ARO-123",
+		" OCPBUGS-7 ": "OCPBUGS-7",
+		"ARO-1020":    "ARO-1020",
+	} {
+		if got := NormalizeTicket(in); got != want {
+			t.Errorf("NormalizeTicket(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
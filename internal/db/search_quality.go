@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/uptrace/bun"
+)
+
+// SearchQuality trades latency for recall in vector searches by setting the
+// HNSW candidate list size (hnsw.ef_search) of the search transaction.
+type SearchQuality string
+
+const (
+	SearchQualityFast     SearchQuality = "fast"
+	SearchQualityBalanced SearchQuality = "balanced" // the default
+	SearchQualityHigh     SearchQuality = "high"
+)
+
+// SearchQualities lists the valid SearchQuality values.
+var SearchQualities = []string{string(SearchQualityFast), string(SearchQualityBalanced), string(SearchQualityHigh)}
+
+// maxEFSearch is the largest hnsw.ef_search pgvector accepts.
+const maxEFSearch = 1000
+
+// efSearch is the hnsw.ef_search for a search returning limit rows. HNSW
+// scans return at most ef_search rows, so it never drops below limit or the
+// re-rank candidate count, up to maxEFSearch. An empty quality is balanced.
+func (q SearchQuality) efSearch(limit, candidates int) int {
+	ef := 40 // pgvector's default
+	switch q {
+	case SearchQualityFast:
+		ef = 20
+	case SearchQualityHigh:
+		ef = 200
+	}
+	return min(max(ef, limit, candidates), maxEFSearch)
+}
+
+// scanSearch runs a vector search query inside a read-only transaction with
+// hnsw.ef_search set for quality. rows counts the rows the index has to
+// produce, those of earlier pages included. A filtered search also turns on
+// hnsw.iterative_scan, so the index keeps scanning when the filter drops
+// most of the first ef_search candidates instead of returning short.
+func (r *SearchRepository) scanSearch(ctx context.Context, q *bun.SelectQuery, quality SearchQuality, rows int, filtered bool) error {
+	candidates := 0
+	if r.reranks() {
+		candidates = r.candidateWindow(rows, 0)
+	}
+	ef := quality.efSearch(rows, candidates)
+	return r.db.RunInTx(ctx, &sql.TxOptions{ReadOnly: true}, func(ctx context.Context, tx bun.Tx) error {
+		// SET does not take bind parameters; ef is an int.
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", ef)); err != nil {
+			return fmt.Errorf("set hnsw.ef_search: %w", err)
+		}
+		if filtered {
+			if _, err := tx.ExecContext(ctx, "SET LOCAL hnsw.iterative_scan = strict_order"); err != nil {
+				return fmt.Errorf("set hnsw.iterative_scan: %w", err)
+			}
+		}
+		return q.Conn(tx).Scan(ctx)
+	})
+}
@@ -0,0 +1,43 @@
+package db
+
+import "testing"
+
+func TestEFSearch(t *testing.T) {
+	for _, tc := range []struct {
+		quality           SearchQuality
+		limit, candidates int
+		want              int
+	}{
+		{"", 10, 0, 40},
+		{SearchQualityBalanced, 10, 0, 40},
+		{SearchQualityFast, 10, 0, 20},
+		{SearchQualityHigh, 10, 0, 200},
+		{SearchQualityFast, 51, 0, 51},
+		{SearchQualityBalanced, 11, 100, 100},
+		{SearchQualityHigh, 5000, 0, maxEFSearch},
+	} {
+		if got := tc.quality.efSearch(tc.limit, tc.candidates); got != tc.want {
+			t.Errorf("%q.efSearch(%d, %d) = %d, want %d", tc.quality, tc.limit, tc.candidates, got, tc.want)
+		}
+	}
+}
+
+func TestSearchFiltered(t *testing.T) {
+	for name, tc := range map[string]struct {
+		filter interface{ filtered() bool }
+		want   bool
+	}{
+		"prs":              {PRSearchFilter{Quality: SearchQualityHigh}, false},
+		"prs by label":     {PRSearchFilter{Labels: []string{"bug"}}, true},
+		"docs":             {DocSearchFilter{}, false},
+		"docs by path":     {DocSearchFilter{PathPrefix: "docs/"}, true},
+		"code by language": {CodeSearchFilter{Languages: []string{"go"}}, true},
+		"diffs":            {PRDiffSearchFilter{Quality: SearchQualityFast}, false},
+		"diffs of a PR":    {PRDiffSearchFilter{PRNumber: 12}, true},
+		"diffs by path":    {PRDiffSearchFilter{PathPrefix: "frontend/"}, true},
+	} {
+		if got := tc.filter.filtered(); got != tc.want {
+			t.Errorf("%s: filtered() = %v, want %v", name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/uptrace/bun"
+)
+
+// ErrPRNotEmbedded is returned by SimilarPRs for a PR with no embedding from
+// the repository's model yet.
+var ErrPRNotEmbedded = errors.New("PR has no embedding for the current model")
+
+// SimilarPRs ranks processed PRs by similarity to PR prNumber's stored
+// embedding, excluding the PR itself. found is false when the PR is not
+// stored.
+func (r *SearchRepository) SimilarPRs(ctx context.Context, prNumber, limit int) (rows []PRSearchRow, found bool, err error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	var source PREmbedding
+	err = r.db.NewSelect().Model(&source).
+		Column("pr_number", "embedding", "embedding_model").
+		Where("pr_number = ?", prNumber).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	if source.Embedding == nil || source.EmbeddingModel == nil || *source.EmbeddingModel != r.embeddingModel {
+		return nil, true, ErrPRNotEmbedded
+	}
+	embedding := source.Embedding.Slice()
+	if err := r.checkVector(embedding); err != nil {
+		return nil, true, err
+	}
+	query := r.rankPRs(r.prSearchQuery(&rows), embedding, limit, 0, func(q *bun.SelectQuery) *bun.SelectQuery {
+		return prSearchFilter(q).Where("pr_number <> ?", prNumber)
+	})
+	if err := query.Scan(ctx); err != nil {
+		return nil, true, err
+	}
+	return rows, true, nil
+}
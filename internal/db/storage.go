@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"log"
+	"strconv"
 	"time"
 
 	pgvector "github.com/pgvector/pgvector-go"
@@ -14,8 +17,25 @@ import (
 
 type SearchRepository struct {
 	TraceCacheMax int
-	retryFailed   bool
-	db            *bun.DB
+	TraceCacheTTL time.Duration // 0 = entries never expire
+	maxAttempts   int           // failed attempts before a PR is dead-lettered; 0 = never
+
+	embeddingModel   string
+	embeddingDim     int
+	quantization     string
+	rerankCandidates int
+	distanceMetric   string
+
+	docFlushSize int // document chunks buffered per INSERT
+
+	// Weights of the title/body and rich-description distances in PR
+	// searches, summing to 1; prDescriptionWeight 0 ranks by embedding only.
+	prTextWeight        float64
+	prDescriptionWeight float64
+
+	repoURL string // links PRs and commits; default: defaultRepoURL
+
+	db *bun.DB
 }
 
 type PRSearchRow struct {
@@ -37,14 +57,53 @@ func NewSearchRepository(database *Database, opts ...func(*SearchRepository)) *S
 	return repo
 }
 
+// defaultRepoURL is the repository PR and commit links point to unless
+// WithRepoURL is given.
+const defaultRepoURL = "https://github.com/Azure/ARO-HCP"
+
+// WithRepoURL links PRs and commits to the repository at url.
+func WithRepoURL(url string) func(*SearchRepository) {
+	return func(r *SearchRepository) { r.repoURL = url }
+}
+
 func WithTraceCacheMax(n int) func(*SearchRepository) {
 	return func(r *SearchRepository) { r.TraceCacheMax = n }
 }
 
-func WithRetryFailed(retry bool) func(*SearchRepository) {
-	return func(r *SearchRepository) { r.retryFailed = retry }
+func WithTraceCacheTTL(ttl time.Duration) func(*SearchRepository) {
+	return func(r *SearchRepository) { r.TraceCacheTTL = ttl }
+}
+
+// WithMaxProcessingAttempts moves a PR to pr_processing_deadletter once its
+// processing has failed n times in a row. Zero never dead-letters.
+func WithMaxProcessingAttempts(n int) func(*SearchRepository) {
+	return func(r *SearchRepository) { r.maxAttempts = n }
+}
+
+// WithDocumentFlushSize makes document batch writers insert chunks n at a
+// time with one multi-row INSERT. Zero or less uses defaultDocFlushSize.
+func WithDocumentFlushSize(n int) func(*SearchRepository) {
+	return func(r *SearchRepository) { r.docFlushSize = n }
 }
 
+// WithPRSearchWeights blends the distance to each PR's rich-description
+// vector into PR searches. text and description are relative weights of the
+// title/body and description distances; PRs without a description vector
+// rank by their title/body distance alone. A zero description weight keeps
+// the single-vector ranking.
+func WithPRSearchWeights(text, description float64) func(*SearchRepository) {
+	return func(r *SearchRepository) {
+		r.prTextWeight, r.prDescriptionWeight = 1, 0
+		if text >= 0 && description > 0 {
+			r.prTextWeight = text / (text + description)
+			r.prDescriptionWeight = description / (text + description)
+		}
+	}
+}
+
+// LatestMergedPR returns the newest merge stored by ingestion, the
+// INCREMENTAL watermark. Rows stored by live lookups are left out: they
+// would move the watermark past the PRs ingestion has not fetched yet.
 func (r *SearchRepository) LatestMergedPR(ctx context.Context) (time.Time, int, error) {
 	var result struct {
 		MergedAt sql.NullTime `bun:"merged_at"`
@@ -52,6 +111,8 @@ func (r *SearchRepository) LatestMergedPR(ctx context.Context) (time.Time, int,
 	}
 	err := r.db.NewSelect().Model((*PREmbedding)(nil)).
 		Column("merged_at", "pr_number").
+		Where("merged_at IS NOT NULL").
+		Where("ingest_source <> ?", SourceGitHubLive).
 		OrderExpr("merged_at DESC, pr_number DESC").
 		Limit(1).
 		Scan(ctx, &result)
@@ -89,21 +150,98 @@ func (r *SearchRepository) OldestMergedPR(ctx context.Context) (time.Time, int,
 	return result.MergedAt.Time, result.PRNumber, nil
 }
 
-func (r *SearchRepository) SearchPRs(ctx context.Context, embedding []float32, limit int) ([]PRSearchRow, error) {
+// SearchCursor is the position of the last row of a search page. Results
+// are ordered by (distance, id), so the next page starts strictly after it.
+// Offset counts the rows of the pages up to it; the index scan has to
+// produce them too before it reaches the next page.
+type SearchCursor struct {
+	Distance float64 `json:"d"`
+	ID       string  `json:"i"`
+	Offset   int     `json:"o,omitempty"`
+}
+
+// skip is the number of rows before the page after c.
+func (c *SearchCursor) skip() int {
+	if c == nil {
+		return 0
+	}
+	return c.Offset
+}
+
+// PRSearchFilter restricts SearchPRs. Empty fields match every PR.
+type PRSearchFilter struct {
+	Labels        []string // PRs carrying every label
+	Milestone     string   // milestone title, matched case-insensitively
+	LinkedIssue   string   // "#12", "other/repo#12" or a Jira key
+	MinSimilarity float64  // drop PRs scoring below this, see Similarity
+	Quality       SearchQuality
+}
+
+// filtered reports whether f drops PRs the index would return.
+func (f PRSearchFilter) filtered() bool {
+	return len(f.Labels) > 0 || f.Milestone != "" || f.LinkedIssue != "" || f.MinSimilarity > 0
+}
+
+func (f PRSearchFilter) apply(q *bun.SelectQuery) *bun.SelectQuery {
+	q = prSearchFilter(q)
+	if len(f.Labels) > 0 {
+		q = q.Where("labels @> ARRAY[?]::text[]", bun.In(f.Labels))
+	}
+	if f.Milestone != "" {
+		q = q.Where("lower(milestone) = lower(?)", f.Milestone)
+	}
+	if f.LinkedIssue != "" {
+		q = q.Where("linked_issues @> ARRAY[?]::text[]", f.LinkedIssue)
+	}
+	return q
+}
+
+func (r *SearchRepository) SearchPRs(ctx context.Context, embedding []float32, limit int, filter PRSearchFilter, after *SearchCursor) ([]PRSearchRow, error) {
 	if limit <= 0 {
 		limit = 10
 	}
+	if err := r.checkVector(embedding); err != nil {
+		return nil, err
+	}
+	var afterID int64
+	if after != nil {
+		id, err := strconv.ParseInt(after.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor id %q", after.ID)
+		}
+		afterID = id
+	}
 	var results []PRSearchRow
-	query := r.db.NewSelect().Model(&results).
-		Column(
-			"id", "pr_number", "pr_title", "pr_body", "author", "created_at",
-			"merged_at", "state", "base_ref", "github_base_sha", "base_merge_base_sha",
-			"head_commit_sha", "merge_commit_sha",
-		).
-		ColumnExpr("embedding <=> ? AS distance", pgvector.NewVector(embedding)).
-		Where("embedding IS NOT NULL"). // Only search processed PRs
-		OrderExpr("distance")
-	query.Limit(limit)
+	query := r.rankPRs(r.prSearchQuery(&results), embedding, limit, after.skip(), filter.apply)
+	expr, args := r.prDistance(pgvector.NewVector(embedding))
+	if after != nil {
+		query.Where("("+expr+", id) > (?, ?)", append(args, after.Distance, afterID)...)
+	}
+	if filter.MinSimilarity > 0 {
+		query.Where(expr+" <= ?", append(args, r.maxDistance(filter.MinSimilarity))...)
+	}
+
+	if err := r.scanSearch(ctx, query, filter.Quality, after.skip()+limit, filter.filtered() || after != nil); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SearchPRsMergedBetween ranks processed PRs merged within [from, to] by
+// similarity to the given embedding.
+func (r *SearchRepository) SearchPRsMergedBetween(ctx context.Context, embedding []float32, from, to time.Time, limit int) ([]PRSearchRow, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if err := r.checkVector(embedding); err != nil {
+		return nil, err
+	}
+	var results []PRSearchRow
+	query := r.rankPRs(r.prSearchQuery(&results), embedding, limit, 0, func(q *bun.SelectQuery) *bun.SelectQuery {
+		return prSearchFilter(q).
+			Where("merged_at >= ?", from).
+			Where("merged_at <= ?", to)
+	})
 
 	if err := query.Scan(ctx); err != nil {
 		return nil, err
@@ -111,29 +249,240 @@ func (r *SearchRepository) SearchPRs(ctx context.Context, embedding []float32, l
 	return results, nil
 }
 
-func (r *SearchRepository) SearchDocs(ctx context.Context, embedding []float32, limit int, component, repo *string) ([]DocSearchRow, error) {
+// SearchPRsByMergeCommits ranks the processed PRs whose merge commit is one
+// of shas, e.g. the commits a deployment shipped.
+func (r *SearchRepository) SearchPRsByMergeCommits(ctx context.Context, embedding []float32, shas []string, limit int) ([]PRSearchRow, error) {
 	if limit <= 0 {
 		limit = 10
 	}
+	if len(shas) == 0 {
+		return nil, nil
+	}
+	if err := r.checkVector(embedding); err != nil {
+		return nil, err
+	}
+	var results []PRSearchRow
+	query := r.rankPRs(r.prSearchQuery(&results), embedding, limit, 0, func(q *bun.SelectQuery) *bun.SelectQuery {
+		return prSearchFilter(q).Where("merge_commit_sha IN (?)", bun.In(shas))
+	})
+	if err := query.Scan(ctx); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *SearchRepository) prSearchQuery(results *[]PRSearchRow) *bun.SelectQuery {
+	return r.db.NewSelect().Model(results).
+		Column(
+			"id", "pr_number", "pr_title", "pr_body", "author", "created_at",
+			"merged_at", "state", "base_ref", "github_base_sha", "base_merge_base_sha",
+			"head_commit_sha", "merge_commit_sha", "rich_description",
+			"analysis_successful", "failure_category", "processed_at",
+			"labels", "milestone", "linked_issues",
+			"additions", "deletions", "changed_files", "top_level_dirs",
+		)
+}
+
+// prDistance is the PR distance to vec and its arguments: the embedding
+// distance, or its blend with the description distance.
+func (r *SearchRepository) prDistance(vec pgvector.Vector) (string, []any) {
+	if r.prDescriptionWeight == 0 {
+		return r.distanceExpr(), []any{vec}
+	}
+	text := r.exactExpr()
+	expr := fmt.Sprintf("%g * (%s) + %g * coalesce(%s, %s)",
+		r.prTextWeight, text, r.prDescriptionWeight, r.exactExprOn("description_embedding"), text)
+	return expr, []any{vec, vec, vec}
+}
+
+// rankPRs is rank for pr_embeddings with blended distances. With re-ranking
+// the candidates are the union of both vectors' index candidates; otherwise
+// every matching PR is scored exactly, which the PR corpus is small enough
+// for.
+func (r *SearchRepository) rankPRs(q *bun.SelectQuery, embedding []float32, limit, skip int, filter func(*bun.SelectQuery) *bun.SelectQuery) *bun.SelectQuery {
+	if r.prDescriptionWeight == 0 {
+		return r.rank(q, (*PREmbedding)(nil), embedding, limit, skip, filter)
+	}
+	vec := pgvector.NewVector(embedding)
+	expr, args := r.prDistance(vec)
+	q = q.ColumnExpr(expr+" AS distance", args...)
+	if r.reranks() {
+		n := r.candidateWindow(limit, skip)
+		candidates := func(column string) *bun.SelectQuery {
+			return filter(r.embeddingFilter(r.db.NewSelect().Model((*PREmbedding)(nil)).Column("id"))).
+				Where("? IS NOT NULL", bun.Ident(column)).
+				OrderExpr(r.approxExprOn(column), vec).
+				Limit(n)
+		}
+		q = q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("id IN (?)", candidates("embedding")).
+				WhereOr("id IN (?)", candidates("description_embedding"))
+		})
+	} else {
+		q = filter(r.embeddingFilter(q))
+	}
+	return q.OrderExpr("distance, id").Limit(limit)
+}
+
+func prSearchFilter(q *bun.SelectQuery) *bun.SelectQuery {
+	return q.Where("embedding IS NOT NULL") // Only search processed PRs
+}
+
+// DocSearchFilter restricts SearchDocs. Empty fields match every chunk.
+type DocSearchFilter struct {
+	Component  string
+	Repo       string
+	DocType    string // readme|docs|adr|runbook|other
+	PathPrefix string // repo-relative, e.g. "docs/"
+	AlertName  string // matches chunks whose front matter lists the alert
+	// MinSimilarity drops chunks scoring below it, see Similarity.
+	MinSimilarity float64
+	Quality       SearchQuality
+}
+
+// filtered reports whether f drops chunks the index would return.
+func (f DocSearchFilter) filtered() bool {
+	return f.Component != "" || f.Repo != "" || f.DocType != "" || f.PathPrefix != "" || f.AlertName != "" || f.MinSimilarity > 0
+}
+
+func (r *SearchRepository) SearchDocs(ctx context.Context, embedding []float32, limit int, filter DocSearchFilter, after *SearchCursor) ([]DocSearchRow, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if err := r.checkVector(embedding); err != nil {
+		return nil, err
+	}
 	var results []DocSearchRow
 	q := r.db.NewSelect().Model(&results).
-		Column("id", "repo", "component", "path", "commit_sha", "source_url").
-		ColumnExpr("substring(chunk_text for 400) AS snippet").
-		ColumnExpr("embedding <=> ? AS distance", pgvector.NewVector(embedding)).
-		OrderExpr("distance").
-		Limit(limit)
-	if component != nil && *component != "" {
-		q = q.Where("component = ?", *component)
-	}
-	if repo != nil && *repo != "" {
-		q = q.Where("repo = ?", *repo)
-	}
-	if err := q.Scan(ctx); err != nil {
+		Column("id", "repo", "component", "path", "commit_sha", "chunk_index", "source_url", "anchor", "start_line", "end_line",
+			"doc_type", "owner", "severity", "alert_names").
+		ColumnExpr("substring(chunk_text for 400) AS snippet")
+	q = r.rank(q, (*DocumentChunk)(nil), embedding, limit, after.skip(), func(q *bun.SelectQuery) *bun.SelectQuery {
+		if filter.Component != "" {
+			q = q.Where("component = ?", filter.Component)
+		}
+		if filter.Repo != "" {
+			q = q.Where("repo = ?", filter.Repo)
+		}
+		if filter.DocType != "" {
+			q = q.Where("doc_type = ?", filter.DocType)
+		}
+		if filter.PathPrefix != "" {
+			q = q.Where("starts_with(path, ?)", filter.PathPrefix)
+		}
+		if filter.AlertName != "" {
+			q = q.Where("alert_names @> ARRAY[?]::text[]", filter.AlertName)
+		}
+		return q
+	})
+	if after != nil {
+		q = q.Where("("+r.distanceExpr()+", id) > (?, ?)", pgvector.NewVector(embedding), after.Distance, after.ID)
+	}
+	if filter.MinSimilarity > 0 {
+		q = q.Where(r.distanceExpr()+" <= ?", pgvector.NewVector(embedding), r.maxDistance(filter.MinSimilarity))
+	}
+	if err := r.scanSearch(ctx, q, filter.Quality, after.skip()+limit, filter.filtered() || after != nil); err != nil {
 		return nil, err
 	}
 	return results, nil
 }
 
+// DocumentChunksAround returns the chunks of a file from n chunks before
+// the chunk at index to n after it, in file order. Only chunks of the
+// repository's embedding model are returned, as those of other models split
+// the file differently.
+func (r *SearchRepository) DocumentChunksAround(ctx context.Context, repo, commitSHA, path string, index, n int) ([]DocumentChunk, error) {
+	var chunks []DocumentChunk
+	err := r.db.NewSelect().Model(&chunks).
+		Column("chunk_index", "chunk_text", "start_line", "end_line", "anchor").
+		Where("repo = ?", repo).
+		Where("commit_sha = ?", commitSHA).
+		Where("path = ?", path).
+		Where("embedding_model = ?", r.embeddingModel).
+		Where("chunk_index BETWEEN ? AND ?", index-n, index+n).
+		Order("d.chunk_index").
+		Scan(ctx)
+	return chunks, err
+}
+
+// PRListFilter selects merged PRs for ListMergedPRs. Zero values leave a
+// bound unset.
+type PRListFilter struct {
+	MergedAfter  time.Time
+	MergedBefore time.Time
+	Author       string   // GitHub login, matched case-insensitively
+	MinLines     int      // additions plus deletions; PRs without stats are excluded
+	WithinDirs   []string // PRs touching only these top-level directories
+	Limit        int
+}
+
+// ListMergedPRs returns merged PRs matching filter, most recently merged first.
+func (r *SearchRepository) ListMergedPRs(ctx context.Context, filter PRListFilter) ([]PREmbedding, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+	var prs []PREmbedding
+	q := r.db.NewSelect().Model(&prs).
+		ExcludeColumn("embedding", "description_embedding").
+		Where("merged_at IS NOT NULL")
+	if !filter.MergedAfter.IsZero() {
+		q = q.Where("merged_at >= ?", filter.MergedAfter)
+	}
+	if !filter.MergedBefore.IsZero() {
+		q = q.Where("merged_at <= ?", filter.MergedBefore)
+	}
+	if filter.Author != "" {
+		q = q.Where("lower(author) = lower(?)", filter.Author)
+	}
+	if filter.MinLines > 0 {
+		q = q.Where("additions + deletions >= ?", filter.MinLines)
+	}
+	if len(filter.WithinDirs) > 0 {
+		q = q.Where("top_level_dirs <@ ARRAY[?]::text[]", bun.In(filter.WithinDirs))
+	}
+	err := q.OrderExpr("merged_at DESC, pr_number DESC").Limit(filter.Limit).Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+// FindPRsByCommitSHAs returns PRs whose merge or head commit is one of shas.
+func (r *SearchRepository) FindPRsByCommitSHAs(ctx context.Context, shas []string) ([]PREmbedding, error) {
+	if len(shas) == 0 {
+		return nil, nil
+	}
+	var prs []PREmbedding
+	err := r.db.NewSelect().Model(&prs).
+		Column("pr_number", "pr_title", "head_commit_sha", "merge_commit_sha").
+		WhereOr("merge_commit_sha IN (?)", bun.In(shas)).
+		WhereOr("head_commit_sha IN (?)", bun.In(shas)).
+		OrderExpr("pr_number DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+// PRsByMergeCommitSHAs returns the PRs merged by any of shas, without their
+// embeddings.
+func (r *SearchRepository) PRsByMergeCommitSHAs(ctx context.Context, shas []string) ([]PREmbedding, error) {
+	if len(shas) == 0 {
+		return nil, nil
+	}
+	var prs []PREmbedding
+	err := r.db.NewSelect().Model(&prs).
+		ExcludeColumn("embedding", "description_embedding").
+		Where("merge_commit_sha IN (?)", bun.In(shas)).
+		OrderExpr("merged_at DESC, pr_number DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
 func (r *SearchRepository) GetPRByNumber(ctx context.Context, number int) (*PREmbedding, error) {
 	pr := new(PREmbedding)
 	err := r.db.NewSelect().Model(pr).Where("pr_number = ?", number).Scan(ctx)
@@ -159,60 +508,359 @@ func (r *SearchRepository) StorePR(ctx context.Context, pr *PREmbedding) error {
 	return err
 }
 
+// UpsertPR stores pr, or refreshes the GitHub metadata of an existing row
+// column by column while leaving its embedding and analysis untouched. The
+// row is only refreshed when pr is at least as recent, by GitHub updated_at,
+// as what is stored, so re-caching an older page never reverts newer
+// metadata. A processed row whose embedded text changed (title, body,
+// labels, milestone or linked issues) or that was merged since (merged_at,
+// merge_commit_sha) is flagged for re-embedding; the result reports whether
+// the refreshed row awaits re-embedding.
+func (r *SearchRepository) UpsertPR(ctx context.Context, pr *PREmbedding) (bool, error) {
+	_, err := r.db.NewInsert().Model(pr).
+		On("CONFLICT (pr_number) DO UPDATE").
+		Set("pr_title = EXCLUDED.pr_title").
+		Set("pr_body = EXCLUDED.pr_body").
+		Set("state = EXCLUDED.state").
+		Set("merged_at = EXCLUDED.merged_at").
+		Set("base_ref = EXCLUDED.base_ref").
+		Set("github_base_sha = EXCLUDED.github_base_sha").
+		Set("head_commit_sha = EXCLUDED.head_commit_sha").
+		Set("merge_commit_sha = EXCLUDED.merge_commit_sha").
+		Set("labels = EXCLUDED.labels").
+		Set("milestone = EXCLUDED.milestone").
+		Set("linked_issues = EXCLUDED.linked_issues").
+		Set("github_updated_at = coalesce(EXCLUDED.github_updated_at, pr_embeddings.github_updated_at)").
+		Set("ingest_source = CASE WHEN EXCLUDED.ingest_source = 'github_live' THEN pr_embeddings.ingest_source ELSE EXCLUDED.ingest_source END").
+		Set("needs_reembed = pr_embeddings.needs_reembed OR (pr_embeddings.processed_at IS NOT NULL AND " +
+			"(pr_embeddings.pr_title, pr_embeddings.pr_body, pr_embeddings.labels, pr_embeddings.milestone, pr_embeddings.linked_issues) IS DISTINCT FROM " +
+			"(EXCLUDED.pr_title, EXCLUDED.pr_body, EXCLUDED.labels, EXCLUDED.milestone, EXCLUDED.linked_issues) OR " +
+			"(pr_embeddings.merged_at, pr_embeddings.merge_commit_sha) IS DISTINCT FROM (EXCLUDED.merged_at, EXCLUDED.merge_commit_sha))").
+		Where("pr_embeddings.github_updated_at IS NULL OR EXCLUDED.github_updated_at >= pr_embeddings.github_updated_at OR " +
+			"(pr_embeddings.ingest_source = 'github_live' AND EXCLUDED.ingest_source <> 'github_live')").
+		Returning("id, needs_reembed").
+		Exec(ctx)
+	return pr.NeedsReembed, err
+}
+
+// GetPRUpdatedAt reports whether ingestion stored PR number and the GitHub
+// updated_at recorded when it was last fetched, nil for rows stored before
+// it was tracked. Rows stored by live lookups do not count, so ingestion
+// neither stops at them nor skips them.
+func (r *SearchRepository) GetPRUpdatedAt(ctx context.Context, number int) (bool, *time.Time, error) {
+	var updatedAt []*time.Time
+	err := r.db.NewSelect().Model((*PREmbedding)(nil)).
+		Column("github_updated_at").
+		Where("pr_number = ?", number).
+		Where("ingest_source <> ?", SourceGitHubLive).
+		Scan(ctx, &updatedAt)
+	if err != nil || len(updatedAt) == 0 {
+		return false, nil, err
+	}
+	return true, updatedAt[0], nil
+}
+
 func (r *SearchRepository) GetUnprocessedPRs(ctx context.Context, limit int) ([]*PREmbedding, error) {
 	if limit <= 0 {
 		limit = 100
 	}
 	var prs []*PREmbedding
-	query := r.db.NewSelect().Model(&prs)
+	query := unprocessedFilter(r.db.NewSelect().Model(&prs), r.embeddingModel, time.Time{})
 
-	if r.retryFailed {
-		// Include unprocessed PRs OR failed analyses
-		query = query.Where("processed_at IS NULL OR analysis_successful = ?", false)
-	} else {
-		// Only unprocessed PRs
-		query = query.Where("processed_at IS NULL")
+	err := query.OrderExpr("merged_at DESC").Limit(limit).Scan(ctx)
+	return prs, err
+}
+
+// ClaimUnprocessedPRs leases up to limit unprocessed PRs to workerID for the
+// visibility timeout. Rows claimed by other workers are skipped until their
+// lease expires, so several workers can consume the queue concurrently.
+//
+// A non-zero retryFailedBefore also queues the PRs whose analysis failed
+// before it for another attempt. Retried PRs that fail again are processed
+// after it and stay out of the queue, so a run does not loop over them.
+func (r *SearchRepository) ClaimUnprocessedPRs(ctx context.Context, workerID string, limit int, visibility time.Duration, retryFailedBefore time.Time) ([]*PREmbedding, error) {
+	if limit <= 0 {
+		limit = 10
 	}
+	candidates := unprocessedFilter(r.db.NewSelect().Model((*PREmbedding)(nil)).Column("id"), r.embeddingModel, retryFailedBefore).
+		Where("claimed_until IS NULL OR claimed_until < now()").
+		OrderExpr("merged_at DESC").
+		Limit(limit).
+		For("UPDATE SKIP LOCKED")
 
-	err := query.OrderExpr("merged_at DESC").Limit(limit).Scan(ctx)
+	var prs []*PREmbedding
+	err := r.db.NewUpdate().
+		Model((*PREmbedding)(nil)).
+		Set("claimed_by = ?", workerID).
+		Set("claimed_until = now() + make_interval(secs => ?)", visibility.Seconds()).
+		Where("id IN (?)", candidates).
+		Returning("*").
+		Scan(ctx, &prs)
 	return prs, err
 }
 
-func (r *SearchRepository) UpdatePRProcessing(ctx context.Context, prNumber int, embedding *pgvector.Vector, richDesc *string, analysisSuccess bool, failureReason *string, failureCategory *string) error {
-	now := time.Now()
+// ReleasePRClaim drops the lease on a PR without marking it processed.
+func (r *SearchRepository) ReleasePRClaim(ctx context.Context, prNumber int, workerID string) error {
 	_, err := r.db.NewUpdate().
 		Model((*PREmbedding)(nil)).
-		Set("embedding = ?", embedding).
-		Set("rich_description = ?", richDesc).
-		Set("analysis_successful = ?", analysisSuccess).
-		Set("failure_reason = ?", failureReason).
-		Set("failure_category = ?", failureCategory).
-		Set("processed_at = ?", now).
-		Where("pr_number = ?", prNumber).
+		Set("claimed_by = NULL").
+		Set("claimed_until = NULL").
+		Where("pr_number = ? AND claimed_by = ?", prNumber, workerID).
 		Exec(ctx)
 	return err
 }
 
-func (r *SearchRepository) CountUnprocessedPRs(ctx context.Context) (int, error) {
-	query := r.db.NewSelect().Model((*PREmbedding)(nil))
+func unprocessedFilter(query *bun.SelectQuery, embeddingModel string, retryFailedBefore time.Time) *bun.SelectQuery {
+	// Only merged PRs are processed; an unmerged row is queued once it merges.
+	return query.Where(notDeadLettered).Where("merged_at IS NOT NULL").WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+		q = q.WhereOr("processed_at IS NULL")
+		q = q.WhereOr("needs_reembed")
+		if !retryFailedBefore.IsZero() {
+			// Include failed analyses, but not those retried already
+			q = q.WhereOr("analysis_successful = ? AND processed_at < ?", false, retryFailedBefore)
+		}
+		if embeddingModel != "" {
+			// Include PRs embedded by another model, re-embedded during a model migration
+			q = q.WhereOr("embedding IS NOT NULL AND embedding_model IS DISTINCT FROM ?", embeddingModel)
+			// Include analysed PRs processed before descriptions were embedded
+			q = q.WhereOr("embedding IS NOT NULL AND analysis_successful AND rich_description IS NOT NULL AND description_embedding IS NULL")
+		}
+		return q
+	})
+}
 
-	if r.retryFailed {
-		// Count unprocessed PRs OR failed analyses
-		query = query.Where("processed_at IS NULL OR analysis_successful = ?", false)
-	} else {
-		// Only unprocessed PRs
-		query = query.Where("processed_at IS NULL")
+// UpdatePRProcessing records the outcome of processing a PR. descEmbedding
+// embeds richDesc alone and is nil without a description.
+func (r *SearchRepository) UpdatePRProcessing(ctx context.Context, prNumber int, embedding, descEmbedding *pgvector.Vector, richDesc *string, analysisSuccess bool, failureReason *string, failureCategory *string) error {
+	var model *string
+	if embedding != nil {
+		if err := r.checkVector(embedding.Slice()); err != nil {
+			return err
+		}
+		model = &r.embeddingModel
+	}
+	if descEmbedding != nil {
+		if err := r.checkVector(descEmbedding.Slice()); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	failed := !analysisSuccess && failureReason != nil && (failureCategory == nil || *failureCategory != disabledFailureCategory)
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		q := tx.NewUpdate().
+			Model((*PREmbedding)(nil)).
+			Set("embedding = ?", embedding).
+			Set("embedding_model = ?", model).
+			Set("description_embedding = ?", descEmbedding).
+			Set("rich_description = ?", richDesc).
+			Set("analysis_successful = ?", analysisSuccess).
+			Set("failure_reason = ?", failureReason).
+			Set("failure_category = ?", failureCategory).
+			Set("processed_at = ?", now).
+			Set("needs_reembed = false").
+			Set("claimed_by = NULL").
+			Set("claimed_until = NULL").
+			Where("pr_number = ?", prNumber)
+		if !failed {
+			q = q.Set("failed_attempts = 0").Set("failure_chain = NULL")
+		} else {
+			q = q.Set("failed_attempts = failed_attempts + 1").
+				Set("failure_chain = array_append(failure_chain, ?)", failureEntry(now, failureCategory, *failureReason))
+		}
+		if _, err := q.Exec(ctx); err != nil {
+			return err
+		}
+		if !failed || r.maxAttempts <= 0 {
+			return nil
+		}
+		return deadLetter(ctx, tx, prNumber, r.maxAttempts)
+	})
+	if err != nil {
+		return err
 	}
+	if err := notifyCorpusChanged(ctx, r.db, CorpusPRs); err != nil {
+		log.Printf("notify PR %d processed: %v", prNumber, err)
+	}
+	return nil
+}
+
+// PRDiffStats is the size of a PR's merge diff.
+type PRDiffStats struct {
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+	TopLevelDirs []string
+}
+
+// UpdatePRDiffStats records the size of a PR's merge diff.
+func (r *SearchRepository) UpdatePRDiffStats(ctx context.Context, prNumber int, stats PRDiffStats) error {
+	_, err := r.db.NewUpdate().
+		Model((*PREmbedding)(nil)).
+		Set("additions = ?", stats.Additions).
+		Set("deletions = ?", stats.Deletions).
+		Set("changed_files = ?", stats.ChangedFiles).
+		Set("top_level_dirs = ARRAY[?]::text[]", bun.In(stats.TopLevelDirs)).
+		Where("pr_number = ?", prNumber).
+		Exec(ctx)
+	return err
+}
+
+// CountUnprocessedPRs counts the PRs ClaimUnprocessedPRs would queue with
+// the same retryFailedBefore.
+func (r *SearchRepository) CountUnprocessedPRs(ctx context.Context, retryFailedBefore time.Time) (int, error) {
+	query := unprocessedFilter(r.db.NewSelect().Model((*PREmbedding)(nil)), r.embeddingModel, retryFailedBefore)
 
 	count, err := query.Count(ctx)
 	return count, err
 }
 
+// HubStats holds corpus counts reported by get_hub_stats.
+type HubStats struct {
+	TotalPRs       int
+	ProcessedPRs   int
+	FailedPRs      int
+	PendingPRs     int
+	Documents      int
+	LatestMergedAt *time.Time
+}
+
+func (r *SearchRepository) HubStats(ctx context.Context) (HubStats, error) {
+	var stats HubStats
+	err := r.db.NewSelect().Model((*PREmbedding)(nil)).
+		ColumnExpr("count(*) AS total_prs").
+		ColumnExpr("count(*) FILTER (WHERE processed_at IS NOT NULL) AS processed_prs").
+		ColumnExpr("count(*) FILTER (WHERE processed_at IS NOT NULL AND NOT analysis_successful) AS failed_prs").
+		ColumnExpr("count(*) FILTER (WHERE processed_at IS NULL) AS pending_prs").
+		ColumnExpr("max(merged_at) AS latest_merged_at").
+		Scan(ctx, &stats.TotalPRs, &stats.ProcessedPRs, &stats.FailedPRs, &stats.PendingPRs, &stats.LatestMergedAt)
+	if err != nil {
+		return HubStats{}, err
+	}
+	stats.Documents, err = r.db.NewSelect().Model((*DocumentChunk)(nil)).Count(ctx)
+	if err != nil {
+		return HubStats{}, err
+	}
+	return stats, nil
+}
+
+func (r *SearchRepository) InsertEvalRun(ctx context.Context, run *EvalRun) error {
+	_, err := r.db.NewInsert().Model(run).Exec(ctx)
+	return err
+}
+
+// RecentEvalRuns returns the latest eval runs, most recent first.
+func (r *SearchRepository) RecentEvalRuns(ctx context.Context, limit int) ([]EvalRun, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	var runs []EvalRun
+	err := r.db.NewSelect().Model(&runs).
+		ExcludeColumn("details").
+		OrderExpr("started_at DESC").
+		Limit(limit).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// RecordDeployment stores d unless the environment was already running
+// d.CommitSHA at d.DeployedAt, so repeated polls only record changes. It
+// reports whether a row was inserted. Concurrent calls for an environment
+// are serialized by a transaction-level advisory lock so two recorders
+// cannot both see the old commit and insert the same change, and the
+// (environment, commit_sha, deployed_at) constraint absorbs redelivered
+// events.
+func (r *SearchRepository) RecordDeployment(ctx context.Context, d *Deployment) (bool, error) {
+	var inserted bool
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", "deployments:"+d.Environment); err != nil {
+			return err
+		}
+		var current string
+		err := tx.NewSelect().Model((*Deployment)(nil)).Column("commit_sha").
+			Where("environment = ? AND deployed_at <= ?", d.Environment, d.DeployedAt).
+			OrderExpr("deployed_at DESC, id DESC").
+			Limit(1).
+			Scan(ctx, &current)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if current == d.CommitSHA {
+			return nil
+		}
+		res, err := tx.NewInsert().Model(d).
+			On("CONFLICT (environment, commit_sha, deployed_at) DO NOTHING").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		inserted = n > 0
+		return nil
+	})
+	return inserted, err
+}
+
+// DeploymentAt returns the deployment environment was running at t, or nil
+// when none is recorded before t.
+func (r *SearchRepository) DeploymentAt(ctx context.Context, environment string, t time.Time) (*Deployment, error) {
+	d := new(Deployment)
+	err := r.db.NewSelect().Model(d).
+		Where("environment = ? AND deployed_at <= ?", environment, t).
+		OrderExpr("deployed_at DESC, id DESC").
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+// DeploymentHistory returns environment's deployments at or before t, most
+// recent first.
+func (r *SearchRepository) DeploymentHistory(ctx context.Context, environment string, t time.Time, limit int) ([]Deployment, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	var deployments []Deployment
+	err := r.db.NewSelect().Model(&deployments).
+		Where("environment = ? AND deployed_at <= ?", environment, t).
+		OrderExpr("deployed_at DESC, id DESC").
+		Limit(limit).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+// DeploymentsBetween returns environment's deployments after from and up to
+// to, oldest first.
+func (r *SearchRepository) DeploymentsBetween(ctx context.Context, environment string, from, to time.Time) ([]Deployment, error) {
+	var deployments []Deployment
+	err := r.db.NewSelect().Model(&deployments).
+		Where("environment = ? AND deployed_at > ? AND deployed_at <= ?", environment, from, to).
+		OrderExpr("deployed_at, id").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
 func (r *SearchRepository) TraceImageCacheGet(ctx context.Context, commitSHA, environment string) (*TraceImageCache, error) {
 	entry := new(TraceImageCache)
-	err := r.db.NewSelect().Model(entry).
-		Where("commit_sha = ? AND environment = ?", commitSHA, environment).
-		Scan(ctx)
+	q := r.db.NewSelect().Model(entry).
+		Where("commit_sha = ? AND environment = ?", commitSHA, environment)
+	if r.TraceCacheTTL > 0 {
+		q = q.Where("inserted_at > ?", time.Now().Add(-r.TraceCacheTTL))
+	}
+	err := q.Scan(ctx)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -242,7 +890,27 @@ func (r *SearchRepository) TraceImageCacheUpsert(ctx context.Context, commitSHA,
 		Model((*TraceImageCache)(nil)).
 		Where("ctid IN (SELECT ctid FROM trace_image_cache ORDER BY inserted_at DESC OFFSET ?)", r.TraceCacheMax).
 		Exec(ctx)
-	return err
+	if err != nil {
+		return err
+	}
+	if r.TraceCacheTTL > 0 {
+		go r.evictExpiredTraceCache(r.TraceCacheTTL)
+	}
+	return nil
+}
+
+// evictExpiredTraceCache deletes cache rows older than ttl. It runs detached
+// from the request that triggered it, so failures are only logged.
+func (r *SearchRepository) evictExpiredTraceCache(ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := r.db.NewDelete().
+		Model((*TraceImageCache)(nil)).
+		Where("inserted_at <= ?", time.Now().Add(-ttl)).
+		Exec(ctx)
+	if err != nil {
+		log.Printf("evict expired trace cache entries: %v", err)
+	}
 }
 
 // DocumentBatchWriter provides atomic replace of all documents for a repository.
@@ -252,6 +920,13 @@ type DocumentBatchWriter interface {
 	// Add a document chunk to the batch
 	Add(ctx context.Context, doc *DocumentChunk) error
 
+	// AddFile stores the full content of a file the batch's chunks come from
+	AddFile(ctx context.Context, commitSHA, path string, content []byte) error
+
+	// UnchangedChunks returns the model's current chunks of path when the
+	// file they were embedded from has the given ContentHash, none otherwise
+	UnchangedChunks(ctx context.Context, path, contentHash string) ([]DocumentChunk, error)
+
 	// Commit atomically replaces old documents with new ones
 	Commit(ctx context.Context) error
 
@@ -265,22 +940,32 @@ type DocumentBatchWriter interface {
 // NewDocumentBatchWriter creates a batch writer for atomically replacing
 // all documents for a given repository.
 func (r *SearchRepository) NewDocumentBatchWriter(ctx context.Context, repo string) (DocumentBatchWriter, error) {
-	return newPGDocumentBatchWriter(ctx, r.db, repo)
+	return newPGDocumentBatchWriter(ctx, r, repo)
 }
 
-// pgDocumentBatchWriter implements DocumentBatchWriter using PostgreSQL temp tables
+// defaultDocFlushSize is the document chunks per INSERT when the repository
+// sets none.
+const defaultDocFlushSize = 500
+
+// pgDocumentBatchWriter implements DocumentBatchWriter using PostgreSQL temp
+// tables. Chunks are buffered and inserted flushSize at a time.
 type pgDocumentBatchWriter struct {
-	db         bun.IDB
 	tx         bun.Tx
+	searchRepo *SearchRepository
 	repo       string
+	pending    []*DocumentChunk
+	flushSize  int
 	count      int
 	committed  bool
 	rolledBack bool
 }
 
-func newPGDocumentBatchWriter(ctx context.Context, db bun.IDB, repo string) (*pgDocumentBatchWriter, error) {
+func newPGDocumentBatchWriter(ctx context.Context, r *SearchRepository, repo string) (*pgDocumentBatchWriter, error) {
+	if r.embeddingModel == "" {
+		return nil, errNoEmbeddingModel
+	}
 	// Start transaction
-	tx, err := db.BeginTx(ctx, nil)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -293,10 +978,15 @@ func newPGDocumentBatchWriter(ctx context.Context, db bun.IDB, repo string) (*pg
 		return nil, err
 	}
 
+	flushSize := r.docFlushSize
+	if flushSize <= 0 {
+		flushSize = defaultDocFlushSize
+	}
 	return &pgDocumentBatchWriter{
-		db:   db,
-		tx:   tx,
-		repo: repo,
+		tx:         tx,
+		searchRepo: r,
+		repo:       repo,
+		flushSize:  flushSize,
 	}, nil
 }
 
@@ -307,19 +997,69 @@ func (w *pgDocumentBatchWriter) Add(ctx context.Context, doc *DocumentChunk) err
 	if w.rolledBack {
 		return errors.New("cannot add after rollback")
 	}
+	if err := w.searchRepo.checkVector(doc.Embedding.Slice()); err != nil {
+		return err
+	}
+	doc.EmbeddingModel = w.searchRepo.embeddingModel
 
+	w.pending = append(w.pending, doc)
+	w.count++
+	if len(w.pending) >= w.flushSize {
+		return w.flush(ctx)
+	}
+	return nil
+}
+
+// flush inserts the buffered chunks into the temp table with one statement.
+func (w *pgDocumentBatchWriter) flush(ctx context.Context) error {
+	if len(w.pending) == 0 {
+		return nil
+	}
 	_, err := w.tx.NewInsert().
-		Model(doc).
+		Model(&w.pending).
 		Table("documents_temp").
 		Exec(ctx)
 	if err != nil {
 		return err
 	}
-
-	w.count++
+	w.pending = w.pending[:0]
 	return nil
 }
 
+func (w *pgDocumentBatchWriter) AddFile(ctx context.Context, commitSHA, path string, content []byte) error {
+	if w.committed {
+		return errors.New("cannot add after commit")
+	}
+	if w.rolledBack {
+		return errors.New("cannot add after rollback")
+	}
+	compressed, err := gzipBytes(content)
+	if err != nil {
+		return err
+	}
+	// Content at a commit never changes, so a stored row is kept as is, only
+	// gaining the hash it was stored without.
+	_, err = w.tx.NewInsert().
+		Model(&DocumentFile{Repo: w.repo, CommitSHA: commitSHA, Path: path, Content: compressed, Size: len(content), ContentSHA256: ContentHash(content)}).
+		On("CONFLICT (repo, commit_sha, path) DO UPDATE").
+		Set("content_sha256 = EXCLUDED.content_sha256").
+		Where("f.content_sha256 IS NULL").
+		Exec(ctx)
+	return err
+}
+
+func (w *pgDocumentBatchWriter) UnchangedChunks(ctx context.Context, path, contentHash string) ([]DocumentChunk, error) {
+	var chunks []DocumentChunk
+	err := w.tx.NewSelect().Model(&chunks).
+		Where("repo = ?", w.repo).
+		Where("path = ?", path).
+		Where("embedding_model = ?", w.searchRepo.embeddingModel).
+		Where("EXISTS (SELECT 1 FROM document_files f WHERE f.repo = ?TableAlias.repo AND f.commit_sha = ?TableAlias.commit_sha AND f.path = ?TableAlias.path AND f.content_sha256 = ?)", contentHash).
+		Order("chunk_index").
+		Scan(ctx)
+	return chunks, err
+}
+
 func (w *pgDocumentBatchWriter) Commit(ctx context.Context) error {
 	if w.committed {
 		return errors.New("already committed")
@@ -328,10 +1068,17 @@ func (w *pgDocumentBatchWriter) Commit(ctx context.Context) error {
 		return errors.New("already rolled back")
 	}
 
-	// Delete old documents for this repo using Bun's query builder
+	if err := w.flush(ctx); err != nil {
+		w.tx.Rollback()
+		return err
+	}
+
+	// Delete this model's old documents for the repo; other models' rows stay
+	// searchable until they are re-embedded.
 	_, err := w.tx.NewDelete().
 		Model((*DocumentChunk)(nil)).
 		Where("repo = ?", w.repo).
+		Where("embedding_model = ?", w.searchRepo.embeddingModel).
 		Exec(ctx)
 	if err != nil {
 		w.tx.Rollback()
@@ -345,6 +1092,22 @@ func (w *pgDocumentBatchWriter) Commit(ctx context.Context) error {
 		return err
 	}
 
+	// Drop the files no document of the repo refers to any more.
+	_, err = w.tx.NewDelete().
+		Model((*DocumentFile)(nil)).
+		Where("f.repo = ?", w.repo).
+		Where("NOT EXISTS (SELECT 1 FROM documents d WHERE d.repo = f.repo AND d.commit_sha = f.commit_sha AND d.path = f.path)").
+		Exec(ctx)
+	if err != nil {
+		w.tx.Rollback()
+		return err
+	}
+
+	if err := notifyCorpusChanged(ctx, w.tx, CorpusDocs); err != nil {
+		w.tx.Rollback()
+		return err
+	}
+
 	// Commit transaction (temp table auto-drops)
 	if err := w.tx.Commit(); err != nil {
 		return err
@@ -363,7 +1126,11 @@ func (w *pgDocumentBatchWriter) Rollback() error {
 	}
 
 	err := w.tx.Rollback()
-	w.rolledBack = true
+	w.rolledBack, w.pending = true, nil
+	if errors.Is(err, sql.ErrTxDone) {
+		// database/sql already rolled back when the context was cancelled.
+		return nil
+	}
 	return err
 }
 
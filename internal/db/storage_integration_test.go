@@ -0,0 +1,622 @@
+//go:build integration
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pgvector "github.com/pgvector/pgvector-go"
+	"github.com/uptrace/bun"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db/dbtest"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	dbtest.Stop()
+	os.Exit(code)
+}
+
+const testModel = "test-embed"
+
+func newRepo(t *testing.T, opts ...func(*db.SearchRepository)) *db.SearchRepository {
+	t.Helper()
+	repo := db.NewSearchRepository(dbtest.NewMigrated(t), append([]func(*db.SearchRepository){db.WithEmbeddingModel(testModel, 3)}, opts...)...)
+	if err := repo.RegisterEmbeddingModel(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func vec(v ...float32) *pgvector.Vector {
+	p := pgvector.NewVector(v)
+	return &p
+}
+
+func TestPRLifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	updated := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	merged := updated.Add(-time.Hour)
+	merge := "abc123"
+
+	for _, pr := range []*db.PREmbedding{
+		{PRNumber: 1, PRTitle: "fix nodepool upgrades", MergedAt: &merged, MergeCommitSHA: &merge, GithubUpdatedAt: &updated},
+		{PRNumber: 2, PRTitle: "bump frontend", MergedAt: &merged},
+	} {
+		if err := repo.StorePR(ctx, pr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exists, at, err := repo.GetPRUpdatedAt(ctx, 1)
+	if err != nil || !exists || at == nil || !at.Equal(updated) {
+		t.Fatalf("GetPRUpdatedAt = %v, %v, %v", exists, at, err)
+	}
+	if n, err := repo.CountUnprocessedPRs(ctx, time.Time{}); err != nil || n != 2 {
+		t.Fatalf("CountUnprocessedPRs = %d, %v", n, err)
+	}
+
+	claimed, err := repo.ClaimUnprocessedPRs(ctx, "worker-a", 10, time.Minute, time.Time{})
+	if err != nil || len(claimed) != 2 {
+		t.Fatalf("ClaimUnprocessedPRs = %d PRs, %v", len(claimed), err)
+	}
+	if again, err := repo.ClaimUnprocessedPRs(ctx, "worker-b", 10, time.Minute, time.Time{}); err != nil || len(again) != 0 {
+		t.Fatalf("second claim = %d PRs, %v; want none while leased", len(again), err)
+	}
+	if err := repo.ReleasePRClaim(ctx, 2, "worker-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	desc := "Fixes upgrades of node pools."
+	if err := repo.UpdatePRProcessing(ctx, 1, vec(1, 0, 0), vec(0, 1, 0), &desc, true, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdatePRProcessing(ctx, 2, vec(0, 0, 1), nil, nil, false, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := repo.CountUnprocessedPRs(ctx, time.Time{}); err != nil || n != 0 {
+		t.Fatalf("CountUnprocessedPRs after processing = %d, %v", n, err)
+	}
+
+	rows, err := repo.SearchPRs(ctx, []float32{0.9, 0.1, 0}, 2, db.PRSearchFilter{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].PRNumber != 1 || rows[0].Distance > rows[1].Distance {
+		t.Fatalf("SearchPRs = %+v", rows)
+	}
+	if _, err := repo.SearchPRs(ctx, []float32{1, 0}, 2, db.PRSearchFilter{}, nil); err == nil {
+		t.Error("SearchPRs accepted a vector of the wrong dimension")
+	}
+
+	prs, err := repo.PRsByMergeCommitSHAs(ctx, []string{merge})
+	if err != nil || len(prs) != 1 || prs[0].PRNumber != 1 {
+		t.Fatalf("PRsByMergeCommitSHAs = %+v, %v", prs, err)
+	}
+
+	// Editing the title of a processed PR queues it for re-embedding.
+	edited := merged.Add(time.Hour)
+	reembed, err := repo.UpsertPR(ctx, &db.PREmbedding{PRNumber: 1, PRTitle: "fix node pool upgrades", MergedAt: &merged, GithubUpdatedAt: &edited})
+	if err != nil || !reembed {
+		t.Fatalf("UpsertPR = %v, %v; want queued for re-embedding", reembed, err)
+	}
+	// Metadata older than the stored row is ignored.
+	if _, err := repo.UpsertPR(ctx, &db.PREmbedding{PRNumber: 1, PRTitle: "stale title", MergedAt: &merged, GithubUpdatedAt: &merged}); err != nil {
+		t.Fatal(err)
+	}
+	if pr, err := repo.GetPRByNumber(ctx, 1); err != nil || pr == nil || pr.PRTitle != "fix node pool upgrades" {
+		t.Fatalf("after a stale upsert PR 1 = %+v, %v", pr, err)
+	}
+	pending, err := repo.GetUnprocessedPRs(ctx, 10)
+	if err != nil || len(pending) != 1 || pending[0].PRNumber != 1 || pending[0].RichDescription == nil {
+		t.Fatalf("GetUnprocessedPRs = %+v, %v", pending, err)
+	}
+}
+
+func TestUnmergedPRsAreNotProcessed(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	if _, err := repo.UpsertPR(ctx, &db.PREmbedding{PRNumber: 9, PRTitle: "wip"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := repo.CountUnprocessedPRs(ctx, time.Time{}); err != nil || n != 0 {
+		t.Fatalf("CountUnprocessedPRs = %d, %v; want the unmerged PR left out", n, err)
+	}
+	// A row processed before it merged is processed again once it does.
+	if err := repo.UpdatePRProcessing(ctx, 9, vec(1, 0, 0), nil, nil, true, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	merged, sha := time.Now(), "def456"
+	reembed, err := repo.UpsertPR(ctx, &db.PREmbedding{PRNumber: 9, PRTitle: "wip", MergedAt: &merged, MergeCommitSHA: &sha})
+	if err != nil || !reembed {
+		t.Fatalf("UpsertPR on merge = %v, %v; want queued for re-embedding", reembed, err)
+	}
+	if n, err := repo.CountUnprocessedPRs(ctx, time.Time{}); err != nil || n != 1 {
+		t.Fatalf("CountUnprocessedPRs after merge = %d, %v", n, err)
+	}
+}
+
+func TestLivePRsAreNotIngested(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	merged := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	updated := merged.Add(time.Hour)
+	if _, err := repo.UpsertPR(ctx, &db.PREmbedding{PRNumber: 7, PRTitle: "live", MergedAt: &merged, GithubUpdatedAt: &updated, IngestSource: db.SourceGitHubLive}); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _, err := repo.GetPRUpdatedAt(ctx, 7); err != nil || exists {
+		t.Fatalf("GetPRUpdatedAt of a live row = %v, %v; want not stored", exists, err)
+	}
+	if at, number, err := repo.LatestMergedPR(ctx); err != nil || !at.IsZero() || number != 0 {
+		t.Fatalf("LatestMergedPR with only a live row = %s, %d, %v; want no watermark", at, number, err)
+	}
+	// Ingestion takes the row over, even with metadata older than the lookup's.
+	if _, err := repo.UpsertPR(ctx, &db.PREmbedding{PRNumber: 7, PRTitle: "ingested", MergedAt: &merged, GithubUpdatedAt: &merged, IngestSource: db.SourceIngest}); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _, err := repo.GetPRUpdatedAt(ctx, 7); err != nil || !exists {
+		t.Fatalf("GetPRUpdatedAt after ingestion = %v, %v; want stored", exists, err)
+	}
+	// A later lookup leaves it ingested.
+	if _, err := repo.UpsertPR(ctx, &db.PREmbedding{PRNumber: 7, PRTitle: "live again", MergedAt: &merged, GithubUpdatedAt: &updated, IngestSource: db.SourceGitHubLive}); err != nil {
+		t.Fatal(err)
+	}
+	if pr, err := repo.GetPRByNumber(ctx, 7); err != nil || pr == nil || pr.PRTitle != "live again" || pr.IngestSource != db.SourceIngest {
+		t.Fatalf("after a live upsert PR 7 = %+v, %v", pr, err)
+	}
+}
+
+func TestIncidentCorrelationQueries(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, sha := range []string{"aaa", "bbb", "ccc"} {
+		d := &db.Deployment{Environment: "prod", CommitSHA: sha, DeployedAt: start.Add(time.Duration(i) * time.Hour), Source: "test"}
+		if _, err := repo.RecordDeployment(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deployments, err := repo.DeploymentsBetween(ctx, "prod", start, start.Add(2*time.Hour))
+	if err != nil || len(deployments) != 2 || deployments[0].CommitSHA != "bbb" || deployments[1].CommitSHA != "ccc" {
+		t.Fatalf("DeploymentsBetween = %+v, %v; want bbb then ccc", deployments, err)
+	}
+
+	merged := start
+	for i, sha := range []string{"bbb", "zzz"} {
+		pr := &db.PREmbedding{PRNumber: i + 1, PRTitle: "change", MergedAt: &merged, MergeCommitSHA: &sha}
+		if err := repo.StorePR(ctx, pr); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.UpdatePRProcessing(ctx, pr.PRNumber, vec(1, 0, 0), nil, nil, true, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows, err := repo.SearchPRsByMergeCommits(ctx, []float32{1, 0, 0}, []string{"bbb", "ccc"}, 10)
+	if err != nil || len(rows) != 1 || rows[0].PRNumber != 1 {
+		t.Fatalf("SearchPRsByMergeCommits = %+v, %v; want PR 1", rows, err)
+	}
+}
+
+func TestPRDeleteCascades(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.NewMigrated(t)
+	repo := db.NewSearchRepository(database, db.WithEmbeddingModel(testModel, 3))
+	if err := repo.RegisterEmbeddingModel(ctx); err != nil {
+		t.Fatal(err)
+	}
+	merged := time.Now()
+	if err := repo.StorePR(ctx, &db.PREmbedding{PRNumber: 1, PRTitle: "ARO-1 fix", MergedAt: &merged}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RecordFeedback(ctx, &db.PRFeedback{PRNumber: 1, Target: db.FeedbackSearchResult, Helpful: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ReplacePRReferences(ctx, 1, []db.PRReference{{Ticket: "ARO-1", Project: "ARO", Source: "title"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ReplacePRDiffChunks(ctx, 1, []db.PRDiffChunk{{Path: "a.go", ChunkText: "diff", Embedding: *vec(1, 0, 0)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := database.Bun().ExecContext(ctx, "DELETE FROM pr_embeddings WHERE pr_number = 1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"pr_feedback", "pr_references", "pr_diff_chunks"} {
+		var n int
+		if err := database.Bun().NewRaw("SELECT count(*) FROM ?", bun.Ident(table)).Scan(ctx, &n); err != nil || n != 0 {
+			t.Errorf("%s has %d rows of the deleted PR, %v", table, n, err)
+		}
+	}
+}
+
+func TestPRDiffChunks(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	merged := time.Now()
+	for _, number := range []int{1, 2} {
+		if err := repo.StorePR(ctx, &db.PREmbedding{PRNumber: number, PRTitle: "change", MergedAt: &merged}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	replace := func(number int, paths ...string) {
+		t.Helper()
+		chunks := make([]db.PRDiffChunk, len(paths))
+		for i, path := range paths {
+			chunks[i] = db.PRDiffChunk{Path: path, ChunkIndex: i, ChunkText: "diff of " + path, Embedding: *vec(1, float32(i), 0)}
+		}
+		if err := repo.ReplacePRDiffChunks(ctx, number, chunks); err != nil {
+			t.Fatal(err)
+		}
+	}
+	search := func(filter db.PRDiffSearchFilter, after *db.SearchCursor) []db.PRDiffSearchRow {
+		t.Helper()
+		rows, err := repo.SearchPRDiffs(ctx, []float32{1, 0, 0}, 10, filter, after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	replace(1, "frontend/a.go", "backend/b.go")
+	replace(2, "frontend/c.go")
+	if rows := search(db.PRDiffSearchFilter{}, nil); len(rows) != 3 {
+		t.Fatalf("SearchPRDiffs = %+v, want 3 chunks", rows)
+	}
+	if rows := search(db.PRDiffSearchFilter{PRNumber: 1, PathPrefix: "backend/"}, nil); len(rows) != 1 || rows[0].Path != "backend/b.go" {
+		t.Fatalf("filtered SearchPRDiffs = %+v", rows)
+	}
+	first := search(db.PRDiffSearchFilter{PRNumber: 1}, nil)[0]
+	after := &db.SearchCursor{Distance: first.Distance, ID: strconv.FormatInt(first.ID, 10), Offset: 1}
+	if rows := search(db.PRDiffSearchFilter{PRNumber: 1}, after); len(rows) != 1 || rows[0].ID == first.ID {
+		t.Fatalf("second page = %+v", rows)
+	}
+
+	// Replacing drops the earlier chunks, down to none.
+	replace(1, "frontend/d.go")
+	if rows := search(db.PRDiffSearchFilter{PRNumber: 1}, nil); len(rows) != 1 || rows[0].Path != "frontend/d.go" {
+		t.Fatalf("after replace = %+v", rows)
+	}
+	replace(1)
+	if rows := search(db.PRDiffSearchFilter{PRNumber: 1}, nil); len(rows) != 0 {
+		t.Fatalf("after replace with none = %+v", rows)
+	}
+	if rows := search(db.PRDiffSearchFilter{PRNumber: 2}, nil); len(rows) != 1 {
+		t.Fatalf("PR 2 chunks = %+v, want kept", rows)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	db.WithMaxProcessingAttempts(2)(repo)
+	merged := time.Now()
+	if err := repo.StorePR(ctx, &db.PREmbedding{PRNumber: 7, PRTitle: "flaky diff", MergedAt: &merged}); err != nil {
+		t.Fatal(err)
+	}
+
+	category := "timeout"
+	for _, reason := range []string{"first", "second"} {
+		if err := repo.UpdatePRProcessing(ctx, 7, nil, nil, nil, false, &reason, &category); err != nil {
+			t.Fatal(err)
+		}
+	}
+	letters, err := repo.DeadLetters(ctx)
+	if err != nil || len(letters) != 1 || letters[0].Attempts != 2 || len(letters[0].FailureChain) != 2 {
+		t.Fatalf("DeadLetters = %+v, %v", letters, err)
+	}
+	if n, err := repo.CountUnprocessedPRs(ctx, time.Now()); err != nil || n != 0 {
+		t.Fatalf("CountUnprocessedPRs = %d, %v; want the dead-lettered PR excluded from retries", n, err)
+	}
+
+	requeued, err := repo.RequeueDeadLetters(ctx, nil)
+	if err != nil || len(requeued) != 1 || requeued[0] != 7 {
+		t.Fatalf("RequeueDeadLetters = %v, %v", requeued, err)
+	}
+	if pr, err := repo.GetPRByNumber(ctx, 7); err != nil || pr.ProcessedAt != nil || pr.FailedAttempts != 0 || len(pr.FailureChain) != 2 {
+		t.Fatalf("requeued PR = %+v, %v", pr, err)
+	}
+}
+
+func TestDocumentBatchWriter(t *testing.T) {
+	ctx := context.Background()
+	// Two chunks per INSERT: the first batch is flushed by Add, the later
+	// ones by Commit.
+	repo := newRepo(t, db.WithDocumentFlushSize(2))
+
+	write := func(commit bool, paths ...string) {
+		t.Helper()
+		w, err := repo.NewDocumentBatchWriter(ctx, "Azure/ARO-HCP")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, path := range paths {
+			doc := &db.DocumentChunk{
+				ID: path, Repo: "Azure/ARO-HCP", Path: path, CommitSHA: "abc", DocType: "docs",
+				ChunkText: "chunk of " + path, Embedding: *vec(1, float32(i), 0),
+			}
+			if err := w.Add(ctx, doc); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.AddFile(ctx, "abc", path, []byte("# "+path+"\n")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if w.Count() != len(paths) {
+			t.Fatalf("Count = %d, want %d", w.Count(), len(paths))
+		}
+		if commit {
+			err = w.Commit(ctx)
+		} else {
+			err = w.Rollback()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := func() []string {
+		t.Helper()
+		rows, err := repo.SearchDocs(ctx, []float32{1, 0, 0}, 10, db.DocSearchFilter{Repo: "Azure/ARO-HCP"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range rows {
+			got = append(got, r.Path)
+		}
+		return got
+	}
+
+	write(true, "docs/a.md", "docs/b.md")
+	if got := paths(); len(got) != 2 || got[0] != "docs/a.md" {
+		t.Fatalf("after first commit: %v", got)
+	}
+	if content, ok, err := repo.DocumentFile(ctx, "Azure/ARO-HCP", "abc", "docs/b.md"); err != nil || !ok || string(content) != "# docs/b.md\n" {
+		t.Fatalf("DocumentFile = %q, %v, %v", content, ok, err)
+	}
+	w, err := repo.NewDocumentBatchWriter(ctx, "Azure/ARO-HCP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks, err := w.UnchangedChunks(ctx, "docs/b.md", db.ContentHash([]byte("# docs/b.md\n"))); err != nil || len(chunks) != 1 || chunks[0].ChunkText != "chunk of docs/b.md" {
+		t.Fatalf("UnchangedChunks of an unchanged file = %+v, %v", chunks, err)
+	}
+	if chunks, err := w.UnchangedChunks(ctx, "docs/b.md", db.ContentHash([]byte("# edited\n"))); err != nil || len(chunks) != 0 {
+		t.Fatalf("UnchangedChunks of a changed file = %+v, %v", chunks, err)
+	}
+	if err := w.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	write(false, "docs/c.md")
+	if got := paths(); len(got) != 2 {
+		t.Fatalf("rolled back batch changed the documents: %v", got)
+	}
+	write(true, "docs/c.md")
+	if got := paths(); len(got) != 1 || got[0] != "docs/c.md" {
+		t.Fatalf("commit did not replace the repo's documents: %v", got)
+	}
+	if _, ok, err := repo.DocumentFile(ctx, "Azure/ARO-HCP", "abc", "docs/a.md"); err != nil || ok {
+		t.Fatalf("file of a replaced document kept: ok=%v err=%v", ok, err)
+	}
+
+	// A cancelled ingest leaves the transaction to database/sql, which rolls
+	// it back; Rollback must still succeed.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	w, err = repo.NewDocumentBatchWriter(cancelCtx, "Azure/ARO-HCP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := w.Commit(cancelCtx); err == nil {
+		t.Fatal("Commit succeeded after cancellation")
+	}
+	if err := w.Rollback(); err != nil {
+		t.Fatalf("Rollback after cancellation: %v", err)
+	}
+	if got := paths(); len(got) != 1 || got[0] != "docs/c.md" {
+		t.Fatalf("cancelled batch changed the documents: %v", got)
+	}
+}
+
+func TestSearchPagesPastRerankWindow(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t, db.WithQuantization(db.QuantizationHalfvec, 2))
+	w, err := repo.NewDocumentBatchWriter(ctx, "Azure/ARO-HCP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 5 {
+		path := fmt.Sprintf("docs/%d.md", i)
+		doc := &db.DocumentChunk{
+			ID: path, Repo: "Azure/ARO-HCP", Path: path, CommitSHA: "abc", DocType: "docs",
+			ChunkText: path, Embedding: *vec(1, float32(i), 0),
+		}
+		if err := w.Add(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each page holds two rows, as many as the re-rank window.
+	var got []string
+	var after *db.SearchCursor
+	for page := 0; page < 5; page++ {
+		rows, err := repo.SearchDocs(ctx, []float32{1, 0, 0}, 2, db.DocSearchFilter{}, after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		for _, row := range rows {
+			got = append(got, row.Path)
+		}
+		last := rows[len(rows)-1]
+		after = &db.SearchCursor{Distance: last.Distance, ID: last.ID, Offset: len(got)}
+	}
+	if len(got) != 5 {
+		t.Fatalf("paged through %v, want all 5 chunks", got)
+	}
+}
+
+func TestWarmUpIndexes(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	merged := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	if err := repo.StorePR(ctx, &db.PREmbedding{PRNumber: 1, PRTitle: "fix", MergedAt: &merged}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdatePRProcessing(ctx, 1, vec(1, 0, 0), nil, nil, true, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	results, err := repo.WarmUpIndexes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queried := 0
+	for _, w := range results {
+		if w.Missing || w.PrewarmErr != nil || w.Blocks == 0 {
+			t.Errorf("index %s: %+v; want prewarmed", w.Index, w)
+		}
+		if !w.Empty {
+			queried++
+		}
+	}
+	// Only pr_embeddings' text index has a vector of the model to query with.
+	if queried != 1 {
+		t.Errorf("queried %d indexes, want 1: %+v", queried, results)
+	}
+}
+
+func TestIndexedSources(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	backend := "backend"
+	w, err := repo.NewDocumentBatchWriter(ctx, "Azure/ARO-HCP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, doc := range []db.DocumentChunk{
+		{Path: "README.md", DocType: "readme", ChunkIndex: 0},
+		{Path: "docs/a.md", DocType: "docs", ChunkIndex: 0},
+		{Path: "docs/a.md", DocType: "docs", ChunkIndex: 1},
+		{Path: "backend/README.md", DocType: "readme", Component: &backend},
+		{Path: "backend/runbook.md", DocType: "runbook", Component: &backend},
+	} {
+		doc.ID, doc.Repo, doc.CommitSHA = strconv.Itoa(i), "Azure/ARO-HCP", "abc"
+		doc.ChunkText, doc.Embedding = doc.Path, *vec(1, float32(i), 0)
+		if err := w.Add(ctx, &doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := repo.IndexedSources(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		component string
+		docTypes  string
+		files     int
+		chunks    int
+	}{
+		{"", "docs,readme", 2, 3},
+		{"backend", "readme,runbook", 2, 2},
+	}
+	if len(sources) != len(want) {
+		t.Fatalf("sources = %+v, want %d", sources, len(want))
+	}
+	for i, w := range want {
+		s := sources[i]
+		component := ""
+		if s.Component != nil {
+			component = *s.Component
+		}
+		if component != w.component || strings.Join(s.DocTypes, ",") != w.docTypes || s.Files != w.files || s.Chunks != w.chunks || s.CommitSHA != "abc" {
+			t.Errorf("source %d = %+v, want %+v", i, s, w)
+		}
+	}
+}
+
+func TestJobQueue(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	job, err := repo.EnqueueJob(ctx, "ingestion", map[string]string{"mode": "CACHE"})
+	if err != nil || job.Status != db.JobQueued {
+		t.Fatalf("EnqueueJob = %+v, %v", job, err)
+	}
+	if active, err := repo.EnqueueJob(ctx, "ingestion", nil); !errors.Is(err, db.ErrJobActive) || active.ID != job.ID {
+		t.Fatalf("second EnqueueJob = %+v, %v; want the queued job and ErrJobActive", active, err)
+	}
+
+	claimed, err := repo.ClaimJob(ctx, "w1", []string{"ingestion"})
+	if err != nil || claimed == nil || claimed.ID != job.ID || claimed.Attempts != 1 || string(claimed.Params) != `{"mode": "CACHE"}` {
+		t.Fatalf("ClaimJob = %+v, %v", claimed, err)
+	}
+	if next, err := repo.ClaimJob(ctx, "w2", []string{"ingestion"}); err != nil || next != nil {
+		t.Fatalf("ClaimJob of an empty queue = %+v, %v", next, err)
+	}
+
+	// w1 stops heartbeating: the job is requeued, then failed once it has
+	// been attempted maxAttempts times.
+	time.Sleep(10 * time.Millisecond)
+	if requeued, failed, err := repo.RequeueStaleJobs(ctx, time.Millisecond, 2); err != nil || requeued != 1 || failed != 0 {
+		t.Fatalf("RequeueStaleJobs = %d, %d, %v", requeued, failed, err)
+	}
+	if claimed, err = repo.ClaimJob(ctx, "w2", []string{"ingestion"}); err != nil || claimed == nil || claimed.Attempts != 2 {
+		t.Fatalf("ClaimJob after requeue = %+v, %v", claimed, err)
+	}
+	if err := repo.UpdateJobProgress(ctx, job.ID, "w1", "cache", 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.FinishJob(ctx, job.ID, "w2", nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.GetJob(ctx, job.ID)
+	if err != nil || got.Status != db.JobSucceeded || got.Phase != nil || got.FinishedAt == nil {
+		t.Fatalf("finished job = %+v, %v; want succeeded without the stale worker's progress", got, err)
+	}
+	if _, err := repo.EnqueueJob(ctx, "ingestion", nil); err != nil {
+		t.Fatalf("EnqueueJob after the run finished: %v", err)
+	}
+	if listed, err := repo.ListJobs(ctx, "ingestion", 10); err != nil || len(listed) != 2 {
+		t.Fatalf("ListJobs = %d jobs, %v", len(listed), err)
+	}
+}
+
+func TestLeadership(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.NewMigrated(t)
+
+	lead, err := database.TryLeadership(ctx, "test")
+	if err != nil || lead == nil {
+		t.Fatalf("TryLeadership = %v, %v", lead, err)
+	}
+	if other, err := database.TryLeadership(ctx, "test"); err != nil || other != nil {
+		t.Fatalf("second TryLeadership = %v, %v; want nil while held", other, err)
+	}
+	if err := lead.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := lead.Release(); err != nil {
+		t.Fatal(err)
+	}
+	next, err := database.TryLeadership(ctx, "test")
+	if err != nil || next == nil {
+		t.Fatalf("TryLeadership after release = %v, %v", next, err)
+	}
+	next.Release()
+}
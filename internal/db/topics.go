@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// PRVectors returns the number, title and embedding of every PR embedded by
+// the repository's model, for clustering.
+func (r *SearchRepository) PRVectors(ctx context.Context) ([]PREmbedding, error) {
+	if r.embeddingModel == "" {
+		return nil, errNoEmbeddingModel
+	}
+	var prs []PREmbedding
+	err := r.db.NewSelect().Model(&prs).
+		Column("pr_number", "pr_title", "embedding").
+		Where("embedding IS NOT NULL").
+		Where("embedding_model = ?", r.embeddingModel).
+		Order("pr_number").
+		Scan(ctx)
+	return prs, err
+}
+
+// ReplaceTopics atomically replaces every topic with topics and assigns
+// each PR in assignments (PR number to topic ID) to its topic. PRs left out
+// are unassigned.
+func (r *SearchRepository) ReplaceTopics(ctx context.Context, topics []PRTopic, assignments map[int]int) error {
+	prs := make([]int, 0, len(assignments))
+	ids := make([]int, 0, len(assignments))
+	for pr, id := range assignments {
+		prs = append(prs, pr)
+		ids = append(ids, id)
+	}
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewUpdate().Model((*PREmbedding)(nil)).Set("topic_id = NULL").Where("topic_id IS NOT NULL").Exec(ctx); err != nil {
+			return err
+		}
+		if _, err := tx.NewDelete().Model((*PRTopic)(nil)).Where("TRUE").Exec(ctx); err != nil {
+			return err
+		}
+		if len(topics) == 0 {
+			return nil
+		}
+		if _, err := tx.NewInsert().Model(&topics).Exec(ctx); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE pr_embeddings SET topic_id = v.topic_id
+FROM unnest(?::int[], ?::int[]) AS v(pr_number, topic_id)
+WHERE pr_embeddings.pr_number = v.pr_number`, pgdialect.Array(prs), pgdialect.Array(ids))
+		return err
+	})
+}
+
+// ListTopics returns every topic, largest first.
+func (r *SearchRepository) ListTopics(ctx context.Context) ([]PRTopic, error) {
+	var topics []PRTopic
+	err := r.db.NewSelect().Model(&topics).Order("size DESC", "id").Scan(ctx)
+	return topics, err
+}
+
+// GetTopic returns the topic with id, or nil if there is none.
+func (r *SearchRepository) GetTopic(ctx context.Context, id int) (*PRTopic, error) {
+	var topics []PRTopic
+	if err := r.db.NewSelect().Model(&topics).Where("id = ?", id).Scan(ctx); err != nil {
+		return nil, err
+	}
+	if len(topics) == 0 {
+		return nil, nil
+	}
+	return &topics[0], nil
+}
+
+// TopicPRs returns up to limit PRs of topic id, most recently merged first,
+// without their embeddings.
+func (r *SearchRepository) TopicPRs(ctx context.Context, id, limit int) ([]PREmbedding, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var prs []PREmbedding
+	err := r.db.NewSelect().Model(&prs).
+		ExcludeColumn("embedding", "description_embedding").
+		Where("topic_id = ?", id).
+		OrderExpr("merged_at DESC NULLS LAST, pr_number DESC").
+		Limit(limit).
+		Scan(ctx)
+	return prs, err
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/uptrace/bun"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/telemetry"
+)
+
+// maxTracedQueryLen bounds the db.statement attribute; vector literals make
+// raw queries very large.
+const maxTracedQueryLen = 2048
+
+// tracingHook opens a span around every query issued through bun, which covers
+// all SearchRepository methods.
+type tracingHook struct{}
+
+var _ bun.QueryHook = tracingHook{}
+
+func (tracingHook) BeforeQuery(ctx context.Context, event *bun.QueryEvent) context.Context {
+	ctx, _ = telemetry.Start(ctx, "db."+event.Operation(),
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", event.Operation()),
+	)
+	return ctx
+}
+
+func (tracingHook) AfterQuery(ctx context.Context, event *bun.QueryEvent) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		span.End()
+		return
+	}
+	query := event.Query
+	if len(query) > maxTracedQueryLen {
+		query = query[:maxTracedQueryLen] + "..."
+	}
+	span.SetAttributes(attribute.String("db.statement", query))
+	if event.Result != nil {
+		if n, err := event.Result.RowsAffected(); err == nil {
+			span.SetAttributes(attribute.Int64("db.rows_affected", n))
+		}
+	}
+	telemetry.End(span, queryError(event.Err))
+}
+
+// queryError drops sql.ErrNoRows, which callers treat as a normal outcome.
+func queryError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
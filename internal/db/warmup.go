@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	pgvector "github.com/pgvector/pgvector-go"
+	"github.com/uptrace/bun"
+)
+
+// IndexWarmup is the outcome of warming one vector index.
+type IndexWarmup struct {
+	Index      string
+	Table      string
+	Missing    bool          // the index does not exist
+	Blocks     int64         // index blocks read into the buffer cache
+	PrewarmErr error         // why the index was not prewarmed; it is still queried
+	Empty      bool          // no vector of the model to query with
+	Latency    time.Duration // of the first query through the index
+}
+
+// WarmUpIndexes reads each of the model's HNSW indexes into the buffer cache
+// with pg_prewarm, creating the extension when it is missing, then times one
+// nearest-neighbour query through the index with a stored vector as the
+// query. Missing indexes are reported, not prewarmed; tables without vectors
+// of the model are prewarmed but not queried. When the extension cannot be
+// created or an index cannot be prewarmed, as on managed servers that do not
+// allow pg_prewarm, the index records why and is queried cold.
+func (r *SearchRepository) WarmUpIndexes(ctx context.Context) ([]IndexWarmup, error) {
+	if r.embeddingModel == "" || r.embeddingDim <= 0 {
+		return nil, errNoEmbeddingModel
+	}
+	var prewarmErr error
+	if _, err := r.db.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS pg_prewarm"); err != nil {
+		prewarmErr = fmt.Errorf("enable pg_prewarm: %w", err)
+	}
+	type target struct{ name, table, column string }
+	targets := make([]target, 0, len(embeddingTables)+1)
+	for _, table := range embeddingTables {
+		targets = append(targets, target{table, table, "embedding"})
+	}
+	targets = append(targets, target{descriptionIndexTable, "pr_embeddings", "description_embedding"})
+
+	results := make([]IndexWarmup, 0, len(targets))
+	for _, t := range targets {
+		w := IndexWarmup{Index: EmbeddingIndexName(t.name, r.embeddingModel, r.quantization, r.distanceMetric), Table: t.table}
+		exists, err := r.db.NewSelect().TableExpr("pg_indexes").Where("indexname = ?", w.Index).Exists(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("look up index %s: %w", w.Index, err)
+		}
+		if !exists {
+			w.Missing = true
+			results = append(results, w)
+			continue
+		}
+		w.PrewarmErr = prewarmErr
+		if w.PrewarmErr == nil {
+			if err := r.db.NewRaw("SELECT pg_prewarm(?::regclass)", w.Index).Scan(ctx, &w.Blocks); err != nil {
+				w.PrewarmErr = err
+			}
+		}
+
+		var vec pgvector.Vector
+		err = r.db.NewSelect().TableExpr("?", bun.Ident(t.table)).
+			ColumnExpr("?", bun.Ident(t.column)).
+			Where("embedding_model = ?", r.embeddingModel).
+			Where("? IS NOT NULL", bun.Ident(t.column)).
+			Limit(1).
+			Scan(ctx, &vec)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Empty = true
+			results = append(results, w)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sample %s vector: %w", t.table, err)
+		}
+
+		var ids []string
+		start := time.Now()
+		err = r.db.NewSelect().TableExpr("?", bun.Ident(t.table)).
+			ColumnExpr("id::text").
+			Where("embedding_model = ?", r.embeddingModel).
+			OrderExpr(r.approxExprOn(t.column), vec).
+			Limit(10).
+			Scan(ctx, &ids)
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", w.Index, err)
+		}
+		w.Latency = time.Since(start)
+		results = append(results, w)
+	}
+	return results, nil
+}
@@ -0,0 +1,124 @@
+package docs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Chunk is a piece of a file together with its location in the file.
+type Chunk struct {
+	Text      string
+	StartLine int    // 1-based; 0 when the chunk could not be located
+	EndLine   int    // 1-based, inclusive
+	Anchor    string // slug of the closest preceding heading, if any
+}
+
+type heading struct {
+	Line int
+	Slug string
+}
+
+// locateChunks maps each chunk back to its line range in content and the
+// nearest preceding markdown heading. Chunks are expected in file order; the
+// splitter may overlap them, so the search resumes just after the previous
+// chunk's start rather than its end.
+func locateChunks(content string, parts []string) []Chunk {
+	headings := markdownHeadings(content)
+	chunks := make([]Chunk, len(parts))
+	from := 0
+	for i, part := range parts {
+		chunks[i].Text = part
+		trimmed := strings.TrimSpace(part)
+		if trimmed == "" {
+			continue
+		}
+		offset := strings.Index(content[from:], trimmed)
+		if offset < 0 {
+			continue
+		}
+		start := from + offset
+		from = start + 1
+
+		startLine := strings.Count(content[:start], "\n") + 1
+		endLine := startLine + strings.Count(trimmed, "\n")
+		chunks[i].StartLine = startLine
+		chunks[i].EndLine = endLine
+		chunks[i].Anchor = anchorFor(headings, startLine)
+	}
+	return chunks
+}
+
+// anchorFor returns the slug of the last heading at or before line.
+func anchorFor(headings []heading, line int) string {
+	anchor := ""
+	for _, h := range headings {
+		if h.Line > line {
+			break
+		}
+		anchor = h.Slug
+	}
+	return anchor
+}
+
+// markdownHeadings returns ATX headings outside fenced code blocks together
+// with GitHub-compatible anchor slugs (duplicates get -1, -2, ... suffixes).
+func markdownHeadings(content string) []heading {
+	var headings []heading
+	seen := make(map[string]int)
+	inFence := false
+	for idx, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		text := strings.TrimLeft(trimmed, "#")
+		if len(trimmed)-len(text) > 6 || (text != "" && !strings.HasPrefix(text, " ")) {
+			continue
+		}
+		slug := slugify(strings.TrimSpace(text))
+		if slug == "" {
+			continue
+		}
+		if n, ok := seen[slug]; ok {
+			seen[slug] = n + 1
+			slug = fmt.Sprintf("%s-%d", slug, n+1)
+		} else {
+			seen[slug] = 0
+		}
+		headings = append(headings, heading{Line: idx + 1, Slug: slug})
+	}
+	return headings
+}
+
+// slugify mirrors GitHub's heading anchor generation: lowercase, drop
+// punctuation other than hyphens and underscores, spaces become hyphens.
+func slugify(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// sourceFragment returns the URL fragment that deep-links to a chunk: its
+// line range when known, since that is the precise citation, else its
+// heading. GitHub only highlights markdown lines in the plain view.
+func sourceFragment(c Chunk) string {
+	if c.StartLine > 0 {
+		return fmt.Sprintf("?plain=1#L%d-L%d", c.StartLine, c.EndLine)
+	}
+	if c.Anchor != "" {
+		return "#" + c.Anchor
+	}
+	return ""
+}
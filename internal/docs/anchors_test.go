@@ -0,0 +1,31 @@
+package docs
+
+import "testing"
+
+func TestLocateChunks_AnchorsAndLines(t *testing.T) {
+	content := "# Intro\n\ntext\n\n## Installing the Operator\n\nstep one\nstep two\n\n```\n# not a heading\n```\n"
+	parts := []string{"# Intro\n\ntext", "## Installing the Operator\n\nstep one\nstep two", "```\n# not a heading\n```"}
+
+	spans := locateChunks(content, parts)
+	if spans[1].Text != parts[1] {
+		t.Fatalf("chunk text not kept: %q", spans[1].Text)
+	}
+	if spans[0].Anchor != "intro" || spans[0].StartLine != 1 || spans[0].EndLine != 3 {
+		t.Fatalf("unexpected first span: %+v", spans[0])
+	}
+	if spans[1].Anchor != "installing-the-operator" || spans[1].StartLine != 5 || spans[1].EndLine != 8 {
+		t.Fatalf("unexpected second span: %+v", spans[1])
+	}
+	if spans[2].Anchor != "installing-the-operator" {
+		t.Fatalf("fenced comment should not become a heading: %+v", spans[2])
+	}
+}
+
+func TestSourceFragment(t *testing.T) {
+	if got := sourceFragment(Chunk{StartLine: 120, EndLine: 168, Anchor: "faq"}); got != "?plain=1#L120-L168" {
+		t.Fatalf("unexpected line fragment %q", got)
+	}
+	if got := sourceFragment(Chunk{Anchor: "faq"}); got != "#faq" {
+		t.Fatalf("unexpected anchor fragment %q", got)
+	}
+}
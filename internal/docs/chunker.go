@@ -1,40 +1,166 @@
 package docs
 
 import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
 	"github.com/tmc/langchaingo/textsplitter"
 )
 
-// mdChunker wraps langchaingo's RecursiveCharacter splitter with markdown-aware separators.
-type mdChunker struct {
+// Splitters of a ChunkSpec: markdown splits at code fences, headings and
+// list items before lines; recursive splits at paragraphs, lines and words.
+const (
+	SplitterMarkdown  = "markdown"
+	SplitterRecursive = "recursive"
+)
+
+// docTypes are the doc_type values chunking can be set for; "default"
+// applies to the types without an entry.
+var docTypes = []string{"default", "readme", "docs", "adr", "runbook", "other"}
+
+// ChunkSpec is how the docs ingester splits files of one doc_type: the
+// splitter and the chunk size and overlap, in characters.
+type ChunkSpec struct {
+	Splitter string
+	Size     int
+	Overlap  int
+}
+
+// String is the splitter:size:overlap form ParseChunkSpecs reads, also
+// recorded on each document chunk.
+func (s ChunkSpec) String() string {
+	return fmt.Sprintf("%s:%d:%d", s.Splitter, s.Size, s.Overlap)
+}
+
+// ParseChunkSpecs parses comma-separated doc_type=splitter:size:overlap
+// entries such as "default=markdown:1000:100,runbook=markdown:2000:200" into
+// specs by doc type.
+func ParseChunkSpecs(spec string) (map[string]ChunkSpec, error) {
+	specs := map[string]ChunkSpec{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		docType, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: want doc_type=splitter:size:overlap", pair)
+		}
+		s, err := ParseChunkSpec(strings.TrimSpace(docType), strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", pair, err)
+		}
+		specs[strings.TrimSpace(docType)] = s
+	}
+	return specs, nil
+}
+
+// ParseChunkSpec parses the splitter:size:overlap spec of docType.
+func ParseChunkSpec(docType, value string) (ChunkSpec, error) {
+	if !slices.Contains(docTypes, docType) {
+		return ChunkSpec{}, fmt.Errorf("doc_type %q is not one of %s", docType, strings.Join(docTypes, ", "))
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 || (parts[0] != SplitterMarkdown && parts[0] != SplitterRecursive) {
+		return ChunkSpec{}, fmt.Errorf("want splitter:size:overlap, splitter %s or %s", SplitterMarkdown, SplitterRecursive)
+	}
+	size, err := strconv.Atoi(parts[1])
+	if err != nil || size <= 0 {
+		return ChunkSpec{}, fmt.Errorf("chunk size must be a positive integer")
+	}
+	overlap, err := strconv.Atoi(parts[2])
+	if err != nil || overlap < 0 || overlap >= size {
+		return ChunkSpec{}, fmt.Errorf("overlap must be between 0 and the chunk size")
+	}
+	return ChunkSpec{Splitter: parts[0], Size: size, Overlap: overlap}, nil
+}
+
+// defaultChunkSpec is used for doc types without a spec and no default one.
+var defaultChunkSpec = ChunkSpec{Splitter: SplitterMarkdown, Size: 1000, Overlap: 100}
+
+// textChunker wraps langchaingo's RecursiveCharacter splitter.
+type textChunker struct {
 	s textsplitter.RecursiveCharacter
 }
 
-func NewMDChunker(chunkSize, overlap int) mdChunker {
+// NewMDChunker splits with markdown-aware separators.
+func NewMDChunker(chunkSize, overlap int) textChunker {
+	return textChunker{s: newSplitter(chunkSize, overlap, []string{
+		"\n```", // code fences
+		"\n# ", "\n## ", "\n### ",
+		"\n- ", "\n* ", // lists
+		"\n", // line
+		"",   // fallback
+	})}
+}
+
+// NewRecursiveChunker splits at paragraphs, then lines, then words,
+// ignoring markdown structure.
+func NewRecursiveChunker(chunkSize, overlap int) textChunker {
+	return textChunker{s: newSplitter(chunkSize, overlap, []string{"\n\n", "\n", " ", ""})}
+}
+
+func newSplitter(chunkSize, overlap int, separators []string) textsplitter.RecursiveCharacter {
 	if chunkSize <= 0 {
 		chunkSize = 1000
 	}
 	if overlap < 0 {
 		overlap = 0
 	}
-	return mdChunker{
-		s: textsplitter.NewRecursiveCharacter(
-			textsplitter.WithSeparators([]string{
-				"\n```", // code fences
-				"\n# ", "\n## ", "\n### ",
-				"\n- ", "\n* ", // lists
-				"\n", // line
-				"",   // fallback
-			}),
-			textsplitter.WithChunkSize(chunkSize),
-			textsplitter.WithChunkOverlap(overlap),
-		),
-	}
+	return textsplitter.NewRecursiveCharacter(
+		textsplitter.WithSeparators(separators),
+		textsplitter.WithChunkSize(chunkSize),
+		textsplitter.WithChunkOverlap(overlap),
+	)
 }
 
-func (c mdChunker) Split(text string) []string {
+// Split splits text into chunks and records the lines and heading each one
+// starts under.
+func (c textChunker) Split(text string) []Chunk {
 	parts, err := c.s.SplitText(text)
 	if err != nil || len(parts) == 0 {
-		return []string{text}
+		parts = []string{text}
+	}
+	return locateChunks(text, parts)
+}
+
+// Chunking picks the chunker of each doc_type from specs keyed by doc type,
+// falling back to the "default" spec and then to markdown:1000:100.
+type Chunking struct {
+	specs    map[string]ChunkSpec
+	chunkers map[ChunkSpec]Chunker
+}
+
+func NewChunking(specs map[string]ChunkSpec) *Chunking {
+	return &Chunking{specs: specs, chunkers: map[ChunkSpec]Chunker{}}
+}
+
+// For returns the chunker of docType and the spec it was built from. A nil
+// Chunking uses markdown:1000:100 for every doc type.
+func (c *Chunking) For(docType string) (Chunker, ChunkSpec) {
+	if c == nil {
+		return newChunker(defaultChunkSpec), defaultChunkSpec
+	}
+	spec, ok := c.specs[docType]
+	if !ok {
+		spec, ok = c.specs["default"]
+	}
+	if !ok {
+		spec = defaultChunkSpec
+	}
+	chunker, ok := c.chunkers[spec]
+	if !ok {
+		chunker = newChunker(spec)
+		c.chunkers[spec] = chunker
+	}
+	return chunker, spec
+}
+
+func newChunker(spec ChunkSpec) Chunker {
+	if spec.Splitter == SplitterRecursive {
+		return NewRecursiveChunker(spec.Size, spec.Overlap)
 	}
-	return parts
+	return NewMDChunker(spec.Size, spec.Overlap)
 }
@@ -0,0 +1,46 @@
+package docs
+
+import "testing"
+
+func TestChunkingFor(t *testing.T) {
+	runbook := ChunkSpec{Splitter: SplitterMarkdown, Size: 2000, Overlap: 200}
+	fallback := ChunkSpec{Splitter: SplitterRecursive, Size: 500, Overlap: 50}
+	c := NewChunking(map[string]ChunkSpec{"runbook": runbook, "default": fallback})
+
+	if _, spec := c.For("runbook"); spec != runbook {
+		t.Errorf("runbook spec = %v, want %v", spec, runbook)
+	}
+	if _, spec := c.For("adr"); spec != fallback {
+		t.Errorf("adr spec = %v, want the default %v", spec, fallback)
+	}
+	if _, spec := NewChunking(nil).For("adr"); spec.String() != "markdown:1000:100" {
+		t.Errorf("spec without config = %v", spec)
+	}
+	if _, spec := (*Chunking)(nil).For("adr"); spec != defaultChunkSpec {
+		t.Errorf("spec of nil Chunking = %v", spec)
+	}
+	chunker, _ := c.For("adr")
+	if chunks := chunker.Split("first paragraph\n\nsecond paragraph"); len(chunks) != 1 {
+		t.Errorf("small text split into %d chunks", len(chunks))
+	}
+}
+
+func TestParseChunkSpecs(t *testing.T) {
+	cases := []struct {
+		spec string
+		ok   bool
+	}{
+		{"default=markdown:1000:100, runbook=recursive:2000:200", true},
+		{"", true},
+		{"runbook=markdown:200:200", false},
+		{"runbook=markdown:0:0", false},
+		{"runbook=html:1000:100", false},
+		{"runbook", false},
+		{"faq=markdown:1000:100", false},
+	}
+	for _, c := range cases {
+		if _, err := ParseChunkSpecs(c.spec); (err == nil) != c.ok {
+			t.Errorf("ParseChunkSpecs(%q) = %v, want ok=%v", c.spec, err, c.ok)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package docs
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxCodeChunkBytes caps a declaration's text; longer bodies are cut so the
+// embedding still sees the signature and doc comment.
+const maxCodeChunkBytes = 8000
+
+// CodeChunk is one top-level declaration of a source file, or one keyed
+// subtree of a configuration file.
+type CodeChunk struct {
+	Symbol    string // e.g. Server.Start, NewClient, defaults.maestro.image
+	Kind      string // func|method|type|const|var|class|interface|enum|function|map|list|value|values|param|resource|module|output
+	StartLine int    // 1-based, including the doc comment
+	EndLine   int    // 1-based, inclusive
+	Text      string
+}
+
+// Languages stored in code_chunks by "ingest code" and "ingest config".
+var (
+	CodeLanguages   = []string{"go", "typescript"}
+	ConfigLanguages = []string{"yaml", "bicep"}
+)
+
+// codeLanguage returns the language ingested for path, or "" to skip it.
+func codeLanguage(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		return "go"
+	case ".ts", ".tsx":
+		if strings.HasSuffix(path, ".d.ts") {
+			return ""
+		}
+		return "typescript"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".bicep", ".bicepparam":
+		return "bicep"
+	}
+	return ""
+}
+
+// splitCode splits src into declarations according to language.
+func splitCode(language string, src []byte) ([]CodeChunk, error) {
+	switch language {
+	case "go":
+		return splitGo(src)
+	case "typescript":
+		return splitTypeScript(src), nil
+	case "yaml":
+		return splitYAML(src), nil
+	case "bicep":
+		return splitDecls(src, bicepDeclRE), nil
+	}
+	return nil, nil
+}
+
+// splitGo returns one chunk per function, method and type spec, and one per
+// const or var block, each with its doc comment.
+func splitGo(src []byte) ([]CodeChunk, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	chunk := func(symbol, kind string, doc *ast.CommentGroup, node ast.Node) CodeChunk {
+		start := node.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		from, to := fset.Position(start), fset.Position(node.End())
+		return CodeChunk{
+			Symbol:    symbol,
+			Kind:      kind,
+			StartLine: from.Line,
+			EndLine:   to.Line,
+			Text:      capCode(src[from.Offset:to.Offset]),
+		}
+	}
+
+	var chunks []CodeChunk
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				chunks = append(chunks, chunk(d.Name.Name, "func", d.Doc, d))
+				continue
+			}
+			chunks = append(chunks, chunk(receiverName(d.Recv.List[0].Type)+"."+d.Name.Name, "method", d.Doc, d))
+		case *ast.GenDecl:
+			switch d.Tok {
+			case token.TYPE:
+				for _, spec := range d.Specs {
+					ts := spec.(*ast.TypeSpec)
+					doc := ts.Doc
+					var node ast.Node = ts
+					if len(d.Specs) == 1 {
+						// Ungrouped: include the "type" keyword and the decl's doc.
+						doc, node = d.Doc, d
+					}
+					chunks = append(chunks, chunk(ts.Name.Name, "type", doc, node))
+				}
+			case token.CONST, token.VAR:
+				var names []string
+				for _, spec := range d.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						names = append(names, name.Name)
+					}
+				}
+				chunks = append(chunks, chunk(strings.Join(names, ","), d.Tok.String(), d.Doc, d))
+			}
+		}
+	}
+	return chunks, nil
+}
+
+// receiverName returns the type name of a method receiver such as *Server
+// or List[T].
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// tsDeclRE matches a top-level TypeScript declaration at the start of a line.
+var tsDeclRE = regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?(?:async\s+)?(function\*?|class|interface|type|enum|const|let|var)\s+([A-Za-z_$][\w$]*)`)
+
+// splitTypeScript approximates declaration boundaries without a parser.
+func splitTypeScript(src []byte) []CodeChunk {
+	chunks := splitDecls(src, tsDeclRE)
+	for i := range chunks {
+		chunks[i].Kind = strings.TrimSuffix(chunks[i].Kind, "*")
+	}
+	return chunks
+}
+
+// splitDecls splits src at unindented lines matching re, whose first and
+// second groups are the declaration kind and symbol. A declaration includes
+// the comment and decorator lines directly above it and runs until the next
+// one.
+func splitDecls(src []byte, re *regexp.Regexp) []CodeChunk {
+	lines := strings.Split(string(src), "\n")
+	type start struct {
+		line         int // 0-based, including leading comments
+		symbol, kind string
+	}
+	var starts []start
+	for i, line := range lines {
+		m := re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		first := i
+		for first > 0 && isCommentLine(lines[first-1]) {
+			first--
+		}
+		if len(starts) > 0 && first <= starts[len(starts)-1].line {
+			first = i
+		}
+		starts = append(starts, start{line: first, symbol: m[2], kind: m[1]})
+	}
+
+	chunks := make([]CodeChunk, 0, len(starts))
+	for i, s := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1].line
+		}
+		for end > s.line+1 && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		chunks = append(chunks, CodeChunk{
+			Symbol:    s.symbol,
+			Kind:      s.kind,
+			StartLine: s.line + 1,
+			EndLine:   end,
+			Text:      capCode([]byte(strings.Join(lines[s.line:end], "\n"))),
+		})
+	}
+	return chunks
+}
+
+func isCommentLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "/*") ||
+		strings.HasPrefix(trimmed, "*") || strings.HasPrefix(trimmed, "@")
+}
+
+func capCode(b []byte) string {
+	if len(b) > maxCodeChunkBytes {
+		b = b[:maxCodeChunkBytes]
+		if i := bytes.LastIndexByte(b, '\n'); i > 0 {
+			b = b[:i]
+		}
+		return string(b) + "\n// ... truncated"
+	}
+	return string(b)
+}
@@ -0,0 +1,135 @@
+package docs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/pgvector/pgvector-go"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/progress"
+)
+
+// CodeIngester embeds the top-level declarations of Go and TypeScript files,
+// or the keyed blocks of YAML and Bicep configuration, into code_chunks,
+// replacing a repository's previous chunks of those languages atomically.
+type CodeIngester struct {
+	Repo      *db.SearchRepository
+	Client    EmbeddingClient
+	Include   []string
+	Exclude   []string
+	MaxFiles  int
+	MaxChunks int
+	ModelName string
+	// Languages selects the files to ingest; defaults to CodeLanguages.
+	Languages []string
+	// Progress reports the files embedded per repository; nil disables it.
+	Progress *progress.Meter
+	// Git configures the clones at RepoSpec.Path.
+	Git gitrepo.Settings
+}
+
+func (i *CodeIngester) Run(ctx context.Context, repos []RepoSpec) error {
+	for _, r := range repos {
+		if err := i.ingestRepo(ctx, r); err != nil {
+			return fmt.Errorf("failed to ingest code of %s: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+func (i *CodeIngester) ingestRepo(ctx context.Context, r RepoSpec) error {
+	repo := gitrepo.New(gitrepo.RepoConfig{Settings: i.Git, Path: r.Path})
+	ref := r.Ref
+	if ref == "" || ref == "HEAD" {
+		head, err := repo.HeadSHA(ctx)
+		if err != nil {
+			return fmt.Errorf("get HEAD: %w", err)
+		}
+		ref = head
+	}
+
+	files, err := repo.ListFiles(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("list files: %w", err)
+	}
+	languages := i.Languages
+	if len(languages) == 0 {
+		languages = CodeLanguages
+	}
+	var candidates []string
+	for _, f := range files {
+		if slices.Contains(languages, codeLanguage(f)) {
+			candidates = append(candidates, f)
+		}
+	}
+	selected := filterFiles(candidates, globsToRegexp(i.Include), globsToRegexp(i.Exclude), i.MaxFiles)
+
+	var chunks []db.CodeChunk
+	for n, p := range selected {
+		i.Progress.Update(r.Name, n, len(selected))
+		if i.MaxChunks > 0 && len(chunks) >= i.MaxChunks {
+			break
+		}
+		// The chunks stored replace all of the repository's, so a file
+		// that cannot be read or embedded fails the run instead of losing
+		// its earlier chunks.
+		content, err := repo.ShowFile(ctx, ref, p)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", p, err)
+		}
+		language := codeLanguage(p)
+		decls, err := splitCode(language, content)
+		if err != nil {
+			log.Printf("code: skip %s: %v", p, err)
+			continue
+		}
+		for _, decl := range decls {
+			if i.MaxChunks > 0 && len(chunks) >= i.MaxChunks {
+				break
+			}
+			// Lead with the location so queries naming a package, file or
+			// key path match.
+			vecs, err := i.Client.EmbedTexts(ctx, []string{fmt.Sprintf("%s %s (%s)\n\n%s", p, decl.Symbol, decl.Kind, decl.Text)})
+			if err != nil {
+				return fmt.Errorf("embed %s %s: %w", p, decl.Symbol, err)
+			}
+			if len(vecs) == 0 {
+				return fmt.Errorf("embed %s %s: no vector returned", p, decl.Symbol)
+			}
+			chunks = append(chunks, db.CodeChunk{
+				ID:        sha256Hex(r.Name + ":" + p + ":" + ref + ":" + decl.Symbol + ":" + i.ModelName + ":" + decl.Text),
+				Repo:      r.Name,
+				Path:      p,
+				CommitSHA: ref,
+				Language:  language,
+				Symbol:    decl.Symbol,
+				Kind:      decl.Kind,
+				StartLine: decl.StartLine,
+				EndLine:   decl.EndLine,
+				ChunkText: decl.Text,
+				Embedding: pgvector.NewVector(vecs[0]),
+				SourceURL: strptr(codeURL(guessURL(r.Name, p, ref), decl)),
+			})
+		}
+	}
+
+	i.Progress.Update(r.Name, len(selected), len(selected))
+
+	if err := i.Repo.ReplaceCodeChunks(ctx, r.Name, languages, chunks); err != nil {
+		return fmt.Errorf("store code chunks: %w", err)
+	}
+	log.Printf("code: stored %d chunks from %d files of %s", len(chunks), len(selected), r.Name)
+	return nil
+}
+
+// codeURL links to a declaration's lines in a file URL.
+func codeURL(fileURL string, decl CodeChunk) string {
+	if fileURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s#L%d-L%d", fileURL, decl.StartLine, decl.EndLine)
+}
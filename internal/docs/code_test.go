@@ -0,0 +1,124 @@
+package docs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestSplitGo(t *testing.T) {
+	src := `package server
+
+import "context"
+
+// Timeout bounds every request.
+const Timeout = 5
+
+// Server serves requests.
+type Server struct{}
+
+type (
+	A int
+	B string
+)
+
+// Start runs the server.
+func (s *Server) Start(ctx context.Context) error {
+	return nil
+}
+
+func New() *Server { return &Server{} }
+`
+	chunks, err := splitGo([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		symbol, kind string
+		start, end   int
+	}{
+		{"Timeout", "const", 5, 6},
+		{"Server", "type", 8, 9},
+		{"A", "type", 12, 12},
+		{"B", "type", 13, 13},
+		{"Server.Start", "method", 16, 19},
+		{"New", "func", 21, 21},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks: %+v", len(chunks), chunks)
+	}
+	for i, w := range want {
+		c := chunks[i]
+		if c.Symbol != w.symbol || c.Kind != w.kind || c.StartLine != w.start || c.EndLine != w.end {
+			t.Errorf("chunk %d = %s %s %d-%d, want %s %s %d-%d", i, c.Symbol, c.Kind, c.StartLine, c.EndLine, w.symbol, w.kind, w.start, w.end)
+		}
+	}
+	if !strings.HasPrefix(chunks[4].Text, "// Start runs the server.") {
+		t.Errorf("method chunk should include its doc comment: %q", chunks[4].Text)
+	}
+}
+
+func TestSplitTypeScript(t *testing.T) {
+	src := `import { x } from "y";
+
+/** Fetches a cluster. */
+export async function getCluster(id: string) {
+  return x(id);
+}
+
+export class ClusterList {
+  items = [];
+}
+`
+	chunks := splitTypeScript([]byte(src))
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks: %+v", len(chunks), chunks)
+	}
+	if c := chunks[0]; c.Symbol != "getCluster" || c.Kind != "function" || c.StartLine != 3 || c.EndLine != 6 {
+		t.Errorf("unexpected first chunk: %+v", c)
+	}
+	if c := chunks[1]; c.Symbol != "ClusterList" || c.Kind != "class" || c.StartLine != 8 || c.EndLine != 10 {
+		t.Errorf("unexpected second chunk: %+v", c)
+	}
+}
+
+type failingEmbedder struct{}
+
+func (failingEmbedder) EmbedTexts(ctx context.Context, inputs []string) ([][]float32, error) {
+	return nil, errors.New("ollama down")
+}
+
+func TestCodeIngesterFailsOnEmbedError(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("main.go"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Commit("init", &git.CommitOptions{Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A nil Repo panics if the ingester goes on to replace the stored chunks.
+	i := &CodeIngester{Client: failingEmbedder{}, Languages: []string{"go"}}
+	err = i.Run(context.Background(), []RepoSpec{{Name: "Azure/ARO-HCP", Path: dir}})
+	if err == nil || !strings.Contains(err.Error(), "ollama down") {
+		t.Fatalf("Run = %v, want the embedding error", err)
+	}
+}
@@ -0,0 +1,178 @@
+package docs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxConfigChunkBytes is the size up to which a YAML subtree is kept whole;
+// larger mappings and lists are split into their children.
+const maxConfigChunkBytes = 1500
+
+// splitYAML splits a YAML file into subtrees keyed by their dotted key path,
+// e.g. "clouds.public.environments.stg.defaults.maestro.image", so a question
+// naming the keys lands on the block that defines them. List items are keyed
+// by their "name" field when they have one. Consecutive scalars of a mapping
+// that is too large to keep whole are grouped under the mapping's path.
+// Files that are not valid YAML, such as Helm templates, are kept whole.
+func splitYAML(src []byte) []CodeChunk {
+	s := yamlSplitter{lines: strings.Split(string(src), "\n")}
+	dec := yaml.NewDecoder(bytes.NewReader(src))
+	var roots []*yaml.Node
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return []CodeChunk{{Kind: "text", StartLine: 1, EndLine: len(s.lines), Text: capCode(src)}}
+		}
+		if len(doc.Content) > 0 {
+			roots = append(roots, doc.Content[0])
+		}
+	}
+	for i, root := range roots {
+		end := len(s.lines)
+		if i+1 < len(roots) {
+			end = roots[i+1].Line - 1
+		}
+		s.split("", root, end)
+	}
+	return s.chunks
+}
+
+type yamlSplitter struct {
+	lines  []string
+	chunks []CodeChunk
+}
+
+type yamlEntry struct {
+	path       string
+	node       *yaml.Node
+	start, end int // 1-based, inclusive, including leading comments
+}
+
+// split emits the children of node, whose entries end at line end.
+func (s *yamlSplitter) split(path string, node *yaml.Node, end int) {
+	entries := s.entries(path, node, end)
+	var run []yamlEntry
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		chunk := s.chunk(run[0].path, "value", run[0].start, run[len(run)-1].end)
+		if len(run) > 1 {
+			chunk.Kind = "values"
+			if path != "" {
+				chunk.Symbol = path
+			} else {
+				keys := make([]string, 0, len(run))
+				for _, e := range run {
+					keys = append(keys, e.path)
+				}
+				chunk.Symbol = strings.Join(keys, ",")
+			}
+		}
+		s.chunks = append(s.chunks, chunk)
+		run = nil
+	}
+
+	for _, e := range entries {
+		size := len(s.text(e.start, e.end))
+		switch {
+		case len(e.node.Content) == 0:
+			if len(run) > 0 && len(s.text(run[0].start, e.end)) > maxConfigChunkBytes {
+				flush()
+			}
+			run = append(run, e)
+		case size <= maxConfigChunkBytes:
+			flush()
+			kind := "map"
+			if e.node.Kind == yaml.SequenceNode {
+				kind = "list"
+			}
+			s.chunks = append(s.chunks, s.chunk(e.path, kind, e.start, e.end))
+		default:
+			flush()
+			s.split(e.path, e.node, e.end)
+		}
+	}
+	flush()
+}
+
+// entries returns the keys of a mapping or the items of a list with their
+// line ranges; each runs until the next one starts.
+func (s *yamlSplitter) entries(path string, node *yaml.Node, end int) []yamlEntry {
+	var entries []yamlEntry
+	add := func(p string, value *yaml.Node, line int) {
+		start := line
+		for start > 1 && strings.HasPrefix(strings.TrimSpace(s.lines[start-2]), "#") {
+			start--
+		}
+		if n := len(entries); n > 0 {
+			if start <= entries[n-1].start {
+				start = line
+			}
+			entries[n-1].end = start - 1
+		}
+		entries = append(entries, yamlEntry{path: p, node: value, start: start, end: end})
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			p := key.Value
+			if path != "" {
+				p = path + "." + key.Value
+			}
+			add(p, value, key.Line)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			add(path+"["+itemName(item, i)+"]", item, item.Line)
+		}
+	}
+	return entries
+}
+
+// itemName identifies a list item by its "name" field, or else its index.
+func itemName(item *yaml.Node, index int) string {
+	if item.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			if item.Content[i].Value == "name" && item.Content[i+1].Kind == yaml.ScalarNode {
+				return item.Content[i+1].Value
+			}
+		}
+	}
+	return strconv.Itoa(index)
+}
+
+func (s *yamlSplitter) chunk(symbol, kind string, start, end int) CodeChunk {
+	for end > start && strings.TrimSpace(s.lines[end-1]) == "" {
+		end--
+	}
+	return CodeChunk{
+		Symbol:    symbol,
+		Kind:      kind,
+		StartLine: start,
+		EndLine:   end,
+		Text:      capCode([]byte(s.text(start, end))),
+	}
+}
+
+func (s *yamlSplitter) text(start, end int) string {
+	end = min(end, len(s.lines))
+	if start < 1 || start > end {
+		return ""
+	}
+	return strings.Join(s.lines[start-1:end], "\n")
+}
+
+// bicepDeclRE matches a top-level Bicep or bicepparam declaration.
+var bicepDeclRE = regexp.MustCompile(`^(param|var|resource|module|output|type|func)\s+([A-Za-z_]\w*)`)
@@ -0,0 +1,89 @@
+package docs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitYAMLKeyPaths(t *testing.T) {
+	// Pushes defaults past maxConfigChunkBytes so it is split by key.
+	big := "          padding:\n" + strings.Repeat("            key: a value long enough to take some room\n", 40)
+	src := `clouds:
+  public:
+    environments:
+      stg:
+        defaults:
+` + big + `          region: uksouth
+          regionShort: uks
+          # Maestro server image.
+          maestro:
+            image:
+              registry: arohcpsvcint.azurecr.io
+              digest: sha256:abc
+resourceGroups:
+- name: global
+  steps:
+  - name: deploy
+`
+	chunks := splitYAML([]byte(src))
+	bySymbol := map[string]CodeChunk{}
+	for _, c := range chunks {
+		bySymbol[c.Symbol] = c
+	}
+
+	maestro, ok := bySymbol["clouds.public.environments.stg.defaults.maestro"]
+	if !ok {
+		t.Fatalf("no maestro chunk in %+v", chunks)
+	}
+	if maestro.Kind != "map" || !strings.HasPrefix(maestro.Text, "          # Maestro server image.") || !strings.Contains(maestro.Text, "digest: sha256:abc") {
+		t.Errorf("maestro chunk = %+v", maestro)
+	}
+	lines := strings.Split(src, "\n")
+	if got := lines[maestro.EndLine-1]; strings.TrimSpace(got) != "digest: sha256:abc" {
+		t.Errorf("maestro chunk ends at %q", got)
+	}
+	if scalars, ok := bySymbol["clouds.public.environments.stg.defaults"]; !ok || scalars.Kind != "values" || !strings.Contains(scalars.Text, "regionShort: uks") {
+		t.Errorf("defaults scalars = %+v", scalars)
+	}
+	if _, ok := bySymbol["resourceGroups"]; !ok {
+		t.Errorf("small top-level list should be one chunk: %+v", chunks)
+	}
+}
+
+func TestSplitYAMLNamedListItems(t *testing.T) {
+	steps := strings.Repeat("- name: step\n  action: ARM\n", 60)
+	chunks := splitYAML([]byte("steps:\n- name: deploy-maestro\n  action: Shell\n" + steps))
+	if len(chunks) == 0 || chunks[0].Symbol != "steps[deploy-maestro]" {
+		t.Errorf("chunks = %+v", chunks)
+	}
+}
+
+func TestSplitYAMLTemplateFallback(t *testing.T) {
+	src := "apiVersion: v1\nkind: ConfigMap\n{{- if .Values.enabled }}\ndata: {}\n{{- end }}\n"
+	chunks := splitYAML([]byte(src))
+	if len(chunks) != 1 || chunks[0].Kind != "text" || chunks[0].StartLine != 1 {
+		t.Errorf("chunks = %+v", chunks)
+	}
+}
+
+func TestSplitBicep(t *testing.T) {
+	src := `@description('The maestro image digest')
+param maestroImageDigest string
+
+var location = resourceGroup().location
+
+module maestro 'maestro.bicep' = {
+  name: 'maestro'
+}
+`
+	chunks := splitDecls([]byte(src), bicepDeclRE)
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks: %+v", len(chunks), chunks)
+	}
+	if c := chunks[0]; c.Symbol != "maestroImageDigest" || c.Kind != "param" || c.StartLine != 1 || c.EndLine != 2 {
+		t.Errorf("param chunk = %+v", c)
+	}
+	if c := chunks[2]; c.Symbol != "maestro" || c.Kind != "module" || c.EndLine != 8 {
+		t.Errorf("module chunk = %+v", c)
+	}
+}
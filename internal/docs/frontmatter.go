@@ -0,0 +1,95 @@
+package docs
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// docMetadata is the YAML front matter a runbook or ADR may declare:
+//
+//	---
+//	owner: team-hcp-sre
+//	severity: critical
+//	alerts: [FrontendDown, FrontendLatencyHigh]
+//	---
+type docMetadata struct {
+	Type     string
+	Owner    string
+	Severity string
+	Alerts   []string
+}
+
+// Front-matter keys accepted for each field, first match wins.
+var (
+	typeKeys     = []string{"doc_type", "type"}
+	ownerKeys    = []string{"owner", "owners", "team"}
+	severityKeys = []string{"severity"}
+	alertKeys    = []string{"alerts", "alert_names", "alertnames", "alert", "alertname"}
+)
+
+// parseFrontMatter reads the YAML block delimited by "---" lines at the top
+// of content. Files without front matter, or with front matter that is not a
+// YAML mapping, yield empty metadata.
+func parseFrontMatter(content string) docMetadata {
+	content = strings.TrimPrefix(content, "\ufeff")
+	if !strings.HasPrefix(content, "---\n") && !strings.HasPrefix(content, "---\r\n") {
+		return docMetadata{}
+	}
+	_, rest, _ := strings.Cut(content, "\n")
+	var block strings.Builder
+	closed := false
+	for _, line := range strings.SplitAfter(rest, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed == "---" || trimmed == "..." {
+			closed = true
+			break
+		}
+		block.WriteString(line)
+	}
+	if !closed {
+		return docMetadata{}
+	}
+
+	var fields map[string]any
+	if err := yaml.Unmarshal([]byte(block.String()), &fields); err != nil {
+		return docMetadata{}
+	}
+	lower := make(map[string]any, len(fields))
+	for k, v := range fields {
+		lower[strings.ToLower(k)] = v
+	}
+	return docMetadata{
+		Type:     strings.ToLower(strings.Join(lookup(lower, typeKeys), "")),
+		Owner:    strings.Join(lookup(lower, ownerKeys), ", "),
+		Severity: strings.ToLower(strings.Join(lookup(lower, severityKeys), "")),
+		Alerts:   lookup(lower, alertKeys),
+	}
+}
+
+// lookup returns the first of keys present in fields as a list of strings,
+// accepting a scalar or a list of scalars.
+func lookup(fields map[string]any, keys []string) []string {
+	for _, key := range keys {
+		value, ok := fields[key]
+		if !ok {
+			continue
+		}
+		var out []string
+		switch v := value.(type) {
+		case []any:
+			for _, item := range v {
+				if s := strings.TrimSpace(fmt.Sprint(item)); s != "" {
+					out = append(out, s)
+				}
+			}
+		case nil:
+		default:
+			if s := strings.TrimSpace(fmt.Sprint(v)); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package docs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	content := "---\nOwner: team-hcp-sre\nseverity: Critical\nalerts:\n  - FrontendDown\n  - FrontendLatencyHigh\n---\n# Frontend down\n"
+	meta := parseFrontMatter(content)
+	want := docMetadata{Owner: "team-hcp-sre", Severity: "critical", Alerts: []string{"FrontendDown", "FrontendLatencyHigh"}}
+	if !reflect.DeepEqual(meta, want) {
+		t.Fatalf("parseFrontMatter = %+v, want %+v", meta, want)
+	}
+	if got := classifyDocType("docs/frontend.md", meta); got != "runbook" {
+		t.Fatalf("classifyDocType with alerts = %q, want runbook", got)
+	}
+
+	single := parseFrontMatter("---\nalert: ClusterStuck\ntype: ADR\n---\nbody")
+	if !reflect.DeepEqual(single.Alerts, []string{"ClusterStuck"}) || single.Type != "adr" {
+		t.Fatalf("scalar alert not parsed: %+v", single)
+	}
+
+	for _, content := range []string{"# No front matter\n", "---\nowner: x\n", "---\n- a list\n---\n"} {
+		if meta := parseFrontMatter(content); !reflect.DeepEqual(meta, docMetadata{}) {
+			t.Fatalf("parseFrontMatter(%q) = %+v, want empty", content, meta)
+		}
+	}
+}
@@ -5,14 +5,17 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"log"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/pgvector/pgvector-go"
 
 	"github.com/roivaz/aro-hcp-intelhub/internal/db"
 	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/progress"
 )
 
 type EmbeddingClient interface {
@@ -20,7 +23,7 @@ type EmbeddingClient interface {
 }
 
 type Chunker interface {
-	Split(text string) []string
+	Split(text string) []Chunk
 }
 
 type RepoSpec struct {
@@ -28,22 +31,49 @@ type RepoSpec struct {
 	Path      string // local path
 	Component string // optional
 	Ref       string // optional ref (default HEAD)
+	// Tarball, when set, supplies the files instead of the clone at Path.
+	Tarball *Tarball
+	// Include, Exclude and Chunking override the ingester's when set.
+	Include  []string
+	Exclude  []string
+	Chunking *Chunking
+}
+
+// fileSource lists and reads the files of a repository at a ref;
+// *gitrepo.Repo and *Tarball implement it.
+type fileSource interface {
+	ListFiles(ctx context.Context, ref string) ([]string, error)
+	ShowFile(ctx context.Context, ref, path string) ([]byte, error)
 }
 
 type Ingester struct {
 	Repo      *db.SearchRepository
 	Client    EmbeddingClient
-	Chunker   Chunker
+	Chunking  *Chunking
 	Include   []string
 	Exclude   []string
 	MaxFiles  int
 	MaxChunks int
 	ModelName string
+	// Force re-chunks and re-embeds every file. Otherwise the chunks of files
+	// whose content hash matches the previous ingest are copied as they are.
+	Force bool
+	// Progress reports the files embedded per repository; nil disables it.
+	Progress *progress.Meter
+	// Git configures the clones at RepoSpec.Path.
+	Git gitrepo.Settings
 }
 
+// Run ingests repos one at a time, replacing each repository's documents in
+// one transaction. When ctx is cancelled the repository in progress is
+// rolled back, keeping its previous documents, while the repositories
+// already ingested stay committed.
 func (i *Ingester) Run(ctx context.Context, repos []RepoSpec) error {
-	for _, r := range repos {
+	for n, r := range repos {
 		if err := i.ingestRepoAtomic(ctx, r); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("interrupted ingesting %s, left unchanged (%d of %d repos done): %w", r.Name, n, len(repos), ctx.Err())
+			}
 			return fmt.Errorf("failed to ingest %s: %w", r.Name, err)
 		}
 	}
@@ -59,14 +89,20 @@ func (i *Ingester) ingestRepoAtomic(ctx context.Context, r RepoSpec) error {
 	defer writer.Rollback() // Safe to call even after commit
 
 	// Get repo reference
-	repo := gitrepo.New(gitrepo.RepoConfig{Path: r.Path})
+	var repo fileSource
 	ref := r.Ref
-	if ref == "" {
-		head, err := repo.HeadSHA(ctx)
-		if err != nil {
-			return fmt.Errorf("get HEAD: %w", err)
+	if r.Tarball != nil {
+		repo, ref = r.Tarball, r.Tarball.SHA
+	} else {
+		clone := gitrepo.New(gitrepo.RepoConfig{Settings: i.Git, Path: r.Path})
+		if ref == "" {
+			head, err := clone.HeadSHA(ctx)
+			if err != nil {
+				return fmt.Errorf("get HEAD: %w", err)
+			}
+			ref = head
 		}
-		ref = head
+		repo = clone
 	}
 
 	// List and filter files
@@ -75,12 +111,20 @@ func (i *Ingester) ingestRepoAtomic(ctx context.Context, r RepoSpec) error {
 		return fmt.Errorf("list files: %w", err)
 	}
 
-	includeRx := globsToRegexp(i.Include)
-	excludeRx := globsToRegexp(i.Exclude)
-	selected := filterFiles(files, includeRx, excludeRx, i.MaxFiles)
+	include, exclude := i.globs(r)
+	selected := filterFiles(files, globsToRegexp(include), globsToRegexp(exclude), i.MaxFiles)
+	chunking := i.Chunking
+	if r.Chunking != nil {
+		chunking = r.Chunking
+	}
 
 	// Process files and add to batch
-	for _, p := range selected {
+	reused := 0
+	for n, p := range selected {
+		i.Progress.Update(r.Name, n, len(selected))
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if i.MaxChunks > 0 && writer.Count() >= i.MaxChunks {
 			break
 		}
@@ -89,10 +133,27 @@ func (i *Ingester) ingestRepoAtomic(ctx context.Context, r RepoSpec) error {
 		if err != nil {
 			continue
 		}
+		// search_docs include_full_file serves the file from here.
+		if err := writer.AddFile(ctx, ref, p, content); err != nil {
+			return fmt.Errorf("store %s: %w", p, err)
+		}
 
-		parts := i.Chunker.Split(string(content))
-		for idx, part := range parts {
-			if strings.TrimSpace(part) == "" {
+		meta := parseFrontMatter(string(content))
+		docType := classifyDocType(p, meta)
+		chunker, spec := chunking.For(docType)
+		if !i.Force {
+			ok, err := i.reuseChunks(ctx, writer, r, ref, p, db.ContentHash(content), spec.String())
+			if err != nil {
+				return fmt.Errorf("reuse chunks of %s: %w", p, err)
+			}
+			if ok {
+				reused++
+				continue
+			}
+		}
+
+		for idx, chunk := range chunker.Split(string(content)) {
+			if strings.TrimSpace(chunk.Text) == "" {
 				continue
 			}
 			if i.MaxChunks > 0 && writer.Count() >= i.MaxChunks {
@@ -100,33 +161,54 @@ func (i *Ingester) ingestRepoAtomic(ctx context.Context, r RepoSpec) error {
 			}
 
 			// Embed the chunk
-			vecs, err := i.Client.EmbedTexts(ctx, []string{part})
+			vecs, err := i.Client.EmbedTexts(ctx, []string{chunk.Text})
 			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
 				continue
 			}
 
 			// Create document
-			id := sha256Hex(r.Name + ":" + p + ":" + ref + ":" + itoa(idx) + ":" + part)
+			id := sha256Hex(r.Name + ":" + p + ":" + ref + ":" + itoa(idx) + ":" + i.ModelName + ":" + chunk.Text)
 			doc := db.DocumentChunk{
 				ID:             id,
 				Repo:           r.Name,
+				Component:      strptr(r.Component),
 				Path:           p,
 				CommitSHA:      ref,
-				DocType:        classifyDocType(p),
+				DocType:        docType,
 				ChunkIndex:     idx,
-				ChunkText:      part,
+				ChunkText:      chunk.Text,
 				Embedding:      pgvector.NewVector(vecs[0]),
 				EmbeddingModel: i.ModelName,
-				SourceURL:      strptr(guessURL(r.Name, p, ref)),
+				SourceURL:      strptr(chunkURL(guessURL(r.Name, p, ref), chunk)),
+				Anchor:         strptr(chunk.Anchor),
+				Owner:          strptr(meta.Owner),
+				Severity:       strptr(meta.Severity),
+				AlertNames:     meta.Alerts,
+				Chunking:       strptr(spec.String()),
+			}
+			if chunk.StartLine > 0 {
+				doc.StartLine = intptr(chunk.StartLine)
+				doc.EndLine = intptr(chunk.EndLine)
 			}
 
 			// Add to batch
 			if err := writer.Add(ctx, &doc); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
 				continue
 			}
 		}
 	}
 
+	i.Progress.Update(r.Name, len(selected), len(selected))
+	if reused > 0 {
+		log.Printf("docs: reused the chunks of %d unchanged files of %s", reused, r.Name)
+	}
+
 	// Commit atomic swap
 	if err := writer.Commit(ctx); err != nil {
 		return fmt.Errorf("commit batch: %w", err)
@@ -135,6 +217,62 @@ func (i *Ingester) ingestRepoAtomic(ctx context.Context, r RepoSpec) error {
 	return nil
 }
 
+// reuseChunks adds the stored chunks of path at ref when its content hash
+// matches the previous ingest and they were split with chunking, skipping
+// chunking and embedding. It reports whether any chunk was reused.
+func (i *Ingester) reuseChunks(ctx context.Context, writer db.DocumentBatchWriter, r RepoSpec, ref, path, hash, chunking string) (bool, error) {
+	chunks, err := writer.UnchangedChunks(ctx, path, hash)
+	if err != nil || len(chunks) == 0 {
+		return false, err
+	}
+	for _, doc := range chunks {
+		if derefString(doc.Chunking) != chunking {
+			return false, nil
+		}
+	}
+	for _, doc := range chunks {
+		if i.MaxChunks > 0 && writer.Count() >= i.MaxChunks {
+			break
+		}
+		chunk := Chunk{Anchor: derefString(doc.Anchor)}
+		if doc.StartLine != nil && doc.EndLine != nil {
+			chunk.StartLine, chunk.EndLine = *doc.StartLine, *doc.EndLine
+		}
+		doc.ID = sha256Hex(r.Name + ":" + path + ":" + ref + ":" + itoa(doc.ChunkIndex) + ":" + i.ModelName + ":" + doc.ChunkText)
+		doc.CommitSHA = ref
+		doc.Component = strptr(r.Component)
+		doc.SourceURL = strptr(chunkURL(guessURL(r.Name, path, ref), chunk))
+		doc.UpdatedAt = time.Time{}
+		if err := writer.Add(ctx, &doc); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// globs are the include and exclude globs of r, defaulting to the
+// ingester's.
+func (i *Ingester) globs(r RepoSpec) (include, exclude []string) {
+	include, exclude = i.Include, i.Exclude
+	if len(r.Include) > 0 {
+		include = r.Include
+	}
+	if len(r.Exclude) > 0 {
+		exclude = r.Exclude
+	}
+	return include, exclude
+}
+
+// Selects returns a filter accepting the paths ingested from r, for
+// fetching only those files of a tarball.
+func (i *Ingester) Selects(r RepoSpec) func(path string) bool {
+	include, exclude := i.globs(r)
+	includeRx, excludeRx := globsToRegexp(include), globsToRegexp(exclude)
+	return func(path string) bool {
+		return len(filterFiles([]string{path}, includeRx, excludeRx, 0)) == 1
+	}
+}
+
 func globsToRegexp(globs []string) *regexp.Regexp {
 	if len(globs) == 0 {
 		return nil
@@ -190,11 +328,23 @@ func itoa(i int) string { return fmtInt(i) }
 
 func fmtInt(i int) string { return strings.TrimPrefix(fmt.Sprintf("%d", i), "+") }
 
-func classifyDocType(path string) string {
+// classifyDocType prefers the type declared in front matter, then treats
+// documents listing alerts as runbooks, then falls back to the path.
+func classifyDocType(path string, meta docMetadata) string {
+	switch meta.Type {
+	case "readme", "docs", "adr", "runbook":
+		return meta.Type
+	}
+	if len(meta.Alerts) > 0 {
+		return "runbook"
+	}
 	base := filepath.Base(path)
 	if strings.EqualFold(base, "README.md") {
 		return "readme"
 	}
+	if strings.Contains(strings.ToLower(path), "runbook") {
+		return "runbook"
+	}
 	if strings.Contains(strings.ToLower(path), "/docs/") {
 		return "docs"
 	}
@@ -212,6 +362,23 @@ func guessURL(repoName, path, ref string) string {
 	return ""
 }
 
+// chunkURL appends the chunk's deep-link fragment to a file URL.
+func chunkURL(fileURL string, chunk Chunk) string {
+	if fileURL == "" {
+		return ""
+	}
+	return fileURL + sourceFragment(chunk)
+}
+
+func intptr(i int) *int { return &i }
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 func strptr(s string) *string {
 	if s == "" {
 		return nil
@@ -0,0 +1,99 @@
+package docs
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepoConfig is one repository of a docs-repos.yaml file or --repo-url
+// flag. Empty fields take the ingester's defaults: HEAD, the repository name
+// as component, the Markdown include globs and DOCS_CHUNKING.
+type RepoConfig struct {
+	URL       string   `yaml:"url" json:"url"`
+	Ref       string   `yaml:"ref" json:"ref,omitempty"`
+	Component string   `yaml:"component" json:"component,omitempty"`
+	Include   []string `yaml:"include" json:"include,omitempty"`
+	Exclude   []string `yaml:"exclude" json:"exclude,omitempty"`
+	// Chunking overrides DOCS_CHUNKING by doc_type, e.g. runbook:
+	// markdown:2000:200.
+	Chunking map[string]string `yaml:"chunking" json:"chunking,omitempty"`
+	Tarball  bool              `yaml:"tarball" json:"tarball,omitempty"` // read from the GitHub tarball instead of a clone
+}
+
+// ReposFile lists the repositories `ingest docs --config` indexes:
+//
+//	repos:
+//	  - url: https://github.com/Azure/ARO-HCP
+//	    ref: main
+//	    component: aro-hcp
+//	    include: ["docs/**/*.md"]
+//	    chunking:
+//	      runbook: markdown:2000:200
+type ReposFile struct {
+	Repos []RepoConfig `yaml:"repos"`
+}
+
+// LoadReposFile reads and validates a docs-repos.yaml file. Unknown fields
+// are rejected so typos do not silently widen the corpus.
+func LoadReposFile(path string) ([]RepoConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var file ReposFile
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(file.Repos) == 0 {
+		return nil, fmt.Errorf("%s lists no repos", path)
+	}
+	for n, repo := range file.Repos {
+		if repo.URL == "" {
+			return nil, fmt.Errorf("%s: repo %d has no url", path, n+1)
+		}
+		if _, err := repo.ChunkSpecs(nil); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, repo.URL, err)
+		}
+	}
+	return file.Repos, nil
+}
+
+// ChunkSpecs returns base with the repository's chunking overrides applied.
+func (c RepoConfig) ChunkSpecs(base map[string]ChunkSpec) (map[string]ChunkSpec, error) {
+	specs := maps.Clone(base)
+	if specs == nil {
+		specs = map[string]ChunkSpec{}
+	}
+	for docType, value := range c.Chunking {
+		spec, err := ParseChunkSpec(docType, value)
+		if err != nil {
+			return nil, fmt.Errorf("chunking of %s: %w", docType, err)
+		}
+		specs[docType] = spec
+	}
+	return specs, nil
+}
+
+// ParseRepoURL parses a --repo-url value, URL[@ref][#component]. The ref is
+// the part after the first @ of the repository path, so SSH URLs such as
+// git@github.com:Azure/ARO-HCP.git@main keep their user.
+func ParseRepoURL(value string) RepoConfig {
+	value, component, _ := strings.Cut(value, "#")
+	pathStart := strings.Index(value, ":")
+	if scheme := strings.Index(value, "://"); scheme >= 0 {
+		pathStart = scheme + 3 + max(strings.Index(value[scheme+3:], "/"), 0)
+	}
+	pathStart = max(pathStart, 0)
+	url, ref := value, ""
+	if at := strings.Index(value[pathStart:], "@"); at >= 0 {
+		url, ref = value[:pathStart+at], value[pathStart+at+1:]
+	}
+	return RepoConfig{URL: url, Ref: ref, Component: component}
+}
@@ -0,0 +1,48 @@
+package docs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadReposFile(t *testing.T) {
+	repos, err := LoadReposFile(filepath.Join("..", "..", "examples", "docs-repos.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 2 || repos[0].Component != "aro-hcp" || !repos[0].Tarball {
+		t.Fatalf("repos = %+v", repos)
+	}
+	base := map[string]ChunkSpec{"default": {Splitter: SplitterMarkdown, Size: 1000, Overlap: 100}}
+	specs, err := repos[0].ChunkSpecs(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if specs["runbook"].Size != 2000 || specs["default"].Size != 1000 || len(base) != 1 {
+		t.Errorf("specs = %v, base = %v", specs, base)
+	}
+
+	bad := filepath.Join(t.TempDir(), "docs-repos.yaml")
+	if err := os.WriteFile(bad, []byte("repos:\n  - url: https://github.com/Azure/ARO-HCP\n    includes: [\"*.md\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadReposFile(bad); err == nil || !strings.Contains(err.Error(), "includes") {
+		t.Errorf("unknown field accepted: %v", err)
+	}
+}
+
+func TestParseRepoURL(t *testing.T) {
+	cases := map[string]RepoConfig{
+		"https://github.com/Azure/ARO-HCP":                          {URL: "https://github.com/Azure/ARO-HCP"},
+		"https://github.com/Azure/ARO-HCP@release/4.18#aro-hcp":     {URL: "https://github.com/Azure/ARO-HCP", Ref: "release/4.18", Component: "aro-hcp"},
+		"https://user@gitlab.example.com/service/clusters#clusters": {URL: "https://user@gitlab.example.com/service/clusters", Component: "clusters"},
+		"git@github.com:openshift/hypershift.git@main":              {URL: "git@github.com:openshift/hypershift.git", Ref: "main"},
+	}
+	for value, want := range cases {
+		if got := ParseRepoURL(value); got.URL != want.URL || got.Ref != want.Ref || got.Component != want.Component {
+			t.Errorf("ParseRepoURL(%q) = %+v, want %+v", value, got, want)
+		}
+	}
+}
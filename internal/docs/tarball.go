@@ -0,0 +1,116 @@
+package docs
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v66/github"
+)
+
+// maxTarballFileBytes skips larger files of a tarball; documentation is far
+// smaller, and the files are held in memory.
+const maxTarballFileBytes = 4 << 20
+
+// Tarball is a repository's files at one commit, read from the GitHub
+// tarball of a ref instead of a clone. Only the files kept when it was
+// fetched are held.
+type Tarball struct {
+	SHA   string // commit the tarball was built from
+	files map[string][]byte
+}
+
+// FetchTarball downloads the tarball of owner/repo at ref (the default
+// branch when empty) through the GitHub API and keeps the files keep
+// accepts, by repo-relative path.
+func FetchTarball(ctx context.Context, client *github.Client, owner, repo, ref string, keep func(path string) bool) (*Tarball, error) {
+	if ref == "HEAD" {
+		ref = ""
+	}
+	link, _, err := client.Repositories.GetArchiveLink(ctx, owner, repo, github.Tarball, &github.RepositoryContentGetOptions{Ref: ref}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("tarball link of %s/%s: %w", owner, repo, err)
+	}
+	// The link is signed, so no credentials are sent to the download host.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download tarball of %s/%s: %w", owner, repo, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download tarball of %s/%s: %s", owner, repo, resp.Status)
+	}
+	return readTarball(resp.Body, keep)
+}
+
+// readTarball reads a gzipped GitHub tarball. Its entries sit under one
+// <owner>-<repo>-<short sha>/ directory, and git archive records the full
+// commit SHA as the comment of the pax global header.
+func readTarball(r io.Reader, keep func(path string) bool) (*Tarball, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("read tarball: %w", err)
+	}
+	defer zr.Close()
+	t := &Tarball{files: map[string][]byte{}}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read tarball: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			t.SHA = hdr.PAXRecords["comment"]
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		_, path, ok := strings.Cut(hdr.Name, "/")
+		if !ok || path == "" || hdr.Size > maxTarballFileBytes || (keep != nil && !keep(path)) {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("read %s from tarball: %w", path, err)
+		}
+		t.files[path] = content
+	}
+	if t.SHA == "" {
+		return nil, errors.New("tarball records no commit SHA")
+	}
+	return t, nil
+}
+
+// ListFiles returns the kept paths, sorted. ref is ignored: a tarball holds
+// a single commit.
+func (t *Tarball) ListFiles(ctx context.Context, ref string) ([]string, error) {
+	paths := make([]string, 0, len(t.files))
+	for path := range t.files {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// ShowFile returns the content of a kept file.
+func (t *Tarball) ShowFile(ctx context.Context, ref, path string) ([]byte, error) {
+	content, ok := t.files[path]
+	if !ok {
+		return nil, fmt.Errorf("%s not in tarball of %s", path, t.SHA)
+	}
+	return content, nil
+}
@@ -0,0 +1,56 @@
+package docs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadTarball(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: map[string]string{"comment": sha}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []struct{ name, body string }{
+		{"Azure-ARO-HCP-0123456/", ""},
+		{"Azure-ARO-HCP-0123456/README.md", "# ARO-HCP\n"},
+		{"Azure-ARO-HCP-0123456/docs/upgrade.md", "Upgrades roll out per stamp.\n"},
+		{"Azure-ARO-HCP-0123456/main.go", "package main\n"},
+	} {
+		hdr := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.body)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(f.name, "/") {
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0o755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	zw.Close()
+
+	ing := Ingester{Include: []string{"**/*.md"}}
+	tb, err := readTarball(&buf, ing.Selects(RepoSpec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tb.SHA != sha {
+		t.Errorf("SHA = %q, want %q", tb.SHA, sha)
+	}
+	files, _ := tb.ListFiles(context.Background(), "")
+	if !slices.Equal(files, []string{"README.md", "docs/upgrade.md"}) {
+		t.Errorf("files = %v", files)
+	}
+	if content, err := tb.ShowFile(context.Background(), "", "docs/upgrade.md"); err != nil || string(content) != "Upgrades roll out per stamp.\n" {
+		t.Errorf("ShowFile = %q, %v", content, err)
+	}
+}
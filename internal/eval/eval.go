@@ -0,0 +1,141 @@
+// Package eval measures retrieval quality against a fixed set of queries with
+// known relevant PRs or documents.
+package eval
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+// Case is a single eval query. Cases with ExpectedPRs are scored against
+// search_prs; otherwise ExpectedPaths are scored against search_docs.
+type Case struct {
+	Name          string   `json:"name"`
+	Query         string   `json:"query"`
+	ExpectedPRs   []int    `json:"expected_prs,omitempty"`
+	ExpectedPaths []string `json:"expected_paths,omitempty"`
+}
+
+// Searcher is the retrieval surface under test.
+type Searcher interface {
+	SearchPRs(ctx context.Context, query string, limit int) ([]types.PRResult, error)
+	SearchDocs(ctx context.Context, query string, limit int, component, repo *string, includeFull bool) ([]types.DocResult, error)
+}
+
+// Report aggregates per-case ranks into recall@k and mean reciprocal rank.
+type Report struct {
+	K         int
+	Cases     int
+	Hits      int
+	RecallAtK float64
+	MRR       float64
+	Results   []types.EvalCaseResult
+}
+
+// LoadCases reads eval cases from a YAML file.
+func LoadCases(path string) ([]Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read eval cases %s: %w", path, err)
+	}
+	var file struct {
+		Cases []Case `json:"cases"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse eval cases %s: %w", path, err)
+	}
+	for i, c := range file.Cases {
+		if strings.TrimSpace(c.Query) == "" {
+			return nil, fmt.Errorf("eval case %d has no query", i)
+		}
+		if len(c.ExpectedPRs) == 0 && len(c.ExpectedPaths) == 0 {
+			return nil, fmt.Errorf("eval case %q has no expected_prs or expected_paths", c.Query)
+		}
+		if c.Name == "" {
+			file.Cases[i].Name = "case-" + strconv.Itoa(i+1)
+		}
+	}
+	return file.Cases, nil
+}
+
+// Run executes every case against searcher and scores the top k results.
+// Search errors are recorded per case and count as misses.
+func Run(ctx context.Context, searcher Searcher, cases []Case, k int) Report {
+	report := Report{K: k, Cases: len(cases)}
+	var reciprocal float64
+	for _, c := range cases {
+		result := types.EvalCaseResult{Name: c.Name, Query: c.Query}
+		ids, err := retrieve(ctx, searcher, c, k)
+		if err != nil {
+			msg := err.Error()
+			result.Error = &msg
+		} else {
+			result.Rank = firstHit(ids, expectedIDs(c))
+		}
+		if result.Rank > 0 {
+			report.Hits++
+			reciprocal += 1 / float64(result.Rank)
+		}
+		report.Results = append(report.Results, result)
+	}
+	if report.Cases > 0 {
+		report.RecallAtK = float64(report.Hits) / float64(report.Cases)
+		report.MRR = reciprocal / float64(report.Cases)
+	}
+	return report
+}
+
+// retrieve returns the identifiers of the top k results for a case: PR numbers
+// for PR cases, repo-relative paths for doc cases.
+func retrieve(ctx context.Context, searcher Searcher, c Case, k int) ([]string, error) {
+	if len(c.ExpectedPRs) > 0 {
+		prs, err := searcher.SearchPRs(ctx, c.Query, k)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, len(prs))
+		for i, pr := range prs {
+			ids[i] = strconv.Itoa(pr.PRNumber)
+		}
+		return ids, nil
+	}
+	docs, err := searcher.SearchDocs(ctx, c.Query, k, nil, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.Path
+	}
+	return ids, nil
+}
+
+func expectedIDs(c Case) map[string]bool {
+	expected := make(map[string]bool)
+	for _, n := range c.ExpectedPRs {
+		expected[strconv.Itoa(n)] = true
+	}
+	if len(c.ExpectedPRs) == 0 {
+		for _, p := range c.ExpectedPaths {
+			expected[p] = true
+		}
+	}
+	return expected
+}
+
+// firstHit returns the 1-based rank of the first expected id, or 0.
+func firstHit(ids []string, expected map[string]bool) int {
+	for i, id := range ids {
+		if expected[id] {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,62 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+type fakeSearcher struct {
+	prs  map[string][]int
+	docs map[string][]string
+}
+
+func (f fakeSearcher) SearchPRs(_ context.Context, query string, limit int) ([]types.PRResult, error) {
+	numbers, ok := f.prs[query]
+	if !ok {
+		return nil, errors.New("no results")
+	}
+	var out []types.PRResult
+	for _, n := range numbers[:min(limit, len(numbers))] {
+		out = append(out, types.PRResult{PRNumber: n})
+	}
+	return out, nil
+}
+
+func (f fakeSearcher) SearchDocs(_ context.Context, query string, limit int, _, _ *string, _ bool) ([]types.DocResult, error) {
+	var out []types.DocResult
+	for _, p := range f.docs[query] {
+		out = append(out, types.DocResult{Path: p})
+	}
+	return out, nil
+}
+
+func TestRun_ScoresRecallAndMRR(t *testing.T) {
+	searcher := fakeSearcher{
+		prs:  map[string][]int{"auth": {10, 20, 30}, "quota": {1, 2}},
+		docs: map[string][]string{"install": {"docs/a.md", "docs/install.md"}},
+	}
+	cases := []Case{
+		{Name: "pr-first", Query: "auth", ExpectedPRs: []int{10}},
+		{Name: "pr-miss", Query: "quota", ExpectedPRs: []int{99}},
+		{Name: "doc-second", Query: "install", ExpectedPaths: []string{"docs/install.md"}},
+		{Name: "error", Query: "unknown", ExpectedPRs: []int{1}},
+	}
+
+	report := Run(context.Background(), searcher, cases, 5)
+	if report.Hits != 2 || report.Cases != 4 {
+		t.Fatalf("unexpected hits/cases: %d/%d", report.Hits, report.Cases)
+	}
+	if report.RecallAtK != 0.5 {
+		t.Fatalf("unexpected recall: %v", report.RecallAtK)
+	}
+	if want := (1 + 0.5) / 4; math.Abs(report.MRR-want) > 1e-9 {
+		t.Fatalf("unexpected mrr: %v want %v", report.MRR, want)
+	}
+	if report.Results[3].Error == nil {
+		t.Fatalf("expected search error to be recorded")
+	}
+}
@@ -0,0 +1,73 @@
+package eval
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+// Scheduler runs the eval suite once a day at a fixed UTC hour and persists
+// the scores in eval_runs.
+type Scheduler struct {
+	Searcher  Searcher
+	Repo      *db.SearchRepository
+	CasesFile string
+	K         int
+	Hour      int // UTC hour of day, 0-23
+}
+
+// Run blocks until ctx is cancelled, running the suite once per day.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		next := nextRun(time.Now().UTC(), s.Hour)
+		log.Printf("eval: next run at %s", next.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := s.RunOnce(ctx); err != nil {
+			log.Printf("eval: run failed: %v", err)
+		}
+	}
+}
+
+// RunOnce executes the suite and stores its report. A run that cannot load
+// its cases is still recorded, with the error, so gaps show up in the trend.
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	started := time.Now().UTC()
+	run := &db.EvalRun{StartedAt: started, K: s.K}
+
+	cases, err := LoadCases(s.CasesFile)
+	if err != nil {
+		msg := err.Error()
+		run.Error = &msg
+	} else {
+		report := Run(ctx, s.Searcher, cases, s.K)
+		run.Cases = report.Cases
+		run.Hits = report.Hits
+		run.RecallAtK = report.RecallAtK
+		run.MRR = report.MRR
+		run.Details = report.Results
+		log.Printf("eval: %d cases, recall@%d=%.3f mrr=%.3f", report.Cases, s.K, report.RecallAtK, report.MRR)
+	}
+	run.FinishedAt = time.Now().UTC()
+
+	if insertErr := s.Repo.InsertEvalRun(ctx, run); insertErr != nil {
+		return insertErr
+	}
+	return err
+}
+
+// nextRun returns the next occurrence of hour:00 UTC strictly after now.
+func nextRun(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
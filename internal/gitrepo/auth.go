@@ -0,0 +1,141 @@
+package gitrepo
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// defaultTokenUser is accepted with GitHub App installation tokens and
+// personal access tokens on both GitHub and GitLab.
+const defaultTokenUser = "x-access-token"
+
+// askpassScript answers git's credential prompts from the environment of the
+// git process, so tokens never reach argv, the remote URL or .git/config.
+const askpassScript = `#!/bin/sh
+case "$1" in
+Username*) printf '%s\n' "$GITREPO_ASKPASS_USER" ;;
+*) printf '%s\n' "$GITREPO_ASKPASS_TOKEN" ;;
+esac
+`
+
+var askpass struct {
+	once sync.Once
+	path string
+	err  error
+}
+
+// askpassPath writes the askpass helper once per process.
+func askpassPath() (string, error) {
+	askpass.once.Do(func() {
+		f, err := os.CreateTemp("", "gitrepo-askpass-*.sh")
+		if err != nil {
+			askpass.err = fmt.Errorf("create askpass helper: %w", err)
+			return
+		}
+		defer f.Close()
+		if _, err := f.WriteString(askpassScript); err != nil {
+			askpass.err = fmt.Errorf("write askpass helper: %w", err)
+			return
+		}
+		if err := f.Chmod(0o700); err != nil {
+			askpass.err = fmt.Errorf("chmod askpass helper: %w", err)
+			return
+		}
+		askpass.path = f.Name()
+	})
+	return askpass.path, askpass.err
+}
+
+// withCredentials fills Token and TokenUser from HostTokens when the caller
+// set none. Tokens are matched by the host of URL, so they are only ever
+// sent to the host they were issued for.
+func withCredentials(cfg RepoConfig) RepoConfig {
+	if cfg.Token == "" && cfg.URL != "" {
+		if cred, ok := cfg.HostTokens[strings.ToLower(urlHost(cfg.URL))]; ok {
+			if user, token, found := strings.Cut(cred, ":"); found {
+				cfg.TokenUser, cfg.Token = user, token
+			} else {
+				cfg.Token = cred
+			}
+		}
+	}
+	if cfg.Token != "" && cfg.TokenUser == "" {
+		cfg.TokenUser = defaultTokenUser
+	}
+	return cfg
+}
+
+// urlHost returns the host of an http(s), ssh or scp-like (git@host:path)
+// remote URL.
+func urlHost(remote string) string {
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if _, rest, ok := strings.Cut(remote, "@"); ok {
+		host, _, _ := strings.Cut(rest, ":")
+		return host
+	}
+	return ""
+}
+
+// isSSH reports whether remote is reached over SSH.
+func isSSH(remote string) bool {
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" {
+		return u.Scheme == "ssh"
+	}
+	return strings.Contains(remote, "@") && strings.Contains(remote, ":")
+}
+
+// authEnv is the environment the exec backend passes to git so it
+// authenticates with the configured token or SSH key.
+func (cfg RepoConfig) authEnv() ([]string, error) {
+	var env []string
+	if cfg.Token != "" {
+		path, err := askpassPath()
+		if err != nil {
+			return nil, err
+		}
+		env = append(env,
+			"GIT_ASKPASS="+path,
+			"GIT_TERMINAL_PROMPT=0",
+			"GITREPO_ASKPASS_USER="+cfg.TokenUser,
+			"GITREPO_ASKPASS_TOKEN="+cfg.Token,
+		)
+	}
+	if cfg.SSHKeyPath != "" {
+		env = append(env, "GIT_SSH_COMMAND=ssh -i '"+strings.ReplaceAll(cfg.SSHKeyPath, "'", `'\''`)+"' -o IdentitiesOnly=yes -o BatchMode=yes")
+	}
+	return env, nil
+}
+
+// authMethod is the go-git equivalent of authEnv for remote; nil uses
+// go-git's defaults (anonymous HTTPS, the SSH agent).
+func (cfg RepoConfig) authMethod(remote string) (transport.AuthMethod, error) {
+	if isSSH(remote) {
+		if cfg.SSHKeyPath == "" {
+			return nil, nil
+		}
+		user := "git"
+		if u, err := url.Parse(remote); err == nil && u.User != nil {
+			user = u.User.Username()
+		} else if name, _, ok := strings.Cut(remote, "@"); ok && !strings.Contains(name, "/") {
+			user = name
+		}
+		auth, err := gitssh.NewPublicKeysFromFile(user, cfg.SSHKeyPath, "")
+		if err != nil {
+			return nil, fmt.Errorf("load SSH key %s: %w", cfg.SSHKeyPath, err)
+		}
+		return auth, nil
+	}
+	if cfg.Token != "" {
+		return &githttp.BasicAuth{Username: cfg.TokenUser, Password: cfg.Token}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,25 @@
+package gitrepo
+
+import "testing"
+
+func TestURLHost(t *testing.T) {
+	cases := []struct {
+		remote string
+		host   string
+		ssh    bool
+	}{
+		{"https://github.com/Azure/ARO-HCP", "github.com", false},
+		{"https://gitlab.example.com:8443/cs/docs.git", "gitlab.example.com", false},
+		{"ssh://git@gitlab.example.com/cs/docs.git", "gitlab.example.com", true},
+		{"git@gitlab.example.com:cs/docs.git", "gitlab.example.com", true},
+		{"/srv/mirror/docs.git", "", false},
+	}
+	for _, c := range cases {
+		if got := urlHost(c.remote); got != c.host {
+			t.Errorf("urlHost(%q) = %q, want %q", c.remote, got, c.host)
+		}
+		if got := isSSH(c.remote); got != c.ssh {
+			t.Errorf("isSSH(%q) = %v, want %v", c.remote, got, c.ssh)
+		}
+	}
+}
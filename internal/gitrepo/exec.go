@@ -0,0 +1,301 @@
+package gitrepo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/telemetry"
+)
+
+type Runner struct {
+	Timeout time.Duration
+	// Progress, when set, receives stderr progress lines as they are written.
+	Progress func(line string)
+	// Env is added to the environment of git.
+	Env []string
+}
+
+func (r Runner) Git(ctx context.Context, dir string, args ...string) (out string, err error) {
+	ctx, span := telemetry.Start(ctx, "git "+gitSubcommand(args), attribute.StringSlice("git.args", args))
+	defer func() { telemetry.End(span, err) }()
+
+	c := exec.CommandContext(ctx, "git", args...)
+	c.Dir = dir
+	if len(r.Env) > 0 {
+		c.Env = append(os.Environ(), r.Env...)
+	}
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if r.Progress != nil {
+		c.Stderr = io.MultiWriter(&stderr, &progressWriter{report: r.Progress})
+	}
+	if err := c.Start(); err != nil {
+		return "", formatGitError(args, err, stderr.String())
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", formatGitError(args, err, stderr.String())
+		}
+		return stdout.String(), nil
+	case <-time.After(r.Timeout):
+		_ = c.Process.Kill()
+		<-done
+		return "", formatGitTimeoutError(args, r.Timeout, stderr.String())
+	case <-ctx.Done():
+		_ = c.Process.Kill()
+		<-done
+		return "", formatGitContextError(args, ctx.Err(), stderr.String())
+	}
+}
+
+// gitSubcommand names the span after the first non-flag argument.
+func gitSubcommand(args []string) string {
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			return a
+		}
+	}
+	return "git"
+}
+
+func formatGitError(args []string, cause error, stderr string) error {
+	cmd := strings.Join(args, " ")
+	stderr = strings.TrimSpace(stderr)
+	if stderr != "" {
+		return fmt.Errorf("git %s: %w: %s", cmd, cause, stderr)
+	}
+	return fmt.Errorf("git %s: %w", cmd, cause)
+}
+
+func formatGitTimeoutError(args []string, timeout time.Duration, stderr string) error {
+	return formatGitError(args, fmt.Errorf("command timed out after %s", timeout), stderr)
+}
+
+func formatGitContextError(args []string, cause error, stderr string) error {
+	if cause == nil {
+		cause = errors.New("context canceled")
+	}
+	return formatGitError(args, cause, stderr)
+}
+
+// execBackend shells out to the system git binary.
+type execBackend struct {
+	cfg    RepoConfig
+	runner Runner
+}
+
+func (b *execBackend) git(ctx context.Context, args ...string) (string, error) {
+	runner := b.runner
+	env, err := b.cfg.authEnv()
+	if err != nil {
+		return "", err
+	}
+	runner.Env = env
+	return runner.Git(ctx, b.cfg.Path, args...)
+}
+
+func (b *execBackend) clone(ctx context.Context, abs string) error {
+	args := []string{"clone", "--filter=blob:none", "--no-tags"}
+	if b.cfg.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(b.cfg.Depth))
+	}
+	if b.cfg.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	if len(b.cfg.SparsePaths) > 0 {
+		args = append(args, "--sparse")
+	}
+	if b.cfg.Progress != nil {
+		args = append(args, "--progress")
+	}
+	args = append(args, b.cfg.URL, abs)
+
+	env, err := b.cfg.authEnv()
+	if err != nil {
+		return err
+	}
+	cloner := Runner{Timeout: b.cfg.CloneTimeout, Progress: b.cfg.Progress, Env: env}
+	if _, err := cloner.Git(ctx, "", args...); err != nil {
+		return err
+	}
+	if len(b.cfg.SparsePaths) > 0 {
+		sparse := append([]string{"sparse-checkout", "set"}, b.cfg.SparsePaths...)
+		if _, err := cloner.Git(ctx, abs, sparse...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *execBackend) fetch(ctx context.Context, extraArgs ...string) error {
+	args := append([]string{"fetch", "--prune", b.cfg.Remote}, extraArgs...)
+	_, err := b.git(ctx, args...)
+	return err
+}
+
+func (b *execBackend) resolve(ctx context.Context, rev string) (string, error) {
+	out, err := b.git(ctx, "rev-parse", "--verify", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (b *execBackend) checkoutDetach(ctx context.Context, ref string) error {
+	_, err := b.git(ctx, "checkout", "--detach", "--end-of-options", ref)
+	return err
+}
+
+func (b *execBackend) mergeDiff(ctx context.Context, mergeSHA string) (string, error) {
+	rangeSpec := fmt.Sprintf("%s^1..%s", mergeSHA, mergeSHA)
+	return b.git(ctx, "show", "--no-color", "--no-ext-diff", "--format=", "--find-renames", "--end-of-options", rangeSpec)
+}
+
+func (b *execBackend) changedFiles(ctx context.Context, mergeSHA string) ([]string, error) {
+	out, err := b.git(ctx, "diff-tree", "--no-commit-id", "--name-only", "-r", "--end-of-options", mergeSHA+"^1", mergeSHA)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(out), nil
+}
+
+func (b *execBackend) isAncestor(ctx context.Context, ancestor, descendant string) (bool, error) {
+	_, err := b.git(ctx, "merge-base", "--is-ancestor", "--end-of-options", ancestor, descendant)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *execBackend) introducedBy(ctx context.Context, sha, ref string) (string, error) {
+	contained, err := b.isAncestor(ctx, sha, ref)
+	if err != nil || !contained {
+		return "", err
+	}
+	out, err := b.git(ctx, "rev-list", "--first-parent", "--ancestry-path", "--reverse", "--end-of-options", sha+".."+ref)
+	if err != nil {
+		return "", err
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	if first == "" {
+		return b.resolve(ctx, sha) // sha is the tip of ref
+	}
+	// When sha is itself on the first-parent chain (a merge, squash or
+	// direct commit), the first descendant's first parent is sha.
+	parent, err := b.resolve(ctx, first+"^1")
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := b.resolve(ctx, sha); err == nil && resolved == parent {
+		return resolved, nil
+	}
+	return first, nil
+}
+
+func (b *execBackend) branchesContaining(ctx context.Context, sha string) ([]string, error) {
+	out, err := b.git(ctx, "branch", "--remotes", "--format=%(refname:short)", "--contains="+sha)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(out), nil
+}
+
+func (b *execBackend) firstParentCommits(ctx context.Context, from, to string) ([]string, error) {
+	out, err := b.git(ctx, "rev-list", "--first-parent", "--end-of-options", from+".."+to)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(out), nil
+}
+
+func (b *execBackend) listFiles(ctx context.Context, ref string) ([]string, error) {
+	out, err := b.git(ctx, "ls-tree", "-r", "--name-only", "--end-of-options", ref)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(out), nil
+}
+
+func (b *execBackend) showFile(ctx context.Context, ref, path string) ([]byte, error) {
+	out, err := b.git(ctx, "show", "--end-of-options", fmt.Sprintf("%s:%s", ref, path))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+func (b *execBackend) blameLines(ctx context.Context, ref, path string, lines []int) (map[int]string, error) {
+	args := []string{"blame", "--porcelain"}
+	for _, line := range lines {
+		args = append(args, "-L", fmt.Sprintf("%d,%d", line, line))
+	}
+	out, err := b.git(ctx, append(args, "--end-of-options", ref, "--", path)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlamePorcelain(out), nil
+}
+
+// parseBlamePorcelain maps final line numbers to commits from the header
+// lines ("<sha> <orig-line> <final-line> [<count>]") of git blame --porcelain.
+func parseBlamePorcelain(out string) map[int]string {
+	commits := map[int]string{}
+	for _, l := range strings.Split(out, "\n") {
+		fields := strings.Fields(l)
+		if len(fields) < 3 || len(fields[0]) != 40 || strings.HasPrefix(l, "\t") {
+			continue
+		}
+		if line, err := strconv.Atoi(fields[2]); err == nil {
+			commits[line] = fields[0]
+		}
+	}
+	return commits
+}
+
+func (b *execBackend) worktreeAdd(ctx context.Context, dir, ref string) error {
+	_, err := b.git(ctx, "worktree", "add", "--detach", "--end-of-options", dir, ref)
+	return err
+}
+
+func (b *execBackend) worktreeRemove(ctx context.Context, dir string) error {
+	_, err := b.git(ctx, "worktree", "remove", dir, "--force")
+	return err
+}
+
+func (b *execBackend) worktreePrune(ctx context.Context) error {
+	_, err := b.git(ctx, "worktree", "prune")
+	return err
+}
+
+func (b *execBackend) configGetAll(ctx context.Context, key string) ([]string, error) {
+	out, err := b.git(ctx, "config", "--local", "--get-all", key)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(out), nil
+}
+
+func (b *execBackend) configAdd(ctx context.Context, key, value string) error {
+	_, err := b.git(ctx, "config", "--local", "--add", key, value)
+	return err
+}
+
+func (b *execBackend) gc(ctx context.Context) error {
+	_, err := Runner{Timeout: 30 * time.Minute}.Git(ctx, b.cfg.Path, "gc", "--quiet")
+	return err
+}
@@ -0,0 +1,66 @@
+package gitrepo
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestExecBackend_RevisionsAreNotOptions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	gitCmd := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	gitCmd("init", "-q")
+	for _, content := range []string{"v1\n", "v2\n"} {
+		if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		gitCmd("add", "README.md")
+		gitCmd("commit", "-q", "-m", "change")
+	}
+
+	ctx := context.Background()
+	r := New(RepoConfig{Path: dir})
+	head, err := r.HeadSHA(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, err := r.ChangedFiles(ctx, head); err != nil || len(files) != 1 {
+		t.Fatalf("ChangedFiles = %v, %v", files, err)
+	}
+	if info, err := r.CommitInfo(ctx, head); err != nil || info.SHA != head {
+		t.Fatalf("CommitInfo = %+v, %v", info, err)
+	}
+
+	target := filepath.Join(t.TempDir(), "written")
+	evil := "--output=" + target
+	calls := map[string]func() error{
+		"ChangedFiles": func() error { _, err := r.ChangedFiles(ctx, evil); return err },
+		"CommitInfo":   func() error { _, err := r.CommitInfo(ctx, evil); return err },
+		"MergeDiff":    func() error { _, err := r.MergeDiff(ctx, evil); return err },
+		"Log":          func() error { _, err := r.Log(ctx, evil, LogOptions{}); return err },
+		"ListFiles":    func() error { _, err := r.ListFiles(ctx, evil); return err },
+		"IsAncestor":   func() error { _, err := r.IsAncestor(ctx, evil, head); return err },
+		"Resolve":      func() error { _, err := r.ResolveRevision(ctx, evil); return err },
+	}
+	for name, call := range calls {
+		if err := call(); err == nil {
+			t.Errorf("%s accepted %q", name, evil)
+		}
+		if _, err := os.Stat(target); err == nil {
+			t.Fatalf("%s treated %q as an option", name, evil)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package gitrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gcLockWait bounds how long garbage collection waits for a clone in use
+// before skipping it until the next run.
+const gcLockWait = 10 * time.Second
+
+// CacheGC keeps the clone cache from filling its volume: clones not used for
+// MaxIdle are deleted and the rest are compacted with git gc. A clone counts
+// as used whenever a Repo takes its lock.
+type CacheGC struct {
+	Dir      string
+	MaxIdle  time.Duration // 0 keeps every clone
+	Interval time.Duration // between runs of Run
+	Backend  string        // compacts the clones; default: exec
+}
+
+// GCResult lists the clones a run removed and compacted.
+type GCResult struct {
+	Removed   []string
+	Compacted []string
+}
+
+// Run blocks until ctx is cancelled, collecting garbage every Interval.
+func (g *CacheGC) Run(ctx context.Context) {
+	ticker := time.NewTicker(g.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		res, err := g.RunOnce(ctx)
+		if err != nil {
+			log.Printf("git cache gc: %v", err)
+		}
+		log.Printf("git cache gc: removed %d idle clones, compacted %d", len(res.Removed), len(res.Compacted))
+	}
+}
+
+// RunOnce collects garbage in every clone directly under Dir. Failures of
+// single clones are joined into the error without stopping the run.
+func (g *CacheGC) RunOnce(ctx context.Context) (GCResult, error) {
+	var res GCResult
+	entries, err := os.ReadDir(g.Dir)
+	if err != nil {
+		return res, fmt.Errorf("read cache dir: %w", err)
+	}
+	var errs []error
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		abs := filepath.Join(g.Dir, e.Name())
+		if !e.IsDir() || !isClone(abs) {
+			continue
+		}
+		removed, err := g.collect(ctx, abs)
+		switch {
+		case errors.Is(err, errUnsupported):
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+		case removed:
+			res.Removed = append(res.Removed, abs)
+		default:
+			res.Compacted = append(res.Compacted, abs)
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+// collect removes the clone at abs if idle and compacts it otherwise. Only
+// removal takes the lock exclusively; git gc is safe alongside readers.
+func (g *CacheGC) collect(ctx context.Context, abs string) (removed bool, err error) {
+	idle := g.MaxIdle > 0 && time.Since(lastUsed(abs)) > g.MaxIdle
+	lockCtx, cancel := context.WithTimeout(ctx, gcLockWait)
+	defer cancel()
+	unlock, err := lockPath(lockCtx, lockFileFor(abs), idle)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	// Check again under the lock: the clone may have been used meanwhile.
+	if idle && time.Since(lastUsed(abs)) > g.MaxIdle {
+		if err := os.RemoveAll(abs); err != nil {
+			return false, err
+		}
+		return true, os.Remove(lockFileFor(abs))
+	}
+	return false, New(RepoConfig{Settings: Settings{Backend: g.Backend}, Path: abs}).backend.gc(ctx)
+}
+
+// markUsed records that the clone guarded by lockFile was used now.
+func markUsed(lockFile string) {
+	now := time.Now()
+	_ = os.Chtimes(lockFile, now, now)
+}
+
+// lastUsed is when a Repo last locked the clone at abs, or when the clone
+// directory last changed if it was never locked.
+func lastUsed(abs string) time.Time {
+	if info, err := os.Stat(lockFileFor(abs)); err == nil {
+		return info.ModTime()
+	}
+	if info, err := os.Stat(abs); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+func isClone(abs string) bool {
+	_, err := os.Stat(filepath.Join(abs, ".git"))
+	return err == nil
+}
+
+// mirrorURL points an http(s) remote at the same path under mirror, e.g.
+// https://github.com/Azure/ARO-HCP becomes
+// https://git-mirror.example.com/github.com/Azure/ARO-HCP. Other remotes are
+// returned unchanged.
+func mirrorURL(mirror, remote string) string {
+	u, err := url.Parse(remote)
+	if mirror == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return remote
+	}
+	return strings.TrimRight(mirror, "/") + "/" + u.Host + u.Path
+}
@@ -0,0 +1,54 @@
+package gitrepo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMirrorURL(t *testing.T) {
+	cases := []struct{ mirror, remote, want string }{
+		{"https://mirror.example.com/", "https://github.com/Azure/ARO-HCP", "https://mirror.example.com/github.com/Azure/ARO-HCP"},
+		{"https://mirror.example.com", "git@github.com:Azure/ARO-HCP.git", "git@github.com:Azure/ARO-HCP.git"},
+		{"", "https://github.com/Azure/ARO-HCP", "https://github.com/Azure/ARO-HCP"},
+	}
+	for _, c := range cases {
+		if got := mirrorURL(c.mirror, c.remote); got != c.want {
+			t.Errorf("mirrorURL(%q, %q) = %q, want %q", c.mirror, c.remote, got, c.want)
+		}
+	}
+}
+
+func TestCacheGCRemovesIdleClones(t *testing.T) {
+	dir := t.TempDir()
+	idle := filepath.Join(dir, "idle")
+	if err := os.MkdirAll(filepath.Join(idle, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "not-a-clone"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockFileFor(idle), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(lockFileFor(idle), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := (&CacheGC{Dir: dir, MaxIdle: 24 * time.Hour}).RunOnce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Removed) != 1 || res.Removed[0] != idle || len(res.Compacted) != 0 {
+		t.Fatalf("result = %+v", res)
+	}
+	if _, err := os.Stat(idle); !os.IsNotExist(err) {
+		t.Errorf("idle clone still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "not-a-clone")); err != nil {
+		t.Errorf("non-clone directory removed: %v", err)
+	}
+}
@@ -0,0 +1,479 @@
+package gitrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// goGitBackend implements Repo with go-git so no git binary is needed. It
+// cannot do partial (blob-filtered) or sparse clones, so clones are larger,
+// and linked worktrees are emulated by writing the commit's tree to disk.
+type goGitBackend struct {
+	cfg RepoConfig
+}
+
+func (b *goGitBackend) open() (*git.Repository, error) {
+	repo, err := git.PlainOpen(b.cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", b.cfg.Path, err)
+	}
+	return repo, nil
+}
+
+func (b *goGitBackend) clone(ctx context.Context, abs string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.CloneTimeout)
+	defer cancel()
+
+	opts := &git.CloneOptions{
+		URL:          b.cfg.URL,
+		RemoteName:   b.cfg.Remote,
+		Depth:        b.cfg.Depth,
+		SingleBranch: b.cfg.SingleBranch,
+		Tags:         git.NoTags,
+	}
+	auth, err := b.cfg.authMethod(b.cfg.URL)
+	if err != nil {
+		return err
+	}
+	opts.Auth = auth
+	if b.cfg.Progress != nil {
+		opts.Progress = &progressWriter{report: b.cfg.Progress}
+	}
+	if _, err := git.PlainCloneContext(ctx, abs, false, opts); err != nil {
+		return fmt.Errorf("clone %s: %w", b.cfg.URL, err)
+	}
+	return nil
+}
+
+func (b *goGitBackend) fetch(ctx context.Context, extraArgs ...string) error {
+	repo, err := b.open()
+	if err != nil {
+		return err
+	}
+	opts := &git.FetchOptions{RemoteName: b.cfg.Remote, Tags: git.NoTags, Prune: true}
+	remote := b.cfg.URL
+	if r, err := repo.Remote(b.cfg.Remote); err == nil && len(r.Config().URLs) > 0 {
+		remote = r.Config().URLs[0]
+	}
+	if opts.Auth, err = b.cfg.authMethod(remote); err != nil {
+		return err
+	}
+	for _, arg := range extraArgs {
+		opts.RefSpecs = append(opts.RefSpecs, gitconfig.RefSpec(arg))
+	}
+	if err := repo.FetchContext(ctx, opts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("fetch %s: %w", b.cfg.Remote, err)
+	}
+	return nil
+}
+
+func (b *goGitBackend) resolve(ctx context.Context, rev string) (string, error) {
+	repo, err := b.open()
+	if err != nil {
+		return "", err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", rev, err)
+	}
+	return hash.String(), nil
+}
+
+func (b *goGitBackend) commit(repo *git.Repository, rev string) (*object.Commit, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", rev, err)
+	}
+	c, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("load commit %s: %w", rev, err)
+	}
+	return c, nil
+}
+
+// firstParentTrees returns the trees of merge^1 and merge.
+func (b *goGitBackend) firstParentTrees(mergeSHA string) (*object.Commit, *object.Commit, error) {
+	repo, err := b.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := b.commit(repo, mergeSHA)
+	if err != nil {
+		return nil, nil, err
+	}
+	parent, err := c.Parent(0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parent of %s: %w", mergeSHA, err)
+	}
+	return parent, c, nil
+}
+
+func (b *goGitBackend) checkoutDetach(ctx context.Context, ref string) error {
+	repo, err := b.open()
+	if err != nil {
+		return err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", ref, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return wt.Checkout(&git.CheckoutOptions{Hash: *hash})
+}
+
+func (b *goGitBackend) mergeDiff(ctx context.Context, mergeSHA string) (string, error) {
+	parent, c, err := b.firstParentTrees(mergeSHA)
+	if err != nil {
+		return "", err
+	}
+	patch, err := parent.PatchContext(ctx, c)
+	if err != nil {
+		return "", fmt.Errorf("diff %s: %w", mergeSHA, err)
+	}
+	return patch.String(), nil
+}
+
+func (b *goGitBackend) changedFiles(ctx context.Context, mergeSHA string) ([]string, error) {
+	parent, c, err := b.firstParentTrees(mergeSHA)
+	if err != nil {
+		return nil, err
+	}
+	from, err := parent.Tree()
+	if err != nil {
+		return nil, err
+	}
+	to, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTreeWithOptions(ctx, from, to, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, fmt.Errorf("diff tree %s: %w", mergeSHA, err)
+	}
+	files := make([]string, 0, len(changes))
+	for _, ch := range changes {
+		name := ch.To.Name
+		if name == "" {
+			name = ch.From.Name
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+func (b *goGitBackend) isAncestor(ctx context.Context, ancestor, descendant string) (bool, error) {
+	repo, err := b.open()
+	if err != nil {
+		return false, err
+	}
+	a, err := b.commit(repo, ancestor)
+	if err != nil {
+		return false, err
+	}
+	d, err := b.commit(repo, descendant)
+	if err != nil {
+		return false, err
+	}
+	return a.IsAncestor(d)
+}
+
+// introducedBy walks ref's first-parent history back to the oldest commit
+// still containing sha. That commit is sha itself when sha was committed
+// directly, and the merge or squash commit that brought it in otherwise.
+func (b *goGitBackend) introducedBy(ctx context.Context, sha, ref string) (string, error) {
+	repo, err := b.open()
+	if err != nil {
+		return "", err
+	}
+	target, err := b.commit(repo, sha)
+	if err != nil {
+		return "", err
+	}
+	c, err := b.commit(repo, ref)
+	if err != nil {
+		return "", err
+	}
+	introducing := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		contains := c.Hash == target.Hash
+		if !contains {
+			if contains, err = target.IsAncestor(c); err != nil {
+				return "", err
+			}
+		}
+		if !contains {
+			return introducing, nil
+		}
+		introducing = c.Hash.String()
+		if c.NumParents() == 0 {
+			return introducing, nil
+		}
+		if c, err = c.Parent(0); err != nil {
+			return "", err
+		}
+	}
+}
+
+func (b *goGitBackend) branchesContaining(ctx context.Context, sha string) ([]string, error) {
+	repo, err := b.open()
+	if err != nil {
+		return nil, err
+	}
+	target, err := b.commit(repo, sha)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+	var branches []string
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if ref.Type() != plumbing.HashReference || !ref.Name().IsRemote() {
+			return nil
+		}
+		tip, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return nil // not a commit
+		}
+		contains := tip.Hash == target.Hash
+		if !contains {
+			if contains, err = target.IsAncestor(tip); err != nil {
+				return err
+			}
+		}
+		if contains {
+			branches = append(branches, ref.Name().Short())
+		}
+		return nil
+	})
+	return branches, err
+}
+
+func (b *goGitBackend) firstParentCommits(ctx context.Context, from, to string) ([]string, error) {
+	commits, err := b.log(ctx, from+".."+to, LogOptions{FirstParent: true})
+	if err != nil {
+		return nil, err
+	}
+	shas := make([]string, len(commits))
+	for i, c := range commits {
+		shas[i] = c.SHA
+	}
+	return shas, nil
+}
+
+func (b *goGitBackend) listFiles(ctx context.Context, ref string) ([]string, error) {
+	repo, err := b.open()
+	if err != nil {
+		return nil, err
+	}
+	c, err := b.commit(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := c.Files()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	err = iter.ForEach(func(f *object.File) error {
+		files = append(files, f.Name)
+		return nil
+	})
+	return files, err
+}
+
+func (b *goGitBackend) showFile(ctx context.Context, ref, path string) ([]byte, error) {
+	repo, err := b.open()
+	if err != nil {
+		return nil, err
+	}
+	c, err := b.commit(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	f, err := c.File(path)
+	if err != nil {
+		return nil, fmt.Errorf("show %s:%s: %w", ref, path, err)
+	}
+	contents, err := f.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(contents), nil
+}
+
+func (b *goGitBackend) blameLines(ctx context.Context, ref, path string, lines []int) (map[int]string, error) {
+	repo, err := b.open()
+	if err != nil {
+		return nil, err
+	}
+	c, err := b.commit(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	result, err := git.Blame(c, path)
+	if err != nil {
+		return nil, fmt.Errorf("blame %s:%s: %w", ref, path, err)
+	}
+	commits := map[int]string{}
+	for _, line := range lines {
+		if line >= 1 && line <= len(result.Lines) {
+			commits[line] = result.Lines[line-1].Hash.String()
+		}
+	}
+	return commits, nil
+}
+
+// worktreeAdd writes the tree at ref into dir. go-git has no linked
+// worktrees; callers only read files from the checkout, so a plain export is
+// enough.
+func (b *goGitBackend) worktreeAdd(ctx context.Context, dir, ref string) error {
+	repo, err := b.open()
+	if err != nil {
+		return err
+	}
+	c, err := b.commit(repo, ref)
+	if err != nil {
+		return err
+	}
+	iter, err := c.Files()
+	if err != nil {
+		return err
+	}
+	return iter.ForEach(func(f *object.File) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return exportFile(dir, f)
+	})
+}
+
+func exportFile(dir string, f *object.File) error {
+	target := filepath.Join(dir, filepath.FromSlash(f.Name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	if f.Mode == filemode.Symlink {
+		linkTarget, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		return os.Symlink(linkTarget, target)
+	}
+
+	perm := os.FileMode(0o644)
+	if f.Mode == filemode.Executable {
+		perm = 0o755
+	}
+	reader, err := f.Reader()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, reader); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (b *goGitBackend) worktreeRemove(ctx context.Context, dir string) error {
+	return os.RemoveAll(dir)
+}
+
+// worktreePrune has nothing to do: go-git checkouts are plain exports.
+func (b *goGitBackend) worktreePrune(ctx context.Context) error {
+	return nil
+}
+
+// configGetAll supports the remote.<name>.fetch and remote.<name>.url keys,
+// which are the only ones this package's callers manage.
+func (b *goGitBackend) configGetAll(ctx context.Context, key string) ([]string, error) {
+	repo, err := b.open()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	remote, field, err := remoteConfigKey(cfg, key)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	switch field {
+	case "fetch":
+		for _, spec := range remote.Fetch {
+			values = append(values, spec.String())
+		}
+	case "url":
+		values = append(values, remote.URLs...)
+	}
+	return values, nil
+}
+
+func (b *goGitBackend) configAdd(ctx context.Context, key, value string) error {
+	repo, err := b.open()
+	if err != nil {
+		return err
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	remote, field, err := remoteConfigKey(cfg, key)
+	if err != nil {
+		return err
+	}
+	switch field {
+	case "fetch":
+		remote.Fetch = append(remote.Fetch, gitconfig.RefSpec(value))
+	case "url":
+		remote.URLs = append(remote.URLs, value)
+	}
+	return repo.SetConfig(cfg)
+}
+
+func remoteConfigKey(cfg *gitconfig.Config, key string) (*gitconfig.RemoteConfig, string, error) {
+	parts := strings.Split(key, ".")
+	if len(parts) != 3 || parts[0] != "remote" || (parts[2] != "fetch" && parts[2] != "url") {
+		return nil, "", fmt.Errorf("config key %s: %w", key, errUnsupported)
+	}
+	remote, ok := cfg.Remotes[parts[1]]
+	if !ok {
+		return nil, "", fmt.Errorf("remote %s not configured", parts[1])
+	}
+	return remote, parts[2], nil
+}
+
+// gc has nothing to compact: go-git stores cloned and fetched objects as
+// packfiles and never writes loose objects to these clones.
+func (b *goGitBackend) gc(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,171 @@
+package gitrepo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func commitFiles(t *testing.T, wt *git.Worktree, dir string, files map[string]string) string {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash, err := wt.Commit("change", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash.String()
+}
+
+func TestGoGitBackend_ReadsCommits(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, wt, dir, map[string]string{"frontend/main.go": "package main\n", "README.md": "v1\n"})
+	second := commitFiles(t, wt, dir, map[string]string{"backend/api.go": "package api\n", "README.md": "v2\n"})
+
+	ctx := context.Background()
+	r := New(RepoConfig{Settings: Settings{Backend: BackendGoGit}, Path: dir})
+
+	head, err := r.HeadSHA(ctx)
+	if err != nil || head != second {
+		t.Fatalf("HeadSHA = %q, %v; want %q", head, err, second)
+	}
+
+	changed, err := r.ChangedFiles(ctx, second)
+	if err != nil {
+		t.Fatalf("ChangedFiles: %v", err)
+	}
+	if len(changed) != 2 {
+		t.Fatalf("unexpected changed files %v", changed)
+	}
+
+	info, err := r.CommitInfo(ctx, second[:10])
+	if err != nil || info.SHA != second || info.Author != "test" || info.Subject != "change" {
+		t.Fatalf("CommitInfo = %+v, %v", info, err)
+	}
+
+	content, err := r.ShowFile(ctx, second, "README.md")
+	if err != nil || string(content) != "v2\n" {
+		t.Fatalf("ShowFile = %q, %v", content, err)
+	}
+
+	blame, err := r.BlameLines(ctx, second, "README.md", []int{1})
+	if err != nil || blame[1] != second {
+		t.Fatalf("BlameLines = %v, %v; want line 1 from %s", blame, err, second)
+	}
+
+	export := filepath.Join(t.TempDir(), "checkout")
+	if err := r.WorktreeAddDetach(ctx, export, second); err != nil {
+		t.Fatalf("WorktreeAddDetach: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(export, "backend", "api.go")); err != nil || string(data) != "package api\n" {
+		t.Fatalf("exported file = %q, %v", data, err)
+	}
+}
+
+func TestGoGitBackend_History(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// c1 -- c2 -- merge on main, with feature commit f1 branching off c1.
+	c1 := commitFiles(t, wt, dir, map[string]string{"README.md": "v1\n"})
+	f1 := commitFiles(t, wt, dir, map[string]string{"feature/x.go": "package feature\n"})
+	if err := wt.Reset(&git.ResetOptions{Commit: plumbing.NewHash(c1), Mode: git.HardReset}); err != nil {
+		t.Fatal(err)
+	}
+	c2 := commitFiles(t, wt, dir, map[string]string{"README.md": "v2\n"})
+	if err := os.MkdirAll(filepath.Join(dir, "feature"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "feature", "x.go"), []byte("package feature\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("feature/x.go"); err != nil {
+		t.Fatal(err)
+	}
+	mergeHash, err := wt.Commit("merge feature", &git.CommitOptions{
+		Author:  &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		Parents: []plumbing.Hash{plumbing.NewHash(c2), plumbing.NewHash(f1)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	merge := mergeHash.String()
+	for name, sha := range map[string]string{"main": merge, "old": c1} {
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewRemoteReferenceName("origin", name), plumbing.NewHash(sha))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	r := New(RepoConfig{Settings: Settings{Backend: BackendGoGit}, Path: dir})
+	shas := func(commits []CommitInfo) []string {
+		out := make([]string, len(commits))
+		for i, c := range commits {
+			out[i] = c.SHA
+		}
+		return out
+	}
+
+	if got, err := r.FirstParentCommits(ctx, c1, merge); err != nil || !slices.Equal(got, []string{merge, c2}) {
+		t.Errorf("FirstParentCommits = %v, %v; want [merge c2]", got, err)
+	}
+	all, err := r.Log(ctx, c1+".."+merge, LogOptions{})
+	if got := shas(all); err != nil || len(got) != 3 || got[0] != merge || !slices.Contains(got, f1) || !slices.Contains(got, c2) {
+		t.Errorf("Log = %v, %v; want merge, c2 and f1", got, err)
+	}
+	if got, err := r.Log(ctx, merge, LogOptions{Paths: []string{"feature"}}); err != nil || !slices.Equal(shas(got), []string{merge, f1}) {
+		t.Errorf("Log of feature/ = %v, %v; want [merge f1]", shas(got), err)
+	}
+	if got, err := r.Log(ctx, merge, LogOptions{FirstParent: true, MaxCount: 2}); err != nil || !slices.Equal(shas(got), []string{merge, c2}) {
+		t.Errorf("Log --first-parent -2 = %v, %v; want [merge c2]", shas(got), err)
+	}
+
+	for sha, want := range map[string]string{f1: merge, c2: c2, c1: c1} {
+		if got, err := r.IntroducedBy(ctx, sha, merge); err != nil || got != want {
+			t.Errorf("IntroducedBy(%s) = %q, %v; want %q", sha[:7], got, err, want)
+		}
+	}
+	if got, err := r.IntroducedBy(ctx, merge, c2); err != nil || got != "" {
+		t.Errorf("IntroducedBy of a later commit = %q, %v; want none", got, err)
+	}
+
+	if got, err := r.BranchesContaining(ctx, f1); err != nil || !slices.Equal(got, []string{"origin/main"}) {
+		t.Errorf("BranchesContaining(f1) = %v, %v", got, err)
+	}
+	if got, err := r.BranchesContaining(ctx, c1); err != nil || len(got) != 2 {
+		t.Errorf("BranchesContaining(c1) = %v, %v; want both branches", got, err)
+	}
+}
@@ -0,0 +1,23 @@
+package gitrepo
+
+import (
+	"context"
+	"time"
+)
+
+// lockPollInterval is how often a blocked lock attempt is retried while
+// waiting for the holder or for the context to be cancelled.
+const lockPollInterval = 50 * time.Millisecond
+
+// LockFile takes the same kind of lock as the clones use on path, creating
+// the file if needed, and returns the func releasing it. When ctx is already
+// done it fails at once if the lock is held, without waiting.
+func LockFile(ctx context.Context, path string, exclusive bool) (func(), error) {
+	return lockPath(ctx, path, exclusive)
+}
+
+// lockFileFor returns the lock file guarding the repository at abs. It lives
+// next to the repository so it can be taken before the clone exists.
+func lockFileFor(abs string) string {
+	return abs + ".lock"
+}
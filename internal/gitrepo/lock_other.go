@@ -0,0 +1,43 @@
+//go:build !unix
+
+package gitrepo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Without flock, fall back to an in-process lock per path. This protects
+// concurrent requests within one server but not separate processes.
+var (
+	pathLocksMu sync.Mutex
+	pathLocks   = map[string]*sync.RWMutex{}
+)
+
+func lockPath(ctx context.Context, path string, exclusive bool) (func(), error) {
+	pathLocksMu.Lock()
+	l, ok := pathLocks[path]
+	if !ok {
+		l = &sync.RWMutex{}
+		pathLocks[path] = l
+	}
+	pathLocksMu.Unlock()
+
+	if ctx.Err() != nil {
+		// Try once, as the flock implementation does.
+		if exclusive && l.TryLock() {
+			return l.Unlock, nil
+		}
+		if !exclusive && l.TryRLock() {
+			return l.RUnlock, nil
+		}
+		return nil, fmt.Errorf("lock %s: %w", path, ctx.Err())
+	}
+	if exclusive {
+		l.Lock()
+		return l.Unlock, nil
+	}
+	l.RLock()
+	return l.RUnlock, nil
+}
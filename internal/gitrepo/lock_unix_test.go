@@ -0,0 +1,37 @@
+//go:build unix
+
+package gitrepo
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockPath_ExclusiveBlocksOthers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo.lock")
+
+	unlock, err := lockPath(context.Background(), path, true)
+	if err != nil {
+		t.Fatalf("exclusive lock: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := lockPath(ctx, path, false); err == nil {
+		t.Fatalf("shared lock acquired while exclusive lock held")
+	}
+
+	unlock()
+	first, err := lockPath(context.Background(), path, false)
+	if err != nil {
+		t.Fatalf("first shared lock: %v", err)
+	}
+	defer first()
+	second, err := lockPath(context.Background(), path, false)
+	if err != nil {
+		t.Fatalf("second shared lock: %v", err)
+	}
+	second()
+}
@@ -0,0 +1,267 @@
+package gitrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+var commitSHARE = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// IsCommitSHA reports whether s is a full or abbreviated lowercase commit
+// SHA, so that it can be passed to git as a revision and not an option.
+func IsCommitSHA(s string) bool {
+	return commitSHARE.MatchString(s)
+}
+
+// CommitInfo is a commit's metadata.
+type CommitInfo struct {
+	SHA         string
+	Author      string
+	AuthorEmail string
+	Date        time.Time // author date
+	Subject     string
+}
+
+// LogOptions restricts Log.
+type LogOptions struct {
+	FirstParent bool     // follow only the first parent of merges
+	MaxCount    int      // 0 = no limit
+	Paths       []string // only commits touching these paths
+}
+
+// ResolveRef resolves a branch or tag name, or any revision, to a commit SHA.
+// Branch names are looked up on the remote first, since local branches of
+// the clone are never updated, so "main" resolves to origin/main.
+func (r *Repo) ResolveRef(ctx context.Context, ref string) (string, error) {
+	unlock, err := r.lock(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	if sha, err := r.backend.resolve(ctx, r.cfg.Remote+"/"+ref); err == nil {
+		return sha, nil
+	}
+	sha, err := r.backend.resolve(ctx, ref)
+	if err == nil {
+		return sha, nil
+	}
+	if tagSHA, tagErr := r.backend.resolve(ctx, "refs/tags/"+ref); tagErr == nil {
+		return tagSHA, nil
+	}
+	return "", err
+}
+
+// CommitInfo returns the author, date and subject of rev.
+func (r *Repo) CommitInfo(ctx context.Context, rev string) (CommitInfo, error) {
+	unlock, err := r.lock(ctx, false)
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	defer unlock()
+	return r.backend.commitInfo(ctx, rev)
+}
+
+// Log lists the commits of revRange ("a..b", or a single revision for its
+// whole history), newest first.
+func (r *Repo) Log(ctx context.Context, revRange string, opts LogOptions) ([]CommitInfo, error) {
+	unlock, err := r.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.backend.log(ctx, revRange, opts)
+}
+
+// logFormat separates the CommitInfo fields with NUL; records end with
+// newlines, which subjects cannot contain.
+const logFormat = "--format=%H%x00%an%x00%ae%x00%aI%x00%s"
+
+func parseLog(out string) ([]CommitInfo, error) {
+	var commits []CommitInfo
+	for _, line := range splitLines(out) {
+		fields := strings.SplitN(line, "\x00", 5)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected git log line %q", line)
+		}
+		date, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("parse commit date %q: %w", fields[3], err)
+		}
+		commits = append(commits, CommitInfo{SHA: fields[0], Author: fields[1], AuthorEmail: fields[2], Date: date, Subject: fields[4]})
+	}
+	return commits, nil
+}
+
+func (b *execBackend) commitInfo(ctx context.Context, rev string) (CommitInfo, error) {
+	out, err := b.git(ctx, "show", "--no-patch", logFormat, "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	commits, err := parseLog(out)
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	if len(commits) != 1 {
+		return CommitInfo{}, fmt.Errorf("no commit %s", rev)
+	}
+	return commits[0], nil
+}
+
+func (b *execBackend) log(ctx context.Context, revRange string, opts LogOptions) ([]CommitInfo, error) {
+	args := []string{"log", logFormat}
+	if opts.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	if opts.MaxCount > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(opts.MaxCount))
+	}
+	args = append(args, "--end-of-options", revRange, "--")
+	out, err := b.git(ctx, append(args, opts.Paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseLog(out)
+}
+
+func (b *goGitBackend) commitInfo(ctx context.Context, rev string) (CommitInfo, error) {
+	repo, err := b.open()
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	c, err := b.commit(repo, rev)
+	if err != nil {
+		return CommitInfo{}, err
+	}
+	return goGitCommitInfo(c), nil
+}
+
+// log walks back from the end of revRange, newest commit first, skipping
+// the commits reachable from its start.
+func (b *goGitBackend) log(ctx context.Context, revRange string, opts LogOptions) ([]CommitInfo, error) {
+	repo, err := b.open()
+	if err != nil {
+		return nil, err
+	}
+	from, to, isRange := strings.Cut(revRange, "..")
+	if !isRange {
+		from, to = "", revRange
+	}
+	if strings.HasPrefix(to, ".") {
+		return nil, fmt.Errorf("log %s: symmetric ranges: %w", revRange, errUnsupported)
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+	tip, err := b.commit(repo, to)
+	if err != nil {
+		return nil, err
+	}
+	excluded := map[plumbing.Hash]bool{}
+	if from != "" {
+		start, err := b.commit(repo, from)
+		if err != nil {
+			return nil, err
+		}
+		err = object.NewCommitPreorderIter(start, nil, nil).ForEach(func(c *object.Commit) error {
+			excluded[c.Hash] = true
+			return ctx.Err()
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var commits []CommitInfo
+	add := func(c *object.Commit) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(opts.Paths) > 0 {
+			touched, err := touchesPaths(ctx, c, opts.Paths)
+			if err != nil || !touched {
+				return err
+			}
+		}
+		commits = append(commits, goGitCommitInfo(c))
+		if opts.MaxCount > 0 && len(commits) == opts.MaxCount {
+			return storer.ErrStop
+		}
+		return nil
+	}
+	if opts.FirstParent {
+		for c := tip; !excluded[c.Hash]; {
+			if err := add(c); err != nil {
+				if errors.Is(err, storer.ErrStop) {
+					break
+				}
+				return nil, err
+			}
+			if c.NumParents() == 0 {
+				break
+			}
+			if c, err = c.Parent(0); err != nil {
+				return nil, err
+			}
+		}
+		return commits, nil
+	}
+	err = object.NewCommitIterCTime(tip, excluded, nil).ForEach(add)
+	if err != nil && !errors.Is(err, storer.ErrStop) {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// touchesPaths reports whether c changed a file at or under one of paths
+// relative to its first parent.
+func touchesPaths(ctx context.Context, c *object.Commit, paths []string) (bool, error) {
+	to, err := c.Tree()
+	if err != nil {
+		return false, err
+	}
+	from := &object.Tree{}
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return false, err
+		}
+		if from, err = parent.Tree(); err != nil {
+			return false, err
+		}
+	}
+	changes, err := object.DiffTreeWithOptions(ctx, from, to, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return false, err
+	}
+	for _, ch := range changes {
+		for _, name := range []string{ch.From.Name, ch.To.Name} {
+			for _, p := range paths {
+				p = strings.TrimSuffix(p, "/")
+				if name != "" && (name == p || strings.HasPrefix(name, p+"/")) {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+func goGitCommitInfo(c *object.Commit) CommitInfo {
+	subject, _, _ := strings.Cut(c.Message, "\n")
+	return CommitInfo{
+		SHA:         c.Hash.String(),
+		Author:      c.Author.Name,
+		AuthorEmail: c.Author.Email,
+		Date:        c.Author.When,
+		Subject:     strings.TrimSpace(subject),
+	}
+}
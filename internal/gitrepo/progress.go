@@ -0,0 +1,44 @@
+package gitrepo
+
+import (
+	"strings"
+	"time"
+)
+
+const progressInterval = 10 * time.Second
+
+// progressWriter turns git's carriage-return progress output into discrete
+// lines. Percent updates within a phase are throttled; phase changes and
+// completed phases are always reported.
+type progressWriter struct {
+	report   func(line string)
+	buf      []byte
+	phase    string
+	lastEmit time.Time
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b != '\r' && b != '\n' {
+			w.buf = append(w.buf, b)
+			continue
+		}
+		w.flush()
+	}
+	return len(p), nil
+}
+
+func (w *progressWriter) flush() {
+	line := strings.TrimSpace(string(w.buf))
+	w.buf = w.buf[:0]
+	if line == "" {
+		return
+	}
+	phase, _, _ := strings.Cut(line, ":")
+	now := time.Now()
+	if phase != w.phase || strings.HasSuffix(line, "done.") || now.Sub(w.lastEmit) >= progressInterval {
+		w.phase = phase
+		w.lastEmit = now
+		w.report(line)
+	}
+}
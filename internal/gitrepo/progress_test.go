@@ -0,0 +1,26 @@
+package gitrepo
+
+import "testing"
+
+func TestProgressWriter_ThrottlesWithinPhase(t *testing.T) {
+	var lines []string
+	w := &progressWriter{report: func(line string) { lines = append(lines, line) }}
+
+	_, _ = w.Write([]byte("Cloning into 'repo'...\nReceiving objects:  10% (1/10)\rReceiving objects:  50% (5/10)\r"))
+	_, _ = w.Write([]byte("Receiving objects: 100% (10/10), done.\nResolving deltas: 100% (3/3), done.\n"))
+
+	want := []string{
+		"Cloning into 'repo'...",
+		"Receiving objects:  10% (1/10)",
+		"Receiving objects: 100% (10/10), done.",
+		"Resolving deltas: 100% (3/3), done.",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %q, want %q", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("line %d: got %q, want %q", i, lines[i], want[i])
+		}
+	}
+}
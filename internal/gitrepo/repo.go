@@ -1,90 +1,108 @@
 package gitrepo
 
 import (
-	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 )
 
+const (
+	BackendExec  = "exec"   // shell out to the system git binary
+	BackendGoGit = "go-git" // pure Go implementation, no git binary required
+)
+
+// Settings configure every repository a process opens, e.g. from GIT_BACKEND,
+// GIT_MIRROR_URL, GIT_TOKENS and GIT_SSH_KEY.
+type Settings struct {
+	// Backend selects the git implementation; default: exec.
+	Backend string
+	// MirrorURL, when set, is cloned from instead of an http(s) URL; see
+	// mirrorURL.
+	MirrorURL string
+	// HostTokens are credentials by lower-case host, "token" or
+	// "user:token", used for HTTPS remotes on that host.
+	HostTokens map[string]string
+	// SSHKeyPath is the private key used for SSH remotes.
+	SSHKeyPath string
+}
+
 type RepoConfig struct {
+	Settings
+
+	// URL is cloned through MirrorURL when set; existing clones keep
+	// fetching from the remote they were cloned from.
 	URL    string
 	Path   string
 	Remote string // default: origin
+
+	// Clone tuning, only used by Ensure when the repo is missing.
+	Depth        int           // shallow clone depth; 0 = full history
+	SingleBranch bool          // clone only the remote's default branch
+	SparsePaths  []string      // cone-mode sparse checkout paths; empty = full checkout (exec only)
+	CloneTimeout time.Duration // default: 30m
+	// Progress receives clone progress lines (e.g. "Receiving objects: 40%").
+	Progress func(line string)
+
+	// Credentials for private HTTPS remotes; default: the HostTokens entry
+	// for the host of URL.
+	Token     string
+	TokenUser string // default: x-access-token
+}
+
+// backend implements the git operations Repo needs. Implementations assume
+// the caller holds the appropriate repo lock.
+type backend interface {
+	clone(ctx context.Context, abs string) error
+	fetch(ctx context.Context, extraArgs ...string) error
+	resolve(ctx context.Context, rev string) (string, error)
+	checkoutDetach(ctx context.Context, ref string) error
+	mergeDiff(ctx context.Context, mergeSHA string) (string, error)
+	changedFiles(ctx context.Context, mergeSHA string) ([]string, error)
+	isAncestor(ctx context.Context, ancestor, descendant string) (bool, error)
+	introducedBy(ctx context.Context, sha, ref string) (string, error)
+	branchesContaining(ctx context.Context, sha string) ([]string, error)
+	firstParentCommits(ctx context.Context, from, to string) ([]string, error)
+	commitInfo(ctx context.Context, rev string) (CommitInfo, error)
+	log(ctx context.Context, revRange string, opts LogOptions) ([]CommitInfo, error)
+	listFiles(ctx context.Context, ref string) ([]string, error)
+	showFile(ctx context.Context, ref, path string) ([]byte, error)
+	blameLines(ctx context.Context, ref, path string, lines []int) (map[int]string, error)
+	worktreeAdd(ctx context.Context, dir, ref string) error
+	worktreeRemove(ctx context.Context, dir string) error
+	worktreePrune(ctx context.Context) error
+	configGetAll(ctx context.Context, key string) ([]string, error)
+	configAdd(ctx context.Context, key, value string) error
+	gc(ctx context.Context) error
 }
 
 type Repo struct {
-	cfg    RepoConfig
-	runner Runner
+	cfg     RepoConfig
+	backend backend
 }
 
 func New(cfg RepoConfig) *Repo {
 	if cfg.Remote == "" {
 		cfg.Remote = "origin"
 	}
-	return &Repo{cfg: cfg, runner: Runner{Timeout: 2 * time.Minute}}
-}
-
-type Runner struct {
-	Timeout time.Duration
-}
-
-func (r Runner) Git(ctx context.Context, dir string, args ...string) (string, error) {
-	c := exec.CommandContext(ctx, "git", args...)
-	c.Dir = dir
-	var stdout, stderr bytes.Buffer
-	c.Stdout = &stdout
-	c.Stderr = &stderr
-	if err := c.Start(); err != nil {
-		return "", formatGitError(args, err, stderr.String())
+	if cfg.CloneTimeout <= 0 {
+		cfg.CloneTimeout = 30 * time.Minute
 	}
-	done := make(chan error, 1)
-	go func() { done <- c.Wait() }()
-	select {
-	case err := <-done:
-		if err != nil {
-			return "", formatGitError(args, err, stderr.String())
-		}
-		return stdout.String(), nil
-	case <-time.After(r.Timeout):
-		_ = c.Process.Kill()
-		<-done
-		return "", formatGitTimeoutError(args, r.Timeout, stderr.String())
-	case <-ctx.Done():
-		_ = c.Process.Kill()
-		<-done
-		return "", formatGitContextError(args, ctx.Err(), stderr.String())
-	}
-}
-
-func formatGitError(args []string, cause error, stderr string) error {
-	cmd := strings.Join(args, " ")
-	stderr = strings.TrimSpace(stderr)
-	if stderr != "" {
-		return fmt.Errorf("git %s: %w: %s", cmd, cause, stderr)
+	if cfg.Backend == "" {
+		cfg.Backend = BackendExec
 	}
-	return fmt.Errorf("git %s: %w", cmd, cause)
-}
-
-func formatGitTimeoutError(args []string, timeout time.Duration, stderr string) error {
-	return formatGitError(args, fmt.Errorf("command timed out after %s", timeout), stderr)
-}
-
-func formatGitContextError(args []string, cause error, stderr string) error {
-	if cause == nil {
-		cause = errors.New("context canceled")
+	cfg.URL = mirrorURL(cfg.MirrorURL, cfg.URL)
+	cfg = withCredentials(cfg)
+	var b backend
+	switch cfg.Backend {
+	case BackendGoGit:
+		b = &goGitBackend{cfg: cfg}
+	default:
+		b = &execBackend{cfg: cfg, runner: Runner{Timeout: 2 * time.Minute}}
 	}
-	return formatGitError(args, cause, stderr)
-}
-
-// Run is a helper to execute arbitrary git subcommands in the repo path.
-func (r *Repo) Run(ctx context.Context, args ...string) (string, error) {
-	return r.runner.Git(ctx, r.cfg.Path, args...)
+	return &Repo{cfg: cfg, backend: b}
 }
 
 // Ensure clones the repo if missing; otherwise fetches.
@@ -93,22 +111,47 @@ func (r *Repo) Ensure(ctx context.Context) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	unlock, err := lockPath(ctx, lockFileFor(abs), true)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	markUsed(lockFileFor(abs))
+
 	if _, err := os.Stat(abs); os.IsNotExist(err) {
-		if _, err := r.runner.Git(ctx, "", "clone", "--filter=blob:none", "--no-tags", r.cfg.URL, abs); err != nil {
+		if err := r.backend.clone(ctx, abs); err != nil {
 			return "", err
 		}
 		return abs, nil
 	}
-	if err := r.Fetch(ctx); err != nil {
+	if err := r.backend.fetch(ctx); err != nil {
 		return "", err
 	}
 	return abs, nil
 }
 
 func (r *Repo) Fetch(ctx context.Context, extraArgs ...string) error {
-	args := append([]string{"fetch", "--prune", r.cfg.Remote}, extraArgs...)
-	_, err := r.runner.Git(ctx, r.cfg.Path, args...)
-	return err
+	unlock, err := r.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return r.backend.fetch(ctx, extraArgs...)
+}
+
+// lock takes the repo's file lock: exclusive for operations that mutate the
+// repository, shared for read-only ones.
+func (r *Repo) lock(ctx context.Context, exclusive bool) (func(), error) {
+	abs, err := filepath.Abs(r.cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockPath(ctx, lockFileFor(abs), exclusive)
+	if err != nil {
+		return nil, err
+	}
+	markUsed(lockFileFor(abs))
+	return unlock, nil
 }
 
 func (r *Repo) CheckoutDetach(ctx context.Context, ref string) error {
@@ -116,52 +159,125 @@ func (r *Repo) CheckoutDetach(ctx context.Context, ref string) error {
 	if head, _ := r.HeadSHA(ctx); head == ref {
 		return nil
 	}
-	_, err := r.runner.Git(ctx, r.cfg.Path, "checkout", "--detach", ref)
-	return err
+	unlock, err := r.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return r.backend.checkoutDetach(ctx, ref)
 }
 
 func (r *Repo) HeadSHA(ctx context.Context) (string, error) {
-	out, err := r.runner.Git(ctx, r.cfg.Path, "rev-parse", "HEAD")
+	return r.ResolveRevision(ctx, "HEAD")
+}
+
+// ResolveRevision resolves a revision (SHA, branch, HEAD, ...) to a commit SHA.
+func (r *Repo) ResolveRevision(ctx context.Context, rev string) (string, error) {
+	unlock, err := r.lock(ctx, false)
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(out), nil
+	defer unlock()
+	return r.backend.resolve(ctx, rev)
 }
 
 // MergeDiff returns a unified diff for merge^1..merge range.
 func (r *Repo) MergeDiff(ctx context.Context, mergeSHA string) (string, error) {
-	rangeSpec := fmt.Sprintf("%s^1..%s", mergeSHA, mergeSHA)
-	out, err := r.runner.Git(ctx, r.cfg.Path, "show", "--no-color", "--no-ext-diff", "--format=", "--find-renames", rangeSpec)
+	unlock, err := r.lock(ctx, false)
 	if err != nil {
 		return "", err
 	}
-	return out, nil
+	defer unlock()
+	return r.backend.mergeDiff(ctx, mergeSHA)
+}
+
+// ChangedFiles returns the paths touched by merge^1..merge.
+func (r *Repo) ChangedFiles(ctx context.Context, mergeSHA string) ([]string, error) {
+	unlock, err := r.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.backend.changedFiles(ctx, mergeSHA)
+}
+
+// IsAncestor reports whether ancestor is reachable from descendant.
+func (r *Repo) IsAncestor(ctx context.Context, ancestor, descendant string) (bool, error) {
+	unlock, err := r.lock(ctx, false)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	return r.backend.isAncestor(ctx, ancestor, descendant)
+}
+
+// IntroducedBy returns the commit on ref's first-parent history that brought
+// sha into ref: the merge or squash commit of the PR containing it, or sha
+// itself when it was committed directly. It returns "" when sha is not
+// reachable from ref.
+func (r *Repo) IntroducedBy(ctx context.Context, sha, ref string) (string, error) {
+	unlock, err := r.lock(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	return r.backend.introducedBy(ctx, sha, ref)
+}
+
+// BranchesContaining lists the remote-tracking branches that contain sha.
+func (r *Repo) BranchesContaining(ctx context.Context, sha string) ([]string, error) {
+	unlock, err := r.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.backend.branchesContaining(ctx, sha)
+}
+
+// FirstParentCommits lists the commits on to's first-parent history that are
+// not reachable from from, newest first. On main these are the merge (or
+// squash) commits of the PRs landed between the two revisions.
+func (r *Repo) FirstParentCommits(ctx context.Context, from, to string) ([]string, error) {
+	unlock, err := r.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.backend.firstParentCommits(ctx, from, to)
 }
 
 // ListFiles returns repo-relative paths at the given ref.
 func (r *Repo) ListFiles(ctx context.Context, ref string) ([]string, error) {
-	out, err := r.runner.Git(ctx, r.cfg.Path, "ls-tree", "-r", "--name-only", ref)
+	unlock, err := r.lock(ctx, false)
 	if err != nil {
 		return nil, err
 	}
-	lines := strings.Split(strings.TrimSpace(out), "\n")
-	var files []string
-	for _, l := range lines {
-		if l != "" {
-			files = append(files, l)
-		}
-	}
-	return files, nil
+	defer unlock()
+	return r.backend.listFiles(ctx, ref)
 }
 
 // ShowFile reads a file blob at ref:path.
 func (r *Repo) ShowFile(ctx context.Context, ref, path string) ([]byte, error) {
-	spec := fmt.Sprintf("%s:%s", ref, path)
-	out, err := r.runner.Git(ctx, r.cfg.Path, "show", spec)
+	unlock, err := r.lock(ctx, false)
 	if err != nil {
 		return nil, err
 	}
-	return []byte(out), nil
+	defer unlock()
+	return r.backend.showFile(ctx, ref, path)
+}
+
+// BlameLines returns the commit that last changed each of the given 1-based
+// lines of path as of ref.
+func (r *Repo) BlameLines(ctx context.Context, ref, path string, lines []int) (map[int]string, error) {
+	if len(lines) == 0 {
+		return map[int]string{}, nil
+	}
+	unlock, err := r.lock(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return r.backend.blameLines(ctx, ref, path, lines)
 }
 
 // WorktreeAddDetach creates a detached worktree at dir for the given ref.
@@ -169,27 +285,67 @@ func (r *Repo) WorktreeAddDetach(ctx context.Context, dir, ref string) error {
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return err
 	}
-	_, err := r.runner.Git(ctx, r.cfg.Path, "worktree", "add", "--detach", dir, ref)
-	return err
+	unlock, err := r.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return r.backend.worktreeAdd(ctx, dir, ref)
 }
 
 // WorktreeRemove removes the worktree at dir.
 func (r *Repo) WorktreeRemove(ctx context.Context, dir string) error {
-	_, err := r.runner.Git(ctx, r.cfg.Path, "worktree", "remove", dir, "--force")
-	return err
+	unlock, err := r.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return r.backend.worktreeRemove(ctx, dir)
+}
+
+// WorktreePrune drops the records of worktrees whose directories were
+// deleted without WorktreeRemove.
+func (r *Repo) WorktreePrune(ctx context.Context) error {
+	unlock, err := r.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return r.backend.worktreePrune(ctx)
 }
 
 // ConfigHasLocal checks if `git config --local --get-all <key>` contains value.
 func (r *Repo) ConfigHasLocal(ctx context.Context, key, value string) (bool, error) {
-	out, err := r.runner.Git(ctx, r.cfg.Path, "config", "--local", "--get-all", key)
+	unlock, err := r.lock(ctx, false)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	values, err := r.backend.configGetAll(ctx, key)
 	if err != nil {
 		return false, nil
 	}
-	return strings.Contains(out, value), nil
+	return strings.Contains(strings.Join(values, "\n"), value), nil
 }
 
 // ConfigAddLocal appends a value to a multivalue local config key.
 func (r *Repo) ConfigAddLocal(ctx context.Context, key, value string) error {
-	_, err := r.runner.Git(ctx, r.cfg.Path, "config", "--local", "--add", key, value)
-	return err
+	unlock, err := r.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return r.backend.configAdd(ctx, key, value)
 }
+
+func splitLines(out string) []string {
+	var lines []string
+	for _, l := range strings.Split(strings.TrimSpace(out), "\n") {
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+var errUnsupported = fmt.Errorf("not supported by the %s backend", BackendGoGit)
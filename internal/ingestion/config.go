@@ -2,6 +2,7 @@ package ingestion
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -9,44 +10,89 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/roivaz/aro-hcp-intelhub/internal/config"
 	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion/diff"
+	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion/embeddings"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
 )
 
 type Config struct {
-	PostgresURL     string
-	OllamaURL       string
-	EmbeddingModel  string
-	GitHubFetchMax  int    // Maximum PRs to fetch from GitHub per run
-	ExecutionMode   string // FULL, CACHE, or PROCESS
-	MaxProcessBatch int    // Maximum PRs to process from DB per run
-	DiffAnalyzer    diff.Config
-	RepositoryURL   string
-	LocalRepoPath   string
-	GitHubToken     string
-	AutoMigrate     bool
-	LLMCallTimeout  time.Duration
-	RetryFailed     bool // Retry diff analysis on previously failed PRs
+	PostgresURL      string
+	OllamaURL        string
+	EmbeddingModel   string
+	EmbeddingDim     int
+	Quantization     string // vector index quantization: none, halfvec or bit
+	RerankCandidates int
+	DistanceMetric   string        // cosine, inner_product or l2
+	Normalize        bool          // Scale embeddings to unit length before storing or searching
+	OllamaAutoPull   bool          // Pull missing models during the startup preflight
+	FakeEmbeddings   bool          // Embed with embeddings.Fake instead of Ollama
+	GitHubFetchMax   int           // Maximum PRs to fetch from GitHub per run
+	ExecutionMode    string        // FULL, CACHE, or PROCESS
+	IngestionMode    string        // UPDATED or INCREMENTAL: how CACHE finds new PRs
+	Lookback         time.Duration // how far back INCREMENTAL starts on an empty database; 0 = all history
+	MaxProcessBatch  int           // Maximum PRs to process from DB per run
+	MaxAttempts      int           // Failed attempts before a PR is dead-lettered
+	DiffAnalyzer     diff.Config
+	GitHubOwner      string // Owner and name of the GitHub repository ingested
+	GitHubRepo       string
+	RepositoryURL    string // Clone URL of that repository
+	LocalRepoPath    string
+	GitHubToken      string
+	FixtureDir       string // Record or replay GitHub responses here when set
+	FixtureMode      string // FixtureRecord or FixtureReplay
+	AutoMigrate      bool
+	LLMCallTimeout   time.Duration
+	RetryFailed      bool     // Retry diff analysis on previously failed PRs
+	TicketProjects   []string // Jira projects whose tickets are recorded in pr_references
+
+	// Queue consumer settings used by WORKER mode
+	WorkerID                string
+	WorkerBatchSize         int
+	WorkerPollInterval      time.Duration
+	WorkerVisibilityTimeout time.Duration
+	WorkerCacheInterval     time.Duration // CACHE run by the elected leader; 0 disables
 }
 
 func LoadConfig() (Config, error) {
 	cfg := Config{
-		PostgresURL:     config.PostgresURL(),
-		OllamaURL:       config.OllamaURL(),
-		EmbeddingModel:  config.EmbeddingModel(),
-		GitHubFetchMax:  config.GitHubFetchMax(),
-		ExecutionMode:   strings.ToUpper(config.ExecutionMode()),
-		MaxProcessBatch: config.MaxProcessBatch(),
+		PostgresURL:      config.PostgresURL(),
+		OllamaURL:        config.OllamaURL(),
+		EmbeddingModel:   config.EmbeddingModel(),
+		EmbeddingDim:     config.EmbeddingDimension(),
+		Quantization:     config.EmbeddingQuantization(),
+		RerankCandidates: config.EmbeddingRerankCandidates(),
+		DistanceMetric:   config.EmbeddingDistance(),
+		Normalize:        config.EmbeddingNormalize(),
+		OllamaAutoPull:   config.OllamaAutoPull(),
+		FakeEmbeddings:   config.FakeEmbeddings(),
+		GitHubFetchMax:   config.GitHubFetchMax(),
+		ExecutionMode:    strings.ToUpper(config.ExecutionMode()),
+		IngestionMode:    strings.ToUpper(config.IngestionMode()),
+		MaxProcessBatch:  config.MaxProcessBatch(),
+		MaxAttempts:      config.MaxProcessingAttempts(),
 		DiffAnalyzer: diff.Config{
 			Enabled:          config.DiffAnalysisEnabled(),
 			ModelName:        config.DiffAnalysisModel(),
 			OllamaURL:        config.DiffAnalysisOllamaURL(),
+			RepoURL:          config.RepoURL(),
 			RepoPath:         filepath.Join(config.CacheDir(), "aro-hcp-repo"),
+			Git:              settings.Git(),
 			MaxContextTokens: config.DiffAnalysisContextTokens(),
+			MaxDiffTokens:    config.DiffAnalysisMaxDiffTokens(),
+			AutoPull:         config.OllamaAutoPull(),
 			Logger:           logr.Logger{},
 		},
-		RepositoryURL: "https://github.com/Azure/ARO-HCP",
-		LocalRepoPath: filepath.Join(config.CacheDir(), "aro-hcp-repo"),
-		GitHubToken:   "",
-		AutoMigrate:   config.AutoMigrate(),
+		GitHubOwner:    config.GitHubOwner(),
+		GitHubRepo:     config.GitHubRepo(),
+		RepositoryURL:  config.RepoURL(),
+		LocalRepoPath:  filepath.Join(config.CacheDir(), "aro-hcp-repo"),
+		GitHubToken:    "",
+		FixtureDir:     config.IngestFixtureDir(),
+		FixtureMode:    config.IngestFixtureMode(),
+		AutoMigrate:    config.AutoMigrate(),
+		TicketProjects: strings.Split(config.TicketProjects(), ","),
+
+		WorkerID:        config.WorkerID(),
+		WorkerBatchSize: config.WorkerBatchSize(),
 	}
 
 	timeout, err := parseDuration(config.LLMCallTimeout(), 2*time.Minute)
@@ -56,9 +102,64 @@ func LoadConfig() (Config, error) {
 	cfg.LLMCallTimeout = timeout
 	cfg.DiffAnalyzer.CallTimeout = timeout
 
+	poll, err := parseDuration(config.WorkerPollInterval(), 30*time.Second)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid worker_poll_interval: %w", err)
+	}
+	cfg.WorkerPollInterval = poll
+
+	visibility, err := parseDuration(config.WorkerVisibilityTimeout(), 30*time.Minute)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid worker_visibility_timeout: %w", err)
+	}
+	cfg.WorkerVisibilityTimeout = visibility
+
+	cacheInterval, err := parseDuration(config.WorkerCacheInterval(), 0)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid worker_cache_interval: %w", err)
+	}
+	cfg.WorkerCacheInterval = cacheInterval
+
+	lookback, err := parseDuration(config.IngestionLookback(), 0)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid ingestion_lookback: %w", err)
+	}
+	cfg.Lookback = lookback
+
+	if cfg.FakeEmbeddings {
+		cfg.EmbeddingModel = embeddings.FakeModelName(cfg.EmbeddingDim)
+	}
+
+	if cfg.WorkerID == "" {
+		host, _ := os.Hostname()
+		cfg.WorkerID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+
 	return cfg, nil
 }
 
+// NewEmbedder returns the embedder cfg selects: a fake one in FakeEmbeddings
+// mode, otherwise an Ollama client built with opts. Its vectors are scaled to
+// unit length when cfg.Normalize is set.
+func NewEmbedder(cfg Config, opts ...func(*embeddings.Client)) (Embedder, error) {
+	var (
+		embedder Embedder
+		err      error
+	)
+	if cfg.FakeEmbeddings {
+		embedder, err = embeddings.NewFake(cfg.EmbeddingDim)
+	} else {
+		embedder, err = embeddings.NewClient(cfg.OllamaURL, cfg.EmbeddingModel, cfg.LLMCallTimeout, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Normalize {
+		embedder = embeddings.Normalized{Embedder: embedder}
+	}
+	return embedder, nil
+}
+
 func parseDuration(value string, fallback time.Duration) (time.Duration, error) {
 	trimmed := strings.TrimSpace(value)
 	if trimmed == "" {
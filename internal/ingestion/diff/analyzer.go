@@ -7,8 +7,13 @@ import (
 	"strings"
 
 	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
+	ollamaapi "github.com/roivaz/aro-hcp-intelhub/internal/ollama"
 )
 
+// maxStoredChunks bounds the diff chunks kept per PR for search_pr_diffs.
+// Files are ranked first, so a large PR keeps its most relevant changes.
+const maxStoredChunks = 100
+
 type Analyzer struct {
 	cfg       Config
 	log       logging.Logger
@@ -26,8 +31,12 @@ func NewAnalyzer(cfg Config) (*Analyzer, error) {
 		return nil, err
 	}
 
-	// Run health check to ensure model is available before processing batch
+	// Verify the model exists (pulling it if allowed), then that it loads,
+	// before processing the batch.
 	if cfg.Enabled {
+		if err := ollamaapi.EnsureModel(context.Background(), cfg.OllamaURL, cfg.ModelName, cfg.AutoPull); err != nil {
+			return nil, err
+		}
 		if err := client.HealthCheck(context.Background()); err != nil {
 			return nil, fmt.Errorf("LLM model '%s' health check failed: %w\nSuggestions:\n  1. Restart Ollama server\n  2. Run: ollama pull %s\n  3. Check available GPU memory", cfg.ModelName, err, cfg.ModelName)
 		}
@@ -47,7 +56,7 @@ func (a *Analyzer) Analyze(ctx context.Context, meta PRMetadata) (Analysis, erro
 		return Analysis{AnalysisSuccessful: false, FailureReason: "diff analyzer disabled", FailureCategory: "disabled"}, nil
 	}
 
-	diffText, err := fetchConsolidatedDiff(ctx, meta, a.cfg.RepoPath, a.log)
+	diffText, err := fetchConsolidatedDiff(ctx, meta, a.cfg.Git, a.cfg.RepoURL, a.cfg.RepoPath, a.log)
 	if err != nil {
 		a.log.Error(err, "fetch diff failed", "pr", meta.Number)
 		return Analysis{AnalysisSuccessful: false, FailureReason: err.Error()}, nil
@@ -57,12 +66,14 @@ func (a *Analyzer) Analyze(ctx context.Context, meta PRMetadata) (Analysis, erro
 	if len(fileChunks) == 0 {
 		return Analysis{AnalysisSuccessful: false, FailureReason: "no diff content"}, nil
 	}
+	diffStats := computeDiffStats(fileChunks)
 
 	included, skipped := filterGeneratedFiles(fileChunks, a.patterns)
 	if len(included) == 0 {
-		return Analysis{AnalysisSuccessful: false, FailureReason: "all files filtered as generated"}, nil
+		return Analysis{AnalysisSuccessful: false, FailureReason: "all files filtered as generated", Stats: &diffStats}, nil
 	}
 
+	included = rankFiles(included)
 	docs, stats := buildDocuments(included, a.log, a.cfg)
 	stats.FilesFiltered = len(skipped)
 	stats.FilesTotal = len(fileChunks)
@@ -76,16 +87,39 @@ func (a *Analyzer) Analyze(ctx context.Context, meta PRMetadata) (Analysis, erro
 		"median_tokens", stats.MedianTokens,
 	)
 
-	if len(docs) > 100 {
-		a.log.Error(fmt.Errorf("large diff detected: %d chunks", len(docs)), "large diff", "pr", meta.Number, "files", len(docs))
-		return Analysis{AnalysisSuccessful: false, FailureReason: "large diff detected",
-			FailureCategory: FailureCategoryLargeDiff}, nil
+	analysis, err := a.analyzeFiles(ctx, meta, included, docs)
+	analysis.Stats = &diffStats
+	analysis.Chunks = docs[:min(len(docs), maxStoredChunks)]
+	return analysis, err
+}
+
+func (a *Analyzer) analyzeFiles(ctx context.Context, meta PRMetadata, included [][2]string, docs []Document) (Analysis, error) {
+	mapFn := a.llmClient.mapChunk
+	directoryLevel := false
+	if len(docs) > maxMapDocuments {
+		a.log.Info("large diff detected; falling back to directory-level summaries", "pr", meta.Number, "chunks", len(docs))
+		docs = buildDirectoryDocuments(included, a.log, a.cfg)
+		if len(docs) == 0 {
+			a.log.Error(fmt.Errorf("large diff detected: %d files", len(included)), "large diff", "pr", meta.Number, "files", len(included))
+			return Analysis{AnalysisSuccessful: false, FailureReason: "large diff detected",
+				FailureCategory: FailureCategoryLargeDiff}, nil
+		}
+		mapFn = a.llmClient.mapDirectory
+		directoryLevel = true
+	}
+
+	var omitted []string
+	if !directoryLevel {
+		docs, omitted = applyTokenBudget(docs, a.cfg.MaxDiffTokens)
+		if len(omitted) > 0 {
+			a.log.Info("token budget exceeded; mapping highest ranked files only", "pr", meta.Number, "budget", a.cfg.MaxDiffTokens, "files_omitted", len(omitted))
+		}
 	}
 
 	mapSummaries := make([]string, 0, len(docs))
 	for idx, doc := range docs {
 		a.log.Debug(fmt.Sprintf("mapping chunk %d/%d", idx+1, len(docs)), "file", doc.FilePath)
-		result, err := a.llmClient.mapChunk(ctx, doc, meta)
+		result, err := mapFn(ctx, doc, meta)
 		if err != nil {
 			a.log.Error(err, "map stage failed", "file", doc.FilePath)
 			reason, category := GetFailureDetails(err)
@@ -103,6 +137,12 @@ func (a *Analyzer) Analyze(ctx context.Context, meta PRMetadata) (Analysis, erro
 	a.log.Debug("Reduce stage completed", "summary", reduceResult)
 
 	richDescription := fmt.Sprintf("## Pull Request Analysis: %s\n\n%s", meta.Title, strings.TrimSpace(reduceResult))
+	if directoryLevel {
+		richDescription += fmt.Sprintf("\n\n_Note: large diff (%d files) summarized at directory level._", len(included))
+	}
+	if len(omitted) > 0 {
+		richDescription += fmt.Sprintf("\n\n_Note: %d lower-priority file(s) not analyzed due to token budget: %s._", len(omitted), strings.Join(omitted, ", "))
+	}
 
 	return Analysis{
 		RichDescription:    richDescription,
@@ -78,3 +78,77 @@ func longDiff() string {
 	}
 	return "diff --git a/file.txt b/file.txt\n" + base + body
 }
+
+func TestBuildDirectoryDocuments_GroupsByDirectory(t *testing.T) {
+	chunks := [][2]string{
+		{"internal/api/v1/types.go", "diff --git a/internal/api/v1/types.go b/internal/api/v1/types.go\n+added\n-removed"},
+		{"internal/api/v1/helpers.go", "diff --git a/internal/api/v1/helpers.go b/internal/api/v1/helpers.go\n+added"},
+		{"README.md", "diff --git a/README.md b/README.md\n+doc"},
+	}
+	docs := buildDirectoryDocuments(chunks, logging.New(logr.Discard()), Config{MaxContextTokens: 4096})
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 directory documents, got %d", len(docs))
+	}
+	if docs[0].FilePath != "." || docs[1].FilePath != "internal/api/v1" {
+		t.Fatalf("unexpected directories %q, %q", docs[0].FilePath, docs[1].FilePath)
+	}
+}
+
+func TestCountChangedLines(t *testing.T) {
+	added, removed := countChangedLines("--- a/f\n+++ b/f\n@@ -1 +1 @@\n-old\n+new\n+extra")
+	if added != 2 || removed != 1 {
+		t.Fatalf("expected +2/-1, got +%d/-%d", added, removed)
+	}
+}
+
+func TestRankFiles_PrefersAPIChanges(t *testing.T) {
+	chunks := [][2]string{
+		{"docs/guide.md", "+a\n+b\n+c"},
+		{"api/v1/types.go", "+a\n+b\n+c"},
+	}
+	ranked := rankFiles(chunks)
+	if ranked[0][0] != "api/v1/types.go" {
+		t.Fatalf("expected api file ranked first, got %s", ranked[0][0])
+	}
+}
+
+func TestApplyTokenBudget(t *testing.T) {
+	docs := []Document{
+		{FilePath: "a.go", TokenCount: 60},
+		{FilePath: "b.go", TokenCount: 60},
+		{FilePath: "c.go", TokenCount: 30},
+	}
+	kept, omitted := applyTokenBudget(docs, 100)
+	if len(kept) != 2 || kept[1].FilePath != "c.go" {
+		t.Fatalf("unexpected kept documents: %+v", kept)
+	}
+	if len(omitted) != 1 || omitted[0] != "b.go" {
+		t.Fatalf("unexpected omitted files: %v", omitted)
+	}
+}
+
+func TestComputeDiffStats(t *testing.T) {
+	diff := `diff --git a/dev-infrastructure/main.bicep b/dev-infrastructure/main.bicep
+index 123..456 100644
+--- a/dev-infrastructure/main.bicep
++++ b/dev-infrastructure/main.bicep
+@@ -1,2 +1,3 @@
+-param a string
++param a int
++param b int
+ ---
+diff --git a/Makefile b/Makefile
+new file mode 100644
+--- /dev/null
++++ b/Makefile
+@@ -0,0 +1 @@
++all:
+`
+	stats := computeDiffStats(splitDiffIntoFiles(diff, logging.New(logr.Discard())))
+	if stats.Additions != 3 || stats.Deletions != 1 || stats.ChangedFiles != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if len(stats.TopLevelDirs) != 2 || stats.TopLevelDirs[0] != "." || stats.TopLevelDirs[1] != "dev-infrastructure" {
+		t.Fatalf("unexpected directories %v", stats.TopLevelDirs)
+	}
+}
@@ -4,14 +4,20 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
 )
 
 type Config struct {
 	Enabled          bool
 	ModelName        string
 	OllamaURL        string
+	RepoURL          string // Cloned into RepoPath when it does not exist yet
 	RepoPath         string
+	Git              gitrepo.Settings // configures the clone at RepoPath
 	MaxContextTokens int
+	MaxDiffTokens    int // Total map-stage token budget per PR (0 = unlimited)
 	CallTimeout      time.Duration
+	AutoPull         bool // Pull ModelName during NewAnalyzer when it is missing
 	Logger           logr.Logger
 }
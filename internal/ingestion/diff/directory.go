@@ -0,0 +1,146 @@
+package diff
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
+)
+
+// maxMapDocuments bounds the number of map calls issued for a single PR.
+const maxMapDocuments = 100
+
+// defaultDirectoryDepth is the number of leading path segments used to group
+// files before falling back to shallower groupings.
+const defaultDirectoryDepth = 3
+
+// buildDirectoryDocuments groups file chunks by directory and produces one
+// condensed document per directory. It is used when a PR is too large to map
+// file by file. The grouping depth is reduced until the number of directories
+// fits within maxMapDocuments.
+func buildDirectoryDocuments(chunks [][2]string, log logging.Logger, cfg Config) []Document {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	chunkSize := cfg.MaxContextTokens
+	if chunkSize == 0 {
+		chunkSize = 4096
+	}
+	targetTokens := chunkSize * 3 / 4
+
+	var groups map[string][][2]string
+	for depth := defaultDirectoryDepth; depth >= 1; depth-- {
+		groups = groupByDirectory(chunks, depth)
+		if len(groups) <= maxMapDocuments {
+			log.Debug("grouped diff by directory", "depth", depth, "directories", len(groups))
+			break
+		}
+	}
+	if len(groups) > maxMapDocuments {
+		return nil
+	}
+
+	dirs := make([]string, 0, len(groups))
+	for dir := range groups {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	docs := make([]Document, 0, len(dirs))
+	for _, dir := range dirs {
+		content := summarizeDirectory(dir, groups[dir], targetTokens)
+		docs = append(docs, Document{FilePath: dir, Content: content, TokenCount: estimateTokens(content)})
+	}
+	return docs
+}
+
+// groupByDirectory buckets file chunks by the first depth segments of their
+// directory. Files at the repository root are grouped under ".".
+func groupByDirectory(chunks [][2]string, depth int) map[string][][2]string {
+	groups := make(map[string][][2]string)
+	for _, chunk := range chunks {
+		dir := directoryPrefix(chunk[0], depth)
+		groups[dir] = append(groups[dir], chunk)
+	}
+	return groups
+}
+
+func directoryPrefix(filePath string, depth int) string {
+	dir := path.Dir(filePath)
+	if dir == "." || dir == "/" {
+		return "."
+	}
+	parts := strings.Split(dir, "/")
+	if depth > 0 && len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+// summarizeDirectory renders a file listing with line counts followed by as
+// many diff excerpts as fit within the token budget.
+func summarizeDirectory(dir string, chunks [][2]string, targetTokens int) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Directory: %s\nFiles changed: %d\n\n", dir, len(chunks))
+	for _, chunk := range chunks {
+		added, removed := countChangedLines(chunk[1])
+		fmt.Fprintf(&builder, "- %s (+%d/-%d)\n", chunk[0], added, removed)
+	}
+
+	header := builder.String()
+	budget := targetTokens - estimateTokens(header)
+	if budget <= 0 {
+		return header
+	}
+
+	builder.WriteString("\nExcerpts:\n")
+	for _, chunk := range chunks {
+		excerpt := changedLinesExcerpt(chunk[1], 20)
+		if excerpt == "" {
+			continue
+		}
+		section := fmt.Sprintf("File: %s\n%s\n", chunk[0], excerpt)
+		cost := estimateTokens(section)
+		if cost > budget {
+			break
+		}
+		builder.WriteString(section)
+		budget -= cost
+	}
+	return builder.String()
+}
+
+// countChangedLines returns the number of added and removed lines in a file diff.
+func countChangedLines(chunk string) (added, removed int) {
+	for _, line := range strings.Split(chunk, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			continue
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// changedLinesExcerpt returns up to max added/removed lines from a file diff.
+func changedLinesExcerpt(chunk string, max int) string {
+	var lines []string
+	for _, line := range strings.Split(chunk, "\n") {
+		if strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---") {
+			continue
+		}
+		if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			lines = append(lines, line)
+			if len(lines) >= max {
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
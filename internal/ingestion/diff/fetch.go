@@ -3,6 +3,7 @@ package diff
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 
@@ -14,7 +15,7 @@ var configureFetchSpecOnce sync.Once
 
 const prFetchSpec = "+refs/pull/*/head:refs/remotes/origin/pr/*"
 
-func fetchConsolidatedDiff(ctx context.Context, meta PRMetadata, repoPath string, log logging.Logger) (string, error) {
+func fetchConsolidatedDiff(ctx context.Context, meta PRMetadata, git gitrepo.Settings, repoURL, repoPath string, log logging.Logger) (string, error) {
 	if repoPath == "" {
 		return "", fmt.Errorf("diff analyzer requires repo path")
 	}
@@ -22,11 +23,17 @@ func fetchConsolidatedDiff(ctx context.Context, meta PRMetadata, repoPath string
 		return "", fmt.Errorf("missing PR number")
 	}
 
-	if err := ensurePRFetchSpec(ctx, repoPath, log); err != nil {
+	repo := gitrepo.New(gitrepo.RepoConfig{Settings: git, URL: repoURL, Path: repoPath})
+	if _, err := os.Stat(repoPath); os.IsNotExist(err) && repoURL != "" {
+		if _, err := repo.Ensure(ctx); err != nil {
+			return "", fmt.Errorf("clone %s: %w", repoURL, err)
+		}
+	}
+
+	if err := ensurePRFetchSpec(ctx, repo, log); err != nil {
 		return "", fmt.Errorf("configure fetch spec: %w", err)
 	}
 
-	repo := gitrepo.New(gitrepo.RepoConfig{Path: repoPath})
 	if err := repo.Fetch(ctx); err != nil {
 		return "", fmt.Errorf("git fetch origin: %w", err)
 	}
@@ -37,8 +44,7 @@ func fetchConsolidatedDiff(ctx context.Context, meta PRMetadata, repoPath string
 		rangeSpec := fmt.Sprintf("%s..%s", parent, meta.MergeCommitSHA)
 		log.Debug("generating diff", "range", rangeSpec)
 		// Prefer 'show' with range to match tracer semantics; keep unified context 3
-		r := gitrepo.New(gitrepo.RepoConfig{Path: repoPath})
-		diff, err := r.MergeDiff(ctx, meta.MergeCommitSHA)
+		diff, err := repo.MergeDiff(ctx, meta.MergeCommitSHA)
 		if err != nil {
 			return "", err
 		}
@@ -51,14 +57,13 @@ func fetchConsolidatedDiff(ctx context.Context, meta PRMetadata, repoPath string
 	return "", fmt.Errorf("merged PR with no merge commit available")
 }
 
-func ensurePRFetchSpec(ctx context.Context, repoPath string, log logging.Logger) error {
+func ensurePRFetchSpec(ctx context.Context, r *gitrepo.Repo, log logging.Logger) error {
 	var returnErr error
 	configureFetchSpecOnce.Do(func() {
-		output, err := gitCommand(ctx, repoPath, "config", "--local", "--get-all", "remote.origin.fetch")
-		if err == nil && strings.Contains(output, prFetchSpec) {
+		if ok, err := r.ConfigHasLocal(ctx, "remote.origin.fetch", prFetchSpec); err == nil && ok {
 			return
 		}
-		if _, err := gitCommand(ctx, repoPath, "config", "--local", "--add", "remote.origin.fetch", prFetchSpec); err != nil {
+		if err := r.ConfigAddLocal(ctx, "remote.origin.fetch", prFetchSpec); err != nil {
 			returnErr = err
 			return
 		}
@@ -66,9 +71,3 @@ func ensurePRFetchSpec(ctx context.Context, repoPath string, log logging.Logger)
 	})
 	return returnErr
 }
-
-// gitCommand retained only for config probing; consider replacing with gitrepo if needed elsewhere.
-func gitCommand(ctx context.Context, repoPath string, args ...string) (string, error) {
-	r := gitrepo.New(gitrepo.RepoConfig{Path: repoPath})
-	return r.Run(ctx, args...)
-}
@@ -10,12 +10,16 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/ollama"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/telemetry"
 )
 
 type llmClient struct {
-	llm *ollama.LLM
-	log logr.Logger
-	to  time.Duration
+	llm   *ollama.LLM
+	model string
+	log   logr.Logger
+	to    time.Duration
 }
 
 func newLLMClient(cfg Config, base logr.Logger) (*llmClient, error) {
@@ -34,7 +38,7 @@ func newLLMClient(cfg Config, base logr.Logger) (*llmClient, error) {
 		return nil, fmt.Errorf("create ollama client: %w", err)
 	}
 
-	return &llmClient{llm: client, log: base, to: cfg.CallTimeout}, nil
+	return &llmClient{llm: client, model: cfg.ModelName, log: base, to: cfg.CallTimeout}, nil
 }
 
 func (c *llmClient) mapChunk(ctx context.Context, doc Document, meta PRMetadata) (string, error) {
@@ -51,7 +55,7 @@ func (c *llmClient) mapChunk(ctx context.Context, doc Document, meta PRMetadata)
 		},
 	}
 
-	resp, err := c.llm.GenerateContent(ctx, messages)
+	resp, err := c.generate(ctx, "map_chunk", messages)
 	if err != nil {
 		return "", c.annotateError(err)
 	}
@@ -61,6 +65,30 @@ func (c *llmClient) mapChunk(ctx context.Context, doc Document, meta PRMetadata)
 	return resp.Choices[0].Content, nil
 }
 
+func (c *llmClient) mapDirectory(ctx context.Context, doc Document, meta PRMetadata) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	prompt := strings.ReplaceAll(directoryMapPromptTemplate, "{{.PRTitle}}", meta.Title)
+	prompt = strings.ReplaceAll(prompt, "{{.Directory}}", doc.FilePath)
+	prompt = strings.ReplaceAll(prompt, "{{.Text}}", doc.Content)
+
+	messages := []llms.MessageContent{
+		{
+			Role:  llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{llms.TextContent{Text: prompt}},
+		},
+	}
+
+	resp, err := c.generate(ctx, "map_directory", messages)
+	if err != nil {
+		return "", c.annotateError(err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("empty directory map response")
+	}
+	return resp.Choices[0].Content, nil
+}
+
 func (c *llmClient) reduceSummary(ctx context.Context, summaries []string, meta PRMetadata) (string, error) {
 	ctx, cancel := c.withTimeout(ctx)
 	defer cancel()
@@ -76,7 +104,7 @@ func (c *llmClient) reduceSummary(ctx context.Context, summaries []string, meta
 		},
 	}
 
-	resp, err := c.llm.GenerateContent(ctx, messages)
+	resp, err := c.generate(ctx, "reduce", messages)
 	if err != nil {
 		return "", c.annotateError(err)
 	}
@@ -87,6 +115,17 @@ func (c *llmClient) reduceSummary(ctx context.Context, summaries []string, meta
 	return resp.Choices[0].Content, nil
 }
 
+// generate runs one LLM call inside a span tagged with the map/reduce stage.
+func (c *llmClient) generate(ctx context.Context, stage string, messages []llms.MessageContent) (*llms.ContentResponse, error) {
+	ctx, span := telemetry.Start(ctx, "llm."+stage,
+		attribute.String("llm.model", c.model),
+		attribute.String("llm.stage", stage),
+	)
+	resp, err := c.llm.GenerateContent(ctx, messages)
+	telemetry.End(span, err)
+	return resp, err
+}
+
 func (c *llmClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
 	if c.to <= 0 {
 		return context.WithCancel(ctx)
@@ -24,6 +24,28 @@ Rules:
 - [FILE: {{.FilePath}}] ...
 - [FILE: {{.FilePath}}] ...`
 
+const directoryMapPromptTemplate = `You are a code analysis tool. The pull request below is too large to review file by file, so you are given a condensed view of every file changed under one directory.
+
+Context:
+- Pull request title: {{.PRTitle}}
+- Directory: {{.Directory}}
+
+Rules:
+- Only report facts visible in the file list or excerpts.
+- Never speculate or use words like "likely", "suggests", "appears", or "possibly".
+- Describe the change at directory level (e.g. "renamed X across 12 files"), not per line.
+- Output exactly one bullet per distinct change, using the format:
+  - [DIR: {{.Directory}}] <concise description>
+- Maximum 4 bullets; each under 25 words.
+
+<changes>
+{{.Text}}
+</changes>
+
+**Observed Changes:**
+- [DIR: {{.Directory}}] ...
+- [DIR: {{.Directory}}] ...`
+
 const reducePromptTemplate = `You are a technical summarizer. Your task is to analyze the provided Pull Request context and create a factual, concise, and structured summary of the changes.
 
 ## Rules:
@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// pathWeights boosts files in directories that usually carry the behavioural
+// change of a PR. The first matching prefix wins.
+var pathWeights = []struct {
+	Prefix string
+	Weight float64
+}{
+	{Prefix: "api/", Weight: 3},
+	{Prefix: "cmd/", Weight: 2.5},
+	{Prefix: "config/", Weight: 2},
+	{Prefix: "internal/", Weight: 1.5},
+	{Prefix: "pkg/", Weight: 1.5},
+	{Prefix: "docs/", Weight: 0.5},
+	{Prefix: "test/", Weight: 0.5},
+}
+
+// scoreFile returns a heuristic importance score for a file diff based on the
+// number of changed lines and the location of the file.
+func scoreFile(filePath, chunk string) float64 {
+	added, removed := countChangedLines(chunk)
+	weight := pathWeight(filePath)
+	if strings.HasSuffix(filePath, "_test.go") {
+		weight *= 0.5
+	}
+	return weight * math.Log1p(float64(added+removed))
+}
+
+func pathWeight(filePath string) float64 {
+	for _, pw := range pathWeights {
+		if strings.HasPrefix(filePath, pw.Prefix) || strings.Contains(filePath, "/"+pw.Prefix) {
+			return pw.Weight
+		}
+	}
+	return 1
+}
+
+// rankFiles orders file chunks by descending importance. Ties keep their
+// original (diff) order.
+func rankFiles(chunks [][2]string) [][2]string {
+	ranked := make([][2]string, len(chunks))
+	copy(ranked, chunks)
+	scores := make(map[string]float64, len(ranked))
+	for _, chunk := range ranked {
+		scores[chunk[0]] = scoreFile(chunk[0], chunk[1])
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i][0]] > scores[ranked[j][0]]
+	})
+	return ranked
+}
+
+// applyTokenBudget keeps documents in order until the cumulative token count
+// would exceed budget and returns the paths of the files that were dropped.
+// A non-positive budget disables truncation. The first document is always kept.
+func applyTokenBudget(docs []Document, budget int) ([]Document, []string) {
+	if budget <= 0 {
+		return docs, nil
+	}
+	kept := make([]Document, 0, len(docs))
+	var omitted []string
+	seen := make(map[string]bool)
+	used := 0
+	for _, doc := range docs {
+		if len(kept) > 0 && used+doc.TokenCount > budget {
+			if !seen[doc.FilePath] {
+				seen[doc.FilePath] = true
+				omitted = append(omitted, doc.FilePath)
+			}
+			continue
+		}
+		kept = append(kept, doc)
+		used += doc.TokenCount
+	}
+	return kept, omitted
+}
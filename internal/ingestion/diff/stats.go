@@ -0,0 +1,46 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+// DiffStats summarizes the size of a PR's merge diff, generated files
+// included.
+type DiffStats struct {
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+	// TopLevelDirs are the first path segments touched, sorted; files at the
+	// repository root count as ".".
+	TopLevelDirs []string
+}
+
+// computeDiffStats counts added and deleted lines in the hunks of each file
+// chunk returned by splitDiffIntoFiles.
+func computeDiffStats(chunks [][2]string) DiffStats {
+	stats := DiffStats{ChangedFiles: len(chunks)}
+	for _, chunk := range chunks {
+		dir, _, found := strings.Cut(chunk[0], "/")
+		if !found {
+			dir = "."
+		}
+		stats.TopLevelDirs = append(stats.TopLevelDirs, dir)
+
+		inHunk := false
+		for _, line := range strings.Split(chunk[1], "\n") {
+			switch {
+			case strings.HasPrefix(line, "@@"):
+				inHunk = true
+			case !inHunk:
+			case strings.HasPrefix(line, "+"):
+				stats.Additions++
+			case strings.HasPrefix(line, "-"):
+				stats.Deletions++
+			}
+		}
+	}
+	slices.Sort(stats.TopLevelDirs)
+	stats.TopLevelDirs = slices.Compact(stats.TopLevelDirs)
+	return stats
+}
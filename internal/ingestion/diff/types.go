@@ -20,6 +20,11 @@ type Analysis struct {
 	AnalysisSuccessful bool            `json:"analysis_successful"`
 	FailureReason      string          `json:"failure_reason,omitempty"`
 	FailureCategory    FailureCategory `json:"failure_category,omitempty"`
+	// Stats is set whenever the diff was fetched, even if analysis failed.
+	Stats *DiffStats `json:"stats,omitempty"`
+	// Chunks are the per-file diff documents of the files not filtered as
+	// generated, set like Stats.
+	Chunks []Document `json:"-"`
 }
 
 type PRMetadata struct {
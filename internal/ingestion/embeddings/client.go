@@ -4,37 +4,68 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/tmc/langchaingo/llms/ollama"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
+	ollamaapi "github.com/roivaz/aro-hcp-intelhub/internal/ollama"
+	"github.com/roivaz/aro-hcp-intelhub/internal/telemetry"
 )
 
 type Client struct {
 	model string
 	llm   *ollama.LLM
 	to    time.Duration
+
+	preflight bool
+	autoPull  bool
+}
+
+// WithoutPreflight skips the model availability check, for callers that may
+// never embed (e.g. CACHE-only ingestion).
+func WithoutPreflight() func(*Client) {
+	return func(c *Client) { c.preflight = false }
 }
 
-func NewClient(baseURL, model string, timeout time.Duration) *Client {
-	opts := []ollama.Option{ollama.WithModel(model)}
+// WithAutoPull pulls the embedding model when the preflight finds it missing.
+func WithAutoPull(pull bool) func(*Client) {
+	return func(c *Client) { c.autoPull = pull }
+}
+
+// NewClient returns an embedding client after verifying that model exists on
+// the Ollama server, so a missing model fails at startup rather than timing
+// out on the first embed.
+func NewClient(baseURL, model string, timeout time.Duration, opts ...func(*Client)) (*Client, error) {
+	llmOpts := []ollama.Option{ollama.WithModel(model)}
 	if trimmed := strings.TrimSpace(baseURL); trimmed != "" {
-		opts = append(opts, ollama.WithServerURL(trimmed))
+		llmOpts = append(llmOpts, ollama.WithServerURL(trimmed))
 	}
-	opts = append(opts, ollama.WithHTTPClient(&http.Client{Timeout: 30 * time.Second}))
+	llmOpts = append(llmOpts, ollama.WithHTTPClient(&http.Client{Timeout: 30 * time.Second}))
 
-	llm, err := ollama.New(opts...)
+	llm, err := ollama.New(llmOpts...)
 	if err != nil {
-		log.Fatalf("create ollama client: %v", err)
+		return nil, fmt.Errorf("create ollama client: %w", err)
 	}
 
-	return &Client{
-		model: model,
-		llm:   llm,
-		to:    timeout,
+	c := &Client{
+		model:     model,
+		llm:       llm,
+		to:        timeout,
+		preflight: true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.preflight {
+		if err := ollamaapi.EnsureModel(context.Background(), baseURL, model, c.autoPull); err != nil {
+			return nil, err
+		}
 	}
+	return c, nil
 }
 
 func (c *Client) EmbedTexts(ctx context.Context, inputs []string) ([][]float32, error) {
@@ -43,17 +74,24 @@ func (c *Client) EmbedTexts(ctx context.Context, inputs []string) ([][]float32,
 	}
 	ctx, cancel := c.withTimeout(ctx)
 	defer cancel()
+	ctx, span := telemetry.Start(ctx, "ollama.embed",
+		attribute.String("llm.model", c.model),
+		attribute.Int("embedding.inputs", len(inputs)),
+	)
+	log := logging.FromContext(ctx).WithName("ollama")
 	start := time.Now()
-	log.Printf("ollama: embedding %d input(s) with model %s", len(inputs), c.model)
+	log.Debug("embedding inputs", "inputs", len(inputs), "model", c.model)
 
 	vectors, err := c.llm.CreateEmbedding(ctx, inputs)
 	if err != nil {
 		annotated := c.annotateError(err)
-		log.Printf("ollama: embedding failed after %s: %v", time.Since(start), annotated)
+		log.Error(annotated, "embedding failed", "model", c.model, "elapsed", time.Since(start).String())
+		telemetry.End(span, annotated)
 		return nil, fmt.Errorf("create embedding: %w", annotated)
 	}
 
-	log.Printf("ollama: embedded %d input(s) in %s", len(vectors), time.Since(start))
+	log.Info("embedded inputs", "inputs", len(vectors), "model", c.model, "elapsed", time.Since(start).String())
+	telemetry.End(span, nil)
 	return vectors, nil
 }
 
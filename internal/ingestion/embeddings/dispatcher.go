@@ -0,0 +1,141 @@
+package embeddings
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
+	"github.com/roivaz/aro-hcp-intelhub/internal/telemetry"
+)
+
+// Priority orders the requests waiting in a Dispatcher.
+type Priority int
+
+const (
+	Background  Priority = iota // ingestion and other batch work
+	Interactive                 // queries a user is waiting on
+)
+
+func (p Priority) String() string {
+	if p == Interactive {
+		return "interactive"
+	}
+	return "background"
+}
+
+// Dispatcher shares an embedder between callers of different priorities,
+// running at most concurrency calls at a time. A freed slot goes to the
+// oldest waiting interactive request, then to the oldest background one, so
+// a backfill only delays a search by the calls already in flight. Interactive
+// traffic is bursty; background work is not protected against starvation.
+type Dispatcher struct {
+	embedder Embedder
+
+	mu       sync.Mutex
+	free     int
+	inFlight int
+	waiting  [Interactive + 1][]chan struct{}
+}
+
+// dispatcherStats is a snapshot of a Dispatcher's queue.
+type dispatcherStats struct {
+	InFlight          int
+	QueuedInteractive int
+	QueuedBackground  int
+}
+
+// NewDispatcher returns a Dispatcher running up to concurrency calls to
+// embedder at once; values below 1 mean 1.
+func NewDispatcher(embedder Embedder, concurrency int) *Dispatcher {
+	return &Dispatcher{embedder: embedder, free: max(concurrency, 1)}
+}
+
+// Embedder returns an embedder whose calls go through d with priority p.
+func (d *Dispatcher) Embedder(p Priority) Embedder {
+	return prioritized{d: d, p: p}
+}
+
+type prioritized struct {
+	d *Dispatcher
+	p Priority
+}
+
+func (e prioritized) EmbedTexts(ctx context.Context, inputs []string) ([][]float32, error) {
+	if err := e.d.acquire(ctx, e.p); err != nil {
+		return nil, err
+	}
+	defer e.d.release()
+	return e.d.embedder.EmbedTexts(ctx, inputs)
+}
+
+// acquire waits for a slot, recording the wait and the queue it found on a
+// span.
+func (d *Dispatcher) acquire(ctx context.Context, p Priority) error {
+	d.mu.Lock()
+	if d.free > 0 {
+		d.free--
+		d.inFlight++
+		d.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	d.waiting[p] = append(d.waiting[p], ready)
+	stats := d.statsLocked()
+	d.mu.Unlock()
+
+	_, span := telemetry.Start(ctx, "embedding.queue",
+		attribute.String("embedding.priority", p.String()),
+		attribute.Int("embedding.in_flight", stats.InFlight),
+		attribute.Int("embedding.queued_interactive", stats.QueuedInteractive),
+		attribute.Int("embedding.queued_background", stats.QueuedBackground),
+	)
+	start := time.Now()
+	select {
+	case <-ready:
+		telemetry.End(span, nil)
+		logging.FromContext(ctx).Debug("embedding slot acquired", "priority", p.String(), "waited", time.Since(start).String(),
+			"queued_interactive", stats.QueuedInteractive, "queued_background", stats.QueuedBackground)
+		return nil
+	case <-ctx.Done():
+		d.mu.Lock()
+		idx := slices.Index(d.waiting[p], ready)
+		if idx >= 0 {
+			d.waiting[p] = slices.Delete(d.waiting[p], idx, idx+1)
+		}
+		d.mu.Unlock()
+		if idx < 0 {
+			// The slot was handed over as ctx ended.
+			d.release()
+		}
+		telemetry.End(span, ctx.Err())
+		return ctx.Err()
+	}
+}
+
+// statsLocked returns the queue depth and calls in flight; d.mu must be held.
+func (d *Dispatcher) statsLocked() dispatcherStats {
+	return dispatcherStats{
+		InFlight:          d.inFlight,
+		QueuedInteractive: len(d.waiting[Interactive]),
+		QueuedBackground:  len(d.waiting[Background]),
+	}
+}
+
+// release hands the slot to the next waiting request, highest priority first.
+func (d *Dispatcher) release() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for p := Interactive; p >= Background; p-- {
+		if len(d.waiting[p]) > 0 {
+			close(d.waiting[p][0])
+			d.waiting[p] = d.waiting[p][1:]
+			return
+		}
+	}
+	d.free++
+	d.inFlight--
+}
@@ -0,0 +1,79 @@
+package embeddings
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingEmbedder blocks every call on gate and records the first input of
+// each call in order.
+type recordingEmbedder struct {
+	gate  chan struct{}
+	mu    sync.Mutex
+	order []string
+}
+
+func (r *recordingEmbedder) EmbedTexts(ctx context.Context, inputs []string) ([][]float32, error) {
+	<-r.gate
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.order = append(r.order, inputs[0])
+	return [][]float32{{1}}, nil
+}
+
+func TestDispatcherPriority(t *testing.T) {
+	ctx := context.Background()
+	rec := &recordingEmbedder{gate: make(chan struct{})}
+	d := NewDispatcher(rec, 1)
+
+	var wg sync.WaitGroup
+	embed := func(p Priority, input string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := d.Embedder(p).EmbedTexts(ctx, []string{input}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	stats := func() dispatcherStats {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.statsLocked()
+	}
+	waitFor := func(want dispatcherStats) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); stats() != want; {
+			if time.Now().After(deadline) {
+				t.Fatalf("stats = %+v, want %+v", stats(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	embed(Background, "backfill 1")
+	waitFor(dispatcherStats{InFlight: 1})
+	embed(Background, "backfill 2")
+	waitFor(dispatcherStats{InFlight: 1, QueuedBackground: 1})
+	embed(Interactive, "search")
+	waitFor(dispatcherStats{InFlight: 1, QueuedInteractive: 1, QueuedBackground: 1})
+
+	// A cancelled request leaves the queue.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := d.Embedder(Interactive).EmbedTexts(cancelled, []string{"gone"}); err == nil {
+		t.Fatal("cancelled request was served")
+	}
+
+	close(rec.gate)
+	wg.Wait()
+	if want := []string{"backfill 1", "search", "backfill 2"}; !slices.Equal(rec.order, want) {
+		t.Fatalf("served %v, want %v", rec.order, want)
+	}
+	if got := stats(); got != (dispatcherStats{}) {
+		t.Fatalf("stats after drain = %+v", got)
+	}
+}
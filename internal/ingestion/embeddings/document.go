@@ -5,28 +5,56 @@ import (
 )
 
 type PRDocument struct {
-	Title       string
-	Body        string
-	Description string
+	Title        string
+	Body         string
+	Description  string // rich description from the diff analysis
+	Labels       []string
+	Milestone    string
+	LinkedIssues []string
 }
 
-func BuildDocument(prTitle, prBody, richDescription string) string {
+func BuildDocument(doc PRDocument) string {
 	var builder strings.Builder
 	builder.WriteString("PR Title: ")
-	builder.WriteString(prTitle)
+	builder.WriteString(doc.Title)
+	if len(doc.Labels) > 0 {
+		builder.WriteString("\nLabels: ")
+		builder.WriteString(strings.Join(doc.Labels, ", "))
+	}
+	if doc.Milestone != "" {
+		builder.WriteString("\nMilestone: ")
+		builder.WriteString(doc.Milestone)
+	}
+	if len(doc.LinkedIssues) > 0 {
+		builder.WriteString("\nLinked issues: ")
+		builder.WriteString(strings.Join(doc.LinkedIssues, ", "))
+	}
 	builder.WriteString("\n\nPR Description: ")
-	if len(prBody) > 2000 {
-		builder.WriteString(prBody[:2000])
+	if len(doc.Body) > 2000 {
+		builder.WriteString(doc.Body[:2000])
 	} else {
-		builder.WriteString(prBody)
+		builder.WriteString(doc.Body)
 	}
-	if richDescription != "" {
+	if doc.Description != "" {
 		builder.WriteString("\n\nAI Analysis: ")
-		if len(richDescription) > 3000 {
-			builder.WriteString(richDescription[:3000])
+		if len(doc.Description) > 3000 {
+			builder.WriteString(doc.Description[:3000])
 		} else {
-			builder.WriteString(richDescription)
+			builder.WriteString(doc.Description)
 		}
 	}
 	return builder.String()
 }
+
+// maxDescriptionDocumentChars bounds the rich description embedded on its own.
+const maxDescriptionDocumentChars = 6000
+
+// BuildDescriptionDocument is the text of a PR's second vector: the rich
+// description alone, so the analysis is searchable without the title and
+// body diluting it.
+func BuildDescriptionDocument(prTitle, richDescription string) string {
+	if len(richDescription) > maxDescriptionDocumentChars {
+		richDescription = richDescription[:maxDescriptionDocumentChars]
+	}
+	return "PR Title: " + prTitle + "\n\nAI Analysis: " + richDescription
+}
@@ -0,0 +1,82 @@
+package embeddings
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Fake embeds texts without a model by hashing their words into a vector, so
+// ingestion and search can run offline. Vectors are deterministic and texts
+// sharing words land close together, which is enough for CI and demos.
+type Fake struct {
+	dim int
+}
+
+// NewFake returns a Fake producing unit vectors of dimension dim.
+func NewFake(dim int) (*Fake, error) {
+	if dim <= 0 {
+		return nil, fmt.Errorf("fake embeddings need a positive dimension, got %d", dim)
+	}
+	return &Fake{dim: dim}, nil
+}
+
+// FakeModelName is the model name fake vectors of dimension dim are stored
+// under, so they never mix with a real model's.
+func FakeModelName(dim int) string { return fmt.Sprintf("fake-%d", dim) }
+
+func (f *Fake) EmbedTexts(ctx context.Context, inputs []string) ([][]float32, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no inputs provided for embedding")
+	}
+	vectors := make([][]float32, len(inputs))
+	for i, input := range inputs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vectors[i] = f.embed(input)
+	}
+	return vectors, nil
+}
+
+// embed adds a signed one-hot vector per lowercased word, at the position
+// and with the sign taken from the word's SHA-256, and normalises the sum.
+func (f *Fake) embed(text string) []float32 {
+	vec := make([]float64, f.dim)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		// Blank texts still need a non-zero vector for cosine distance.
+		words = []string{text}
+	}
+	for _, word := range words {
+		sum := sha256.Sum256([]byte(word))
+		pos := binary.BigEndian.Uint64(sum[:8]) % uint64(f.dim)
+		if sum[8]&1 == 0 {
+			vec[pos]++
+		} else {
+			vec[pos]--
+		}
+	}
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	out := make([]float32, f.dim)
+	if norm == 0 {
+		// Words cancelled out; fall back to the first word's position.
+		sum := sha256.Sum256([]byte(words[0]))
+		out[binary.BigEndian.Uint64(sum[:8])%uint64(f.dim)] = 1
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for i, v := range vec {
+		out[i] = float32(v / norm)
+	}
+	return out
+}
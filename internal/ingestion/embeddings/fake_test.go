@@ -0,0 +1,51 @@
+package embeddings
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestFakeEmbedTexts(t *testing.T) {
+	fake, err := NewFake(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := []string{"Bump HyperShift operator", "bump hypershift operator!", "Fix frontend metrics", ""}
+	first, err := fake.EmbedTexts(context.Background(), inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := fake.EmbedTexts(context.Background(), inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, vec := range first {
+		if len(vec) != 64 {
+			t.Fatalf("input %d: dimension %d, want 64", i, len(vec))
+		}
+		if !slices.Equal(vec, second[i]) {
+			t.Errorf("input %d: vectors differ between calls", i)
+		}
+		var norm float64
+		for _, v := range vec {
+			norm += float64(v) * float64(v)
+		}
+		if math.Abs(norm-1) > 1e-5 {
+			t.Errorf("input %d: squared norm %f, want 1", i, norm)
+		}
+	}
+	if !slices.Equal(first[0], first[1]) {
+		t.Error("case and punctuation changed the vector")
+	}
+	if slices.Equal(first[0], first[2]) {
+		t.Error("different texts got the same vector")
+	}
+}
+
+func TestNewFakeRejectsBadDimension(t *testing.T) {
+	if _, err := NewFake(0); err == nil {
+		t.Fatal("NewFake(0) succeeded")
+	}
+}
@@ -0,0 +1,43 @@
+package embeddings
+
+import (
+	"context"
+	"math"
+)
+
+// Embedder is what Normalized wraps; Client and Fake implement it.
+type Embedder interface {
+	EmbedTexts(ctx context.Context, inputs []string) ([][]float32, error)
+}
+
+// Normalized scales the vectors of an embedder to unit length, which inner
+// product and L2 distances need to rank like cosine and map onto similarity.
+type Normalized struct {
+	Embedder
+}
+
+func (n Normalized) EmbedTexts(ctx context.Context, inputs []string) ([][]float32, error) {
+	vectors, err := n.Embedder.EmbedTexts(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	for _, vec := range vectors {
+		Normalize(vec)
+	}
+	return vectors, nil
+}
+
+// Normalize scales vec in place to unit length; zero vectors are left as is.
+func Normalize(vec []float32) {
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return
+	}
+	norm = math.Sqrt(norm)
+	for i, v := range vec {
+		vec[i] = float32(float64(v) / norm)
+	}
+}
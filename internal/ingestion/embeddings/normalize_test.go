@@ -0,0 +1,36 @@
+package embeddings
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+type constEmbedder []float32
+
+func (c constEmbedder) EmbedTexts(ctx context.Context, inputs []string) ([][]float32, error) {
+	vectors := make([][]float32, len(inputs))
+	for i := range inputs {
+		vectors[i] = append([]float32(nil), c...)
+	}
+	return vectors, nil
+}
+
+func TestNormalized(t *testing.T) {
+	vectors, err := Normalized{Embedder: constEmbedder{3, 0, 4}}.EmbedTexts(context.Background(), []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float32{0.6, 0, 0.8}
+	for i, v := range vectors[0] {
+		if math.Abs(float64(v-want[i])) > 1e-6 {
+			t.Fatalf("vector = %v, want %v", vectors[0], want)
+		}
+	}
+
+	zero := []float32{0, 0}
+	Normalize(zero)
+	if zero[0] != 0 || zero[1] != 0 {
+		t.Errorf("zero vector = %v, want it unchanged", zero)
+	}
+}
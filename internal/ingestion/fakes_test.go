@@ -0,0 +1,184 @@
+package ingestion
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	pgvector "github.com/pgvector/pgvector-go"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+// fakeFetcher serves pages of merged PRs, most recently updated first.
+type fakeFetcher struct {
+	pages [][]PRChange
+}
+
+func (f *fakeFetcher) FetchBatch(ctx context.Context, page int) (*FetchResult, error) {
+	if page > len(f.pages) {
+		return &FetchResult{}, nil
+	}
+	prs := f.pages[page-1]
+	res := &FetchResult{PRs: prs, PageCount: len(prs)}
+	if page < len(f.pages) {
+		res.HasMore, res.NextPage = true, page+1
+	}
+	return res, nil
+}
+
+func (f *fakeFetcher) FetchSince(ctx context.Context, watermark time.Time, lastNumber, page int) (*FetchResult, error) {
+	res, err := f.FetchBatch(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	return sinceWatermark(res, watermark, lastNumber), nil
+}
+
+func (f *fakeFetcher) AddTimelineIssues(ctx context.Context, pr *PRChange) error { return nil }
+
+// fakeEmbedder returns one vector per input, or err.
+type fakeEmbedder struct {
+	inputs [][]string
+	err    error
+}
+
+func (e *fakeEmbedder) EmbedTexts(ctx context.Context, inputs []string) ([][]float32, error) {
+	e.inputs = append(e.inputs, inputs)
+	if e.err != nil {
+		return nil, e.err
+	}
+	vectors := make([][]float32, len(inputs))
+	for i := range inputs {
+		vectors[i] = []float32{float32(i + 1)}
+	}
+	return vectors, nil
+}
+
+// fakeRepo keeps PRs in memory. Processing a PR sets ProcessedAt, so PRs
+// leave the unprocessed queue like they do in the database.
+type fakeRepo struct {
+	prs         map[int]*db.PREmbedding
+	refs        map[int][]db.PRReference
+	diffChunks  map[int][]db.PRDiffChunk
+	claims      map[int]string
+	retryBefore time.Time // last retryFailedBefore passed to the queue
+	stored      []int
+	refreshed   []int
+}
+
+func newFakeRepo(prs ...*db.PREmbedding) *fakeRepo {
+	r := &fakeRepo{prs: map[int]*db.PREmbedding{}, refs: map[int][]db.PRReference{}, diffChunks: map[int][]db.PRDiffChunk{}, claims: map[int]string{}}
+	for _, pr := range prs {
+		r.prs[pr.PRNumber] = pr
+	}
+	return r
+}
+
+func (r *fakeRepo) RegisterEmbeddingModel(ctx context.Context) error { return nil }
+
+func (r *fakeRepo) GetPRUpdatedAt(ctx context.Context, number int) (bool, *time.Time, error) {
+	pr, ok := r.prs[number]
+	if !ok || pr.IngestSource == db.SourceGitHubLive {
+		return false, nil, nil
+	}
+	return true, pr.GithubUpdatedAt, nil
+}
+
+func (r *fakeRepo) LatestMergedPR(ctx context.Context) (time.Time, int, error) {
+	var at time.Time
+	number := 0
+	for _, pr := range r.prs {
+		if pr.IngestSource == db.SourceGitHubLive {
+			continue
+		}
+		if pr.MergedAt != nil && (pr.MergedAt.After(at) || pr.MergedAt.Equal(at) && pr.PRNumber > number) {
+			at, number = *pr.MergedAt, pr.PRNumber
+		}
+	}
+	return at, number, nil
+}
+
+func (r *fakeRepo) UpsertPR(ctx context.Context, pr *db.PREmbedding) (bool, error) {
+	stored, ok := r.prs[pr.PRNumber]
+	if !ok {
+		r.prs[pr.PRNumber] = pr
+		r.stored = append(r.stored, pr.PRNumber)
+		return false, nil
+	}
+	r.refreshed = append(r.refreshed, pr.PRNumber)
+	takeover := stored.IngestSource == db.SourceGitHubLive && pr.IngestSource != db.SourceGitHubLive
+	if !takeover && stored.GithubUpdatedAt != nil && (pr.GithubUpdatedAt == nil || pr.GithubUpdatedAt.Before(*stored.GithubUpdatedAt)) {
+		return false, nil
+	}
+	if takeover {
+		stored.IngestSource = pr.IngestSource
+	}
+	if stored.ProcessedAt != nil && (stored.PRTitle != pr.PRTitle || stored.PRBody != pr.PRBody) {
+		stored.NeedsReembed = true
+	}
+	stored.PRTitle, stored.PRBody, stored.GithubUpdatedAt = pr.PRTitle, pr.PRBody, pr.GithubUpdatedAt
+	return stored.NeedsReembed, nil
+}
+
+func (r *fakeRepo) ReplacePRReferences(ctx context.Context, prNumber int, refs []db.PRReference) error {
+	r.refs[prNumber] = refs
+	return nil
+}
+
+func (r *fakeRepo) unprocessed(retryFailedBefore time.Time) []*db.PREmbedding {
+	r.retryBefore = retryFailedBefore
+	var prs []*db.PREmbedding
+	for _, pr := range r.prs {
+		retry := !retryFailedBefore.IsZero() && !pr.AnalysisSuccessful && pr.ProcessedAt != nil && pr.ProcessedAt.Before(retryFailedBefore)
+		if pr.ProcessedAt == nil || pr.NeedsReembed || retry {
+			prs = append(prs, pr)
+		}
+	}
+	sort.Slice(prs, func(i, j int) bool { return prs[i].PRNumber > prs[j].PRNumber })
+	return prs
+}
+
+func (r *fakeRepo) CountUnprocessedPRs(ctx context.Context, retryFailedBefore time.Time) (int, error) {
+	return len(r.unprocessed(retryFailedBefore)), nil
+}
+
+func (r *fakeRepo) ClaimUnprocessedPRs(ctx context.Context, workerID string, limit int, visibility time.Duration, retryFailedBefore time.Time) ([]*db.PREmbedding, error) {
+	var prs []*db.PREmbedding
+	for _, pr := range r.unprocessed(retryFailedBefore) {
+		if len(prs) == limit {
+			break
+		}
+		if _, claimed := r.claims[pr.PRNumber]; !claimed {
+			r.claims[pr.PRNumber] = workerID
+			prs = append(prs, pr)
+		}
+	}
+	return prs, nil
+}
+
+func (r *fakeRepo) ReleasePRClaim(ctx context.Context, prNumber int, workerID string) error {
+	if r.claims[prNumber] == workerID {
+		delete(r.claims, prNumber)
+	}
+	return nil
+}
+
+func (r *fakeRepo) UpdatePRDiffStats(ctx context.Context, prNumber int, stats db.PRDiffStats) error {
+	return nil
+}
+
+func (r *fakeRepo) ReplacePRDiffChunks(ctx context.Context, prNumber int, chunks []db.PRDiffChunk) error {
+	r.diffChunks[prNumber] = chunks
+	return nil
+}
+
+func (r *fakeRepo) UpdatePRProcessing(ctx context.Context, prNumber int, embedding, descEmbedding *pgvector.Vector, richDesc *string, analysisSuccess bool, failureReason *string, failureCategory *string) error {
+	pr := r.prs[prNumber]
+	now := time.Now()
+	pr.Embedding, pr.DescriptionEmbedding, pr.RichDescription = embedding, descEmbedding, richDesc
+	pr.AnalysisSuccessful, pr.FailureReason, pr.FailureCategory = analysisSuccess, failureReason, failureCategory
+	pr.ProcessedAt, pr.NeedsReembed = &now, false
+	delete(r.claims, prNumber)
+	return nil
+}
@@ -0,0 +1,140 @@
+package ingestion
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Fixture modes for WithFixtures.
+const (
+	FixtureRecord = "record"
+	FixtureReplay = "replay"
+)
+
+// fixtureHeaders are the response headers kept in fixtures: go-github reads
+// pagination from Link.
+var fixtureHeaders = []string{"Content-Type", "Link"}
+
+// fixture is a recorded GitHub response, stored as JSON.
+type fixture struct {
+	Method string              `json:"method"`
+	URL    string              `json:"url"`
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header,omitempty"`
+	Body   json.RawMessage     `json:"body,omitempty"`
+}
+
+// fixtureTransport records responses to dir or replays them from it. Requests
+// are keyed by method, path and query, so fixtures recorded with a token
+// replay without one.
+type fixtureTransport struct {
+	dir    string
+	record bool
+	next   http.RoundTripper
+}
+
+// WithFixtures makes the GitHub client record its responses to dir in
+// FixtureRecord mode, or answer from them in FixtureReplay mode without
+// touching the network.
+func WithFixtures(dir, mode string) func(*http.Client) {
+	return func(c *http.Client) {
+		next := c.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		c.Transport = &fixtureTransport{dir: dir, record: mode == FixtureRecord, next: next}
+	}
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := filepath.Join(t.dir, fixtureName(req))
+	if !t.record {
+		return t.replay(req, path)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err := writeFixture(path, req, resp, body); err != nil {
+		return nil, fmt.Errorf("record fixture: %w", err)
+	}
+	return resp, nil
+}
+
+func (t *fixtureTransport) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no fixture for %s %s in %s; record one with INGEST_FIXTURE_MODE=%s",
+			req.Method, req.URL.RequestURI(), t.dir, FixtureRecord)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(f.Header),
+		Body:          io.NopCloser(bytes.NewReader(f.Body)),
+		ContentLength: int64(len(f.Body)),
+		Request:       req,
+	}, nil
+}
+
+func writeFixture(path string, req *http.Request, resp *http.Response, body []byte) error {
+	f := fixture{Method: req.Method, URL: req.URL.RequestURI(), Status: resp.StatusCode, Header: map[string][]string{}}
+	for _, name := range fixtureHeaders {
+		if values := resp.Header.Values(name); len(values) > 0 {
+			f.Header[name] = values
+		}
+	}
+	if json.Valid(body) {
+		f.Body = body
+	} else if len(body) > 0 {
+		// Keep non-JSON bodies, such as HTML error pages, as a JSON string.
+		f.Body, _ = json.Marshal(string(body))
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+var unsafeFixtureChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fixtureName names the fixture of req after its method and path, for
+// browsing, plus a hash of the query so pages get distinct files.
+func fixtureName(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.RequestURI()))
+	path := strings.Trim(unsafeFixtureChars.ReplaceAllString(req.URL.Path, "_"), "_")
+	return fmt.Sprintf("%s_%s_%s.json", strings.ToLower(req.Method), path, hex.EncodeToString(sum[:4]))
+}
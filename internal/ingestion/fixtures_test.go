@@ -0,0 +1,51 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFixturesRecordAndReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=2>; rel="next"`, "http://"+r.Host, r.URL.Path))
+		}
+		fmt.Fprintf(w, `[{"number": %s, "merged_at": "2025-01-02T03:04:05Z"}]`, r.URL.Query().Get("page"))
+	}))
+	dir := t.TempDir()
+
+	fetch := func(mode string) (*FetchResult, error) {
+		client := NewGitHubClient("", WithFixtures(dir, mode))
+		base, _ := url.Parse(srv.URL + "/")
+		client.BaseURL = base
+		return NewGitHubFetcher(client, "Azure", "ARO-HCP").FetchBatch(context.Background(), 1)
+	}
+
+	recorded, err := fetch(FixtureRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	replayed, err := fetch(FixtureReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed.PRs) != 1 || replayed.PRs[0].Number != 1 {
+		t.Fatalf("replayed PRs = %+v, want PR 1", replayed.PRs)
+	}
+	if replayed.NextPage != recorded.NextPage || replayed.NextPage != 2 {
+		t.Errorf("replayed next page %d, recorded %d, want 2", replayed.NextPage, recorded.NextPage)
+	}
+
+	client := NewGitHubClient("", WithFixtures(dir, FixtureReplay))
+	_, err = NewGitHubFetcher(client, "Azure", "ARO-HCP").FetchBatch(context.Background(), 2)
+	if err == nil || !strings.Contains(err.Error(), "no fixture") {
+		t.Errorf("replay of an unrecorded page: err = %v, want no fixture", err)
+	}
+}
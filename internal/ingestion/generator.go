@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"time"
 
 	pgvector "github.com/pgvector/pgvector-go"
 
@@ -12,23 +14,87 @@ import (
 	dbmigrate "github.com/roivaz/aro-hcp-intelhub/internal/db/migrate"
 	diffanalyzer "github.com/roivaz/aro-hcp-intelhub/internal/ingestion/diff"
 	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion/embeddings"
+	"github.com/roivaz/aro-hcp-intelhub/internal/progress"
 )
 
+// defaultProcessBatch is how many PRs PROCESS mode leases at a time when
+// WorkerBatchSize is unset.
+const defaultProcessBatch = 5
+
+// diffChunkEmbedBatch bounds the diff chunks embedded per request.
+const diffChunkEmbedBatch = 16
+
+// ProgressFunc receives the number of completed and total work items for the
+// current phase ("cache" or "process").
+type ProgressFunc func(phase string, done, total int)
+
+// Fetcher lists merged PRs; *GitHubFetcher implements it.
+type Fetcher interface {
+	FetchBatch(ctx context.Context, page int) (*FetchResult, error)
+	FetchSince(ctx context.Context, watermark time.Time, lastNumber, page int) (*FetchResult, error)
+	AddTimelineIssues(ctx context.Context, pr *PRChange) error
+}
+
+// Embedder embeds PR documents; *embeddings.Client and *embeddings.Fake
+// implement it.
+type Embedder interface {
+	EmbedTexts(ctx context.Context, inputs []string) ([][]float32, error)
+}
+
+// PRRepository stores PRs and their processing state; *db.SearchRepository
+// implements it.
+type PRRepository interface {
+	RegisterEmbeddingModel(ctx context.Context) error
+	GetPRUpdatedAt(ctx context.Context, number int) (bool, *time.Time, error)
+	LatestMergedPR(ctx context.Context) (time.Time, int, error)
+	UpsertPR(ctx context.Context, pr *db.PREmbedding) (bool, error)
+	ReplacePRReferences(ctx context.Context, prNumber int, refs []db.PRReference) error
+	CountUnprocessedPRs(ctx context.Context, retryFailedBefore time.Time) (int, error)
+	ClaimUnprocessedPRs(ctx context.Context, workerID string, limit int, visibility time.Duration, retryFailedBefore time.Time) ([]*db.PREmbedding, error)
+	ReleasePRClaim(ctx context.Context, prNumber int, workerID string) error
+	UpdatePRDiffStats(ctx context.Context, prNumber int, stats db.PRDiffStats) error
+	ReplacePRDiffChunks(ctx context.Context, prNumber int, chunks []db.PRDiffChunk) error
+	UpdatePRProcessing(ctx context.Context, prNumber int, embedding, descEmbedding *pgvector.Vector, richDesc *string, analysisSuccess bool, failureReason *string, failureCategory *string) error
+}
+
 type Generator struct {
 	cfg         Config
-	db          *db.Database
-	repo        *db.SearchRepository
-	embedClient *embeddings.Client
-	fetcher     *GitHubFetcher
+	db          *db.Database // nil skips the schema check in Run
+	repo        PRRepository
+	embedClient Embedder
+	fetcher     Fetcher
+	tickets     *TicketParser
+	progress    ProgressFunc
+	meter       *progress.Meter
 }
 
-func NewGenerator(cfg Config, database *db.Database, repo *db.SearchRepository, embed *embeddings.Client, fetcher *GitHubFetcher) *Generator {
-	return &Generator{cfg: cfg, db: database, repo: repo, embedClient: embed, fetcher: fetcher}
+func NewGenerator(cfg Config, database *db.Database, repo PRRepository, embed Embedder, fetcher Fetcher) *Generator {
+	return &Generator{cfg: cfg, db: database, repo: repo, embedClient: embed, fetcher: fetcher, tickets: NewTicketParser(cfg.TicketProjects), meter: progress.New("ingest")}
+}
+
+// WithProgress registers a callback invoked as PRs are cached or processed.
+func (g *Generator) WithProgress(fn ProgressFunc) *Generator {
+	g.progress = fn
+	return g
+}
+
+func (g *Generator) reportProgress(phase string, done, total int) {
+	g.meter.Update(phase, done, total)
+	if g.progress != nil {
+		g.progress(phase, done, total)
+	}
 }
 
 func (g *Generator) Run(ctx context.Context) error {
-	if err := dbmigrate.EnsureCurrent(ctx, g.db.Bun(), "", g.cfg.AutoMigrate); err != nil {
-		return err
+	if g.db != nil {
+		if err := dbmigrate.EnsureCurrent(ctx, g.db.Bun(), "", g.cfg.AutoMigrate); err != nil {
+			return err
+		}
+	}
+	if !strings.EqualFold(g.cfg.ExecutionMode, "CACHE") {
+		if err := g.repo.RegisterEmbeddingModel(ctx); err != nil {
+			return err
+		}
 	}
 
 	switch strings.ToUpper(g.cfg.ExecutionMode) {
@@ -36,10 +102,12 @@ func (g *Generator) Run(ctx context.Context) error {
 		return g.RunCache(ctx)
 	case "PROCESS":
 		return g.RunProcess(ctx)
+	case "WORKER":
+		return g.RunWorker(ctx)
 	case "FULL", "":
 		return g.RunFull(ctx)
 	default:
-		return fmt.Errorf("invalid execution mode: %s (must be FULL, CACHE, or PROCESS)", g.cfg.ExecutionMode)
+		return fmt.Errorf("invalid execution mode: %s (must be FULL, CACHE, PROCESS, or WORKER)", g.cfg.ExecutionMode)
 	}
 }
 
@@ -65,13 +133,14 @@ func (g *Generator) RunProcess(ctx context.Context) error {
 		limit = g.cfg.GitHubFetchMax
 	}
 
-	// Apply retry mode to repository if enabled
+	// In retry mode, failed analyses from before this run are queued again.
+	var retryBefore time.Time
 	if g.cfg.RetryFailed {
-		db.WithRetryFailed(true)(g.repo)
+		retryBefore = time.Now()
 		log.Printf("retry mode enabled: will retry previously failed diff analyses")
 	}
 
-	unprocessedCount, err := g.repo.CountUnprocessedPRs(ctx)
+	unprocessedCount, err := g.repo.CountUnprocessedPRs(ctx, retryBefore)
 	if err != nil {
 		return fmt.Errorf("count unprocessed PRs: %w", err)
 	}
@@ -83,63 +152,115 @@ func (g *Generator) RunProcess(ctx context.Context) error {
 		return nil
 	}
 
-	prs, err := g.repo.GetUnprocessedPRs(ctx, limit)
+	analyzer, err := g.newAnalyzer()
 	if err != nil {
-		return fmt.Errorf("get unprocessed PRs: %w", err)
+		return err
 	}
 
-	log.Printf("process: processing %d PRs sequentially", len(prs))
-
-	var analyzer *diffanalyzer.Analyzer
-	if g.cfg.DiffAnalyzer.Enabled {
-		a, err := diffanalyzer.NewAnalyzer(g.cfg.DiffAnalyzer)
+	// Lease PRs a batch at a time, like WORKER mode, so replicas running
+	// PROCESS concurrently split the queue instead of analysing the same PRs,
+	// and each lease only has to outlive one batch.
+	batch := g.cfg.WorkerBatchSize
+	if batch <= 0 {
+		batch = defaultProcessBatch
+	}
+	total := min(unprocessedCount, limit)
+	var failed []*db.PREmbedding
+	defer func() { g.releaseClaims(failed) }()
+	processed, attempted := 0, 0
+	g.reportProgress("process", 0, total)
+	for attempted < limit {
+		prs, err := g.repo.ClaimUnprocessedPRs(ctx, g.cfg.WorkerID, min(batch, limit-attempted), g.cfg.WorkerVisibilityTimeout, retryBefore)
 		if err != nil {
-			return fmt.Errorf("init diff analyzer: %w", err)
+			return fmt.Errorf("claim unprocessed PRs: %w", err)
 		}
-		analyzer = a
-	}
-
-	// Process PRs sequentially
-	processed := 0
-	for _, pr := range prs {
-		if err := g.processSinglePR(ctx, pr, analyzer); err != nil {
-			log.Printf("process: error processing PR #%d: %v", pr.PRNumber, err)
-			continue
+		if len(prs) == 0 {
+			break
+		}
+		for idx, pr := range prs {
+			if err := ctx.Err(); err != nil {
+				failed = append(failed, prs[idx:]...)
+				return err
+			}
+			if err := g.processSinglePR(ctx, pr, analyzer); err != nil {
+				log.Printf("process: error processing PR #%d: %v", pr.PRNumber, err)
+				// Keep the lease until the run ends so this run does not
+				// claim the PR again.
+				failed = append(failed, pr)
+			} else {
+				processed++
+			}
+			attempted++
+			g.reportProgress("process", attempted, max(total, attempted))
 		}
-		processed++
 	}
 
-	log.Printf("process: processed %d PR(s)", processed)
+	log.Printf("process: processed %d of %d claimed PR(s)", processed, attempted)
 	return nil
 }
 
+// newAnalyzer returns the diff analyzer, or nil when diff analysis is disabled.
+func (g *Generator) newAnalyzer() (*diffanalyzer.Analyzer, error) {
+	if !g.cfg.DiffAnalyzer.Enabled {
+		return nil, nil
+	}
+	a, err := diffanalyzer.NewAnalyzer(g.cfg.DiffAnalyzer)
+	if err != nil {
+		return nil, fmt.Errorf("init diff analyzer: %w", err)
+	}
+	return a, nil
+}
+
 func (g *Generator) RunCache(ctx context.Context) error {
 	log.Printf("cache mode: fetching and storing PR metadata only (no embeddings/analysis)")
 
-	newPRs, err := g.fetchNewPRs(ctx)
+	newPRs, editedPRs, err := g.fetchNewPRs(ctx)
 	if err != nil {
 		return err
 	}
 
-	if len(newPRs) == 0 {
-		log.Printf("cache: no new PRs to store")
+	if len(newPRs) == 0 && len(editedPRs) == 0 {
+		log.Printf("cache: no new or edited PRs to store")
 		return nil
 	}
 
-	return g.cachePRs(ctx, newPRs)
+	g.addTimelineIssues(ctx, newPRs)
+	g.addTimelineIssues(ctx, editedPRs)
+	if err := g.cachePRs(ctx, newPRs); err != nil {
+		return err
+	}
+	return g.refreshPRs(ctx, editedPRs)
 }
 
-// fetchNewPRs fetches new PRs from GitHub that aren't already in the database
-func (g *Generator) fetchNewPRs(ctx context.Context) ([]PRChange, error) {
-	var newPRs []PRChange
+// addTimelineIssues adds the issues cross-referencing each PR to those its
+// body links. A failed lookup leaves the PR with the body's links only.
+func (g *Generator) addTimelineIssues(ctx context.Context, prs []PRChange) {
+	for i := range prs {
+		if err := g.fetcher.AddTimelineIssues(ctx, &prs[i]); err != nil {
+			log.Printf("cache: %v", err)
+		}
+	}
+}
+
+// fetchNewPRs walks merged PRs from GitHub, most recently updated first, and
+// returns the PRs not stored yet and the stored PRs GitHub has updated since
+// they were fetched. It stops at the first stored PR that has not been
+// updated, since every PR after it is older.
+func (g *Generator) fetchNewPRs(ctx context.Context) ([]PRChange, []PRChange, error) {
+	if strings.EqualFold(g.cfg.IngestionMode, "INCREMENTAL") {
+		prs, err := g.fetchSinceWatermark(ctx)
+		return prs, nil, err
+	}
+	var newPRs, editedPRs []PRChange
 	currentPage := 1
 	totalFetched := 0
 	reachedCached := false
+	full := func() bool { return len(newPRs)+len(editedPRs) >= g.cfg.GitHubFetchMax }
 
-	for len(newPRs) < g.cfg.GitHubFetchMax {
+	for !full() {
 		result, err := g.fetcher.FetchBatch(ctx, currentPage)
 		if err != nil {
-			return nil, fmt.Errorf("fetch batch prs (page %d): %w", currentPage, err)
+			return nil, nil, fmt.Errorf("fetch batch prs (page %d): %w", currentPage, err)
 		}
 
 		if result.PageCount == 0 {
@@ -149,64 +270,136 @@ func (g *Generator) fetchNewPRs(ctx context.Context) ([]PRChange, error) {
 		totalFetched += result.PageCount
 
 		for _, pr := range result.PRs {
-			if len(newPRs) >= g.cfg.GitHubFetchMax {
+			if full() {
 				break
 			}
 
-			exists, err := g.repo.HasPR(ctx, pr.Number)
+			exists, storedUpdatedAt, err := g.repo.GetPRUpdatedAt(ctx, pr.Number)
 			if err != nil {
-				return nil, fmt.Errorf("check PR existence: %w", err)
+				return nil, nil, fmt.Errorf("check PR existence: %w", err)
 			}
-			if exists {
-				log.Printf("PR #%d already stored, stopping at first cached PR", pr.Number)
-				reachedCached = true
-				break
+			if !exists {
+				newPRs = append(newPRs, pr)
+				continue
 			}
-			newPRs = append(newPRs, pr)
+			// Rows stored before updated_at was tracked are refreshed once.
+			if storedUpdatedAt == nil || pr.UpdatedAt.After(*storedUpdatedAt) {
+				editedPRs = append(editedPRs, pr)
+				continue
+			}
+			log.Printf("PR #%d already stored and unchanged, stopping", pr.Number)
+			reachedCached = true
+			break
 		}
 
-		if len(newPRs) >= g.cfg.GitHubFetchMax || reachedCached || !result.HasMore {
+		if full() || reachedCached || !result.HasMore {
 			break
 		}
 
 		currentPage = result.NextPage
 	}
 
-	log.Printf("cache: scanned %d PRs from GitHub total, found %d new", totalFetched, len(newPRs))
-	return newPRs, nil
+	log.Printf("cache: scanned %d PRs from GitHub total, found %d new and %d updated", totalFetched, len(newPRs), len(editedPRs))
+	return newPRs, editedPRs, nil
 }
 
-func (g *Generator) cachePRs(ctx context.Context, prs []PRChange) error {
-	for _, pr := range prs {
-		record := &db.PREmbedding{
-			PRNumber:           pr.Number,
-			PRTitle:            pr.Title,
-			PRBody:             pr.Body,
-			Author:             pr.Author,
-			CreatedAt:          pr.CreatedAt,
-			MergedAt:           pr.MergedAt,
-			State:              pr.State,
-			BaseRef:            pr.BaseRef,
-			GithubBaseSHA:      nullableString(pr.BaseSHA),
-			HeadCommitSHA:      nullableString(pr.HeadCommitSHA),
-			MergeCommitSHA:     nullableString(pr.MergeCommitSHA),
-			Embedding:          nil, // Not processed yet
-			RichDescription:    nil,
-			AnalysisSuccessful: false,
-			FailureReason:      nil,
-			ProcessedAt:        nil, // Mark as unprocessed
+// fetchSinceWatermark returns the PRs merged after the newest merge
+// ingestion stored, the watermark, wherever GitHub lists them; PRs stored by
+// live lookups are fetched again. Without ingested merges the watermark is
+// Lookback ago. Only the GitHubFetchMax merged first are kept, so the
+// watermark never skips a PR and the next run resumes where this one stopped.
+func (g *Generator) fetchSinceWatermark(ctx context.Context) ([]PRChange, error) {
+	watermark, lastNumber, err := g.repo.LatestMergedPR(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load watermark: %w", err)
+	}
+	if watermark.IsZero() && g.cfg.Lookback > 0 {
+		// An empty database starts at the lookback, not the first PR ever.
+		watermark = time.Now().Add(-g.cfg.Lookback)
+	}
+	var prs []PRChange
+	for page := 1; ; {
+		result, err := g.fetcher.FetchSince(ctx, watermark, lastNumber, page)
+		if err != nil {
+			return nil, fmt.Errorf("fetch prs since %s (page %d): %w", watermark.Format(time.RFC3339), page, err)
+		}
+		for _, pr := range result.PRs {
+			exists, _, err := g.repo.GetPRUpdatedAt(ctx, pr.Number)
+			if err != nil {
+				return nil, fmt.Errorf("check PR existence: %w", err)
+			}
+			if !exists {
+				prs = append(prs, pr)
+			}
+		}
+		if !result.HasMore {
+			break
 		}
+		page = result.NextPage
+	}
 
-		if err := g.repo.StorePR(ctx, record); err != nil {
+	sort.SliceStable(prs, func(i, j int) bool {
+		a, b := prs[i].MergedAt, prs[j].MergedAt
+		if !a.Equal(*b) {
+			return a.Before(*b)
+		}
+		return prs[i].Number < prs[j].Number
+	})
+	found := len(prs)
+	if len(prs) > g.cfg.GitHubFetchMax {
+		prs = prs[:g.cfg.GitHubFetchMax]
+	}
+	log.Printf("cache: found %d PRs merged after #%d at %s, storing %d", found, lastNumber, watermark.Format(time.RFC3339), len(prs))
+	return prs, nil
+}
+
+func (g *Generator) cachePRs(ctx context.Context, prs []PRChange) error {
+	if len(prs) == 0 {
+		return nil
+	}
+	g.reportProgress("cache", 0, len(prs))
+	for idx, pr := range prs {
+		record := pr.Record() // ProcessedAt is nil, so the PR is queued for processing
+		if _, err := g.repo.UpsertPR(ctx, record); err != nil {
 			return fmt.Errorf("store PR #%d: %w", pr.Number, err)
 		}
-		log.Printf("cache: stored PR #%d (unprocessed)", pr.Number)
+		if err := g.repo.ReplacePRReferences(ctx, pr.Number, g.tickets.Parse(pr.Title, pr.Body)); err != nil {
+			return fmt.Errorf("store tickets of PR #%d: %w", pr.Number, err)
+		}
+		g.reportProgress("cache", idx+1, len(prs))
 	}
 
 	log.Printf("cached %d new PRs without processing", len(prs))
 	return nil
 }
 
+// refreshPRs stores the current title, body and metadata of PRs updated on
+// GitHub. Those whose title or body changed after processing are queued for
+// re-embedding.
+func (g *Generator) refreshPRs(ctx context.Context, prs []PRChange) error {
+	if len(prs) == 0 {
+		return nil
+	}
+	requeued := 0
+	g.reportProgress("refresh", 0, len(prs))
+	for idx, pr := range prs {
+		reembed, err := g.repo.UpsertPR(ctx, pr.Record())
+		if err != nil {
+			return fmt.Errorf("refresh PR #%d: %w", pr.Number, err)
+		}
+		if err := g.repo.ReplacePRReferences(ctx, pr.Number, g.tickets.Parse(pr.Title, pr.Body)); err != nil {
+			return fmt.Errorf("store tickets of PR #%d: %w", pr.Number, err)
+		}
+		if reembed {
+			log.Printf("cache: PR #%d edited, queued for re-embedding", pr.Number)
+			requeued++
+		}
+		g.reportProgress("refresh", idx+1, len(prs))
+	}
+	log.Printf("cache: refreshed %d updated PRs, %d queued for re-embedding", len(prs), requeued)
+	return nil
+}
+
 func (g *Generator) processSinglePR(ctx context.Context, pr *db.PREmbedding, analyzer *diffanalyzer.Analyzer) error {
 	// STEP 1: Run diff analysis FIRST (if enabled)
 	var richDescription *string
@@ -214,8 +407,12 @@ func (g *Generator) processSinglePR(ctx context.Context, pr *db.PREmbedding, ana
 	var failureReason *string
 	var failureCategory *string
 
-	if analyzer != nil {
-		log.Printf("process: analyzing diff for PR #%d", pr.PRNumber)
+	if reembedOnly(pr, g.cfg.EmbeddingModel) {
+		// Embedded by a previous model, before descriptions had their own
+		// vector, or edited since: keep the analysis, refresh the vectors.
+		richDescription = pr.RichDescription
+		analysisSuccessful = true
+	} else if analyzer != nil {
 		metadata := diffanalyzer.PRMetadata{
 			Number:         pr.PRNumber,
 			Title:          pr.PRTitle,
@@ -228,6 +425,22 @@ func (g *Generator) processSinglePR(ctx context.Context, pr *db.PREmbedding, ana
 			MergedAt:       pr.MergedAt,
 		}
 		analysis, err := analyzer.Analyze(ctx, metadata)
+		if analysis.Stats != nil {
+			stats := db.PRDiffStats{
+				Additions:    analysis.Stats.Additions,
+				Deletions:    analysis.Stats.Deletions,
+				ChangedFiles: analysis.Stats.ChangedFiles,
+				TopLevelDirs: analysis.Stats.TopLevelDirs,
+			}
+			if err := g.repo.UpdatePRDiffStats(ctx, pr.PRNumber, stats); err != nil {
+				log.Printf("process: store diff stats of PR #%d: %v", pr.PRNumber, err)
+			}
+		}
+		// Replaced even when empty so chunks of an earlier diff do not
+		// outlive it.
+		if err := g.storeDiffChunks(ctx, pr.PRNumber, analysis.Chunks); err != nil {
+			log.Printf("process: store diff chunks of PR #%d: %v", pr.PRNumber, err)
+		}
 		if err != nil {
 			reason, category := diffanalyzer.GetFailureDetails(err)
 			failureReason = strPtr(reason)
@@ -251,37 +464,88 @@ func (g *Generator) processSinglePR(ctx context.Context, pr *db.PREmbedding, ana
 
 	// STEP 2: Generate embedding INCLUDING rich description
 	// This is critical for search quality - embeddings include LLM analysis of code changes
-	log.Printf("process: generating embedding for PR #%d", pr.PRNumber)
 	richDescText := stringValue(richDescription)
-	document := embeddings.BuildDocument(pr.PRTitle, pr.PRBody, richDescText)
-	vectors, err := g.embedClient.EmbedTexts(ctx, []string{document})
+	documents := []string{embeddings.BuildDocument(embeddings.PRDocument{
+		Title:        pr.PRTitle,
+		Body:         pr.PRBody,
+		Description:  richDescText,
+		Labels:       pr.Labels,
+		Milestone:    stringValue(pr.Milestone),
+		LinkedIssues: pr.LinkedIssues,
+	})}
+	if richDescText != "" {
+		// Second vector: the rich description on its own.
+		documents = append(documents, embeddings.BuildDescriptionDocument(pr.PRTitle, richDescText))
+	}
+	vectors, err := g.embedClient.EmbedTexts(ctx, documents)
 	if err != nil {
 		reason, category := diffanalyzer.GetFailureDetails(err)
 		log.Printf("process: embedding failed for PR #%d: %v", pr.PRNumber, err)
-		if updateErr := g.repo.UpdatePRProcessing(ctx, pr.PRNumber, nil, richDescription, analysisSuccessful, strPtr(reason), strPtr(string(category))); updateErr != nil {
+		if updateErr := g.repo.UpdatePRProcessing(ctx, pr.PRNumber, nil, nil, richDescription, analysisSuccessful, strPtr(reason), strPtr(string(category))); updateErr != nil {
 			return fmt.Errorf("update PR #%d after embedding failure: %w", pr.PRNumber, updateErr)
 		}
 		return nil
 	}
-	if len(vectors) == 0 {
-		reason := "embedding returned no vectors"
-		if updateErr := g.repo.UpdatePRProcessing(ctx, pr.PRNumber, nil, richDescription, analysisSuccessful, strPtr(reason), strPtr("empty_embedding")); updateErr != nil {
+	if len(vectors) < len(documents) {
+		reason := fmt.Sprintf("embedding returned %d of %d vectors", len(vectors), len(documents))
+		if updateErr := g.repo.UpdatePRProcessing(ctx, pr.PRNumber, nil, nil, richDescription, analysisSuccessful, strPtr(reason), strPtr("empty_embedding")); updateErr != nil {
 			return fmt.Errorf("update PR #%d after empty embedding: %w", pr.PRNumber, updateErr)
 		}
 		return nil
 	}
 
 	embedding := pgvector.NewVector(vectors[0])
+	var descEmbedding *pgvector.Vector
+	if len(vectors) > 1 {
+		v := pgvector.NewVector(vectors[1])
+		descEmbedding = &v
+	}
 
 	// STEP 3: Update database with embedding + analysis results
-	if err := g.repo.UpdatePRProcessing(ctx, pr.PRNumber, &embedding, richDescription, analysisSuccessful, failureReason, failureCategory); err != nil {
+	if err := g.repo.UpdatePRProcessing(ctx, pr.PRNumber, &embedding, descEmbedding, richDescription, analysisSuccessful, failureReason, failureCategory); err != nil {
 		return fmt.Errorf("update PR #%d: %w", pr.PRNumber, err)
 	}
 
-	log.Printf("process: completed PR #%d (analysis_successful=%v)", pr.PRNumber, analysisSuccessful)
 	return nil
 }
 
+// storeDiffChunks embeds the diff chunks of a PR, diffChunkEmbedBatch at a
+// time, and replaces the stored ones for search_pr_diffs.
+func (g *Generator) storeDiffChunks(ctx context.Context, prNumber int, docs []diffanalyzer.Document) error {
+	chunks := make([]db.PRDiffChunk, 0, len(docs))
+	for start := 0; start < len(docs); start += diffChunkEmbedBatch {
+		batch := docs[start:min(start+diffChunkEmbedBatch, len(docs))]
+		texts := make([]string, len(batch))
+		for i, doc := range batch {
+			texts[i] = doc.Content
+		}
+		vectors, err := g.embedClient.EmbedTexts(ctx, texts)
+		if err != nil {
+			return err
+		}
+		if len(vectors) < len(batch) {
+			return fmt.Errorf("embedding returned %d of %d vectors", len(vectors), len(batch))
+		}
+		for i, doc := range batch {
+			chunks = append(chunks, db.PRDiffChunk{
+				Path:       doc.FilePath,
+				ChunkIndex: start + i,
+				ChunkText:  doc.Content,
+				Embedding:  pgvector.NewVector(vectors[i]),
+			})
+		}
+	}
+	return g.repo.ReplacePRDiffChunks(ctx, prNumber, chunks)
+}
+
+// reembedOnly reports whether pr has a successful analysis and only needs
+// vectors from the active model, its missing description vector, or new
+// vectors after its title or body was edited.
+func reembedOnly(pr *db.PREmbedding, model string) bool {
+	return pr.ProcessedAt != nil && pr.AnalysisSuccessful && pr.RichDescription != nil &&
+		pr.EmbeddingModel != nil && (*pr.EmbeddingModel != model || pr.DescriptionEmbedding == nil || pr.NeedsReembed)
+}
+
 func stringValue(s *string) string {
 	if s == nil {
 		return ""
@@ -0,0 +1,274 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	diffanalyzer "github.com/roivaz/aro-hcp-intelhub/internal/ingestion/diff"
+)
+
+func TestRunCache(t *testing.T) {
+	old := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := old.Add(24 * time.Hour)
+	repo := newFakeRepo(
+		&db.PREmbedding{PRNumber: 4, PRTitle: "old title", GithubUpdatedAt: &old},
+		&db.PREmbedding{PRNumber: 3, GithubUpdatedAt: &recent},
+	)
+	fetcher := &fakeFetcher{pages: [][]PRChange{
+		{{Number: 5, Title: "ARO-12: new", UpdatedAt: recent}, {Number: 4, Title: "edited", UpdatedAt: recent}},
+		{{Number: 3, UpdatedAt: recent}, {Number: 2, UpdatedAt: old}},
+	}}
+	g := NewGenerator(Config{GitHubFetchMax: 10, TicketProjects: []string{"ARO"}}, nil, repo, &fakeEmbedder{}, fetcher)
+
+	if err := g.RunCache(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(repo.stored, []int{5}) || !slices.Equal(repo.refreshed, []int{4}) {
+		t.Errorf("stored %v, refreshed %v; want [5], [4]", repo.stored, repo.refreshed)
+	}
+	if _, ok := repo.prs[2]; ok {
+		t.Error("PR 2 is older than the first unchanged PR but was stored")
+	}
+	if refs := repo.refs[5]; len(refs) != 1 || refs[0].Ticket != "ARO-12" {
+		t.Errorf("tickets of PR 5 = %+v", refs)
+	}
+	if repo.prs[4].PRTitle != "edited" {
+		t.Errorf("PR 4 title = %q", repo.prs[4].PRTitle)
+	}
+}
+
+func TestRunCacheLivePR(t *testing.T) {
+	old := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := old.Add(24 * time.Hour)
+	// PR 6 was stored by a live lookup after PR 3 was ingested; PRs 4 and 5
+	// were merged in between.
+	repo := newFakeRepo(
+		&db.PREmbedding{PRNumber: 6, GithubUpdatedAt: &recent, IngestSource: db.SourceGitHubLive},
+		&db.PREmbedding{PRNumber: 3, GithubUpdatedAt: &old, IngestSource: db.SourceIngest},
+	)
+	fetcher := &fakeFetcher{pages: [][]PRChange{
+		{{Number: 6, UpdatedAt: recent}, {Number: 5, UpdatedAt: recent}, {Number: 4, UpdatedAt: recent}, {Number: 3, UpdatedAt: old}},
+	}}
+	g := NewGenerator(Config{GitHubFetchMax: 10}, nil, repo, &fakeEmbedder{}, fetcher)
+
+	if err := g.RunCache(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(repo.stored, []int{5, 4}) || !slices.Equal(repo.refreshed, []int{6}) {
+		t.Errorf("stored %v, refreshed %v; want [5 4], [6]", repo.stored, repo.refreshed)
+	}
+	if src := repo.prs[6].IngestSource; src != db.SourceIngest {
+		t.Errorf("PR 6 source = %q, want taken over by ingestion", src)
+	}
+}
+
+func TestRunCacheIncremental(t *testing.T) {
+	at := func(h int) *time.Time {
+		v := time.Date(2025, 1, 1, h, 0, 0, 0, time.UTC)
+		return &v
+	}
+	// PR 3 is stored and the watermark; PR 5 was merged before it but is
+	// listed after it, and PR 1 was merged at the same time with a lower number.
+	repo := newFakeRepo(&db.PREmbedding{PRNumber: 3, MergedAt: at(10), GithubUpdatedAt: at(10)})
+	fetcher := &fakeFetcher{pages: [][]PRChange{
+		{{Number: 7, MergedAt: at(13), UpdatedAt: *at(13)}, {Number: 6, MergedAt: at(12), UpdatedAt: *at(12)}, {Number: 3, MergedAt: at(10), UpdatedAt: *at(11)}},
+		{{Number: 4, MergedAt: at(10), UpdatedAt: *at(11)}, {Number: 5, MergedAt: at(11), UpdatedAt: *at(11)}, {Number: 1, MergedAt: at(10), UpdatedAt: *at(10)}},
+		{{Number: 2, MergedAt: at(9), UpdatedAt: *at(9)}},
+		{{Number: 0, MergedAt: at(14), UpdatedAt: *at(14)}},
+	}}
+	g := NewGenerator(Config{GitHubFetchMax: 3, IngestionMode: "INCREMENTAL"}, nil, repo, &fakeEmbedder{}, fetcher)
+
+	if err := g.RunCache(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Oldest merges first, capped at GitHubFetchMax; page 4 is never read.
+	if !slices.Equal(repo.stored, []int{4, 5, 6}) {
+		t.Errorf("stored %v, want [4 5 6]", repo.stored)
+	}
+}
+
+func TestRunCacheIncrementalLivePR(t *testing.T) {
+	at := func(h int) *time.Time {
+		v := time.Date(2025, 1, 1, h, 0, 0, 0, time.UTC)
+		return &v
+	}
+	// PR 8 was stored by a live lookup; it must not become the watermark.
+	repo := newFakeRepo(
+		&db.PREmbedding{PRNumber: 3, MergedAt: at(10), GithubUpdatedAt: at(10), IngestSource: db.SourceIngest},
+		&db.PREmbedding{PRNumber: 8, MergedAt: at(15), GithubUpdatedAt: at(15), IngestSource: db.SourceGitHubLive},
+	)
+	fetcher := &fakeFetcher{pages: [][]PRChange{
+		{{Number: 8, MergedAt: at(15), UpdatedAt: *at(15)}, {Number: 7, MergedAt: at(13), UpdatedAt: *at(13)}, {Number: 6, MergedAt: at(12), UpdatedAt: *at(12)}, {Number: 3, MergedAt: at(10), UpdatedAt: *at(10)}},
+	}}
+	g := NewGenerator(Config{GitHubFetchMax: 10, IngestionMode: "INCREMENTAL"}, nil, repo, &fakeEmbedder{}, fetcher)
+
+	if err := g.RunCache(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(repo.stored, []int{6, 7}) || !slices.Equal(repo.refreshed, []int{8}) {
+		t.Errorf("stored %v, refreshed %v; want [6 7], [8]", repo.stored, repo.refreshed)
+	}
+	if src := repo.prs[8].IngestSource; src != db.SourceIngest {
+		t.Errorf("PR 8 source = %q, want taken over by ingestion", src)
+	}
+}
+
+func TestRunCacheIncrementalLookback(t *testing.T) {
+	ago := func(days int) *time.Time {
+		v := time.Now().Add(-time.Duration(days) * 24 * time.Hour)
+		return &v
+	}
+	pages := [][]PRChange{
+		{{Number: 3, MergedAt: ago(1), UpdatedAt: *ago(1)}, {Number: 2, MergedAt: ago(5), UpdatedAt: *ago(5)}},
+		{{Number: 1, MergedAt: ago(400), UpdatedAt: *ago(400)}},
+	}
+	for _, tc := range []struct {
+		lookback time.Duration
+		want     []int
+	}{
+		{0, []int{1, 2, 3}},
+		{30 * 24 * time.Hour, []int{2, 3}},
+		{48 * time.Hour, []int{3}},
+	} {
+		repo := newFakeRepo()
+		g := NewGenerator(Config{GitHubFetchMax: 10, IngestionMode: "INCREMENTAL", Lookback: tc.lookback}, nil, repo, &fakeEmbedder{}, &fakeFetcher{pages: pages})
+		if err := g.RunCache(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(repo.stored, tc.want) {
+			t.Errorf("lookback %s: stored %v, want %v", tc.lookback, repo.stored, tc.want)
+		}
+	}
+}
+
+func TestRunProcess(t *testing.T) {
+	model, desc := "nomic-embed-text", "rich description"
+	processed := time.Now()
+	repo := newFakeRepo(
+		&db.PREmbedding{PRNumber: 7, PRTitle: "new PR"},
+		&db.PREmbedding{PRNumber: 6, PRTitle: "edited PR", ProcessedAt: &processed, AnalysisSuccessful: true, RichDescription: &desc, EmbeddingModel: &model, NeedsReembed: true},
+	)
+	embedder := &fakeEmbedder{}
+	g := NewGenerator(Config{EmbeddingModel: model, MaxProcessBatch: 10}, nil, repo, embedder, &fakeFetcher{})
+
+	if err := g.RunProcess(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(embedder.inputs) != 2 || len(embedder.inputs[0]) != 1 || len(embedder.inputs[1]) != 2 {
+		t.Fatalf("embedded %d batches: %q", len(embedder.inputs), embedder.inputs)
+	}
+	if pr := repo.prs[7]; pr.Embedding == nil || pr.DescriptionEmbedding != nil || pr.AnalysisSuccessful {
+		t.Errorf("PR 7 = %+v, want embedded without analysis", pr)
+	}
+	// Re-embedding keeps the analysis and adds the description vector.
+	if pr := repo.prs[6]; pr.DescriptionEmbedding == nil || !pr.AnalysisSuccessful || pr.RichDescription == nil {
+		t.Errorf("PR 6 = %+v, want re-embedded with its analysis", pr)
+	}
+}
+
+func TestStoreDiffChunks(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo(&db.PREmbedding{PRNumber: 7})
+	embedder := &fakeEmbedder{}
+	g := NewGenerator(Config{}, nil, repo, embedder, &fakeFetcher{})
+
+	docs := make([]diffanalyzer.Document, diffChunkEmbedBatch+1)
+	for i := range docs {
+		docs[i] = diffanalyzer.Document{FilePath: fmt.Sprintf("pkg/%d.go", i), Content: fmt.Sprintf("diff %d", i)}
+	}
+	if err := g.storeDiffChunks(ctx, 7, docs); err != nil {
+		t.Fatal(err)
+	}
+	if len(embedder.inputs) != 2 || len(embedder.inputs[0]) != diffChunkEmbedBatch || len(embedder.inputs[1]) != 1 {
+		t.Fatalf("embedded batches of %d inputs; want %d and 1", len(embedder.inputs), diffChunkEmbedBatch)
+	}
+	chunks := repo.diffChunks[7]
+	if len(chunks) != len(docs) {
+		t.Fatalf("stored %d chunks, want %d", len(chunks), len(docs))
+	}
+	if last := chunks[diffChunkEmbedBatch]; last.ChunkIndex != diffChunkEmbedBatch || last.Path != docs[diffChunkEmbedBatch].FilePath || last.ChunkText != docs[diffChunkEmbedBatch].Content {
+		t.Errorf("last chunk = %+v", last)
+	}
+
+	// A diff without chunks clears the earlier ones.
+	if err := g.storeDiffChunks(ctx, 7, nil); err != nil {
+		t.Fatal(err)
+	}
+	if chunks, ok := repo.diffChunks[7]; !ok || len(chunks) != 0 {
+		t.Errorf("chunks after an empty diff = %+v, %v; want replaced with none", chunks, ok)
+	}
+
+	embedder.err = errors.New("ollama down")
+	if err := g.storeDiffChunks(ctx, 8, docs); err == nil {
+		t.Error("embedding failure not returned")
+	}
+	if _, ok := repo.diffChunks[8]; ok {
+		t.Error("chunks stored despite the embedding failure")
+	}
+}
+
+func TestRunProcessRecordsEmbeddingFailures(t *testing.T) {
+	repo := newFakeRepo(&db.PREmbedding{PRNumber: 8})
+	g := NewGenerator(Config{MaxProcessBatch: 10, RetryFailed: true}, nil, repo, &fakeEmbedder{err: errors.New("ollama down")}, &fakeFetcher{})
+
+	if err := g.RunProcess(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if repo.retryBefore.IsZero() {
+		t.Error("retry mode not applied to the queue")
+	}
+	if pr := repo.prs[8]; pr.Embedding != nil || pr.FailureReason == nil {
+		t.Errorf("PR 8 = %+v, want a recorded failure", pr)
+	}
+}
+
+func TestRunProcessSkipsClaimedPRs(t *testing.T) {
+	repo := newFakeRepo(&db.PREmbedding{PRNumber: 1}, &db.PREmbedding{PRNumber: 2}, &db.PREmbedding{PRNumber: 3})
+	repo.claims[2] = "other-replica"
+	g := NewGenerator(Config{MaxProcessBatch: 10, WorkerID: "me", WorkerBatchSize: 1}, nil, repo, &fakeEmbedder{}, &fakeFetcher{})
+
+	if err := g.RunProcess(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if repo.prs[1].ProcessedAt == nil || repo.prs[3].ProcessedAt == nil {
+		t.Error("unclaimed PRs 1 and 3 were not processed")
+	}
+	if repo.prs[2].ProcessedAt != nil || repo.claims[2] != "other-replica" {
+		t.Error("PR 2, leased by another replica, was processed")
+	}
+}
+
+func TestRunFull(t *testing.T) {
+	repo := newFakeRepo()
+	fetcher := &fakeFetcher{pages: [][]PRChange{{{Number: 9, Title: "fresh", UpdatedAt: time.Now()}}}}
+	g := NewGenerator(Config{ExecutionMode: "FULL", GitHubFetchMax: 10, MaxProcessBatch: 10}, nil, repo, &fakeEmbedder{}, fetcher)
+
+	var phases []string
+	g.WithProgress(func(phase string, done, total int) {
+		if done == total {
+			phases = append(phases, phase)
+		}
+	})
+	if err := g.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if pr := repo.prs[9]; pr == nil || pr.Embedding == nil || pr.ProcessedAt == nil {
+		t.Fatalf("PR 9 = %+v, want cached and processed", pr)
+	}
+	if !slices.Equal(phases, []string{"cache", "process"}) {
+		t.Errorf("completed phases %v", phases)
+	}
+}
+
+func TestWorkerBackoff(t *testing.T) {
+	for n, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 10: 30 * time.Second} {
+		if got := workerBackoff(time.Second, 30*time.Second, n); got != want {
+			t.Errorf("workerBackoff(n=%d) = %s, want %s", n, got, want)
+		}
+	}
+}
@@ -2,35 +2,64 @@ package ingestion
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/google/go-github/v66/github"
 	"golang.org/x/oauth2"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
 )
 
-func NewGitHubClient(token string) *github.Client {
-	if token == "" {
-		return github.NewClient(&http.Client{Timeout: 30 * time.Second})
+func NewGitHubClient(token string, opts ...func(*http.Client)) *github.Client {
+	tc := &http.Client{}
+	if token != "" {
+		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+		tc = oauth2.NewClient(context.Background(), ts)
 	}
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(context.Background(), ts)
 	tc.Timeout = 30 * time.Second
+	for _, opt := range opts {
+		opt(tc)
+	}
 	return github.NewClient(tc)
 }
 
+// GitHubClient returns a GitHub client for cfg, recording or replaying
+// fixtures when FixtureDir is set.
+func (cfg Config) GitHubClient() *github.Client {
+	if cfg.FixtureDir == "" {
+		return NewGitHubClient(cfg.GitHubToken)
+	}
+	return NewGitHubClient(cfg.GitHubToken, WithFixtures(cfg.FixtureDir, cfg.FixtureMode))
+}
+
+// GitHubFetcher returns a fetcher for the PRs of GitHubOwner/GitHubRepo.
+func (cfg Config) GitHubFetcher() *GitHubFetcher {
+	return NewGitHubFetcher(cfg.GitHubClient(), cfg.GitHubOwner, cfg.GitHubRepo)
+}
+
+// RepoName is the GitHub repository in owner/name form, e.g. Azure/ARO-HCP.
+func (cfg Config) RepoName() string {
+	return cfg.GitHubOwner + "/" + cfg.GitHubRepo
+}
+
 type PRChange struct {
 	Number         int
 	Title          string
 	Body           string
 	Author         string
 	CreatedAt      time.Time
+	UpdatedAt      time.Time
 	MergedAt       *time.Time
 	State          string
 	HeadCommitSHA  string
 	BaseRef        string
 	BaseSHA        string
 	MergeCommitSHA string
+	Labels         []string
+	Milestone      string
+	LinkedIssues   []string // "#12", "other/repo#12" or Jira keys such as "ARO-1234"
 }
 
 type GitHubFetcher struct {
@@ -43,23 +72,76 @@ func NewGitHubFetcher(client *github.Client, owner, repo string) *GitHubFetcher
 	return &GitHubFetcher{client: client, owner: owner, repo: repo}
 }
 
-func buildPRChange(pr *github.PullRequest) PRChange {
-	mergedAt := pr.GetMergedAt().Time
+func (f *GitHubFetcher) buildPRChange(pr *github.PullRequest) PRChange {
+	var mergedAt *time.Time
+	if pr.MergedAt != nil {
+		t := pr.GetMergedAt().Time
+		mergedAt = &t
+	}
+	var labels []string
+	for _, l := range pr.Labels {
+		labels = append(labels, l.GetName())
+	}
 	return PRChange{
 		Number:         pr.GetNumber(),
 		Title:          pr.GetTitle(),
 		Body:           pr.GetBody(),
 		Author:         pr.GetUser().GetLogin(),
 		CreatedAt:      pr.GetCreatedAt().Time,
-		MergedAt:       &mergedAt,
+		UpdatedAt:      pr.GetUpdatedAt().Time,
+		MergedAt:       mergedAt,
 		State:          pr.GetState(),
 		HeadCommitSHA:  pr.GetHead().GetSHA(),
 		BaseRef:        pr.GetBase().GetRef(),
 		BaseSHA:        pr.GetBase().GetSHA(),
 		MergeCommitSHA: pr.GetMergeCommitSHA(),
+		Labels:         labels,
+		Milestone:      pr.GetMilestone().GetTitle(),
+		LinkedIssues:   parseLinkedIssues(f.owner, f.repo, pr.GetBody()),
+	}
+}
+
+// Record converts pr into an unprocessed pr_embeddings row.
+func (pr PRChange) Record() *db.PREmbedding {
+	var updatedAt *time.Time
+	if !pr.UpdatedAt.IsZero() {
+		updatedAt = &pr.UpdatedAt
+	}
+	return &db.PREmbedding{
+		PRNumber:        pr.Number,
+		PRTitle:         pr.Title,
+		PRBody:          pr.Body,
+		Author:          pr.Author,
+		CreatedAt:       pr.CreatedAt,
+		MergedAt:        pr.MergedAt,
+		State:           pr.State,
+		BaseRef:         pr.BaseRef,
+		GithubBaseSHA:   nullableString(pr.BaseSHA),
+		HeadCommitSHA:   nullableString(pr.HeadCommitSHA),
+		MergeCommitSHA:  nullableString(pr.MergeCommitSHA),
+		GithubUpdatedAt: updatedAt,
+		Labels:          pr.Labels,
+		Milestone:       nullableString(pr.Milestone),
+		LinkedIssues:    pr.LinkedIssues,
+		IngestSource:    db.SourceIngest,
 	}
 }
 
+// FetchPR fetches a single PR, merged or not, with the issues linked to it.
+// A failed timeline lookup is logged and leaves the PR with the issues its
+// body links.
+func (f *GitHubFetcher) FetchPR(ctx context.Context, number int) (PRChange, error) {
+	pr, _, err := f.client.PullRequests.Get(ctx, f.owner, f.repo, number)
+	if err != nil {
+		return PRChange{}, err
+	}
+	change := f.buildPRChange(pr)
+	if err := f.AddTimelineIssues(ctx, &change); err != nil {
+		log.Printf("fetch PR #%d: %v", number, err)
+	}
+	return change, nil
+}
+
 type FetchResult struct {
 	PRs       []PRChange
 	NextPage  int
@@ -90,7 +172,7 @@ func (f *GitHubFetcher) FetchBatch(ctx context.Context, page int) (*FetchResult,
 		if pr.MergedAt == nil {
 			continue
 		}
-		results = append(results, buildPRChange(pr))
+		results = append(results, f.buildPRChange(pr))
 	}
 
 	return &FetchResult{
@@ -100,3 +182,32 @@ func (f *GitHubFetcher) FetchBatch(ctx context.Context, page int) (*FetchResult,
 		PageCount: len(prs),
 	}, nil
 }
+
+// FetchSince returns page of the merged PRs FetchBatch lists, keeping those
+// merged after watermark, or at it with a number above lastNumber. Merging
+// updates a PR, so no later page can hold such a PR once a page reaches PRs
+// last updated before watermark; HasMore is cleared there.
+func (f *GitHubFetcher) FetchSince(ctx context.Context, watermark time.Time, lastNumber, page int) (*FetchResult, error) {
+	result, err := f.FetchBatch(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	return sinceWatermark(result, watermark, lastNumber), nil
+}
+
+func sinceWatermark(result *FetchResult, watermark time.Time, lastNumber int) *FetchResult {
+	var kept []PRChange
+	for _, pr := range result.PRs {
+		if pr.UpdatedAt.Before(watermark) {
+			result.HasMore, result.NextPage = false, 0
+		}
+		if pr.MergedAt == nil {
+			continue
+		}
+		if pr.MergedAt.After(watermark) || (pr.MergedAt.Equal(watermark) && pr.Number > lastNumber) {
+			kept = append(kept, pr)
+		}
+	}
+	result.PRs = kept
+	return result
+}
@@ -0,0 +1,81 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v66/github"
+)
+
+var (
+	// closingRef matches GitHub closing keywords: "Fixes #12", "closes Azure/ARO-HCP#34".
+	closingRef = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+([\w.-]+/[\w.-]+)?#(\d+)\b`)
+	// issueURL matches links to GitHub issues.
+	issueURL = regexp.MustCompile(`https://github\.com/([\w.-]+/[\w.-]+)/issues/(\d+)\b`)
+	// jiraURL matches links to Jira issues, e.g. https://issues.redhat.com/browse/ARO-1234.
+	jiraURL = regexp.MustCompile(`https?://[\w.-]+/browse/([A-Z][A-Z0-9]+-\d+)\b`)
+)
+
+// issueRef formats a GitHub issue reference: "#12" within owner/repo,
+// "other/repo#12" elsewhere.
+func issueRef(owner, repo, issueRepo, number string) string {
+	if issueRepo == "" || strings.EqualFold(issueRepo, owner+"/"+repo) {
+		return "#" + number
+	}
+	return issueRepo + "#" + number
+}
+
+// parseLinkedIssues extracts the issues a PR body links to: issues closed
+// with GitHub keywords, GitHub issue URLs and Jira browse URLs.
+func parseLinkedIssues(owner, repo, body string) []string {
+	var issues []string
+	for _, m := range closingRef.FindAllStringSubmatch(body, -1) {
+		issues = append(issues, issueRef(owner, repo, m[1], m[2]))
+	}
+	for _, m := range issueURL.FindAllStringSubmatch(body, -1) {
+		issues = append(issues, issueRef(owner, repo, m[1], m[2]))
+	}
+	for _, m := range jiraURL.FindAllStringSubmatch(body, -1) {
+		issues = append(issues, m[1])
+	}
+	return mergeIssues(nil, issues)
+}
+
+// mergeIssues adds extra to issues, sorted and without duplicates.
+func mergeIssues(issues, extra []string) []string {
+	merged := slices.Concat(issues, extra)
+	slices.Sort(merged)
+	return slices.Compact(merged)
+}
+
+// AddTimelineIssues adds to pr.LinkedIssues the issues whose timeline
+// cross-references it, as reported by the PR timeline API.
+func (f *GitHubFetcher) AddTimelineIssues(ctx context.Context, pr *PRChange) error {
+	var issues []string
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		events, resp, err := f.client.Issues.ListIssueTimeline(ctx, f.owner, f.repo, pr.Number, opts)
+		if err != nil {
+			return fmt.Errorf("timeline of PR #%d: %w", pr.Number, err)
+		}
+		for _, e := range events {
+			if e.GetEvent() != "cross-referenced" || e.GetSource().GetIssue() == nil {
+				continue
+			}
+			issue := e.GetSource().GetIssue()
+			if issue.IsPullRequest() {
+				continue
+			}
+			issues = append(issues, issueRef(f.owner, f.repo, issue.GetRepository().GetFullName(), fmt.Sprint(issue.GetNumber())))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	pr.LinkedIssues = mergeIssues(pr.LinkedIssues, issues)
+	return nil
+}
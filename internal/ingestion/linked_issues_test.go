@@ -0,0 +1,49 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseLinkedIssues(t *testing.T) {
+	body := `Fixes #12, closes Azure/ARO-HCP#7 and resolves openshift/hypershift#99.
+
+See https://github.com/Azure/ARO-HCP/issues/12 and https://issues.redhat.com/browse/ARO-1234.
+Not an issue: #45 or PR https://github.com/Azure/ARO-HCP/pull/3.`
+	got := parseLinkedIssues("Azure", "ARO-HCP", body)
+	want := []string{"#12", "#7", "ARO-1234", "openshift/hypershift#99"}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseLinkedIssues = %v, want %v", got, want)
+	}
+	if got := parseLinkedIssues("Azure", "ARO-HCP", "no links"); len(got) != 0 {
+		t.Errorf("parseLinkedIssues without links = %v", got)
+	}
+}
+
+func TestFetchPRWithoutTimeline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/timeline") {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"number": 5, "body": "Fixes #12"}`)
+	}))
+	defer srv.Close()
+	client := NewGitHubClient("")
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	pr, err := NewGitHubFetcher(client, "Azure", "ARO-HCP").FetchPR(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("FetchPR failed on a timeline error: %v", err)
+	}
+	if pr.Number != 5 || !slices.Equal(pr.LinkedIssues, []string{"#12"}) {
+		t.Fatalf("FetchPR = %+v, want PR 5 linking #12 from its body", pr)
+	}
+}
@@ -0,0 +1,110 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/jobs"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const (
+	RunStatusQueued    = db.JobQueued
+	RunStatusRunning   = db.JobRunning
+	RunStatusSucceeded = db.JobSucceeded
+	RunStatusFailed    = db.JobFailed
+)
+
+// GeneratorFactory builds a Generator for a single run with the given config.
+type GeneratorFactory func(cfg Config) *Generator
+
+// JobStore queues and reads jobs; *db.SearchRepository implements it.
+type JobStore interface {
+	EnqueueJob(ctx context.Context, kind string, params any) (*db.Job, error)
+	GetJob(ctx context.Context, id string) (*db.Job, error)
+}
+
+// RunParams are the parameters of an ingestion job.
+type RunParams struct {
+	Mode string `json:"mode"` // execution mode: CACHE, PROCESS or FULL
+}
+
+// RunManager queues ingestion runs as jobs of kind jobs.KindIngestion and
+// runs them through Handle, so a run survives a restart of the server that
+// started it and can be polled from any replica. Only one run may be queued
+// or running at a time.
+type RunManager struct {
+	cfg     Config
+	factory GeneratorFactory
+	jobs    JobStore
+}
+
+func NewRunManager(cfg Config, store JobStore, factory GeneratorFactory) *RunManager {
+	return &RunManager{cfg: cfg, factory: factory, jobs: store}
+}
+
+// Start queues a CACHE or PROCESS run and returns its initial status.
+func (m *RunManager) Start(ctx context.Context, mode string) (types.IngestionRun, error) {
+	mode = strings.ToUpper(strings.TrimSpace(mode))
+	if mode != "CACHE" && mode != "PROCESS" {
+		return types.IngestionRun{}, fmt.Errorf("invalid mode: %s (must be CACHE or PROCESS)", mode)
+	}
+	job, err := m.jobs.EnqueueJob(ctx, jobs.KindIngestion, RunParams{Mode: mode})
+	if errors.Is(err, db.ErrJobActive) {
+		return types.IngestionRun{}, fmt.Errorf("ingestion run %s is already in progress", job.ID)
+	}
+	if err != nil {
+		return types.IngestionRun{}, err
+	}
+	return ingestionRun(job), nil
+}
+
+// Get returns the status of a run by ID.
+func (m *RunManager) Get(ctx context.Context, id string) (types.IngestionRun, bool, error) {
+	job, err := m.jobs.GetJob(ctx, id)
+	if err != nil || job == nil || job.Kind != jobs.KindIngestion {
+		return types.IngestionRun{}, false, err
+	}
+	return ingestionRun(job), true, nil
+}
+
+// Handle runs an ingestion job.
+func (m *RunManager) Handle(ctx context.Context, job *db.Job, progress jobs.Progress) error {
+	var params RunParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return fmt.Errorf("decode ingestion job params: %w", err)
+	}
+	cfg := m.cfg
+	cfg.ExecutionMode = params.Mode
+	return m.factory(cfg).WithProgress(ProgressFunc(progress)).Run(ctx)
+}
+
+func ingestionRun(job *db.Job) types.IngestionRun {
+	var params RunParams
+	_ = json.Unmarshal(job.Params, &params)
+	run := types.IngestionRun{
+		RunID:     job.ID,
+		Mode:      params.Mode,
+		Status:    job.Status,
+		Done:      job.Done,
+		Total:     job.Total,
+		StartedAt: job.CreatedAt.UTC().Format(time.RFC3339),
+		Error:     job.Error,
+	}
+	if job.StartedAt != nil {
+		run.StartedAt = job.StartedAt.UTC().Format(time.RFC3339)
+	}
+	if job.Phase != nil {
+		run.Phase = *job.Phase
+	}
+	if job.FinishedAt != nil {
+		finished := job.FinishedAt.UTC().Format(time.RFC3339)
+		run.FinishedAt = &finished
+	}
+	return run
+}
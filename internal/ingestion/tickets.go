@@ -0,0 +1,52 @@
+package ingestion
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+// TicketParser finds issue-tracker tickets such as ARO-1234 or OCPBUGS-567
+// in PR text. Only the configured projects are matched, so version strings
+// like UTF-8 or SHA-256 are not mistaken for tickets.
+type TicketParser struct {
+	pattern *regexp.Regexp
+}
+
+// NewTicketParser returns a parser for tickets of projects, or nil when
+// projects is empty.
+func NewTicketParser(projects []string) *TicketParser {
+	var quoted []string
+	for _, p := range projects {
+		if p = strings.TrimSpace(p); p != "" {
+			quoted = append(quoted, regexp.QuoteMeta(p))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+	return &TicketParser{pattern: regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)-(\d+)\b`)}
+}
+
+// Parse returns the tickets mentioned in title or body, once each, with the
+// title taking precedence as the source. A nil parser finds nothing.
+func (p *TicketParser) Parse(title, body string) []db.PRReference {
+	if p == nil {
+		return nil
+	}
+	var refs []db.PRReference
+	seen := map[string]bool{}
+	for _, text := range []struct{ source, text string }{{"title", title}, {"body", body}} {
+		for _, m := range p.pattern.FindAllStringSubmatch(text.text, -1) {
+			project := strings.ToUpper(m[1])
+			ticket := db.NormalizeTicket(project + "-" + m[2])
+			if seen[ticket] || strings.HasSuffix(ticket, "-") {
+				continue
+			}
+			seen[ticket] = true
+			refs = append(refs, db.PRReference{Ticket: ticket, Project: project, Source: text.source})
+		}
+	}
+	return refs
+}
@@ -0,0 +1,21 @@
+package ingestion
+
+import "testing"
+
+func TestTicketParser(t *testing.T) {
+	p := NewTicketParser([]string{"ARO", "OCPBUGS"})
+	refs := p.Parse("ARO-1234: fix nodepool upgrades",
+		"Backport of aro-1234 for OCPBUGS-0567.\nSee https://issues.redhat.com/browse/OCPBUGS-567, UTF-8, SHA-256 and ARO-HCP.")
+	want := []struct{ ticket, source string }{{"ARO-1234", "title"}, {"OCPBUGS-567", "body"}}
+	if len(refs) != len(want) {
+		t.Fatalf("Parse = %+v, want %v", refs, want)
+	}
+	for i, w := range want {
+		if refs[i].Ticket != w.ticket || refs[i].Source != w.source {
+			t.Errorf("ref %d = %+v, want %v", i, refs[i], w)
+		}
+	}
+	if refs := NewTicketParser(nil).Parse("ARO-1", ""); refs != nil {
+		t.Errorf("nil parser found %v", refs)
+	}
+}
@@ -0,0 +1,178 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+// RunWorker consumes the processing queue until ctx is cancelled. Each
+// iteration leases a batch of unprocessed PRs for the visibility timeout, so
+// several workers can share the queue and PRs held by a crashed worker become
+// claimable again once their lease expires.
+//
+// With a WorkerCacheInterval the workers also elect a leader that runs CACHE
+// at that interval; see leadCache.
+//
+// A PR that fails to process keeps its lease, so it is retried once the lease
+// expires rather than reclaimed at once, and a batch in which every PR failed
+// backs the worker off exponentially from WorkerPollInterval up to the
+// visibility timeout. Failed analyses are not retried (RetryFailed does not
+// apply here); use PROCESS mode with --retry-failed for those.
+func (g *Generator) RunWorker(ctx context.Context) error {
+	log.Printf("worker mode: consuming processing queue as %s (batch=%d, visibility=%s, poll=%s)",
+		g.cfg.WorkerID, g.cfg.WorkerBatchSize, g.cfg.WorkerVisibilityTimeout, g.cfg.WorkerPollInterval)
+
+	analyzer, err := g.newAnalyzer()
+	if err != nil {
+		return err
+	}
+
+	if g.cfg.WorkerCacheInterval > 0 {
+		if g.db == nil {
+			return fmt.Errorf("worker: leader election for CACHE runs needs a database")
+		}
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.leadCache(ctx)
+		}()
+		// Wait for the leader to release its lock before returning.
+		defer wg.Wait()
+	}
+
+	failedBatches := 0
+	for {
+		prs, err := g.repo.ClaimUnprocessedPRs(ctx, g.cfg.WorkerID, g.cfg.WorkerBatchSize, g.cfg.WorkerVisibilityTimeout, time.Time{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("worker: claim failed: %v", err)
+		}
+
+		if len(prs) == 0 {
+			if !sleepContext(ctx, g.cfg.WorkerPollInterval) {
+				log.Printf("worker: shutting down")
+				return nil
+			}
+			continue
+		}
+
+		log.Printf("worker: claimed %d PR(s)", len(prs))
+		processed := 0
+		for idx, pr := range prs {
+			if ctx.Err() != nil {
+				g.releaseClaims(prs[idx:])
+				log.Printf("worker: shutting down")
+				return nil
+			}
+			if err := g.processSinglePR(ctx, pr, analyzer); err != nil {
+				log.Printf("worker: error processing PR #%d: %v", pr.PRNumber, err)
+				continue
+			}
+			processed++
+		}
+		if processed > 0 {
+			failedBatches = 0
+			continue
+		}
+		failedBatches++
+		wait := workerBackoff(g.cfg.WorkerPollInterval, g.cfg.WorkerVisibilityTimeout, failedBatches)
+		log.Printf("worker: every PR in the batch failed; backing off for %s", wait)
+		if !sleepContext(ctx, wait) {
+			log.Printf("worker: shutting down")
+			return nil
+		}
+	}
+}
+
+// workerBackoff is the wait after the nth consecutive batch in which every
+// PR failed: base doubled each time, capped at limit.
+func workerBackoff(base, limit time.Duration, n int) time.Duration {
+	wait := base
+	for i := 1; i < n && wait < limit; i++ {
+		wait *= 2
+	}
+	return min(wait, limit)
+}
+
+// cacheLeaderLock names the advisory lock electing the worker that runs CACHE.
+const cacheLeaderLock = "intelhub:ingest:cache"
+
+// leadCache runs CACHE every WorkerCacheInterval while this worker holds the
+// leader lock, and otherwise polls for it, until ctx is done. Only one of the
+// workers sharing a database caches PRs from GitHub, so replicas neither
+// multiply API calls nor store the same PRs twice; processing stays shared
+// through the claims of RunWorker.
+func (g *Generator) leadCache(ctx context.Context) {
+	for {
+		lead, err := g.db.TryLeadership(ctx, cacheLeaderLock)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("worker: leader election: %v", err)
+		}
+		if lead != nil {
+			log.Printf("worker: %s is now the CACHE leader (every %s)", g.cfg.WorkerID, g.cfg.WorkerCacheInterval)
+			err := g.cacheWhileLeader(ctx, lead)
+			if rerr := lead.Release(); rerr != nil {
+				log.Printf("worker: release leader lock: %v", rerr)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("worker: lost CACHE leadership: %v", err)
+		}
+		if !sleepContext(ctx, g.cfg.WorkerPollInterval) {
+			return
+		}
+	}
+}
+
+// cacheWhileLeader runs CACHE at once and then every WorkerCacheInterval,
+// checking the lock every WorkerPollInterval in between. It returns when ctx
+// is done or the lock is lost.
+func (g *Generator) cacheWhileLeader(ctx context.Context, lead *db.Leadership) error {
+	next := time.Now()
+	for {
+		if err := lead.Check(ctx); err != nil {
+			return err
+		}
+		if !time.Now().Before(next) {
+			if err := g.RunCache(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("worker: cache run failed: %v", err)
+			}
+			next = time.Now().Add(g.cfg.WorkerCacheInterval)
+		}
+		if !sleepContext(ctx, min(g.cfg.WorkerPollInterval, time.Until(next))) {
+			return ctx.Err()
+		}
+	}
+}
+
+// releaseClaims returns unfinished PRs to the queue. It uses a fresh context
+// because it typically runs after the worker context has been cancelled.
+func (g *Generator) releaseClaims(prs []*db.PREmbedding) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, pr := range prs {
+		if err := g.repo.ReleasePRClaim(ctx, pr.PRNumber, g.cfg.WorkerID); err != nil {
+			log.Printf("release claim for PR #%d: %v", pr.PRNumber, err)
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
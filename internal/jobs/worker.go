@@ -0,0 +1,164 @@
+// Package jobs runs long-running work queued in the jobs table. Jobs are
+// queued by MCP tools and CLIs with SearchRepository.EnqueueJob and run by a
+// Worker in any process with a handler for their kind, so they survive
+// restarts and their progress is visible everywhere.
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+const (
+	defaultPoll       = 5 * time.Second
+	defaultStaleAfter = 2 * time.Minute
+)
+
+// Job kinds.
+const (
+	KindIngestion = "ingestion" // trigger_ingestion runs
+	KindCluster   = "cluster"   // ingest cluster --async
+	KindDocs      = "docs"      // ingest controller
+)
+
+// progressInterval bounds how often a job's progress is written while its
+// phase does not change.
+const progressInterval = time.Second
+
+// Progress reports the completed and total work items of a job's phase.
+type Progress func(phase string, done, total int)
+
+// Handler runs a job. ctx is cancelled when the worker stops.
+type Handler func(ctx context.Context, job *db.Job, progress Progress) error
+
+// Store persists jobs; *db.SearchRepository implements it.
+type Store interface {
+	ClaimJob(ctx context.Context, worker string, kinds []string) (*db.Job, error)
+	UpdateJobProgress(ctx context.Context, id, worker, phase string, done, total int) error
+	HeartbeatJob(ctx context.Context, id, worker string) error
+	FinishJob(ctx context.Context, id, worker string, runErr error) error
+	RequeueStaleJobs(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int, int, error)
+}
+
+// Worker claims queued jobs of the kinds it has handlers for and runs them
+// one at a time.
+type Worker struct {
+	Store    Store
+	Handlers map[string]Handler
+	// ID names the worker in the jobs table; hostname-pid when empty.
+	ID string
+	// Poll is how often the queue is checked when idle; 5s when 0.
+	Poll time.Duration
+	// StaleAfter is how long a running job may go without a heartbeat
+	// before it is taken back from its worker; 2m when 0. Heartbeats are
+	// sent at a quarter of it.
+	StaleAfter time.Duration
+	// MaxAttempts fails a job taken back that many times instead of
+	// requeuing it.
+	MaxAttempts int
+}
+
+// Run processes jobs until ctx is done. A job interrupted by ctx stays
+// running until it goes stale, and is then requeued for another worker.
+func (w *Worker) Run(ctx context.Context) {
+	id := w.workerID()
+	kinds := make([]string, 0, len(w.Handlers))
+	for kind := range w.Handlers {
+		kinds = append(kinds, kind)
+	}
+	poll := cmp.Or(w.Poll, defaultPoll)
+	staleAfter := cmp.Or(w.StaleAfter, defaultStaleAfter)
+	log.Printf("jobs: worker %s running %v jobs", id, kinds)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		if requeued, failed, err := w.Store.RequeueStaleJobs(ctx, staleAfter, max(w.MaxAttempts, 1)); err != nil {
+			log.Printf("jobs: %v", err)
+		} else if requeued+failed > 0 {
+			log.Printf("jobs: took back %d stale jobs (%d requeued, %d failed)", requeued+failed, requeued, failed)
+		}
+		job, err := w.Store.ClaimJob(ctx, id, kinds)
+		if err != nil {
+			log.Printf("jobs: claim: %v", err)
+		}
+		if job == nil {
+			timer.Reset(poll)
+			continue
+		}
+		w.run(ctx, id, job, staleAfter)
+		timer.Reset(0)
+	}
+}
+
+func (w *Worker) run(ctx context.Context, id string, job *db.Job, staleAfter time.Duration) {
+	log.Printf("jobs: %s job %s started (attempt %d)", job.Kind, job.ID, job.Attempts)
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go w.heartbeat(runCtx, id, job.ID, staleAfter/4)
+
+	var (
+		mu        sync.Mutex
+		lastPhase string
+		lastWrite time.Time
+	)
+	progress := func(phase string, done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if phase == lastPhase && done < total && time.Since(lastWrite) < progressInterval {
+			return
+		}
+		lastPhase, lastWrite = phase, time.Now()
+		if err := w.Store.UpdateJobProgress(runCtx, job.ID, id, phase, done, total); err != nil {
+			log.Printf("jobs: %s job %s: record progress: %v", job.Kind, job.ID, err)
+		}
+	}
+	err := w.Handlers[job.Kind](runCtx, job, progress)
+	if ctx.Err() != nil {
+		log.Printf("jobs: %s job %s interrupted; it is requeued once stale", job.Kind, job.ID)
+		return
+	}
+	if ferr := w.Store.FinishJob(ctx, job.ID, id, err); ferr != nil {
+		log.Printf("jobs: %s job %s: record outcome: %v", job.Kind, job.ID, ferr)
+	}
+	if err != nil {
+		log.Printf("jobs: %s job %s failed: %v", job.Kind, job.ID, err)
+		return
+	}
+	log.Printf("jobs: %s job %s succeeded", job.Kind, job.ID)
+}
+
+func (w *Worker) heartbeat(ctx context.Context, id, jobID string, interval time.Duration) {
+	ticker := time.NewTicker(max(interval, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Store.HeartbeatJob(ctx, jobID, id); err != nil && ctx.Err() == nil {
+				log.Printf("jobs: heartbeat job %s: %v", jobID, err)
+			}
+		}
+	}
+}
+
+func (w *Worker) workerID() string {
+	if w.ID != "" {
+		return w.ID
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+type fakeStore struct {
+	mu       sync.Mutex
+	queue    []*db.Job
+	progress []string
+	finished map[string]error
+	done     chan struct{}
+}
+
+func (s *fakeStore) ClaimJob(ctx context.Context, worker string, kinds []string) (*db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return nil, nil
+	}
+	job := s.queue[0]
+	s.queue = s.queue[1:]
+	return job, nil
+}
+
+func (s *fakeStore) UpdateJobProgress(ctx context.Context, id, worker, phase string, done, total int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress = append(s.progress, phase)
+	return nil
+}
+
+func (s *fakeStore) HeartbeatJob(ctx context.Context, id, worker string) error { return nil }
+
+func (s *fakeStore) FinishJob(ctx context.Context, id, worker string, runErr error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished[id] = runErr
+	if len(s.finished) == 2 {
+		close(s.done)
+	}
+	return nil
+}
+
+func (s *fakeStore) RequeueStaleJobs(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int, int, error) {
+	return 0, 0, nil
+}
+
+func TestWorkerRun(t *testing.T) {
+	store := &fakeStore{
+		queue:    []*db.Job{{ID: "a", Kind: KindCluster}, {ID: "b", Kind: KindCluster}},
+		finished: map[string]error{},
+		done:     make(chan struct{}),
+	}
+	failure := errors.New("no PRs")
+	w := &Worker{Store: store, ID: "test", Poll: time.Millisecond, Handlers: map[string]Handler{
+		KindCluster: func(ctx context.Context, job *db.Job, progress Progress) error {
+			// Updates within progressInterval are dropped unless the phase
+			// changes or it completes.
+			for i := range 10 {
+				progress("cluster", i, 10)
+			}
+			progress("cluster", 10, 10)
+			if job.ID == "b" {
+				return failure
+			}
+			return nil
+		},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+	select {
+	case <-store.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("jobs not finished")
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if err := store.finished["a"]; err != nil {
+		t.Errorf("job a finished with %v, want success", err)
+	}
+	if err := store.finished["b"]; !errors.Is(err, failure) {
+		t.Errorf("job b finished with %v, want %v", err, failure)
+	}
+	if len(store.progress) != 4 {
+		t.Errorf("recorded %d progress updates, want 2 per job", len(store.progress))
+	}
+}
@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+type requestIDKey struct{}
+
+var defaultLogger = sync.OnceValue(func() Logger { return New(DefaultLogger()) })
+
+// NewRequestID returns a random identifier for correlating the log lines of
+// one request.
+func NewRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// WithRequestID stores id in ctx so loggers derived with ForContext tag their
+// lines with it.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ForContext returns the logger tagged with the request ID carried by ctx.
+func (l Logger) ForContext(ctx context.Context) Logger {
+	if id := RequestID(ctx); id != "" {
+		return l.WithValues("request_id", id)
+	}
+	return l
+}
+
+// FromContext returns the module default logger tagged with the request ID
+// carried by ctx, for code paths that are not handed a Logger.
+func FromContext(ctx context.Context) Logger {
+	return defaultLogger().ForContext(ctx)
+}
@@ -0,0 +1,23 @@
+package logging
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRequestIDRoundTrip(t *testing.T) {
+	if got := RequestID(context.Background()); got != "" {
+		t.Fatalf("RequestID on empty context = %q, want empty", got)
+	}
+	id := NewRequestID()
+	if len(id) != 16 {
+		t.Fatalf("NewRequestID() = %q, want 16 hex chars", id)
+	}
+	if other := NewRequestID(); other == id {
+		t.Fatalf("NewRequestID returned %q twice", id)
+	}
+	ctx := WithRequestID(context.Background(), id)
+	if got := RequestID(ctx); got != id {
+		t.Fatalf("RequestID = %q, want %q", got, id)
+	}
+}
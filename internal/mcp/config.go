@@ -1,24 +1,49 @@
 package mcp
 
 import (
+	"context"
+	"io"
 	"log"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/server"
 
+	"github.com/roivaz/aro-hcp-intelhub/internal/ask"
 	"github.com/roivaz/aro-hcp-intelhub/internal/config"
 	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/docs"
+	"github.com/roivaz/aro-hcp-intelhub/internal/eval"
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
 	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion"
 	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion/embeddings"
+	"github.com/roivaz/aro-hcp-intelhub/internal/jobs"
 	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
 	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools"
+	"github.com/roivaz/aro-hcp-intelhub/internal/releasenotes"
+	"github.com/roivaz/aro-hcp-intelhub/internal/settings"
+	"github.com/roivaz/aro-hcp-intelhub/internal/topics"
 	"github.com/roivaz/aro-hcp-intelhub/internal/traceimages"
 )
 
 type Config struct {
 	ToolAdapters map[string]ToolAdapter
-	Options      []server.StreamableHTTPOption
-	Database     *db.Database
+	// Transport selects the HTTP handler: "sse" serves SSEOptions, anything
+	// else streamable HTTP with Options. stdio uses neither.
+	Transport  string
+	Options    []server.StreamableHTTPOption
+	SSEOptions []server.SSEOption
+	Database   *db.Database
+	// Closers are closed by Server.Close before the database.
+	Closers []io.Closer
+	// Background tasks are started by Server.Run and stop when its context
+	// is cancelled.
+	Background []func(ctx context.Context)
+	// ToolTimeout bounds every tool call unless ToolTimeouts names the tool;
+	// 0 means no budget.
+	ToolTimeout  time.Duration
+	ToolTimeouts map[string]time.Duration
 }
 
 func DefaultConfig() Config {
@@ -27,22 +52,69 @@ func DefaultConfig() Config {
 		log.Fatalf("failed to load ingestion config: %v", err)
 	}
 
-	database, err := db.NewDatabase(db.Config{DSN: ingestionCfg.PostgresURL})
+	database, err := db.NewDatabase(settings.Database(ingestionCfg.PostgresURL))
 	if err != nil {
 		log.Fatalf("failed to connect database: %v", err)
 	}
 
-	repo := db.NewSearchRepository(database, db.WithTraceCacheMax(config.TraceCacheMaxEntries()))
-	embedClient := embeddings.NewClient(ingestionCfg.OllamaURL, ingestionCfg.EmbeddingModel, ingestionCfg.LLMCallTimeout)
-	searchService := tools.NewDBSearchService(repo, embedClient)
-	detailsService := tools.NewDBDetailsService(repo)
+	repo := db.NewSearchRepository(database,
+		db.WithRepoURL(config.RepoURL()),
+		db.WithTraceCacheMax(config.TraceCacheMaxEntries()),
+		db.WithTraceCacheTTL(config.TraceCacheTTL()),
+		db.WithEmbeddingModel(ingestionCfg.EmbeddingModel, ingestionCfg.EmbeddingDim),
+		db.WithQuantization(ingestionCfg.Quantization, ingestionCfg.RerankCandidates),
+		db.WithDistanceMetric(ingestionCfg.DistanceMetric),
+		db.WithPRSearchWeights(config.PRSearchTextWeight(), config.PRSearchDescriptionWeight()))
+	if err := repo.RegisterEmbeddingModel(context.Background()); err != nil {
+		log.Fatalf("failed to register embedding model: %v", err)
+	}
+	var background []func(ctx context.Context)
+	if config.MCPIndexWarmup() {
+		threshold := config.MCPWarmupThreshold()
+		background = append(background, func(ctx context.Context) { warmUpIndexes(ctx, repo, threshold) })
+	}
+	embedClient, err := ingestion.NewEmbedder(ingestionCfg, embeddings.WithAutoPull(ingestionCfg.OllamaAutoPull))
+	if err != nil {
+		log.Fatalf("failed to initialise embeddings client: %v", err)
+	}
+	// Searches and in-process ingestion share Ollama; searches go first.
+	dispatcher := embeddings.NewDispatcher(embedClient, config.EmbeddingConcurrency())
+	toolTimeouts, err := ParseToolTimeouts(config.MCPToolTimeouts())
+	if err != nil {
+		log.Fatalf("invalid %s: %v", config.KeyMCPToolTimeouts, err)
+	}
+	searchService := tools.NewDBSearchService(repo, dispatcher.Embedder(embeddings.Interactive))
+	if cache := tools.NewSearchCache(config.SearchCacheTTL(), config.SearchCacheMaxEntries()); cache != nil {
+		searchService.Cache = cache
+		// Ingestion runs in other processes too, so changes arrive over
+		// LISTEN/NOTIFY rather than from the in-process run manager.
+		background = append(background, func(ctx context.Context) {
+			if err := database.ListenCorpusChanges(ctx, cache.Invalidate); err != nil {
+				log.Printf("search cache invalidation disabled: %v", err)
+			}
+		})
+	}
+	fetcher := ingestionCfg.GitHubFetcher()
+	detailsService := tools.NewDBDetailsService(repo, fetcher)
 
 	baseLogger := logging.DefaultLogger()
 	traceTracer, err := traceimages.NewTracer(traceimages.Config{
-		RepoPath:   filepath.Join(config.CacheDir(), "aro-hcp-repo"),
-		SkopeoPath: config.TraceSkopeoPath(),
-		PullSecret: config.TracePullSecret(),
-		Logger:     logging.New(baseLogger.WithName("trace")),
+		RepoPath:           filepath.Join(config.CacheDir(), "aro-hcp-repo"),
+		RepoURL:            config.RepoURL(),
+		SkopeoPath:         config.TraceSkopeoPath(),
+		PullSecret:         config.TracePullSecret(),
+		MaxTagCandidates:   config.TraceMaxTagCandidates(),
+		SkopeoOnly:         config.TraceSkopeoOnly(),
+		InspectConcurrency: config.TraceInspectConcurrency(),
+		InspectTimeout:     config.TraceInspectTimeout(),
+		RegistryTimeout:    config.TraceRegistryTimeout(),
+		RegistryCooldown:   config.TraceRegistryCooldown(),
+		WorktreePoolSize:   config.TraceWorktreePoolSize(),
+		RenderCommand:      config.TraceRenderCommand(),
+		RenderTimeout:      config.TraceRenderTimeout(),
+		SyftPath:           config.TraceSyftPath(),
+		Git:                settings.Git(),
+		Logger:             logging.New(baseLogger.WithName("trace")),
 	})
 	if err != nil {
 		log.Fatalf("failed to init trace tracer: %v", err)
@@ -50,18 +122,127 @@ func DefaultConfig() Config {
 
 	traceService := traceimages.New(traceTracer, repo, logging.New(baseLogger.WithName("traceimages")))
 	traceAdapter := tools.NewTraceImagesServiceAdapter(traceService)
+	componentCommits := &traceimages.CommitLog{
+		CacheDir: config.CacheDir(),
+		RepoURL:  config.RepoURL(),
+		RepoPath: filepath.Join(config.CacheDir(), "aro-hcp-repo"),
+		Git:      settings.Git(),
+	}
+	if repos := config.TraceComponentRepos(); repos != "" {
+		componentCommits.AllowedRepos = strings.Split(repos, ",")
+	}
+
+	runManager := ingestion.NewRunManager(ingestionCfg, repo, func(cfg ingestion.Config) *ingestion.Generator {
+		return ingestion.NewGenerator(cfg, database, repo, dispatcher.Embedder(embeddings.Background), fetcher)
+	})
+	if config.JobsWorker() {
+		labeler, err := topics.NewLabeler(config.DiffAnalysisOllamaURL(), config.DiffAnalysisModel(), ingestionCfg.LLMCallTimeout)
+		if err != nil {
+			log.Fatalf("failed to init topic labeler: %v", err)
+		}
+		worker := &jobs.Worker{
+			Store: repo,
+			Handlers: map[string]jobs.Handler{
+				jobs.KindIngestion: runManager.Handle,
+				jobs.KindCluster:   topics.JobHandler(repo, ingestionCfg.EmbeddingModel, labeler),
+			},
+			ID:          ingestionCfg.WorkerID,
+			Poll:        config.JobsPollInterval(),
+			StaleAfter:  config.JobsStaleAfter(),
+			MaxAttempts: config.JobsMaxAttempts(),
+		}
+		background = append(background, worker.Run)
+	}
+
+	repoClone := gitrepo.New(gitrepo.RepoConfig{Settings: settings.Git(), Path: filepath.Join(config.CacheDir(), "aro-hcp-repo")})
+	searchService.Git = repoClone
+	commitContext := tools.NewDBCommitContextService(repo, searchService, repoClone, traceimages.Environments())
+
+	if config.EvalEnabled() {
+		scheduler := &eval.Scheduler{
+			Searcher:  searchService,
+			Repo:      repo,
+			CasesFile: config.EvalCasesFile(),
+			K:         config.EvalK(),
+			Hour:      config.EvalHour(),
+		}
+		background = append(background, scheduler.Run)
+	}
+	if interval := config.GitCacheGCInterval(); interval > 0 {
+		gc := &gitrepo.CacheGC{Dir: config.CacheDir(), MaxIdle: config.GitCacheMaxIdle(), Interval: interval, Backend: config.GitBackend()}
+		background = append(background, gc.Run)
+	}
+
+	adapters := map[string]ToolAdapter{
+		"search_prs":              &tools.SearchPRsHandler{Service: searchService, MinSimilarity: config.SearchMinSimilarity()},
+		"get_pr_details":          &tools.GetPRDetailsHandler{Service: detailsService},
+		"trace_images":            &tools.TraceImagesHandler{Service: traceAdapter},
+		"trace_component_commits": &tools.TraceComponentCommitsHandler{Service: componentCommits},
+		"search_docs":             &tools.SearchDocsHandler{Service: searchService, MinSimilarity: config.SearchMinSimilarity()},
+		"search_all":              &tools.SearchAllHandler{Service: searchService},
+		"search_code":             &tools.SearchCodeHandler{Service: searchService, Languages: docs.CodeLanguages},
+		"search_config":           &tools.SearchCodeHandler{Service: searchService, Languages: docs.ConfigLanguages},
+		"search_pr_diffs":         &tools.SearchPRDiffsHandler{Service: searchService},
+		"correlate_incident":      &tools.CorrelateIncidentHandler{Service: searchService},
+		"list_prs":                &tools.ListPRsHandler{Service: tools.NewDBPRLister(repo)},
+		"find_prs_for_ticket":     &tools.FindPRsForTicketHandler{Service: tools.NewDBTicketService(repo)},
+		"find_similar_prs":        &tools.FindSimilarPRsHandler{Service: tools.NewDBSimilarPRService(repo)},
+		"list_pr_topics":          &tools.ListPRTopicsHandler{Service: tools.NewDBTopicService(repo)},
+		"trigger_ingestion":       &tools.TriggerIngestionHandler{Service: runManager, AdminToken: config.MCPAdminToken()},
+		"get_ingestion_run":       &tools.GetIngestionRunHandler{Service: runManager},
+		"list_jobs":               &tools.ListJobsHandler{Service: repo},
+		"feedback":                &tools.FeedbackHandler{Service: repo},
+		"list_failed_analyses":    &tools.ListFailedAnalysesHandler{Service: tools.NewDBFailedAnalysesService(repo), AdminToken: config.MCPAdminToken()},
+		"get_hub_stats":           &tools.GetHubStatsHandler{Service: tools.NewDBHubStatsService(repo)},
+		"list_indexed_sources":    &tools.ListIndexedSourcesHandler{Service: repo},
+		"commit_context":          &tools.CommitContextHandler{Service: commitContext},
+		"find_pr_for_commit":      &tools.FindPRForCommitHandler{Service: tools.NewDBCommitPRResolver(repo, repoClone)},
+		"get_deployment":          &tools.GetDeploymentHandler{Service: tools.NewDBDeploymentService(repo)},
+		"release_notes":           &tools.ReleaseNotesHandler{Service: &releasenotes.Generator{Git: repoClone, Repo: repo, Deployments: repo}},
+	}
+	if config.AskEnabled() {
+		answerer, err := ask.NewAnswerer(config.DiffAnalysisOllamaURL(), config.AskModel(), ingestionCfg.LLMCallTimeout)
+		if err != nil {
+			log.Fatalf("failed to init ask_intelhub: %v", err)
+		}
+		adapters["ask_intelhub"] = &tools.AskIntelHubHandler{Service: tools.NewDBAskService(searchService, answerer)}
+	}
 
 	return Config{
-		ToolAdapters: map[string]ToolAdapter{
-			"search_prs":     &tools.SearchPRsHandler{Service: searchService},
-			"get_pr_details": &tools.GetPRDetailsHandler{Service: detailsService},
-			"trace_images":   &tools.TraceImagesHandler{Service: traceAdapter},
-			"search_docs":    &tools.SearchDocsHandler{Service: searchService},
-		},
-		Options: []server.StreamableHTTPOption{
-			server.WithEndpointPath("/mcp/jsonrpc"),
-			server.WithStateLess(true),
-		},
-		Database: database,
+		ToolAdapters: adapters,
+		Transport:    config.MCPTransport(),
+		Options:      streamableOptions(),
+		SSEOptions:   sseOptions(),
+		Database:     database,
+		Closers:      []io.Closer{traceTracer},
+		Background:   background,
+		ToolTimeout:  config.MCPToolTimeout(),
+		ToolTimeouts: toolTimeouts,
+	}
+}
+
+// streamableOptions serves streamable HTTP on /mcp/jsonrpc, stateless unless
+// MCP_STATEFUL is set.
+func streamableOptions() []server.StreamableHTTPOption {
+	opts := []server.StreamableHTTPOption{server.WithEndpointPath("/mcp/jsonrpc")}
+	if !config.MCPStateful() {
+		return append(opts, server.WithStateLess(true))
+	}
+	return append(opts,
+		server.WithSessionIdManager(newSessionManager(config.MCPSessionIdleTimeout(), config.MCPMaxSessions())),
+		server.WithHeartbeatInterval(config.MCPHeartbeatInterval()),
+	)
+}
+
+// sseOptions serves the SSE stream on /mcp/sse and client messages on
+// /mcp/message.
+func sseOptions() []server.SSEOption {
+	opts := []server.SSEOption{
+		server.WithStaticBasePath("/mcp"),
+		server.WithBaseURL(config.MCPBaseURL()),
+	}
+	if keepAlive := config.MCPSSEKeepAlive(); keepAlive > 0 {
+		opts = append(opts, server.WithKeepAliveInterval(keepAlive))
 	}
+	return opts
 }
@@ -2,13 +2,24 @@ package mcp
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
 	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+	"github.com/roivaz/aro-hcp-intelhub/internal/telemetry"
 )
 
 type ToolAdapter interface {
@@ -17,22 +28,41 @@ type ToolAdapter interface {
 
 type Server struct {
 	MCP     *server.MCPServer
-	HTTP    *server.StreamableHTTPServer
+	HTTP    *server.StreamableHTTPServer // nil when serving SSE
+	SSE     *server.SSEServer            // nil unless serving SSE
 	Handler http.Handler
 	DB      *db.Database
+
+	closers    []io.Closer
+	background []func(ctx context.Context)
+
+	mu       sync.Mutex
+	draining bool
+	calls    sync.WaitGroup
+	// abort is cancelled when Shutdown gives up waiting, cancelling the
+	// contexts of the tool calls still running.
+	abort      context.Context
+	abortCalls context.CancelFunc
 }
 
+// errShuttingDown is returned to tool calls that arrive while draining.
+const errShuttingDown = "server is shutting down; retry the call"
+
 func New(cfg Config) *Server {
 	mcpServer := server.NewMCPServer(
 		"aro-hcp-server",
 		"1.0.0",
 		server.WithToolCapabilities(true),
 	)
+	s := &Server{MCP: mcpServer, DB: cfg.Database, closers: cfg.Closers, background: cfg.Background}
+	s.abort, s.abortCalls = context.WithCancel(context.Background())
 
 	// Register tools with their proper schemas using mcp-go builder pattern
 	toolDefinitions := map[string]mcp.Tool{
 		"search_docs": mcp.NewTool("search_docs",
-			mcp.WithDescription("Semantic search across documentation using embeddings. Returns relevant documentation chunks with similarity scores from the ARO-HCP repository."),
+			mcp.WithDescription("Semantic search across documentation using embeddings. Returns relevant documentation chunks with similarity scores from the ARO-HCP repository. Each result carries start_line/end_line and a source_url linking to those lines, for precise citations."),
+			readOnlyTool("Search documentation"),
+			mcp.WithOutputSchema[types.SearchDocsResponse](),
 			mcp.WithString("query",
 				mcp.Required(),
 				mcp.Description("Natural language search query (e.g., 'How does cluster creation work?')"),
@@ -41,17 +71,152 @@ func New(cfg Config) *Server {
 				mcp.Description("Maximum number of results to return (default: 10)"),
 			),
 			mcp.WithString("component",
-				mcp.Description("Optional: Filter results by component name (e.g., 'cluster-service', 'maestro')"),
+				mcp.Description("Optional: Filter results by the component of the source repo: its repository name (e.g., 'ARO-HCP', 'hypershift') unless ingestion named it"),
 			),
 			mcp.WithString("repo",
 				mcp.Description("Optional: Filter results by repository URL"),
 			),
+			mcp.WithString("doc_type",
+				mcp.Description("Optional: Filter results by document type"),
+				mcp.Enum("readme", "docs", "adr", "runbook", "other"),
+			),
+			mcp.WithString("path_prefix",
+				mcp.Description("Optional: Only return chunks from files under this repo-relative path (e.g., 'docs/sre/')"),
+			),
+			mcp.WithString("alert_name",
+				mcp.Description("Optional: Only return runbooks/ADRs whose front matter lists this alert (exact name, e.g., 'FrontendDown')"),
+			),
 			mcp.WithBoolean("include_full_file",
 				mcp.Description("Include full file content in results (default: false)"),
 			),
+			mcp.WithNumber("context_chunks",
+				mcp.Description("Optional: Also return up to this many chunks before and after each hit, from the same file (max 5, default: 0)"),
+			),
+			mcp.WithNumber("min_similarity",
+				mcp.Description("Optional: Drop results whose similarity (from 0 to 1; 0.5 is unrelated) is below this. Defaults to the server's SEARCH_MIN_SIMILARITY; 0 returns the top results regardless of relevance"),
+			),
+			mcp.WithString("search_quality",
+				mcp.Description("Optional: Trade latency for recall in the vector index scan: fast, balanced (default) or high. Try high when expected results are missing, especially with filters"),
+				mcp.Enum("fast", "balanced", "high"),
+			),
+			mcp.WithString("cursor",
+				mcp.Description("Optional: next_cursor from a previous response with the same query and filters, to fetch the next page"),
+			),
+		),
+		"search_code": mcp.NewTool("search_code",
+			mcp.WithDescription("Semantic search across Go and TypeScript source code ingested with 'ingest code'. Returns whole declarations (functions, methods, types) with their file, line range and a source_url linking to those lines. Use this for implementation questions the documentation does not cover."),
+			readOnlyTool("Search source code"),
+			mcp.WithOutputSchema[types.SearchCodeResponse](),
+			mcp.WithString("query",
+				mcp.Required(),
+				mcp.Description("Natural language or identifier query (e.g., 'where is the cluster deletion timeout enforced')"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of results to return (default: 10)"),
+			),
+			mcp.WithString("repo",
+				mcp.Description("Optional: Filter results by repository (e.g., 'Azure/ARO-HCP')"),
+			),
+			mcp.WithString("language",
+				mcp.Description("Optional: Filter results by language"),
+				mcp.Enum("go", "typescript"),
+			),
+			mcp.WithString("path_prefix",
+				mcp.Description("Optional: Only return declarations from files under this repo-relative path (e.g., 'frontend/pkg/')"),
+			),
+			mcp.WithString("search_quality",
+				mcp.Description("Optional: Trade latency for recall in the vector index scan: fast, balanced (default) or high. Try high when expected results are missing, especially with filters"),
+				mcp.Enum("fast", "balanced", "high"),
+			),
+			mcp.WithString("cursor",
+				mcp.Description("Optional: next_cursor from a previous response with the same query and filters, to fetch the next page"),
+			),
+		),
+		"search_pr_diffs": mcp.NewTool("search_pr_diffs",
+			mcp.WithDescription("Semantic search across the merge diffs of processed PRs, one file (or part of a large file) per result, generated files excluded. Use this to find PRs by what their code changes do rather than by their title or description, e.g. which PRs touched retry handling in the frontend. Diffs are stored when diff analysis is enabled during ingestion."),
+			readOnlyTool("Search PR diffs"),
+			mcp.WithOutputSchema[types.SearchPRDiffsResponse](),
+			mcp.WithString("query",
+				mcp.Required(),
+				mcp.Description("Natural language or code query (e.g., 'add exponential backoff to the maestro client')"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of results to return (default: 10)"),
+			),
+			mcp.WithNumber("pr_number",
+				mcp.Description("Optional: Only search the diff of this PR"),
+			),
+			mcp.WithString("path_prefix",
+				mcp.Description("Optional: Only return changes to files under this repo-relative path (e.g., 'frontend/pkg/')"),
+			),
+			mcp.WithString("search_quality",
+				mcp.Description("Optional: Trade latency for recall in the vector index scan: fast, balanced (default) or high. Try high when expected results are missing, especially with filters"),
+				mcp.Enum("fast", "balanced", "high"),
+			),
+			mcp.WithString("cursor",
+				mcp.Description("Optional: next_cursor from a previous response with the same query and filters, to fetch the next page"),
+			),
+		),
+		"search_config": mcp.NewTool("search_config",
+			mcp.WithDescription("Semantic search across ARO-HCP configuration ingested with 'ingest config': config/ YAML, dev-infrastructure/ Bicep and Helm values, and pipeline definitions. YAML results are subtrees labelled with their dotted key path (symbol), e.g. 'clouds.public.environments.stg.defaults.maestro.image'; Bicep results are whole param, var, resource, module and output declarations. Use this to find where a setting, image digest or SKU is defined for an environment."),
+			readOnlyTool("Search configuration"),
+			mcp.WithOutputSchema[types.SearchCodeResponse](),
+			mcp.WithString("query",
+				mcp.Required(),
+				mcp.Description("Natural language or key query (e.g., 'where is the maestro image digest defined for stg')"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of results to return (default: 10)"),
+			),
+			mcp.WithString("repo",
+				mcp.Description("Optional: Filter results by repository (e.g., 'Azure/ARO-HCP')"),
+			),
+			mcp.WithString("language",
+				mcp.Description("Optional: Filter results by file type"),
+				mcp.Enum("yaml", "bicep"),
+			),
+			mcp.WithString("path_prefix",
+				mcp.Description("Optional: Only return blocks from files under this repo-relative path (e.g., 'config/', 'dev-infrastructure/')"),
+			),
+			mcp.WithString("search_quality",
+				mcp.Description("Optional: Trade latency for recall in the vector index scan: fast, balanced (default) or high. Try high when expected results are missing, especially with filters"),
+				mcp.Enum("fast", "balanced", "high"),
+			),
+			mcp.WithString("cursor",
+				mcp.Description("Optional: next_cursor from a previous response with the same query and filters, to fetch the next page"),
+			),
+		),
+		"ask_intelhub": mcp.NewTool("ask_intelhub",
+			mcp.WithDescription("Answer a question about ARO-HCP in prose, composed by an LLM from the best-matching PRs and documentation chunks (as ranked by search_all). Statements cite their sources inline as Markdown links, [PR #1234](url) or [D2](url), and the cited sources are listed in citations. Slower than the search tools; prefer those when you will read the sources yourself."),
+			readOnlyTool("Ask IntelHub"),
+			mcp.WithOutputSchema[types.AskResponse](),
+			mcp.WithString("question",
+				mcp.Required(),
+				mcp.Description("The question to answer (e.g., 'How are hosted cluster upgrades rolled out across regions?')"),
+			),
+			mcp.WithNumber("max_sources",
+				mcp.Description("Maximum PRs and documentation chunks to answer from (default: 6, max: 20)"),
+			),
+		),
+		"search_all": mcp.NewTool("search_all",
+			mcp.WithDescription("Semantic search across pull requests and documentation at once. Returns PRs and documentation chunks interleaved by a score comparable across both, each tagged with source_type (pr or doc). Use search_prs or search_docs for filters and pagination."),
+			readOnlyTool("Search PRs and documentation"),
+			mcp.WithOutputSchema[types.SearchAllResponse](),
+			mcp.WithString("query",
+				mcp.Required(),
+				mcp.Description("Natural language search query (e.g., 'How are cluster upgrades rolled out?')"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of results to return across both sources (default: 10, max: 50)"),
+			),
+			mcp.WithNumber("body_max_chars",
+				mcp.Description("Maximum characters of each PR body to return (default: 2000, 0 = full body)"),
+			),
 		),
 		"search_prs": mcp.NewTool("search_prs",
 			mcp.WithDescription("Semantic search across pull requests using embeddings. Returns relevant PRs with similarity scores, titles, descriptions, and metadata."),
+			readOnlyTool("Search pull requests"),
+			mcp.WithOutputSchema[types.SearchPRsResponse](),
 			mcp.WithString("query",
 				mcp.Required(),
 				mcp.Description("Natural language search query (e.g., 'PRs related to authentication')"),
@@ -59,16 +224,293 @@ func New(cfg Config) *Server {
 			mcp.WithNumber("limit",
 				mcp.Description("Maximum number of results to return (default: 10)"),
 			),
+			mcp.WithNumber("body_max_chars",
+				mcp.Description("Maximum characters of each PR body to return (default: 2000, 0 = full body). Truncated bodies set is_truncated; use get_pr_details for the full text."),
+			),
+			mcp.WithBoolean("include_analysis",
+				mcp.Description("Include each PR's analysis status (pending, succeeded, failed) and LLM-generated rich description (default: false)"),
+			),
+			mcp.WithNumber("analysis_max_chars",
+				mcp.Description("Maximum characters of each rich description when include_analysis is set (default: 4000, 0 = full text)"),
+			),
+			mcp.WithArray("labels",
+				mcp.Description("Optional: Only return PRs carrying all of these GitHub labels (e.g., ['area/frontend'])"),
+				mcp.WithStringItems(),
+			),
+			mcp.WithString("milestone",
+				mcp.Description("Optional: Only return PRs in this GitHub milestone (title, case-insensitive)"),
+			),
+			mcp.WithString("linked_issue",
+				mcp.Description("Optional: Only return PRs linked to this issue: a GitHub issue number ('1234' or '#1234'), 'org/repo#12' for other repositories, or a Jira key ('ARO-1234')"),
+			),
+			mcp.WithNumber("min_similarity",
+				mcp.Description("Optional: Drop results whose similarity (from 0 to 1; 0.5 is unrelated) is below this. Defaults to the server's SEARCH_MIN_SIMILARITY; 0 returns the top results regardless of relevance"),
+			),
+			mcp.WithString("search_quality",
+				mcp.Description("Optional: Trade latency for recall in the vector index scan: fast, balanced (default) or high. Try high when expected results are missing, especially with filters"),
+				mcp.Enum("fast", "balanced", "high"),
+			),
+			mcp.WithString("cursor",
+				mcp.Description("Optional: next_cursor from a previous response with the same query and filters, to fetch the next page"),
+			),
 		),
 		"get_pr_details": mcp.NewTool("get_pr_details",
-			mcp.WithDescription("Retrieve detailed information about a specific pull request by its number, including title, body, status, and metadata."),
+			mcp.WithDescription("Retrieve detailed information about a specific pull request by its number, including title, body, status, metadata and, once analysed, the size of its merge diff (additions, deletions, changed files, top-level directories)."),
+			cachingTool("Get pull request details"),
+			mcp.WithOutputSchema[types.Result[types.PRResult]](),
 			mcp.WithNumber("pr_number",
 				mcp.Required(),
 				mcp.Description("The pull request number (e.g., 1234)"),
 			),
+			mcp.WithBoolean("live",
+				mcp.Description("Fetch the PR from GitHub when it is not in the database or not yet merged there, storing it for later analysis. The result's source is github_live when this happens (default: false)"),
+			),
+		),
+		"correlate_incident": mcp.NewTool("correlate_incident",
+			mcp.WithDescription("Correlate an incident description with the pull requests that reached an environment in a time window. With environment set, candidates are the PRs shipped by that environment's recorded deployments in the window, each listed with the PRs it shipped; otherwise they are the PRs merged in the window. Candidates are ranked by semantic similarity to the incident text."),
+			readOnlyTool("Correlate incident with merged PRs"),
+			mcp.WithOutputSchema[types.CorrelateIncidentResponse](),
+			mcp.WithString("incident",
+				mcp.Required(),
+				mcp.Description("Free-form incident description, alert text or symptoms (e.g., 'cluster creation stuck in provisioning in westus3')"),
+			),
+			mcp.WithString("environment",
+				mcp.Description("Optional: Environment the incident happened in; candidates are limited to the PRs its deployments shipped in the window"),
+				mcp.Enum("dev", "stg", "prod", "int"),
+			),
+			mcp.WithString("window_start",
+				mcp.Description("Optional: RFC3339 start of the window (default: window_end minus lookback_hours)"),
+			),
+			mcp.WithString("window_end",
+				mcp.Description("Optional: RFC3339 end of the window, usually the incident start time (default: now)"),
+			),
+			mcp.WithNumber("lookback_hours",
+				mcp.Description("Optional: Hours to look back from window_end when window_start is not set (default: 72)"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of candidate PRs to return (default: 10)"),
+			),
+		),
+		"find_prs_for_ticket": mcp.NewTool("find_prs_for_ticket",
+			mcp.WithDescription("Find the pull requests whose title or body mentions an issue-tracker ticket (ARO-1234, OCPBUGS-567, ...), most recently merged first. Use this to jump from a bug ID in an incident to the code changes behind it."),
+			readOnlyTool("Find pull requests for a ticket"),
+			mcp.WithOutputSchema[types.FindPRsForTicketResponse](),
+			mcp.WithString("ticket",
+				mcp.Required(),
+				mcp.Description("Jira key (e.g., 'OCPBUGS-12345') or its issues.redhat.com/browse URL"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of PRs to return (default: 50, max: 500)"),
+			),
+			mcp.WithNumber("body_max_chars",
+				mcp.Description("Maximum characters of each PR body to return (default: 2000, 0 = full body)"),
+			),
+		),
+		"find_similar_prs": mcp.NewTool("find_similar_prs",
+			mcp.WithDescription("Find the merged pull requests most similar to a given PR, using its stored embedding rather than a text query. Use this when reviewing a PR to find prior art, earlier attempts at the same change, or changes that may have caused or fixed a related regression. The PR must already be processed."),
+			readOnlyTool("Find similar pull requests"),
+			mcp.WithOutputSchema[types.FindSimilarPRsResponse](),
+			mcp.WithNumber("pr_number",
+				mcp.Required(),
+				mcp.Description("PR number to find neighbours of"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of PRs to return (default: 10, max: 500)"),
+			),
+			mcp.WithNumber("body_max_chars",
+				mcp.Description("Maximum characters of each PR body to return (default: 2000, 0 = full body)"),
+			),
+		),
+		"list_pr_topics": mcp.NewTool("list_pr_topics",
+			mcp.WithDescription("Browse the themes of merged pull requests, such as 'Network policy changes'. Without topic_id, lists every topic with its PR count, largest first; with topic_id, returns that topic and its PRs, most recently merged first. Topics come from clustering PR embeddings offline with 'ingest cluster'."),
+			readOnlyTool("List pull request topics"),
+			mcp.WithOutputSchema[types.ListPRTopicsResponse](),
+			mcp.WithNumber("topic_id",
+				mcp.Description("Optional: topic to list the PRs of, from a previous call without topic_id"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of PRs to return with topic_id (default: 50, max: 500)"),
+			),
+			mcp.WithNumber("body_max_chars",
+				mcp.Description("Maximum characters of each PR body to return (default: 2000, 0 = full body)"),
+			),
+		),
+		"list_prs": mcp.NewTool("list_prs",
+			mcp.WithDescription("List pull requests merged in a time window, most recent first, optionally filtered by author. Use this instead of search_prs for time-based questions such as 'what merged yesterday evening'."),
+			readOnlyTool("List merged pull requests"),
+			mcp.WithOutputSchema[types.ListPRsResponse](),
+			mcp.WithString("merged_after",
+				mcp.Description("Optional: RFC3339 lower bound on merge time (inclusive)"),
+			),
+			mcp.WithString("merged_before",
+				mcp.Description("Optional: RFC3339 upper bound on merge time (inclusive)"),
+			),
+			mcp.WithString("author",
+				mcp.Description("Optional: GitHub login of the PR author"),
+			),
+			mcp.WithNumber("min_lines_changed",
+				mcp.Description("Optional: Only PRs whose merge diff adds plus deletes at least this many lines. PRs not yet analysed have no diff statistics and are excluded."),
+			),
+			mcp.WithArray("within_dirs",
+				mcp.Description("Optional: Only PRs touching nothing outside these top-level directories, '.' for files at the repository root (e.g., ['dev-infrastructure', 'config'] for infra-only PRs)"),
+				mcp.WithStringItems(),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of PRs to return (default: 50, max: 500)"),
+			),
+			mcp.WithNumber("body_max_chars",
+				mcp.Description("Maximum characters of each PR body to return (default: 2000, 0 = full body)"),
+			),
+		),
+		"trigger_ingestion": mcp.NewTool("trigger_ingestion",
+			mcp.WithDescription("Admin only: queue an asynchronous PR ingestion run. CACHE fetches new PR metadata from GitHub; PROCESS generates analyses and embeddings for cached PRs. Runs are durable jobs that resume after a server restart. Returns a run ID to poll with get_ingestion_run."),
+			adminTool("Trigger ingestion run"),
+			mcp.WithOutputSchema[types.Result[types.IngestionRun]](),
+			mcp.WithString("mode",
+				mcp.Required(),
+				mcp.Description("Ingestion mode to run"),
+				mcp.Enum("CACHE", "PROCESS"),
+			),
+			mcp.WithString("admin_token",
+				mcp.Required(),
+				mcp.Description("Admin token configured on the server via MCP_ADMIN_TOKEN"),
+			),
+		),
+		"get_ingestion_run": mcp.NewTool("get_ingestion_run",
+			mcp.WithDescription("Get the status and progress of an ingestion run started with trigger_ingestion."),
+			readOnlyTool("Get ingestion run"),
+			mcp.WithOutputSchema[types.Result[types.IngestionRun]](),
+			mcp.WithString("run_id",
+				mcp.Required(),
+				mcp.Description("Run ID returned by trigger_ingestion"),
+			),
+		),
+		"list_jobs": mcp.NewTool("list_jobs",
+			mcp.WithDescription("List background jobs, newest first, with their status and progress: ingestion runs started with trigger_ingestion and topic clustering queued with 'ingest cluster --async'. Jobs are stored in the database and survive server restarts. With job_id, returns that job only."),
+			readOnlyTool("List background jobs"),
+			mcp.WithOutputSchema[types.ListJobsResponse](),
+			mcp.WithString("job_id",
+				mcp.Description("Optional: job to return, such as a run ID returned by trigger_ingestion"),
+			),
+			mcp.WithString("kind",
+				mcp.Description("Optional: only list jobs of this kind"),
+				mcp.Enum("ingestion", "cluster"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of jobs to return (default: 20, max: 200)"),
+			),
+		),
+		"feedback": mcp.NewTool("feedback",
+			mcp.WithDescription("Mark a PR returned by search_prs (or another PR tool) as helpful or unhelpful, either as a search result for a query or for the quality of its AI-generated rich description. Feedback is aggregated to tune ranking, prompts and models."),
+			recordingTool("Give feedback"),
+			mcp.WithOutputSchema[types.Result[types.FeedbackReceipt]](),
+			mcp.WithNumber("pr_number",
+				mcp.Required(),
+				mcp.Description("PR the feedback is about"),
+			),
+			mcp.WithBoolean("helpful",
+				mcp.Required(),
+				mcp.Description("true if the result or description was helpful, false if not"),
+			),
+			mcp.WithString("target",
+				mcp.Description("What is being rated (default: search_result)"),
+				mcp.Enum("search_result", "rich_description"),
+			),
+			mcp.WithString("query",
+				mcp.Description("Optional: the search query that returned the PR"),
+			),
+			mcp.WithString("comment",
+				mcp.Description("Optional: why it was or was not helpful"),
+			),
+		),
+		"list_failed_analyses": mcp.NewTool("list_failed_analyses",
+			mcp.WithDescription("List PRs whose diff analysis failed, grouped by failure category (timeout, large_diff, error, ...) with the largest group first. Pass requeue with PR numbers and the admin token to mark those failures for reprocessing by the next PROCESS run."),
+			maintenanceTool("List failed analyses"),
+			mcp.WithOutputSchema[types.FailedAnalysesResponse](),
+			mcp.WithString("failure_category",
+				mcp.Description("Optional: only report this failure category"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum PRs listed per category, most recently processed first (default: 20, max: 200)"),
+			),
+			mcp.WithArray("requeue",
+				mcp.Description("Optional: PR numbers whose failed analysis should be retried; requires admin_token"),
+				mcp.WithNumberItems(),
+			),
+			mcp.WithString("admin_token",
+				mcp.Description("Admin token configured on the server via MCP_ADMIN_TOKEN; required with requeue"),
+			),
+		),
+		"commit_context": mcp.NewTool("commit_context",
+			mcp.WithDescription("Everything known about a commit in one call: the PR that contains it (with its AI-generated rich description), changed components, cached trace_images results per environment, and related documentation. Use this as the first step of incident triage for a suspect commit."),
+			readOnlyTool("Commit context"),
+			mcp.WithOutputSchema[types.Result[types.CommitContext]](),
+			mcp.WithString("sha",
+				mcp.Required(),
+				mcp.Description("Full 40-character commit SHA (merge or head commit of a PR)"),
+			),
+		),
+		"find_pr_for_commit": mcp.NewTool("find_pr_for_commit",
+			mcp.WithDescription("Resolve the pull request that introduced any commit SHA, including commits inside a PR branch or abbreviated SHAs from alerts. Matches stored merge/head SHAs first, then walks main's history in the local clone."),
+			readOnlyTool("Find PR for commit"),
+			mcp.WithOutputSchema[types.Result[types.CommitPRResolution]](),
+			mcp.WithString("sha",
+				mcp.Required(),
+				mcp.Description("Commit SHA, full or abbreviated"),
+			),
+		),
+		"get_deployment": mcp.NewTool("get_deployment",
+			mcp.WithDescription("Report which ARO-HCP commit an environment was running at a point in time (e.g. 'what was in prod at 14:00 UTC'), with the PR that commit merged and the preceding deployments. Requires deployments recorded by 'ingest deployments'."),
+			readOnlyTool("Get deployment"),
+			mcp.WithOutputSchema[types.GetDeploymentResponse](),
+			mcp.WithString("environment",
+				mcp.Required(),
+				mcp.Description("Deployment environment"),
+				mcp.Enum("dev", "stg", "prod", "int"),
+			),
+			mcp.WithString("at",
+				mcp.Description("Optional: RFC3339 point in time (default: now)"),
+			),
+			mcp.WithNumber("history",
+				mcp.Description("Number of deployments to return, most recent first (default: 5)"),
+			),
+		),
+		"release_notes": mcp.NewTool("release_notes",
+			mcp.WithDescription("Generate a markdown changelog of the PRs merged between two ARO-HCP commits, or between the commits two environments run (e.g. what stg has that prod does not), grouped by component with summaries from the stored PR analyses."),
+			readOnlyTool("Release notes"),
+			mcp.WithOutputSchema[types.ReleaseNotesResponse](),
+			mcp.WithString("from_sha",
+				mcp.Description("Older commit SHA or ref (exclusive); use with to_sha"),
+			),
+			mcp.WithString("to_sha",
+				mcp.Description("Newer commit SHA or ref (inclusive); use with from_sha"),
+			),
+			mcp.WithString("from_environment",
+				mcp.Description("Environment running the older commit (e.g. prod); use with to_environment"),
+				mcp.Enum("dev", "stg", "prod", "int"),
+			),
+			mcp.WithString("to_environment",
+				mcp.Description("Environment running the newer commit (e.g. stg); use with from_environment"),
+				mcp.Enum("dev", "stg", "prod", "int"),
+			),
+		),
+		"list_indexed_sources": mcp.NewTool("list_indexed_sources",
+			mcp.WithDescription("List the repositories and components whose documentation is indexed, with their doc types, file and chunk counts, last commit indexed and last update time. Use the repo, component and doc_type values as search_docs filters instead of guessing them."),
+			readOnlyTool("List indexed documentation sources"),
+			mcp.WithOutputSchema[types.ListIndexedSourcesResponse](),
+		),
+		"get_hub_stats": mcp.NewTool("get_hub_stats",
+			mcp.WithDescription("Report corpus statistics (PRs ingested, processed, failed and pending; documentation chunks) and the trend of recent retrieval-quality eval runs (recall@k and MRR)."),
+			readOnlyTool("Hub statistics"),
+			mcp.WithOutputSchema[types.Result[types.HubStats]](),
+			mcp.WithNumber("eval_runs",
+				mcp.Description("Number of recent eval runs to include (default: 10)"),
+			),
 		),
 		"trace_images": mcp.NewTool("trace_images",
-			mcp.WithDescription("Trace container images used in deployments for a specific commit and environment. Returns image references, tags, and deployment manifests."),
+			mcp.WithDescription("Trace container images used in deployments for a specific commit and environment. Returns image references, tags, and deployment manifests, and for each component the config file and YAML key that set its digest with the commit and PR that last changed it."),
+			cachingTool("Trace images"),
+			mcp.WithOutputSchema[types.TraceImagesResult](),
 			mcp.WithString("commit_sha",
 				mcp.Required(),
 				mcp.Description("Git commit SHA to trace images from (full 40-character SHA)"),
@@ -78,27 +520,217 @@ func New(cfg Config) *Server {
 				mcp.Description("Deployment environment"),
 				mcp.Enum("dev", "stg", "prod", "int"),
 			),
+			mcp.WithBoolean("force_refresh",
+				mcp.Description("Bypass the trace cache and re-trace, replacing the cached entry (default: false)"),
+			),
+			mcp.WithBoolean("include_sbom",
+				mcp.Description("Add each component's SBOM summary: package count and notable CVE-relevant packages such as openssl, glibc and the Go stdlib (default: false; slower on a first request)"),
+			),
+		),
+		"trace_component_commits": mcp.NewTool("trace_component_commits",
+			mcp.WithDescription("List what is inside a traced component image: the commits of the component's source repository (Hypershift, Maestro, Cluster Service, ...) up to the source SHA trace_images reported for it, newest first. Pass since_sha, e.g. the source SHA another environment runs, to get only the commits between the two. The repository is cloned on first use, so a first call can be slow."),
+			cachingTool("Trace component commits"),
+			mcp.WithOutputSchema[types.ComponentCommitsResponse](),
+			mcp.WithString("source_sha",
+				mcp.Required(),
+				mcp.Description("Source commit the image was built from (source_sha in trace_images results)"),
+			),
+			mcp.WithString("component",
+				mcp.Description("Component name as returned by trace_images (e.g. 'Hypershift'); used to find the source repository"),
+			),
+			mcp.WithString("source_repo_url",
+				mcp.Description("Optional: Source repository URL (source_repo_url in trace_images results); overrides the one known for component. Only https URLs of known component repositories are accepted"),
+			),
+			mcp.WithString("since_sha",
+				mcp.Description("Optional: Older source commit (exclusive); only commits after it are listed"),
+			),
+			mcp.WithNumber("max_commits",
+				mcp.Description("Maximum commits to return (default: 30, max: 200)"),
+			),
 		),
 	}
 
+	toolLog := logging.New(logging.DefaultLogger()).WithName("mcp")
+	for name := range cfg.ToolTimeouts {
+		if _, ok := toolDefinitions[name]; !ok {
+			toolLog.Info("ignoring timeout for unknown tool", "tool", name)
+		}
+	}
 	for name, adapter := range cfg.ToolAdapters {
 		tool := toolDefinitions[name]
 		mcpServer.AddTool(tool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return adapter.ToolAdapter(ctx, req)
+			if !s.beginCall() {
+				return mcp.NewToolResultError(errShuttingDown), nil
+			}
+			defer s.calls.Done()
+			budget := cfg.ToolTimeout
+			if d, ok := cfg.ToolTimeouts[name]; ok {
+				budget = d
+			}
+			var cancel context.CancelFunc
+			if budget > 0 {
+				ctx, cancel = context.WithTimeout(ctx, budget)
+			} else {
+				ctx, cancel = context.WithCancel(ctx)
+			}
+			defer cancel()
+			defer context.AfterFunc(s.abort, cancel)()
+
+			// The HTTP middleware normally assigns the ID; fall back for other transports.
+			if logging.RequestID(ctx) == "" {
+				ctx = logging.WithRequestID(ctx, logging.NewRequestID())
+			}
+			log := toolLog.ForContext(ctx).WithValues("tool", name)
+			ctx, span := telemetry.Start(ctx, "mcp.tool "+name,
+				attribute.String("mcp.tool", name),
+				attribute.String("request_id", logging.RequestID(ctx)),
+			)
+
+			start := time.Now()
+			log.Debug("tool call started")
+			result, err := adapter.ToolAdapter(ctx, req)
+			if budget > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				log.Info("tool call exceeded its budget", "budget", budget.String(), "error", fmt.Sprint(err))
+				result, err = overBudget(name, budget, result, err), nil
+			}
+			switch {
+			case err != nil:
+				log.Error(err, "tool call failed", "elapsed", time.Since(start).String())
+			case result != nil && result.IsError:
+				span.SetStatus(codes.Error, "tool returned an error result")
+				log.Info("tool call returned an error result", "elapsed", time.Since(start).String())
+			default:
+				log.Info("tool call completed", "elapsed", time.Since(start).String())
+			}
+			telemetry.End(span, err)
+			return result, err
 		})
 	}
 
-	httpServer := server.NewStreamableHTTPServer(mcpServer, cfg.Options...)
+	if cfg.Transport == "sse" {
+		s.SSE = server.NewSSEServer(mcpServer, cfg.SSEOptions...)
+		s.Handler = s.SSE
+		return s
+	}
+
+	s.HTTP = server.NewStreamableHTTPServer(mcpServer, cfg.Options...)
+	s.Handler = s.HTTP
+	return s
+}
+
+// beginCall registers a tool call unless the server is draining.
+func (s *Server) beginCall() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.draining {
+		return false
+	}
+	s.calls.Add(1)
+	return true
+}
+
+// Shutdown stops accepting tool calls and waits for those in flight. When
+// ctx ends first, the remaining calls are cancelled and ctx's error is
+// returned. Call Close afterwards to release the database and worktrees.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.calls.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.abortCalls()
+		return ctx.Err()
+	}
+}
+
+// readOnlyTool annotates a tool that only reads the hub's database and local
+// clones.
+func readOnlyTool(title string) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{
+		Title:           title,
+		ReadOnlyHint:    mcp.ToBoolPtr(true),
+		DestructiveHint: mcp.ToBoolPtr(false),
+		IdempotentHint:  mcp.ToBoolPtr(true),
+		OpenWorldHint:   mcp.ToBoolPtr(false),
+	})
+}
+
+// cachingTool annotates a tool that may call GitHub or a registry and store
+// what it fetched, without changing anything already ingested.
+func cachingTool(title string) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{
+		Title:           title,
+		ReadOnlyHint:    mcp.ToBoolPtr(false),
+		DestructiveHint: mcp.ToBoolPtr(false),
+		IdempotentHint:  mcp.ToBoolPtr(true),
+		OpenWorldHint:   mcp.ToBoolPtr(true),
+	})
+}
+
+// recordingTool annotates a tool that appends a record; every call adds
+// another.
+func recordingTool(title string) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{
+		Title:           title,
+		ReadOnlyHint:    mcp.ToBoolPtr(false),
+		DestructiveHint: mcp.ToBoolPtr(false),
+		IdempotentHint:  mcp.ToBoolPtr(false),
+		OpenWorldHint:   mcp.ToBoolPtr(false),
+	})
+}
+
+// maintenanceTool annotates a tool that can reset ingestion state in the
+// database; repeating a call has no further effect.
+func maintenanceTool(title string) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{
+		Title:           title,
+		ReadOnlyHint:    mcp.ToBoolPtr(false),
+		DestructiveHint: mcp.ToBoolPtr(false),
+		IdempotentHint:  mcp.ToBoolPtr(true),
+		OpenWorldHint:   mcp.ToBoolPtr(false),
+	})
+}
+
+// adminTool annotates a tool that starts work with side effects; every call
+// starts another run.
+func adminTool(title string) mcp.ToolOption {
+	return mcp.WithToolAnnotation(mcp.ToolAnnotation{
+		Title:           title,
+		ReadOnlyHint:    mcp.ToBoolPtr(false),
+		DestructiveHint: mcp.ToBoolPtr(false),
+		IdempotentHint:  mcp.ToBoolPtr(false),
+		OpenWorldHint:   mcp.ToBoolPtr(true),
+	})
+}
 
-	return &Server{
-		MCP:     mcpServer,
-		HTTP:    httpServer,
-		Handler: httpServer,
-		DB:      cfg.Database,
+// Run runs the background tasks until ctx is cancelled and all of them have
+// returned.
+func (s *Server) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, task := range s.background {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			task(ctx)
+		}()
 	}
+	wg.Wait()
 }
 
 func (s *Server) Close() {
+	for _, c := range s.closers {
+		if err := c.Close(); err != nil {
+			log.Printf("error closing %T: %v", c, err)
+		}
+	}
 	if s.DB != nil {
 		if err := s.DB.Close(); err != nil {
 			log.Printf("error closing database: %v", err)
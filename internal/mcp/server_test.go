@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrainsCalls(t *testing.T) {
+	s := New(Config{})
+	if !s.beginCall() {
+		t.Fatal("call refused before shutdown")
+	}
+
+	finished := make(chan error, 1)
+	go func() { finished <- s.Shutdown(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	if s.beginCall() {
+		t.Error("call accepted while draining")
+	}
+	select {
+	case <-finished:
+		t.Fatal("Shutdown returned with a call in flight")
+	default:
+	}
+	s.calls.Done()
+	if err := <-finished; err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+}
+
+func TestShutdownCancelsCallsAfterGrace(t *testing.T) {
+	s := New(Config{})
+	s.beginCall()
+	defer s.calls.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want deadline exceeded", err)
+	}
+	if s.abort.Err() == nil {
+		t.Error("in-flight calls were not cancelled")
+	}
+}
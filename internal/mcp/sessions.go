@@ -0,0 +1,100 @@
+package mcp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+const sessionIDPrefix = "mcp-session-"
+
+var errMissingSession = errors.New("missing session id")
+
+// sessionManager issues and tracks streamable HTTP session IDs when the
+// server runs stateful. Sessions idle longer than idleTimeout, evicted to
+// stay under maxSessions, or issued before a restart are reported as
+// terminated, which tells clients to initialize a new one.
+type sessionManager struct {
+	idleTimeout time.Duration // 0 keeps sessions until deleted or evicted
+	maxSessions int           // 0 = unlimited
+	now         func() time.Time
+
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+}
+
+func newSessionManager(idleTimeout time.Duration, maxSessions int) *sessionManager {
+	return &sessionManager{
+		idleTimeout: idleTimeout,
+		maxSessions: maxSessions,
+		now:         time.Now,
+		lastSeen:    make(map[string]time.Time),
+	}
+}
+
+func (m *sessionManager) Generate() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	id := sessionIDPrefix + hex.EncodeToString(b[:])
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.pruneLocked(now)
+	if m.maxSessions > 0 && len(m.lastSeen) >= m.maxSessions {
+		m.evictOldestLocked()
+	}
+	m.lastSeen[id] = now
+	return id
+}
+
+func (m *sessionManager) Validate(sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, errMissingSession
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	last, ok := m.lastSeen[sessionID]
+	if !ok {
+		return true, nil
+	}
+	now := m.now()
+	if m.expired(last, now) {
+		delete(m.lastSeen, sessionID)
+		return true, nil
+	}
+	m.lastSeen[sessionID] = now
+	return false, nil
+}
+
+func (m *sessionManager) Terminate(sessionID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.lastSeen, sessionID)
+	return false, nil
+}
+
+func (m *sessionManager) expired(last, now time.Time) bool {
+	return m.idleTimeout > 0 && now.Sub(last) > m.idleTimeout
+}
+
+func (m *sessionManager) pruneLocked(now time.Time) {
+	for id, last := range m.lastSeen {
+		if m.expired(last, now) {
+			delete(m.lastSeen, id)
+		}
+	}
+}
+
+func (m *sessionManager) evictOldestLocked() {
+	var oldest string
+	var oldestSeen time.Time
+	for id, last := range m.lastSeen {
+		if oldest == "" || last.Before(oldestSeen) {
+			oldest, oldestSeen = id, last
+		}
+	}
+	delete(m.lastSeen, oldest)
+}
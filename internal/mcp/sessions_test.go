@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionManager(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	m := newSessionManager(10*time.Minute, 2)
+	m.now = func() time.Time { return now }
+
+	a := m.Generate()
+	if !strings.HasPrefix(a, sessionIDPrefix) {
+		t.Fatalf("session id %q lacks prefix", a)
+	}
+	if terminated, err := m.Validate(a); err != nil || terminated {
+		t.Fatalf("fresh session: terminated=%v err=%v", terminated, err)
+	}
+	if _, err := m.Validate(""); err == nil {
+		t.Error("empty session id should be invalid")
+	}
+	if terminated, _ := m.Validate(sessionIDPrefix + "unknown"); !terminated {
+		t.Error("unknown session should be reported terminated")
+	}
+
+	now = now.Add(5 * time.Minute)
+	b := m.Generate()
+	now = now.Add(time.Minute)
+	if terminated, _ := m.Validate(a); terminated {
+		t.Error("validating refreshes the idle timer; a should be live")
+	}
+
+	// At capacity: the least recently seen session (b) is evicted.
+	c := m.Generate()
+	if terminated, _ := m.Validate(b); !terminated {
+		t.Error("b should have been evicted")
+	}
+	if len(m.lastSeen) != 2 {
+		t.Errorf("%d sessions tracked, want 2", len(m.lastSeen))
+	}
+
+	now = now.Add(11 * time.Minute)
+	if terminated, _ := m.Validate(c); !terminated {
+		t.Error("idle session should expire")
+	}
+
+	d := m.Generate()
+	if _, err := m.Terminate(d); err != nil {
+		t.Fatal(err)
+	}
+	if terminated, _ := m.Validate(d); !terminated {
+		t.Error("deleted session should be terminated")
+	}
+}
@@ -0,0 +1,43 @@
+package mcp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+)
+
+// ParseToolTimeouts parses comma-separated tool=duration pairs such as
+// "trace_images=120s,search_docs=10s". A duration of 0 disables the budget
+// for that tool.
+func ParseToolTimeouts(spec string) (map[string]time.Duration, error) {
+	timeouts, err := config.ParseDurationPairs(spec)
+	if err != nil {
+		return nil, fmt.Errorf("tool timeout %w", err)
+	}
+	return timeouts, nil
+}
+
+// overBudget turns the outcome of a tool call whose budget expired into a
+// result for the client. A successful result is whatever the tool gathered
+// before the deadline, so it is returned flagged as partial in _meta and in
+// an extra text block; anything else becomes an error result naming the
+// budget instead of a bare context error.
+func overBudget(tool string, budget time.Duration, result *mcp.CallToolResult, err error) *mcp.CallToolResult {
+	if err != nil || result == nil || result.IsError {
+		return mcp.NewToolResultError(fmt.Sprintf("%s did not finish within its %s budget; narrow the request or retry later", tool, budget))
+	}
+	if result.Meta == nil {
+		result.Meta = &mcp.Meta{}
+	}
+	if result.Meta.AdditionalFields == nil {
+		result.Meta.AdditionalFields = map[string]any{}
+	}
+	result.Meta.AdditionalFields["partial"] = true
+	result.Meta.AdditionalFields["budget"] = budget.String()
+	result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf(
+		"Partial result: %s exceeded its %s budget; entries that did not finish report an error.", tool, budget)))
+	return result
+}
@@ -0,0 +1,45 @@
+package mcp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestParseToolTimeouts(t *testing.T) {
+	got, err := ParseToolTimeouts(" trace_images=120s, search_docs=10s,,get_hub_stats=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{"trace_images": 120 * time.Second, "search_docs": 10 * time.Second, "get_hub_stats": 0}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %s, want %s", k, got[k], v)
+		}
+	}
+	for _, bad := range []string{"trace_images", "=10s", "search_docs=soon", "search_docs=-1s"} {
+		if _, err := ParseToolTimeouts(bad); err == nil {
+			t.Errorf("ParseToolTimeouts(%q) should fail", bad)
+		}
+	}
+}
+
+func TestOverBudget(t *testing.T) {
+	partial := overBudget("trace_images", 2*time.Minute, mcp.NewToolResultText(`{"results":{}}`), nil)
+	if partial.IsError || partial.Meta == nil || partial.Meta.AdditionalFields["partial"] != true {
+		t.Errorf("successful result should be flagged partial: %+v", partial)
+	}
+	if len(partial.Content) != 2 {
+		t.Errorf("want the original content plus a notice, got %d blocks", len(partial.Content))
+	}
+
+	failed := overBudget("search_docs", 10*time.Second, nil, errors.New("context deadline exceeded"))
+	if !failed.IsError {
+		t.Errorf("failed call should become an error result: %+v", failed)
+	}
+}
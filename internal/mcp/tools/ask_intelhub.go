@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/ask"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const (
+	defaultAskSources = 6
+	maxAskSources     = 20
+	// askSourceChars bounds the text of each source in the prompt.
+	askSourceChars = 2000
+)
+
+type AskService interface {
+	Ask(ctx context.Context, question string, sources int) (types.AskResponse, error)
+}
+
+// AskIntelHubHandler answers a question from retrieved PRs and docs.
+type AskIntelHubHandler struct{ Service AskService }
+
+func (h *AskIntelHubHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	question, _ := args["question"].(string)
+	if strings.TrimSpace(question) == "" {
+		return mcp.NewToolResultError("question parameter is required"), nil
+	}
+	sources := defaultAskSources
+	if raw, ok := args["max_sources"].(float64); ok && raw > 0 {
+		sources = min(int(raw), maxAskSources)
+	}
+	resp, err := h.Service.Ask(ctx, question, sources)
+	if err != nil {
+		return nil, err
+	}
+	return structuredResult(resp), nil
+}
+
+// Answerer composes an answer from sources; *ask.Answerer implements it.
+type Answerer interface {
+	Model() string
+	Answer(ctx context.Context, question string, sources []ask.Source) (string, []ask.Source, error)
+}
+
+// DBAskService retrieves sources with search_all's ranking and has Answerer
+// answer from them.
+type DBAskService struct {
+	search   *DBSearchService
+	answerer Answerer
+}
+
+func NewDBAskService(search *DBSearchService, answerer Answerer) *DBAskService {
+	return &DBAskService{search: search, answerer: answerer}
+}
+
+func (s *DBAskService) Ask(ctx context.Context, question string, limit int) (types.AskResponse, error) {
+	results, err := s.search.SearchAll(ctx, question, limit)
+	if err != nil {
+		return types.AskResponse{}, err
+	}
+	resp := types.AskResponse{Question: question, Citations: []types.AskCitation{}, Model: s.answerer.Model()}
+	if len(results) == 0 {
+		resp.Answer = "No PRs or documentation match the question."
+		return resp, nil
+	}
+
+	sources := make([]ask.Source, 0, len(results))
+	citations := map[string]types.AskCitation{}
+	docs := 0
+	for _, r := range results {
+		var src ask.Source
+		var c types.AskCitation
+		if r.PR != nil {
+			body, _ := truncateBody(r.PR.Body, askSourceChars)
+			src = ask.Source{Label: fmt.Sprintf("PR #%d", r.PR.PRNumber), Title: r.PR.Title, URL: r.PR.GithubURL, Text: body}
+			number := r.PR.PRNumber
+			c = types.AskCitation{SourceType: types.SourcePR, PRNumber: &number}
+		} else {
+			doc := r.Doc
+			text, err := s.docText(ctx, doc)
+			if err != nil {
+				return types.AskResponse{}, err
+			}
+			text, _ = truncateBody(text, askSourceChars)
+			docs++
+			src = ask.Source{Label: fmt.Sprintf("D%d", docs), Title: doc.Path, URL: derefString(doc.SourceURL), Text: text}
+			c = types.AskCitation{SourceType: types.SourceDoc}
+		}
+		c.Label, c.Title, c.URL = src.Label, src.Title, src.URL
+		citations[src.Label] = c
+		sources = append(sources, src)
+	}
+
+	answer, cited, err := s.answerer.Answer(ctx, question, sources)
+	if err != nil {
+		return types.AskResponse{}, fmt.Errorf("compose answer: %w", err)
+	}
+	resp.Answer = answer
+	for _, src := range cited {
+		resp.Citations = append(resp.Citations, citations[src.Label])
+	}
+	return resp, nil
+}
+
+// docText is the full text of a doc hit's chunk with a chunk on either
+// side, which gives the model more than the hit's 400-character snippet.
+func (s *DBAskService) docText(ctx context.Context, doc *types.DocResult) (string, error) {
+	chunks, err := s.search.Repository.DocumentChunksAround(ctx, doc.Repo, doc.CommitSHA, doc.Path, doc.ChunkIndex, 1)
+	if err != nil {
+		return "", fmt.Errorf("load chunks around %s#%d: %w", doc.Path, doc.ChunkIndex, err)
+	}
+	if len(chunks) == 0 {
+		return doc.Snippet, nil
+	}
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.ChunkText
+	}
+	return strings.Join(texts, "\n"), nil
+}
@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const commitContextDocs = 3
+
+type CommitContextService interface {
+	CommitContext(ctx context.Context, sha string) (types.CommitContext, error)
+}
+
+type CommitContextHandler struct {
+	Service CommitContextService
+}
+
+// dbCommitContextService assembles a commit bundle from stored PRs, the trace
+// cache, docs search and the local repository clone. Each source is
+// best-effort: failures are reported in Errors instead of failing the call.
+type dbCommitContextService struct {
+	repo         *db.SearchRepository
+	docs         DocSearchService
+	git          *gitrepo.Repo
+	environments []string
+}
+
+func NewDBCommitContextService(repo *db.SearchRepository, docs DocSearchService, git *gitrepo.Repo, environments []string) CommitContextService {
+	return &dbCommitContextService{repo: repo, docs: docs, git: git, environments: environments}
+}
+
+func (s *dbCommitContextService) CommitContext(ctx context.Context, sha string) (types.CommitContext, error) {
+	result := types.CommitContext{
+		CommitSHA:   sha,
+		Components:  []string{},
+		Traces:      map[string]types.TraceImagesResponse{},
+		RelatedDocs: []types.DocResult{},
+	}
+
+	prs, err := s.repo.FindPRsByCommitSHAs(ctx, []string{sha})
+	if err != nil {
+		return types.CommitContext{}, fmt.Errorf("find PR for commit: %w", err)
+	}
+	if len(prs) > 0 {
+		// FindPRsByCommitSHAs only selects the columns needed for matching.
+		full, err := s.repo.GetPRByNumber(ctx, prs[0].PRNumber)
+		if err != nil {
+			return types.CommitContext{}, fmt.Errorf("load PR %d: %w", prs[0].PRNumber, err)
+		}
+		if full != nil {
+			pr := s.repo.ToPRResult(*full, nil)
+			result.PR = &pr
+			result.RichDescription = full.RichDescription
+			result.MatchedOn = "head_commit_sha"
+			if full.MergeCommitSHA != nil && *full.MergeCommitSHA == sha {
+				result.MatchedOn = "merge_commit_sha"
+			}
+		}
+	}
+
+	if s.git != nil {
+		files, err := s.git.ChangedFiles(ctx, sha)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("changed files: %v", err))
+		} else {
+			result.ChangedFiles = len(files)
+			result.Components = componentsFromPaths(files)
+		}
+	}
+
+	for _, env := range s.environments {
+		entry, err := s.repo.TraceImageCacheGet(ctx, sha, env)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("trace cache %s: %v", env, err))
+			continue
+		}
+		if entry != nil {
+			result.Traces[env] = entry.Response
+		}
+	}
+
+	if result.PR != nil && s.docs != nil {
+		docs, _, err := s.docs.SearchDocsPage(ctx, result.PR.Title, commitContextDocs, db.DocSearchFilter{}, DocExpansion{}, "")
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("related docs: %v", err))
+		} else {
+			result.RelatedDocs = docs
+		}
+	}
+
+	return result, nil
+}
+
+// componentsFromPaths maps changed files to their top-level directories, which
+// is how ARO-HCP lays out its components (frontend/, backend/, ...).
+func componentsFromPaths(paths []string) []string {
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		dir, _, found := strings.Cut(p, "/")
+		if !found {
+			continue
+		}
+		seen[dir] = true
+	}
+	components := make([]string, 0, len(seen))
+	for dir := range seen {
+		components = append(components, dir)
+	}
+	sort.Strings(components)
+	return components
+}
+
+func (h *CommitContextHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sha, _ := req.GetArguments()["sha"].(string)
+	sha = strings.ToLower(strings.TrimSpace(sha))
+	if sha == "" {
+		return mcp.NewToolResultError("sha parameter is required"), nil
+	}
+	if !gitrepo.IsCommitSHA(sha) {
+		return mcp.NewToolResultError("sha must be a hex commit SHA of 7 to 40 characters"), nil
+	}
+
+	bundle, err := h.Service.CommitContext(ctx, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	response := types.Result[types.CommitContext]{Result: bundle}
+
+	return structuredResult(response), nil
+}
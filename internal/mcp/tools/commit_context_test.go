@@ -0,0 +1,26 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCommitToolsRejectNonSHA(t *testing.T) {
+	// Nil services would panic if the handlers got past validation.
+	handlers := map[string]func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error){
+		"commit_context":     (&CommitContextHandler{}).ToolAdapter,
+		"find_pr_for_commit": (&FindPRForCommitHandler{}).ToolAdapter,
+	}
+	for name, handle := range handlers {
+		for _, sha := range []string{"--output=/tmp/pwned", "HEAD", "abc", "main^1", "xyz1234"} {
+			req := mcp.CallToolRequest{}
+			req.Params.Arguments = map[string]any{"sha": sha}
+			res, err := handle(context.Background(), req)
+			if err != nil || res == nil || !res.IsError {
+				t.Errorf("%s sha %q: result %+v, %v; want a tool error", name, sha, res, err)
+			}
+		}
+	}
+}
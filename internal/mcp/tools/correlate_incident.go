@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const defaultIncidentLookback = 72 * time.Hour
+
+type IncidentCorrelator interface {
+	// CorrelateIncident ranks the PRs that may have caused incident: those
+	// environment's deployments shipped between from and to, or with no
+	// environment those merged in that window.
+	CorrelateIncident(ctx context.Context, incident, environment string, from, to time.Time, limit int) (types.CorrelateIncidentResponse, error)
+}
+
+// IncidentStore is what correlate_incident reads; *db.SearchRepository
+// implements it.
+type IncidentStore interface {
+	SearchPRsMergedBetween(ctx context.Context, embedding []float32, from, to time.Time, limit int) ([]db.PRSearchRow, error)
+	SearchPRsByMergeCommits(ctx context.Context, embedding []float32, shas []string, limit int) ([]db.PRSearchRow, error)
+	DeploymentAt(ctx context.Context, environment string, t time.Time) (*db.Deployment, error)
+	DeploymentsBetween(ctx context.Context, environment string, from, to time.Time) ([]db.Deployment, error)
+	Similarity(distance float64) float64
+	ToPRResult(entity db.PREmbedding, similarity *float64) types.PRResult
+}
+
+// CommitLister lists the commits of a range; *gitrepo.Repo implements it.
+type CommitLister interface {
+	Log(ctx context.Context, revRange string, opts gitrepo.LogOptions) ([]gitrepo.CommitInfo, error)
+}
+
+type incidentCorrelator struct {
+	store IncidentStore
+	embed EmbeddingClient
+	git   CommitLister // optional
+}
+
+func (c incidentCorrelator) correlate(ctx context.Context, incident, environment string, from, to time.Time, limit int) (types.CorrelateIncidentResponse, error) {
+	resp := types.CorrelateIncidentResponse{Environment: environment, Candidates: []types.PRResult{}}
+	if strings.TrimSpace(incident) == "" {
+		return resp, nil
+	}
+	vectors, err := c.embed.EmbedTexts(ctx, []string{incident})
+	if err != nil {
+		return resp, fmt.Errorf("embed incident: %w", err)
+	}
+	if len(vectors) == 0 {
+		return resp, nil
+	}
+
+	var deployments []db.Deployment
+	if environment != "" {
+		if deployments, err = c.store.DeploymentsBetween(ctx, environment, from, to); err != nil {
+			return resp, fmt.Errorf("load deployments: %w", err)
+		}
+		if len(deployments) == 0 {
+			resp.Note = fmt.Sprintf("no deployment of %s is recorded in the window; candidates are the PRs merged in it", environment)
+		}
+	}
+	if len(deployments) == 0 {
+		rows, err := c.store.SearchPRsMergedBetween(ctx, vectors[0], from, to, limit)
+		if err != nil {
+			return resp, fmt.Errorf("search embeddings in window: %w", err)
+		}
+		resp.Candidates = prResults(c.store, rows, false)
+		return resp, nil
+	}
+
+	shipped, err := c.shipped(ctx, &resp, environment, from, deployments)
+	if err != nil {
+		return resp, err
+	}
+	shas := make([]string, 0, len(shipped))
+	for sha := range shipped {
+		shas = append(shas, sha)
+	}
+	rows, err := c.store.SearchPRsByMergeCommits(ctx, vectors[0], shas, limit)
+	if err != nil {
+		return resp, fmt.Errorf("search deployed PRs: %w", err)
+	}
+	for _, row := range rows {
+		if row.MergeCommitSHA != nil {
+			d := &resp.Deployments[shipped[*row.MergeCommitSHA]]
+			d.PRNumbers = append(d.PRNumbers, row.PRNumber)
+		}
+	}
+	resp.Candidates = prResults(c.store, rows, false)
+	return resp, nil
+}
+
+// shipped fills resp.Deployments from deployments and maps each commit they
+// shipped, on the first-parent history since the commit running before, to
+// its deployment's index. Without git, or when the range cannot be listed,
+// a deployment ships its own commit only.
+func (c incidentCorrelator) shipped(ctx context.Context, resp *types.CorrelateIncidentResponse, environment string, from time.Time, deployments []db.Deployment) (map[string]int, error) {
+	previous := ""
+	before, err := c.store.DeploymentAt(ctx, environment, from)
+	if err != nil {
+		return nil, fmt.Errorf("load deployment at window start: %w", err)
+	}
+	if before != nil {
+		previous = before.CommitSHA
+	}
+
+	shipped := map[string]int{}
+	for i, d := range deployments {
+		resp.Deployments = append(resp.Deployments, types.IncidentDeployment{
+			CommitSHA:   d.CommitSHA,
+			DeployedAt:  d.DeployedAt.UTC().Format(time.RFC3339),
+			PreviousSHA: previous,
+			PRNumbers:   []int{},
+		})
+		shipped[d.CommitSHA] = i
+		if previous != "" && previous != d.CommitSHA && c.git != nil {
+			commits, err := c.git.Log(ctx, previous+".."+d.CommitSHA, gitrepo.LogOptions{FirstParent: true})
+			if err != nil {
+				resp.Errors = append(resp.Errors, fmt.Sprintf("commits shipped by %s: %v", d.CommitSHA, err))
+			}
+			for _, commit := range commits {
+				shipped[commit.SHA] = i
+			}
+		}
+		previous = d.CommitSHA
+	}
+	return shipped, nil
+}
+
+type CorrelateIncidentHandler struct {
+	Service IncidentCorrelator
+}
+
+func (h *CorrelateIncidentHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	incident, _ := args["incident"].(string)
+	if strings.TrimSpace(incident) == "" {
+		return mcp.NewToolResultError("incident parameter is required"), nil
+	}
+	environment, _ := args["environment"].(string)
+	environment = strings.TrimSpace(environment)
+
+	to := time.Now().UTC()
+	if raw, ok := args["window_end"].(string); ok && raw != "" {
+		parsed, err := parseTimeArgument("window_end", raw)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		to = parsed
+	}
+
+	lookback := defaultIncidentLookback
+	if raw, ok := args["lookback_hours"].(float64); ok && raw > 0 {
+		lookback = time.Duration(raw * float64(time.Hour))
+	}
+	from := to.Add(-lookback)
+	if raw, ok := args["window_start"].(string); ok && raw != "" {
+		parsed, err := parseTimeArgument("window_start", raw)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		from = parsed
+	}
+	if !from.Before(to) {
+		return mcp.NewToolResultError("window_start must be before window_end"), nil
+	}
+
+	limit := 10
+	if raw, ok := args["limit"].(float64); ok && int(raw) > 0 {
+		limit = int(raw)
+	}
+
+	response, err := h.Service.CorrelateIncident(ctx, incident, environment, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	response.WindowStart = from.Format(time.RFC3339)
+	response.WindowEnd = to.Format(time.RFC3339)
+	response.Total = len(response.Candidates)
+
+	return structuredResult(response), nil
+}
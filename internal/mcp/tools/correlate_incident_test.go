@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+type fakeCorrelator struct {
+	environment string
+	from, to    time.Time
+	limit       int
+}
+
+func (f *fakeCorrelator) CorrelateIncident(_ context.Context, _, environment string, from, to time.Time, limit int) (types.CorrelateIncidentResponse, error) {
+	f.environment, f.from, f.to, f.limit = environment, from, to, limit
+	return types.CorrelateIncidentResponse{Candidates: []types.PRResult{{PRNumber: 1}, {PRNumber: 2}}}, nil
+}
+
+func TestCorrelateIncidentHandler(t *testing.T) {
+	call := func(args map[string]any) (*fakeCorrelator, *mcp.CallToolResult) {
+		t.Helper()
+		f := &fakeCorrelator{}
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := (&CorrelateIncidentHandler{Service: f}).ToolAdapter(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f, res
+	}
+
+	f, res := call(map[string]any{"incident": "nodepools stuck", "environment": "prod", "window_end": "2026-03-04T00:00:00Z", "lookback_hours": float64(24)})
+	if res.IsError {
+		t.Fatalf("result = %+v", res)
+	}
+	end := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	if f.environment != "prod" || !f.to.Equal(end) || !f.from.Equal(end.Add(-24*time.Hour)) || f.limit != 10 {
+		t.Fatalf("called with %+v", f)
+	}
+	resp := res.StructuredContent.(types.CorrelateIncidentResponse)
+	if resp.Total != 2 || resp.WindowStart != "2026-03-03T00:00:00Z" || resp.WindowEnd != "2026-03-04T00:00:00Z" {
+		t.Fatalf("response = %+v", resp)
+	}
+
+	for _, args := range []map[string]any{
+		{},
+		{"incident": "x", "window_start": "2026-03-04T00:00:00Z", "window_end": "2026-03-03T00:00:00Z"},
+		{"incident": "x", "window_end": "yesterday"},
+	} {
+		if _, res := call(args); !res.IsError {
+			t.Errorf("args %v: result %+v, want a tool error", args, res)
+		}
+	}
+}
+
+type fakeIncidentStore struct {
+	deployments []db.Deployment
+	before      *db.Deployment
+	prs         []db.PRSearchRow
+	searched    []string // merge commits passed to SearchPRsByMergeCommits
+	inWindow    bool     // SearchPRsMergedBetween was called
+}
+
+func (f *fakeIncidentStore) SearchPRsMergedBetween(context.Context, []float32, time.Time, time.Time, int) ([]db.PRSearchRow, error) {
+	f.inWindow = true
+	return f.prs, nil
+}
+
+func (f *fakeIncidentStore) SearchPRsByMergeCommits(_ context.Context, _ []float32, shas []string, _ int) ([]db.PRSearchRow, error) {
+	f.searched = shas
+	var rows []db.PRSearchRow
+	for _, row := range f.prs {
+		if row.MergeCommitSHA != nil && slices.Contains(shas, *row.MergeCommitSHA) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (f *fakeIncidentStore) DeploymentAt(context.Context, string, time.Time) (*db.Deployment, error) {
+	return f.before, nil
+}
+
+func (f *fakeIncidentStore) DeploymentsBetween(context.Context, string, time.Time, time.Time) ([]db.Deployment, error) {
+	return f.deployments, nil
+}
+
+func (f *fakeIncidentStore) Similarity(distance float64) float64 { return 1 - distance }
+
+func (f *fakeIncidentStore) ToPRResult(entity db.PREmbedding, similarity *float64) types.PRResult {
+	return (&db.SearchRepository{}).ToPRResult(entity, similarity)
+}
+
+type fakeCommitLister map[string][]string
+
+func (f fakeCommitLister) Log(_ context.Context, revRange string, _ gitrepo.LogOptions) ([]gitrepo.CommitInfo, error) {
+	shas, ok := f[revRange]
+	if !ok {
+		return nil, errors.New("unknown revision")
+	}
+	commits := make([]gitrepo.CommitInfo, 0, len(shas))
+	for _, sha := range shas {
+		commits = append(commits, gitrepo.CommitInfo{SHA: sha})
+	}
+	return commits, nil
+}
+
+type fixedEmbedder struct{}
+
+func (fixedEmbedder) EmbedTexts(_ context.Context, inputs []string) ([][]float32, error) {
+	return [][]float32{{1}}, nil
+}
+
+func TestCorrelateIncidentJoinsDeployments(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(72 * time.Hour)
+	pr := func(number int, sha string) db.PRSearchRow {
+		row := db.PRSearchRow{Distance: 0.1}
+		row.PRNumber, row.MergeCommitSHA = number, &sha
+		return row
+	}
+	store := &fakeIncidentStore{
+		before: &db.Deployment{CommitSHA: "aaa"},
+		deployments: []db.Deployment{
+			{CommitSHA: "ccc", DeployedAt: from.Add(time.Hour)},
+			{CommitSHA: "eee", DeployedAt: from.Add(2 * time.Hour)},
+		},
+		prs: []db.PRSearchRow{pr(1, "bbb"), pr(2, "ccc"), pr(3, "eee"), pr(4, "zzz")},
+	}
+	// ccc..eee cannot be listed, so the second deployment ships eee only.
+	git := fakeCommitLister{"aaa..ccc": {"ccc", "bbb"}}
+	c := incidentCorrelator{store: store, embed: fixedEmbedder{}, git: git}
+
+	resp, err := c.correlate(ctx, "nodepools stuck", "prod", from, to, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.inWindow {
+		t.Fatal("searched the merge window despite deployments")
+	}
+	slices.Sort(store.searched)
+	if want := []string{"bbb", "ccc", "eee"}; !slices.Equal(store.searched, want) {
+		t.Fatalf("searched %v, want %v", store.searched, want)
+	}
+	if len(resp.Deployments) != 2 || resp.Deployments[0].PreviousSHA != "aaa" || resp.Deployments[1].PreviousSHA != "ccc" {
+		t.Fatalf("deployments = %+v", resp.Deployments)
+	}
+	if got := resp.Deployments[0].PRNumbers; !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("first deployment shipped %v, want [1 2]", got)
+	}
+	if got := resp.Deployments[1].PRNumbers; !slices.Equal(got, []int{3}) {
+		t.Errorf("second deployment shipped %v, want [3]", got)
+	}
+	if len(resp.Candidates) != 3 || len(resp.Errors) != 1 {
+		t.Fatalf("candidates = %+v, errors = %v", resp.Candidates, resp.Errors)
+	}
+
+	// Without deployments in the window it falls back to the merge window.
+	store = &fakeIncidentStore{prs: []db.PRSearchRow{pr(4, "zzz")}}
+	c.store = store
+	resp, err = c.correlate(ctx, "nodepools stuck", "prod", from, to, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !store.inWindow || len(resp.Candidates) != 1 || resp.Note == "" {
+		t.Fatalf("fallback response = %+v", resp)
+	}
+}
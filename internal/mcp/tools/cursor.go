@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+var errInvalidCursor = errors.New("invalid cursor: pass next_cursor from a previous call with the same query and filters")
+
+// pageCursor is the opaque token returned as next_cursor. It embeds a
+// fingerprint of the query and filters so a cursor cannot be replayed
+// against a different search.
+type pageCursor struct {
+	db.SearchCursor
+	Fingerprint string `json:"f"`
+}
+
+// searchFingerprint identifies a search by its query and filters.
+func searchFingerprint(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func encodeCursor(pos db.SearchCursor, fingerprint string) string {
+	b, _ := json.Marshal(pageCursor{SearchCursor: pos, Fingerprint: fingerprint})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor returns nil for an empty token.
+func decodeCursor(token, fingerprint string) (*db.SearchCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Fingerprint != fingerprint || c.ID == "" || c.Offset < 0 {
+		return nil, errInvalidCursor
+	}
+	return &c.SearchCursor, nil
+}
+
+// nextOffset is the Offset of the cursor following a page of limit rows
+// after after.
+func nextOffset(after *db.SearchCursor, limit int) int {
+	if after == nil {
+		return limit
+	}
+	return after.Offset + limit
+}
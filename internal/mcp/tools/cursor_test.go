@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	fp := searchFingerprint("etcd backup", "", "")
+	pos := db.SearchCursor{Distance: 0.123456789, ID: "42", Offset: nextOffset(&db.SearchCursor{Offset: 20}, 10)}
+
+	got, err := decodeCursor(encodeCursor(pos, fp), fp)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if *got != pos || got.Offset != 30 {
+		t.Fatalf("round trip = %+v, want %+v at offset 30", *got, pos)
+	}
+
+	if c, err := decodeCursor("", fp); c != nil || err != nil {
+		t.Fatalf("empty cursor = %v, %v; want nil, nil", c, err)
+	}
+	if _, err := decodeCursor(encodeCursor(pos, fp), searchFingerprint("other query", "", "")); err == nil {
+		t.Fatal("expected error for cursor from a different search")
+	}
+	if _, err := decodeCursor(encodeCursor(db.SearchCursor{ID: "42", Offset: -1}, fp), fp); err == nil {
+		t.Fatal("expected error for negative offset")
+	}
+	if _, err := decodeCursor("not-base64!", fp); err == nil {
+		t.Fatal("expected error for malformed cursor")
+	}
+}
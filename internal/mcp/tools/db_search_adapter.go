@@ -1,79 +1,398 @@
 package tools
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/roivaz/aro-hcp-intelhub/internal/db"
-	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion/embeddings"
 	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
 )
 
+// EmbeddingClient embeds search queries; *embeddings.Client and
+// *embeddings.Fake implement it.
+type EmbeddingClient interface {
+	EmbedTexts(ctx context.Context, inputs []string) ([][]float32, error)
+}
+
 type DBSearchService struct {
 	Repository  *db.SearchRepository
-	EmbedClient *embeddings.Client
+	EmbedClient EmbeddingClient
+	// Cache serves repeated search_prs and search_docs pages; nil disables it.
+	Cache *SearchCache
+	// Git lists the commits a deployment shipped for correlate_incident;
+	// nil limits each deployment to its own commit.
+	Git CommitLister
 }
 
-func NewDBSearchService(repo *db.SearchRepository, embed *embeddings.Client) *DBSearchService {
+func NewDBSearchService(repo *db.SearchRepository, embed EmbeddingClient) *DBSearchService {
 	return &DBSearchService{Repository: repo, EmbedClient: embed}
 }
 
 func (s *DBSearchService) SearchPRs(ctx context.Context, query string, limit int) ([]types.PRResult, error) {
+	results, _, err := s.SearchPRsPage(ctx, query, limit, db.PRSearchFilter{}, "")
+	return results, err
+}
+
+// SearchPRsPage returns up to limit PRs after cursor and the cursor of the
+// next page, which is empty on the last page.
+func (s *DBSearchService) SearchPRsPage(ctx context.Context, query string, limit int, filter db.PRSearchFilter, cursor string) ([]types.PRResult, string, error) {
 	if strings.TrimSpace(query) == "" {
-		return []types.PRResult{}, nil
+		return []types.PRResult{}, "", nil
+	}
+	fingerprint := searchFingerprint(query, strings.Join(filter.Labels, ","), filter.Milestone, filter.LinkedIssue, strconv.FormatFloat(filter.MinSimilarity, 'g', -1, 64), string(filter.Quality))
+	key := searchFingerprint("prs", fingerprint, strconv.Itoa(limit), cursor)
+	return cachedPage(s.Cache, db.CorpusPRs, key, func() ([]types.PRResult, string, error) {
+		return s.searchPRsPage(ctx, query, fingerprint, limit, filter, cursor)
+	})
+}
+
+func (s *DBSearchService) searchPRsPage(ctx context.Context, query, fingerprint string, limit int, filter db.PRSearchFilter, cursor string) ([]types.PRResult, string, error) {
+	after, err := decodeCursor(cursor, fingerprint)
+	if err != nil {
+		return nil, "", err
 	}
 
 	vectors, err := s.EmbedClient.EmbedTexts(ctx, []string{query})
 	if err != nil {
-		return nil, fmt.Errorf("embed query: %w", err)
+		return nil, "", fmt.Errorf("embed query: %w", err)
 	}
 	if len(vectors) == 0 {
-		return []types.PRResult{}, nil
+		return []types.PRResult{}, "", nil
 	}
 
-	rows, err := s.Repository.SearchPRs(ctx, vectors[0], limit)
+	// Fetch one extra row to learn whether another page exists.
+	rows, err := s.Repository.SearchPRs(ctx, vectors[0], limit+1, filter, after)
 	if err != nil {
-		return nil, fmt.Errorf("search embeddings: %w", err)
+		return nil, "", fmt.Errorf("search embeddings: %w", err)
+	}
+	next := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		next = encodeCursor(db.SearchCursor{Distance: last.Distance, ID: strconv.FormatInt(last.ID, 10), Offset: nextOffset(after, limit)}, fingerprint)
 	}
 
+	return prResults(s.Repository, rows, true), next, nil
+}
+
+func (s *DBSearchService) CorrelateIncident(ctx context.Context, incident, environment string, from, to time.Time, limit int) (types.CorrelateIncidentResponse, error) {
+	c := incidentCorrelator{store: s.Repository, embed: s.EmbedClient, git: s.Git}
+	return c.correlate(ctx, incident, environment, from, to, limit)
+}
+
+// prResultBuilder maps distances to similarities and stored PRs to results;
+// *db.SearchRepository implements it.
+type prResultBuilder interface {
+	Similarity(distance float64) float64
+	ToPRResult(entity db.PREmbedding, similarity *float64) types.PRResult
+}
+
+func prResults(repo prResultBuilder, rows []db.PRSearchRow, withAnalysis bool) []types.PRResult {
 	results := make([]types.PRResult, 0, len(rows))
 	for _, row := range rows {
-		similarity := 1 - (row.Distance / 2.0)
-		result := db.ToPRResult(row.PREmbedding, &similarity)
+		similarity, distance := repo.Similarity(row.Distance), row.Distance
+		result := repo.ToPRResult(row.PREmbedding, &similarity)
+		result.Distance = &distance
+		if withAnalysis {
+			result.Analysis = db.ToPRAnalysis(row.PREmbedding)
+		}
 		results = append(results, result)
 	}
-	return results, nil
+	return results
 }
 
 func (s *DBSearchService) SearchDocs(ctx context.Context, query string, limit int, component, repo *string, includeFull bool) ([]types.DocResult, error) {
+	filter := db.DocSearchFilter{Component: derefString(component), Repo: derefString(repo)}
+	results, _, err := s.SearchDocsPage(ctx, query, limit, filter, DocExpansion{IncludeFullFile: includeFull}, "")
+	return results, err
+}
+
+// SearchDocsPage returns up to limit chunks after cursor and the cursor of the
+// next page, which is empty on the last page.
+func (s *DBSearchService) SearchDocsPage(ctx context.Context, query string, limit int, filter db.DocSearchFilter, expand DocExpansion, cursor string) ([]types.DocResult, string, error) {
 	if strings.TrimSpace(query) == "" {
-		return []types.DocResult{}, nil
+		return []types.DocResult{}, "", nil
+	}
+	fingerprint := searchFingerprint(query, filter.Component, filter.Repo, filter.DocType, filter.PathPrefix, filter.AlertName, strconv.FormatFloat(filter.MinSimilarity, 'g', -1, 64), string(filter.Quality))
+	key := searchFingerprint("docs", fingerprint, strconv.Itoa(limit), cursor)
+	results, next, err := cachedPage(s.Cache, db.CorpusDocs, key, func() ([]types.DocResult, string, error) {
+		return s.searchDocsPage(ctx, query, fingerprint, limit, filter, cursor)
+	})
+	if err != nil || expand == (DocExpansion{}) {
+		return results, next, err
+	}
+	// Cached pages are shared, so expansions go on a copy.
+	results = slices.Clone(results)
+	if expand.IncludeFullFile {
+		if err := s.addFullFiles(ctx, results); err != nil {
+			return nil, "", err
+		}
+	}
+	if expand.ContextChunks > 0 {
+		if err := s.addContextChunks(ctx, results, expand.ContextChunks); err != nil {
+			return nil, "", err
+		}
+	}
+	return results, next, nil
+}
+
+// addContextChunks sets the n chunks on either side of each result.
+func (s *DBSearchService) addContextChunks(ctx context.Context, results []types.DocResult, n int) error {
+	for i := range results {
+		r := &results[i]
+		chunks, err := s.Repository.DocumentChunksAround(ctx, r.Repo, r.CommitSHA, r.Path, r.ChunkIndex, n)
+		if err != nil {
+			return fmt.Errorf("load chunks around %s#%d: %w", r.Path, r.ChunkIndex, err)
+		}
+		for _, c := range chunks {
+			if c.ChunkIndex == r.ChunkIndex {
+				continue
+			}
+			r.ContextChunks = append(r.ContextChunks, types.DocContextChunk{
+				ChunkIndex: c.ChunkIndex,
+				Text:       c.ChunkText,
+				StartLine:  c.StartLine,
+				EndLine:    c.EndLine,
+				Anchor:     c.Anchor,
+			})
+		}
+	}
+	return nil
+}
+
+// addFullFiles sets the content of each result's file as stored at
+// ingestion. Files ingested before contents were stored are left without.
+func (s *DBSearchService) addFullFiles(ctx context.Context, results []types.DocResult) error {
+	for i := range results {
+		r := &results[i]
+		content, ok, err := s.Repository.DocumentFile(ctx, r.Repo, r.CommitSHA, r.Path)
+		if err != nil {
+			return fmt.Errorf("load file %s: %w", r.Path, err)
+		}
+		if ok {
+			text := string(content)
+			r.Content = &text
+		}
+	}
+	return nil
+}
+
+func (s *DBSearchService) searchDocsPage(ctx context.Context, query, fingerprint string, limit int, filter db.DocSearchFilter, cursor string) ([]types.DocResult, string, error) {
+	after, err := decodeCursor(cursor, fingerprint)
+	if err != nil {
+		return nil, "", err
 	}
 	vectors, err := s.EmbedClient.EmbedTexts(ctx, []string{query})
 	if err != nil {
-		return nil, fmt.Errorf("embed query: %w", err)
+		return nil, "", fmt.Errorf("embed query: %w", err)
 	}
 	if len(vectors) == 0 {
-		return []types.DocResult{}, nil
+		return []types.DocResult{}, "", nil
 	}
-	rows, err := s.Repository.SearchDocs(ctx, vectors[0], limit, component, repo)
+	rows, err := s.Repository.SearchDocs(ctx, vectors[0], limit+1, filter, after)
 	if err != nil {
-		return nil, fmt.Errorf("search docs: %w", err)
+		return nil, "", fmt.Errorf("search docs: %w", err)
+	}
+	next := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		next = encodeCursor(db.SearchCursor{Distance: last.Distance, ID: last.ID, Offset: nextOffset(after, limit)}, fingerprint)
 	}
+	return docResults(s.Repository, rows), next, nil
+}
+
+func docResults(repo *db.SearchRepository, rows []db.DocSearchRow) []types.DocResult {
 	results := make([]types.DocResult, 0, len(rows))
 	for _, row := range rows {
-		sim := 1 - row.Distance
-		r := types.DocResult{
+		results = append(results, types.DocResult{
 			Repo:       row.DocumentChunk.Repo,
 			Component:  row.DocumentChunk.Component,
 			Path:       row.DocumentChunk.Path,
 			CommitSHA:  row.DocumentChunk.CommitSHA,
+			ChunkIndex: row.DocumentChunk.ChunkIndex,
 			SourceURL:  row.DocumentChunk.SourceURL,
+			StartLine:  row.DocumentChunk.StartLine,
+			EndLine:    row.DocumentChunk.EndLine,
+			Anchor:     row.DocumentChunk.Anchor,
+			DocType:    row.DocumentChunk.DocType,
+			Owner:      row.DocumentChunk.Owner,
+			Severity:   row.DocumentChunk.Severity,
+			AlertNames: row.DocumentChunk.AlertNames,
 			Snippet:    row.Snippet,
-			Similarity: sim,
-		}
-		results = append(results, r)
+			Similarity: repo.Similarity(row.Distance),
+			Distance:   row.Distance,
+		})
+	}
+	return results
+}
+
+// SearchAll ranks PRs and documentation chunks against one embedding of
+// query and returns the best limit of either kind. Both are scored by
+// similarity under the same metric, so their scores compare directly.
+func (s *DBSearchService) SearchAll(ctx context.Context, query string, limit int) ([]types.SearchAllResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return []types.SearchAllResult{}, nil
+	}
+	vectors, err := s.EmbedClient.EmbedTexts(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	if len(vectors) == 0 {
+		return []types.SearchAllResult{}, nil
+	}
+	prRows, err := s.Repository.SearchPRs(ctx, vectors[0], limit, db.PRSearchFilter{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("search embeddings: %w", err)
+	}
+	docRows, err := s.Repository.SearchDocs(ctx, vectors[0], limit, db.DocSearchFilter{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("search docs: %w", err)
+	}
+
+	results := make([]types.SearchAllResult, 0, len(prRows)+len(docRows))
+	for i, pr := range prResults(s.Repository, prRows, false) {
+		results = append(results, types.SearchAllResult{SourceType: types.SourcePR, Score: s.Repository.Similarity(prRows[i].Distance), PR: &pr})
+	}
+	for i, doc := range docResults(s.Repository, docRows) {
+		results = append(results, types.SearchAllResult{SourceType: types.SourceDoc, Score: s.Repository.Similarity(docRows[i].Distance), Doc: &doc})
+	}
+	return rankSearchAll(results, limit), nil
+}
+
+// rankSearchAll orders results by score, best first, and keeps the first
+// limit. The sort is stable, so ties keep PRs ahead of docs and each source's
+// own order.
+func rankSearchAll(results []types.SearchAllResult, limit int) []types.SearchAllResult {
+	slices.SortStableFunc(results, func(a, b types.SearchAllResult) int { return cmp.Compare(b.Score, a.Score) })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// SearchCodePage returns up to limit declarations after cursor and the cursor
+// of the next page, which is empty on the last page.
+func (s *DBSearchService) SearchCodePage(ctx context.Context, query string, limit int, filter db.CodeSearchFilter, cursor string) ([]types.CodeResult, string, error) {
+	if strings.TrimSpace(query) == "" {
+		return []types.CodeResult{}, "", nil
+	}
+	fingerprint := searchFingerprint("code", query, filter.Repo, filter.Language, strings.Join(filter.Languages, ","), filter.PathPrefix, string(filter.Quality))
+	after, err := decodeCursor(cursor, fingerprint)
+	if err != nil {
+		return nil, "", err
+	}
+	vectors, err := s.EmbedClient.EmbedTexts(ctx, []string{query})
+	if err != nil {
+		return nil, "", fmt.Errorf("embed query: %w", err)
+	}
+	if len(vectors) == 0 {
+		return []types.CodeResult{}, "", nil
+	}
+	rows, err := s.Repository.SearchCode(ctx, vectors[0], limit+1, filter, after)
+	if err != nil {
+		return nil, "", fmt.Errorf("search code: %w", err)
+	}
+	next := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		next = encodeCursor(db.SearchCursor{Distance: last.Distance, ID: last.ID, Offset: nextOffset(after, limit)}, fingerprint)
+	}
+	results := make([]types.CodeResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, types.CodeResult{
+			Repo:       row.Repo,
+			Path:       row.Path,
+			CommitSHA:  row.CommitSHA,
+			Language:   row.Language,
+			Symbol:     row.Symbol,
+			Kind:       row.Kind,
+			StartLine:  row.StartLine,
+			EndLine:    row.EndLine,
+			SourceURL:  row.SourceURL,
+			Code:       row.ChunkText,
+			Similarity: s.Repository.Similarity(row.Distance),
+			Distance:   row.Distance,
+		})
+	}
+	return results, next, nil
+}
+
+// SearchPRDiffsPage returns up to limit diff chunks after cursor and the
+// cursor of the next page, which is empty on the last page.
+func (s *DBSearchService) SearchPRDiffsPage(ctx context.Context, query string, limit int, filter db.PRDiffSearchFilter, cursor string) ([]types.PRDiffResult, string, error) {
+	if strings.TrimSpace(query) == "" {
+		return []types.PRDiffResult{}, "", nil
+	}
+	fingerprint := searchFingerprint("diffs", query, strconv.Itoa(filter.PRNumber), filter.PathPrefix, string(filter.Quality))
+	key := searchFingerprint(fingerprint, strconv.Itoa(limit), cursor)
+	return cachedPage(s.Cache, db.CorpusPRs, key, func() ([]types.PRDiffResult, string, error) {
+		return s.searchPRDiffsPage(ctx, query, fingerprint, limit, filter, cursor)
+	})
+}
+
+func (s *DBSearchService) searchPRDiffsPage(ctx context.Context, query, fingerprint string, limit int, filter db.PRDiffSearchFilter, cursor string) ([]types.PRDiffResult, string, error) {
+	after, err := decodeCursor(cursor, fingerprint)
+	if err != nil {
+		return nil, "", err
+	}
+	vectors, err := s.EmbedClient.EmbedTexts(ctx, []string{query})
+	if err != nil {
+		return nil, "", fmt.Errorf("embed query: %w", err)
+	}
+	if len(vectors) == 0 {
+		return []types.PRDiffResult{}, "", nil
+	}
+	rows, err := s.Repository.SearchPRDiffs(ctx, vectors[0], limit+1, filter, after)
+	if err != nil {
+		return nil, "", fmt.Errorf("search PR diffs: %w", err)
+	}
+	next := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		next = encodeCursor(db.SearchCursor{Distance: last.Distance, ID: strconv.FormatInt(last.ID, 10), Offset: nextOffset(after, limit)}, fingerprint)
+	}
+
+	numbers := make([]int, 0, len(rows))
+	for _, row := range rows {
+		numbers = append(numbers, row.PRNumber)
+	}
+	prs, err := s.Repository.PRsByNumbers(ctx, numbers)
+	if err != nil {
+		return nil, "", fmt.Errorf("load PRs: %w", err)
+	}
+	byNumber := make(map[int]types.PRResult, len(prs))
+	for _, pr := range prs {
+		byNumber[pr.PRNumber] = s.Repository.ToPRResult(pr, nil)
+	}
+	results := make([]types.PRDiffResult, 0, len(rows))
+	for _, row := range rows {
+		pr := byNumber[row.PRNumber]
+		results = append(results, types.PRDiffResult{
+			PRNumber:   row.PRNumber,
+			Title:      pr.Title,
+			MergedAt:   pr.MergedAt,
+			GithubURL:  s.Repository.PRURL(row.PRNumber),
+			Path:       row.Path,
+			ChunkIndex: row.ChunkIndex,
+			Diff:       row.ChunkText,
+			Similarity: s.Repository.Similarity(row.Distance),
+			Distance:   row.Distance,
+		})
 	}
-	return results, nil
+	return results, next, nil
 }
@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const (
+	defaultFailedPerCategory = 20
+	maxFailedPerCategory     = 200
+)
+
+type FailedAnalysesService interface {
+	FailedAnalyses(ctx context.Context, category string, perCategory int) ([]types.FailureGroup, error)
+	Requeue(ctx context.Context, prNumbers []int) ([]int, error)
+}
+
+// ListFailedAnalysesHandler reports failed PR analyses by failure category
+// and, given the admin token, requeues selected PRs for reprocessing.
+type ListFailedAnalysesHandler struct {
+	Service    FailedAnalysesService
+	AdminToken string
+}
+
+type dbFailedAnalysesService struct {
+	repo *db.SearchRepository
+}
+
+func NewDBFailedAnalysesService(repo *db.SearchRepository) FailedAnalysesService {
+	return &dbFailedAnalysesService{repo: repo}
+}
+
+func (s *dbFailedAnalysesService) FailedAnalyses(ctx context.Context, category string, perCategory int) ([]types.FailureGroup, error) {
+	groups, err := s.repo.FailedAnalyses(ctx, category, perCategory)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]types.FailureGroup, 0, len(groups))
+	for _, g := range groups {
+		results = append(results, s.repo.ToFailureGroup(g))
+	}
+	return results, nil
+}
+
+func (s *dbFailedAnalysesService) Requeue(ctx context.Context, prNumbers []int) ([]int, error) {
+	return s.repo.RequeuePRs(ctx, prNumbers)
+}
+
+func (h *ListFailedAnalysesHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	category, _ := args["failure_category"].(string)
+	perCategory := defaultFailedPerCategory
+	if raw, ok := args["limit"].(float64); ok && int(raw) > 0 {
+		perCategory = min(int(raw), maxFailedPerCategory)
+	}
+
+	var requeue []int
+	if raw, ok := args["requeue"].([]any); ok {
+		for _, v := range raw {
+			n, ok := v.(float64)
+			if !ok || n <= 0 || n != float64(int(n)) {
+				return mcp.NewToolResultError(fmt.Sprintf("requeue: %v is not a PR number", v)), nil
+			}
+			requeue = append(requeue, int(n))
+		}
+	}
+
+	response := types.FailedAnalysesResponse{}
+	if len(requeue) > 0 {
+		if h.AdminToken == "" {
+			return mcp.NewToolResultError("requeue is disabled: MCP_ADMIN_TOKEN is not configured"), nil
+		}
+		token, _ := args["admin_token"].(string)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) != 1 {
+			return mcp.NewToolResultError("invalid admin_token"), nil
+		}
+		requeued, err := h.Service.Requeue(ctx, requeue)
+		if err != nil {
+			return nil, err
+		}
+		response.Requeued = requeued
+	}
+
+	groups, err := h.Service.FailedAnalyses(ctx, category, perCategory)
+	if err != nil {
+		return nil, err
+	}
+	response.Groups = groups
+	for _, g := range groups {
+		response.TotalFailed += g.Count
+	}
+
+	return structuredResult(response), nil
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+// maxFeedbackChars bounds the query and comment stored with feedback.
+const maxFeedbackChars = 2000
+
+type FeedbackRecorder interface {
+	HasPR(ctx context.Context, number int) (bool, error)
+	RecordFeedback(ctx context.Context, fb *db.PRFeedback) error
+}
+
+// FeedbackHandler stores a client's helpful/unhelpful mark on a search result
+// or a rich description.
+type FeedbackHandler struct {
+	Service FeedbackRecorder
+}
+
+func (h *FeedbackHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	number, ok := args["pr_number"].(float64)
+	if !ok || number <= 0 {
+		return mcp.NewToolResultError("pr_number is required"), nil
+	}
+	helpful, ok := args["helpful"].(bool)
+	if !ok {
+		return mcp.NewToolResultError("helpful is required"), nil
+	}
+	target := db.FeedbackSearchResult
+	if raw, ok := args["target"].(string); ok && raw != "" {
+		target = raw
+	}
+	if target != db.FeedbackSearchResult && target != db.FeedbackRichDescription {
+		return mcp.NewToolResultError(fmt.Sprintf("target must be %s or %s", db.FeedbackSearchResult, db.FeedbackRichDescription)), nil
+	}
+
+	exists, err := h.Service.HasPR(ctx, int(number))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return mcp.NewToolResultError(fmt.Sprintf("PR #%d is not ingested", int(number))), nil
+	}
+
+	fb := &db.PRFeedback{
+		PRNumber: int(number),
+		Target:   target,
+		Helpful:  helpful,
+		Query:    optionalText(args["query"]),
+		Comment:  optionalText(args["comment"]),
+	}
+	if err := h.Service.RecordFeedback(ctx, fb); err != nil {
+		return nil, err
+	}
+
+	response := types.Result[types.FeedbackReceipt]{Result: types.FeedbackReceipt{
+		PRNumber: fb.PRNumber,
+		Target:   fb.Target,
+		Helpful:  fb.Helpful,
+		Recorded: true,
+	}}
+
+	return structuredResult(response), nil
+}
+
+// optionalText returns a trimmed, length-capped copy of a string argument,
+// or nil when it is missing or blank.
+func optionalText(raw any) *string {
+	s, _ := raw.(string)
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if r := []rune(s); len(r) > maxFeedbackChars {
+		s = string(r[:maxFeedbackChars])
+	}
+	return &s
+}
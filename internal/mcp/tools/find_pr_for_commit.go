@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const (
+	mainBranchRef = "origin/main"
+	// prBranchScan bounds how many recent PRs are checked for the commit in
+	// their branch history when it is not reachable from main.
+	prBranchScan = 200
+)
+
+type CommitPRResolver interface {
+	FindPRForCommit(ctx context.Context, sha string) (types.CommitPRResolution, error)
+}
+
+type FindPRForCommitHandler struct {
+	Service CommitPRResolver
+}
+
+// dbCommitPRResolver matches a commit against stored merge/head SHAs first and
+// falls back to walking the local clone. Git failures are reported in Errors.
+type dbCommitPRResolver struct {
+	repo *db.SearchRepository
+	git  *gitrepo.Repo
+}
+
+func NewDBCommitPRResolver(repo *db.SearchRepository, git *gitrepo.Repo) CommitPRResolver {
+	return &dbCommitPRResolver{repo: repo, git: git}
+}
+
+func (s *dbCommitPRResolver) FindPRForCommit(ctx context.Context, sha string) (types.CommitPRResolution, error) {
+	result := types.CommitPRResolution{CommitSHA: sha}
+	if s.git != nil {
+		// Expand abbreviated SHAs so they can match stored ones.
+		if info, err := s.git.CommitInfo(ctx, sha); err == nil {
+			result.CommitSHA = info.SHA
+			result.Subject = info.Subject
+			result.Author = info.Author
+			result.Date = &info.Date
+		} else {
+			result.Errors = append(result.Errors, fmt.Sprintf("resolve commit: %v", err))
+		}
+	}
+
+	found, err := s.matchStored(ctx, &result, result.CommitSHA)
+	if err != nil || found || s.git == nil {
+		return result, err
+	}
+
+	if branches, err := s.git.BranchesContaining(ctx, result.CommitSHA); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("branches containing commit: %v", err))
+	} else {
+		result.Branches = branches
+	}
+
+	introducing, err := s.git.IntroducedBy(ctx, result.CommitSHA, mainBranchRef)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("find introducing commit on %s: %v", mainBranchRef, err))
+	}
+	if introducing != "" {
+		result.IntroducingCommit = introducing
+		if introducing != result.CommitSHA {
+			found, err := s.matchStored(ctx, &result, introducing)
+			if found {
+				result.MatchedOn = "introduced_by"
+			}
+			return result, err
+		}
+		return result, nil // committed directly to main
+	}
+
+	return result, s.matchPRBranch(ctx, &result)
+}
+
+// matchStored looks sha up among stored merge and head commit SHAs.
+func (s *dbCommitPRResolver) matchStored(ctx context.Context, result *types.CommitPRResolution, sha string) (bool, error) {
+	prs, err := s.repo.FindPRsByCommitSHAs(ctx, []string{sha})
+	if err != nil {
+		return false, fmt.Errorf("find PR for commit: %w", err)
+	}
+	if len(prs) == 0 {
+		return false, nil
+	}
+	if err := s.setPR(ctx, result, prs[0].PRNumber); err != nil {
+		return false, err
+	}
+	result.MatchedOn = "head_commit_sha"
+	if prs[0].MergeCommitSHA != nil && *prs[0].MergeCommitSHA == sha {
+		result.MatchedOn = "merge_commit_sha"
+	}
+	return true, nil
+}
+
+// matchPRBranch finds the earliest merged recent PR whose head commit
+// descends from the commit, for commits that never reached main directly
+// (e.g. squash-merged branches).
+func (s *dbCommitPRResolver) matchPRBranch(ctx context.Context, result *types.CommitPRResolution) error {
+	prs, err := s.repo.ListMergedPRs(ctx, db.PRListFilter{Limit: prBranchScan})
+	if err != nil {
+		return fmt.Errorf("list recent PRs: %w", err)
+	}
+	for i := len(prs) - 1; i >= 0; i-- {
+		head := prs[i].HeadCommitSHA
+		if head == nil {
+			continue
+		}
+		// Heads of unfetched PR branches are missing locally; skip them.
+		if ok, err := s.git.IsAncestor(ctx, result.CommitSHA, *head); err != nil || !ok {
+			continue
+		}
+		if err := s.setPR(ctx, result, prs[i].PRNumber); err != nil {
+			return err
+		}
+		result.MatchedOn = "pr_branch"
+		return nil
+	}
+	return nil
+}
+
+func (s *dbCommitPRResolver) setPR(ctx context.Context, result *types.CommitPRResolution, number int) error {
+	entity, err := s.repo.GetPRByNumber(ctx, number)
+	if err != nil {
+		return fmt.Errorf("load PR %d: %w", number, err)
+	}
+	if entity != nil {
+		pr := s.repo.ToPRResult(*entity, nil)
+		result.PR = &pr
+	}
+	return nil
+}
+
+func (h *FindPRForCommitHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sha, _ := req.GetArguments()["sha"].(string)
+	sha = strings.ToLower(strings.TrimSpace(sha))
+	if sha == "" {
+		return mcp.NewToolResultError("sha parameter is required"), nil
+	}
+	if !gitrepo.IsCommitSHA(sha) {
+		return mcp.NewToolResultError("sha must be a hex commit SHA of 7 to 40 characters"), nil
+	}
+
+	resolution, err := h.Service.FindPRForCommit(ctx, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	response := types.Result[types.CommitPRResolution]{Result: resolution}
+
+	return structuredResult(response), nil
+}
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const defaultTicketPRsLimit = 50
+
+var ticketPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]+-[1-9][0-9]*$`)
+
+type TicketService interface {
+	FindPRsForTicket(ctx context.Context, ticket string, limit int) ([]types.TicketPR, error)
+}
+
+// FindPRsForTicketHandler lists the PRs whose title or body mentions an
+// issue-tracker ticket.
+type FindPRsForTicketHandler struct {
+	Service TicketService
+}
+
+type dbTicketService struct {
+	repo *db.SearchRepository
+}
+
+func NewDBTicketService(repo *db.SearchRepository) TicketService {
+	return &dbTicketService{repo: repo}
+}
+
+func (s *dbTicketService) FindPRsForTicket(ctx context.Context, ticket string, limit int) ([]types.TicketPR, error) {
+	prs, err := s.repo.FindPRsForTicket(ctx, ticket, limit)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]types.TicketPR, 0, len(prs))
+	for _, pr := range prs {
+		results = append(results, types.TicketPR{PRResult: s.repo.ToPRResult(pr.PR, nil), ReferencedIn: pr.Source})
+	}
+	return results, nil
+}
+
+func (h *FindPRsForTicketHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	raw, _ := args["ticket"].(string)
+	ticket := strings.ToUpper(strings.TrimSpace(raw))
+	if i := strings.LastIndex(ticket, "/BROWSE/"); i >= 0 {
+		ticket = ticket[i+len("/BROWSE/"):]
+	}
+	if !ticketPattern.MatchString(ticket) {
+		return mcp.NewToolResultError("ticket must be a Jira key such as ARO-1234 or OCPBUGS-567"), nil
+	}
+	limit := defaultTicketPRsLimit
+	if raw, ok := args["limit"].(float64); ok && int(raw) > 0 {
+		limit = min(int(raw), maxListPRsLimit)
+	}
+	bodyMax := defaultBodyMaxChars
+	if raw, ok := args["body_max_chars"].(float64); ok && raw >= 0 {
+		bodyMax = int(raw)
+	}
+
+	results, err := h.Service.FindPRsForTicket(ctx, ticket, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Body, results[i].IsTruncated = truncateBody(results[i].Body, bodyMax)
+	}
+
+	response := types.FindPRsForTicketResponse{Ticket: ticket, Results: results, Total: len(results)}
+
+	return structuredResult(response), nil
+}
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const defaultSimilarPRsLimit = 10
+
+var errPRNotFound = errors.New("PR not found")
+
+type SimilarPRService interface {
+	FindSimilarPRs(ctx context.Context, prNumber, limit int) ([]types.PRResult, error)
+}
+
+// FindSimilarPRsHandler finds the PRs nearest to a stored PR's embedding,
+// for prior art and possible regressions.
+type FindSimilarPRsHandler struct {
+	Service SimilarPRService
+}
+
+type dbSimilarPRService struct {
+	repo *db.SearchRepository
+}
+
+func NewDBSimilarPRService(repo *db.SearchRepository) SimilarPRService {
+	return &dbSimilarPRService{repo: repo}
+}
+
+func (s *dbSimilarPRService) FindSimilarPRs(ctx context.Context, prNumber, limit int) ([]types.PRResult, error) {
+	rows, found, err := s.repo.SimilarPRs(ctx, prNumber, limit)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errPRNotFound
+	}
+	return prResults(s.repo, rows, false), nil
+}
+
+func (h *FindSimilarPRsHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	prNumber, err := parseIntArgument(args["pr_number"])
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	limit := defaultSimilarPRsLimit
+	if raw, ok := args["limit"].(float64); ok && int(raw) > 0 {
+		limit = min(int(raw), maxListPRsLimit)
+	}
+	bodyMax := defaultBodyMaxChars
+	if raw, ok := args["body_max_chars"].(float64); ok && raw >= 0 {
+		bodyMax = int(raw)
+	}
+
+	results, err := h.Service.FindSimilarPRs(ctx, prNumber, limit)
+	switch {
+	case errors.Is(err, errPRNotFound):
+		return mcp.NewToolResultError(fmt.Sprintf("PR #%d not found", prNumber)), nil
+	case errors.Is(err, db.ErrPRNotEmbedded):
+		return mcp.NewToolResultError(fmt.Sprintf("PR #%d has not been processed yet; try again after the next PROCESS run", prNumber)), nil
+	case err != nil:
+		return nil, err
+	}
+	for i := range results {
+		results[i].Body, results[i].IsTruncated = truncateBody(results[i].Body, bodyMax)
+	}
+
+	response := types.FindSimilarPRsResponse{PRNumber: prNumber, Results: results, Total: len(results)}
+
+	return structuredResult(response), nil
+}
@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const defaultDeploymentHistory = 5
+
+type DeploymentService interface {
+	// Deployments returns environment's deployments at or before at, the
+	// first being the one running at that time.
+	Deployments(ctx context.Context, environment string, at time.Time, limit int) ([]types.Deployment, error)
+}
+
+type GetDeploymentHandler struct {
+	Service DeploymentService
+}
+
+type dbDeploymentService struct {
+	repo *db.SearchRepository
+}
+
+func NewDBDeploymentService(repo *db.SearchRepository) DeploymentService {
+	return &dbDeploymentService{repo: repo}
+}
+
+func (s *dbDeploymentService) Deployments(ctx context.Context, environment string, at time.Time, limit int) ([]types.Deployment, error) {
+	rows, err := s.repo.DeploymentHistory(ctx, environment, at, limit)
+	if err != nil {
+		return nil, err
+	}
+	shas := make([]string, 0, len(rows))
+	for _, row := range rows {
+		shas = append(shas, row.CommitSHA)
+	}
+	prs, err := s.repo.PRsByMergeCommitSHAs(ctx, shas)
+	if err != nil {
+		return nil, err
+	}
+	byMerge := make(map[string]db.PREmbedding, len(prs))
+	for _, pr := range prs {
+		byMerge[*pr.MergeCommitSHA] = pr
+	}
+
+	deployments := make([]types.Deployment, 0, len(rows))
+	for _, row := range rows {
+		d := types.Deployment{
+			Environment: row.Environment,
+			CommitSHA:   row.CommitSHA,
+			DeployedAt:  row.DeployedAt.UTC().Format(time.RFC3339),
+			Source:      row.Source,
+		}
+		if pr, ok := byMerge[row.CommitSHA]; ok {
+			result := s.repo.ToPRResult(pr, nil)
+			d.PR = &result
+		}
+		deployments = append(deployments, d)
+	}
+	return deployments, nil
+}
+
+func (h *GetDeploymentHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	environment, _ := args["environment"].(string)
+	environment = strings.TrimSpace(environment)
+	if environment == "" {
+		return mcp.NewToolResultError("environment parameter is required"), nil
+	}
+	at := time.Now().UTC()
+	if raw, ok := args["at"].(string); ok && raw != "" {
+		parsed, err := parseTimeArgument("at", raw)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		at = parsed
+	}
+	limit := defaultDeploymentHistory
+	if raw, ok := args["history"].(float64); ok && int(raw) > 0 {
+		limit = int(raw)
+	}
+
+	deployments, err := h.Service.Deployments(ctx, environment, at, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := types.GetDeploymentResponse{Environment: environment, At: at.Format(time.RFC3339), History: deployments}
+	if len(deployments) > 0 {
+		response.Running = &deployments[0]
+	}
+
+	return structuredResult(response), nil
+}
@@ -2,15 +2,31 @@ package tools
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/mark3labs/mcp-go/mcp"
 
 	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/ingestion"
 	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
 )
 
+const (
+	sourceDatabase   = "database"
+	sourceGitHubLive = "github_live"
+)
+
 type DetailsService interface {
-	GetPRByNumber(ctx context.Context, prNumber int) (types.PRResult, error)
+	// GetPRByNumber returns the stored PR. With live set, a PR that is
+	// missing or not yet merged in the database is fetched from GitHub; it is
+	// stored only once merged, since every stored PR is queued for
+	// processing.
+	GetPRByNumber(ctx context.Context, prNumber int, live bool) (types.PRResult, error)
+}
+
+// PRFetcher fetches a single PR from GitHub.
+type PRFetcher interface {
+	FetchPR(ctx context.Context, number int) (ingestion.PRChange, error)
 }
 
 type GetPRDetailsHandler struct {
@@ -18,39 +34,67 @@ type GetPRDetailsHandler struct {
 }
 
 type dbDetailsService struct {
-	repo *db.SearchRepository
+	repo    *db.SearchRepository
+	fetcher PRFetcher
 }
 
-func NewDBDetailsService(repo *db.SearchRepository) DetailsService {
-	return &dbDetailsService{repo: repo}
+// NewDBDetailsService returns a DetailsService backed by repo. fetcher may be
+// nil, in which case live lookups are rejected.
+func NewDBDetailsService(repo *db.SearchRepository, fetcher PRFetcher) DetailsService {
+	return &dbDetailsService{repo: repo, fetcher: fetcher}
 }
 
-func (s *dbDetailsService) GetPRByNumber(ctx context.Context, prNumber int) (types.PRResult, error) {
+func (s *dbDetailsService) GetPRByNumber(ctx context.Context, prNumber int, live bool) (types.PRResult, error) {
 	entity, err := s.repo.GetPRByNumber(ctx, prNumber)
 	if err != nil {
 		return types.PRResult{}, err
 	}
+	// Ingestion only stores merged PRs, so an unmerged row is stale.
+	if live && (entity == nil || entity.MergedAt == nil) {
+		return s.fetchLive(ctx, prNumber)
+	}
 	if entity == nil {
 		return types.PRResult{}, nil
 	}
-	result := db.ToPRResult(*entity, nil)
+	result := s.repo.ToPRResult(*entity, nil)
+	result.Source = sourceDatabase
+	return result, nil
+}
+
+func (s *dbDetailsService) fetchLive(ctx context.Context, prNumber int) (types.PRResult, error) {
+	if s.fetcher == nil {
+		return types.PRResult{}, fmt.Errorf("live GitHub lookups are not configured")
+	}
+	pr, err := s.fetcher.FetchPR(ctx, prNumber)
+	if err != nil {
+		return types.PRResult{}, fmt.Errorf("fetch PR #%d from GitHub: %w", prNumber, err)
+	}
+	record := pr.Record()
+	// Marked live so ingestion still fetches the PRs merged before it.
+	record.IngestSource = db.SourceGitHubLive
+	if record.MergedAt != nil {
+		if _, err := s.repo.UpsertPR(ctx, record); err != nil {
+			return types.PRResult{}, fmt.Errorf("store PR #%d: %w", prNumber, err)
+		}
+	}
+	result := s.repo.ToPRResult(*record, nil)
+	result.Source = sourceGitHubLive
 	return result, nil
 }
 
 func (h *GetPRDetailsHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	idValue := req.GetArguments()["pr_number"]
-	number, err := parseIntArgument(idValue)
+	args := req.GetArguments()
+	number, err := parseIntArgument(args["pr_number"])
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	pr, err := h.Service.GetPRByNumber(ctx, number)
+	live, _ := args["live"].(bool)
+	pr, err := h.Service.GetPRByNumber(ctx, number, live)
 	if err != nil {
 		return nil, err
 	}
 
-	response := struct {
-		Result types.PRResult `json:"result"`
-	}{Result: pr}
+	response := types.Result[types.PRResult]{Result: pr}
 
-	return mcp.NewToolResultText(string(mustMarshal(response))), nil
+	return structuredResult(response), nil
 }
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+type HubStatsService interface {
+	HubStats(ctx context.Context, evalRuns int) (types.HubStats, error)
+}
+
+type GetHubStatsHandler struct {
+	Service HubStatsService
+}
+
+type dbHubStatsService struct {
+	repo *db.SearchRepository
+}
+
+func NewDBHubStatsService(repo *db.SearchRepository) HubStatsService {
+	return &dbHubStatsService{repo: repo}
+}
+
+func (s *dbHubStatsService) HubStats(ctx context.Context, evalRuns int) (types.HubStats, error) {
+	stats, err := s.repo.HubStats(ctx)
+	if err != nil {
+		return types.HubStats{}, err
+	}
+	runs, err := s.repo.RecentEvalRuns(ctx, evalRuns)
+	if err != nil {
+		return types.HubStats{}, err
+	}
+
+	result := types.HubStats{
+		TotalPRs:     stats.TotalPRs,
+		ProcessedPRs: stats.ProcessedPRs,
+		FailedPRs:    stats.FailedPRs,
+		PendingPRs:   stats.PendingPRs,
+		Documents:    stats.Documents,
+		EvalRuns:     make([]types.EvalRunSummary, 0, len(runs)),
+	}
+	if stats.LatestMergedAt != nil {
+		latest := stats.LatestMergedAt.UTC().Format(time.RFC3339)
+		result.LatestMergedAt = &latest
+	}
+	for _, run := range runs {
+		result.EvalRuns = append(result.EvalRuns, types.EvalRunSummary{
+			StartedAt:  run.StartedAt.UTC().Format(time.RFC3339),
+			FinishedAt: run.FinishedAt.UTC().Format(time.RFC3339),
+			K:          run.K,
+			Cases:      run.Cases,
+			Hits:       run.Hits,
+			RecallAtK:  run.RecallAtK,
+			MRR:        run.MRR,
+			Error:      run.Error,
+		})
+	}
+	return result, nil
+}
+
+func (h *GetHubStatsHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	evalRuns := 10
+	if raw, ok := req.GetArguments()["eval_runs"].(float64); ok && int(raw) > 0 {
+		evalRuns = int(raw)
+	}
+	stats, err := h.Service.HubStats(ctx, evalRuns)
+	if err != nil {
+		return nil, err
+	}
+
+	response := types.Result[types.HubStats]{Result: stats}
+
+	return structuredResult(response), nil
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+// IngestionRunner queues ingestion runs as jobs and reports their status;
+// *ingestion.RunManager implements it.
+type IngestionRunner interface {
+	Start(ctx context.Context, mode string) (types.IngestionRun, error)
+	Get(ctx context.Context, id string) (types.IngestionRun, bool, error)
+}
+
+// TriggerIngestionHandler starts an asynchronous ingestion run. It is only
+// available when an admin token is configured and supplied by the caller.
+type TriggerIngestionHandler struct {
+	Service    IngestionRunner
+	AdminToken string
+}
+
+func (h *TriggerIngestionHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if h.AdminToken == "" {
+		return mcp.NewToolResultError("trigger_ingestion is disabled: MCP_ADMIN_TOKEN is not configured"), nil
+	}
+	args := req.GetArguments()
+	token, _ := args["admin_token"].(string)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) != 1 {
+		return mcp.NewToolResultError("invalid admin_token"), nil
+	}
+	mode, _ := args["mode"].(string)
+	if strings.TrimSpace(mode) == "" {
+		return mcp.NewToolResultError("mode is required"), nil
+	}
+
+	run, err := h.Service.Start(ctx, mode)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	response := types.Result[types.IngestionRun]{Result: run}
+
+	return structuredResult(response), nil
+}
+
+type GetIngestionRunHandler struct {
+	Service IngestionRunner
+}
+
+func (h *GetIngestionRunHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, _ := req.GetArguments()["run_id"].(string)
+	if strings.TrimSpace(id) == "" {
+		return mcp.NewToolResultError("run_id is required"), nil
+	}
+	run, ok, err := h.Service.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return mcp.NewToolResultError("ingestion run not found: " + id), nil
+	}
+
+	response := types.Result[types.IngestionRun]{Result: run}
+
+	return structuredResult(response), nil
+}
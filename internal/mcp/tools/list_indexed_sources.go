@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+// IndexedSourcesService lists the indexed documentation sources;
+// *db.SearchRepository implements it.
+type IndexedSourcesService interface {
+	IndexedSources(ctx context.Context) ([]db.IndexedSource, error)
+}
+
+type ListIndexedSourcesHandler struct {
+	Service IndexedSourcesService
+}
+
+func (h *ListIndexedSourcesHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sources, err := h.Service.IndexedSources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := types.ListIndexedSourcesResponse{Sources: make([]types.IndexedSource, 0, len(sources))}
+	for _, s := range sources {
+		resp.Sources = append(resp.Sources, types.IndexedSource{
+			Repo:       s.Repo,
+			Component:  s.Component,
+			DocTypes:   s.DocTypes,
+			Files:      s.Files,
+			Chunks:     s.Chunks,
+			CommitSHA:  s.CommitSHA,
+			LastUpdate: s.LastUpdate.UTC().Format(time.RFC3339),
+		})
+	}
+	return structuredResult(resp), nil
+}
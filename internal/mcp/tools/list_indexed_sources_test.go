@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+type fakeIndexedSources []db.IndexedSource
+
+func (f fakeIndexedSources) IndexedSources(context.Context) ([]db.IndexedSource, error) {
+	return f, nil
+}
+
+func TestListIndexedSources(t *testing.T) {
+	backend := "backend"
+	updated := time.Date(2025, 10, 1, 14, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	cases := []struct {
+		name    string
+		sources fakeIndexedSources
+		want    string
+	}{
+		{"none", nil, `{"sources":[]}`},
+		{"repo and component", fakeIndexedSources{
+			{Repo: "Azure/ARO-HCP", DocTypes: []string{"docs"}, Files: 2, Chunks: 3, CommitSHA: "abc", LastUpdate: updated},
+			{Repo: "Azure/ARO-HCP", Component: &backend, DocTypes: []string{"readme", "runbook"}, Files: 1, Chunks: 1, CommitSHA: "def", LastUpdate: updated},
+		}, `{"sources":[` +
+			`{"repo":"Azure/ARO-HCP","doc_types":["docs"],"files":2,"chunks":3,"commit_sha":"abc","last_update":"2025-10-01T12:00:00Z"},` +
+			`{"repo":"Azure/ARO-HCP","component":"backend","doc_types":["readme","runbook"],"files":1,"chunks":1,"commit_sha":"def","last_update":"2025-10-01T12:00:00Z"}]}`},
+	}
+	for _, c := range cases {
+		res, err := (&ListIndexedSourcesHandler{Service: c.sources}).ToolAdapter(context.Background(), mcp.CallToolRequest{})
+		if err != nil || res.IsError {
+			t.Errorf("%s: result %+v, %v", c.name, res, err)
+			continue
+		}
+		got, err := json.Marshal(res.StructuredContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != c.want {
+			t.Errorf("%s: got %s\nwant %s", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const (
+	defaultJobsLimit = 20
+	maxJobsLimit     = 200
+)
+
+// JobsService reads the jobs table; *db.SearchRepository implements it.
+type JobsService interface {
+	GetJob(ctx context.Context, id string) (*db.Job, error)
+	ListJobs(ctx context.Context, kind string, limit int) ([]db.Job, error)
+}
+
+// ListJobsHandler reports the status of background jobs.
+type ListJobsHandler struct {
+	Service JobsService
+}
+
+func (h *ListJobsHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	if id, _ := args["job_id"].(string); strings.TrimSpace(id) != "" {
+		job, err := h.Service.GetJob(ctx, strings.TrimSpace(id))
+		if err != nil {
+			return nil, err
+		}
+		if job == nil {
+			return mcp.NewToolResultError("job not found: " + id), nil
+		}
+		return structuredResult(types.ListJobsResponse{Jobs: []types.Job{toJob(*job)}}), nil
+	}
+	kind, _ := args["kind"].(string)
+	limit := defaultJobsLimit
+	if raw, ok := args["limit"].(float64); ok && raw > 0 {
+		limit = min(int(raw), maxJobsLimit)
+	}
+	found, err := h.Service.ListJobs(ctx, strings.TrimSpace(kind), limit)
+	if err != nil {
+		return nil, err
+	}
+	resp := types.ListJobsResponse{Jobs: make([]types.Job, 0, len(found))}
+	for _, j := range found {
+		resp.Jobs = append(resp.Jobs, toJob(j))
+	}
+	return structuredResult(resp), nil
+}
+
+func toJob(j db.Job) types.Job {
+	job := types.Job{
+		JobID:      j.ID,
+		Kind:       j.Kind,
+		Status:     j.Status,
+		Done:       j.Done,
+		Total:      j.Total,
+		Attempts:   j.Attempts,
+		Worker:     j.Worker,
+		CreatedAt:  j.CreatedAt.UTC().Format(time.RFC3339),
+		StartedAt:  formatTime(j.StartedAt),
+		FinishedAt: formatTime(j.FinishedAt),
+		Error:      j.Error,
+	}
+	_ = json.Unmarshal(j.Params, &job.Params)
+	if j.Phase != nil {
+		job.Phase = *j.Phase
+	}
+	return job
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.UTC().Format(time.RFC3339)
+	return &s
+}
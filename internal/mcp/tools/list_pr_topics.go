@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const defaultTopicPRsLimit = 50
+
+type TopicService interface {
+	ListTopics(ctx context.Context) ([]types.PRTopic, error)
+	// TopicPRs returns the topic with id and up to limit of its PRs, or a
+	// nil topic when there is none.
+	TopicPRs(ctx context.Context, id, limit int) (*types.PRTopic, []types.PRResult, error)
+}
+
+// ListPRTopicsHandler browses the PR topics found by 'ingest cluster'.
+type ListPRTopicsHandler struct {
+	Service TopicService
+}
+
+type dbTopicService struct {
+	repo *db.SearchRepository
+}
+
+func NewDBTopicService(repo *db.SearchRepository) TopicService {
+	return &dbTopicService{repo: repo}
+}
+
+func toPRTopic(t db.PRTopic) types.PRTopic {
+	return types.PRTopic{ID: t.ID, Label: t.Label, Summary: t.Summary, Size: t.Size, CreatedAt: t.CreatedAt.Format(time.RFC3339)}
+}
+
+func (s *dbTopicService) ListTopics(ctx context.Context) ([]types.PRTopic, error) {
+	found, err := s.repo.ListTopics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]types.PRTopic, 0, len(found))
+	for _, t := range found {
+		results = append(results, toPRTopic(t))
+	}
+	return results, nil
+}
+
+func (s *dbTopicService) TopicPRs(ctx context.Context, id, limit int) (*types.PRTopic, []types.PRResult, error) {
+	topic, err := s.repo.GetTopic(ctx, id)
+	if err != nil || topic == nil {
+		return nil, nil, err
+	}
+	prs, err := s.repo.TopicPRs(ctx, id, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make([]types.PRResult, 0, len(prs))
+	for _, pr := range prs {
+		results = append(results, s.repo.ToPRResult(pr, nil))
+	}
+	t := toPRTopic(*topic)
+	return &t, results, nil
+}
+
+func (h *ListPRTopicsHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	raw, ok := args["topic_id"].(float64)
+	if !ok {
+		found, err := h.Service.ListTopics(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			return mcp.NewToolResultError("no topics yet; run 'ingest cluster' to build them"), nil
+		}
+		return structuredResult(types.ListPRTopicsResponse{Topics: found}), nil
+	}
+
+	limit := defaultTopicPRsLimit
+	if raw, ok := args["limit"].(float64); ok && int(raw) > 0 {
+		limit = min(int(raw), maxListPRsLimit)
+	}
+	bodyMax := defaultBodyMaxChars
+	if raw, ok := args["body_max_chars"].(float64); ok && raw >= 0 {
+		bodyMax = int(raw)
+	}
+	topic, prs, err := h.Service.TopicPRs(ctx, int(raw), limit)
+	if err != nil {
+		return nil, err
+	}
+	if topic == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("topic %d not found; call list_pr_topics without topic_id for the current topics", int(raw))), nil
+	}
+	for i := range prs {
+		prs[i].Body, prs[i].IsTruncated = truncateBody(prs[i].Body, bodyMax)
+	}
+
+	return structuredResult(types.ListPRTopicsResponse{Topic: topic, PRs: prs}), nil
+}
@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const (
+	defaultListPRsLimit = 50
+	maxListPRsLimit     = 500
+)
+
+type PRLister interface {
+	ListPRs(ctx context.Context, filter db.PRListFilter) ([]types.PRResult, error)
+}
+
+// ListPRsHandler lists merged PRs by merge time without semantic ranking.
+type ListPRsHandler struct {
+	Service PRLister
+}
+
+type dbPRLister struct {
+	repo *db.SearchRepository
+}
+
+func NewDBPRLister(repo *db.SearchRepository) PRLister {
+	return &dbPRLister{repo: repo}
+}
+
+func (s *dbPRLister) ListPRs(ctx context.Context, filter db.PRListFilter) ([]types.PRResult, error) {
+	prs, err := s.repo.ListMergedPRs(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]types.PRResult, 0, len(prs))
+	for _, pr := range prs {
+		results = append(results, s.repo.ToPRResult(pr, nil))
+	}
+	return results, nil
+}
+
+func (h *ListPRsHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	filter := db.PRListFilter{Limit: defaultListPRsLimit}
+
+	if raw, ok := args["merged_after"].(string); ok && raw != "" {
+		parsed, err := parseTimeArgument("merged_after", raw)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		filter.MergedAfter = parsed
+	}
+	if raw, ok := args["merged_before"].(string); ok && raw != "" {
+		parsed, err := parseTimeArgument("merged_before", raw)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		filter.MergedBefore = parsed
+	}
+	if !filter.MergedAfter.IsZero() && !filter.MergedBefore.IsZero() && !filter.MergedAfter.Before(filter.MergedBefore) {
+		return mcp.NewToolResultError("merged_after must be before merged_before"), nil
+	}
+	if raw, ok := args["author"].(string); ok {
+		filter.Author = strings.TrimPrefix(strings.TrimSpace(raw), "@")
+	}
+	if raw, ok := args["min_lines_changed"].(float64); ok && raw > 0 {
+		filter.MinLines = int(raw)
+	}
+	filter.WithinDirs = stringArrayArgument(args["within_dirs"])
+	if raw, ok := args["limit"].(float64); ok && int(raw) > 0 {
+		filter.Limit = min(int(raw), maxListPRsLimit)
+	}
+	bodyMax := defaultBodyMaxChars
+	if raw, ok := args["body_max_chars"].(float64); ok && raw >= 0 {
+		bodyMax = int(raw)
+	}
+
+	results, err := h.Service.ListPRs(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Body, results[i].IsTruncated = truncateBody(results[i].Body, bodyMax)
+	}
+
+	response := types.ListPRsResponse{
+		MergedAfter:  formatOptionalTime(filter.MergedAfter),
+		MergedBefore: formatOptionalTime(filter.MergedBefore),
+		Author:       filter.Author,
+		MinLines:     filter.MinLines,
+		WithinDirs:   filter.WithinDirs,
+		Results:      results,
+		Total:        len(results),
+	}
+
+	return structuredResult(response), nil
+}
+
+func formatOptionalTime(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+	s := t.Format(time.RFC3339)
+	return &s
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+	"github.com/roivaz/aro-hcp-intelhub/internal/releasenotes"
+)
+
+type ReleaseNotesService interface {
+	Between(ctx context.Context, from, to string) (releasenotes.Notes, error)
+	BetweenEnvironments(ctx context.Context, from, to string) (releasenotes.Notes, error)
+}
+
+type ReleaseNotesHandler struct {
+	Service ReleaseNotesService
+}
+
+func (h *ReleaseNotesHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	str := func(name string) string {
+		v, _ := args[name].(string)
+		return strings.TrimSpace(v)
+	}
+	fromSHA, toSHA := str("from_sha"), str("to_sha")
+	fromEnv, toEnv := str("from_environment"), str("to_environment")
+
+	var notes releasenotes.Notes
+	var err error
+	switch {
+	case fromSHA != "" && toSHA != "":
+		notes, err = h.Service.Between(ctx, fromSHA, toSHA)
+	case fromEnv != "" && toEnv != "":
+		notes, err = h.Service.BetweenEnvironments(ctx, fromEnv, toEnv)
+	default:
+		return mcp.NewToolResultError("provide either from_sha and to_sha, or from_environment and to_environment"), nil
+	}
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	response := types.ReleaseNotesResponse{
+		FromSHA:   notes.From,
+		ToSHA:     notes.To,
+		PRCount:   len(notes.Entries),
+		Unmatched: notes.Unmatched,
+		Markdown:  notes.Markdown(),
+	}
+
+	return structuredResult(response), nil
+}
@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+// maxSearchAllLimit bounds search_all results; each source is searched for
+// that many candidates.
+const maxSearchAllLimit = 50
+
+type SearchAllService interface {
+	SearchAll(ctx context.Context, query string, limit int) ([]types.SearchAllResult, error)
+}
+
+// SearchAllHandler searches PRs and documentation together, for questions
+// that may be answered by either.
+type SearchAllHandler struct{ Service SearchAllService }
+
+func (h *SearchAllHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	query, _ := args["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return mcp.NewToolResultError("query parameter is required"), nil
+	}
+	limit := 10
+	if raw, ok := args["limit"].(float64); ok && raw > 0 {
+		limit = min(int(raw), maxSearchAllLimit)
+	}
+	bodyMax := defaultBodyMaxChars
+	if raw, ok := args["body_max_chars"].(float64); ok && raw >= 0 {
+		bodyMax = int(raw)
+	}
+
+	results, err := h.Service.SearchAll(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		if r.PR != nil {
+			r.PR.Body, r.PR.IsTruncated = truncateBody(r.PR.Body, bodyMax)
+		}
+	}
+
+	return structuredResult(types.SearchAllResponse{Query: query, Results: results, Total: len(results)}), nil
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+func TestRankSearchAll(t *testing.T) {
+	pr := func(n int, score float64) types.SearchAllResult {
+		return types.SearchAllResult{SourceType: types.SourcePR, Score: score, PR: &types.PRResult{PRNumber: n}}
+	}
+	doc := func(path string, score float64) types.SearchAllResult {
+		return types.SearchAllResult{SourceType: types.SourceDoc, Score: score, Doc: &types.DocResult{Path: path}}
+	}
+	label := func(results []types.SearchAllResult) []string {
+		var labels []string
+		for _, r := range results {
+			if r.PR != nil {
+				labels = append(labels, "pr:"+strconv.Itoa(r.PR.PRNumber))
+			} else {
+				labels = append(labels, "doc:"+r.Doc.Path)
+			}
+		}
+		return labels
+	}
+
+	cases := []struct {
+		name    string
+		results []types.SearchAllResult
+		limit   int
+		want    []string
+	}{
+		{"interleaved by score", []types.SearchAllResult{pr(1, 0.9), pr(2, 0.5), doc("a.md", 0.7), doc("b.md", 0.4)}, 10, []string{"pr:1", "doc:a.md", "pr:2", "doc:b.md"}},
+		{"ties keep PRs first", []types.SearchAllResult{pr(1, 0.8), doc("a.md", 0.8)}, 10, []string{"pr:1", "doc:a.md"}},
+		{"cut to limit", []types.SearchAllResult{pr(1, 0.2), doc("a.md", 0.9), doc("b.md", 0.8)}, 2, []string{"doc:a.md", "doc:b.md"}},
+		{"empty", nil, 10, nil},
+	}
+	for _, c := range cases {
+		if got := label(rankSearchAll(c.results, c.limit)); !slices.Equal(got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+// fakeSearchAll records the limit search_all asks for.
+type fakeSearchAll struct {
+	limit   int
+	results []types.SearchAllResult
+}
+
+func (f *fakeSearchAll) SearchAll(ctx context.Context, query string, limit int) ([]types.SearchAllResult, error) {
+	f.limit = limit
+	return f.results, nil
+}
+
+func TestSearchAllArguments(t *testing.T) {
+	cases := []struct {
+		args      map[string]any
+		wantLimit int
+		wantBody  string
+	}{
+		{map[string]any{"query": "q"}, 10, "0123456789"},
+		{map[string]any{"query": "q", "limit": 500.0}, maxSearchAllLimit, "0123456789"},
+		{map[string]any{"query": "q", "limit": 3.0, "body_max_chars": 4.0}, 3, "0123"},
+	}
+	for _, c := range cases {
+		service := &fakeSearchAll{results: []types.SearchAllResult{{SourceType: types.SourcePR, PR: &types.PRResult{Body: "0123456789"}}}}
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = c.args
+		res, err := (&SearchAllHandler{Service: service}).ToolAdapter(context.Background(), req)
+		if err != nil || res.IsError {
+			t.Errorf("args %v: result %+v, %v", c.args, res, err)
+			continue
+		}
+		if body := service.results[0].PR.Body; service.limit != c.wantLimit || body != c.wantBody {
+			t.Errorf("args %v: limit %d body %q, want %d %q", c.args, service.limit, body, c.wantLimit, c.wantBody)
+		}
+	}
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"query": " "}
+	if res, err := (&SearchAllHandler{}).ToolAdapter(context.Background(), req); err != nil || !res.IsError {
+		t.Errorf("blank query: result %+v, %v; want a tool error", res, err)
+	}
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// SearchCache keeps search pages for a short TTL so dashboards repeating the
+// same queries skip the embedding call and the vector search. Entries are
+// grouped by corpus (db.CorpusPRs, db.CorpusDocs) and dropped when the
+// corpus changes. A nil *SearchCache caches nothing.
+type SearchCache struct {
+	ttl        time.Duration
+	maxEntries int // 0 = unlimited
+	now        func() time.Time
+
+	mu          sync.Mutex
+	entries     map[string]searchCacheEntry
+	generations map[string]uint64
+}
+
+type searchCacheEntry struct {
+	corpus  string
+	results any
+	next    string
+	expires time.Time
+}
+
+// NewSearchCache returns a cache holding pages for ttl, or nil when ttl is
+// not positive.
+func NewSearchCache(ttl time.Duration, maxEntries int) *SearchCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &SearchCache{
+		ttl:         ttl,
+		maxEntries:  maxEntries,
+		now:         time.Now,
+		entries:     make(map[string]searchCacheEntry),
+		generations: make(map[string]uint64),
+	}
+}
+
+// Invalidate drops the pages of corpus, or of every corpus when it is "".
+func (c *SearchCache) Invalidate(corpus string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if corpus == "" || e.corpus == corpus {
+			delete(c.entries, key)
+		}
+	}
+	c.generations[corpus]++
+}
+
+// generation changes whenever corpus is invalidated, alone or with every
+// other corpus, so a page fetched across an invalidation is not stored.
+func (c *SearchCache) generation(corpus string) uint64 {
+	return c.generations[corpus] + c.generations[""]
+}
+
+func (c *SearchCache) get(key string) (searchCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if ok && !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	return e, ok
+}
+
+func (c *SearchCache) put(key string, e searchCacheEntry, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation(e.corpus) != generation {
+		return
+	}
+	now := c.now()
+	if c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		var oldest string
+		for k, old := range c.entries {
+			if !now.Before(old.expires) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldest == "" || old.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			delete(c.entries, oldest)
+		}
+	}
+	e.expires = now.Add(c.ttl)
+	c.entries[key] = e
+}
+
+// cachedPage returns the page stored under key or fetches and stores it.
+// Callers get their own copy of the results slice to modify.
+func cachedPage[T any](c *SearchCache, corpus, key string, fetch func() ([]T, string, error)) ([]T, string, error) {
+	if c == nil {
+		return fetch()
+	}
+	c.mu.Lock()
+	generation := c.generation(corpus)
+	c.mu.Unlock()
+	if e, ok := c.get(key); ok && e.corpus == corpus {
+		return slices.Clone(e.results.([]T)), e.next, nil
+	}
+	results, next, err := fetch()
+	if err != nil {
+		return nil, "", err
+	}
+	c.put(key, searchCacheEntry{corpus: corpus, results: slices.Clone(results), next: next}, generation)
+	return results, next, nil
+}
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchCache(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	c := NewSearchCache(time.Minute, 2)
+	c.now = func() time.Time { return now }
+
+	fetches := 0
+	fetch := func() ([]string, string, error) {
+		fetches++
+		return []string{"a", "b"}, "next", nil
+	}
+
+	got, next, _ := cachedPage(c, "prs", "q1", fetch)
+	got[0] = "modified"
+	got, next, _ = cachedPage(c, "prs", "q1", fetch)
+	if fetches != 1 || got[0] != "a" || next != "next" {
+		t.Fatalf("second call: fetches=%d got=%v next=%q", fetches, got, next)
+	}
+
+	cachedPage(c, "docs", "q2", fetch)
+	c.Invalidate("prs")
+	cachedPage(c, "prs", "q1", fetch)
+	cachedPage(c, "docs", "q2", fetch)
+	if fetches != 3 {
+		t.Errorf("after invalidating prs: %d fetches, want 3", fetches)
+	}
+
+	now = now.Add(2 * time.Minute)
+	cachedPage(c, "docs", "q2", fetch)
+	if fetches != 4 {
+		t.Errorf("expired entry served: %d fetches, want 4", fetches)
+	}
+
+	// A page fetched across an invalidation is not stored.
+	cachedPage(c, "docs", "q3", func() ([]string, string, error) {
+		c.Invalidate("")
+		return fetch()
+	})
+	cachedPage(c, "docs", "q3", fetch)
+	if fetches != 6 {
+		t.Errorf("stale page stored: %d fetches, want 6", fetches)
+	}
+
+	if len(c.entries) > 2 {
+		t.Errorf("%d entries cached, want at most 2", len(c.entries))
+	}
+
+	var disabled *SearchCache
+	cachedPage(disabled, "prs", "q1", fetch)
+	cachedPage(disabled, "prs", "q1", fetch)
+	if fetches != 8 {
+		t.Errorf("nil cache: %d fetches, want 8", fetches)
+	}
+}
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+type CodeSearchService interface {
+	SearchCodePage(ctx context.Context, query string, limit int, filter db.CodeSearchFilter, cursor string) ([]types.CodeResult, string, error)
+}
+
+// SearchCodeHandler serves search_code and, restricted to the configuration
+// languages, search_config.
+type SearchCodeHandler struct {
+	Service   CodeSearchService
+	Languages []string
+}
+
+func (h *SearchCodeHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	query, _ := args["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return mcp.NewToolResultError("query parameter is required"), nil
+	}
+	limit := 10
+	if raw, ok := args["limit"].(float64); ok && int(raw) > 0 {
+		limit = int(raw)
+	}
+	var filter db.CodeSearchFilter
+	filter.Repo, _ = args["repo"].(string)
+	filter.Language, _ = args["language"].(string)
+	filter.Languages = h.Languages
+	filter.PathPrefix, _ = args["path_prefix"].(string)
+	filter.PathPrefix = strings.TrimPrefix(filter.PathPrefix, "/")
+	quality, err := searchQualityArgument(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	filter.Quality = quality
+
+	cursor, _ := args["cursor"].(string)
+	results, next, err := h.Service.SearchCodePage(ctx, query, limit, filter, cursor)
+	if errors.Is(err, errInvalidCursor) {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := types.SearchCodeResponse{Query: query, Results: results, Total: len(results), NextCursor: next}
+
+	return structuredResult(response), nil
+}
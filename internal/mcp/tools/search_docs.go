@@ -2,22 +2,38 @@ package tools
 
 import (
 	"context"
-	"path/filepath"
+	"errors"
+	"slices"
 	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 
-	vcsurl "github.com/gitsight/go-vcsurl"
-	"github.com/roivaz/aro-hcp-intelhub/internal/config"
-	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
 	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
 )
 
 type DocSearchService interface {
-	SearchDocs(ctx context.Context, query string, limit int, component, repo *string, includeFull bool) ([]types.DocResult, error)
+	SearchDocsPage(ctx context.Context, query string, limit int, filter db.DocSearchFilter, expand DocExpansion, cursor string) ([]types.DocResult, string, error)
 }
 
-type SearchDocsHandler struct{ Service DocSearchService }
+// maxContextChunks bounds context_chunks, beyond which include_full_file is
+// the better fit.
+const maxContextChunks = 5
+
+// DocExpansion asks for more of each hit's file than the matched chunk.
+type DocExpansion struct {
+	IncludeFullFile bool // the whole file
+	ContextChunks   int  // up to this many chunks before and after the hit
+}
+
+// docTypes are the values the docs ingester assigns to DocumentChunk.DocType.
+var docTypes = []string{"readme", "docs", "adr", "runbook", "other"}
+
+type SearchDocsHandler struct {
+	Service DocSearchService
+	// MinSimilarity is the min_similarity applied when a call sets none.
+	MinSimilarity float64
+}
 
 func (h *SearchDocsHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := req.GetArguments()
@@ -31,51 +47,41 @@ func (h *SearchDocsHandler) ToolAdapter(ctx context.Context, req mcp.CallToolReq
 			limit = int(raw)
 		}
 	}
-	var componentPtr, repoPtr *string
-	if v, ok := args["component"].(string); ok && v != "" {
-		componentPtr = &v
+	var filter db.DocSearchFilter
+	filter.Component, _ = args["component"].(string)
+	filter.Repo, _ = args["repo"].(string)
+	filter.DocType, _ = args["doc_type"].(string)
+	filter.PathPrefix, _ = args["path_prefix"].(string)
+	filter.AlertName, _ = args["alert_name"].(string)
+	if filter.DocType != "" && !slices.Contains(docTypes, filter.DocType) {
+		return mcp.NewToolResultError("doc_type must be one of: " + strings.Join(docTypes, ", ")), nil
 	}
-	if v, ok := args["repo"].(string); ok && v != "" {
-		repoPtr = &v
+	filter.PathPrefix = strings.TrimPrefix(filter.PathPrefix, "/")
+	minSimilarity, err := minSimilarityArgument(args, h.MinSimilarity)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
-	includeFull := false
-	if v, ok := args["include_full_file"].(bool); ok {
-		includeFull = v
+	filter.MinSimilarity = minSimilarity
+	filter.Quality, err = searchQualityArgument(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var expand DocExpansion
+	expand.IncludeFullFile, _ = args["include_full_file"].(bool)
+	if raw, ok := args["context_chunks"].(float64); ok && raw > 0 {
+		expand.ContextChunks = min(int(raw), maxContextChunks)
 	}
 
-	results, err := h.Service.SearchDocs(ctx, query, limit, componentPtr, repoPtr, includeFull)
+	cursor, _ := args["cursor"].(string)
+	results, next, err := h.Service.SearchDocsPage(ctx, query, limit, filter, expand, cursor)
+	if errors.Is(err, errInvalidCursor) {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if includeFull {
-		// Enrich with full file content from local cache
-		for i := range results {
-			r := &results[i]
-			if r.Repo == "" || r.CommitSHA == "" || r.Path == "" {
-				continue
-			}
-
-			info, err := vcsurl.Parse(r.Repo)
-			if err != nil {
-				continue
-			}
-			localPath := filepath.Join(config.CacheDir(), info.Name)
-			// Ensure repo exists as in ingest command
-			_, _ = gitrepo.New(gitrepo.RepoConfig{URL: r.Repo, Path: localPath}).Ensure(ctx)
-			gr := gitrepo.New(gitrepo.RepoConfig{Path: localPath})
-			if content, err := gr.ShowFile(ctx, r.CommitSHA, r.Path); err == nil {
-				s := string(content)
-				r.Content = &s
-			}
-		}
-	}
-
-	response := struct {
-		Query   string            `json:"query"`
-		Results []types.DocResult `json:"results"`
-		Total   int               `json:"total_found"`
-	}{Query: query, Results: results, Total: len(results)}
+	response := types.SearchDocsResponse{Query: query, Results: results, Total: len(results), NextCursor: next}
 
-	return mcp.NewToolResultText(string(mustMarshal(response))), nil
+	return structuredResult(response), nil
 }
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+// fakeDocSearch records the expansion search_docs asks for.
+type fakeDocSearch struct {
+	expand DocExpansion
+}
+
+func (f *fakeDocSearch) SearchDocsPage(ctx context.Context, query string, limit int, filter db.DocSearchFilter, expand DocExpansion, cursor string) ([]types.DocResult, string, error) {
+	f.expand = expand
+	return nil, "", nil
+}
+
+func TestSearchDocsExpansion(t *testing.T) {
+	cases := []struct {
+		args map[string]any
+		want DocExpansion
+	}{
+		{map[string]any{}, DocExpansion{}},
+		{map[string]any{"context_chunks": 2.0}, DocExpansion{ContextChunks: 2}},
+		{map[string]any{"context_chunks": 50.0}, DocExpansion{ContextChunks: maxContextChunks}},
+		{map[string]any{"context_chunks": -1.0}, DocExpansion{}},
+		{map[string]any{"context_chunks": "2"}, DocExpansion{}},
+		{map[string]any{"include_full_file": true, "context_chunks": 1.0}, DocExpansion{IncludeFullFile: true, ContextChunks: 1}},
+	}
+	for _, c := range cases {
+		service := &fakeDocSearch{}
+		args := map[string]any{"query": "rollout"}
+		maps.Copy(args, c.args)
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := (&SearchDocsHandler{Service: service}).ToolAdapter(context.Background(), req)
+		if err != nil || res.IsError {
+			t.Errorf("args %v: result %+v, %v", c.args, res, err)
+			continue
+		}
+		if service.expand != c.want {
+			t.Errorf("args %v: expansion %+v, want %+v", c.args, service.expand, c.want)
+		}
+	}
+}
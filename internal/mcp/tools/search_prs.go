@@ -2,18 +2,29 @@ package tools
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
 	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
 )
 
+const (
+	defaultBodyMaxChars     = 2000
+	defaultAnalysisMaxChars = 4000
+)
+
 type SearchService interface {
-	SearchPRs(ctx context.Context, query string, limit int) ([]types.PRResult, error)
+	SearchPRsPage(ctx context.Context, query string, limit int, filter db.PRSearchFilter, cursor string) ([]types.PRResult, string, error)
 }
 
 type SearchPRsHandler struct {
 	Service SearchService
+	// MinSimilarity is the min_similarity applied when a call sets none.
+	MinSimilarity float64
 }
 
 type SearchPRsParams struct {
@@ -34,16 +45,75 @@ func (h *SearchPRsHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequ
 			limit = parsed
 		}
 	}
-	results, err := h.Service.SearchPRs(ctx, query, limit)
+	bodyMax := defaultBodyMaxChars
+	if rawMax, ok := args["body_max_chars"].(float64); ok && rawMax >= 0 {
+		bodyMax = int(rawMax)
+	}
+	includeAnalysis, _ := args["include_analysis"].(bool)
+	analysisMax := defaultAnalysisMaxChars
+	if rawMax, ok := args["analysis_max_chars"].(float64); ok && rawMax >= 0 {
+		analysisMax = int(rawMax)
+	}
+	var filter db.PRSearchFilter
+	filter.Labels = stringArrayArgument(args["labels"])
+	filter.Milestone, _ = args["milestone"].(string)
+	filter.LinkedIssue = normalizeIssueRef(args["linked_issue"])
+	minSimilarity, err := minSimilarityArgument(args, h.MinSimilarity)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	filter.MinSimilarity = minSimilarity
+	filter.Quality, err = searchQualityArgument(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	cursor, _ := args["cursor"].(string)
+	results, next, err := h.Service.SearchPRsPage(ctx, query, limit, filter, cursor)
+	if errors.Is(err, errInvalidCursor) {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	for i := range results {
+		results[i].Body, results[i].IsTruncated = truncateBody(results[i].Body, bodyMax)
+		analysis := results[i].Analysis
+		if !includeAnalysis || analysis == nil {
+			results[i].Analysis = nil
+			continue
+		}
+		if analysis.RichDescription != nil {
+			// Truncate a copy; the service may share analyses between calls.
+			a := *analysis
+			desc, truncated := truncateBody(*a.RichDescription, analysisMax)
+			a.RichDescription, a.IsTruncated = &desc, truncated
+			results[i].Analysis = &a
+		}
+	}
 
-	response := struct {
-		Query   string           `json:"query"`
-		Results []types.PRResult `json:"results"`
-		Total   int              `json:"total_found"`
-	}{Query: query, Results: results, Total: len(results)}
+	response := types.SearchPRsResponse{Query: query, Results: results, Total: len(results), NextCursor: next}
 
-	return mcp.NewToolResultText(string(mustMarshal(response))), nil
+	return structuredResult(response), nil
+}
+
+// normalizeIssueRef accepts a linked issue as 1234, "#1234", "org/repo#12"
+// or a Jira key such as "aro-1234" and returns it in the form ingestion
+// stores.
+func normalizeIssueRef(raw any) string {
+	var ref string
+	switch v := raw.(type) {
+	case float64:
+		return fmt.Sprintf("#%d", int(v))
+	case string:
+		ref = strings.TrimSpace(v)
+	}
+	switch {
+	case ref == "":
+		return ""
+	case ref[0] >= '0' && ref[0] <= '9':
+		return "#" + ref
+	case !strings.Contains(ref, "#"):
+		return strings.ToUpper(ref)
+	}
+	return ref
 }
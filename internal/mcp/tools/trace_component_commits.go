@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+const (
+	defaultComponentCommits = 30
+	maxComponentCommits     = 200
+)
+
+// ComponentCommitsService lists the source commits inside a component image;
+// *traceimages.CommitLog implements it.
+type ComponentCommitsService interface {
+	ComponentCommits(ctx context.Context, component, sourceRepoURL, sourceSHA, sinceSHA string, max int) (types.ComponentCommitsResponse, error)
+}
+
+type TraceComponentCommitsHandler struct {
+	Service ComponentCommitsService
+}
+
+func (h *TraceComponentCommitsHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	str := func(name string) string {
+		v, _ := args[name].(string)
+		return strings.TrimSpace(v)
+	}
+	component, repoURL, sha, since := str("component"), str("source_repo_url"), str("source_sha"), str("since_sha")
+	if sha == "" {
+		return mcp.NewToolResultError("source_sha is required"), nil
+	}
+	sha, since = strings.ToLower(sha), strings.ToLower(since)
+	if !gitrepo.IsCommitSHA(sha) || (since != "" && !gitrepo.IsCommitSHA(since)) {
+		return mcp.NewToolResultError("source_sha and since_sha must be hex commit SHAs"), nil
+	}
+	if component == "" && repoURL == "" {
+		return mcp.NewToolResultError("provide component or source_repo_url"), nil
+	}
+	limit := defaultComponentCommits
+	if raw, ok := args["max_commits"].(float64); ok && raw > 0 {
+		limit = min(int(raw), maxComponentCommits)
+	}
+
+	resp, err := h.Service.ComponentCommits(ctx, component, repoURL, sha, since, limit)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return structuredResult(resp), nil
+}
@@ -9,7 +9,7 @@ import (
 )
 
 type TraceService interface {
-	TraceImages(ctx context.Context, commitSHA, environment string) (types.TraceImagesResponse, error)
+	TraceImages(ctx context.Context, commitSHA, environment string, forceRefresh, includeSBOM bool) (types.TraceImagesResponse, error)
 }
 
 type TraceImagesHandler struct {
@@ -26,20 +26,18 @@ func (h *TraceImagesHandler) ToolAdapter(ctx context.Context, req mcp.CallToolRe
 	if env == "" {
 		return mcp.NewToolResultError("environment is required"), nil
 	}
-	resp, err := h.Service.TraceImages(ctx, commit, env)
+	forceRefresh, _ := args["force_refresh"].(bool)
+	includeSBOM, _ := args["include_sbom"].(bool)
+	resp, err := h.Service.TraceImages(ctx, commit, env, forceRefresh, includeSBOM)
 	if err != nil {
 		return nil, err
 	}
 
-	response := struct {
-		CommitSHA   string                    `json:"commit_sha"`
-		Environment string                    `json:"environment"`
-		Results     types.TraceImagesResponse `json:"results"`
-	}{
+	response := types.TraceImagesResult{
 		CommitSHA:   commit,
 		Environment: env,
 		Results:     resp,
 	}
 
-	return mcp.NewToolResultText(string(mustMarshal(response))), nil
+	return structuredResult(response), nil
 }
@@ -17,9 +17,9 @@ func NewTraceImagesServiceAdapter(svc *traceimages.Service) *TraceImagesServiceA
 	return &TraceImagesServiceAdapter{Service: svc}
 }
 
-func (a *TraceImagesServiceAdapter) TraceImages(ctx context.Context, commitSHA, environment string) (types.TraceImagesResponse, error) {
+func (a *TraceImagesServiceAdapter) TraceImages(ctx context.Context, commitSHA, environment string, forceRefresh, includeSBOM bool) (types.TraceImagesResponse, error) {
 	if a.Service == nil {
 		return types.TraceImagesResponse{}, fmt.Errorf("trace service not configured")
 	}
-	return a.Service.TraceImages(ctx, commitSHA, environment)
+	return a.Service.TraceImages(ctx, commitSHA, environment, forceRefresh, includeSBOM)
 }
@@ -0,0 +1,18 @@
+package types
+
+// AskResponse is the output of ask_intelhub.
+type AskResponse struct {
+	Question  string        `json:"question"`
+	Answer    string        `json:"answer"` // Markdown; citations link to their source
+	Citations []AskCitation `json:"citations"`
+	Model     string        `json:"model"`
+}
+
+// AskCitation is a source the answer cites.
+type AskCitation struct {
+	Label      string `json:"label"`       // as cited in the answer: "PR #1234" or "D2"
+	SourceType string `json:"source_type"` // pr|doc
+	Title      string `json:"title"`
+	URL        string `json:"url,omitempty"`
+	PRNumber   *int   `json:"pr_number,omitempty"`
+}
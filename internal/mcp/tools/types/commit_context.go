@@ -0,0 +1,14 @@
+package types
+
+// CommitContext bundles everything the hub knows about a commit.
+type CommitContext struct {
+	CommitSHA       string                         `json:"commit_sha"`
+	PR              *PRResult                      `json:"pr,omitempty"`
+	MatchedOn       string                         `json:"matched_on,omitempty"` // merge_commit_sha|head_commit_sha
+	RichDescription *string                        `json:"rich_description,omitempty"`
+	Components      []string                       `json:"components" jsonschema:"nullable"`
+	ChangedFiles    int                            `json:"changed_files"`
+	Traces          map[string]TraceImagesResponse `json:"traces" jsonschema:"nullable"` // cached traces keyed by environment
+	RelatedDocs     []DocResult                    `json:"related_docs" jsonschema:"nullable"`
+	Errors          []string                       `json:"errors,omitempty"`
+}
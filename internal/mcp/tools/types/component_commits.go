@@ -0,0 +1,23 @@
+package types
+
+// ComponentCommit is a commit in a component's source repository.
+type ComponentCommit struct {
+	SHA     string `json:"sha"`
+	Author  string `json:"author"`
+	Date    string `json:"date"` // RFC3339 author date
+	Subject string `json:"subject"`
+	URL     string `json:"url,omitempty"`
+}
+
+// ComponentCommitsResponse is the output of trace_component_commits: the
+// history of a traced component's source repository up to the commit its
+// image was built from.
+type ComponentCommitsResponse struct {
+	Component     string            `json:"component,omitempty"`
+	SourceRepoURL string            `json:"source_repo_url"`
+	SourceSHA     string            `json:"source_sha"`
+	SinceSHA      string            `json:"since_sha,omitempty"`
+	Commits       []ComponentCommit `json:"commits"`
+	// Truncated is set when more commits than max_commits are in range.
+	Truncated bool `json:"truncated"`
+}
@@ -0,0 +1,19 @@
+package types
+
+// Deployment is an ARO-HCP commit an environment started running.
+type Deployment struct {
+	Environment string    `json:"environment"`
+	CommitSHA   string    `json:"commit_sha"`
+	DeployedAt  string    `json:"deployed_at"`
+	Source      string    `json:"source"` // poll|webhook
+	PR          *PRResult `json:"pr,omitempty"`
+}
+
+// GetDeploymentResponse is the output of get_deployment. Running is the
+// deployment in effect at At, or null before the first recorded one.
+type GetDeploymentResponse struct {
+	Environment string       `json:"environment"`
+	At          string       `json:"at"`
+	Running     *Deployment  `json:"running" jsonschema:"nullable"`
+	History     []Deployment `json:"history" jsonschema:"nullable"`
+}
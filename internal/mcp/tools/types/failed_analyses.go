@@ -0,0 +1,27 @@
+package types
+
+// FailedAnalysis is a PR whose diff analysis failed.
+type FailedAnalysis struct {
+	PRNumber      int     `json:"pr_number"`
+	Title         string  `json:"title"`
+	Author        string  `json:"author"`
+	MergedAt      *string `json:"merged_at" jsonschema:"nullable"`
+	ProcessedAt   string  `json:"processed_at"`
+	FailureReason *string `json:"failure_reason,omitempty"`
+	GithubURL     string  `json:"github_url"`
+}
+
+// FailureGroup holds the failed analyses of one failure category. Count is
+// the size of the whole category; PRs lists the most recent of them.
+type FailureGroup struct {
+	Category string           `json:"failure_category"`
+	Count    int              `json:"count"`
+	PRs      []FailedAnalysis `json:"prs" jsonschema:"nullable"`
+}
+
+// FailedAnalysesResponse is the output of list_failed_analyses.
+type FailedAnalysesResponse struct {
+	Groups      []FailureGroup `json:"groups" jsonschema:"nullable"`
+	TotalFailed int            `json:"total_failed"`
+	Requeued    []int          `json:"requeued,omitempty"` // PRs marked for reprocessing by this call
+}
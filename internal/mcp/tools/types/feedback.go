@@ -0,0 +1,9 @@
+package types
+
+// FeedbackReceipt acknowledges a feedback call.
+type FeedbackReceipt struct {
+	PRNumber int    `json:"pr_number"`
+	Target   string `json:"target"`
+	Helpful  bool   `json:"helpful"`
+	Recorded bool   `json:"recorded"`
+}
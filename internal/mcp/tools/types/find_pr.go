@@ -0,0 +1,19 @@
+package types
+
+import "time"
+
+// CommitPRResolution is the PR that introduced a commit, and how it was found.
+type CommitPRResolution struct {
+	CommitSHA string     `json:"commit_sha"`
+	Subject   string     `json:"subject,omitempty"`
+	Author    string     `json:"author,omitempty"`
+	Date      *time.Time `json:"date,omitempty"` // author date
+	PR        *PRResult  `json:"pr,omitempty"`
+	// MatchedOn is merge_commit_sha or head_commit_sha for a direct match,
+	// introduced_by when the commit reached main through PR's merge commit,
+	// or pr_branch when it is an ancestor of the PR's head commit.
+	MatchedOn         string   `json:"matched_on,omitempty"`
+	IntroducingCommit string   `json:"introducing_commit,omitempty"` // first-parent commit on main that brought the commit in
+	Branches          []string `json:"branches,omitempty"`           // remote branches containing the commit
+	Errors            []string `json:"errors,omitempty"`
+}
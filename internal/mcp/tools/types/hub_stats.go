@@ -0,0 +1,30 @@
+package types
+
+// EvalCaseResult is the outcome of a single retrieval eval case.
+type EvalCaseResult struct {
+	Name  string  `json:"name"`
+	Query string  `json:"query"`
+	Rank  int     `json:"rank"` // 1-based position of the first expected hit; 0 = miss
+	Error *string `json:"error,omitempty"`
+}
+
+type EvalRunSummary struct {
+	StartedAt  string  `json:"started_at"`
+	FinishedAt string  `json:"finished_at"`
+	K          int     `json:"k"`
+	Cases      int     `json:"cases"`
+	Hits       int     `json:"hits"`
+	RecallAtK  float64 `json:"recall_at_k"`
+	MRR        float64 `json:"mrr"`
+	Error      *string `json:"error,omitempty"`
+}
+
+type HubStats struct {
+	TotalPRs       int              `json:"total_prs"`
+	ProcessedPRs   int              `json:"processed_prs"`
+	FailedPRs      int              `json:"failed_prs"`
+	PendingPRs     int              `json:"pending_prs"`
+	Documents      int              `json:"documents"`
+	LatestMergedAt *string          `json:"latest_merged_at,omitempty"`
+	EvalRuns       []EvalRunSummary `json:"eval_runs" jsonschema:"nullable"` // most recent first
+}
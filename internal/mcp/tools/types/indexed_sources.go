@@ -0,0 +1,18 @@
+package types
+
+// IndexedSource is a repository and component whose documentation is
+// indexed, with the values search_docs filters accept.
+type IndexedSource struct {
+	Repo       string   `json:"repo"`
+	Component  *string  `json:"component,omitempty"`
+	DocTypes   []string `json:"doc_types"`
+	Files      int      `json:"files"`
+	Chunks     int      `json:"chunks"`
+	CommitSHA  string   `json:"commit_sha"`  // last commit indexed
+	LastUpdate string   `json:"last_update"` // RFC3339
+}
+
+// ListIndexedSourcesResponse is the output of list_indexed_sources.
+type ListIndexedSourcesResponse struct {
+	Sources []IndexedSource `json:"sources"`
+}
@@ -0,0 +1,13 @@
+package types
+
+type IngestionRun struct {
+	RunID      string  `json:"run_id"`
+	Mode       string  `json:"mode"`
+	Status     string  `json:"status"` // queued|running|succeeded|failed
+	Phase      string  `json:"phase,omitempty"`
+	Done       int     `json:"done"`
+	Total      int     `json:"total"`
+	StartedAt  string  `json:"started_at"`
+	FinishedAt *string `json:"finished_at,omitempty"`
+	Error      *string `json:"error,omitempty"`
+}
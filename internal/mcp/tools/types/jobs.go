@@ -0,0 +1,24 @@
+package types
+
+// Job is a background job queued by trigger_ingestion or a CLI, such as
+// 'ingest cluster --async'.
+type Job struct {
+	JobID      string         `json:"job_id"`
+	Kind       string         `json:"kind"`   // ingestion|cluster
+	Status     string         `json:"status"` // queued|running|succeeded|failed
+	Params     map[string]any `json:"params,omitempty"`
+	Phase      string         `json:"phase,omitempty"`
+	Done       int            `json:"done"`
+	Total      int            `json:"total"`
+	Attempts   int            `json:"attempts"`
+	Worker     *string        `json:"worker,omitempty"`
+	CreatedAt  string         `json:"created_at"`
+	StartedAt  *string        `json:"started_at,omitempty"`
+	FinishedAt *string        `json:"finished_at,omitempty"`
+	Error      *string        `json:"error,omitempty"`
+}
+
+// ListJobsResponse is the output of list_jobs, newest job first.
+type ListJobsResponse struct {
+	Jobs []Job `json:"jobs"`
+}
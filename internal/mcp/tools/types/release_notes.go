@@ -0,0 +1,10 @@
+package types
+
+// ReleaseNotesResponse is the output of release_notes.
+type ReleaseNotesResponse struct {
+	FromSHA   string `json:"from_sha"`
+	ToSHA     string `json:"to_sha"`
+	PRCount   int    `json:"pr_count"`
+	Unmatched int    `json:"unmatched_commits"`
+	Markdown  string `json:"markdown"`
+}
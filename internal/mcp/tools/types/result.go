@@ -0,0 +1,6 @@
+package types
+
+// Result wraps the output of tools that return a single object.
+type Result[T any] struct {
+	Result T `json:"result"`
+}
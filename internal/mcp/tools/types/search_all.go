@@ -0,0 +1,23 @@
+package types
+
+// Source types of search_all results.
+const (
+	SourcePR  = "pr"
+	SourceDoc = "doc"
+)
+
+// SearchAllResult is a PR or a documentation chunk found by search_all;
+// SourceType says which of PR and Doc is set.
+type SearchAllResult struct {
+	SourceType string     `json:"source_type"` // pr|doc
+	Score      float64    `json:"score"`       // 0-1, comparable across source types
+	PR         *PRResult  `json:"pr,omitempty"`
+	Doc        *DocResult `json:"doc,omitempty"`
+}
+
+// SearchAllResponse is the output of search_all.
+type SearchAllResponse struct {
+	Query   string            `json:"query"`
+	Results []SearchAllResult `json:"results"`
+	Total   int               `json:"total_found"`
+}
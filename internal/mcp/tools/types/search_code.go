@@ -0,0 +1,25 @@
+package types
+
+// CodeResult is a source declaration returned by search_code.
+type CodeResult struct {
+	Repo       string  `json:"repo"`
+	Path       string  `json:"path"`
+	CommitSHA  string  `json:"commit_sha"`
+	Language   string  `json:"language"`
+	Symbol     string  `json:"symbol"`
+	Kind       string  `json:"kind"`
+	StartLine  int     `json:"start_line"`
+	EndLine    int     `json:"end_line"`
+	SourceURL  *string `json:"source_url,omitempty"`
+	Code       string  `json:"code"`
+	Similarity float64 `json:"similarity"` // distance mapped onto [0, 1] for the model's metric
+	Distance   float64 `json:"distance"`   // distance to the query under the model's metric
+}
+
+// SearchCodeResponse is the output of search_code and search_config.
+type SearchCodeResponse struct {
+	Query      string       `json:"query"`
+	Results    []CodeResult `json:"results"`
+	Total      int          `json:"total_found"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
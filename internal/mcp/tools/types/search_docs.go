@@ -1,12 +1,41 @@
 package types
 
 type DocResult struct {
-	Repo       string  `json:"repo"`
-	Component  *string `json:"component,omitempty"`
-	Path       string  `json:"path"`
-	CommitSHA  string  `json:"commit_sha"`
-	SourceURL  *string `json:"source_url,omitempty"`
-	Snippet    string  `json:"snippet"`
-	Similarity float64 `json:"similarity"`
-	Content    *string `json:"content,omitempty"`
+	Repo       string   `json:"repo"`
+	Component  *string  `json:"component,omitempty"`
+	Path       string   `json:"path"`
+	CommitSHA  string   `json:"commit_sha"`
+	ChunkIndex int      `json:"chunk_index"`
+	SourceURL  *string  `json:"source_url,omitempty"`
+	StartLine  *int     `json:"start_line,omitempty"` // 1-based, inclusive
+	EndLine    *int     `json:"end_line,omitempty"`
+	Anchor     *string  `json:"anchor,omitempty"`
+	DocType    string   `json:"doc_type,omitempty"`
+	Owner      *string  `json:"owner,omitempty"`
+	Severity   *string  `json:"severity,omitempty"`
+	AlertNames []string `json:"alert_names,omitempty"`
+	Snippet    string   `json:"snippet"`
+	Similarity float64  `json:"similarity"` // distance mapped onto [0, 1] for the model's metric
+	Distance   float64  `json:"distance"`   // distance to the query under the model's metric
+	Content    *string  `json:"content,omitempty"`
+	// ContextChunks are the neighbouring chunks requested with
+	// context_chunks, in file order.
+	ContextChunks []DocContextChunk `json:"context_chunks,omitempty"`
+}
+
+// DocContextChunk is a chunk next to a search_docs hit.
+type DocContextChunk struct {
+	ChunkIndex int     `json:"chunk_index"`
+	Text       string  `json:"text"`
+	StartLine  *int    `json:"start_line,omitempty"`
+	EndLine    *int    `json:"end_line,omitempty"`
+	Anchor     *string `json:"anchor,omitempty"`
+}
+
+// SearchDocsResponse is the output of search_docs.
+type SearchDocsResponse struct {
+	Query      string      `json:"query"`
+	Results    []DocResult `json:"results"`
+	Total      int         `json:"total_found"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
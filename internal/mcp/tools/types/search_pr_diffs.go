@@ -0,0 +1,22 @@
+package types
+
+// PRDiffResult is a piece of a PR's merge diff returned by search_pr_diffs.
+type PRDiffResult struct {
+	PRNumber   int     `json:"pr_number"`
+	Title      string  `json:"title"`
+	MergedAt   *string `json:"merged_at" jsonschema:"nullable"`
+	GithubURL  string  `json:"github_url"`
+	Path       string  `json:"path"`
+	ChunkIndex int     `json:"chunk_index"`
+	Diff       string  `json:"diff"`
+	Similarity float64 `json:"similarity"` // distance mapped onto [0, 1] for the model's metric
+	Distance   float64 `json:"distance"`   // distance to the query under the model's metric
+}
+
+// SearchPRDiffsResponse is the output of search_pr_diffs.
+type SearchPRDiffsResponse struct {
+	Query      string         `json:"query"`
+	Results    []PRDiffResult `json:"results"`
+	Total      int            `json:"total_found"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
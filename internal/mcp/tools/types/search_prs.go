@@ -1,13 +1,84 @@
 package types
 
 type PRResult struct {
-	PRNumber        int      `json:"pr_number"`
-	Title           string   `json:"title"`
-	Body            string   `json:"body"`
-	Author          string   `json:"author"`
-	State           string   `json:"state"`
-	CreatedAt       string   `json:"created_at"`
-	MergedAt        *string  `json:"merged_at"`
-	GithubURL       string   `json:"github_url"`
-	SimilarityScore *float64 `json:"similarity_score,omitempty"`
+	PRNumber        int         `json:"pr_number"`
+	Title           string      `json:"title"`
+	Body            string      `json:"body"`
+	IsTruncated     bool        `json:"is_truncated"`
+	Author          string      `json:"author"`
+	State           string      `json:"state"`
+	CreatedAt       string      `json:"created_at"`
+	MergedAt        *string     `json:"merged_at" jsonschema:"nullable"`
+	GithubURL       string      `json:"github_url"`
+	Labels          []string    `json:"labels,omitempty"`
+	Milestone       *string     `json:"milestone,omitempty"`
+	LinkedIssues    []string    `json:"linked_issues,omitempty"`
+	Changes         *PRChanges  `json:"changes,omitempty"`
+	SimilarityScore *float64    `json:"similarity_score,omitempty"` // distance mapped onto [0, 1] for the model's metric
+	Distance        *float64    `json:"distance,omitempty"`         // distance to the query under the model's metric
+	Analysis        *PRAnalysis `json:"analysis,omitempty"`
+	Source          string      `json:"source,omitempty"` // database|github_live
+}
+
+// PRChanges is the size of a PR's merge diff, present once the diff
+// analyzer has processed the PR.
+type PRChanges struct {
+	Additions    int      `json:"additions"`
+	Deletions    int      `json:"deletions"`
+	ChangedFiles int      `json:"changed_files"`
+	TopLevelDirs []string `json:"top_level_dirs"` // "." for files at the repository root
+}
+
+// PRAnalysis is the diff analysis stored for a PR. Status is pending until the
+// PR has been processed, then succeeded or failed.
+type PRAnalysis struct {
+	Status          string  `json:"status"`
+	RichDescription *string `json:"rich_description,omitempty"`
+	IsTruncated     bool    `json:"is_truncated"`
+	FailureCategory *string `json:"failure_category,omitempty"`
+	ProcessedAt     *string `json:"processed_at,omitempty"`
+}
+
+// SearchPRsResponse is the output of search_prs.
+type SearchPRsResponse struct {
+	Query      string     `json:"query"`
+	Results    []PRResult `json:"results"`
+	Total      int        `json:"total_found"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// ListPRsResponse is the output of list_prs.
+type ListPRsResponse struct {
+	MergedAfter  *string    `json:"merged_after,omitempty"`
+	MergedBefore *string    `json:"merged_before,omitempty"`
+	Author       string     `json:"author,omitempty"`
+	MinLines     int        `json:"min_lines_changed,omitempty"`
+	WithinDirs   []string   `json:"within_dirs,omitempty"`
+	Results      []PRResult `json:"results" jsonschema:"nullable"`
+	Total        int        `json:"total_found"`
+}
+
+// CorrelateIncidentResponse is the output of correlate_incident. With an
+// environment, Candidates are the PRs its Deployments in the window shipped;
+// otherwise, or when none is recorded (see Note), the PRs merged in it.
+type CorrelateIncidentResponse struct {
+	WindowStart string               `json:"window_start"`
+	WindowEnd   string               `json:"window_end"`
+	Environment string               `json:"environment,omitempty"`
+	Deployments []IncidentDeployment `json:"deployments,omitempty"`
+	Candidates  []PRResult           `json:"candidates" jsonschema:"nullable"`
+	Total       int                  `json:"total_found"`
+	Note        string               `json:"note,omitempty"`
+	Errors      []string             `json:"errors,omitempty"`
+}
+
+// IncidentDeployment is a deployment in the incident window and the
+// candidate PRs it shipped.
+type IncidentDeployment struct {
+	CommitSHA  string `json:"commit_sha"`
+	DeployedAt string `json:"deployed_at"`
+	// PreviousSHA is the commit the environment ran before, empty when no
+	// earlier deployment is recorded.
+	PreviousSHA string `json:"previous_sha,omitempty"`
+	PRNumbers   []int  `json:"candidate_pr_numbers"`
 }
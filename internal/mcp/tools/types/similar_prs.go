@@ -0,0 +1,8 @@
+package types
+
+// FindSimilarPRsResponse is the output of find_similar_prs.
+type FindSimilarPRsResponse struct {
+	PRNumber int        `json:"pr_number"`
+	Results  []PRResult `json:"results" jsonschema:"nullable"`
+	Total    int        `json:"total_found"`
+}
@@ -0,0 +1,14 @@
+package types
+
+// TicketPR is a PR mentioning a ticket.
+type TicketPR struct {
+	PRResult
+	ReferencedIn string `json:"referenced_in"` // title|body
+}
+
+// FindPRsForTicketResponse is the output of find_prs_for_ticket.
+type FindPRsForTicketResponse struct {
+	Ticket  string     `json:"ticket"`
+	Results []TicketPR `json:"results" jsonschema:"nullable"`
+	Total   int        `json:"total_found"`
+}
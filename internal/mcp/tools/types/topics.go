@@ -0,0 +1,18 @@
+package types
+
+// PRTopic is a cluster of similar PRs found by 'ingest cluster'.
+type PRTopic struct {
+	ID        int     `json:"topic_id"`
+	Label     string  `json:"label"`
+	Summary   *string `json:"summary,omitempty"`
+	Size      int     `json:"pr_count"`
+	CreatedAt string  `json:"clustered_at"`
+}
+
+// ListPRTopicsResponse is the output of list_pr_topics: every topic, or one
+// topic and its PRs when a topic_id is given.
+type ListPRTopicsResponse struct {
+	Topics []PRTopic  `json:"topics,omitempty"`
+	Topic  *PRTopic   `json:"topic,omitempty"`
+	PRs    []PRResult `json:"prs,omitempty"`
+}
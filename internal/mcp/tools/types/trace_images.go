@@ -1,18 +1,74 @@
 package types
 
 type ComponentTraceInfo struct {
-	Name          string  `json:"name"`
-	Registry      string  `json:"registry"`
-	Repository    string  `json:"repository"`
-	Digest        string  `json:"digest"`
-	SourceSHA     *string `json:"source_sha"`
-	SourceRepoURL *string `json:"source_repo_url"`
-	Error         *string `json:"error"`
+	Name       string `json:"name"`
+	Registry   string `json:"registry"`
+	Repository string `json:"repository"`
+	Digest     string `json:"digest"`
+	// ConfigTag is the tag the config references instead of a digest; Digest
+	// is then what the tag pointed at when the trace ran, which may differ
+	// from what was deployed for an old commit.
+	ConfigTag     string        `json:"config_tag,omitempty"`
+	SourceSHA     *string       `json:"source_sha" jsonschema:"nullable"`
+	SourceRepoURL *string       `json:"source_repo_url" jsonschema:"nullable"`
+	Tags          []string      `json:"tags,omitempty"`
+	LinkedPRs     []LinkedPR    `json:"linked_prs,omitempty"`
+	SBOM          *SBOMSummary  `json:"sbom,omitempty"`
+	ConfigSource  *ConfigSource `json:"config_source,omitempty"`
+	Error         *string       `json:"error" jsonschema:"nullable"`
+	// ErrorClass is "registry_unreachable" when the component was not
+	// inspected because its registry timed out; it then carries only the
+	// configured image reference.
+	ErrorClass *string `json:"error_class,omitempty" jsonschema:"nullable"`
+}
+
+// ConfigSource is where the traced commit sets a component's digest, and the
+// commit and PR that last changed that line.
+type ConfigSource struct {
+	File    string  `json:"file"`     // repo-relative path
+	KeyPath string  `json:"key_path"` // dotted YAML path of the digest, or tag
+	Line    int     `json:"line,omitempty"`
+	Commit  *string `json:"commit,omitempty" jsonschema:"nullable"` // from git blame
+	// MergeCommit is the first-parent commit that brought Commit into the
+	// traced commit: the merge of the PR, when Commit is a branch commit.
+	MergeCommit *string   `json:"merge_commit,omitempty" jsonschema:"nullable"`
+	PR          *LinkedPR `json:"pr,omitempty"`
+	Error       *string   `json:"error,omitempty" jsonschema:"nullable"`
+}
+
+// SBOMSummary summarizes a component's software bill of materials.
+type SBOMSummary struct {
+	Source       string        `json:"source"`           // attached|syft|none
+	Format       string        `json:"format,omitempty"` // spdx|cyclonedx
+	PackageCount int           `json:"package_count"`
+	Notable      []SBOMPackage `json:"notable_packages,omitempty"`
+	Error        *string       `json:"error,omitempty" jsonschema:"nullable"`
+}
+
+// SBOMPackage is a package listed in an SBOM.
+type SBOMPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// LinkedPR is an ingested pull request whose merge or head commit matches a
+// component's source SHA.
+type LinkedPR struct {
+	PRNumber  int    `json:"pr_number"`
+	Title     string `json:"title"`
+	MatchedOn string `json:"matched_on"` // merge_commit_sha|head_commit_sha
 }
 
 type TraceImagesResponse struct {
 	CommitSHA   string               `json:"commit_sha"`
 	Environment string               `json:"environment"`
-	Components  []ComponentTraceInfo `json:"components"`
-	Errors      []string             `json:"errors"`
+	Components  []ComponentTraceInfo `json:"components" jsonschema:"nullable"`
+	Errors      []string             `json:"errors" jsonschema:"nullable"`
+}
+
+// TraceImagesResult is the output of trace_images.
+type TraceImagesResult struct {
+	CommitSHA   string              `json:"commit_sha"`
+	Environment string              `json:"environment"`
+	Results     TraceImagesResponse `json:"results"`
 }
@@ -3,8 +3,28 @@ package tools
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
 )
 
+// stringArrayArgument returns the non-empty strings of an array argument.
+func stringArrayArgument(value any) []string {
+	raw, _ := value.([]any)
+	var values []string
+	for _, v := range raw {
+		if s, ok := v.(string); ok && strings.TrimSpace(s) != "" {
+			values = append(values, strings.TrimSpace(s))
+		}
+	}
+	return values
+}
+
 func parseIntArgument(value any) (int, error) {
 	switch v := value.(type) {
 	case float64:
@@ -22,6 +42,57 @@ func parseIntArgument(value any) (int, error) {
 	}
 }
 
+// minSimilarityArgument returns the min_similarity argument, or fallback
+// when it is absent.
+func minSimilarityArgument(args map[string]any, fallback float64) (float64, error) {
+	raw, ok := args["min_similarity"].(float64)
+	if !ok {
+		return fallback, nil
+	}
+	if raw < 0 || raw > 1 {
+		return 0, fmt.Errorf("min_similarity must be between 0 and 1")
+	}
+	return raw, nil
+}
+
+// searchQualityArgument returns the search_quality argument, empty when it
+// is absent.
+func searchQualityArgument(args map[string]any) (db.SearchQuality, error) {
+	raw, _ := args["search_quality"].(string)
+	if raw == "" {
+		return "", nil
+	}
+	if !slices.Contains(db.SearchQualities, raw) {
+		return "", fmt.Errorf("search_quality must be one of: %s", strings.Join(db.SearchQualities, ", "))
+	}
+	return db.SearchQuality(raw), nil
+}
+
+func parseTimeArgument(name, value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC3339 timestamp (e.g. 2025-10-01T14:00:00Z)", name)
+	}
+	return t.UTC(), nil
+}
+
+// truncateBody cuts body to at most max runes. A max of 0 or less keeps the
+// full body.
+func truncateBody(body string, max int) (string, bool) {
+	if max <= 0 || utf8.RuneCountInString(body) <= max {
+		return body, false
+	}
+	runes := []rune(body)
+	return string(runes[:max]), true
+}
+
+// structuredResult returns v as structured content matching the tool's
+// output schema, with its JSON as the text content for clients that predate
+// structured results.
+func structuredResult(v any) *mcp.CallToolResult {
+	return mcp.NewToolResultStructured(v, string(mustMarshal(v)))
+}
+
 func mustMarshal(v interface{}) []byte {
 	b, err := json.Marshal(v)
 	if err != nil {
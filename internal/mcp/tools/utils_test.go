@@ -0,0 +1,24 @@
+package tools
+
+import "testing"
+
+func TestMinSimilarityArgument(t *testing.T) {
+	for _, tc := range []struct {
+		args map[string]any
+		want float64
+		ok   bool
+	}{
+		{map[string]any{}, 0.3, true},
+		{map[string]any{"min_similarity": 0.0}, 0, true},
+		{map[string]any{"min_similarity": 0.75}, 0.75, true},
+		{map[string]any{"min_similarity": 1.0}, 1, true},
+		{map[string]any{"min_similarity": "0.5"}, 0.3, true},
+		{map[string]any{"min_similarity": -0.1}, 0, false},
+		{map[string]any{"min_similarity": 1.5}, 0, false},
+	} {
+		got, err := minSimilarityArgument(tc.args, 0.3)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("minSimilarityArgument(%v) = %v, %v; want %v, ok=%v", tc.args, got, err, tc.want, tc.ok)
+		}
+	}
+}
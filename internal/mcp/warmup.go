@@ -0,0 +1,37 @@
+package mcp
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+// warmUpIndexes reads the vector indexes into cache and warns about missing
+// indexes, indexes it could not prewarm and first queries slower than
+// threshold.
+func warmUpIndexes(ctx context.Context, repo *db.SearchRepository, threshold time.Duration) {
+	start := time.Now()
+	results, err := repo.WarmUpIndexes(ctx)
+	if err != nil {
+		log.Printf("WARNING: vector index warm-up failed: %v", err)
+		return
+	}
+	var blocks int64
+	for _, w := range results {
+		blocks += w.Blocks
+		if w.PrewarmErr != nil {
+			log.Printf("WARNING: vector index %s on %s not prewarmed: %v", w.Index, w.Table, w.PrewarmErr)
+		}
+		switch {
+		case w.Missing:
+			log.Printf("WARNING: vector index %s on %s is missing; searches scan the table", w.Index, w.Table)
+		case w.Empty:
+			log.Printf("vector index warm-up: %s has no vectors of the model, skipped", w.Table)
+		case threshold > 0 && w.Latency > threshold:
+			log.Printf("WARNING: first query through vector index %s on %s took %s (threshold %s)", w.Index, w.Table, w.Latency.Round(time.Millisecond), threshold)
+		}
+	}
+	log.Printf("vector index warm-up: %d indexes, %d blocks prewarmed in %s", len(results), blocks, time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,131 @@
+// Package ollama holds helpers for the Ollama HTTP API that the langchaingo
+// client does not cover: listing and pulling models.
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// listTimeout bounds the model listing request; pulls are bounded only by ctx
+// because large models take minutes to download.
+const listTimeout = 30 * time.Second
+
+// AvailableModels lists the models installed on the Ollama server at baseURL.
+func AvailableModels(ctx context.Context, baseURL string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, listTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint(baseURL, "/api/tags"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build ollama request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("reach ollama at %s: %w", baseURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama at %s returned %s", baseURL, resp.Status)
+	}
+
+	var payload struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("decode ollama models: %w", err)
+	}
+	names := make([]string, 0, len(payload.Models))
+	for _, m := range payload.Models {
+		names = append(names, m.Name)
+	}
+	return names, nil
+}
+
+// HasModel reports whether model is among available. Ollama lists untagged
+// models with an explicit ":latest" tag, so both spellings match.
+func HasModel(available []string, model string) bool {
+	want := model
+	if !strings.Contains(want, ":") {
+		want += ":latest"
+	}
+	for _, name := range available {
+		if name == model || name == want {
+			return true
+		}
+	}
+	return false
+}
+
+// PullModel asks the Ollama server to download model and waits until it is
+// available.
+func PullModel(ctx context.Context, baseURL, model string) error {
+	body, err := json.Marshal(map[string]any{"model": model, "stream": false})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint(baseURL, "/api/pull"), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build ollama request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("pull %s from ollama at %s: %w", model, baseURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pull %s from ollama at %s: %s: %s", model, baseURL, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var status struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return fmt.Errorf("decode ollama pull response: %w", err)
+	}
+	if status.Error != "" {
+		return fmt.Errorf("pull %s from ollama at %s: %s", model, baseURL, status.Error)
+	}
+	return nil
+}
+
+// EnsureModel verifies that model is installed on the Ollama server, pulling
+// it when autoPull is set. The error names the server and model so startup
+// failures are actionable.
+func EnsureModel(ctx context.Context, baseURL, model string, autoPull bool) error {
+	available, err := AvailableModels(ctx, baseURL)
+	if err != nil {
+		return fmt.Errorf("ollama preflight: %w", err)
+	}
+	if HasModel(available, model) {
+		return nil
+	}
+	if !autoPull {
+		return fmt.Errorf("ollama preflight: model %q is not available on %s; run 'ollama pull %s' or set OLLAMA_AUTO_PULL=true", model, baseURL, model)
+	}
+	if err := PullModel(ctx, baseURL, model); err != nil {
+		return fmt.Errorf("ollama preflight: %w", err)
+	}
+	return nil
+}
+
+// DefaultURL is where the Ollama server listens when no URL is configured.
+const DefaultURL = "http://localhost:11434"
+
+func endpoint(baseURL, path string) string {
+	base := strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if base == "" {
+		base = DefaultURL
+	}
+	return base + path
+}
@@ -0,0 +1,138 @@
+// Package progress reports how far long-running batch jobs have got: done
+// and total items, throughput and the estimated time left.
+package progress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
+)
+
+const (
+	// logInterval spaces the log lines written when not on a terminal.
+	logInterval = 30 * time.Second
+	// drawInterval limits redraws of the terminal bar.
+	drawInterval = 200 * time.Millisecond
+	barWidth     = 30
+)
+
+// Meter turns done/total updates into a progress bar on a terminal, or into
+// periodic structured log lines otherwise. Its Update method has the shape
+// of ingestion.ProgressFunc. A nil Meter ignores updates.
+type Meter struct {
+	name string
+	out  io.Writer // terminal to draw the bar on; nil logs instead
+	log  logging.Logger
+	now  func() time.Time
+
+	mu       sync.Mutex
+	phase    string
+	total    int
+	done     int
+	start    time.Time
+	lastEmit time.Time
+}
+
+// New returns a Meter for the job name that draws a bar when stderr is a
+// terminal and logs otherwise.
+func New(name string) *Meter {
+	m := &Meter{name: name, log: logging.FromContext(context.Background()).WithName(name), now: time.Now}
+	if isTerminal(os.Stderr) {
+		m.out = os.Stderr
+	}
+	return m
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Update records that done of total items of phase are complete. A new phase,
+// or done dropping back to zero, restarts the rate and ETA estimate.
+func (m *Meter) Update(phase string, done, total int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if phase != m.phase || done < m.done || m.start.IsZero() {
+		m.phase, m.start, m.lastEmit = phase, now, time.Time{}
+	}
+	m.done, m.total = done, total
+
+	finished := done >= total
+	interval := logInterval
+	if m.out != nil {
+		interval = drawInterval
+	}
+	if !finished && done > 0 && now.Sub(m.lastEmit) < interval {
+		return
+	}
+	m.lastEmit = now
+	m.emit(now, finished)
+}
+
+func (m *Meter) emit(now time.Time, finished bool) {
+	elapsed := now.Sub(m.start)
+	rate := Rate(m.done, elapsed)
+	eta := ETA(m.done, m.total, elapsed)
+	if m.out == nil {
+		if m.done == 0 && !finished {
+			m.log.Info("started", "phase", m.phase, "total", m.total)
+			return
+		}
+		kv := []any{"phase", m.phase, "done", m.done, "total", m.total, "per_second", fmt.Sprintf("%.2f", rate)}
+		if finished {
+			m.log.Info("finished", append(kv, "elapsed", elapsed.Round(time.Second).String())...)
+			return
+		}
+		m.log.Info("progress", append(kv, "eta", eta.Round(time.Second).String())...)
+		return
+	}
+	line := fmt.Sprintf("\r\033[K%s %s %s %d/%d %.1f/s", m.name, m.phase, bar(m.done, m.total), m.done, m.total, rate)
+	if finished {
+		line += fmt.Sprintf(" in %s\n", elapsed.Round(time.Second))
+	} else if m.done > 0 {
+		line += fmt.Sprintf(" ETA %s", eta.Round(time.Second))
+	}
+	fmt.Fprint(m.out, line)
+}
+
+// bar renders done/total as a fixed-width bar such as [=====>    ].
+func bar(done, total int) string {
+	filled := 0
+	if total > 0 {
+		filled = min(done*barWidth/total, barWidth)
+	}
+	head := ""
+	if filled < barWidth && filled > 0 {
+		filled--
+		head = ">"
+	}
+	return "[" + strings.Repeat("=", filled) + head + strings.Repeat(" ", barWidth-filled-len(head)) + "]"
+}
+
+// Rate is the number of items completed per second.
+func Rate(done int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(done) / elapsed.Seconds()
+}
+
+// ETA extrapolates the time left from the rate so far; it is zero until an
+// item has completed.
+func ETA(done, total int, elapsed time.Duration) time.Duration {
+	if done <= 0 || done >= total {
+		return 0
+	}
+	return time.Duration(float64(elapsed) * float64(total-done) / float64(done))
+}
@@ -0,0 +1,54 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestETA(t *testing.T) {
+	for _, tc := range []struct {
+		done, total int
+		elapsed     time.Duration
+		want        time.Duration
+	}{
+		{done: 0, total: 10, elapsed: time.Minute, want: 0},
+		{done: 5, total: 10, elapsed: time.Minute, want: time.Minute},
+		{done: 1, total: 4, elapsed: 10 * time.Second, want: 30 * time.Second},
+		{done: 10, total: 10, elapsed: time.Minute, want: 0},
+	} {
+		if got := ETA(tc.done, tc.total, tc.elapsed); got != tc.want {
+			t.Errorf("ETA(%d, %d, %s) = %s, want %s", tc.done, tc.total, tc.elapsed, got, tc.want)
+		}
+	}
+}
+
+func TestBar(t *testing.T) {
+	for done, want := range map[int]string{
+		0:  "[" + strings.Repeat(" ", barWidth) + "]",
+		5:  "[" + strings.Repeat("=", barWidth/2-1) + ">" + strings.Repeat(" ", barWidth/2) + "]",
+		10: "[" + strings.Repeat("=", barWidth) + "]",
+	} {
+		if got := bar(done, 10); got != want {
+			t.Errorf("bar(%d, 10) = %q, want %q", done, got, want)
+		}
+	}
+}
+
+func TestMeterThrottlesRedraws(t *testing.T) {
+	var out bytes.Buffer
+	now := time.Unix(0, 0)
+	m := &Meter{name: "ingest", out: &out, now: func() time.Time { return now }}
+	m.Update("process", 0, 3)
+	m.Update("process", 1, 3) // within drawInterval of the last draw
+	now = now.Add(time.Second)
+	m.Update("process", 2, 3)
+	m.Update("process", 3, 3) // finishing always draws
+	if got := strings.Count(out.String(), "\r"); got != 3 {
+		t.Errorf("drew %d times, want 3:\n%q", got, out.String())
+	}
+	if !strings.HasSuffix(out.String(), "3/3 3.0/s in 1s\n") {
+		t.Errorf("final line = %q", out.String())
+	}
+}
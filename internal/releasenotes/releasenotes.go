@@ -0,0 +1,223 @@
+// Package releasenotes composes markdown changelogs from the PRs merged
+// between two ARO-HCP revisions, using their stored rich descriptions.
+package releasenotes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+)
+
+const (
+	// otherGroup collects PRs whose changed files could not be listed or do
+	// not sit under a top-level directory.
+	otherGroup = "other"
+	// summaryMaxChars caps the rich-description excerpt shown per PR.
+	summaryMaxChars = 400
+)
+
+// Git is the subset of gitrepo.Repo the generator needs.
+type Git interface {
+	ResolveRef(ctx context.Context, ref string) (string, error)
+	Log(ctx context.Context, revRange string, opts gitrepo.LogOptions) ([]gitrepo.CommitInfo, error)
+	ChangedFiles(ctx context.Context, mergeSHA string) ([]string, error)
+}
+
+// PRStore looks up stored PRs by merge commit and links to them.
+type PRStore interface {
+	PRsByMergeCommitSHAs(ctx context.Context, shas []string) ([]db.PREmbedding, error)
+	PRURL(prNumber int) string
+}
+
+// DeploymentStore looks up the deployments recorded for an environment.
+type DeploymentStore interface {
+	DeploymentAt(ctx context.Context, environment string, t time.Time) (*db.Deployment, error)
+}
+
+type Generator struct {
+	Git         Git
+	Repo        PRStore
+	Deployments DeploymentStore // optional; required for BetweenEnvironments
+}
+
+// Entry is one PR in the changelog.
+type Entry struct {
+	PRNumber int
+	URL      string
+	Title    string
+	Author   string
+	MergedAt *time.Time
+	Summary  string
+	Group    string
+}
+
+// Notes is a changelog for the range From..To.
+type Notes struct {
+	From, To string
+	Entries  []Entry
+	// Unmatched counts first-parent commits in the range with no stored PR,
+	// e.g. direct pushes or PRs not yet ingested.
+	Unmatched int
+}
+
+// Between collects the PRs merged after from and up to to.
+func (g *Generator) Between(ctx context.Context, from, to string) (Notes, error) {
+	fromSHA, err := g.Git.ResolveRef(ctx, from)
+	if err != nil {
+		return Notes{}, fmt.Errorf("resolve %s: %w", from, err)
+	}
+	toSHA, err := g.Git.ResolveRef(ctx, to)
+	if err != nil {
+		return Notes{}, fmt.Errorf("resolve %s: %w", to, err)
+	}
+	history, err := g.Git.Log(ctx, fromSHA+".."+toSHA, gitrepo.LogOptions{FirstParent: true})
+	if err != nil {
+		return Notes{}, fmt.Errorf("list commits %s..%s: %w", fromSHA, toSHA, err)
+	}
+	commits := make([]string, len(history))
+	for i, c := range history {
+		commits[i] = c.SHA
+	}
+	prs, err := g.Repo.PRsByMergeCommitSHAs(ctx, commits)
+	if err != nil {
+		return Notes{}, fmt.Errorf("load PRs: %w", err)
+	}
+
+	notes := Notes{From: fromSHA, To: toSHA, Unmatched: len(commits) - len(prs)}
+	for _, pr := range prs {
+		group := otherGroup
+		if pr.MergeCommitSHA != nil {
+			if files, err := g.Git.ChangedFiles(ctx, *pr.MergeCommitSHA); err == nil {
+				group = primaryComponent(files)
+			}
+		}
+		notes.Entries = append(notes.Entries, Entry{
+			PRNumber: pr.PRNumber,
+			URL:      g.Repo.PRURL(pr.PRNumber),
+			Title:    pr.PRTitle,
+			Author:   pr.Author,
+			MergedAt: pr.MergedAt,
+			Summary:  summarize(pr.RichDescription),
+			Group:    group,
+		})
+	}
+	return notes, nil
+}
+
+// BetweenEnvironments collects the PRs running in to but not yet in from,
+// e.g. from=prod, to=stg.
+func (g *Generator) BetweenEnvironments(ctx context.Context, from, to string) (Notes, error) {
+	if g.Deployments == nil {
+		return Notes{}, fmt.Errorf("environment lookups are not configured")
+	}
+	fromSHA, err := g.deployedCommit(ctx, from)
+	if err != nil {
+		return Notes{}, err
+	}
+	toSHA, err := g.deployedCommit(ctx, to)
+	if err != nil {
+		return Notes{}, err
+	}
+	return g.Between(ctx, fromSHA, toSHA)
+}
+
+// deployedCommit returns the ARO-HCP commit of env's latest recorded
+// deployment.
+func (g *Generator) deployedCommit(ctx context.Context, env string) (string, error) {
+	d, err := g.Deployments.DeploymentAt(ctx, env, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("load deployment of %s: %w", env, err)
+	}
+	if d == nil {
+		return "", fmt.Errorf("no deployment of %s is recorded", env)
+	}
+	return d.CommitSHA, nil
+}
+
+// primaryComponent returns the top-level directory with the most changed files.
+func primaryComponent(files []string) string {
+	counts := make(map[string]int)
+	for _, f := range files {
+		if dir, _, found := strings.Cut(f, "/"); found {
+			counts[dir]++
+		}
+	}
+	best := otherGroup
+	for dir, n := range counts {
+		if n > counts[best] || (n == counts[best] && dir < best) {
+			best = dir
+		}
+	}
+	return best
+}
+
+// summarize returns the first paragraph of a rich description, cut at
+// summaryMaxChars runes.
+func summarize(richDescription *string) string {
+	if richDescription == nil {
+		return ""
+	}
+	text := strings.TrimSpace(*richDescription)
+	if para, _, found := strings.Cut(text, "\n\n"); found {
+		text = para
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > summaryMaxChars {
+		text = strings.TrimSpace(string(runes[:summaryMaxChars])) + "…"
+	}
+	return text
+}
+
+// Markdown renders notes grouped by component, groups sorted by name with
+// "other" last and PRs newest first within a group.
+func (n Notes) Markdown() string {
+	groups := make(map[string][]Entry)
+	var names []string
+	for _, e := range n.Entries {
+		if _, ok := groups[e.Group]; !ok {
+			names = append(names, e.Group)
+		}
+		groups[e.Group] = append(groups[e.Group], e)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == otherGroup) != (names[j] == otherGroup) {
+			return names[j] == otherGroup
+		}
+		return names[i] < names[j]
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Changes %s..%s\n\n", shortSHA(n.From), shortSHA(n.To))
+	if len(n.Entries) == 0 {
+		b.WriteString("No ingested PRs in this range.\n")
+	}
+	for _, name := range names {
+		entries := groups[name]
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].PRNumber > entries[j].PRNumber })
+		fmt.Fprintf(&b, "## %s\n\n", name)
+		for _, e := range entries {
+			fmt.Fprintf(&b, "- [#%d](%s) %s (@%s)", e.PRNumber, e.URL, e.Title, e.Author)
+			if e.Summary != "" {
+				fmt.Fprintf(&b, "\n  %s", e.Summary)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	if n.Unmatched > 0 {
+		fmt.Fprintf(&b, "_%d commit(s) in this range have no ingested PR._\n", n.Unmatched)
+	}
+	return b.String()
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
@@ -0,0 +1,74 @@
+package releasenotes
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+func TestPrimaryComponent(t *testing.T) {
+	files := []string{"backend/a.go", "frontend/b.go", "backend/c.go", "README.md"}
+	if got := primaryComponent(files); got != "backend" {
+		t.Fatalf("primaryComponent = %q, want backend", got)
+	}
+	if got := primaryComponent([]string{"README.md"}); got != otherGroup {
+		t.Fatalf("primaryComponent(top-level only) = %q, want %q", got, otherGroup)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	desc := "Adds retries to\nthe frontend.\n\nDetails follow."
+	if got := summarize(&desc); got != "Adds retries to the frontend." {
+		t.Fatalf("summarize = %q", got)
+	}
+	if got := summarize(nil); got != "" {
+		t.Fatalf("summarize(nil) = %q", got)
+	}
+}
+
+func TestMarkdownGroupsWithOtherLast(t *testing.T) {
+	notes := Notes{
+		From: "aaaaaaaaaaaaaaaa",
+		To:   "bbbbbbbbbbbbbbbb",
+		Entries: []Entry{
+			{PRNumber: 1, Title: "misc", Author: "x", Group: otherGroup},
+			{PRNumber: 2, Title: "fe", Author: "y", Group: "frontend", Summary: "Frontend change."},
+			{PRNumber: 3, Title: "be", Author: "z", Group: "backend"},
+		},
+		Unmatched: 2,
+	}
+	md := notes.Markdown()
+
+	order := []string{"# Changes aaaaaaaaaaaa..bbbbbbbbbbbb", "## backend", "## frontend", "  Frontend change.", "## other", "_2 commit(s)"}
+	pos := 0
+	for _, want := range order {
+		idx := strings.Index(md[pos:], want)
+		if idx < 0 {
+			t.Fatalf("missing %q in order; markdown:\n%s", want, md)
+		}
+		pos += idx
+	}
+}
+
+type fakeDeployments map[string]string
+
+func (f fakeDeployments) DeploymentAt(ctx context.Context, environment string, t time.Time) (*db.Deployment, error) {
+	sha, ok := f[environment]
+	if !ok {
+		return nil, nil
+	}
+	return &db.Deployment{Environment: environment, CommitSHA: sha, DeployedAt: t}, nil
+}
+
+func TestDeployedCommit(t *testing.T) {
+	g := &Generator{Deployments: fakeDeployments{"stg": "abc123"}}
+	if sha, err := g.deployedCommit(context.Background(), "stg"); err != nil || sha != "abc123" {
+		t.Fatalf("deployedCommit(stg) = %q, %v", sha, err)
+	}
+	if _, err := g.deployedCommit(context.Background(), "prod"); err == nil {
+		t.Fatal("deployedCommit(prod) succeeded with no recorded deployment")
+	}
+}
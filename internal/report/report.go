@@ -0,0 +1,212 @@
+// Package report renders a stored PR analysis as a shareable Markdown, Slack
+// or HTML document for incident channels.
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatSlack    = "slack"
+	FormatHTML     = "html"
+)
+
+// PRReport is everything rendered for one PR.
+type PRReport struct {
+	Number          int
+	Title           string
+	Author          string
+	URL             string
+	MergedAt        *time.Time
+	MergeCommitSHA  string
+	CommitURL       string // link to MergeCommitSHA; omitted when empty
+	AnalysisStatus  string // pending|succeeded|failed
+	RichDescription string
+	// FilesByComponent counts changed files per top-level directory.
+	FilesByComponent map[string]int
+	Traces           []TraceLink
+}
+
+// TraceLink summarises a cached trace_images result for one environment.
+type TraceLink struct {
+	Environment string
+	Images      []string // component@short-digest
+}
+
+// TotalFiles is the number of changed files across all components.
+func (r PRReport) TotalFiles() int {
+	n := 0
+	for _, c := range r.FilesByComponent {
+		n += c
+	}
+	return n
+}
+
+type componentCount struct {
+	Name  string
+	Files int
+}
+
+// Components returns FilesByComponent sorted by file count, then name.
+func (r PRReport) Components() []componentCount {
+	out := make([]componentCount, 0, len(r.FilesByComponent))
+	for name, n := range r.FilesByComponent {
+		out = append(out, componentCount{Name: name, Files: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Files != out[j].Files {
+			return out[i].Files > out[j].Files
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Render writes r to w in format.
+func Render(w io.Writer, format string, r PRReport) error {
+	switch format {
+	case FormatMarkdown:
+		_, err := io.WriteString(w, markdown(r))
+		return err
+	case FormatSlack:
+		_, err := io.WriteString(w, slack(r))
+		return err
+	case FormatHTML:
+		return htmlTemplate.Execute(w, r)
+	default:
+		return fmt.Errorf("unknown format %q (valid: %s, %s, %s)", format, FormatMarkdown, FormatSlack, FormatHTML)
+	}
+}
+
+func markdown(r PRReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# PR #%d: %s\n\n", r.Number, r.Title)
+	fmt.Fprintf(&b, "- Author: @%s\n", r.Author)
+	fmt.Fprintf(&b, "- Link: %s\n", r.URL)
+	if r.MergedAt != nil {
+		fmt.Fprintf(&b, "- Merged: %s\n", r.MergedAt.UTC().Format(time.RFC3339))
+	}
+	if u := r.CommitURL; u != "" && r.MergeCommitSHA != "" {
+		fmt.Fprintf(&b, "- Merge commit: [%s](%s)\n", shortSHA(r.MergeCommitSHA), u)
+	}
+	fmt.Fprintf(&b, "- Analysis: %s\n", r.AnalysisStatus)
+
+	b.WriteString("\n## Analysis\n\n")
+	b.WriteString(orPlaceholder(r.RichDescription))
+	b.WriteString("\n")
+
+	if len(r.FilesByComponent) > 0 {
+		fmt.Fprintf(&b, "\n## Changed files (%d)\n\n| Component | Files |\n|---|---|\n", r.TotalFiles())
+		for _, c := range r.Components() {
+			fmt.Fprintf(&b, "| %s | %d |\n", c.Name, c.Files)
+		}
+	}
+	if len(r.Traces) > 0 {
+		b.WriteString("\n## Traced images\n\n")
+		for _, t := range r.Traces {
+			fmt.Fprintf(&b, "- **%s**: %s\n", t.Environment, strings.Join(t.Images, ", "))
+		}
+	}
+	return b.String()
+}
+
+// slack renders Slack mrkdwn: single-asterisk bold, <url|text> links and no
+// headings or tables.
+func slack(r PRReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*<%s|PR #%d>: %s*\n", r.URL, r.Number, slackEscape(r.Title))
+	meta := []string{"by @" + r.Author}
+	if r.MergedAt != nil {
+		meta = append(meta, "merged "+r.MergedAt.UTC().Format("2006-01-02 15:04 MST"))
+	}
+	if u := r.CommitURL; u != "" && r.MergeCommitSHA != "" {
+		meta = append(meta, fmt.Sprintf("<%s|%s>", u, shortSHA(r.MergeCommitSHA)))
+	}
+	meta = append(meta, "analysis "+r.AnalysisStatus)
+	b.WriteString(strings.Join(meta, " · "))
+	b.WriteString("\n\n")
+	b.WriteString(slackEscape(orPlaceholder(r.RichDescription)))
+	b.WriteString("\n")
+
+	if len(r.FilesByComponent) > 0 {
+		fmt.Fprintf(&b, "\n*Changed files (%d)*\n", r.TotalFiles())
+		for _, c := range r.Components() {
+			fmt.Fprintf(&b, "• %s: %d\n", slackEscape(c.Name), c.Files)
+		}
+	}
+	if len(r.Traces) > 0 {
+		b.WriteString("\n*Traced images*\n")
+		for _, t := range r.Traces {
+			fmt.Fprintf(&b, "• %s: %s\n", t.Environment, slackEscape(strings.Join(t.Images, ", ")))
+		}
+	}
+	return b.String()
+}
+
+// slackEscape escapes the three characters Slack treats as control sequences.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func orPlaceholder(desc string) string {
+	if strings.TrimSpace(desc) == "" {
+		return "_No analysis stored for this PR._"
+	}
+	return strings.TrimSpace(desc)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"short": shortSHA,
+	"rfc3339": func(t *time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>PR #{{.Number}}: {{.Title}}</title></head>
+<body>
+<h1><a href="{{.URL}}">PR #{{.Number}}</a>: {{.Title}}</h1>
+<ul>
+<li>Author: @{{.Author}}</li>
+{{- if .MergedAt}}
+<li>Merged: {{rfc3339 .MergedAt}}</li>
+{{- end}}
+{{- if and .MergeCommitSHA .CommitURL}}
+<li>Merge commit: <a href="{{.CommitURL}}">{{short .MergeCommitSHA}}</a></li>
+{{- end}}
+<li>Analysis: {{.AnalysisStatus}}</li>
+</ul>
+<h2>Analysis</h2>
+{{if .RichDescription}}<pre style="white-space: pre-wrap">{{.RichDescription}}</pre>{{else}}<p><em>No analysis stored for this PR.</em></p>{{end}}
+{{- if .FilesByComponent}}
+<h2>Changed files ({{.TotalFiles}})</h2>
+<table>
+<tr><th>Component</th><th>Files</th></tr>
+{{- range .Components}}
+<tr><td>{{.Name}}</td><td>{{.Files}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Traces}}
+<h2>Traced images</h2>
+<ul>
+{{- range .Traces}}
+<li><strong>{{.Environment}}</strong>: {{range $i, $img := .Images}}{{if $i}}, {{end}}{{$img}}{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`))
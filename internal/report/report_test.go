@@ -0,0 +1,46 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func sampleReport() PRReport {
+	return PRReport{
+		Number:           1234,
+		Title:            "Fix <frontend> retries",
+		Author:           "octocat",
+		URL:              "https://github.com/Azure/ARO-HCP/pull/1234",
+		MergeCommitSHA:   "0123456789abcdef0123456789abcdef01234567",
+		AnalysisStatus:   "succeeded",
+		RichDescription:  "Retries & backoff for the frontend.",
+		FilesByComponent: map[string]int{"frontend": 3, "backend": 1},
+		Traces:           []TraceLink{{Environment: "prod", Images: []string{"Frontend@sha256:abc"}}},
+	}
+}
+
+func TestRenderFormats(t *testing.T) {
+	cases := map[string][]string{
+		FormatMarkdown: {"# PR #1234: Fix <frontend> retries", "| frontend | 3 |", "- **prod**: Frontend@sha256:abc"},
+		FormatSlack:    {"*<https://github.com/Azure/ARO-HCP/pull/1234|PR #1234>: Fix &lt;frontend&gt; retries*", "Retries &amp; backoff", "• frontend: 3"},
+		FormatHTML:     {"<title>PR #1234: Fix &lt;frontend&gt; retries</title>", "<td>frontend</td><td>3</td>", "Changed files (4)"},
+	}
+	for format, wants := range cases {
+		var buf bytes.Buffer
+		if err := Render(&buf, format, sampleReport()); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s output missing %q:\n%s", format, want, buf.String())
+			}
+		}
+	}
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	if err := Render(&bytes.Buffer{}, "pdf", sampleReport()); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
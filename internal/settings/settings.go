@@ -0,0 +1,37 @@
+// Package settings builds, from the process configuration, the settings of
+// the packages that take them explicitly, such as db and gitrepo.
+package settings
+
+import (
+	"github.com/roivaz/aro-hcp-intelhub/internal/config"
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+)
+
+// Database returns a db.Config for dsn with pool, timeout and retry settings
+// taken from the environment.
+func Database(dsn string) db.Config {
+	return db.Config{
+		DSN:              dsn,
+		MaxOpenConns:     config.DBMaxOpenConns(),
+		MaxIdleConns:     config.DBMaxIdleConns(),
+		ConnMaxLifetime:  config.DBConnMaxLifetime(),
+		ConnMaxIdleTime:  config.DBConnMaxIdleTime(),
+		StatementTimeout: config.DBStatementTimeout(),
+		ConnectRetries:   config.DBConnectRetries(),
+		ConnectBackoff:   config.DBConnectBackoff(),
+	}
+}
+
+// Git returns the settings of every repository the process opens:
+// GIT_BACKEND, GIT_MIRROR_URL, GIT_TOKENS and GIT_SSH_KEY. Malformed
+// GIT_TOKENS are left out; config validation reports them.
+func Git() gitrepo.Settings {
+	tokens, _ := config.ParseHostTokens(config.GitTokens())
+	return gitrepo.Settings{
+		Backend:    config.GitBackend(),
+		MirrorURL:  config.GitMirrorURL(),
+		HostTokens: tokens,
+		SSHKeyPath: config.GitSSHKey(),
+	}
+}
@@ -0,0 +1,87 @@
+package topics
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"slices"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+)
+
+// Options configures Build.
+type Options struct {
+	K          int // clusters; DefaultK when 0
+	Iterations int
+	Seed       uint64
+	// Samples is how many of the titles closest to a topic's centroid are
+	// shown to the labeler.
+	Samples int
+	// Labeler names topics; nil names each topic after its most central PR.
+	Labeler *Labeler
+}
+
+// Build clusters prs by embedding and returns the labelled topics, largest
+// first with IDs from 1, and the topic ID of each PR number.
+func Build(ctx context.Context, prs []db.PREmbedding, model string, opts Options) ([]db.PRTopic, map[int]int, error) {
+	vectors := make([][]float32, len(prs))
+	for i, pr := range prs {
+		vectors[i] = pr.Embedding.Slice()
+	}
+	k := opts.K
+	if k <= 0 {
+		k = DefaultK(len(prs))
+	}
+	clustering := KMeans(vectors, k, opts.Iterations, opts.Seed)
+
+	members := make([][]int, len(clustering.Centroids))
+	for i, c := range clustering.Assignments {
+		members[c] = append(members[c], i)
+	}
+	// Largest cluster first; empty clusters are dropped.
+	order := make([]int, 0, len(members))
+	for c := range members {
+		if len(members[c]) > 0 {
+			order = append(order, c)
+		}
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return len(members[b]) - len(members[a]) })
+
+	topics := make([]db.PRTopic, 0, len(order))
+	assignments := make(map[int]int, len(prs))
+	for rank, c := range order {
+		id := rank + 1
+		idx := members[c]
+		centroid := clustering.Centroids[c]
+		similarity := make(map[int]float64, len(idx))
+		for _, i := range idx {
+			similarity[i] = dot(centroid, normalized(vectors[i]))
+		}
+		slices.SortFunc(idx, func(a, b int) int { return cmp.Compare(similarity[b], similarity[a]) })
+		titles := make([]string, 0, min(len(idx), max(opts.Samples, 1)))
+		for _, i := range idx[:cap(titles)] {
+			titles = append(titles, prs[i].PRTitle)
+		}
+
+		topic := db.PRTopic{ID: id, Label: titles[0], Size: len(idx), EmbeddingModel: model}
+		if opts.Labeler != nil {
+			label, summary, err := opts.Labeler.Label(ctx, titles)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, nil, ctx.Err()
+				}
+				log.Printf("cluster: label topic %d: %v; using its most central title", id, err)
+			} else {
+				topic.Label = label
+				if summary != "" {
+					topic.Summary = &summary
+				}
+			}
+		}
+		topics = append(topics, topic)
+		for _, i := range idx {
+			assignments[prs[i].PRNumber] = id
+		}
+	}
+	return topics, assignments, nil
+}
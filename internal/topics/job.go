@@ -0,0 +1,68 @@
+package topics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/db"
+	"github.com/roivaz/aro-hcp-intelhub/internal/jobs"
+)
+
+// JobParams are the parameters of a cluster job, the flags of
+// 'ingest cluster'.
+type JobParams struct {
+	K          int    `json:"k,omitempty"`
+	Iterations int    `json:"iterations"`
+	Seed       uint64 `json:"seed"`
+	Samples    int    `json:"samples"`
+	NoLabels   bool   `json:"no_labels,omitempty"`
+}
+
+// DefaultJobParams are the defaults of 'ingest cluster'.
+var DefaultJobParams = JobParams{Iterations: 50, Seed: 1, Samples: 15}
+
+// Cluster groups the PRs embedded with model into topics and replaces the
+// stored topics with them. It returns the topics and the number of PRs.
+func Cluster(ctx context.Context, repo *db.SearchRepository, model string, opts Options) ([]db.PRTopic, int, error) {
+	prs, err := repo.PRVectors(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(prs) < 2 {
+		return nil, 0, fmt.Errorf("%d PRs embedded with %s; nothing to cluster", len(prs), model)
+	}
+	found, assignments, err := Build(ctx, prs, model, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := repo.ReplaceTopics(ctx, found, assignments); err != nil {
+		return nil, 0, err
+	}
+	return found, len(assignments), nil
+}
+
+// JobHandler runs cluster jobs over the PRs embedded with model. labeler
+// names the topics unless the job sets NoLabels; nil names every topic after
+// its most central PR.
+func JobHandler(repo *db.SearchRepository, model string, labeler *Labeler) jobs.Handler {
+	return func(ctx context.Context, job *db.Job, progress jobs.Progress) error {
+		var params JobParams
+		if err := json.Unmarshal(job.Params, &params); err != nil {
+			return fmt.Errorf("decode cluster job params: %w", err)
+		}
+		opts := Options{K: params.K, Iterations: params.Iterations, Seed: params.Seed, Samples: params.Samples}
+		if !params.NoLabels {
+			opts.Labeler = labeler
+		}
+		progress("cluster", 0, 1)
+		found, prs, err := Cluster(ctx, repo, model, opts)
+		if err != nil {
+			return err
+		}
+		progress("cluster", 1, 1)
+		log.Printf("cluster job %s: stored %d topics over %d PRs", job.ID, len(found), prs)
+		return nil
+	}
+}
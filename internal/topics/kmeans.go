@@ -0,0 +1,140 @@
+// Package topics groups PRs into themes by clustering their embeddings and
+// names each theme with an LLM.
+package topics
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// Clustering is the result of KMeans: the cluster of each input vector and
+// the unit-length centroid of each cluster.
+type Clustering struct {
+	Assignments []int
+	Centroids   [][]float32
+}
+
+// KMeans runs spherical k-means (cosine similarity) over vectors with k-means++
+// seeding, stopping after iterations rounds or when no assignment changes.
+// k is capped at len(vectors). The same seed yields the same clustering.
+func KMeans(vectors [][]float32, k, iterations int, seed uint64) Clustering {
+	points := make([][]float32, len(vectors))
+	for i, v := range vectors {
+		points[i] = normalized(v)
+	}
+	k = min(k, len(points))
+	if k <= 0 {
+		return Clustering{}
+	}
+	rng := rand.New(rand.NewPCG(seed, seed))
+	centroids := seedCentroids(points, k, rng)
+	assign := make([]int, len(points))
+	for i := range assign {
+		assign[i] = -1
+	}
+	for iter := 0; iter < max(iterations, 1); iter++ {
+		changed := false
+		for i, p := range points {
+			best := nearest(centroids, p)
+			if best != assign[i] {
+				assign[i], changed = best, true
+			}
+		}
+		if !changed {
+			break
+		}
+		centroids = recompute(points, assign, centroids, rng)
+	}
+	return Clustering{Assignments: assign, Centroids: centroids}
+}
+
+// seedCentroids picks k initial centroids, each chosen with probability
+// proportional to its cosine distance from the nearest centroid so far.
+func seedCentroids(points [][]float32, k int, rng *rand.Rand) [][]float32 {
+	centroids := [][]float32{points[rng.IntN(len(points))]}
+	dist := make([]float64, len(points))
+	for len(centroids) < k {
+		total := 0.0
+		for i, p := range points {
+			dist[i] = 1 - dot(centroids[nearest(centroids, p)], p)
+			total += dist[i]
+		}
+		if total <= 0 {
+			// Fewer distinct points than clusters.
+			centroids = append(centroids, points[rng.IntN(len(points))])
+			continue
+		}
+		target := rng.Float64() * total
+		pick := len(points) - 1
+		for i, d := range dist {
+			if target -= d; target <= 0 {
+				pick = i
+				break
+			}
+		}
+		centroids = append(centroids, points[pick])
+	}
+	return centroids
+}
+
+// recompute returns the normalized mean of each cluster. An empty cluster is
+// restarted at a random point.
+func recompute(points [][]float32, assign []int, old [][]float32, rng *rand.Rand) [][]float32 {
+	dim := len(points[0])
+	sums := make([][]float32, len(old))
+	for c := range sums {
+		sums[c] = make([]float32, dim)
+	}
+	counts := make([]int, len(old))
+	for i, p := range points {
+		c := assign[i]
+		counts[c]++
+		for j, x := range p {
+			sums[c][j] += x
+		}
+	}
+	for c := range sums {
+		if counts[c] == 0 {
+			sums[c] = points[rng.IntN(len(points))]
+			continue
+		}
+		sums[c] = normalized(sums[c])
+	}
+	return sums
+}
+
+func nearest(centroids [][]float32, p []float32) int {
+	best, bestSim := 0, math.Inf(-1)
+	for c, centroid := range centroids {
+		if sim := dot(centroid, p); sim > bestSim {
+			best, bestSim = c, sim
+		}
+	}
+	return best
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range min(len(a), len(b)) {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+func normalized(v []float32) []float32 {
+	norm := math.Sqrt(dot(v, v))
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+// DefaultK is the cluster count used when none is given: sqrt(n/2), between
+// 2 and 50.
+func DefaultK(n int) int {
+	return min(max(int(math.Round(math.Sqrt(float64(n)/2))), 2), 50)
+}
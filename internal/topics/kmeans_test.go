@@ -0,0 +1,40 @@
+package topics
+
+import "testing"
+
+func TestKMeans(t *testing.T) {
+	vectors := [][]float32{
+		{1, 0.1, 0}, {0.9, 0, 0.1}, {1, 0, 0},
+		{0, 1, 0.1}, {0.1, 0.9, 0}, {0, 1, 0},
+		{0, 0.1, 1}, {0, 0, 0.8},
+	}
+	got := KMeans(vectors, 3, 20, 1)
+	if len(got.Centroids) != 3 {
+		t.Fatalf("got %d centroids, want 3", len(got.Centroids))
+	}
+	groups := [][]int{{0, 1, 2}, {3, 4, 5}, {6, 7}}
+	seen := map[int]bool{}
+	for _, g := range groups {
+		c := got.Assignments[g[0]]
+		if seen[c] {
+			t.Fatalf("groups share cluster %d: %v", c, got.Assignments)
+		}
+		seen[c] = true
+		for _, i := range g[1:] {
+			if got.Assignments[i] != c {
+				t.Errorf("vector %d in cluster %d, want %d: %v", i, got.Assignments[i], c, got.Assignments)
+			}
+		}
+	}
+
+	if got := KMeans(vectors[:2], 5, 10, 1); len(got.Centroids) != 2 {
+		t.Errorf("k not capped at the number of vectors: %d centroids", len(got.Centroids))
+	}
+}
+
+func TestParseLabel(t *testing.T) {
+	label, summary := parseLabel("Sure!\n**Label:** \"Network policy changes\"\nSummary: Tightens egress rules.\n")
+	if label != "Network policy changes" || summary != "Tightens egress rules." {
+		t.Errorf("parseLabel = %q, %q", label, summary)
+	}
+}
@@ -0,0 +1,80 @@
+package topics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/ollama"
+)
+
+const labelPrompt = `The following pull requests to the ARO-HCP repository (Azure Red Hat
+OpenShift hosted control planes) were grouped together by similarity:
+
+%s
+Name the common theme. Answer with exactly two lines:
+Label: <a short topic name of at most six words, e.g. "Network policy changes">
+Summary: <one sentence describing what these pull requests change>`
+
+// Labeler names clusters of PRs with an Ollama model.
+type Labeler struct {
+	llm     *ollama.LLM
+	timeout time.Duration
+}
+
+func NewLabeler(ollamaURL, model string, timeout time.Duration) (*Labeler, error) {
+	llm, err := ollama.New(ollama.WithModel(model), ollama.WithServerURL(ollamaURL), ollama.WithKeepAlive("5m"))
+	if err != nil {
+		return nil, fmt.Errorf("create ollama client: %w", err)
+	}
+	return &Labeler{llm: llm, timeout: timeout}, nil
+}
+
+// Label returns a short name and a one-sentence summary for the PRs titled
+// titles, most representative first.
+func (l *Labeler) Label(ctx context.Context, titles []string) (string, string, error) {
+	if l.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+	}
+	var list strings.Builder
+	for _, t := range titles {
+		fmt.Fprintf(&list, "- %s\n", t)
+	}
+	resp, err := l.llm.GenerateContent(ctx, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, fmt.Sprintf(labelPrompt, list.String())),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", "", fmt.Errorf("empty response")
+	}
+	label, summary := parseLabel(resp.Choices[0].Content)
+	if label == "" {
+		return "", "", fmt.Errorf("no label in response %q", resp.Choices[0].Content)
+	}
+	return label, summary, nil
+}
+
+func parseLabel(content string) (label, summary string) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(strings.Trim(line, "*"))
+		if v, ok := cutPrefixFold(line, "label:"); ok && label == "" {
+			label = strings.Trim(v, `"* `)
+		} else if v, ok := cutPrefixFold(line, "summary:"); ok && summary == "" {
+			summary = strings.Trim(v, "* ")
+		}
+	}
+	return label, summary
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return "", false
+	}
+	return s[len(prefix):], true
+}
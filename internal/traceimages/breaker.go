@@ -0,0 +1,96 @@
+package traceimages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// ErrorClassRegistryUnreachable classifies components that were not
+// inspected because their registry timed out or refused connections.
+const ErrorClassRegistryUnreachable = "registry_unreachable"
+
+var errRegistryUnreachable = errors.New("registry unreachable")
+
+// registryBreakers are per-registry circuit breakers. A registry that could
+// not be reached is skipped for the cooldown, so traces return digest-only
+// components at once instead of waiting on every timeout again.
+type registryBreakers struct {
+	cooldown time.Duration // <= 0 disables the breakers
+	now      func() time.Time
+
+	mu   sync.Mutex
+	open map[string]openBreaker
+}
+
+type openBreaker struct {
+	until time.Time
+	cause error
+}
+
+func newRegistryBreakers(cooldown time.Duration) *registryBreakers {
+	return &registryBreakers{cooldown: cooldown, now: time.Now, open: map[string]openBreaker{}}
+}
+
+// guard runs fn against registry unless its breaker is open. Failures to
+// reach the registry open the breaker and are returned wrapping
+// errRegistryUnreachable. parent is the caller's context: failures after it
+// ended are the caller's budget running out, not the registry's fault.
+func (b *registryBreakers) guard(parent context.Context, registry string, fn func() error) error {
+	b.mu.Lock()
+	if open, ok := b.open[registry]; ok {
+		if wait := open.until.Sub(b.now()); wait > 0 {
+			b.mu.Unlock()
+			return fmt.Errorf("%w: %s: skipped for %s after: %v", errRegistryUnreachable, registry, wait.Round(time.Second), open.cause)
+		}
+		delete(b.open, registry)
+	}
+	b.mu.Unlock()
+
+	err := fn()
+	if err == nil || parent.Err() != nil || !isUnreachable(err) {
+		return err
+	}
+	if b.cooldown > 0 {
+		b.mu.Lock()
+		b.open[registry] = openBreaker{until: b.now().Add(b.cooldown), cause: err}
+		b.mu.Unlock()
+	}
+	return fmt.Errorf("%w: %s: %v", errRegistryUnreachable, registry, err)
+}
+
+// isUnreachable reports whether err means the registry could not be reached
+// or did not answer in time, from the native client or skopeo's output.
+func isUnreachable(err error) bool {
+	if errors.Is(err, errRegistryUnreachable) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return true
+	}
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		switch terr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	msg := err.Error()
+	for _, pattern := range []string{"i/o timeout", "connection refused", "no such host", "TLS handshake timeout", "context deadline exceeded", "Client.Timeout exceeded"} {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
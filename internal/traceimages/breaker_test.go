@@ -0,0 +1,44 @@
+package traceimages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRegistryBreakers(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	b := newRegistryBreakers(time.Minute)
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	calls := 0
+	timeout := func() error { calls++; return fmt.Errorf("get manifest: %w", context.DeadlineExceeded) }
+	ok := func() error { calls++; return nil }
+
+	if err := b.guard(ctx, "quay.io", func() error { calls++; return errors.New("unauthorized") }); errors.Is(err, errRegistryUnreachable) {
+		t.Fatalf("registry error classified unreachable: %v", err)
+	}
+	if err := b.guard(ctx, "quay.io", timeout); !errors.Is(err, errRegistryUnreachable) {
+		t.Fatalf("timeout not classified unreachable: %v", err)
+	}
+	if err := b.guard(ctx, "quay.io", ok); !errors.Is(err, errRegistryUnreachable) || calls != 2 {
+		t.Fatalf("open breaker: err=%v calls=%d", err, calls)
+	}
+	if err := b.guard(ctx, "example.azurecr.io", ok); err != nil {
+		t.Fatalf("other registry: %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := b.guard(ctx, "quay.io", ok); err != nil || calls != 4 {
+		t.Fatalf("after cooldown: err=%v calls=%d", err, calls)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := b.guard(cancelled, "quay.io", timeout); errors.Is(err, errRegistryUnreachable) {
+		t.Fatalf("caller's cancellation classified unreachable: %v", err)
+	}
+}
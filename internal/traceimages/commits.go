@@ -0,0 +1,202 @@
+package traceimages
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	vcsurl "github.com/gitsight/go-vcsurl"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	tooltypes "github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+// componentReposDir holds the clones of component source repositories,
+// laid out by host and repository name.
+const componentReposDir = "component-repos"
+
+// SourceRepo returns the source repository of the named component, with
+// repoURL (Azure/ARO-HCP when empty) standing in for components built from
+// the traced repository.
+func SourceRepo(component, repoURL string) (string, bool) {
+	mapping, ok := componentMappings[component]
+	if !ok {
+		return "", false
+	}
+	if mapping.SourceRepo == "" {
+		if repoURL == "" {
+			return defaultRepoURL, true
+		}
+		return repoURL, true
+	}
+	return mapping.SourceRepo, true
+}
+
+// CommitLog lists what went into a component image: the commits of the
+// component's source repository up to the SHA the image was built from.
+type CommitLog struct {
+	CacheDir string // component repos are cloned under CacheDir/component-repos
+	// RepoURL and RepoPath are the traced repository and its existing clone,
+	// reused for the components built from it.
+	RepoURL  string
+	RepoPath string
+	// AllowedRepos are https source repositories that may be passed as
+	// sourceRepoURL besides those of componentMappings and RepoURL.
+	AllowedRepos []string
+	// Git configures the clones.
+	Git gitrepo.Settings
+}
+
+// ComponentCommits returns up to max commits of sourceRepoURL reachable from
+// sourceSHA, newest first, stopping at sinceSHA when set. sourceRepoURL
+// defaults to the repository componentMappings records for component, and
+// must otherwise be one of the allowed repositories. The repository is cloned
+// on first use and fetched when sourceSHA is unknown.
+func (c *CommitLog) ComponentCommits(ctx context.Context, component, sourceRepoURL, sourceSHA, sinceSHA string, max int) (tooltypes.ComponentCommitsResponse, error) {
+	for _, sha := range []string{sourceSHA, sinceSHA} {
+		if sha != "" && !gitrepo.IsCommitSHA(sha) {
+			return tooltypes.ComponentCommitsResponse{}, fmt.Errorf("invalid commit SHA %q", sha)
+		}
+	}
+	if sourceRepoURL != "" {
+		if err := c.checkAllowed(sourceRepoURL); err != nil {
+			return tooltypes.ComponentCommitsResponse{}, err
+		}
+	} else {
+		url, ok := SourceRepo(component, c.RepoURL)
+		if !ok {
+			return tooltypes.ComponentCommitsResponse{}, fmt.Errorf("unknown component %q (known: %s); pass its source_repo_url", component, strings.Join(sortedKeys(componentMappings), ", "))
+		}
+		sourceRepoURL = url
+	}
+	repo, err := c.repo(sourceRepoURL)
+	if err != nil {
+		return tooltypes.ComponentCommitsResponse{}, err
+	}
+
+	head, err := c.commit(ctx, repo, sourceRepoURL, sourceSHA)
+	if err != nil {
+		return tooltypes.ComponentCommitsResponse{}, err
+	}
+	revRange := head.SHA
+	if sinceSHA != "" {
+		since, err := c.commit(ctx, repo, sourceRepoURL, sinceSHA)
+		if err != nil {
+			return tooltypes.ComponentCommitsResponse{}, err
+		}
+		revRange = since.SHA + ".." + head.SHA
+		sinceSHA = since.SHA
+	}
+	commits, err := repo.Log(ctx, revRange, gitrepo.LogOptions{MaxCount: max + 1})
+	if err != nil {
+		return tooltypes.ComponentCommitsResponse{}, fmt.Errorf("git log %s: %w", revRange, err)
+	}
+
+	resp := tooltypes.ComponentCommitsResponse{
+		Component:     component,
+		SourceRepoURL: sourceRepoURL,
+		SourceSHA:     head.SHA,
+		SinceSHA:      sinceSHA,
+		Commits:       []tooltypes.ComponentCommit{},
+	}
+	if len(commits) > max {
+		commits, resp.Truncated = commits[:max], true
+	}
+	web := webURL(sourceRepoURL)
+	for _, ci := range commits {
+		commit := tooltypes.ComponentCommit{
+			SHA:     ci.SHA,
+			Author:  ci.Author,
+			Date:    ci.Date.UTC().Format(time.RFC3339),
+			Subject: ci.Subject,
+		}
+		if web != "" {
+			commit.URL = web + "/commit/" + ci.SHA
+		}
+		resp.Commits = append(resp.Commits, commit)
+	}
+	return resp, nil
+}
+
+// checkAllowed rejects source repositories other than the https URLs of
+// componentMappings, RepoURL and AllowedRepos: the clone runs with the
+// credentials configured for the host and lands on local disk.
+func (c *CommitLog) checkAllowed(repoURL string) error {
+	parsed, err := url.Parse(repoURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" || parsed.User != nil {
+		return fmt.Errorf("source repo %q: only https URLs are supported", repoURL)
+	}
+	allowed := slices.Clone(c.AllowedRepos)
+	allowed = append(allowed, cmp.Or(c.RepoURL, defaultRepoURL))
+	for _, mapping := range componentMappings {
+		if mapping.SourceRepo != "" {
+			allowed = append(allowed, mapping.SourceRepo)
+		}
+	}
+	if !slices.ContainsFunc(allowed, func(a string) bool { return sameRepo(a, repoURL) }) {
+		return fmt.Errorf("source repo %q is not a known component repository", repoURL)
+	}
+	return nil
+}
+
+// repo returns the clone of url at clonePath.
+func (c *CommitLog) repo(url string) (*gitrepo.Repo, error) {
+	path, err := c.clonePath(url)
+	if err != nil {
+		return nil, err
+	}
+	return gitrepo.New(gitrepo.RepoConfig{Settings: c.Git, URL: url, Path: path}), nil
+}
+
+// clonePath is where url is cloned: the traced repository's own clone, or
+// CacheDir/component-repos/<host>/<name>.
+func (c *CommitLog) clonePath(url string) (string, error) {
+	if c.RepoPath != "" && sameRepo(url, c.RepoURL) {
+		return c.RepoPath, nil
+	}
+	parsed, err := vcsurl.Parse(url)
+	if err != nil {
+		return "", fmt.Errorf("source repo %q: %w", url, err)
+	}
+	rel := filepath.Clean(filepath.FromSlash(parsed.ID))
+	if rel == "." || filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("source repo %q: unsupported URL", url)
+	}
+	return filepath.Join(c.CacheDir, componentReposDir, rel), nil
+}
+
+// commit resolves sha in repo, cloning or fetching it first when needed.
+func (c *CommitLog) commit(ctx context.Context, repo *gitrepo.Repo, url, sha string) (gitrepo.CommitInfo, error) {
+	if info, err := repo.CommitInfo(ctx, sha); err == nil {
+		return info, nil
+	}
+	if _, err := repo.Ensure(ctx); err != nil {
+		return gitrepo.CommitInfo{}, fmt.Errorf("update clone of %s: %w", url, err)
+	}
+	info, err := repo.CommitInfo(ctx, sha)
+	if err != nil {
+		return gitrepo.CommitInfo{}, fmt.Errorf("commit %s not found in %s: %w", sha, url, err)
+	}
+	return info, nil
+}
+
+func sameRepo(a, b string) bool {
+	return trimRepoURL(a) == trimRepoURL(b)
+}
+
+func trimRepoURL(url string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+}
+
+// webURL is the browsable form of an http(s) clone URL, empty for others.
+func webURL(url string) string {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return ""
+	}
+	return trimRepoURL(url)
+}
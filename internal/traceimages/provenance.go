@@ -0,0 +1,121 @@
+package traceimages
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	tooltypes "github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+// configSources locates each component's digest (or tag) in the environment config
+// of the checkout and blames its line as of commitSHA. Components whose
+// digest the file does not set, e.g. one inherited from a base config, get
+// a source with an error and no commit, as do all components when the
+// tracer generated the file, which git cannot blame.
+func (t *Tracer) configSources(ctx context.Context, commitSHA, checkoutDir string, src envFile, generated bool) map[string]*tooltypes.ConfigSource {
+	log := t.log.ForContext(ctx)
+	file := filepath.ToSlash(src.Path)
+	sources := make(map[string]*tooltypes.ConfigSource, len(imageConfigPaths))
+
+	var root yaml.Node
+	data, err := os.ReadFile(filepath.Join(checkoutDir, src.Path))
+	if err == nil {
+		err = yaml.Unmarshal(data, &root)
+	}
+	if err != nil {
+		log.Error(err, "parse config for provenance failed", "file", file)
+	}
+
+	var lines []int
+	for name, path := range imageConfigPaths {
+		keyPath := append(append(append([]string{}, src.BasePath...), path...), "digest")
+		line := yamlLine(&root, keyPath)
+		if line == 0 {
+			// Components referenced by tag.
+			tagPath := append(keyPath[:len(keyPath)-1:len(keyPath)-1], "tag")
+			if tagLine := yamlLine(&root, tagPath); tagLine > 0 {
+				keyPath, line = tagPath, tagLine
+			}
+		}
+		source := &tooltypes.ConfigSource{File: file, KeyPath: strings.Join(keyPath, ".")}
+		if line > 0 {
+			source.Line = line
+			lines = append(lines, line)
+		} else {
+			msg := fmt.Sprintf("%s does not set %s", file, source.KeyPath)
+			source.Error = &msg
+		}
+		sources[name] = source
+	}
+
+	if generated {
+		msg := fmt.Sprintf("%s was rendered by the tracer; it is not in commit %s", file, commitSHA)
+		for _, source := range sources {
+			if source.Error == nil {
+				source.Error = &msg
+			}
+		}
+		return sources
+	}
+	blame, err := t.repo.BlameLines(ctx, commitSHA, src.Path, lines)
+	if err != nil {
+		log.Error(err, "blame config failed", "file", file)
+		msg := fmt.Sprintf("blame %s: %v", file, err)
+		for _, source := range sources {
+			if source.Error == nil {
+				source.Error = &msg
+			}
+		}
+		return sources
+	}
+	merges := map[string]string{}
+	for _, source := range sources {
+		sha, ok := blame[source.Line]
+		if source.Line == 0 || !ok {
+			continue
+		}
+		source.Commit = &sha
+		merge, seen := merges[sha]
+		if !seen {
+			// Best effort: the go-git backend cannot walk first-parent history.
+			merge, err = t.repo.IntroducedBy(ctx, sha, commitSHA)
+			if err != nil {
+				log.Debug("resolve config change merge failed", "commit", sha, "error", err.Error())
+			}
+			merges[sha] = merge
+		}
+		if merge != "" && merge != sha {
+			source.MergeCommit = &merge
+		}
+	}
+	return sources
+}
+
+// yamlLine returns the 1-based line of the value at path in a YAML document,
+// or 0 when the document does not set it.
+func yamlLine(node *yaml.Node, path []string) int {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return 0
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+			}
+		}
+		if next == nil {
+			return 0
+		}
+		node = next
+	}
+	return node.Line
+}
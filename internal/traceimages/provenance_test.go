@@ -0,0 +1,84 @@
+package traceimages
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
+)
+
+func TestYAMLLine(t *testing.T) {
+	doc := `clouds:
+  public:
+    environments:
+      int:
+        defaults:
+          backend:
+            image:
+              registry: arohcpsvcint.azurecr.io
+              digest: sha256:abc
+`
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(doc), &root); err != nil {
+		t.Fatal(err)
+	}
+	path := []string{"clouds", "public", "environments", "int", "defaults", "backend", "image"}
+	if got := yamlLine(&root, append(path, "digest")); got != 9 {
+		t.Errorf("digest line = %d, want 9", got)
+	}
+	if got := yamlLine(&root, append(path, "repository")); got != 0 {
+		t.Errorf("missing key line = %d, want 0", got)
+	}
+	if got := yamlLine(&root, []string{"clouds", "public", "environments", "int", "defaults", "backend", "image", "digest", "x"}); got != 0 {
+		t.Errorf("path through a scalar = %d, want 0", got)
+	}
+}
+
+func TestConfigSourcesByTag(t *testing.T) {
+	dir := t.TempDir()
+	doc := `defaults:
+  backend:
+    image:
+      registry: arohcpsvcint.azurecr.io
+      digest: sha256:abc
+  frontend:
+    image:
+      registry: arohcpsvcint.azurecr.io
+      tag: v1.2.3
+  maestro:
+    image:
+      registry: quay.io
+      digest: sha256:def
+      tag: ignored
+`
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tracer := &Tracer{log: logging.New(logging.DefaultLogger())}
+	sources := tracer.configSources(context.Background(), "abc", dir, envFile{Path: "config.yaml", BasePath: []string{"defaults"}}, true)
+
+	cases := []struct {
+		component string
+		keyPath   string
+		line      int
+	}{
+		{"Backend", "defaults.backend.image.digest", 5},
+		{"Frontend", "defaults.frontend.image.tag", 9},
+		{"Maestro", "defaults.maestro.image.digest", 13},
+		{"Hypershift", "defaults.hypershift.image.digest", 0},
+	}
+	for _, c := range cases {
+		source := sources[c.component]
+		if source == nil {
+			t.Errorf("%s: no source", c.component)
+			continue
+		}
+		if source.KeyPath != c.keyPath || source.Line != c.line {
+			t.Errorf("%s: source = %s line %d, want %s line %d", c.component, source.KeyPath, source.Line, c.keyPath, c.line)
+		}
+	}
+}
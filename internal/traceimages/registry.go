@@ -0,0 +1,177 @@
+package traceimages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// registryClient talks to registries directly with go-containerregistry so
+// tracing does not depend on the skopeo binary.
+type registryClient struct {
+	keychain  authn.Keychain
+	transport http.RoundTripper
+}
+
+// newRegistryClient returns a client whose connections, TLS handshakes and
+// response headers each time out after timeout, so an unreachable registry
+// fails fast instead of holding the inspection until its own timeout.
+func newRegistryClient(pullSecret string, timeout time.Duration) (*registryClient, error) {
+	base := remote.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	base.TLSHandshakeTimeout = timeout
+	base.ResponseHeaderTimeout = timeout
+	client := &registryClient{keychain: pullSecretKeychain{}, transport: base}
+	if pullSecret == "" {
+		return client, nil
+	}
+	keychain, err := loadPullSecret(pullSecret)
+	if err != nil {
+		return nil, err
+	}
+	client.keychain = keychain
+	return client, nil
+}
+
+func (c *registryClient) options(ctx context.Context) []remote.Option {
+	return []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithTransport(c.transport),
+		remote.WithAuthFromKeychain(c.keychain),
+		remote.WithPlatform(v1.Platform{OS: "linux", Architecture: "amd64"}),
+	}
+}
+
+// inspect returns the config labels of the image at digest, resolving
+// manifest lists to linux/amd64.
+func (c *registryClient) inspect(ctx context.Context, registry, repository, digest string) (imageInfo, error) {
+	ref, err := name.NewDigest(fmt.Sprintf("%s/%s@%s", registry, repository, digest))
+	if err != nil {
+		return imageInfo{}, fmt.Errorf("parse image reference: %w", err)
+	}
+	desc, err := remote.Get(ref, c.options(ctx)...)
+	if err != nil {
+		return imageInfo{}, fmt.Errorf("get manifest %s: %w", ref, err)
+	}
+	img, err := desc.Image()
+	if err != nil {
+		return imageInfo{}, fmt.Errorf("resolve image %s: %w", ref, err)
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return imageInfo{}, fmt.Errorf("read config %s: %w", ref, err)
+	}
+
+	info := imageInfo{Labels: map[string]string{}}
+	for key, value := range cfg.Config.Labels {
+		if key != "" && value != "" {
+			info.Labels[key] = value
+		}
+	}
+	return info, nil
+}
+
+// resolveTag returns the manifest digest tag currently points at.
+func (c *registryClient) resolveTag(ctx context.Context, registry, repository, tag string) (string, error) {
+	ref, err := name.NewTag(fmt.Sprintf("%s/%s:%s", registry, repository, tag))
+	if err != nil {
+		return "", fmt.Errorf("parse image reference: %w", err)
+	}
+	desc, err := remote.Head(ref, c.options(ctx)...)
+	if err != nil {
+		return "", fmt.Errorf("head %s: %w", ref, err)
+	}
+	return desc.Digest.String(), nil
+}
+
+// tagsForDigest resolves up to maxTags candidate tags with HEAD requests and
+// returns those whose digest matches.
+func (c *registryClient) tagsForDigest(ctx context.Context, registry, repository, digest string, maxTags int) ([]string, error) {
+	repo, err := name.NewRepository(registry + "/" + repository)
+	if err != nil {
+		return nil, fmt.Errorf("parse repository: %w", err)
+	}
+	listed, err := remote.List(repo, c.options(ctx)...)
+	if err != nil {
+		return nil, fmt.Errorf("list tags %s: %w", repo, err)
+	}
+	var matches []string
+	for _, tag := range candidateTags(listed, maxTags) {
+		desc, err := remote.Head(repo.Tag(tag), c.options(ctx)...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return matches, ctx.Err()
+			}
+			continue
+		}
+		if desc.Digest.String() == digest {
+			matches = append(matches, tag)
+		}
+	}
+	return matches, nil
+}
+
+// candidateTags drops signature/attestation tags (sha256-*) and keeps the
+// last max entries of the listing. Registries list tags in lexical order, not
+// by push time, so these are the newest only for tags that sort by date or
+// version; other tags beyond max are never matched.
+func candidateTags(tags []string, max int) []string {
+	var out []string
+	for _, tag := range tags {
+		if tag == "" || strings.HasPrefix(tag, "sha256-") {
+			continue
+		}
+		out = append(out, tag)
+	}
+	if max > 0 && len(out) > max {
+		out = out[len(out)-max:]
+	}
+	return out
+}
+
+// pullSecretKeychain resolves credentials from a docker config style pull
+// secret. Entries may be keyed by registry host or by a repository prefix
+// (e.g. quay.io/org); the longest matching key wins.
+type pullSecretKeychain struct {
+	auths map[string]authn.AuthConfig
+}
+
+func loadPullSecret(path string) (pullSecretKeychain, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return pullSecretKeychain{}, fmt.Errorf("read pull secret %s: %w", path, err)
+	}
+	var file struct {
+		Auths map[string]authn.AuthConfig `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return pullSecretKeychain{}, fmt.Errorf("parse pull secret %s: %w", path, err)
+	}
+	return pullSecretKeychain{auths: file.Auths}, nil
+}
+
+func (k pullSecretKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	full := target.String()
+	best := ""
+	for key := range k.auths {
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+		trimmed = strings.TrimSuffix(trimmed, "/")
+		if (trimmed == target.RegistryStr() || strings.HasPrefix(full, trimmed+"/") || full == trimmed) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return authn.Anonymous, nil
+	}
+	return authn.FromConfig(k.auths[best]), nil
+}
@@ -0,0 +1,147 @@
+package traceimages
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestPullSecretKeychain_LongestPrefixWins(t *testing.T) {
+	kc := pullSecretKeychain{auths: map[string]authn.AuthConfig{
+		"quay.io":             {Username: "host"},
+		"quay.io/app-sre":     {Username: "org"},
+		"https://example.com": {Username: "scheme"},
+	}}
+
+	cases := map[string]string{
+		"quay.io/app-sre/uhc-clusters-service":  "org",
+		"quay.io/acm-d/rhtap-hypershift":        "host",
+		"example.com/team/image":                "scheme",
+		"arohcpsvcdev.azurecr.io/arohcpbackend": "",
+	}
+	for image, want := range cases {
+		repo, err := name.NewRepository(image)
+		if err != nil {
+			t.Fatalf("parse %s: %v", image, err)
+		}
+		auth, err := kc.Resolve(repo)
+		if err != nil {
+			t.Fatalf("resolve %s: %v", image, err)
+		}
+		cfg, err := auth.Authorization()
+		if err != nil {
+			t.Fatalf("authorization %s: %v", image, err)
+		}
+		if cfg.Username != want {
+			t.Fatalf("%s: got user %q, want %q", image, cfg.Username, want)
+		}
+	}
+}
+
+func TestCandidateTags(t *testing.T) {
+	got := candidateTags([]string{"v1", "sha256-abc.sig", "v2", "v3"}, 2)
+	if len(got) != 2 || got[0] != "v2" || got[1] != "v3" {
+		t.Fatalf("unexpected tags %v", got)
+	}
+}
+
+func TestInspectImageWithoutTagListing(t *testing.T) {
+	registryHandler := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/tags/list") {
+			http.Error(w, "tag listing disabled", http.StatusForbidden)
+			return
+		}
+		registryHandler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err = mutate.Config(img, v1.Config{Labels: map[string]string{"vcs-ref": "abc123"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := name.NewTag(host + "/hcp/frontend:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newRegistryClient("", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := &Tracer{cfg: Config{MaxTagCandidates: 10}, registry: client}
+	info, err := tracer.inspectImage(context.Background(), host, "hcp/frontend", digest.String())
+	if err != nil {
+		t.Fatalf("inspectImage = %v; want the labels despite the tag listing failure", err)
+	}
+	if info.Labels["vcs-ref"] != "abc123" || len(info.Tags) != 0 {
+		t.Fatalf("info = %+v", info)
+	}
+}
+
+func TestResolveTag(t *testing.T) {
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := name.NewTag(host + "/hcp/frontend:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newRegistryClient("", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		tag    string
+		digest string
+		ok     bool
+	}{
+		{"v1", digest.String(), true},
+		{"v2", "", false},
+		{"not a tag", "", false},
+	}
+	for _, c := range cases {
+		got, err := client.resolveTag(context.Background(), host, "hcp/frontend", c.tag)
+		if (err == nil) != c.ok || got != c.digest {
+			t.Errorf("resolveTag(%q) = %q, %v; want %q, ok=%v", c.tag, got, err, c.digest, c.ok)
+		}
+	}
+}
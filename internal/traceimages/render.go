@@ -0,0 +1,92 @@
+package traceimages
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/telemetry"
+)
+
+// renderedMarker flags a worktree whose rendered config the tracer generated,
+// for later traces reusing it.
+const renderedMarker = ".tracer-rendered"
+
+// renderBranch is the branch a commit must be on for its Makefile to be run:
+// the shared clone also fetches pull request heads, including forks'.
+const renderBranch = "origin/main"
+
+// ensureRendered makes sure src exists in the checkout of a rendered
+// environment, running RenderCommand in the checkout when the commit does not
+// contain it (older commits, or ones that did not re-render). Only commits of
+// renderBranch are rendered, in a sandbox. It reports whether the file was
+// rendered by the tracer rather than committed.
+func (t *Tracer) ensureRendered(ctx context.Context, checkoutDir, commitSHA string, src envFile) (bool, error) {
+	if !src.Rendered {
+		return false, nil
+	}
+	path := filepath.Join(checkoutDir, src.Path)
+	marker := filepath.Join(checkoutDir, renderedMarker)
+	// Pooled worktrees are shared by concurrent traces of the commit.
+	t.renderMu.Lock()
+	defer t.renderMu.Unlock()
+	if _, err := os.Stat(path); err == nil || !errors.Is(err, os.ErrNotExist) {
+		_, markErr := os.Stat(marker)
+		return markErr == nil, nil
+	}
+	if t.cfg.RenderCommand == "" {
+		return false, fmt.Errorf("%s is not in this commit and config rendering is disabled", src.Path)
+	}
+	onMain, err := t.repo.IsAncestor(ctx, commitSHA, renderBranch)
+	if err != nil {
+		return false, fmt.Errorf("check %s is on %s: %w", commitSHA, renderBranch, err)
+	}
+	if !onMain {
+		return false, fmt.Errorf("%s is not in this commit, and commits not on %s are not rendered", src.Path, renderBranch)
+	}
+
+	t.log.ForContext(ctx).Info("rendered config missing; rendering", "file", src.Path, "command", t.cfg.RenderCommand)
+	renderCtx, cancel := context.WithTimeout(ctx, t.cfg.RenderTimeout)
+	defer cancel()
+	if err := t.runRender(renderCtx, checkoutDir); err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return false, fmt.Errorf("%q did not produce %s", t.cfg.RenderCommand, src.Path)
+	}
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		return false, fmt.Errorf("mark rendered config: %w", err)
+	}
+	return true, nil
+}
+
+func (t *Tracer) runRender(ctx context.Context, dir string) (err error) {
+	ctx, span := telemetry.Start(ctx, "render config", attribute.String("render.command", t.cfg.RenderCommand))
+	defer func() { telemetry.End(span, err) }()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", t.cfg.RenderCommand)
+	cmd.Dir = dir
+	// Nothing of the server's environment, tokens included, is passed on.
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "HOME=" + dir}
+	if err := sandbox(cmd); err != nil {
+		return err
+	}
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		out := strings.TrimSpace(output.String())
+		if len(out) > 2000 {
+			out = "..." + out[len(out)-2000:]
+		}
+		return fmt.Errorf("render config with %q: %v: %s", t.cfg.RenderCommand, err, out)
+	}
+	return nil
+}
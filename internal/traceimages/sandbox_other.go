@@ -0,0 +1,13 @@
+//go:build !linux
+
+package traceimages
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// sandbox needs Linux namespaces; elsewhere config rendering is refused.
+func sandbox(cmd *exec.Cmd) error {
+	return errors.New("config rendering needs a Linux sandbox")
+}
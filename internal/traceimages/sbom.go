@@ -0,0 +1,254 @@
+package traceimages
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel/attribute"
+
+	tooltypes "github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+	"github.com/roivaz/aro-hcp-intelhub/internal/telemetry"
+)
+
+// maxSBOMBytes bounds the SBOM documents read from registries and syft.
+const maxSBOMBytes = 64 << 20
+
+var errNoSBOM = errors.New("no SBOM attached")
+
+// notableSBOMPackages are packages whose versions matter for CVE triage:
+// TLS, libc, compression and the Go runtime and networking libraries.
+var notableSBOMPackages = map[string]bool{
+	"openssl":                      true,
+	"openssl-libs":                 true,
+	"glibc":                        true,
+	"zlib":                         true,
+	"curl":                         true,
+	"libcurl":                      true,
+	"krb5-libs":                    true,
+	"libxml2":                      true,
+	"expat":                        true,
+	"sqlite-libs":                  true,
+	"stdlib":                       true, // the Go standard library, as syft names it
+	"golang.org/x/net":             true,
+	"golang.org/x/crypto":          true,
+	"google.golang.org/grpc":       true,
+	"google.golang.org/protobuf":   true,
+	"github.com/golang-jwt/jwt/v4": true,
+	"github.com/golang-jwt/jwt/v5": true,
+	"k8s.io/apimachinery":          true,
+}
+
+// SBOM summarizes the SBOM of the image at digest: the one attached to it
+// with cosign, or one generated by syft when SyftPath is set. Images with
+// neither are reported with source "none".
+func (t *Tracer) SBOM(ctx context.Context, registry, repository, digest string) (tooltypes.SBOMSummary, error) {
+	data, source, err := t.fetchSBOM(ctx, registry, repository, digest)
+	if errors.Is(err, errNoSBOM) {
+		return tooltypes.SBOMSummary{Source: "none"}, nil
+	}
+	if err != nil {
+		return tooltypes.SBOMSummary{}, err
+	}
+	format, packages, err := parseSBOM(data)
+	if err != nil {
+		return tooltypes.SBOMSummary{}, err
+	}
+	return tooltypes.SBOMSummary{
+		Source:       source,
+		Format:       format,
+		PackageCount: len(packages),
+		Notable:      notablePackages(packages),
+	}, nil
+}
+
+func (t *Tracer) fetchSBOM(ctx context.Context, registry, repository, digest string) ([]byte, string, error) {
+	if t.registry != nil {
+		data, err := t.registry.attachedSBOM(ctx, registry, repository, digest)
+		if err == nil {
+			return data, "attached", nil
+		}
+		if !errors.Is(err, errNoSBOM) {
+			return nil, "", err
+		}
+	}
+	if t.cfg.SyftPath == "" {
+		return nil, "", errNoSBOM
+	}
+	data, err := t.runSyft(ctx, fmt.Sprintf("registry:%s/%s@%s", registry, repository, digest))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, "syft", nil
+}
+
+// attachedSBOM reads the SBOM cosign attaches to an image: the single layer
+// of the "sha256-<hex>.sbom" tag, or else an SPDX or CycloneDX attestation
+// among the layers of the "sha256-<hex>.att" tag.
+func (c *registryClient) attachedSBOM(ctx context.Context, registry, repository, digest string) ([]byte, error) {
+	repo, err := name.NewRepository(registry + "/" + repository)
+	if err != nil {
+		return nil, fmt.Errorf("parse repository: %w", err)
+	}
+	prefix := strings.Replace(digest, ":", "-", 1)
+	layers, err := c.tagLayers(ctx, repo, prefix+".sbom")
+	if err != nil && !errors.Is(err, errNoSBOM) {
+		return nil, fmt.Errorf("get SBOM of %s@%s: %w", repo, digest, err)
+	}
+	if len(layers) > 0 {
+		return readLayer(layers[0])
+	}
+	layers, err = c.tagLayers(ctx, repo, prefix+".att")
+	if err != nil {
+		if errors.Is(err, errNoSBOM) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("get attestations of %s@%s: %w", repo, digest, err)
+	}
+	for _, layer := range layers {
+		data, err := readLayer(layer)
+		if err != nil {
+			return nil, err
+		}
+		if statement, ok := sbomStatement(data); ok {
+			return statement, nil
+		}
+	}
+	return nil, errNoSBOM
+}
+
+// tagLayers returns the layers of the image tagged tag in repo, errNoSBOM
+// when the tag does not exist.
+func (c *registryClient) tagLayers(ctx context.Context, repo name.Repository, tag string) ([]v1.Layer, error) {
+	img, err := remote.Image(repo.Tag(tag), c.options(ctx)...)
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+			return nil, errNoSBOM
+		}
+		return nil, err
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("read %s manifest: %w", tag, err)
+	}
+	return layers, nil
+}
+
+// readLayer returns the content of layer, gunzipped when compressed.
+func readLayer(layer v1.Layer) ([]byte, error) {
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, fmt.Errorf("read SBOM layer: %w", err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxSBOMBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read SBOM layer: %w", err)
+	}
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("read SBOM layer: %w", err)
+		}
+		defer zr.Close()
+		return io.ReadAll(io.LimitReader(zr, maxSBOMBytes))
+	}
+	return data, nil
+}
+
+// sbomStatement returns the in-toto statement of a cosign attestation layer,
+// a DSSE envelope, when it attests an SPDX or CycloneDX SBOM.
+func sbomStatement(envelope []byte) ([]byte, bool) {
+	payload := gjson.GetBytes(envelope, "payload")
+	if !payload.Exists() {
+		return nil, false
+	}
+	statement, err := base64.StdEncoding.DecodeString(payload.Str)
+	if err != nil {
+		return nil, false
+	}
+	predicateType := gjson.GetBytes(statement, "predicateType").Str
+	if !strings.Contains(predicateType, "spdx") && !strings.Contains(predicateType, "cyclonedx") {
+		return nil, false
+	}
+	return statement, true
+}
+
+func (t *Tracer) runSyft(ctx context.Context, source string) (_ []byte, err error) {
+	ctx, span := telemetry.Start(ctx, "syft scan", attribute.String("syft.source", source))
+	defer func() { telemetry.End(span, err) }()
+
+	cmd := exec.CommandContext(ctx, t.cfg.SyftPath, "scan", source, "-o", "cyclonedx-json", "-q")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("syft scan %s: %v: %s", source, err, strings.TrimSpace(stderr.String()))
+	}
+	if len(output) > maxSBOMBytes {
+		return nil, fmt.Errorf("syft scan %s: SBOM larger than %d bytes", source, maxSBOMBytes)
+	}
+	return output, nil
+}
+
+// parseSBOM returns the format and packages of an SPDX or CycloneDX JSON
+// document, unwrapping an in-toto attestation statement.
+func parseSBOM(data []byte) (string, []tooltypes.SBOMPackage, error) {
+	if !gjson.ValidBytes(data) {
+		return "", nil, fmt.Errorf("SBOM is not JSON")
+	}
+	doc := gjson.ParseBytes(data)
+	if predicate := doc.Get("predicate"); predicate.IsObject() {
+		doc = predicate
+	}
+	var packages []tooltypes.SBOMPackage
+	switch {
+	case doc.Get("spdxVersion").Exists():
+		doc.Get("packages").ForEach(func(_, pkg gjson.Result) bool {
+			packages = append(packages, tooltypes.SBOMPackage{Name: pkg.Get("name").Str, Version: pkg.Get("versionInfo").Str})
+			return true
+		})
+		return "spdx", packages, nil
+	case doc.Get("bomFormat").Str == "CycloneDX":
+		doc.Get("components").ForEach(func(_, pkg gjson.Result) bool {
+			packages = append(packages, tooltypes.SBOMPackage{Name: pkg.Get("name").Str, Version: pkg.Get("version").Str})
+			return true
+		})
+		return "cyclonedx", packages, nil
+	default:
+		return "", nil, fmt.Errorf("SBOM is neither SPDX nor CycloneDX JSON")
+	}
+}
+
+// notablePackages returns the distinct notable packages, sorted by name.
+func notablePackages(packages []tooltypes.SBOMPackage) []tooltypes.SBOMPackage {
+	seen := map[tooltypes.SBOMPackage]bool{}
+	var notable []tooltypes.SBOMPackage
+	for _, pkg := range packages {
+		if notableSBOMPackages[pkg.Name] && !seen[pkg] {
+			seen[pkg] = true
+			notable = append(notable, pkg)
+		}
+	}
+	sort.Slice(notable, func(i, j int) bool {
+		if notable[i].Name != notable[j].Name {
+			return notable[i].Name < notable[j].Name
+		}
+		return notable[i].Version < notable[j].Version
+	})
+	return notable
+}
@@ -0,0 +1,93 @@
+package traceimages
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+
+	tooltypes "github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+)
+
+func TestParseSBOM(t *testing.T) {
+	spdx := `{"spdxVersion":"SPDX-2.3","packages":[
+		{"name":"openssl-libs","versionInfo":"3.0.7"},
+		{"name":"bash","versionInfo":"5.1"},
+		{"name":"openssl-libs","versionInfo":"3.0.7"}]}`
+	format, packages, err := parseSBOM([]byte(spdx))
+	if err != nil || format != "spdx" || len(packages) != 3 {
+		t.Fatalf("spdx: format=%q packages=%d err=%v", format, len(packages), err)
+	}
+	want := []tooltypes.SBOMPackage{{Name: "openssl-libs", Version: "3.0.7"}}
+	if got := notablePackages(packages); !reflect.DeepEqual(got, want) {
+		t.Errorf("notable = %v, want %v", got, want)
+	}
+
+	attestation := `{"predicateType":"https://cyclonedx.org/bom","predicate":{"bomFormat":"CycloneDX",
+		"components":[{"name":"stdlib","version":"go1.22.5"},{"name":"golang.org/x/net","version":"v0.25.0"}]}}`
+	format, packages, err = parseSBOM([]byte(attestation))
+	if err != nil || format != "cyclonedx" || len(notablePackages(packages)) != 2 {
+		t.Errorf("cyclonedx attestation: format=%q packages=%v err=%v", format, packages, err)
+	}
+
+	if _, _, err := parseSBOM([]byte(`{"foo":1}`)); err == nil {
+		t.Error("unknown document parsed")
+	}
+}
+
+func TestAttachedSBOMFromAttestation(t *testing.T) {
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	envelope := func(predicateType, predicate string) v1.Layer {
+		statement := fmt.Sprintf(`{"predicateType":%q,"predicate":%s}`, predicateType, predicate)
+		data := fmt.Sprintf(`{"payloadType":"application/vnd.in-toto+json","payload":%q}`, base64.StdEncoding.EncodeToString([]byte(statement)))
+		return static.NewLayer([]byte(data), "application/vnd.dsse.envelope.v1+json")
+	}
+	att, err := mutate.AppendLayers(empty.Image,
+		envelope("https://slsa.dev/provenance/v1", `{}`),
+		envelope("https://spdx.dev/Document", `{"spdxVersion":"SPDX-2.3","packages":[{"name":"zlib","versionInfo":"1.3"}]}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	ref, err := name.NewTag(host + "/hcp/frontend:sha256-" + strings.Repeat("ab", 32) + ".att")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, att); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newRegistryClient("", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := &Tracer{registry: client}
+	summary, err := tracer.SBOM(context.Background(), host, "hcp/frontend", digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Source != "attached" || summary.Format != "spdx" || summary.PackageCount != 1 {
+		t.Fatalf("summary = %+v, want the SPDX attestation", summary)
+	}
+	if summary, err := tracer.SBOM(context.Background(), host, "hcp/backend", digest); err != nil || summary.Source != "none" {
+		t.Fatalf("SBOM of an image without attachments = %+v, %v", summary, err)
+	}
+}
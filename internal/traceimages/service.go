@@ -3,6 +3,8 @@ package traceimages
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/roivaz/aro-hcp-intelhub/internal/db"
 	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
@@ -22,50 +24,152 @@ func New(tracer *Tracer, repo *db.SearchRepository, log logging.Logger) *Service
 }
 
 // TraceImages returns the trace information for a commit/environment pair, serving cached results when possible.
-func (s *Service) TraceImages(ctx context.Context, commitSHA, environment string) (tooltypes.TraceImagesResponse, error) {
+// forceRefresh skips the cache lookup and replaces the cached entry with a fresh trace.
+// includeSBOM adds each component's SBOM summary. Summaries are cached with
+// the trace: a cached trace gets the summaries it lacks or failed to look up,
+// and forceRefresh looks them all up again.
+func (s *Service) TraceImages(ctx context.Context, commitSHA, environment string, forceRefresh, includeSBOM bool) (tooltypes.TraceImagesResponse, error) {
+	log := s.log.ForContext(ctx)
 	if commitSHA == "" || environment == "" {
 		return tooltypes.TraceImagesResponse{}, fmt.Errorf("commit and environment are required")
 	}
 
 	if s.repo == nil {
-		s.log.Debug("no cache repository configured; invoking tracer")
-		return s.traceAndBuild(ctx, commitSHA, environment)
+		log.Debug("no cache repository configured; invoking tracer")
+		resp, err := s.traceAndBuild(ctx, commitSHA, environment)
+		if err == nil && includeSBOM {
+			s.addSBOMs(ctx, resp.Components)
+		}
+		return resp, err
 	}
 
-	s.log.Debug("checking trace cache", "commit", commitSHA, "environment", environment)
-	cached, err := s.repo.TraceImageCacheGet(ctx, commitSHA, environment)
-	if err != nil {
-		s.log.Error(err, "trace cache lookup failed", "commit", commitSHA, "environment", environment)
-		return tooltypes.TraceImagesResponse{}, err
-	}
-	if cached != nil {
-		s.log.Debug("cache hit", "commit", commitSHA, "environment", environment)
-		return cached.Response, nil
+	if forceRefresh {
+		log.Debug("forced refresh; skipping trace cache", "commit", commitSHA, "environment", environment)
+	} else {
+		log.Debug("checking trace cache", "commit", commitSHA, "environment", environment)
+		cached, err := s.repo.TraceImageCacheGet(ctx, commitSHA, environment)
+		if err != nil {
+			log.Error(err, "trace cache lookup failed", "commit", commitSHA, "environment", environment)
+			return tooltypes.TraceImagesResponse{}, err
+		}
+		if cached != nil {
+			log.Debug("cache hit", "commit", commitSHA, "environment", environment)
+			resp := cached.Response
+			if !includeSBOM {
+				return withoutSBOMs(resp), nil
+			}
+			if s.addSBOMs(ctx, resp.Components) > 0 {
+				s.cache(ctx, resp)
+			}
+			return resp, nil
+		}
+		log.Debug("cache miss", "commit", commitSHA, "environment", environment)
 	}
 
-	s.log.Debug("cache miss", "commit", commitSHA, "environment", environment)
 	resp, err := s.traceAndBuild(ctx, commitSHA, environment)
 	if err != nil {
 		return tooltypes.TraceImagesResponse{}, err
 	}
+	if includeSBOM {
+		s.addSBOMs(ctx, resp.Components)
+	}
 
 	if hasErrors(resp) {
-		s.log.Debug("skipping cache due to errors", "commit", commitSHA, "environment", environment, "errors", resp.Errors)
+		log.Debug("skipping cache due to errors", "commit", commitSHA, "environment", environment, "errors", resp.Errors)
 		return resp, nil
 	}
 
 	if err := s.repo.TraceImageCacheUpsert(ctx, commitSHA, environment, resp); err != nil {
-		s.log.Error(err, "trace cache upsert failed", "commit", commitSHA, "environment", environment)
+		log.Error(err, "trace cache upsert failed", "commit", commitSHA, "environment", environment)
 		return tooltypes.TraceImagesResponse{}, err
 	}
 
 	return resp, nil
 }
 
+// cache replaces the cached trace with resp after adding SBOMs to it. Failures
+// are logged: the caller already has the response to return.
+func (s *Service) cache(ctx context.Context, resp tooltypes.TraceImagesResponse) {
+	log := s.log.ForContext(ctx)
+	if hasErrors(resp) {
+		log.Debug("skipping cache due to errors", "commit", resp.CommitSHA, "environment", resp.Environment)
+		return
+	}
+	if err := s.repo.TraceImageCacheUpsert(ctx, resp.CommitSHA, resp.Environment, resp); err != nil {
+		log.Error(err, "trace cache upsert failed", "commit", resp.CommitSHA, "environment", resp.Environment)
+	}
+}
+
+// addSBOMs sets the SBOM summary of every component with a digest that has
+// none or a failed one, looking them up concurrently like the trace's image
+// inspections. It returns the number of components looked up.
+func (s *Service) addSBOMs(ctx context.Context, components []tooltypes.ComponentTraceInfo) int {
+	sem := make(chan struct{}, s.tracer.cfg.InspectConcurrency)
+	var wg sync.WaitGroup
+	looked := 0
+	for i := range components {
+		comp := &components[i]
+		if comp.Registry == "" || comp.Repository == "" || comp.Digest == "" {
+			continue
+		}
+		if comp.SBOM != nil && comp.SBOM.Error == nil {
+			continue
+		}
+		looked++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			sbomCtx, cancel := context.WithTimeout(ctx, s.tracer.cfg.InspectTimeout)
+			defer cancel()
+			var summary tooltypes.SBOMSummary
+			err := s.tracer.breakers.guard(ctx, comp.Registry, func() (err error) {
+				summary, err = s.tracer.SBOM(sbomCtx, comp.Registry, comp.Repository, comp.Digest)
+				return err
+			})
+			if err != nil {
+				s.log.ForContext(ctx).Error(err, "SBOM lookup failed", "component", comp.Name)
+				msg := err.Error()
+				summary = tooltypes.SBOMSummary{Error: &msg}
+			}
+			comp.SBOM = &summary
+		}()
+	}
+	wg.Wait()
+	return looked
+}
+
+func hasSBOMs(resp tooltypes.TraceImagesResponse) bool {
+	for _, comp := range resp.Components {
+		if comp.SBOM != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutSBOMs returns resp without the SBOM summaries a previous request
+// cached with it.
+func withoutSBOMs(resp tooltypes.TraceImagesResponse) tooltypes.TraceImagesResponse {
+	if !hasSBOMs(resp) {
+		return resp
+	}
+	components := make([]tooltypes.ComponentTraceInfo, len(resp.Components))
+	for i, comp := range resp.Components {
+		comp.SBOM = nil
+		components[i] = comp
+	}
+	resp.Components = components
+	return resp
+}
+
 func (s *Service) traceAndBuild(ctx context.Context, commitSHA, environment string) (tooltypes.TraceImagesResponse, error) {
+	log := s.log.ForContext(ctx)
 	result, err := s.tracer.Trace(ctx, commitSHA, environment)
 	if err != nil {
-		s.log.Error(err, "trace execution failed", "commit", commitSHA, "environment", environment)
+		log.Error(err, "trace execution failed", "commit", commitSHA, "environment", environment)
 		return tooltypes.TraceImagesResponse{}, err
 	}
 
@@ -76,12 +180,18 @@ func (s *Service) traceAndBuild(ctx context.Context, commitSHA, environment stri
 			Registry:      comp.Registry,
 			Repository:    comp.Repository,
 			Digest:        comp.Digest,
+			ConfigTag:     comp.ConfigTag,
 			SourceSHA:     comp.SourceSHA,
 			SourceRepoURL: comp.SourceRepoURL,
+			Tags:          comp.Tags,
+			ConfigSource:  comp.ConfigSource,
 			Error:         comp.Error,
+			ErrorClass:    comp.ErrorClass,
 		}
 	}
 
+	s.linkPRs(ctx, components)
+
 	return tooltypes.TraceImagesResponse{
 		CommitSHA:   result.CommitSHA,
 		Environment: result.Environment,
@@ -90,6 +200,103 @@ func (s *Service) traceAndBuild(ctx context.Context, commitSHA, environment stri
 	}, nil
 }
 
+// linkPRs attaches ingested PRs whose merge or head commit matches each
+// component's source SHA, and the PR behind each config source commit.
+// Lookup failures are logged and leave components unlinked.
+func (s *Service) linkPRs(ctx context.Context, components []tooltypes.ComponentTraceInfo) {
+	log := s.log.ForContext(ctx)
+	if s.repo == nil {
+		return
+	}
+	var shas []string
+	for _, comp := range components {
+		if comp.SourceSHA != nil && *comp.SourceSHA != "" {
+			shas = append(shas, *comp.SourceSHA)
+		}
+		if src := comp.ConfigSource; src != nil {
+			shas = append(shas, configCommits(*src)...)
+		}
+	}
+	if len(shas) == 0 {
+		return
+	}
+
+	prs, err := s.repo.FindPRsByCommitSHAs(ctx, shas)
+	if err != nil {
+		log.Error(err, "link components to PRs failed")
+		return
+	}
+
+	for i := range components {
+		if src := components[i].ConfigSource; src != nil {
+			src.PR = configSourcePR(*src, prs)
+		}
+		if components[i].SourceSHA == nil {
+			continue
+		}
+		sha := *components[i].SourceSHA
+		for _, pr := range prs {
+			switch {
+			case pr.MergeCommitSHA != nil && *pr.MergeCommitSHA == sha:
+				components[i].LinkedPRs = append(components[i].LinkedPRs, tooltypes.LinkedPR{PRNumber: pr.PRNumber, Title: pr.PRTitle, MatchedOn: "merge_commit_sha"})
+			case pr.HeadCommitSHA != nil && *pr.HeadCommitSHA == sha:
+				components[i].LinkedPRs = append(components[i].LinkedPRs, tooltypes.LinkedPR{PRNumber: pr.PRNumber, Title: pr.PRTitle, MatchedOn: "head_commit_sha"})
+			}
+		}
+	}
+}
+
+// configCommits returns the merge and blamed commits of src, in that order.
+func configCommits(src tooltypes.ConfigSource) []string {
+	var shas []string
+	for _, sha := range []*string{src.MergeCommit, src.Commit} {
+		if sha != nil && *sha != "" {
+			shas = append(shas, *sha)
+		}
+	}
+	return shas
+}
+
+// configSourcePR returns the PR that last changed src's digest line: the one
+// merged as one of its commits, else the one whose head commit it is.
+func configSourcePR(src tooltypes.ConfigSource, prs []db.PREmbedding) *tooltypes.LinkedPR {
+	shas := configCommits(src)
+	for _, sha := range shas {
+		for _, pr := range prs {
+			if pr.MergeCommitSHA != nil && *pr.MergeCommitSHA == sha {
+				return &tooltypes.LinkedPR{PRNumber: pr.PRNumber, Title: pr.PRTitle, MatchedOn: "merge_commit_sha"}
+			}
+		}
+	}
+	for _, sha := range shas {
+		for _, pr := range prs {
+			if pr.HeadCommitSHA != nil && *pr.HeadCommitSHA == sha {
+				return &tooltypes.LinkedPR{PRNumber: pr.PRNumber, Title: pr.PRTitle, MatchedOn: "head_commit_sha"}
+			}
+		}
+	}
+	return nil
+}
+
+// DeployedCommit returns the ARO-HCP commit the traced environment runs: the
+// source SHA of the first component built from repoURL, the repository the
+// tracer was configured with (the default one when empty).
+func DeployedCommit(resp tooltypes.TraceImagesResponse, repoURL string) (string, bool) {
+	if repoURL == "" {
+		repoURL = defaultRepoURL
+	}
+	repoURL = strings.TrimSuffix(repoURL, "/")
+	for _, comp := range resp.Components {
+		if comp.SourceSHA == nil || *comp.SourceSHA == "" || comp.SourceRepoURL == nil {
+			continue
+		}
+		if strings.TrimSuffix(*comp.SourceRepoURL, "/") == repoURL {
+			return *comp.SourceSHA, true
+		}
+	}
+	return "", false
+}
+
 func hasErrors(resp tooltypes.TraceImagesResponse) bool {
 	if len(resp.Errors) > 0 {
 		return true
@@ -98,6 +305,9 @@ func hasErrors(resp tooltypes.TraceImagesResponse) bool {
 		if comp.Error != nil && *comp.Error != "" {
 			return true
 		}
+		if comp.SBOM != nil && comp.SBOM.Error != nil {
+			return true
+		}
 	}
 	return false
 }
@@ -2,22 +2,40 @@ package traceimages
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel/attribute"
 	"sigs.k8s.io/yaml"
 
 	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
 	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
+	"github.com/roivaz/aro-hcp-intelhub/internal/telemetry"
 )
 
-const defaultRepoURL = "https://github.com/Azure/ARO-HCP"
+const (
+	defaultRepoURL          = "https://github.com/Azure/ARO-HCP"
+	defaultMaxTagCandidates = 50
+	defaultInspectWorkers   = 4
+	defaultInspectTimeout   = 2 * time.Minute
+	defaultRegistryTimeout  = 15 * time.Second
+	defaultRegistryCooldown = 5 * time.Minute
+	defaultRenderTimeout    = 5 * time.Minute
+	defaultWorktreePoolSize = 4
+)
 
+// componentMappings fills in where components come from. An empty SourceRepo
+// means the traced repository itself, Config.RepoURL.
 var componentMappings = map[string]struct {
 	Registry   string
 	Repository string
@@ -26,12 +44,10 @@ var componentMappings = map[string]struct {
 	"Backend": {
 		Registry:   "arohcpsvcdev.azurecr.io",
 		Repository: "arohcpbackend",
-		SourceRepo: "https://github.com/Azure/ARO-HCP",
 	},
 	"Frontend": {
 		Registry:   "arohcpsvcdev.azurecr.io",
 		Repository: "arohcpfrontend",
-		SourceRepo: "https://github.com/Azure/ARO-HCP",
 	},
 	"Cluster Service": {
 		Registry:   "quay.io",
@@ -97,12 +113,16 @@ var imageConfigPaths = map[string][]string{
 type envFile struct {
 	Path     string
 	BasePath []string
+	// Rendered files are generated from the config templates; commits that
+	// lack one are rendered with Config.RenderCommand.
+	Rendered bool
 }
 
 var environmentConfigSources = map[string]envFile{
 	"dev": {
 		Path:     filepath.Join("config", "rendered", "dev", "dev", "westus3.yaml"),
 		BasePath: nil,
+		Rendered: true,
 	},
 	"int": {
 		Path:     filepath.Join("config", "config.msft.clouds-overlay.yaml"),
@@ -118,18 +138,59 @@ var environmentConfigSources = map[string]envFile{
 	},
 }
 
+// Environments returns the environment names Trace accepts, sorted.
+func Environments() []string {
+	return sortedKeys(environmentConfigSources)
+}
+
 type Config struct {
 	RepoPath   string
 	SkopeoPath string
 	PullSecret string
 	RepoURL    string
-	Logger     logging.Logger
+	// MaxTagCandidates caps how many tags, the last in the registry's lexical
+	// listing, are resolved when looking for tags that point at a digest (0
+	// uses the default, <0 disables).
+	MaxTagCandidates int
+	// SkopeoOnly disables the native registry client and always shells out
+	// to skopeo.
+	SkopeoOnly bool
+	// InspectConcurrency bounds how many components are inspected at once.
+	InspectConcurrency int
+	// InspectTimeout bounds a single component inspection, tag lookup included.
+	InspectTimeout time.Duration
+	// RegistryTimeout bounds connecting to a registry and waiting for each
+	// of its responses.
+	RegistryTimeout time.Duration
+	// RegistryCooldown is how long a registry that could not be reached is
+	// skipped, its components returned digest-only (<0 disables skipping).
+	RegistryCooldown time.Duration
+	// WorktreePoolSize is how many checkouts of recently traced commits are
+	// kept for reuse.
+	WorktreePoolSize int
+	// RenderCommand renders the environment configs in a checkout whose
+	// commit lacks a rendered file (run with sh -c in a sandbox without
+	// network, for commits on origin/main only; empty disables), and
+	// RenderTimeout bounds it.
+	RenderCommand string
+	RenderTimeout time.Duration
+	// SyftPath is the syft binary used to generate SBOMs of images without
+	// an attached one; empty disables generation.
+	SyftPath string
+	// Git configures the clone at RepoPath.
+	Git    gitrepo.Settings
+	Logger logging.Logger
 }
 
 type Tracer struct {
-	cfg  Config
-	repo *gitrepo.Repo
-	log  logging.Logger
+	cfg      Config
+	repo     *gitrepo.Repo
+	registry *registryClient // nil when SkopeoOnly is set
+	breakers *registryBreakers
+	log      logging.Logger
+
+	worktrees *worktreePool
+	renderMu  sync.Mutex // serializes rendering into shared worktrees
 }
 
 func NewTracer(cfg Config) (*Tracer, error) {
@@ -142,6 +203,27 @@ func NewTracer(cfg Config) (*Tracer, error) {
 	if cfg.RepoURL == "" {
 		cfg.RepoURL = defaultRepoURL
 	}
+	if cfg.MaxTagCandidates == 0 {
+		cfg.MaxTagCandidates = defaultMaxTagCandidates
+	}
+	if cfg.InspectConcurrency <= 0 {
+		cfg.InspectConcurrency = defaultInspectWorkers
+	}
+	if cfg.InspectTimeout <= 0 {
+		cfg.InspectTimeout = defaultInspectTimeout
+	}
+	if cfg.WorktreePoolSize <= 0 {
+		cfg.WorktreePoolSize = defaultWorktreePoolSize
+	}
+	if cfg.RenderTimeout <= 0 {
+		cfg.RenderTimeout = defaultRenderTimeout
+	}
+	if cfg.RegistryTimeout <= 0 {
+		cfg.RegistryTimeout = defaultRegistryTimeout
+	}
+	if cfg.RegistryCooldown == 0 {
+		cfg.RegistryCooldown = defaultRegistryCooldown
+	}
 
 	log := cfg.Logger
 	if log.Logr().GetSink() == nil {
@@ -149,9 +231,30 @@ func NewTracer(cfg Config) (*Tracer, error) {
 	}
 	log = log.WithName("traceimages.tracer")
 
-	repo := gitrepo.New(gitrepo.RepoConfig{URL: cfg.RepoURL, Path: cfg.RepoPath})
+	repo := gitrepo.New(gitrepo.RepoConfig{
+		Settings: cfg.Git,
+		URL:      cfg.RepoURL,
+		Path:     cfg.RepoPath,
+		Progress: func(line string) { log.Info("clone progress", "repo", cfg.RepoURL, "progress", line) },
+	})
 
-	return &Tracer{cfg: cfg, repo: repo, log: log}, nil
+	var registry *registryClient
+	if !cfg.SkopeoOnly {
+		client, err := newRegistryClient(cfg.PullSecret, cfg.RegistryTimeout)
+		if err != nil {
+			return nil, err
+		}
+		registry = client
+	}
+
+	return &Tracer{
+		cfg:       cfg,
+		repo:      repo,
+		registry:  registry,
+		breakers:  newRegistryBreakers(cfg.RegistryCooldown),
+		log:       log,
+		worktrees: newWorktreePool(repo, cfg.WorktreePoolSize, log),
+	}, nil
 }
 
 func (t *Tracer) Trace(ctx context.Context, commitSHA, environment string) (TraceResult, error) {
@@ -163,21 +266,23 @@ func (t *Tracer) Trace(ctx context.Context, commitSHA, environment string) (Trac
 	}
 
 	if err := t.ensureRepo(ctx); err != nil {
-		t.log.Error(err, "prepare repo failed")
+		t.log.ForContext(ctx).Error(err, "prepare repo failed")
 		result.Errors = append(result.Errors, fmt.Sprintf("prepare repo: %v", err))
 		return result, nil
 	}
 
-	repoPath, err := filepath.Abs(t.cfg.RepoPath)
+	checkoutDir, release, err := t.worktrees.acquire(ctx, commitSHA)
 	if err != nil {
-		return result, fmt.Errorf("resolve repo path: %w", err)
+		return result, err
 	}
+	defer release()
 
-	checkoutDir, restore, err := t.checkoutCommit(ctx, repoPath, commitSHA)
+	generated, err := t.ensureRendered(ctx, checkoutDir, commitSHA, source)
 	if err != nil {
-		return result, err
+		t.log.ForContext(ctx).Error(err, "render config failed", "file", source.Path)
+		result.Errors = append(result.Errors, fmt.Sprintf("render config: %v", err))
+		return result, nil
 	}
-	defer restore()
 
 	envConfig, err := loadEnvironmentConfig(checkoutDir, source)
 	if err != nil {
@@ -185,20 +290,17 @@ func (t *Tracer) Trace(ctx context.Context, commitSHA, environment string) (Trac
 		return result, nil
 	}
 
+	sources := t.configSources(ctx, commitSHA, checkoutDir, source, generated)
 	components := make([]Component, 0, len(imageConfigPaths))
-	var errs []string
-
 	for _, name := range sortedKeys(imageConfigPaths) {
 		section := getNested(envConfig, imageConfigPaths[name])
-		registry := stringFromMap(section, "registry")
-		repository := stringFromMap(section, "repository")
-		digest := stringFromMap(section, "digest")
-
 		component := Component{
-			Name:       name,
-			Registry:   registry,
-			Repository: repository,
-			Digest:     digest,
+			Name:         name,
+			Registry:     stringFromMap(section, "registry"),
+			Repository:   stringFromMap(section, "repository"),
+			Digest:       stringFromMap(section, "digest"),
+			ConfigTag:    stringFromMap(section, "tag"),
+			ConfigSource: sources[name],
 		}
 
 		if mapping, ok := componentMappings[name]; ok {
@@ -208,32 +310,72 @@ func (t *Tracer) Trace(ctx context.Context, commitSHA, environment string) (Trac
 			if component.Repository == "" && mapping.Repository != "" {
 				component.Repository = mapping.Repository
 			}
-			if mapping.SourceRepo != "" {
-				src := mapping.SourceRepo
-				component.SourceRepoURL = &src
+			src := mapping.SourceRepo
+			if src == "" {
+				src = t.cfg.RepoURL
 			}
+			component.SourceRepoURL = &src
 		}
+		components = append(components, component)
+	}
 
+	// Inspect in parallel; each worker only writes its own slot so errors can
+	// be collected afterwards in component order.
+	componentErrs := make([]string, len(components))
+	sem := make(chan struct{}, t.cfg.InspectConcurrency)
+	var wg sync.WaitGroup
+	for i := range components {
+		component := &components[i]
 		if component.Registry == "" || component.Repository == "" {
-			err := fmt.Errorf("missing registry or repository for %s", name)
-			errs = append(errs, err.Error())
-			msg := err.Error()
+			msg := fmt.Sprintf("missing registry or repository for %s", component.Name)
 			component.Error = &msg
-			components = append(components, component)
+			componentErrs[i] = msg
 			continue
 		}
 
-		labels, err := t.inspectImage(ctx, component.Registry, component.Repository, component.Digest)
-		if err != nil {
-			t.log.Error(err, "inspect image failed", "component", name)
-			msg := err.Error()
-			component.Error = &msg
-			errs = append(errs, fmt.Sprintf("inspect %s: %v", name, err))
-		} else if sha := labels["vcs-ref"]; sha != "" {
-			component.SourceSHA = &sha
-		}
+		wg.Add(1)
+		go func(i int, component *Component) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			inspectCtx, cancel := context.WithTimeout(ctx, t.cfg.InspectTimeout)
+			defer cancel()
+			var info imageInfo
+			err := t.breakers.guard(ctx, component.Registry, func() (err error) {
+				if component.Digest == "" && component.ConfigTag != "" {
+					component.Digest, err = t.resolveTag(inspectCtx, component.Registry, component.Repository, component.ConfigTag)
+					if err != nil {
+						return fmt.Errorf("resolve tag %s: %w", component.ConfigTag, err)
+					}
+				}
+				info, err = t.inspectImage(inspectCtx, component.Registry, component.Repository, component.Digest)
+				return err
+			})
+			if err != nil {
+				t.log.ForContext(ctx).Error(err, "inspect image failed", "component", component.Name)
+				msg := err.Error()
+				component.Error = &msg
+				if errors.Is(err, errRegistryUnreachable) {
+					class := ErrorClassRegistryUnreachable
+					component.ErrorClass = &class
+				}
+				componentErrs[i] = fmt.Sprintf("inspect %s: %v", component.Name, err)
+				return
+			}
+			if sha := info.Labels["vcs-ref"]; sha != "" {
+				component.SourceSHA = &sha
+			}
+			component.Tags = info.Tags
+		}(i, component)
+	}
+	wg.Wait()
 
-		components = append(components, component)
+	var errs []string
+	for _, msg := range componentErrs {
+		if msg != "" {
+			errs = append(errs, msg)
+		}
 	}
 
 	result.Components = components
@@ -271,36 +413,69 @@ func (t *Tracer) ensureRepo(ctx context.Context) error {
 	return err
 }
 
-func (t *Tracer) checkoutCommit(ctx context.Context, repoPath, commit string) (string, func(), error) {
-	if _, err := t.repo.Run(ctx, "rev-parse", commit); err != nil {
-		return "", nil, fmt.Errorf("resolve commit %s: %w", commit, err)
-	}
+// Close removes the pooled worktrees, including those of traces still in
+// progress, for shutdowns that could not wait for them to finish.
+func (t *Tracer) Close() error {
+	t.worktrees.close()
+	return nil
+}
 
-	checkoutDir, err := os.MkdirTemp("", "aro-hcp-checkout-*")
-	if err != nil {
-		return "", nil, fmt.Errorf("create temp checkout: %w", err)
-	}
+type imageInfo struct {
+	Labels map[string]string
+	Tags   []string
+}
 
-	cleanup := func() {
-		if err := os.RemoveAll(checkoutDir); err != nil {
-			t.log.Error(err, "cleanup checkout dir failed", "dir", checkoutDir)
+// inspectImage resolves image labels and tags with the native registry client,
+// falling back to skopeo when the client is disabled or fails to read the
+// labels.
+func (t *Tracer) inspectImage(ctx context.Context, registry, repository, digest string) (imageInfo, error) {
+	if t.registry != nil {
+		info, err := t.registry.inspect(ctx, registry, repository, digest)
+		if err == nil {
+			// Tags are best-effort, as with skopeo.
+			if t.cfg.MaxTagCandidates >= 0 {
+				info.Tags, err = t.registry.tagsForDigest(ctx, registry, repository, digest, t.cfg.MaxTagCandidates)
+				if err != nil {
+					t.log.ForContext(ctx).Debug("list tags failed", "repository", registry+"/"+repository, "error", err.Error())
+				}
+			}
+			return info, nil
 		}
+		// skopeo would only wait on the same unreachable registry again.
+		if ctx.Err() != nil || isUnreachable(err) {
+			return imageInfo{}, err
+		}
+		t.log.ForContext(ctx).Debug("native registry inspect failed; falling back to skopeo", "image", registry+"/"+repository, "error", err.Error())
 	}
+	return t.inspectImageSkopeo(ctx, registry, repository, digest)
+}
 
-	if err := t.repo.WorktreeAddDetach(ctx, checkoutDir, commit); err != nil {
-		cleanup()
-		return "", nil, fmt.Errorf("create worktree: %w", err)
-	}
-
-	return checkoutDir, func() {
-		if err := t.repo.WorktreeRemove(context.Background(), checkoutDir); err != nil {
-			t.log.Error(err, "remove worktree failed", "dir", checkoutDir)
+// resolveTag returns the digest tag points at, with the native registry
+// client or skopeo, like inspectImage.
+func (t *Tracer) resolveTag(ctx context.Context, registry, repository, tag string) (string, error) {
+	if t.registry != nil {
+		digest, err := t.registry.resolveTag(ctx, registry, repository, tag)
+		if err == nil {
+			return digest, nil
 		}
-		cleanup()
-	}, nil
+		if ctx.Err() != nil || isUnreachable(err) {
+			return "", err
+		}
+		t.log.ForContext(ctx).Debug("native tag lookup failed; falling back to skopeo", "image", registry+"/"+repository+":"+tag, "error", err.Error())
+	}
+	args := []string{"inspect", "--raw"}
+	if t.cfg.PullSecret != "" {
+		args = append(args, "--authfile", t.cfg.PullSecret)
+	}
+	args = append(args, fmt.Sprintf("docker://%s/%s:%s", registry, repository, tag))
+	manifest, err := t.runSkopeo(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	return manifestDigest(manifest), nil
 }
 
-func (t *Tracer) inspectImage(ctx context.Context, registry, repository, digest string) (map[string]string, error) {
+func (t *Tracer) inspectImageSkopeo(ctx context.Context, registry, repository, digest string) (imageInfo, error) {
 	imageRef := fmt.Sprintf("%s/%s@%s", registry, repository, digest)
 	args := []string{"inspect", "--raw"}
 	if t.cfg.PullSecret != "" {
@@ -309,12 +484,12 @@ func (t *Tracer) inspectImage(ctx context.Context, registry, repository, digest
 	args = append(args, "docker://"+imageRef)
 	output, err := t.runSkopeo(ctx, args...)
 	if err != nil {
-		return nil, err
+		return imageInfo{}, err
 	}
 	manifestJSON := string(output)
 	configRef, err := resolveConfigReference(manifestJSON, registry, repository, digest)
 	if err != nil {
-		return nil, err
+		return imageInfo{}, err
 	}
 
 	configArgs := []string{"inspect", "--config"}
@@ -324,7 +499,7 @@ func (t *Tracer) inspectImage(ctx context.Context, registry, repository, digest
 	configArgs = append(configArgs, configRef)
 	configData, err := t.runSkopeo(ctx, configArgs...)
 	if err != nil {
-		return nil, err
+		return imageInfo{}, err
 	}
 
 	labels := make(map[string]string)
@@ -336,7 +511,62 @@ func (t *Tracer) inspectImage(ctx context.Context, registry, repository, digest
 		return true
 	})
 
-	return labels, nil
+	// Tag history is best-effort: a registry that refuses tag listing should
+	// not hide the source SHA we already resolved.
+	tags, err := t.tagsForDigest(ctx, registry, repository, digest)
+	if err != nil {
+		t.log.ForContext(ctx).Debug("list tags failed", "repository", registry+"/"+repository, "error", err.Error())
+	}
+
+	return imageInfo{Labels: labels, Tags: tags}, nil
+}
+
+// tagsForDigest lists the repository tags and returns those whose manifest
+// digest matches digest. Only MaxTagCandidates tags are resolved, since each
+// one costs a registry round trip; see candidateTags.
+func (t *Tracer) tagsForDigest(ctx context.Context, registry, repository, digest string) ([]string, error) {
+	if t.cfg.MaxTagCandidates < 0 || digest == "" {
+		return nil, nil
+	}
+	args := []string{"list-tags"}
+	if t.cfg.PullSecret != "" {
+		args = append(args, "--authfile", t.cfg.PullSecret)
+	}
+	args = append(args, fmt.Sprintf("docker://%s/%s", registry, repository))
+	output, err := t.runSkopeo(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var listed []string
+	for _, tag := range gjson.GetBytes(output, "Tags").Array() {
+		listed = append(listed, tag.Str)
+	}
+	candidates := candidateTags(listed, t.cfg.MaxTagCandidates)
+	var matches []string
+	for _, tag := range candidates {
+		rawArgs := []string{"inspect", "--raw"}
+		if t.cfg.PullSecret != "" {
+			rawArgs = append(rawArgs, "--authfile", t.cfg.PullSecret)
+		}
+		rawArgs = append(rawArgs, fmt.Sprintf("docker://%s/%s:%s", registry, repository, tag))
+		manifest, err := t.runSkopeo(ctx, rawArgs...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return matches, ctx.Err()
+			}
+			continue
+		}
+		if manifestDigest(manifest) == digest {
+			matches = append(matches, tag)
+		}
+	}
+	return matches, nil
+}
+
+func manifestDigest(manifest []byte) string {
+	sum := sha256.Sum256(manifest)
+	return "sha256:" + hex.EncodeToString(sum[:])
 }
 
 func resolveConfigReference(manifest string, registry, repository, digest string) (string, error) {
@@ -368,16 +598,19 @@ func resolveConfigReference(manifest string, registry, repository, digest string
 	}
 }
 
-func (t *Tracer) runSkopeo(ctx context.Context, args ...string) ([]byte, error) {
+func (t *Tracer) runSkopeo(ctx context.Context, args ...string) (_ []byte, err error) {
+	ctx, span := telemetry.Start(ctx, "skopeo "+args[0], attribute.StringSlice("skopeo.args", args))
+	defer func() { telemetry.End(span, err) }()
+
 	cmd := exec.CommandContext(ctx, t.cfg.SkopeoPath, args...)
 	cmd.Env = os.Environ()
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		trimmed := strings.TrimSpace(string(output))
 		if trimmed != "" {
-			t.log.Debug("skopeo stderr", "output", trimmed)
+			t.log.ForContext(ctx).Debug("skopeo stderr", "output", trimmed)
 		}
-		t.log.Error(err, "skopeo command failed", "args", args)
+		t.log.ForContext(ctx).Error(err, "skopeo command failed", "args", args)
 		return nil, fmt.Errorf("skopeo %s: %v: %s", strings.Join(args, " "), err, trimmed)
 	}
 	return output, nil
@@ -1,13 +1,19 @@
 package traceimages
 
+import tooltypes "github.com/roivaz/aro-hcp-intelhub/internal/mcp/tools/types"
+
 type Component struct {
 	Name          string
 	Registry      string
 	Repository    string
 	Digest        string
+	ConfigTag     string // set when the config references a tag, not a digest
 	SourceSHA     *string
 	SourceRepoURL *string
+	Tags          []string
+	ConfigSource  *tooltypes.ConfigSource
 	Error         *string
+	ErrorClass    *string
 }
 
 type TraceResult struct {
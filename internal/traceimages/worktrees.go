@@ -0,0 +1,243 @@
+package traceimages
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
+)
+
+const (
+	checkoutPattern = "aro-hcp-checkout-*"
+	// staleCheckoutAge is how old a checkout directory must be before the
+	// startup cleanup considers it. It covers the moment between creating a
+	// checkout and locking it.
+	staleCheckoutAge = time.Hour
+)
+
+// checkoutLock is the lock file a pool holds for as long as it keeps the
+// checkout at dir, so other processes leave the checkout alone.
+func checkoutLock(dir string) string {
+	return dir + ".lock"
+}
+
+// worktreePool keeps detached worktrees of recently traced commits so
+// repeated traces of a commit (one per environment, force refreshes) skip
+// the checkout. Worktrees not in use are evicted least recently used first
+// once the pool holds more than size; worktrees in use are never evicted,
+// so the pool may briefly exceed size.
+type worktreePool struct {
+	repo *gitrepo.Repo
+	size int
+	log  logging.Logger
+	now  func() time.Time
+
+	cleanup sync.Once
+
+	mu      sync.Mutex
+	entries map[string]*worktree // by resolved commit SHA
+}
+
+type worktree struct {
+	dir      string
+	refs     int
+	lastUsed time.Time
+	ready    chan struct{} // closed once dir is checked out or err is set
+	err      error
+	unlock   func() // releases checkoutLock(dir)
+}
+
+func newWorktreePool(repo *gitrepo.Repo, size int, log logging.Logger) *worktreePool {
+	return &worktreePool{repo: repo, size: max(size, 1), log: log, now: time.Now, entries: map[string]*worktree{}}
+}
+
+// acquire returns a checkout of commit and a func releasing it back to the
+// pool. Concurrent acquires of one commit share a single checkout.
+func (p *worktreePool) acquire(ctx context.Context, commit string) (string, func(), error) {
+	p.cleanup.Do(func() { p.removeStale(ctx) })
+
+	sha, err := p.repo.ResolveRevision(ctx, commit)
+	if err != nil {
+		return "", nil, fmt.Errorf("resolve commit %s: %w", commit, err)
+	}
+
+	p.mu.Lock()
+	wt, ok := p.entries[sha]
+	if !ok {
+		wt = &worktree{ready: make(chan struct{})}
+		p.entries[sha] = wt
+	}
+	wt.refs++
+	wt.lastUsed = p.now()
+	p.mu.Unlock()
+	release := func() { p.release(wt) }
+
+	if !ok {
+		wt.dir, wt.unlock, wt.err = p.create(ctx, sha)
+		close(wt.ready)
+	} else {
+		select {
+		case <-wt.ready:
+		case <-ctx.Done():
+			release()
+			return "", nil, ctx.Err()
+		}
+	}
+	err = wt.err
+	if err == nil {
+		if _, statErr := os.Stat(wt.dir); statErr != nil {
+			err = fmt.Errorf("worktree %s vanished: %w", wt.dir, statErr)
+		}
+	}
+	if err != nil {
+		p.discard(sha, wt)
+		release()
+		return "", nil, err
+	}
+	now := p.now()
+	if err := os.Chtimes(wt.dir, now, now); err != nil {
+		p.log.ForContext(ctx).Debug("touch worktree failed", "dir", wt.dir, "error", err.Error())
+	}
+	return wt.dir, release, nil
+}
+
+func (p *worktreePool) create(ctx context.Context, sha string) (string, func(), error) {
+	dir, err := os.MkdirTemp("", checkoutPattern)
+	if err != nil {
+		return "", nil, fmt.Errorf("create temp checkout: %w", err)
+	}
+	unlock, err := gitrepo.LockFile(ctx, checkoutLock(dir), true)
+	if err == nil {
+		err = p.repo.WorktreeAddDetach(ctx, dir, sha)
+		if err != nil {
+			_ = os.Remove(checkoutLock(dir))
+			unlock()
+		}
+	}
+	if err != nil {
+		if err := os.RemoveAll(dir); err != nil {
+			p.log.ForContext(ctx).Error(err, "cleanup checkout dir failed", "dir", dir)
+		}
+		return "", nil, fmt.Errorf("create worktree: %w", err)
+	}
+	return dir, unlock, nil
+}
+
+// discard drops a failed entry so the next acquire checks the commit out
+// again.
+func (p *worktreePool) discard(sha string, wt *worktree) {
+	p.mu.Lock()
+	dropped := p.entries[sha] == wt
+	if dropped {
+		delete(p.entries, sha)
+	}
+	p.mu.Unlock()
+	if dropped && wt.unlock != nil {
+		_ = os.Remove(checkoutLock(wt.dir))
+		wt.unlock()
+	}
+}
+
+func (p *worktreePool) release(wt *worktree) {
+	p.mu.Lock()
+	wt.refs--
+	var evicted []*worktree
+	for len(p.entries) > p.size {
+		sha, oldest := "", (*worktree)(nil)
+		for s, e := range p.entries {
+			if e.refs == 0 && (oldest == nil || e.lastUsed.Before(oldest.lastUsed)) {
+				sha, oldest = s, e
+			}
+		}
+		if oldest == nil {
+			break
+		}
+		delete(p.entries, sha)
+		if oldest.err == nil {
+			evicted = append(evicted, oldest)
+		}
+	}
+	p.mu.Unlock()
+	for _, wt := range evicted {
+		p.remove(context.Background(), wt)
+	}
+}
+
+// remove deletes the checkout of wt, then gives up its lock.
+func (p *worktreePool) remove(ctx context.Context, wt *worktree) {
+	if err := p.repo.WorktreeRemove(ctx, wt.dir); err != nil {
+		p.log.ForContext(ctx).Error(err, "remove worktree failed", "dir", wt.dir)
+	}
+	if err := os.RemoveAll(wt.dir); err != nil {
+		p.log.ForContext(ctx).Error(err, "cleanup checkout dir failed", "dir", wt.dir)
+	}
+	_ = os.Remove(checkoutLock(wt.dir))
+	wt.unlock()
+}
+
+// close removes every pooled worktree, in use or not.
+func (p *worktreePool) close() {
+	p.mu.Lock()
+	var pooled []*worktree
+	for _, wt := range p.entries {
+		select {
+		case <-wt.ready:
+			if wt.err == nil {
+				pooled = append(pooled, wt)
+			}
+		default:
+		}
+	}
+	p.entries = map[string]*worktree{}
+	p.mu.Unlock()
+	for _, wt := range pooled {
+		p.remove(context.Background(), wt)
+	}
+}
+
+// removeStale deletes checkout directories left behind by crashed processes
+// and prunes their worktree records. A checkout is only removed once its lock
+// is taken, so those another live process keeps are left alone.
+func (p *worktreePool) removeStale(ctx context.Context) {
+	log := p.log.ForContext(ctx)
+	dirs, err := filepath.Glob(filepath.Join(os.TempDir(), checkoutPattern))
+	if err != nil {
+		return
+	}
+	// A done context makes LockFile give up at once on held locks.
+	tryCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	removed := 0
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() || p.now().Sub(info.ModTime()) < staleCheckoutAge {
+			continue
+		}
+		unlock, err := gitrepo.LockFile(tryCtx, checkoutLock(dir), true)
+		if err != nil {
+			continue
+		}
+		err = os.RemoveAll(dir)
+		if err == nil {
+			_ = os.Remove(checkoutLock(dir))
+		}
+		unlock()
+		if err != nil {
+			log.Error(err, "remove stale checkout failed", "dir", dir)
+			continue
+		}
+		removed++
+	}
+	if removed == 0 {
+		return
+	}
+	log.Info("removed stale checkouts", "count", removed)
+	if err := p.repo.WorktreePrune(ctx); err != nil {
+		log.Error(err, "prune worktrees failed")
+	}
+}
@@ -0,0 +1,100 @@
+package traceimages
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/roivaz/aro-hcp-intelhub/internal/gitrepo"
+	"github.com/roivaz/aro-hcp-intelhub/internal/logging"
+)
+
+func TestWorktreePool(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	stale := filepath.Join(os.TempDir(), "aro-hcp-checkout-stale")
+	if err := os.Mkdir(stale, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// An old checkout another process still holds is kept.
+	held := filepath.Join(os.TempDir(), "aro-hcp-checkout-held")
+	if err := os.Mkdir(held, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := gitrepo.LockFile(context.Background(), checkoutLock(held), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	old := time.Now().Add(-2 * staleCheckoutAge)
+	for _, dir := range []string{stale, held} {
+		if err := os.Chtimes(dir, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var commits []string
+	for _, content := range []string{"v1\n", "v2\n"} {
+		if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add("README.md"); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := wt.Commit("change", &git.CommitOptions{Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, hash.String())
+	}
+
+	ctx := context.Background()
+	pool := newWorktreePool(gitrepo.New(gitrepo.RepoConfig{Settings: gitrepo.Settings{Backend: gitrepo.BackendGoGit}, Path: dir}), 1, logging.New(logging.DefaultLogger()))
+
+	first, release1, err := pool.acquire(ctx, commits[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale checkout not removed: %v", err)
+	}
+	if _, err := os.Stat(held); err != nil {
+		t.Errorf("held checkout removed: %v", err)
+	}
+	again, release2, err := pool.acquire(ctx, commits[0])
+	if err != nil || again != first {
+		t.Fatalf("second acquire = %q, %v; want shared %q", again, err, first)
+	}
+	release1()
+	release2()
+
+	second, release3, err := pool.acquire(ctx, commits[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(second, "README.md")); err != nil || string(data) != "v2\n" {
+		t.Fatalf("checkout = %q, %v", data, err)
+	}
+	release3()
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Errorf("least recently used worktree kept: %v", err)
+	}
+
+	pool.close()
+	if _, err := os.Stat(second); !os.IsNotExist(err) {
+		t.Errorf("worktree kept after close: %v", err)
+	}
+}